@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/changelog"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/diff"
+	"github.com/kirmad/superopencode/internal/format"
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+const changelogPrompt = `Write the changelog section of a release from the commits below, grouped by type.
+
+- Keep the "## " heading and the "### " sections as given, drop sections that end up empty.
+- Rewrite each entry as a short sentence about the change for users of the project, not about the code.
+- Merge entries about the same change and leave out changes users don't notice, like CI or test updates, unless nothing else is left.
+- Keep the pull request numbers and commit hashes in parentheses.
+- Put breaking changes first in their section.
+
+Respond with the markdown section only.
+
+`
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog <from>..<to>",
+	Short: "Draft a changelog section from a range of commits",
+	Long: `Gather the commits of a git range, group them by their conventional commit
+type and let the model draft a changelog section from them.
+
+The section is added to the top of the changelog file. The diff is shown
+first and the file is only written after you confirm it.`,
+	Example: `
+  # Changes since the last release
+  opencode changelog v1.2.0..HEAD --version v1.3.0
+
+  # Group the commits without the model and write without asking
+  opencode changelog v1.2.0..v1.3.0 --no-model --yes
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: runChangelog,
+}
+
+func runChangelog(cmd *cobra.Command, args []string) error {
+	rangeSpec := args[0]
+	version, _ := cmd.Flags().GetString("version")
+	file, _ := cmd.Flags().GetString("file")
+	noModel, _ := cmd.Flags().GetBool("no-model")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	from, to, ok := strings.Cut(rangeSpec, "..")
+	if !ok || from == "" {
+		return fmt.Errorf("invalid range %q, use <from>..<to>", rangeSpec)
+	}
+	if version == "" {
+		version = "Unreleased"
+		if to != "" && to != "HEAD" {
+			version = to
+		}
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current working directory: %w", err)
+	}
+	if _, err := config.Load(cwd, false); err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	ctx := context.Background()
+	commits, err := changelog.Commits(ctx, cwd, rangeSpec)
+	if err != nil {
+		return err
+	}
+	sections := changelog.Group(commits)
+	if len(sections) == 0 {
+		return fmt.Errorf("no commits in %s", rangeSpec)
+	}
+	heading := fmt.Sprintf("%s (%s)", version, time.Now().Format("2006-01-02"))
+	section := changelog.Format(heading, sections)
+
+	if !noModel {
+		spinner := format.NewSpinner("Drafting the changelog...")
+		spinner.Start()
+		draft, err := agent.Complete(ctx, config.AgentSummarizer, changelogPrompt+section)
+		spinner.Stop()
+		if err != nil {
+			return fmt.Errorf("failed to draft the changelog: %w", err)
+		}
+		if !strings.HasPrefix(draft, "## ") {
+			draft = "## " + heading + "\n\n" + draft
+		}
+		section = draft
+	}
+
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(cwd, file)
+	}
+	before, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	after := changelog.Insert(string(before), section)
+
+	unified, additions, removals := diff.GenerateDiff(string(before), after, file)
+	fmt.Println(unified)
+	fmt.Printf("%d additions, %d removals\n", additions, removals)
+
+	if !yes {
+		if !isatty.IsTerminal(os.Stdin.Fd()) {
+			fmt.Println("Nothing written, run with --yes to write the changelog")
+			return nil
+		}
+		fmt.Printf("Write %s? [y/N] ", filepath.Base(file))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			fmt.Println("Nothing written")
+			return nil
+		}
+	}
+	if err := os.WriteFile(file, []byte(after), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	fmt.Printf("Updated %s\n", file)
+	return nil
+}
+
+func init() {
+	changelogCmd.Flags().String("version", "", "Heading of the section, defaults to the end of the range or Unreleased")
+	changelogCmd.Flags().String("file", "CHANGELOG.md", "Changelog file to update")
+	changelogCmd.Flags().Bool("no-model", false, "Write the grouped commits without drafting them with the model")
+	changelogCmd.Flags().BoolP("yes", "y", false, "Write the changelog without asking for confirmation")
+
+	rootCmd.AddCommand(changelogCmd)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+	Long: `Inspect the configuration and where its values come from.
+
+The configuration is layered, later layers take precedence:
+  1. The defaults
+  2. The user config, e.g. $HOME/.opencode.json
+  3. .opencode.json in the working directory
+  4. .opencode/config.json or .opencode/config.yaml in the working directory
+  5. Environment variables, e.g. OPENCODE_DEBUG`,
+}
+
+var configWhichCmd = &cobra.Command{
+	Use:   "which <key>",
+	Short: "Show a config value and where it came from",
+	Example: `
+  # The theme and the file that sets it
+  opencode config which tui.theme
+
+  # The model of the coder agent
+  opencode config which agents.coder.model
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigWhich,
+}
+
+func runConfigWhich(cmd *cobra.Command, args []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current working directory: %w", err)
+	}
+	if _, err := config.Load(cwd, false); err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	value, source, ok := config.Which(args[0])
+	if !ok {
+		return fmt.Errorf("%s is not set", args[0])
+	}
+	output, err := json.Marshal(value)
+	if err != nil {
+		output = []byte(fmt.Sprint(value))
+	}
+	fmt.Printf("%s = %s\n", args[0], output)
+	fmt.Printf("from %s\n", source)
+	return nil
+}
+
+func init() {
+	configCmd.AddCommand(configWhichCmd)
+	rootCmd.AddCommand(configCmd)
+}
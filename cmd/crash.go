@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/browser"
+)
+
+const issuesURL = "https://github.com/kirmad/superopencode/issues/new"
+
+// maxIssueSummary keeps the prefilled issue body within URL length limits
+const maxIssueSummary = 4000
+
+// OfferCrashReport points the user to the crash reports written by this run
+// and offers to open a prefilled bug report. Nothing is shared unless the
+// user agrees, and the bundle itself is never uploaded.
+func OfferCrashReport() {
+	bundles := logging.CrashBundles()
+	if len(bundles) == 0 {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "\nOpenCode recovered from %d crash(es). Crash reports with stack traces, recent logs and a redacted config were written to:\n", len(bundles))
+	for _, dir := range bundles {
+		fmt.Fprintf(os.Stderr, "  %s\n", dir)
+	}
+	fmt.Fprintf(os.Stderr, "Please review them and attach them to a bug report at %s\n", issuesURL)
+
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		return
+	}
+	fmt.Fprint(os.Stderr, "Open a prefilled bug report in your browser? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+		return
+	}
+	if err := browser.OpenURL(crashIssueURL(bundles[0])); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open the browser: %v\n", err)
+	}
+}
+
+// crashIssueURL returns a new issue URL prefilled with the crash summary
+func crashIssueURL(bundle string) string {
+	summary, err := os.ReadFile(filepath.Join(bundle, "crash.txt"))
+	if err != nil {
+		summary = []byte("Crash report: " + bundle)
+	}
+	text := string(summary)
+	if len(text) > maxIssueSummary {
+		text = text[:maxIssueSummary] + "\n…"
+	}
+	title, _, _ := strings.Cut(text, "\n")
+
+	query := url.Values{}
+	query.Set("title", title)
+	query.Set("body", "<!-- Describe what you were doing and attach the files of the crash report -->\n\n```\n"+text+"\n```\n")
+	return issuesURL + "?" + query.Encode()
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/app"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/daemon"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/format"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/settings"
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Serve non-interactive prompts from a warm background process",
+	Long: `Run the app in the foreground and serve the prompts of 'opencode -p' run in
+the same project over a unix socket in the data directory. The config, the
+database, the LSP servers and the MCP tools are loaded once, so prompts start
+right away instead of paying the startup cost on every invocation.
+
+'opencode -p' falls back to running the prompt itself when no daemon is
+running. Restart the daemon after changing the config.`,
+	Example: `
+  # Start the daemon of the current project in the background
+  opencode daemon &
+
+  # Prompts are now served by the daemon
+  opencode -p "Explain the use of context in Go"
+
+  # Show and stop the daemon
+  opencode daemon status
+  opencode daemon stop
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		app, err := app.New(ctx, conn, settings.FromConfig(config.Get()))
+		if err != nil {
+			logging.Error("Failed to create app: %v", err)
+			return err
+		}
+		defer app.Shutdown()
+		initMCPTools(ctx, app)
+		app.SyncKnowledge(ctx)
+
+		server, err := daemon.Listen(daemonSocketPath(), app)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Daemon listening on %s\n", daemonSocketPath())
+		return server.Serve(ctx)
+	},
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the daemon of the current project",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		response, err := daemon.Send(daemonSocketPath(), daemon.Request{Command: daemon.CommandStatus})
+		if errors.Is(err, daemon.ErrNotRunning) {
+			fmt.Println("No daemon running")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if response.Status == nil {
+			return fmt.Errorf("unexpected response: %s", response.Error)
+		}
+		status := response.Status
+		fmt.Printf("Daemon running on %s\n", daemonSocketPath())
+		fmt.Printf("PID:     %d\n", status.PID)
+		fmt.Printf("Uptime:  %s\n", time.Since(status.StartedAt).Round(time.Second))
+		fmt.Printf("Prompts: %d served, %d running\n", status.Runs, status.Running)
+		return nil
+	},
+}
+
+var daemonStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the daemon of the current project",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		_, err := daemon.Send(daemonSocketPath(), daemon.Request{Command: daemon.CommandStop})
+		if errors.Is(err, daemon.ErrNotRunning) {
+			fmt.Println("No daemon running")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println("Daemon stopped")
+		return nil
+	},
+}
+
+func daemonSocketPath() string {
+	return daemon.SocketPath(config.Get().Data.Directory)
+}
+
+// runWithDaemon runs the prompt in the daemon of the project. ok is false
+// when no daemon is running and the prompt has to be run locally.
+func runWithDaemon(prompt string, outputFormat string, quiet bool, processors []config.OutputProcessor) (ok bool, err error) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var spinner *format.Spinner
+	if !quiet {
+		spinner = format.NewSpinner("Thinking...")
+		spinner.Start()
+		defer spinner.Stop()
+	}
+
+	response, err := daemon.SendContext(ctx, daemonSocketPath(), daemon.Request{
+		Command: daemon.CommandRun,
+		Prompt:  prompt,
+	})
+	switch {
+	case errors.Is(err, daemon.ErrNotRunning):
+		return false, nil
+	case errors.Is(err, context.Canceled):
+		return true, nil
+	case err != nil:
+		return true, err
+	case response.Error != "":
+		return true, errors.New(response.Error)
+	}
+
+	// Daemons started by an earlier version only send the output
+	result := format.Result{Response: response.Output}
+	if response.Result != nil {
+		result = *response.Result
+	}
+	output, err := format.Render(ctx, result, outputFormat, processors)
+	if err != nil {
+		return true, err
+	}
+	if spinner != nil {
+		spinner.Stop()
+	}
+	fmt.Println(output)
+	return true, nil
+}
+
+func init() {
+	daemonCmd.AddCommand(daemonStatusCmd, daemonStopCmd)
+	rootCmd.AddCommand(daemonCmd)
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/kirmad/superopencode/internal/app"
+	"github.com/kirmad/superopencode/internal/checkpoint"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/history"
+	"github.com/kirmad/superopencode/internal/locale"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [from] [to]",
+	Short: "Show the changes of a session between two points",
+	Long: `Print the combined diff of the files a session changed between two of its
+points, as a patch that applies with git apply.
+
+A point is "start", before the session changed anything, "end", its latest
+state, the number of a turn for the state after that turn, or the name of a
+checkpoint. The diff goes from the start to the end by default.`,
+	Example: `
+  # Everything the latest session changed
+  opencode diff
+
+  # The points of a session
+  opencode diff --session 3f2a9c --points
+
+  # The changes of turns 3 to 5, saved as a patch file
+  opencode diff 2 5 -o good-part.patch
+
+  # The changes since a checkpoint
+  opencode diff before-refactor end --stat
+  `,
+	Args: cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessionID, _ := cmd.Flags().GetString("session")
+		output, _ := cmd.Flags().GetString("output")
+		showPoints, _ := cmd.Flags().GetBool("points")
+		stat, _ := cmd.Flags().GetBool("stat")
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		q := db.New(conn)
+
+		ctx := context.Background()
+		sessions := session.NewService(q)
+		if sessionID == "" {
+			all, err := sessions.List(ctx)
+			if err != nil {
+				return err
+			}
+			if len(all) == 0 {
+				return errors.New("no sessions yet")
+			}
+			sessionID = all[0].ID
+		} else if _, err := sessions.Get(ctx, sessionID); err != nil {
+			return fmt.Errorf("unknown session %s", sessionID)
+		}
+
+		msgs, err := message.NewService(q).List(ctx, sessionID)
+		if err != nil {
+			return err
+		}
+		checkpoints, err := checkpoint.List(app.CheckpointsDir(sessionID))
+		if err != nil {
+			return err
+		}
+		points := app.SessionPoints(msgs, checkpoints)
+		if showPoints {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "POINT\tTIME\tDESCRIPTION\n")
+			for _, point := range points {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", point.Name, locale.DateTime(point.Time), point.Label)
+			}
+			return w.Flush()
+		}
+
+		names := []string{app.StartPoint, app.EndPoint}
+		copy(names, args)
+		from, err := app.FindSessionPoint(points, names[0])
+		if err != nil {
+			return err
+		}
+		to, err := app.FindSessionPoint(points, names[1])
+		if err != nil {
+			return err
+		}
+		files, err := history.NewService(q, conn, nil).ListBySession(ctx, sessionID)
+		if err != nil {
+			return err
+		}
+		changes := app.DiffSessionPoints(files, from, to)
+		if len(changes) == 0 {
+			fmt.Fprintf(os.Stderr, "No changes from %s to %s\n", from.Label, to.Label)
+			return nil
+		}
+
+		if stat {
+			wd := config.WorkingDirectory()
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, change := range changes {
+				path, err := filepath.Rel(wd, change.Path)
+				if err != nil {
+					path = change.Path
+				}
+				note := ""
+				switch {
+				case change.Created:
+					note = "created"
+				case change.Deleted:
+					note = "deleted"
+				}
+				fmt.Fprintf(w, "+%d\t-%d\t%s\t%s\n", change.Additions, change.Removals, path, note)
+			}
+			return w.Flush()
+		}
+		patch := app.Patch(changes)
+		if output == "" {
+			fmt.Print(patch)
+			return nil
+		}
+		if err := os.WriteFile(output, []byte(patch), 0o644); err != nil {
+			return fmt.Errorf("failed to write the patch: %w", err)
+		}
+		fmt.Printf("Wrote the changes of %d files, from %s to %s, to %s\n", len(changes), from.Label, to.Label, output)
+		return nil
+	},
+}
+
+func init() {
+	diffCmd.Flags().StringP("session", "s", "", "Session to compare (default the latest session)")
+	diffCmd.Flags().StringP("output", "o", "", "Write the patch to a file instead of printing it")
+	diffCmd.Flags().Bool("points", false, "List the points of the session")
+	diffCmd.Flags().Bool("stat", false, "Only list the changed files with their added and removed lines")
+	rootCmd.AddCommand(diffCmd)
+}
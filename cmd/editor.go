@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kirmad/superopencode/internal/app"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/editor"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/settings"
+	"github.com/spf13/cobra"
+)
+
+var editorCmd = &cobra.Command{
+	Use:   "editor",
+	Short: "Serve editor plugins over a local socket",
+	Long: `Run the app in the foreground as the engine of editor plugins, such as Neovim
+or VS Code extensions. Plugins connect to a unix socket in the data directory,
+or run this command with --stdio and talk over its stdin and stdout.
+
+Every message is one JSON line. Requests have an id, a method and params:
+
+  initialize    Version, working directory, model and methods
+  session/new   Create a session: {"title"}
+  context/add   Pin a buffer or selection to a session: {"session_id", "buffer"}
+  prompt        Run a prompt: {"session_id", "prompt", "context", "apply"}
+  cancel        Cancel the prompt of a session: {"session_id"}
+
+A prompt returns the answer and the changes of the agent as unified diffs with
+the new content of each file. The files are restored afterwards so the plugin
+applies the changes to its buffers, unless "apply" is set. Plugins can't
+answer permission requests, so their sessions run with all permissions
+granted.`,
+	Example: `
+  # Serve the plugins of the current project
+  opencode editor
+
+  # Serve a plugin that started OpenCode over stdio
+  opencode editor --stdio
+
+  # Send a request by hand
+  echo '{"id":1,"method":"initialize"}' | opencode editor --stdio
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stdio, _ := cmd.Flags().GetBool("stdio")
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		conn, dbWarning, err := connectDB(false)
+		if err != nil {
+			return err
+		}
+		if dbWarning != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", dbWarning)
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		app, err := app.New(ctx, conn, settings.FromConfig(config.Get()))
+		if err != nil {
+			logging.Error("Failed to create app: %v", err)
+			return err
+		}
+		defer app.Shutdown()
+		initMCPTools(ctx, app)
+
+		if stdio {
+			editor.NewServer(app.EditorEngine()).ServeConn(ctx, stdioConn{})
+			return nil
+		}
+		path := editor.SocketPath(config.Get().Data.Directory)
+		server, err := editor.Listen(path, app.EditorEngine())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Editor bridge listening on %s\n", path)
+		return server.Serve(ctx)
+	},
+}
+
+// stdioConn is the connection of a plugin over stdin and stdout
+type stdioConn struct{}
+
+func (stdioConn) Read(p []byte) (int, error)  { return os.Stdin.Read(p) }
+func (stdioConn) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (stdioConn) Close() error                { return os.Stdin.Close() }
+
+func init() {
+	editorCmd.Flags().Bool("stdio", false, "Serve a single plugin over stdin and stdout instead of the socket")
+	rootCmd.AddCommand(editorCmd)
+}
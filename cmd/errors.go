@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/errorpattern"
+	"github.com/kirmad/superopencode/internal/locale"
+	"github.com/spf13/cobra"
+)
+
+var errorsCmd = &cobra.Command{
+	Use:   "errors",
+	Short: "Show the errors that recur across sessions",
+	Long: `Display the tool and LLM errors hit again and again across sessions, with
+how often they were hit and what fixed them the last time.
+
+Errors are only recorded with errorPatterns.enabled. When an error recurs
+errorPatterns.minOccurrences times, the agent is told what fixed it last time.`,
+	Example: `
+  # Errors hit at least 3 times
+  opencode errors
+
+  # Errors hit at least twice, up to 50 of them
+  opencode errors --min 2 --limit 50
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		minOccurrences, _ := cmd.Flags().GetInt("min")
+		limit, _ := cmd.Flags().GetInt("limit")
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("min") {
+			minOccurrences = config.Get().ErrorPatterns.MinOccurrences
+		}
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		patterns, err := errorpattern.NewService(db.New(conn)).List(context.Background(), minOccurrences, limit)
+		if err != nil {
+			return fmt.Errorf("failed to read error patterns: %w", err)
+		}
+		if len(patterns) == 0 {
+			if !config.Get().ErrorPatterns.Enabled {
+				fmt.Println("Error patterns are disabled, enable them with errorPatterns.enabled")
+				return nil
+			}
+			fmt.Printf("No error was hit %d times or more\n", minOccurrences)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "HITS\tSESSIONS\tLAST SEEN\tKIND\tNAME\tERROR\tLAST FIX\n")
+		for _, pattern := range patterns {
+			resolution := pattern.Resolution
+			if resolution == "" {
+				resolution = "-"
+			}
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
+				pattern.Occurrences, pattern.Sessions, locale.DateTime(pattern.LastSeen),
+				pattern.Kind, pattern.Name, pattern.Message, resolution)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	errorsCmd.Flags().Int("min", 0, "Minimum number of hits of the errors listed (default errorPatterns.minOccurrences)")
+	errorsCmd.Flags().Int("limit", 20, "Maximum number of errors listed")
+	rootCmd.AddCommand(errorsCmd)
+}
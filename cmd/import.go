@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/importer"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>...",
+	Short: "Import conversations from other coding agents",
+	Long: `Convert conversations exported by other coding agents into sessions of the
+current project, so you can keep your history and continue them here.
+
+Supported formats:
+  claude-code  Session transcripts, ~/.claude/projects/<project>/<session>.jsonl
+  aider        Chat history, .aider.chat.history.md (a session per aider run)
+  cursor       Markdown written by "Export Chat"
+
+The format is detected from the file, --format forces it. Tool calls are
+summed up in the text of the messages and the files they read or edited are
+listed below them.`,
+	Example: `
+  # Import a Claude Code session
+  opencode import ~/.claude/projects/-home-me-project/2f6c1e0a.jsonl
+
+  # Import every run of aider in the project
+  opencode import .aider.chat.history.md
+  `,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		q := db.New(conn)
+		sessions := session.NewService(q)
+		messages := message.NewService(q)
+
+		ctx := context.Background()
+		for _, path := range args {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			fileFormat := importer.Format(format)
+			if fileFormat == "" {
+				if fileFormat, err = importer.Detect(path, data); err != nil {
+					return err
+				}
+			}
+			conversations, err := importer.Parse(fileFormat, data)
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			if len(conversations) == 0 {
+				fmt.Printf("%s: no conversation to import\n", path)
+				continue
+			}
+			for _, conversation := range conversations {
+				sess, err := importer.Save(ctx, sessions, messages, conversation)
+				if err != nil {
+					return fmt.Errorf("failed to import %s: %w", path, err)
+				}
+				fmt.Printf("Imported %q (%d messages) from %s\n", sess.Title, len(conversation.Messages), path)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	importCmd.Flags().String("format", "", "Export format: claude-code, aider or cursor (detected when empty)")
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kirmad/superopencode/internal/shellintegration"
+	"github.com/spf13/cobra"
+)
+
+var integrationsCmd = &cobra.Command{
+	Use:   "integrations",
+	Short: "Integrate OpenCode with other tools",
+}
+
+var integrationsShellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Install shell functions for common workflows",
+	Long: `Install shell functions that run OpenCode for common workflows:
+
+  ocfix [command]   Run the command, or the previous one again, and triage
+                    its output when it fails
+  occommit [flags]  Commit the staged changes with a drafted message, opened
+                    in the editor for review
+
+The functions are written for bash, zsh or fish, the shell of $SHELL unless
+--shell is set, and are available in new shells.`,
+	Example: `
+  # Install the functions for the current shell
+  opencode integrations shell
+
+  # Show the functions for zsh without installing them
+  opencode integrations shell --shell zsh --print
+
+  # Remove the functions
+  opencode integrations shell --uninstall
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell, _ := cmd.Flags().GetString("shell")
+		printOnly, _ := cmd.Flags().GetBool("print")
+		uninstall, _ := cmd.Flags().GetBool("uninstall")
+
+		if shell == "" {
+			detected, err := shellintegration.Detect()
+			if err != nil {
+				return err
+			}
+			shell = detected
+		}
+		if printOnly {
+			script, err := shellintegration.Script(shell)
+			if err != nil {
+				return err
+			}
+			fmt.Print(script)
+			return nil
+		}
+
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to find the home directory: %w", err)
+		}
+		if uninstall {
+			changed, err := shellintegration.Uninstall(shell, home)
+			if err != nil {
+				return err
+			}
+			if len(changed) == 0 {
+				fmt.Printf("The %s functions are not installed\n", shell)
+				return nil
+			}
+			for _, path := range changed {
+				fmt.Printf("Updated %s\n", path)
+			}
+			fmt.Printf("Removed the %s functions, they stay defined in the shells already running\n", shell)
+			return nil
+		}
+
+		paths, err := shellintegration.Install(shell, home)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s\n", paths.Script)
+		if paths.Startup != "" {
+			fmt.Printf("Updated %s\n", paths.Startup)
+		}
+		fmt.Println("The ocfix and occommit functions are available in new shells")
+		return nil
+	},
+}
+
+func init() {
+	integrationsShellCmd.Flags().String("shell", "", "Shell to install the functions for (bash, zsh, fish), defaults to $SHELL")
+	integrationsShellCmd.Flags().Bool("print", false, "Print the functions instead of installing them")
+	integrationsShellCmd.Flags().Bool("uninstall", false, "Remove the installed functions")
+	integrationsShellCmd.MarkFlagsMutuallyExclusive("print", "uninstall")
+
+	integrationsCmd.AddCommand(integrationsShellCmd)
+	rootCmd.AddCommand(integrationsCmd)
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/knowledge"
+	"github.com/kirmad/superopencode/internal/locale"
+	"github.com/spf13/cobra"
+)
+
+var knowledgeCmd = &cobra.Command{
+	Use:   "knowledge",
+	Short: "Manage the internal docs indexed for the research subagent",
+	Long: `Index the documentation configured in knowledge.sources, a Confluence space,
+a Notion export or a docs site, so the research subagent can search it with
+the knowledge_search tool and cite its pages.
+
+The TUI and the daemon sync the sources every knowledge.refreshHours.`,
+}
+
+var knowledgeSyncCmd = &cobra.Command{
+	Use:   "sync [source...]",
+	Short: "Index the changes of the knowledge sources, all of them by default",
+	Example: `
+  # Sync all the sources
+  opencode knowledge sync
+
+  # Sync the Confluence space configured as "wiki"
+  opencode knowledge sync wiki
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		sources := config.Get().Knowledge.Sources
+		names := args
+		if len(names) == 0 {
+			names = knowledgeSourceNames()
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("no knowledge sources configured, add them to knowledge.sources")
+		}
+		index, err := knowledge.Shared()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+		var failed bool
+		for _, name := range names {
+			source, ok := sources[name]
+			if !ok {
+				return fmt.Errorf("unknown knowledge source %q", name)
+			}
+			fmt.Printf("Syncing %s...\n", name)
+			report, err := index.Sync(ctx, name, source)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+				failed = true
+				continue
+			}
+			fmt.Printf("%s: %d pages, %d indexed, %d removed, %d chunks embedded in %s\n",
+				name, report.Pages, report.Updated, report.Removed, report.Chunks, report.Duration.Round(time.Second))
+		}
+		if failed {
+			return fmt.Errorf("some sources failed to sync")
+		}
+		return nil
+	},
+}
+
+var knowledgeStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the knowledge sources and when they were last synced",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		names := knowledgeSourceNames()
+		if len(names) == 0 {
+			fmt.Println("No knowledge sources configured")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "SOURCE\tTYPE\tLAST SYNC\n")
+		for _, name := range names {
+			last := "never"
+			if t := knowledge.LastSync(name); !t.IsZero() {
+				last = locale.DateTime(t)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", name, config.Get().Knowledge.Sources[name].Type, last)
+		}
+		return w.Flush()
+	},
+}
+
+func knowledgeSourceNames() []string {
+	var names []string
+	for name := range config.Get().Knowledge.Sources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func init() {
+	knowledgeCmd.AddCommand(knowledgeSyncCmd, knowledgeStatusCmd)
+	rootCmd.AddCommand(knowledgeCmd)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/pkg/browser"
+	"github.com/spf13/cobra"
+)
+
+// mcpLoginTimeout is how long the login waits for the browser redirect
+const mcpLoginTimeout = 5 * time.Minute
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Manage the MCP servers",
+}
+
+var mcpStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Connect to the MCP servers and show their health",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		statuses := agent.CheckMCPServers(context.Background())
+		if len(statuses) == 0 {
+			fmt.Println("No MCP servers configured")
+			return nil
+		}
+		for _, status := range statuses {
+			fmt.Println(status)
+		}
+		return nil
+	},
+}
+
+var mcpLoginCmd = &cobra.Command{
+	Use:   "login <server>",
+	Short: "Log in to a remote MCP server with the browser",
+	Long: `Log in to an MCP server configured with oauth.authUrl. The browser opens the
+login page of the server and the token it grants is stored in the data
+directory, it is refreshed when it expires.`,
+	Example: `
+  # Log in to the server named github in mcpServers
+  opencode mcp login github
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		ctx, cancelTimeout := context.WithTimeout(ctx, mcpLoginTimeout)
+		defer cancelTimeout()
+
+		err := agent.MCPLogin(ctx, args[0], func(authURL string) {
+			fmt.Printf("Opening the login page, if it doesn't open visit:\n%s\n", authURL)
+			_ = browser.OpenURL(authURL)
+		})
+		if err != nil {
+			return fmt.Errorf("login failed: %w", err)
+		}
+		fmt.Printf("Logged in to %s\n", args[0])
+		return nil
+	},
+}
+
+func loadConfig() error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current working directory: %w", err)
+	}
+	if _, err := config.Load(cwd, false); err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	mcpCmd.AddCommand(mcpStatusCmd, mcpLoginCmd)
+	rootCmd.AddCommand(mcpCmd)
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/metrics"
+	"github.com/spf13/cobra"
+)
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Show agent task trends",
+	Long: `Display weekly trends of the tasks run by subagents: the number of runs,
+the success rate, the average duration and the cost per subagent type.
+
+Compare the weeks to spot regressions in agent performance, e.g. after
+switching models or changing prompts.
+
+With --latency, display the weekly median and 90th percentile of the time
+the turns waited for the first token, per model.
+
+Run opencode metrics tasks to report on the tasks themselves.`,
+	Example: `
+  # Trends of the last 4 weeks
+  opencode metrics
+
+  # Trends of the last 12 weeks as JSON
+  opencode metrics --weeks 12 --json
+
+  # First token latency of the last 4 weeks
+  opencode metrics --latency
+  `,
+	RunE: runMetrics,
+}
+
+func runMetrics(cmd *cobra.Command, args []string) error {
+	weeks, _ := cmd.Flags().GetInt("weeks")
+	asJSON, _ := cmd.Flags().GetBool("json")
+	latency, _ := cmd.Flags().GetBool("latency")
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current working directory: %w", err)
+	}
+	if _, err := config.Load(cwd, false); err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	conn, err := db.Connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if latency {
+		return printLatencyTrends(metrics.NewService(db.New(conn)), weeks, asJSON)
+	}
+
+	trends, err := metrics.NewService(db.New(conn)).Trends(context.Background(), weeks)
+	if err != nil {
+		return fmt.Errorf("failed to read task metrics: %w", err)
+	}
+
+	if asJSON {
+		output, err := json.MarshalIndent(trends, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal task metrics: %w", err)
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	if len(trends) == 0 {
+		fmt.Printf("No tasks recorded in the last %d weeks\n", weeks)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "WEEK\tSUBAGENT\tRUNS\tSUCCESS\tFAILED\tCANCELED\tAVG DURATION\tCOST\tCOST/RUN\n")
+	for _, trend := range trends {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.0f%%\t%d\t%d\t%s\t$%.2f\t$%.4f\n",
+			trend.Week, trend.Subagent, trend.Runs, trend.SuccessRate()*100, trend.Failed, trend.Canceled,
+			trend.AvgDuration.Round(time.Second), trend.Cost, trend.CostPerRun())
+	}
+	return w.Flush()
+}
+
+func printLatencyTrends(service metrics.Service, weeks int, asJSON bool) error {
+	trends, err := service.LatencyTrends(context.Background(), weeks)
+	if err != nil {
+		return fmt.Errorf("failed to read latency metrics: %w", err)
+	}
+
+	if asJSON {
+		output, err := json.MarshalIndent(trends, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal latency metrics: %w", err)
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	if len(trends) == 0 {
+		fmt.Printf("No turns recorded in the last %d weeks\n", weeks)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "WEEK\tMODEL\tTURNS\tMEDIAN TTFT\tP90 TTFT\n")
+	for _, trend := range trends {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n",
+			trend.Week, trend.Model, trend.Turns,
+			trend.Median.Round(10*time.Millisecond), trend.P90.Round(10*time.Millisecond))
+	}
+	return w.Flush()
+}
+
+func init() {
+	metricsCmd.Flags().Int("weeks", 4, "Number of weeks to report, including the current week")
+	metricsCmd.Flags().Bool("json", false, "Output the trends as JSON")
+	metricsCmd.Flags().Bool("latency", false, "Report the time to the first token instead of the tasks")
+
+	rootCmd.AddCommand(metricsCmd)
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/locale"
+	"github.com/kirmad/superopencode/internal/metrics"
+	"github.com/spf13/cobra"
+)
+
+var metricsTasksCmd = &cobra.Command{
+	Use:   "tasks",
+	Short: "Report on the tasks run by subagents",
+	Long: `Report on the tasks recorded in the last weeks: the runs, success rate,
+retries, durations, tokens and cost per subagent type, followed by the most
+recent tasks.
+
+With --parallel, report on the parallel_tasks runs instead: the number of
+tasks and levels of their dependency graph, how many tasks ran at once, and
+whether the run was stopped by its budget.`,
+	Example: `
+  # Tasks of the last 4 weeks
+  opencode metrics tasks
+
+  # Failed tasks of a session
+  opencode metrics tasks --session 3f2a9c --status failed
+
+  # Parallel runs of the last 12 weeks as JSON
+  opencode metrics tasks --parallel --weeks 12 --json
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		weeks, _ := cmd.Flags().GetInt("weeks")
+		sessionID, _ := cmd.Flags().GetString("session")
+		subagent, _ := cmd.Flags().GetString("subagent")
+		status, _ := cmd.Flags().GetString("status")
+		limit, _ := cmd.Flags().GetInt("limit")
+		parallel, _ := cmd.Flags().GetBool("parallel")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		service := metrics.NewService(db.New(conn))
+
+		if parallel {
+			runs, err := service.ParallelRuns(context.Background(), weeks)
+			if err != nil {
+				return fmt.Errorf("failed to read parallel run metrics: %w", err)
+			}
+			runs = slices.DeleteFunc(runs, func(run metrics.ParallelRun) bool {
+				return sessionID != "" && run.SessionID != sessionID
+			})
+			return printParallelRuns(runs, weeks, limit, asJSON)
+		}
+
+		tasks, err := service.Tasks(context.Background(), weeks)
+		if err != nil {
+			return fmt.Errorf("failed to read task metrics: %w", err)
+		}
+		tasks = slices.DeleteFunc(tasks, func(task metrics.Task) bool {
+			return (sessionID != "" && task.SessionID != sessionID) ||
+				(subagent != "" && task.Subagent != subagent) ||
+				(status != "" && task.Status != status)
+		})
+		return printTasks(tasks, weeks, limit, asJSON)
+	},
+}
+
+func printTasks(tasks []metrics.Task, weeks, limit int, asJSON bool) error {
+	summaries := metrics.Summarize(tasks)
+	// The most recent first
+	slices.Reverse(tasks)
+	if limit > 0 && len(tasks) > limit {
+		tasks = tasks[:limit]
+	}
+
+	if asJSON {
+		output, err := json.MarshalIndent(map[string]any{"summaries": summaries, "tasks": tasks}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal task metrics: %w", err)
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	if len(tasks) == 0 {
+		fmt.Printf("No tasks recorded in the last %d weeks\n", weeks)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "SUBAGENT\tRUNS\tSUCCESS\tFAILED\tCANCELED\tOVER BUDGET\tRETRIES\tAVG DURATION\tP90 DURATION\tTOKENS\tCOST\tCOST/RUN\n")
+	for _, summary := range summaries {
+		fmt.Fprintf(w, "%s\t%d\t%.0f%%\t%d\t%d\t%d\t%d\t%s\t%s\t%d\t$%.2f\t$%.4f\n",
+			summary.Subagent, summary.Runs, summary.SuccessRate()*100, summary.Failed, summary.Canceled, summary.BudgetExceeded, summary.Retries,
+			summary.AvgDuration.Round(time.Second), summary.P90Duration.Round(time.Second), summary.Tokens, summary.Cost, summary.CostPerRun())
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "TIME\tTASK\tSESSION\tSUBAGENT\tSTATUS\tRETRIES\tDURATION\tTOKENS\tCOST\n")
+	for _, task := range tasks {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%d\t$%.4f\n",
+			locale.DateTime(task.Created), task.ID, task.SessionID, task.Subagent, task.Status, task.RetryAttempts,
+			task.Duration.Round(time.Second), task.PromptTokens+task.CompletionTokens, task.Cost)
+	}
+	return w.Flush()
+}
+
+func printParallelRuns(runs []metrics.ParallelRun, weeks, limit int, asJSON bool) error {
+	slices.Reverse(runs)
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+
+	if asJSON {
+		type jsonRun struct {
+			metrics.ParallelRun
+			DAG json.RawMessage `json:"DAG,omitempty"`
+		}
+		output := make([]jsonRun, len(runs))
+		for i, run := range runs {
+			output[i] = jsonRun{ParallelRun: run}
+			if json.Valid([]byte(run.DAG)) {
+				output[i].DAG = json.RawMessage(run.DAG)
+			}
+		}
+		data, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal parallel run metrics: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(runs) == 0 {
+		fmt.Printf("No parallel runs recorded in the last %d weeks\n", weeks)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "TIME\tRUN\tSESSION\tTASKS\tSUCCEEDED\tLEVELS\tMAX PARALLEL\tDURATION\tTOKENS\tCOST\tNOTE\n")
+	for _, run := range runs {
+		note := ""
+		if run.BudgetExceeded {
+			note = "budget exceeded"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\t%d\t$%.4f\t%s\n",
+			locale.DateTime(run.Created), run.ID, run.SessionID, run.Tasks, run.Succeeded, run.Levels, run.MaxParallel,
+			run.Duration.Round(time.Second), run.Tokens, run.Cost, note)
+	}
+	return w.Flush()
+}
+
+func init() {
+	metricsTasksCmd.Flags().Int("weeks", 4, "Number of weeks to report, including the current week")
+	metricsTasksCmd.Flags().StringP("session", "s", "", "Only the tasks launched from this session")
+	metricsTasksCmd.Flags().String("subagent", "", "Only the tasks of this subagent type")
+	metricsTasksCmd.Flags().String("status", "", "Only the tasks with this status: done, failed, canceled or budget_exceeded")
+	metricsTasksCmd.Flags().Int("limit", 20, "Number of recent tasks to list, 0 for all")
+	metricsTasksCmd.Flags().Bool("parallel", false, "Report on the parallel_tasks runs instead of the tasks")
+	metricsTasksCmd.Flags().Bool("json", false, "Output the report as JSON")
+
+	metricsCmd.AddCommand(metricsTasksCmd)
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/kirmad/superopencode/internal/preset"
+	"github.com/spf13/cobra"
+)
+
+var presetCmd = &cobra.Command{
+	Use:   "preset",
+	Short: "Share an agent setup",
+	Long: `A preset bundles the agent models, the allowed tools, the project instruction
+files, the session templates and the project commands into a single file, so
+a team can share a standardized agent setup.`,
+}
+
+var presetExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the agent setup of the project to a preset file",
+	Example: `
+  # Share the setup of the project
+  opencode preset export --name backend --output backend.preset.json
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("name")
+		description, _ := cmd.Flags().GetString("description")
+		output, _ := cmd.Flags().GetString("output")
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		p, err := preset.Export(name, description)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+		if output == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(output, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", output, err)
+		}
+		fmt.Printf("Exported %d agents, %d instruction files, %d templates and %d commands to %s\n",
+			len(p.Agents), len(p.Instructions), len(p.Templates), len(p.Commands), output)
+		return nil
+	},
+}
+
+var presetImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Apply a preset file to the project",
+	Long: `Apply a preset file: the agents and the allowed tools are written to the config
+file, the instruction files, templates and commands to the project. Existing
+files are kept unless --force is set.`,
+	Example: `
+  # Apply the setup shared by the team
+  opencode preset import backend.preset.json
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", args[0], err)
+		}
+		p, err := preset.Parse(data)
+		if err != nil {
+			return err
+		}
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		result, err := preset.Import(p, force)
+		for _, name := range result.Agents {
+			fmt.Printf("Configured agent %s\n", name)
+		}
+		for _, path := range result.Written {
+			fmt.Printf("Wrote %s\n", path)
+		}
+		for _, path := range result.Skipped {
+			fmt.Printf("Kept existing %s, use --force to replace it\n", path)
+		}
+		return err
+	},
+}
+
+func init() {
+	presetExportCmd.Flags().String("name", "", "Name of the preset")
+	presetExportCmd.Flags().String("description", "", "Description of the preset")
+	presetExportCmd.Flags().StringP("output", "o", "", "File to write the preset to, stdout when empty")
+	presetImportCmd.Flags().Bool("force", false, "Replace existing files")
+	presetCmd.AddCommand(presetExportCmd, presetImportCmd)
+	rootCmd.AddCommand(presetCmd)
+}
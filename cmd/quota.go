@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/quota"
+	"github.com/spf13/cobra"
+)
+
+var quotaCmd = &cobra.Command{
+	Use:   "quota",
+	Short: "Show provider usage and quotas",
+	Long: `Display the token and cost usage of each provider API key for a month,
+together with the monthly quotas configured for the keys.
+
+Keys are identified by a fingerprint, the keys themselves are never stored.`,
+	Example: `
+  # Usage of the current month
+  opencode quota
+
+  # Usage of an earlier month as JSON
+  opencode quota --month 2026-09 --json
+  `,
+	RunE: runQuota,
+}
+
+func runQuota(cmd *cobra.Command, args []string) error {
+	month, _ := cmd.Flags().GetString("month")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current working directory: %w", err)
+	}
+	if _, err := config.Load(cwd, false); err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	conn, err := db.Connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if month == "" {
+		month = quota.CurrentMonth()
+	}
+	report, err := quota.NewService(db.New(conn)).Report(context.Background(), month)
+	if err != nil {
+		return fmt.Errorf("failed to read usage: %w", err)
+	}
+
+	if asJSON {
+		output, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal usage: %w", err)
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	if len(report) == 0 {
+		fmt.Printf("No usage recorded for %s\n", month)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "PROVIDER\tKEY\tTOKENS\tCOST\tQUOTA\tUSED\tMODE\n")
+	for _, usage := range report {
+		limit, used, mode := "-", "-", "-"
+		if usage.Quota.Enabled() {
+			limit = formatQuota(usage.Quota)
+			used = fmt.Sprintf("%.0f%%", usage.Used()*100)
+			mode = usage.Quota.Mode
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t$%.2f\t%s\t%s\t%s\n",
+			usage.Provider, usage.KeyID, usage.Tokens(), usage.Cost, limit, used, mode)
+	}
+	return w.Flush()
+}
+
+func formatQuota(q config.QuotaConfig) string {
+	switch {
+	case q.MonthlyTokens > 0 && q.MonthlyCost > 0:
+		return fmt.Sprintf("%d tokens / $%.2f", q.MonthlyTokens, q.MonthlyCost)
+	case q.MonthlyTokens > 0:
+		return fmt.Sprintf("%d tokens", q.MonthlyTokens)
+	default:
+		return fmt.Sprintf("$%.2f", q.MonthlyCost)
+	}
+}
+
+func init() {
+	quotaCmd.Flags().String("month", "", "Month to report as YYYY-MM (default: current month)")
+	quotaCmd.Flags().Bool("json", false, "Output the report as JSON")
+
+	rootCmd.AddCommand(quotaCmd)
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"os"
 	"sync"
@@ -12,13 +13,18 @@ import (
 	"github.com/kirmad/superopencode/internal/app"
 	"github.com/kirmad/superopencode/internal/config"
 	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/dryrun"
 	"github.com/kirmad/superopencode/internal/format"
 	"github.com/kirmad/superopencode/internal/llm/agent"
 	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
 	"github.com/kirmad/superopencode/internal/pubsub"
+	"github.com/kirmad/superopencode/internal/session"
+	"github.com/kirmad/superopencode/internal/settings"
 	"github.com/kirmad/superopencode/internal/tui"
 	"github.com/kirmad/superopencode/internal/version"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var rootCmd = &cobra.Command{
@@ -45,6 +51,18 @@ to assist developers in writing, debugging, and understanding code directly from
 
   # Run a single non-interactive prompt with JSON output format
   opencode -p "Explain the use of context in Go" -f json
+
+  # Run a non-interactive prompt on the output of a command
+  git diff | opencode -p "Review this diff"
+
+  # Propose changes as a diff without applying them
+  opencode -p "Add a --verbose flag" --output diff | git apply
+
+  # Run a prompt without the daemon of the project
+  opencode -p "Explain the use of context in Go" --no-daemon
+
+  # Name the tmux window after the session and show its state
+  opencode --attach-tmux
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// If the help flag is set, show the help message
@@ -65,12 +83,29 @@ to assist developers in writing, debugging, and understanding code directly from
 		quiet, _ := cmd.Flags().GetBool("quiet")
 		detailedLogs, _ := cmd.Flags().GetBool("detailed-logs")
 		dangerouslySkipPermissions, _ := cmd.Flags().GetBool("dangerously-skip-permissions")
+		noCache, _ := cmd.Flags().GetBool("no-cache")
+		post, _ := cmd.Flags().GetStringSlice("post")
+		noStdin, _ := cmd.Flags().GetBool("no-stdin")
+		ephemeral, _ := cmd.Flags().GetBool("ephemeral")
+		stdinLimit, _ := cmd.Flags().GetInt("stdin-limit")
 
 		// Validate format option
 		if !format.IsValid(outputFormat) {
 			return fmt.Errorf("invalid format option: %s\n%s", outputFormat, format.GetHelpText())
 		}
 
+		// Input piped into a non-interactive run is attached to the prompt
+		if prompt != "" && !noStdin {
+			input, err := readPipedInput(os.Stdin, stdinLimit*1024)
+			if err != nil {
+				return err
+			}
+			if input.truncated() {
+				fmt.Fprintf(os.Stderr, "Warning: stdin has %d bytes, only the first %d are attached to the prompt (see --stdin-limit)\n", input.size, len(input.content))
+			}
+			prompt = withPipedInput(prompt, input)
+		}
+
 		if cwd != "" {
 			err := os.Chdir(cwd)
 			if err != nil {
@@ -88,14 +123,47 @@ to assist developers in writing, debugging, and understanding code directly from
 		if err != nil {
 			return err
 		}
+
+		// The post-processors of the flag replace the default ones
+		if !cmd.Flag("post").Changed {
+			post = cfg.Output.Default
+		}
+		processors, err := format.Processors(post)
+		if err != nil {
+			return err
+		}
 		
-		// Override config with CLI flags if provided
+		// A run with the diff format changes a scratch worktree, the working
+		// tree is left alone and the changes are printed as a diff
+		var scratch *dryrun.Workspace
+		if f, _ := format.Parse(outputFormat); prompt != "" && f == format.Diff {
+			scratch, err = dryrun.New(context.Background(), cwd)
+			if err != nil {
+				return err
+			}
+			defer scratch.Remove()
+			config.SetWorkingDirectory(scratch.WorkingDir)
+		}
+
+		if prompt != "" && scratch == nil && daemonMayServe(cmd) {
+			if ok, err := runWithDaemon(prompt, outputFormat, quiet, processors); ok {
+				return err
+			}
+		}
+
+		// CLI flags override the settings of the config
+		initial := settings.FromConfig(cfg)
 		if cmd.Flag("detailed-logs").Changed {
-			cfg.DetailedLogs = detailedLogs
+			initial.DetailedLogs = detailedLogs
+		}
+		initial.SkipPermissions = dangerouslySkipPermissions
+		// Responses are only cached for non-interactive runs
+		if prompt == "" || noCache {
+			cfg.ResponseCache.Enabled = false
 		}
 
 		// Connect DB, this will also run migrations
-		conn, err := db.Connect()
+		conn, dbWarning, err := connectDB(ephemeral)
 		if err != nil {
 			return err
 		}
@@ -104,7 +172,7 @@ to assist developers in writing, debugging, and understanding code directly from
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		app, err := app.New(ctx, conn)
+		app, err := app.New(ctx, conn, initial)
 		if err != nil {
 			logging.Error("Failed to create app: %v", err)
 			return err
@@ -117,21 +185,39 @@ to assist developers in writing, debugging, and understanding code directly from
 
 		// Non-interactive mode
 		if prompt != "" {
+			app.DryRun = scratch
+			if dbWarning != "" {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", dbWarning)
+			}
 			// Run non-interactive flow using the App method
-			return app.RunNonInteractive(ctx, prompt, outputFormat, quiet, dangerouslySkipPermissions)
+			return app.RunNonInteractive(ctx, prompt, outputFormat, quiet, processors)
 		}
 
 		// Interactive mode
 		// Set up the TUI
 		zone.NewGlobal()
 		program := tea.NewProgram(
-			tui.New(app, dangerouslySkipPermissions),
+			tui.New(app),
 			tea.WithAltScreen(),
 		)
 
 		// Setup the subscriptions, this will send services events to the TUI
 		ch, cancelSubs := setupSubscriptions(app, ctx)
 
+		if dbWarning != "" {
+			logging.WarnPersist(dbWarning, logging.PersistTimeArg, ephemeralWarningTime)
+		}
+
+		// Reminders of the agent come back as messages while the TUI runs
+		app.DeliverReminders(ctx)
+		app.SyncKnowledge(ctx)
+
+		if attachTmux, _ := cmd.Flags().GetBool("attach-tmux"); attachTmux {
+			if err := app.AttachTmux(ctx); err != nil {
+				logging.WarnPersist(fmt.Sprintf("Not attached to tmux: %v", err))
+			}
+		}
+
 		// Create a context for the TUI message handler
 		tuiCtx, tuiCancel := context.WithCancel(ctx)
 		var tuiWg sync.WaitGroup
@@ -179,6 +265,7 @@ to assist developers in writing, debugging, and understanding code directly from
 		// Run the TUI
 		result, err := program.Run()
 		cleanup()
+		OfferCrashReport()
 
 		if err != nil {
 			logging.Error("TUI error: %v", err)
@@ -190,6 +277,43 @@ to assist developers in writing, debugging, and understanding code directly from
 	},
 }
 
+// daemonMayServe reports whether a running daemon may serve a prompt, unless
+// the flags ask for a behavior it wasn't started with. The daemon keeps its
+// sessions in the database of the data directory, ephemeral runs keep theirs
+// in memory.
+func daemonMayServe(cmd *cobra.Command) bool {
+	noDaemon, _ := cmd.Flags().GetBool("no-daemon")
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	ephemeral, _ := cmd.Flags().GetBool("ephemeral")
+	return !noDaemon && !noCache && !ephemeral && !cmd.Flag("detailed-logs").Changed
+}
+
+// ephemeralWarningTime is how long the warning about an in-memory database
+// stays in the status bar
+const ephemeralWarningTime = 30 * time.Second
+
+// connectDB connects to the database of the data directory, or to an
+// in-memory one when it's asked for or when the database can't be opened,
+// e.g. on a read-only filesystem or when the file is corrupted. It returns
+// the warning to show the user when the database is in memory.
+func connectDB(ephemeral bool) (*sql.DB, string, error) {
+	const lost = "sessions, history and usage are lost on exit"
+	if ephemeral {
+		conn, err := db.ConnectEphemeral()
+		return conn, "Using an in-memory database, " + lost, err
+	}
+	conn, err := db.Connect()
+	if err == nil {
+		return conn, "", nil
+	}
+	logging.Error("Failed to open the database, falling back to an in-memory one", "error", err)
+	conn, ephemeralErr := db.ConnectEphemeral()
+	if ephemeralErr != nil {
+		return nil, "", err
+	}
+	return conn, fmt.Sprintf("Database unavailable (%v), running in memory: %s", err, lost), nil
+}
+
 // attemptTUIRecovery tries to recover the TUI after a panic
 func attemptTUIRecovery(program *tea.Program) {
 	logging.Info("Attempting to recover TUI after panic")
@@ -213,17 +337,25 @@ func initMCPTools(ctx context.Context, app *app.App) {
 	}()
 }
 
+// subscriptionQueueSize bounds the events waiting for the TUI per
+// subscription
+const subscriptionQueueSize = 256
+
 func setupSubscriber[T any](
 	ctx context.Context,
 	wg *sync.WaitGroup,
 	name string,
 	subscriber func(context.Context) <-chan pubsub.Event[T],
+	coalesce func(pubsub.Event[T]) string,
 	outputCh chan<- tea.Msg,
 ) {
-	wg.Add(1)
+	queue := pubsub.NewQueue(subscriptionQueueSize, coalesce)
+
+	wg.Add(2)
 	go func() {
 		defer wg.Done()
 		defer logging.RecoverPanic(fmt.Sprintf("subscription-%s", name), nil)
+		defer queue.Close()
 
 		subCh := subscriber(ctx)
 
@@ -234,14 +366,8 @@ func setupSubscriber[T any](
 					logging.Info("subscription channel closed", "name", name)
 					return
 				}
-
-				var msg tea.Msg = event
-
-				select {
-				case outputCh <- msg:
-				case <-time.After(2 * time.Second):
-					logging.Warn("message dropped due to slow consumer", "name", name)
-				case <-ctx.Done():
+				// Waits while the queue is full of events that can't be coalesced
+				if err := queue.Push(ctx, event); err != nil {
 					logging.Info("subscription cancelled", "name", name)
 					return
 				}
@@ -251,6 +377,51 @@ func setupSubscriber[T any](
 			}
 		}
 	}()
+
+	go func() {
+		defer wg.Done()
+		defer logging.RecoverPanic(fmt.Sprintf("subscription-%s-output", name), nil)
+
+		for {
+			event, ok := queue.Pop(ctx)
+			if !ok {
+				return
+			}
+			var msg tea.Msg = event
+
+			select {
+			case outputCh <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// updatedMessageKey coalesces the updates of a streamed message, only its
+// latest content is rendered
+func updatedMessageKey(event pubsub.Event[message.Message]) string {
+	if event.Type != pubsub.UpdatedEvent {
+		return ""
+	}
+	return event.Payload.ID
+}
+
+// updatedSessionKey coalesces the updates of a session, e.g. its token usage
+func updatedSessionKey(event pubsub.Event[session.Session]) string {
+	if event.Type != pubsub.UpdatedEvent {
+		return ""
+	}
+	return event.Payload.ID
+}
+
+// updatedTaskProgressKey coalesces the progress of a running task, the
+// rendered activity is read from the task when the event is handled
+func updatedTaskProgressKey(event pubsub.Event[agent.TaskProgress]) string {
+	if event.Type != pubsub.UpdatedEvent {
+		return ""
+	}
+	return event.Payload.CallID
 }
 
 func setupSubscriptions(app *app.App, parentCtx context.Context) (chan tea.Msg, func()) {
@@ -259,11 +430,14 @@ func setupSubscriptions(app *app.App, parentCtx context.Context) (chan tea.Msg,
 	wg := sync.WaitGroup{}
 	ctx, cancel := context.WithCancel(parentCtx) // Inherit from parent context
 
-	setupSubscriber(ctx, &wg, "logging", logging.Subscribe, ch)
-	setupSubscriber(ctx, &wg, "sessions", app.Sessions.Subscribe, ch)
-	setupSubscriber(ctx, &wg, "messages", app.Messages.Subscribe, ch)
-	setupSubscriber(ctx, &wg, "permissions", app.Permissions.Subscribe, ch)
-	setupSubscriber(ctx, &wg, "coderAgent", app.CoderAgent.Subscribe, ch)
+	setupSubscriber(ctx, &wg, "logging", logging.Subscribe, nil, ch)
+	setupSubscriber(ctx, &wg, "sessions", app.Sessions.Subscribe, updatedSessionKey, ch)
+	setupSubscriber(ctx, &wg, "messages", app.Messages.Subscribe, updatedMessageKey, ch)
+	setupSubscriber(ctx, &wg, "permissions", app.Permissions.Subscribe, nil, ch)
+	setupSubscriber(ctx, &wg, "coderAgent", app.CoderAgent.Subscribe, nil, ch)
+	setupSubscriber(ctx, &wg, "taskProgress", agent.SubscribeTaskProgress, updatedTaskProgressKey, ch)
+	setupSubscriber(ctx, &wg, "settings", app.Settings.Subscribe, nil, ch)
+	setupSubscriber(ctx, &wg, "locks", app.Locks.Subscribe, nil, ch)
 
 	cleanupFunc := func() {
 		logging.Info("Cancelling all subscriptions")
@@ -304,7 +478,17 @@ func init() {
 
 	// Add format flag with validation logic
 	rootCmd.Flags().StringP("output-format", "f", format.Text.String(),
-		"Output format for non-interactive mode (text, json)")
+		"Output format for non-interactive mode (text, json, diff)")
+	// --output is a shorter name of --output-format
+	rootCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "output" {
+			name = "output-format"
+		}
+		return pflag.NormalizedName(name)
+	})
+
+	// Add post-processors of the output in non-interactive mode
+	rootCmd.Flags().StringSliceP("post", "P", nil, "Output post-processors to apply in order in non-interactive mode, by name (default output.default of the config)")
 
 	// Add quiet flag to hide spinner in non-interactive mode
 	rootCmd.Flags().BoolP("quiet", "q", false, "Hide spinner in non-interactive mode")
@@ -312,6 +496,22 @@ func init() {
 	// Add detailed logging flags
 	rootCmd.Flags().Bool("detailed-logs", false, "Enable detailed logging of LLM interactions")
 
+	// Add response cache override for non-interactive mode
+	rootCmd.Flags().Bool("no-cache", false, "Bypass the response cache in non-interactive mode")
+
+	// Add piped input handling for non-interactive mode
+	rootCmd.Flags().Bool("no-stdin", false, "Don't attach the input piped into a non-interactive prompt")
+	rootCmd.Flags().Int("stdin-limit", defaultStdinLimitKB, "KiB of piped input attached to a non-interactive prompt, the rest is cut off")
+
+	// Add daemon bypass for non-interactive mode
+	rootCmd.Flags().Bool("no-daemon", false, "Run the prompt in this process even when a daemon is running")
+
+	// Add tmux integration for interactive mode
+	rootCmd.Flags().Bool("attach-tmux", false, "Name the tmux window after the session and set its @opencode_status option")
+
+	// Add in-memory database
+	rootCmd.Flags().Bool("ephemeral", false, "Use an in-memory database, nothing is kept after exit")
+
 	// Add dangerous permission bypass flag
 	rootCmd.Flags().Bool("dangerously-skip-permissions", false, "⚠️ DANGEROUS: Skip all tool permission checks")
 
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useDataDirectory points the data directory of the config at dir until the
+// test ends
+func useDataDirectory(t *testing.T, dir string) {
+	t.Helper()
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	cfg := config.Get()
+	previous := cfg.Data.Directory
+	cfg.Data.Directory = dir
+	t.Cleanup(func() { cfg.Data.Directory = previous })
+}
+
+func TestConnectDB(t *testing.T) {
+	dir := t.TempDir()
+	useDataDirectory(t, dir)
+	conn, warning, err := connectDB(false)
+	require.NoError(t, err)
+	assert.Empty(t, warning)
+	assert.False(t, db.InMemory(conn))
+	require.NoError(t, conn.Close())
+	assert.FileExists(t, filepath.Join(dir, "opencode.db"))
+
+	conn, warning, err = connectDB(true)
+	require.NoError(t, err)
+	assert.Equal(t, "Using an in-memory database, sessions, history and usage are lost on exit", warning)
+	assert.True(t, db.InMemory(conn), "the snapshot journal is off")
+	require.NoError(t, conn.Close())
+}
+
+func TestDaemonMayServe(t *testing.T) {
+	// Fresh flags, the flags of the root command keep what they're set to
+	newCmd := func() *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().Bool("no-daemon", false, "")
+		cmd.Flags().Bool("no-cache", false, "")
+		cmd.Flags().Bool("ephemeral", false, "")
+		cmd.Flags().Bool("detailed-logs", false, "")
+		return cmd
+	}
+	assert.True(t, daemonMayServe(newCmd()))
+	for _, flag := range []string{"no-daemon", "no-cache", "ephemeral", "detailed-logs"} {
+		cmd := newCmd()
+		require.NoError(t, cmd.Flags().Set(flag, "true"))
+		assert.False(t, daemonMayServe(cmd), "--%s runs in the invoking process", flag)
+	}
+	cmd := newCmd()
+	require.NoError(t, cmd.Flags().Set("detailed-logs", "false"))
+	assert.False(t, daemonMayServe(cmd), "the daemon keeps its detailed logs setting")
+}
+
+func TestConnectDBFallback(t *testing.T) {
+	// The data directory can't be created over a file
+	file := filepath.Join(t.TempDir(), "data")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+	useDataDirectory(t, file)
+
+	conn, warning, err := connectDB(false)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Contains(t, warning, "Database unavailable (failed to create data directory")
+	assert.Contains(t, warning, "running in memory: sessions, history and usage are lost on exit")
+
+	// The in-memory database has the schema
+	var sessions int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&sessions))
+	assert.Zero(t, sessions)
+}
+
+func TestConnectDBCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "opencode.db"), []byte("not a database, not at all a database"), 0o644))
+	useDataDirectory(t, dir)
+
+	conn, warning, err := connectDB(false)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Contains(t, warning, "Database unavailable")
+	content, err := os.ReadFile(filepath.Join(dir, "opencode.db"))
+	require.NoError(t, err)
+	assert.Equal(t, "not a database, not at all a database", string(content), "the corrupted file is left for the user")
+}
@@ -98,6 +98,14 @@ func generateSchema() map[string]any {
 		},
 	}
 
+	schema["properties"].(map[string]any)["allowedTools"] = map[string]any{
+		"type":        "array",
+		"description": "Tools the agents may use, all when empty. Patterns like \"github_*\" match MCP tools",
+		"items": map[string]any{
+			"type": "string",
+		},
+	}
+
 	schema["properties"].(map[string]any)["tui"] = map[string]any{
 		"type":        "object",
 		"description": "Terminal User Interface configuration",
@@ -118,6 +126,11 @@ func generateSchema() map[string]any {
 					"tron",
 				},
 			},
+			"hideUsage": map[string]any{
+				"type":        "boolean",
+				"description": "Hide the tokens and cost under assistant messages",
+				"default":     false,
+			},
 		},
 	}
 
@@ -150,20 +163,69 @@ func generateSchema() map[string]any {
 				"type": map[string]any{
 					"type":        "string",
 					"description": "Type of MCP server",
-					"enum":        []string{"stdio", "sse"},
+					"enum":        []string{"stdio", "sse", "http"},
 					"default":     "stdio",
 				},
 				"url": map[string]any{
 					"type":        "string",
-					"description": "URL for SSE type MCP servers",
+					"description": "URL for SSE and HTTP type MCP servers",
 				},
 				"headers": map[string]any{
 					"type":        "object",
-					"description": "HTTP headers for SSE type MCP servers",
+					"description": "HTTP headers for SSE and HTTP type MCP servers",
 					"additionalProperties": map[string]any{
 						"type": "string",
 					},
 				},
+				"oauth": map[string]any{
+					"type":        "object",
+					"description": "OAuth for SSE and HTTP type MCP servers, with authUrl the user logs in with opencode mcp login, without it the client credentials grant is used",
+					"properties": map[string]any{
+						"clientId": map[string]any{
+							"type":        "string",
+							"description": "OAuth client ID",
+						},
+						"clientSecret": map[string]any{
+							"type":        "string",
+							"description": "OAuth client secret",
+						},
+						"authUrl": map[string]any{
+							"type":        "string",
+							"description": "Authorization endpoint for the browser login",
+						},
+						"tokenUrl": map[string]any{
+							"type":        "string",
+							"description": "Token endpoint",
+						},
+						"scopes": map[string]any{
+							"type":        "array",
+							"description": "Scopes to request",
+							"items": map[string]any{
+								"type": "string",
+							},
+						},
+						"redirectPort": map[string]any{
+							"type":        "integer",
+							"description": "Local port of the login redirect",
+							"default":     19876,
+						},
+					},
+					"required": []string{"clientId", "tokenUrl"},
+				},
+				"allowedTools": map[string]any{
+					"type":        "array",
+					"description": "Glob patterns of the server tools to expose, all when empty",
+					"items": map[string]any{
+						"type": "string",
+					},
+				},
+				"deniedTools": map[string]any{
+					"type":        "array",
+					"description": "Glob patterns of the server tools to hide, they win over allowedTools",
+					"items": map[string]any{
+						"type": "string",
+					},
+				},
 			},
 			"required": []string{"command"},
 		},
@@ -186,6 +248,28 @@ func generateSchema() map[string]any {
 					"description": "Whether the provider is disabled",
 					"default":     false,
 				},
+				"quota": map[string]any{
+					"type":        "object",
+					"description": "Monthly usage quota of the provider API key",
+					"properties": map[string]any{
+						"monthlyTokens": map[string]any{
+							"type":        "integer",
+							"description": "Maximum number of tokens per month, 0 for no limit",
+							"minimum":     0,
+						},
+						"monthlyCost": map[string]any{
+							"type":        "number",
+							"description": "Maximum cost in USD per month, 0 for no limit",
+							"minimum":     0,
+						},
+						"mode": map[string]any{
+							"type":        "string",
+							"description": "Whether to warn or to stop new requests once the quota is used up",
+							"enum":        []string{"warn", "stop"},
+							"default":     "warn",
+						},
+					},
+				},
 			},
 		},
 	}
@@ -250,6 +334,9 @@ func generateSchema() map[string]any {
 		string(config.AgentCoder),
 		string(config.AgentTask),
 		string(config.AgentTitle),
+		string(config.AgentReviewer),
+		string(config.AgentSecurity),
+		string(config.AgentMigrator),
 	}
 
 	for _, agentName := range knownAgents {
@@ -304,5 +391,807 @@ func generateSchema() map[string]any {
 		},
 	}
 
+	// Add response cache
+	schema["properties"].(map[string]any)["responseCache"] = map[string]any{
+		"type":        "object",
+		"description": "Response cache used by non-interactive runs",
+		"properties": map[string]any{
+			"enabled": map[string]any{
+				"type":        "boolean",
+				"description": "Whether responses are cached",
+				"default":     false,
+			},
+			"ttlHours": map[string]any{
+				"type":        "integer",
+				"description": "Hours a cached response is kept, until the cache is cleared when 0",
+				"default":     168,
+				"minimum":     0,
+			},
+		},
+	}
+
+	// Add speculative drafting
+	schema["properties"].(map[string]any)["speculative"] = map[string]any{
+		"type":        "object",
+		"description": "Two-tier generation for the coder agent: a draft model generates every step and the coder model verifies risky actions",
+		"properties": map[string]any{
+			"enabled": map[string]any{
+				"type":        "boolean",
+				"description": "Whether speculative drafting is enabled",
+				"default":     false,
+			},
+			"draftModel": map[string]any{
+				"type":        "string",
+				"description": "Model ID generating the drafts",
+			},
+			"rules": map[string]any{
+				"type":        "object",
+				"description": "Action of the drafted calls by tool name, \"*\" for any tool",
+				"additionalProperties": map[string]any{
+					"type": "string",
+					"enum": []string{"draft", "verify"},
+				},
+				"default": map[string]any{
+					"*":     "draft",
+					"bash":  "verify",
+					"edit":  "verify",
+					"write": "verify",
+					"patch": "verify",
+				},
+			},
+		},
+	}
+
+	// Add self-review
+	schema["properties"].(map[string]any)["selfReview"] = map[string]any{
+		"type":        "object",
+		"description": "Verification pass run after the coder agent changed files during a turn",
+		"properties": map[string]any{
+			"enabled": map[string]any{
+				"type":        "boolean",
+				"description": "Whether the self-review pass runs",
+				"default":     false,
+			},
+			"checks": map[string]any{
+				"type":        "array",
+				"description": "Shell commands that must succeed, e.g. \"go build ./...\"",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+			"maxFixAttempts": map[string]any{
+				"type":        "integer",
+				"description": "Fix rounds allowed when checks fail",
+				"default":     2,
+				"minimum":     0,
+			},
+			"timeoutSeconds": map[string]any{
+				"type":        "integer",
+				"description": "Timeout of each check",
+				"default":     300,
+				"minimum":     1,
+			},
+		},
+	}
+
+	// Add model router
+	schema["properties"].(map[string]any)["router"] = map[string]any{
+		"type":        "object",
+		"description": "Picks the coder model of each request by its complexity",
+		"properties": map[string]any{
+			"enabled": map[string]any{
+				"type":        "boolean",
+				"description": "Whether requests are routed",
+				"default":     false,
+			},
+			"tiers": map[string]any{
+				"type":        "object",
+				"description": "Model ID by tier, the coder model for missing tiers",
+				"properties": map[string]any{
+					"trivial": map[string]any{
+						"type":        "string",
+						"description": "Model for short questions without code changes",
+					},
+					"standard": map[string]any{
+						"type":        "string",
+						"description": "Model for the other requests",
+					},
+					"complex": map[string]any{
+						"type":        "string",
+						"description": "Model for changes spanning several files",
+					},
+				},
+				"additionalProperties": false,
+			},
+			"trivialMaxChars": map[string]any{
+				"type":        "integer",
+				"description": "Longer requests are never trivial",
+				"default":     200,
+				"minimum":     0,
+			},
+			"complexMinFiles": map[string]any{
+				"type":        "integer",
+				"description": "File references that make a request complex",
+				"default":     3,
+				"minimum":     1,
+			},
+			"complexKeywords": map[string]any{
+				"type":        "array",
+				"description": "Words that make a request complex",
+				"items": map[string]any{
+					"type": "string",
+				},
+				"default": []string{"refactor", "migrate", "rewrite", "rename", "redesign", "across", "every file", "whole codebase"},
+			},
+		},
+	}
+
+	// Add log configuration
+	logLevels := []string{"debug", "info", "warn", "error"}
+	schema["properties"].(map[string]any)["log"] = map[string]any{
+		"type":        "object",
+		"description": "Log levels and the structured log file",
+		"properties": map[string]any{
+			"level": map[string]any{
+				"type":        "string",
+				"description": "Default log level",
+				"default":     "info",
+				"enum":        logLevels,
+			},
+			"modules": map[string]any{
+				"type":        "object",
+				"description": "Log level by module, e.g. \"llm/agent\": \"debug\"",
+				"additionalProperties": map[string]any{
+					"type": "string",
+					"enum": logLevels,
+				},
+			},
+			"file": map[string]any{
+				"type":        "boolean",
+				"description": "Write JSON logs to logs/opencode.log in the data directory",
+				"default":     false,
+			},
+			"maxSizeMB": map[string]any{
+				"type":        "integer",
+				"description": "Size after which the log file is rotated",
+				"default":     10,
+				"minimum":     1,
+			},
+			"maxFiles": map[string]any{
+				"type":        "integer",
+				"description": "Rotated log files to keep",
+				"default":     5,
+				"minimum":     0,
+			},
+		},
+	}
+
+	// Add docs generation
+	schema["properties"].(map[string]any)["docs"] = map[string]any{
+		"type":        "object",
+		"description": "Where the generate_docs tool writes Markdown docs and which files and symbols it leaves alone",
+		"properties": map[string]any{
+			"dir": map[string]any{
+				"type":        "string",
+				"description": "Directory of the Markdown docs, relative to the working directory",
+				"default":     "docs",
+			},
+			"exclude": map[string]any{
+				"type":        "array",
+				"description": "Glob patterns of file paths or symbol names, e.g. \"internal/db/*\" or \"Test*\"",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+		},
+	}
+
+	// Add test generation
+	schema["properties"].(map[string]any)["genTests"] = map[string]any{
+		"type":        "object",
+		"description": "When the /gen-tests workflow stops generating tests",
+		"properties": map[string]any{
+			"targetDelta": map[string]any{
+				"type":        "number",
+				"description": "Coverage gain to reach, in percentage points",
+				"default":     10,
+				"minimum":     0,
+			},
+			"maxAttempts": map[string]any{
+				"type":        "integer",
+				"description": "Rounds of test generation at most",
+				"default":     3,
+				"minimum":     1,
+			},
+		},
+	}
+
+	// Add dependency audit
+	schema["properties"].(map[string]any)["dependencyAudit"] = map[string]any{
+		"type":        "object",
+		"description": "Licenses the dependency_audit tool accepts",
+		"properties": map[string]any{
+			"allowedLicenses": map[string]any{
+				"type":        "array",
+				"description": "SPDX identifiers, e.g. \"MIT\", no license check when empty",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+		},
+	}
+
+	// Add migrations
+	schema["properties"].(map[string]any)["migration"] = map[string]any{
+		"type":        "object",
+		"description": "How the /migrate workflow splits and checks the files of a migration",
+		"properties": map[string]any{
+			"batchSize": map[string]any{
+				"type":        "integer",
+				"description": "Files per subagent",
+				"default":     10,
+				"minimum":     1,
+			},
+			"parallel": map[string]any{
+				"type":        "integer",
+				"description": "Batches migrated at the same time",
+				"default":     3,
+				"minimum":     1,
+			},
+			"verifyCommand": map[string]any{
+				"type":        "string",
+				"description": "Command that must pass in the worktree of each batch, e.g. \"go build ./...\"",
+			},
+			"maxFixAttempts": map[string]any{
+				"type":        "integer",
+				"description": "Rounds to fix a failing verify command",
+				"default":     2,
+				"minimum":     0,
+			},
+		},
+	}
+
+	// Add session branches
+	schema["properties"].(map[string]any)["sessionBranches"] = map[string]any{
+		"type":        "object",
+		"description": "Whether each session works on its own git branch",
+		"properties": map[string]any{
+			"enabled": map[string]any{
+				"type":        "boolean",
+				"description": "Whether sessions get their own branch",
+				"default":     false,
+			},
+			"prefix": map[string]any{
+				"type":        "string",
+				"description": "Branch name before the session ID",
+				"default":     "opencode/session-",
+			},
+		},
+	}
+
+	// Add file detection
+	schema["properties"].(map[string]any)["fileDetection"] = map[string]any{
+		"type":        "object",
+		"description": "Files the file tools don't read, search and edit like source files",
+		"properties": map[string]any{
+			"lockfiles": map[string]any{
+				"type":        "array",
+				"description": "Glob patterns of lockfile paths or names, e.g. \"package-lock.json\"",
+				"items": map[string]any{
+					"type": "string",
+				},
+				"default": []string{
+					"package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "bun.lockb",
+					"go.sum", "Cargo.lock", "poetry.lock", "Pipfile.lock", "uv.lock", "Gemfile.lock",
+					"composer.lock", "mix.lock", "pubspec.lock", "packages.lock.json", "flake.lock",
+				},
+			},
+			"generated": map[string]any{
+				"type":        "array",
+				"description": "Glob patterns of generated files, e.g. \"*.pb.go\"",
+				"items": map[string]any{
+					"type": "string",
+				},
+				"default": []string{
+					"*.pb.go", "*_pb2.py", "*.pb.cc", "*.pb.h", "*.min.js", "*.min.css", "*.map", "*_generated.go", "*.gen.go",
+				},
+			},
+			"minifiedLineLength": map[string]any{
+				"type":        "integer",
+				"description": "Average line length above which a file counts as minified",
+				"default":     1000,
+				"minimum":     1,
+			},
+		},
+	}
+
+	// Add shell configuration
+	schema["properties"].(map[string]any)["shell"] = map[string]any{
+		"type":        "object",
+		"description": "Shell used by the bash tool",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Path of the shell, $SHELL or /bin/bash when empty",
+			},
+			"args": map[string]any{
+				"type":        "array",
+				"description": "Arguments of the shell",
+				"items": map[string]any{
+					"type": "string",
+				},
+				"default": []string{"-l"},
+			},
+			"env": map[string]any{
+				"type":        "array",
+				"description": "KEY=VALUE variables injected into the bash commands, the value may reference environment variables like ${NPM_TOKEN}",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+		},
+	}
+
+	// Add idle resources
+	schema["properties"].(map[string]any)["idle"] = map[string]any{
+		"type":        "object",
+		"description": "When memory held for idle sessions and LSP servers is released",
+		"properties": map[string]any{
+			"releaseSessionsMinutes": map[string]any{
+				"type":        "integer",
+				"description": "Idle time after which the pinned context of background sessions moves to disk, never when 0",
+				"default":     30,
+				"minimum":     0,
+			},
+			"suspendLSPMinutes": map[string]any{
+				"type":        "integer",
+				"description": "Idle time after which the LSP servers stop until the next interaction, never when 0",
+				"default":     15,
+				"minimum":     0,
+			},
+		},
+	}
+
+	// Add HTTP client
+	schema["properties"].(map[string]any)["http"] = map[string]any{
+		"type":        "object",
+		"description": "Connections to the providers, a timeout of 0 disables it",
+		"properties": map[string]any{
+			"connectTimeoutSeconds": map[string]any{
+				"type":        "integer",
+				"description": "Timeout of dialing and the TLS handshake",
+				"default":     10,
+				"minimum":     0,
+			},
+			"firstByteTimeoutSeconds": map[string]any{
+				"type":        "integer",
+				"description": "Timeout from sending the request to the response headers",
+				"default":     0,
+				"minimum":     0,
+			},
+			"totalTimeoutSeconds": map[string]any{
+				"type":        "integer",
+				"description": "Timeout of the whole request, streamed responses included",
+				"default":     0,
+				"minimum":     0,
+			},
+			"maxIdleConnsPerHost": map[string]any{
+				"type":        "integer",
+				"description": "Kept alive connections per provider host",
+				"default":     16,
+				"minimum":     0,
+			},
+			"idleConnTimeoutSeconds": map[string]any{
+				"type":        "integer",
+				"description": "How long an unused connection is kept alive",
+				"default":     90,
+				"minimum":     0,
+			},
+			"compressRequests": map[string]any{
+				"type":        "boolean",
+				"description": "Gzip request bodies, for endpoints that accept them",
+				"default":     false,
+			},
+		},
+	}
+
+	// Add latency
+	schema["properties"].(map[string]any)["latency"] = map[string]any{
+		"type":        "object",
+		"description": "How the time to the first token of a turn is kept low",
+		"properties": map[string]any{
+			"warmup": map[string]any{
+				"type":        "boolean",
+				"description": "Open a connection to the provider when a session opens or the model changes",
+				"default":     false,
+			},
+			"firstTokenTargetMs": map[string]any{
+				"type":        "integer",
+				"description": "Turns slower to their first token are flagged, never when 0",
+				"default":     2000,
+				"minimum":     0,
+			},
+		},
+	}
+
+	// Add usage stats
+	schema["properties"].(map[string]any)["analytics"] = map[string]any{
+		"type":        "object",
+		"description": "Anonymous usage stats, kept in the local database and sent only when an endpoint and categories to share are set",
+		"properties": map[string]any{
+			"disabled": map[string]any{
+				"type":        "boolean",
+				"description": "Don't record usage stats",
+				"default":     false,
+			},
+			"endpoint": map[string]any{
+				"type":        "string",
+				"description": "URL the daily reports are posted to",
+			},
+			"share": map[string]any{
+				"type":        "array",
+				"description": "Categories sent to the endpoint",
+				"items": map[string]any{
+					"type": "string",
+					"enum": []string{"sessions", "turns", "tools"},
+				},
+			},
+		},
+	}
+
+	// Add task sessions
+	schema["properties"].(map[string]any)["taskSessions"] = map[string]any{
+		"type":        "object",
+		"description": "How long the sessions created by tasks, reviews and title generation are kept",
+		"properties": map[string]any{
+			"retentionDays": map[string]any{
+				"type":        "integer",
+				"description": "Days since their last update after which they're collected, never when 0",
+				"default":     0,
+				"minimum":     0,
+			},
+			"action": map[string]any{
+				"type":        "string",
+				"description": "What happens to them past their retention: archive drops their messages and file versions, delete removes them",
+				"default":     "delete",
+				"enum":        []string{"archive", "delete"},
+			},
+			"showInPicker": map[string]any{
+				"type":        "boolean",
+				"description": "List them in the session picker",
+				"default":     false,
+			},
+		},
+	}
+
+	// Add tool output summaries
+	schema["properties"].(map[string]any)["toolOutputSummary"] = map[string]any{
+		"type":        "object",
+		"description": "When a cheap model summarizes long tool outputs, the full output is kept in a file the summary points to",
+		"properties": map[string]any{
+			"enabled": map[string]any{
+				"type":        "boolean",
+				"description": "Whether long tool outputs are summarized",
+				"default":     false,
+			},
+			"model": map[string]any{
+				"type":        "string",
+				"description": "Model ID writing the summaries, the title model when empty",
+			},
+			"thresholdTokens": map[string]any{
+				"type":        "integer",
+				"description": "Outputs longer than this are summarized",
+				"default":     8000,
+				"minimum":     1,
+			},
+			"tools": map[string]any{
+				"type":        "array",
+				"description": "Tools whose outputs are summarized, \"*\" for all",
+				"items": map[string]any{
+					"type": "string",
+				},
+				"default": []string{"bash", "fetch", "sourcegraph", "flaky_test", "profile", "dependency_audit"},
+			},
+		},
+	}
+
+	// Add output post-processors
+	schema["properties"].(map[string]any)["output"] = map[string]any{
+		"type":        "object",
+		"description": "Post-processors of non-interactive runs",
+		"properties": map[string]any{
+			"processors": map[string]any{
+				"type":        "object",
+				"description": "Post-processors by name, each sets exactly one of its fields",
+				"additionalProperties": map[string]any{
+					"type":        "object",
+					"description": "Post-processor configuration",
+					"properties": map[string]any{
+						"jq": map[string]any{
+							"type":        "string",
+							"description": "jq filter run on the result as JSON, strings are printed raw",
+						},
+						"template": map[string]any{
+							"type":        "string",
+							"description": "Go template executed with the result",
+						},
+						"command": map[string]any{
+							"type":        "string",
+							"description": "Shell command reading the output on stdin and printing the new one",
+						},
+					},
+				},
+			},
+			"default": map[string]any{
+				"type":        "array",
+				"description": "Processors applied in order when --post isn't given",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+		},
+	}
+
+	// Add tool budgets
+	schema["properties"].(map[string]any)["toolBudgets"] = map[string]any{
+		"type":        "object",
+		"description": "Time and failure limits by tool name, \"*\" for any other tool. A tool whose recent runs failed too often is paused for a while",
+		"additionalProperties": map[string]any{
+			"type":        "object",
+			"description": "Tool budget, the limits it doesn't set come from the \"*\" budget",
+			"properties": map[string]any{
+				"timeoutSeconds": map[string]any{
+					"type":        "integer",
+					"description": "Time after which a run is aborted, no limit when 0",
+					"minimum":     0,
+				},
+				"maxFailures": map[string]any{
+					"type":        "integer",
+					"description": "Failed runs within the window that pause the tool, never when 0",
+					"minimum":     0,
+				},
+				"failureWindow": map[string]any{
+					"type":        "integer",
+					"description": "Latest runs of the tool in a session counted for maxFailures",
+					"minimum":     0,
+				},
+				"cooldownSeconds": map[string]any{
+					"type":        "integer",
+					"description": "Pause after which the tool may run again",
+					"minimum":     0,
+				},
+			},
+		},
+		"default": map[string]any{
+			"*":    map[string]any{"maxFailures": 5, "failureWindow": 10, "cooldownSeconds": 120},
+			"bash": map[string]any{"timeoutSeconds": 600},
+			"grep": map[string]any{"timeoutSeconds": 30},
+			"glob": map[string]any{"timeoutSeconds": 30},
+		},
+	}
+
+	// Add large prompts
+	schema["properties"].(map[string]any)["largePrompts"] = map[string]any{
+		"type":        "object",
+		"description": "When a prompt too large for a turn is saved in chunks the agent pages through with the read_prompt tool",
+		"properties": map[string]any{
+			"enabled": map[string]any{
+				"type":        "boolean",
+				"description": "Whether large prompts are chunked",
+				"default":     true,
+			},
+			"thresholdTokens": map[string]any{
+				"type":        "integer",
+				"description": "Prompts longer than this are chunked, half the input budget of the model when 0",
+				"default":     0,
+				"minimum":     0,
+			},
+			"chunkTokens": map[string]any{
+				"type":        "integer",
+				"description": "Size of a chunk",
+				"default":     4000,
+				"minimum":     1,
+			},
+		},
+	}
+
+	// Add locale
+	schema["properties"].(map[string]any)["locale"] = map[string]any{
+		"type":        "object",
+		"description": "How dates, times and numbers are written and the language the agents answer in, the environment's locale and timezone when empty",
+		"properties": map[string]any{
+			"format": map[string]any{
+				"type":        "string",
+				"description": "Locale of the formats, e.g. \"de-DE\", or \"iso\"",
+			},
+			"timezone": map[string]any{
+				"type":        "string",
+				"description": "IANA timezone, e.g. \"Europe/Berlin\"",
+			},
+			"language": map[string]any{
+				"type":        "string",
+				"description": "Response language, e.g. \"German\" or \"de\", the language of the user's messages when empty",
+			},
+		},
+	}
+
+	// Add embeddings
+	schema["properties"].(map[string]any)["embedding"] = map[string]any{
+		"type":        "object",
+		"description": "Provider of the embeddings shared by the features ranking text by meaning, disabled when the provider is empty",
+		"properties": map[string]any{
+			"provider": map[string]any{
+				"type":        "string",
+				"description": "Embedding provider, gguf is a GGUF model served by the llama.cpp server",
+				"enum":        []string{"openai", "voyage", "ollama", "gguf"},
+			},
+			"model": map[string]any{
+				"type":        "string",
+				"description": "Model of the provider, its default one when empty",
+			},
+			"baseURL": map[string]any{
+				"type":        "string",
+				"description": "Endpoint of the provider, e.g. of a local server",
+			},
+			"apiKey": map[string]any{
+				"type":        "string",
+				"description": "Key of the provider, the one of the OpenAI provider or $VOYAGE_API_KEY when empty",
+			},
+			"batchSize": map[string]any{
+				"type":        "integer",
+				"description": "Texts embedded per request",
+				"default":     64,
+				"minimum":     1,
+			},
+		},
+	}
+
+	// Add vector store
+	schema["properties"].(map[string]any)["vectorStore"] = map[string]any{
+		"type":        "object",
+		"description": "Where embeddings are stored and searched",
+		"properties": map[string]any{
+			"backend": map[string]any{
+				"type":        "string",
+				"description": "Vector store backend, SQLite suits most projects, Qdrant and pgvector scale to large monorepos",
+				"default":     "sqlite",
+				"enum":        []string{"sqlite", "qdrant", "pgvector"},
+			},
+			"url": map[string]any{
+				"type":        "string",
+				"description": "Endpoint of Qdrant, or connection string of PostgreSQL",
+			},
+			"apiKey": map[string]any{
+				"type":        "string",
+				"description": "Key of Qdrant, $QDRANT_API_KEY when empty",
+			},
+			"prefix": map[string]any{
+				"type":        "string",
+				"description": "Prefix of the collections or tables, to share a server between projects",
+				"default":     "opencode_",
+			},
+		},
+	}
+
+	// Add knowledge sources
+	schema["properties"].(map[string]any)["knowledge"] = map[string]any{
+		"type":        "object",
+		"description": "External documentation indexed for the research subagent's knowledge_search tool",
+		"properties": map[string]any{
+			"sources": map[string]any{
+				"type":        "object",
+				"description": "Knowledge sources by name",
+				"additionalProperties": map[string]any{
+					"type":        "object",
+					"description": "Knowledge source configuration",
+					"properties": map[string]any{
+						"type": map[string]any{
+							"type":        "string",
+							"description": "Type of the source",
+							"enum":        []string{"confluence", "notion", "site"},
+						},
+						"url": map[string]any{
+							"type":        "string",
+							"description": "Base URL of Confluence, or the start page of a docs site",
+						},
+						"space": map[string]any{
+							"type":        "string",
+							"description": "Key of the Confluence space",
+						},
+						"path": map[string]any{
+							"type":        "string",
+							"description": "Directory of a Notion export in Markdown",
+						},
+						"username": map[string]any{
+							"type":        "string",
+							"description": "Confluence Cloud user the token belongs to, a personal access token is used alone when empty",
+						},
+						"token": map[string]any{
+							"type":        "string",
+							"description": "Confluence API token, $CONFLUENCE_TOKEN when empty",
+						},
+						"maxPages": map[string]any{
+							"type":        "integer",
+							"description": "Pages crawled from a docs site",
+							"minimum":     1,
+						},
+					},
+					"required": []string{"type"},
+				},
+			},
+			"refreshHours": map[string]any{
+				"type":        "integer",
+				"description": "Age of the index of a source before it's synced again, only by hand when 0",
+				"default":     24,
+				"minimum":     0,
+			},
+		},
+	}
+
+	// Add error patterns
+	schema["properties"].(map[string]any)["errorPatterns"] = map[string]any{
+		"type":        "object",
+		"description": "Learning of the tool and LLM errors that recur across sessions, suggesting what fixed them before",
+		"properties": map[string]any{
+			"enabled": map[string]any{
+				"type":        "boolean",
+				"description": "Whether recurring errors are learned",
+				"default":     false,
+			},
+			"minOccurrences": map[string]any{
+				"type":        "integer",
+				"description": "Occurrences of an error before suggestions are made",
+				"default":     3,
+				"minimum":     1,
+			},
+		},
+	}
+
+	// Add tool pruning
+	schema["properties"].(map[string]any)["toolPruning"] = map[string]any{
+		"type":        "object",
+		"description": "Trims the tools a model rarely uses successfully from the tools sent to it, by the tool calls recorded in the usage stats",
+		"properties": map[string]any{
+			"enabled": map[string]any{
+				"type":        "boolean",
+				"description": "Whether tools are pruned",
+				"default":     false,
+			},
+			"models": map[string]any{
+				"type":        "array",
+				"description": "Model IDs whose tools are pruned, usually the weaker ones",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+			"minCalls": map[string]any{
+				"type":        "integer",
+				"description": "Tool calls recorded for a model before its tools are pruned",
+				"default":     50,
+				"minimum":     0,
+			},
+			"minShare": map[string]any{
+				"type":        "number",
+				"description": "Share of the successful calls of the model a tool needs to be kept",
+				"default":     0.01,
+				"minimum":     0,
+			},
+			"days": map[string]any{
+				"type":        "integer",
+				"description": "Days of usage stats considered",
+				"default":     30,
+				"minimum":     1,
+			},
+			"alwaysInclude": map[string]any{
+				"type":        "array",
+				"description": "Tools never pruned",
+				"items": map[string]any{
+					"type": "string",
+				},
+				"default": []string{"view", "edit", "write", "bash"},
+			},
+		},
+	}
+
 	return schema
 }
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/locale"
+	"github.com/kirmad/superopencode/internal/session"
+	"github.com/kirmad/superopencode/internal/sessionexport"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Search, export and import sessions",
+}
+
+var sessionsSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search the messages of the sessions",
+	Long: `Find the sessions whose messages match a query, the best match first, with
+a snippet of the best matching message.
+
+The text of the messages and the input of their tool calls are searched, not
+the results of the tools. All words of the query must appear in a message,
+the last one matches as a prefix, words in double quotes match as a phrase,
+and words match their other forms, e.g. "retry" matches "retrying".`,
+	Example: `
+  # Sessions that talked about retries
+  opencode sessions search retry policy
+
+  # A phrase, task sessions included
+  opencode sessions search '"rate limit"' --tasks
+  `,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, _ := cmd.Flags().GetInt("limit")
+		tasks, _ := cmd.Flags().GetBool("tasks")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		query := strings.Join(args, " ")
+		results, err := session.NewService(db.New(conn)).Search(context.Background(), query, limit, tasks)
+		if err != nil {
+			return fmt.Errorf("failed to search the sessions: %w", err)
+		}
+		if asJSON {
+			output, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal the results: %w", err)
+			}
+			fmt.Println(string(output))
+			return nil
+		}
+		if len(results) == 0 {
+			fmt.Printf("No session matches %q\n", query)
+			return nil
+		}
+
+		start, end := "**", "**"
+		if isatty.IsTerminal(os.Stdout.Fd()) {
+			start, end = "\x1b[1m", "\x1b[0m"
+		}
+		for i, result := range results {
+			matches := "1 match"
+			if result.Matches > 1 {
+				matches = fmt.Sprintf("%d matches", result.Matches)
+			}
+			fmt.Printf("%d. %s\n   %s, %s, %s\n   %s\n\n", i+1, result.Session.Title, result.Session.ID,
+				locale.DateTime(time.Unix(result.Session.UpdatedAt, 0)), matches,
+				strings.NewReplacer(session.MatchStart, start, session.MatchEnd, end).Replace(result.Snippet))
+		}
+		return nil
+	},
+}
+
+var sessionsExportCmd = &cobra.Command{
+	Use:   "export [session]",
+	Short: "Export a session with its messages and task sessions",
+	Long: `Write a session to a portable file: its messages with their tool calls and
+results, its tokens and cost, and the sessions of the tasks it ran.
+
+The json format is an archive that "opencode sessions import" loads back, on
+this machine or another one. The md format renders the conversation as
+markdown to read or share it, it can't be imported. The latest session is
+exported by default.`,
+	Example: `
+  # Archive the latest session
+  opencode sessions export -o session.json
+
+  # Share a session as markdown
+  opencode sessions export 3f2a9c --format md -o session.md
+  `,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		if format != "json" && format != "md" {
+			return fmt.Errorf("unknown format %q, use json or md", format)
+		}
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		q := db.New(conn)
+
+		ctx := context.Background()
+		sessionID := ""
+		if len(args) > 0 {
+			sessionID = args[0]
+		} else {
+			all, err := session.NewService(q).List(ctx)
+			if err != nil {
+				return err
+			}
+			if len(all) == 0 {
+				return errors.New("no sessions yet")
+			}
+			sessionID = all[0].ID
+		}
+		archive, err := sessionexport.Export(ctx, q, sessionID)
+		if err != nil {
+			return err
+		}
+
+		var data []byte
+		if format == "md" {
+			markdown, err := archive.Markdown()
+			if err != nil {
+				return err
+			}
+			data = []byte(markdown)
+		} else if data, err = json.MarshalIndent(archive, "", "  "); err != nil {
+			return fmt.Errorf("failed to marshal the session: %w", err)
+		}
+		if output == "" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(output, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write the session: %w", err)
+		}
+		fmt.Printf("Exported %q with %d task sessions to %s\n", archive.Sessions[0].Title, len(archive.Sessions)-1, output)
+		return nil
+	},
+}
+
+var sessionsImportCmd = &cobra.Command{
+	Use:   "import <file>...",
+	Short: "Import sessions exported with \"opencode sessions export\"",
+	Long: `Load sessions exported as json back into the database, with their
+messages and task sessions. The sessions keep their IDs, a session that
+exists already is not imported again.
+
+To import conversations of other coding agents, use "opencode import".`,
+	Example: `
+  # Import an archived session
+  opencode sessions import session.json
+  `,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		ctx := context.Background()
+		for _, path := range args {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			archive, err := sessionexport.Read(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			if err := sessionexport.Import(ctx, conn, archive); err != nil {
+				return fmt.Errorf("failed to import %s: %w", path, err)
+			}
+			root := archive.Sessions[0]
+			fmt.Printf("Imported %q (%d messages, %d task sessions) from %s\n", root.Title, len(root.Messages), len(archive.Sessions)-1, path)
+		}
+		return nil
+	},
+}
+
+func init() {
+	sessionsExportCmd.Flags().String("format", "json", "Export format: json or md")
+	sessionsExportCmd.Flags().StringP("output", "o", "", "File to write, stdout when empty")
+
+	sessionsSearchCmd.Flags().Int("limit", 20, "Number of sessions to list, 0 for all")
+	sessionsSearchCmd.Flags().Bool("tasks", false, "Search the task sessions too")
+	sessionsSearchCmd.Flags().Bool("json", false, "Output the results as JSON")
+
+	sessionsCmd.AddCommand(sessionsSearchCmd, sessionsExportCmd, sessionsImportCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/kirmad/superopencode/internal/analytics"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/spf13/cobra"
+)
+
+// statsTopTools is how many tools the stats list
+const statsTopTools = 10
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show your usage patterns",
+	Long: `Display the anonymous usage stats recorded on this machine: the sessions
+started per day, the success rate of the turns per model and the most used
+tools with their success rate.
+
+With --model, list the tools a model called and their success rate instead,
+the tools toolPruning trims from its requests are picked by them.
+
+The stats are kept in the local database. Nothing is sent anywhere unless
+analytics.endpoint and analytics.share are configured.`,
+	Example: `
+  # Usage of the last 30 days
+  opencode stats
+
+  # Usage of the last week as JSON
+  opencode stats --days 7 --json
+
+  # Tools called by a model
+  opencode stats --model gpt-4.1-mini
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		days, _ := cmd.Flags().GetInt("days")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		model, _ := cmd.Flags().GetString("model")
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		service := analytics.NewService(db.New(conn))
+		if model != "" {
+			return printModelTools(service, model, days, asJSON)
+		}
+		stats, err := service.Stats(context.Background(), days)
+		if err != nil {
+			return fmt.Errorf("failed to read usage stats: %w", err)
+		}
+		if asJSON {
+			output, err := json.MarshalIndent(stats, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal usage stats: %w", err)
+			}
+			fmt.Println(string(output))
+			return nil
+		}
+		return printStats(stats)
+	},
+}
+
+func printStats(stats analytics.Stats) error {
+	if len(stats.Daily) == 0 {
+		if config.Get().Analytics.Disabled {
+			fmt.Println("Usage stats are disabled by analytics.disabled")
+			return nil
+		}
+		fmt.Printf("No usage recorded in the last %d days\n", stats.Days)
+		return nil
+	}
+
+	fmt.Printf("Last %d days: %d sessions, %.1f per day, %.0f%% of the turns succeeded\n\n",
+		stats.Days, stats.Sessions, stats.SessionsPerDay(), stats.TurnSuccessRate()*100)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "DAY\tSESSIONS\tTURNS\tTOOL CALLS\n")
+	for _, day := range stats.Daily {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", day.Date, day.Sessions, day.Turns, day.ToolCalls)
+	}
+	printUsages(w, "MODEL", "TURNS", stats.Turns)
+	printUsages(w, "TOOL", "CALLS", stats.Tools[:min(len(stats.Tools), statsTopTools)])
+	return w.Flush()
+}
+
+func printModelTools(service analytics.Service, model string, days int, asJSON bool) error {
+	usage, err := service.ToolUsageByModel(context.Background(), days)
+	if err != nil {
+		return fmt.Errorf("failed to read usage stats: %w", err)
+	}
+	tools := usage[model]
+	if asJSON {
+		output, err := json.MarshalIndent(tools, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal usage stats: %w", err)
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+	if len(tools) == 0 {
+		fmt.Printf("No tool calls of %s recorded in the last %d days\n", model, days)
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	printUsages(w, "TOOL", "CALLS", tools)
+	return w.Flush()
+}
+
+func printUsages(w *tabwriter.Writer, name, count string, usages []analytics.Usage) {
+	if len(usages) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\t%s\tSUCCESS\n", name, count)
+	for _, usage := range usages {
+		fmt.Fprintf(w, "%s\t%d\t%.0f%%\n", usage.Name, usage.Count, usage.SuccessRate()*100)
+	}
+}
+
+func init() {
+	statsCmd.Flags().Int("days", 30, "Number of days to report, including today")
+	statsCmd.Flags().Bool("json", false, "Output the stats as JSON")
+	statsCmd.Flags().String("model", "", "List the tools called by this model")
+	rootCmd.AddCommand(statsCmd)
+}
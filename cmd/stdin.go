@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// defaultStdinLimitKB is how much of the piped input a prompt gets by default
+const defaultStdinLimitKB = 100
+
+// pipedInput is the input piped into a non-interactive run
+type pipedInput struct {
+	content string
+	size    int // Bytes read, more than the content when truncated
+}
+
+func (p pipedInput) truncated() bool {
+	return p.size > len(p.content)
+}
+
+// readPipedInput reads stdin when it's piped or redirected from a file, up to
+// limit bytes. Nothing is read from a terminal or a character device such as
+// /dev/null.
+func readPipedInput(stdin *os.File, limit int) (pipedInput, error) {
+	if isatty.IsTerminal(stdin.Fd()) || isatty.IsCygwinTerminal(stdin.Fd()) {
+		return pipedInput{}, nil
+	}
+	info, err := stdin.Stat()
+	if err != nil || info.Mode()&os.ModeNamedPipe == 0 && !info.Mode().IsRegular() {
+		return pipedInput{}, nil
+	}
+
+	// One byte more than the limit tells whether there is more, the rest is
+	// counted without keeping it
+	data, err := io.ReadAll(io.LimitReader(stdin, int64(limit)+1))
+	if err != nil {
+		return pipedInput{}, fmt.Errorf("failed to read stdin: %w", err)
+	}
+	size := len(data)
+	if size > limit {
+		rest, err := io.Copy(io.Discard, stdin)
+		if err != nil {
+			return pipedInput{}, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		size += int(rest)
+		data = data[:limit]
+		// Cut at the last full line
+		if i := bytes.LastIndexByte(data, '\n'); i > 0 {
+			data = data[:i+1]
+		}
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return pipedInput{}, fmt.Errorf("stdin looks like binary data, pipe text into the prompt")
+	}
+	return pipedInput{content: strings.ToValidUTF8(string(data), ""), size: size}, nil
+}
+
+// withPipedInput appends the piped input to the prompt as context
+func withPipedInput(prompt string, input pipedInput) string {
+	if input.size == 0 {
+		return prompt
+	}
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	sb.WriteString("\n\nThe input piped into this prompt:\n")
+	if input.truncated() {
+		fmt.Fprintf(&sb, "<stdin bytes=\"%d\" truncated=\"true\">\n", input.size)
+	} else {
+		fmt.Fprintf(&sb, "<stdin bytes=\"%d\">\n", input.size)
+	}
+	sb.WriteString(input.content)
+	if !strings.HasSuffix(input.content, "\n") {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("</stdin>")
+	if input.truncated() {
+		fmt.Fprintf(&sb, "\nOnly the first %d of the %d bytes are included, say so when the rest could matter for the answer.", len(input.content), input.size)
+	}
+	return sb.String()
+}
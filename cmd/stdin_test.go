@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stdinFile(t *testing.T, content string) *os.File {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdin")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestReadPipedInput(t *testing.T) {
+	input, err := readPipedInput(stdinFile(t, "diff --git a/x b/x\n+added\n"), 1024)
+	require.NoError(t, err)
+	assert.False(t, input.truncated())
+	prompt := withPipedInput("review this diff", input)
+	assert.Equal(t, "review this diff\n\nThe input piped into this prompt:\n<stdin bytes=\"26\">\ndiff --git a/x b/x\n+added\n</stdin>", prompt)
+
+	input, err = readPipedInput(stdinFile(t, strings.Repeat("line\n", 100)), 52)
+	require.NoError(t, err)
+	assert.True(t, input.truncated())
+	assert.Equal(t, 500, input.size)
+	assert.Equal(t, strings.Repeat("line\n", 10), input.content, "cut at the last full line")
+	assert.Contains(t, withPipedInput("summarize", input), "Only the first 50 of the 500 bytes are included")
+
+	input, err = readPipedInput(stdinFile(t, ""), 1024)
+	require.NoError(t, err)
+	assert.Equal(t, "summarize", withPipedInput("summarize", input))
+
+	_, err = readPipedInput(stdinFile(t, "\x7fELF\x00\x01"), 1024)
+	assert.Error(t, err)
+
+	devNull, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	defer devNull.Close()
+	input, err = readPipedInput(devNull, 1024)
+	require.NoError(t, err)
+	assert.Zero(t, input.size)
+}
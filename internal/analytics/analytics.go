@@ -0,0 +1,246 @@
+// Package analytics keeps anonymous usage stats in the local database: the
+// sessions started, the turns and the tool calls with their outcome, without
+// any content. Daily reports of the categories the user chose to share are
+// sent to an endpoint only when configured.
+package analytics
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+)
+
+// Event kinds
+const (
+	KindSession = "session"
+	KindTurn    = "turn"
+	KindTool    = "tool"
+)
+
+// dayLayout formats the days of the stats and reports
+const dayLayout = "2006-01-02"
+
+// Event is a usage event
+type Event struct {
+	Kind    string
+	Name    string // Model of a turn, name of a tool
+	Model   string // Model that called a tool
+	Success bool
+}
+
+// Usage counts the turns of a model or the calls of a tool
+type Usage struct {
+	Name   string `json:"name"`
+	Count  int    `json:"count"`
+	Failed int    `json:"failed"`
+}
+
+// SuccessRate returns the share of the turns or calls that succeeded
+func (u Usage) SuccessRate() float64 {
+	if u.Count == 0 {
+		return 0
+	}
+	return float64(u.Count-u.Failed) / float64(u.Count)
+}
+
+// Day counts the usage events of a day
+type Day struct {
+	Date      string `json:"date"`
+	Sessions  int    `json:"sessions"`
+	Turns     int    `json:"turns"`
+	ToolCalls int    `json:"toolCalls"`
+}
+
+// Stats summarizes the usage of the last days
+type Stats struct {
+	Days     int     `json:"days"`
+	Sessions int     `json:"sessions"`
+	Daily    []Day   `json:"daily"` // Days with usage, oldest first
+	Turns    []Usage `json:"turns"` // Per model, most used first
+	Tools    []Usage `json:"tools"` // Most used first
+}
+
+// SessionsPerDay returns the average number of sessions started per day
+func (s Stats) SessionsPerDay() float64 {
+	if s.Days == 0 {
+		return 0
+	}
+	return float64(s.Sessions) / float64(s.Days)
+}
+
+// TurnSuccessRate returns the share of all the turns that succeeded
+func (s Stats) TurnSuccessRate() float64 {
+	var total Usage
+	for _, turns := range s.Turns {
+		total.Count += turns.Count
+		total.Failed += turns.Failed
+	}
+	return total.SuccessRate()
+}
+
+type Service interface {
+	// Record stores a usage event, unless analytics are disabled
+	Record(ctx context.Context, event Event) error
+	// Stats summarizes the usage of the last days, today included
+	Stats(ctx context.Context, days int) (Stats, error)
+	// ToolUsageByModel returns the calls of every tool per model that made
+	// them in the last days, most used first. It is never shared.
+	ToolUsageByModel(ctx context.Context, days int) (map[string][]Usage, error)
+	// SubmitReports sends the reports of the days not sent yet to the
+	// configured endpoint, when the user opted in
+	SubmitReports(ctx context.Context) error
+}
+
+type service struct {
+	q db.Querier
+}
+
+func NewService(q db.Querier) Service {
+	return &service{q: q}
+}
+
+func (s *service) Record(ctx context.Context, event Event) error {
+	if cfg := config.Get(); cfg != nil && cfg.Analytics.Disabled {
+		return nil
+	}
+	success := int64(0)
+	if event.Success {
+		success = 1
+	}
+	return s.q.CreateUsageEvent(ctx, db.CreateUsageEventParams{
+		ID:      uuid.New().String(),
+		Kind:    event.Kind,
+		Name:    event.Name,
+		Success: success,
+		Model:   event.Model,
+	})
+}
+
+func (s *service) Stats(ctx context.Context, days int) (Stats, error) {
+	if days <= 0 {
+		days = 1
+	}
+	start := dayStart(time.Now()).AddDate(0, 0, -(days - 1))
+	rows, err := s.q.ListUsageEventsSince(ctx, start.Unix())
+	if err != nil {
+		return Stats{}, err
+	}
+	stats := aggregate(rows)
+	stats.Days = days
+	return stats, nil
+}
+
+func (s *service) ToolUsageByModel(ctx context.Context, days int) (map[string][]Usage, error) {
+	if days <= 0 {
+		days = 1
+	}
+	start := dayStart(time.Now()).AddDate(0, 0, -(days - 1))
+	rows, err := s.q.ListUsageEventsSince(ctx, start.Unix())
+	if err != nil {
+		return nil, err
+	}
+	return toolUsageByModel(rows), nil
+}
+
+// toolUsageByModel counts the tool calls per model, calls recorded without
+// their model are left out
+func toolUsageByModel(rows []db.UsageEvent) map[string][]Usage {
+	byModel := make(map[string]map[string]*Usage)
+	for _, row := range rows {
+		if row.Kind != KindTool || row.Model == "" {
+			continue
+		}
+		tools, ok := byModel[row.Model]
+		if !ok {
+			tools = make(map[string]*Usage)
+			byModel[row.Model] = tools
+		}
+		usage, ok := tools[row.Name]
+		if !ok {
+			usage = &Usage{Name: row.Name}
+			tools[row.Name] = usage
+		}
+		usage.Count++
+		if row.Success == 0 {
+			usage.Failed++
+		}
+	}
+	usages := make(map[string][]Usage, len(byModel))
+	for model, tools := range byModel {
+		usages[model] = sortUsages(tools)
+	}
+	return usages
+}
+
+// aggregate sums usage events up per day, model and tool
+func aggregate(rows []db.UsageEvent) Stats {
+	var stats Stats
+	daily := make(map[string]*Day)
+	turns := make(map[string]*Usage)
+	tools := make(map[string]*Usage)
+	count := func(usages map[string]*Usage, row db.UsageEvent) {
+		usage, ok := usages[row.Name]
+		if !ok {
+			usage = &Usage{Name: row.Name}
+			usages[row.Name] = usage
+		}
+		usage.Count++
+		if row.Success == 0 {
+			usage.Failed++
+		}
+	}
+
+	for _, row := range rows {
+		date := time.Unix(row.CreatedAt, 0).Format(dayLayout)
+		day, ok := daily[date]
+		if !ok {
+			day = &Day{Date: date}
+			daily[date] = day
+		}
+		switch row.Kind {
+		case KindSession:
+			stats.Sessions++
+			day.Sessions++
+		case KindTurn:
+			day.Turns++
+			count(turns, row)
+		case KindTool:
+			day.ToolCalls++
+			count(tools, row)
+		}
+	}
+
+	for _, day := range daily {
+		stats.Daily = append(stats.Daily, *day)
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Date < stats.Daily[j].Date
+	})
+	stats.Turns = sortUsages(turns)
+	stats.Tools = sortUsages(tools)
+	return stats
+}
+
+// sortUsages returns the usages, most used first
+func sortUsages(usages map[string]*Usage) []Usage {
+	sorted := make([]Usage, 0, len(usages))
+	for _, usage := range usages {
+		sorted = append(sorted, *usage)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// dayStart returns the midnight of the day of t
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
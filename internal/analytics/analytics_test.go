@@ -0,0 +1,78 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregate(t *testing.T) {
+	monday := time.Date(2026, 10, 12, 10, 0, 0, 0, time.Local)
+	tuesday := monday.AddDate(0, 0, 1)
+	rows := []db.UsageEvent{
+		{Kind: KindSession, Success: 1, CreatedAt: monday.Unix()},
+		{Kind: KindTurn, Name: "claude-4-sonnet", Success: 1, CreatedAt: monday.Unix()},
+		{Kind: KindTool, Name: "view", Success: 1, CreatedAt: monday.Unix()},
+		{Kind: KindTool, Name: "bash", Success: 0, CreatedAt: monday.Unix()},
+		{Kind: KindTool, Name: "view", Success: 1, CreatedAt: tuesday.Unix()},
+		{Kind: KindSession, Success: 1, CreatedAt: tuesday.Unix()},
+		{Kind: KindTurn, Name: "claude-4-sonnet", Success: 0, CreatedAt: tuesday.Unix()},
+	}
+
+	stats := aggregate(rows)
+	stats.Days = 4
+	assert.Equal(t, 2, stats.Sessions)
+	assert.InDelta(t, 0.5, stats.SessionsPerDay(), 1e-9)
+	assert.Equal(t, []Day{
+		{Date: "2026-10-12", Sessions: 1, Turns: 1, ToolCalls: 2},
+		{Date: "2026-10-13", Sessions: 1, Turns: 1, ToolCalls: 1},
+	}, stats.Daily)
+	assert.Equal(t, []Usage{{Name: "claude-4-sonnet", Count: 2, Failed: 1}}, stats.Turns)
+	assert.InDelta(t, 0.5, stats.TurnSuccessRate(), 1e-9)
+	assert.Equal(t, []Usage{{Name: "view", Count: 2}, {Name: "bash", Count: 1, Failed: 1}}, stats.Tools)
+	assert.InDelta(t, 1, stats.Tools[0].SuccessRate(), 1e-9)
+}
+
+func TestToolUsageByModel(t *testing.T) {
+	usages := toolUsageByModel([]db.UsageEvent{
+		{Kind: KindTurn, Name: "gpt-4.1", Success: 1},
+		{Kind: KindTool, Name: "view", Model: "gpt-4.1", Success: 1},
+		{Kind: KindTool, Name: "grep", Model: "gpt-4.1", Success: 0},
+		{Kind: KindTool, Name: "grep", Model: "gpt-4.1", Success: 1},
+		{Kind: KindTool, Name: "view", Model: "claude-4-sonnet", Success: 1},
+		{Kind: KindTool, Name: "bash", Success: 1},
+	})
+	assert.Equal(t, map[string][]Usage{
+		"gpt-4.1":         {{Name: "grep", Count: 2, Failed: 1}, {Name: "view", Count: 1}},
+		"claude-4-sonnet": {{Name: "view", Count: 1}},
+	}, usages)
+}
+
+func TestNewReport(t *testing.T) {
+	stats := Stats{
+		Sessions: 3,
+		Turns:    []Usage{{Name: "gpt-4.1", Count: 5}},
+		Tools: []Usage{
+			{Name: "view", Count: 4},
+			{Name: "github_create_issue", Count: 2, Failed: 1},
+			{Name: "jira_search", Count: 1},
+		},
+	}
+	servers := map[string]config.MCPServer{"github": {}, "jira": {}}
+
+	report := newReport("2026-10-15", stats, []string{config.AnalyticsSessions, config.AnalyticsTools}, servers)
+	assert.Equal(t, "2026-10-15", report.Date)
+	require.NotNil(t, report.Sessions)
+	assert.Equal(t, 3, *report.Sessions)
+	assert.Nil(t, report.Turns, "turns aren't shared")
+	assert.Equal(t, []Usage{{Name: "view", Count: 4}, {Name: mcpToolName, Count: 3, Failed: 1}}, report.Tools)
+
+	report = newReport("2026-10-15", stats, []string{config.AnalyticsTurns}, servers)
+	assert.Nil(t, report.Sessions)
+	assert.Nil(t, report.Tools)
+	assert.Equal(t, stats.Turns, report.Turns)
+}
@@ -0,0 +1,168 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/version"
+)
+
+const (
+	// maxReportDays limits how many past days are reported at once
+	maxReportDays = 7
+	// mcpToolName replaces the names of MCP tools in reports, they tell
+	// which servers are used
+	mcpToolName = "mcp"
+	// stateFile remembers the last day reported, in the data directory
+	stateFile = "analytics.json"
+)
+
+// Report is the usage of a day sent to the analytics endpoint, with only the
+// shared categories
+type Report struct {
+	Version  string  `json:"version"`
+	Date     string  `json:"date"`
+	Sessions *int    `json:"sessions,omitempty"`
+	Turns    []Usage `json:"turns,omitempty"`
+	Tools    []Usage `json:"tools,omitempty"`
+}
+
+type reportState struct {
+	LastReported string `json:"lastReported"`
+}
+
+func (s *service) SubmitReports(ctx context.Context) error {
+	cfg := config.Get()
+	if cfg == nil || cfg.Analytics.Disabled || cfg.Analytics.Endpoint == "" || len(cfg.Analytics.Share) == 0 {
+		return nil
+	}
+	statePath := filepath.Join(cfg.Data.Directory, stateFile)
+	state := readState(statePath)
+
+	// The first report is about yesterday, the days before opting in stay
+	// local
+	today := dayStart(time.Now())
+	first := today.AddDate(0, 0, -1)
+	if last, err := time.ParseInLocation(dayLayout, state.LastReported, time.Local); err == nil {
+		first = last.AddDate(0, 0, 1)
+	}
+	first = maxTime(first, today.AddDate(0, 0, -maxReportDays))
+	if !first.Before(today) {
+		return nil
+	}
+
+	rows, err := s.q.ListUsageEventsSince(ctx, first.Unix())
+	if err != nil {
+		return err
+	}
+	byDay := make(map[string][]db.UsageEvent)
+	for _, row := range rows {
+		date := time.Unix(row.CreatedAt, 0).Format(dayLayout)
+		byDay[date] = append(byDay[date], row)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	for day := first; day.Before(today); day = day.AddDate(0, 0, 1) {
+		date := day.Format(dayLayout)
+		if events := byDay[date]; len(events) > 0 {
+			report := newReport(date, aggregate(events), cfg.Analytics.Share, cfg.MCPServers)
+			if err := postReport(ctx, client, cfg.Analytics.Endpoint, report); err != nil {
+				return err
+			}
+		}
+		state.LastReported = date
+		if err := writeState(statePath, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newReport keeps the shared categories of the stats of a day
+func newReport(date string, stats Stats, share []string, mcpServers map[string]config.MCPServer) Report {
+	report := Report{Version: version.Version, Date: date}
+	if slices.Contains(share, config.AnalyticsSessions) {
+		report.Sessions = &stats.Sessions
+	}
+	if slices.Contains(share, config.AnalyticsTurns) {
+		report.Turns = stats.Turns
+	}
+	if slices.Contains(share, config.AnalyticsTools) {
+		tools := make(map[string]*Usage)
+		for _, usage := range stats.Tools {
+			name := usage.Name
+			for server := range mcpServers {
+				if strings.HasPrefix(name, server+"_") {
+					name = mcpToolName
+					break
+				}
+			}
+			total, ok := tools[name]
+			if !ok {
+				total = &Usage{Name: name}
+				tools[name] = total
+			}
+			total.Count += usage.Count
+			total.Failed += usage.Failed
+		}
+		report.Tools = sortUsages(tools)
+	}
+	return report
+}
+
+func postReport(ctx context.Context, client *http.Client, endpoint string, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid analytics endpoint: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send the analytics report: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("analytics endpoint answered %s", resp.Status)
+	}
+	return nil
+}
+
+func readState(path string) reportState {
+	var state reportState
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+func writeState(path string, state reportState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save the analytics state: %w", err)
+	}
+	return nil
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/analytics"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/pubsub"
+	"github.com/kirmad/superopencode/internal/session"
+)
+
+// recordUsage stores the sessions started by the user, the turns of the
+// coder agent and the tool calls in the anonymous usage stats. Tool calls
+// are recorded with the model that made them, the last one to respond in
+// their session.
+func (app *App) recordUsage(ctx context.Context) {
+	defer logging.RecoverPanic("usage-recorder", nil)
+	sessions := app.Sessions.Subscribe(ctx)
+	messages := app.Messages.Subscribe(ctx)
+	agentEvents := app.CoderAgent.Subscribe(ctx)
+	sessionModels := make(map[string]string)
+	for {
+		var events []analytics.Event
+		select {
+		case event, ok := <-sessions:
+			if !ok {
+				return
+			}
+			if event.Type == pubsub.DeletedEvent {
+				delete(sessionModels, event.Payload.ID)
+			}
+			events = sessionUsage(event)
+		case event, ok := <-messages:
+			if !ok {
+				return
+			}
+			if event.Payload.Role == message.Assistant && event.Payload.Model != "" {
+				sessionModels[event.Payload.SessionID] = string(event.Payload.Model)
+			}
+			events = toolUsage(event, sessionModels[event.Payload.SessionID])
+		case event, ok := <-agentEvents:
+			if !ok {
+				return
+			}
+			events = turnUsage(event, app.CoderAgent)
+		case <-ctx.Done():
+			return
+		}
+		for _, event := range events {
+			if err := app.Analytics.Record(ctx, event); err != nil {
+				logging.Warn("Failed to record a usage event", "error", err)
+			}
+		}
+	}
+}
+
+func sessionUsage(event pubsub.Event[session.Session]) []analytics.Event {
+	// Task sessions are started by the agent
+	if event.Type != pubsub.CreatedEvent || event.Payload.ParentSessionID != "" {
+		return nil
+	}
+	return []analytics.Event{{Kind: analytics.KindSession, Success: true}}
+}
+
+func toolUsage(event pubsub.Event[message.Message], model string) []analytics.Event {
+	if event.Type != pubsub.CreatedEvent || event.Payload.Role != message.Tool {
+		return nil
+	}
+	var events []analytics.Event
+	for _, result := range event.Payload.ToolResults() {
+		events = append(events, analytics.Event{Kind: analytics.KindTool, Name: result.Name, Model: model, Success: !result.IsError})
+	}
+	return events
+}
+
+func turnUsage(event pubsub.Event[agent.AgentEvent], coder agent.Service) []analytics.Event {
+	payload := event.Payload
+	switch {
+	case payload.Type == agent.AgentEventTypeResponse && payload.Done:
+		return []analytics.Event{{Kind: analytics.KindTurn, Name: string(coder.Model().ID), Success: true}}
+	case payload.Type == agent.AgentEventTypeError:
+		if errors.Is(payload.Error, agent.ErrRequestCancelled) || errors.Is(payload.Error, context.Canceled) {
+			return nil
+		}
+		return []analytics.Event{{Kind: analytics.KindTurn, Name: string(coder.Model().ID), Success: false}}
+	}
+	return nil
+}
+
+// submitAnalytics sends the daily usage reports the user opted in to
+func (app *App) submitAnalytics(ctx context.Context) {
+	defer logging.RecoverPanic("analytics-reports", nil)
+	if err := app.Analytics.SubmitReports(ctx); err != nil {
+		logging.Warn("Failed to submit the usage reports", "error", err)
+	}
+}
+
+// toolUsageRefreshInterval is how often the tool calls per model the tools
+// are pruned by are read again
+const toolUsageRefreshInterval = time.Hour
+
+// refreshToolUsage loads the tool calls per model recorded in the usage
+// stats for the agents to prune the tools of the models that rarely use them
+func (app *App) refreshToolUsage(ctx context.Context) {
+	defer logging.RecoverPanic("tool-usage", nil)
+	ticker := time.NewTicker(toolUsageRefreshInterval)
+	defer ticker.Stop()
+	for {
+		if cfg := config.Get().ToolPruning; cfg.Enabled {
+			usage, err := app.Analytics.ToolUsageByModel(ctx, cfg.Days)
+			if err != nil {
+				logging.Warn("Failed to read the tool usage per model", "error", err)
+			} else {
+				agent.SetToolUsage(usage)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -6,20 +6,30 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/kirmad/superopencode/internal/analytics"
 	"github.com/kirmad/superopencode/internal/config"
 	"github.com/kirmad/superopencode/internal/db"
 	"github.com/kirmad/superopencode/internal/detailed_logging"
+	"github.com/kirmad/superopencode/internal/dryrun"
+	"github.com/kirmad/superopencode/internal/errorpattern"
 	"github.com/kirmad/superopencode/internal/format"
 	"github.com/kirmad/superopencode/internal/history"
 	"github.com/kirmad/superopencode/internal/llm/agent"
 	"github.com/kirmad/superopencode/internal/logging"
 	"github.com/kirmad/superopencode/internal/lsp"
 	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/metrics"
 	"github.com/kirmad/superopencode/internal/permission"
+	"github.com/kirmad/superopencode/internal/quota"
 	"github.com/kirmad/superopencode/internal/session"
+	"github.com/kirmad/superopencode/internal/sessionlock"
+	"github.com/kirmad/superopencode/internal/settings"
+	"github.com/kirmad/superopencode/internal/tmux"
 	"github.com/kirmad/superopencode/internal/tui/theme"
 )
 
@@ -28,6 +38,13 @@ type App struct {
 	Messages    message.Service
 	History     history.Service
 	Permissions permission.Service
+	Quotas      quota.Service
+	Metrics     metrics.Service
+	Analytics   analytics.Service
+	Settings    settings.Service
+	Locks       *sessionlock.Manager
+
+	ErrorPatterns errorpattern.Service
 
 	CoderAgent agent.Service
 
@@ -35,42 +52,77 @@ type App struct {
 
 	clientsMutex sync.RWMutex
 
-	watcherCancelFuncs []context.CancelFunc
+	watcherCancelFuncs map[string]context.CancelFunc // LSP client name -> cancel of its watcher
 	cancelFuncsMutex   sync.Mutex
 	watcherWG          sync.WaitGroup
 
+	idle *idleMonitor
+	tmux *tmux.Window // Window named after the current session, nil unless attached
+
 	DetailedLogger *detailed_logging.DetailedLogger
+
+	// DryRun is the scratch worktree the agent changes instead of the
+	// working tree in a run with the diff output format, nil otherwise
+	DryRun *dryrun.Workspace
 }
 
-func New(ctx context.Context, conn *sql.DB) (*App, error) {
+func New(ctx context.Context, conn *sql.DB, initial settings.Settings) (*App, error) {
 	q := db.New(conn)
 	sessions := session.NewService(q)
 	messages := message.NewService(q)
-	files := history.NewService(q, conn)
+	// Nothing of an in-memory database outlives the run, neither do the
+	// pre-images of its changes
+	var journal *history.Journal
+	if !db.InMemory(conn) {
+		journal = history.NewJournal(filepath.Join(config.Get().Data.Directory, "snapshots"))
+	}
+	files := history.NewService(q, conn, journal)
 
 	app := &App{
 		Sessions:    sessions,
 		Messages:    messages,
 		History:     files,
 		Permissions: permission.NewPermissionService(),
+		Quotas:      quota.NewService(q),
+		Metrics:     metrics.NewService(q),
+		Analytics:   analytics.NewService(q),
+		Settings:    settings.NewService(initial),
+		Locks:       sessionlock.NewManager(filepath.Join(config.Get().Data.Directory, "locks")),
 		LSPClients:  make(map[string]*lsp.Client),
+
+		ErrorPatterns: errorpattern.NewService(q),
+
+		watcherCancelFuncs: make(map[string]context.CancelFunc),
+		idle:               newIdleMonitor(),
 	}
 
 	// Initialize theme based on configuration
 	app.initTheme()
 
+	// Repair what a crash may have left behind before sessions are used
+	app.checkSessions(ctx, q)
+	app.checkTaskSessions(ctx)
+	if recovered, err := files.Recover(ctx); err != nil {
+		logging.Warn("Failed to recover file snapshots", "error", err)
+	} else if recovered > 0 {
+		logging.Info("Recovered the file changes interrupted by a crash", "files", recovered)
+	}
+
 	// Initialize detailed logging if enabled
-	cfg := config.Get()
-	if cfg != nil && cfg.DetailedLogs {
+	if initial.DetailedLogs {
 		detailedLogger, err := detailed_logging.NewDetailedLogger(true)
 		if err != nil {
 			logging.Warn("Failed to initialize detailed logging", "error", err)
 		} else {
 			app.DetailedLogger = detailedLogger
+			app.Permissions = loggedPermissions{Service: app.Permissions, logger: detailedLogger}
 			logging.Info("Detailed logging enabled")
 		}
 	}
 
+	// Tool errors and what fixed them are learned from the tool results
+	app.Messages = newLearningMessages(app.Messages, app.ErrorPatterns)
+
 	// Initialize LSP clients in the background
 	go app.initLSPClients(ctx)
 
@@ -79,11 +131,14 @@ func New(ctx context.Context, conn *sql.DB) (*App, error) {
 		config.AgentCoder,
 		app.Sessions,
 		app.Messages,
+		app.Quotas,
 		agent.CoderAgentTools(
 			app.Permissions,
 			app.Sessions,
 			app.Messages,
 			app.History,
+			app.Quotas,
+			app.Metrics,
 			app.LSPClients,
 		),
 		app.DetailedLogger,
@@ -92,6 +147,16 @@ func New(ctx context.Context, conn *sql.DB) (*App, error) {
 		logging.Error("Failed to create coder agent", err)
 		return nil, err
 	}
+	app.CoderAgent = lockedAgent{Service: app.CoderAgent, locks: app.Locks}
+	app.CoderAgent = newLearningAgent(app.CoderAgent, app.ErrorPatterns)
+
+	go app.watchIdle(ctx)
+	go app.watchLocks(ctx)
+	go app.recordLatencies(ctx)
+	go app.recordUsage(ctx)
+	go app.submitAnalytics(ctx)
+	go app.refreshToolUsage(ctx)
+	app.Warmup()
 
 	return app, nil
 }
@@ -113,7 +178,8 @@ func (app *App) initTheme() {
 }
 
 // RunNonInteractive handles the execution flow when a prompt is provided via CLI flag.
-func (a *App) RunNonInteractive(ctx context.Context, prompt string, outputFormat string, quiet bool, dangerouslySkipPermissions bool) error {
+// The output is shaped by the post-processors before it's printed.
+func (a *App) RunNonInteractive(ctx context.Context, prompt string, outputFormat string, quiet bool, processors []config.OutputProcessor) error {
 	logging.Info("Running in non-interactive mode")
 
 	// Start spinner if not in quiet mode
@@ -124,6 +190,46 @@ func (a *App) RunNonInteractive(ctx context.Context, prompt string, outputFormat
 		defer spinner.Stop()
 	}
 
+	result, err := a.RunPrompt(ctx, prompt)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, agent.ErrRequestCancelled) {
+			return nil
+		}
+		return err
+	}
+	if a.DryRun != nil {
+		if result.Diff, err = a.DryRun.Diff(ctx); err != nil {
+			return fmt.Errorf("failed to diff the proposed changes: %w", err)
+		}
+	}
+	output, err := format.Render(ctx, result, outputFormat, processors)
+	if err != nil {
+		return err
+	}
+
+	// Stop spinner before printing output
+	if !quiet && spinner != nil {
+		spinner.Stop()
+	}
+
+	if f, _ := format.Parse(outputFormat); f == format.Diff {
+		// stdout only gets the diff, so it can be piped to git apply
+		if !quiet {
+			fmt.Fprintln(os.Stderr, result.Response)
+		}
+		if result.Diff == "" {
+			fmt.Fprintln(os.Stderr, "No changes proposed")
+		}
+		fmt.Print(output)
+		return nil
+	}
+	fmt.Println(output)
+	return nil
+}
+
+// RunPrompt runs a prompt in a new session with all permissions granted and
+// returns the answer with the usage and cost of the session
+func (a *App) RunPrompt(ctx context.Context, prompt string) (format.Result, error) {
 	const maxPromptLengthForTitle = 100
 	titlePrefix := "Non-interactive: "
 	var titleSuffix string
@@ -137,34 +243,40 @@ func (a *App) RunNonInteractive(ctx context.Context, prompt string, outputFormat
 
 	sess, err := a.Sessions.Create(ctx, title)
 	if err != nil {
-		return fmt.Errorf("failed to create session for non-interactive mode: %w", err)
+		return format.Result{}, fmt.Errorf("failed to create session for non-interactive mode: %w", err)
 	}
 	logging.Info("Created session for non-interactive run", "session_id", sess.ID)
 
 	// Automatically approve all permission requests for this non-interactive session
 	// or if the dangerous flag is set
-	if dangerouslySkipPermissions {
+	if a.Settings.Get().SkipPermissions {
 		logging.Warn("⚠️ DANGEROUS: --dangerously-skip-permissions active. All tool permissions bypassed for session %s", sess.ID)
 	}
 	a.Permissions.AutoApproveSession(sess.ID)
 
+	// The branches of the repository are shared with the scratch worktree
+	// of a dry run, it leaves them alone
+	if a.DryRun != nil {
+		logging.Info("Dry run in a scratch worktree", "dir", a.DryRun.Dir)
+	} else if branch, err := a.EnterSessionBranch(ctx, sess.ID); err != nil {
+		logging.Warn("Failed to check out the session branch", "error", err)
+	} else if branch != "" {
+		logging.Info("Working on the session branch", "branch", branch)
+	}
+
+	started := time.Now()
 	done, err := a.CoderAgent.Run(ctx, sess.ID, prompt)
 	if err != nil {
-		return fmt.Errorf("failed to start agent processing stream: %w", err)
+		return format.Result{}, fmt.Errorf("failed to start agent processing stream: %w", err)
 	}
 
 	result := <-done
 	if result.Error != nil {
 		if errors.Is(result.Error, context.Canceled) || errors.Is(result.Error, agent.ErrRequestCancelled) {
 			logging.Info("Agent processing cancelled", "session_id", sess.ID)
-			return nil
+			return format.Result{}, result.Error
 		}
-		return fmt.Errorf("agent processing failed: %w", result.Error)
-	}
-
-	// Stop spinner before printing output
-	if !quiet && spinner != nil {
-		spinner.Stop()
+		return format.Result{}, fmt.Errorf("agent processing failed: %w", result.Error)
 	}
 
 	// Get the text content from the response
@@ -173,11 +285,21 @@ func (a *App) RunNonInteractive(ctx context.Context, prompt string, outputFormat
 		content = result.Message.Content().String()
 	}
 
-	fmt.Println(format.FormatOutput(content, outputFormat))
-
 	logging.Info("Non-interactive run completed", "session_id", sess.ID)
 
-	return nil
+	run := format.Result{
+		Response:   content,
+		SessionID:  sess.ID,
+		Model:      string(result.Message.Model),
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	// The session has the usage and cost of the whole run, tasks included
+	if sess, err = a.Sessions.Get(ctx, sess.ID); err == nil {
+		run.PromptTokens = sess.PromptTokens
+		run.CompletionTokens = sess.CompletionTokens
+		run.Cost = sess.Cost
+	}
+	return run, nil
 }
 
 // Shutdown performs a clean shutdown of the application
@@ -204,6 +326,21 @@ func (app *App) Shutdown() {
 		cancel()
 	}
 
+	// Write the batched updates of messages still streaming
+	if err := app.Messages.Flush(context.Background()); err != nil {
+		logging.Error("Failed to persist messages", "error", err)
+	}
+
+	// Let other processes drive the sessions of this one
+	app.Locks.ReleaseAll()
+
+	// Hand the tmux window back to tmux
+	if app.tmux != nil {
+		detachCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		app.tmux.Detach(detachCtx)
+		cancel()
+	}
+
 	// Shutdown detailed logger if enabled
 	if app.DetailedLogger != nil {
 		if err := app.DetailedLogger.Close(); err != nil {
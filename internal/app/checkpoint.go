@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kirmad/superopencode/internal/checkpoint"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/history"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+)
+
+// CheckpointsDir is where the named checkpoints of a session are kept
+func CheckpointsDir(sessionID string) string {
+	return filepath.Join(config.Get().Data.Directory, "checkpoints", sessionID)
+}
+
+// SaveCheckpoint snapshots the workspace of a session as a named checkpoint:
+// the files the session changed, and the modified and untracked files of the
+// repository
+func (app *App) SaveCheckpoint(ctx context.Context, sessionID, name string) (checkpoint.Checkpoint, error) {
+	if sessionID == "" {
+		return checkpoint.Checkpoint{}, errors.New("no session selected")
+	}
+	files, err := app.History.ListLatestSessionFiles(ctx, sessionID)
+	if err != nil {
+		return checkpoint.Checkpoint{}, err
+	}
+	touched := make([]string, len(files))
+	for i, file := range files {
+		touched[i] = file.Path
+	}
+	return checkpoint.Save(ctx, CheckpointsDir(sessionID), config.WorkingDirectory(), name, touched)
+}
+
+// RestoreCheckpoint brings the workspace back to a named checkpoint of a
+// session and returns the files it changed. The restored content of the
+// files in the history of the session becomes their latest version, they
+// are snapshotted before they change so a crash in between is recovered.
+func (app *App) RestoreCheckpoint(ctx context.Context, sessionID, name string) ([]string, error) {
+	if sessionID == "" {
+		return nil, errors.New("no session selected")
+	}
+	files, err := app.History.ListBySession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	original := make(map[string]string)
+	for _, file := range files {
+		if file.Version == history.InitialVersion {
+			original[file.Path] = file.Content
+		}
+	}
+
+	snapshots := make(map[string]history.Snapshot)
+	snapshot := func(path string) error {
+		if _, ok := original[path]; !ok {
+			return nil
+		}
+		snapshot, err := app.History.Snapshot(ctx, sessionID, path)
+		if err != nil {
+			return fmt.Errorf("failed to snapshot %s: %w", path, err)
+		}
+		snapshots[path] = snapshot
+		return nil
+	}
+	wd := config.WorkingDirectory()
+	restored, err := checkpoint.Restore(ctx, CheckpointsDir(sessionID), wd, name, original, snapshot)
+	for _, path := range restored {
+		path = filepath.Join(wd, path)
+		tools.RecordFileWrite(sessionID, path)
+		if _, ok := original[path]; !ok {
+			continue
+		}
+		content, _ := os.ReadFile(path)
+		_, historyErr := app.History.CreateVersion(ctx, sessionID, path, string(content))
+		history.ReleaseRecorded(app.History, snapshots[path], historyErr)
+		delete(snapshots, path)
+	}
+	// The files left are unchanged, the restore failed before it wrote them
+	for _, snapshot := range snapshots {
+		app.History.Release(snapshot)
+	}
+	return restored, err
+}
+
+// ListCheckpoints returns the named checkpoints of a session, the most recent
+// first
+func (app *App) ListCheckpoints(sessionID string) ([]checkpoint.Checkpoint, error) {
+	if sessionID == "" {
+		return nil, errors.New("no session selected")
+	}
+	return checkpoint.List(CheckpointsDir(sessionID))
+}
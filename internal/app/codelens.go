@@ -0,0 +1,201 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/lsp"
+	"github.com/kirmad/superopencode/internal/lsp/protocol"
+)
+
+// maxLensReferences is the number of references a references lens lists
+const maxLensReferences = 50
+
+// referencesLensPattern matches the titles of lenses counting references,
+// e.g. "3 references" from typescript-language-server or rust-analyzer
+var referencesLensPattern = regexp.MustCompile(`(?i)^\d+ (references?|implementations?)$`)
+
+// CodeLens is an action an LSP server offers on a line of a file, like
+// running a test or listing the references of a function
+type CodeLens struct {
+	Client  string
+	Path    string
+	Line    int // 1-based
+	Title   string
+	Command protocol.Command
+	Range   protocol.Range
+}
+
+// String returns the lens as path:line title
+func (l CodeLens) String() string {
+	return fmt.Sprintf("%s:%d %s", relativePath(l.Path), l.Line, l.Title)
+}
+
+// CodeLenses returns the code lenses of a file from all LSP clients, ordered
+// by line. Lenses the server doesn't resolve to a command are left out.
+func (app *App) CodeLenses(ctx context.Context, path string) ([]CodeLens, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(config.WorkingDirectory(), path)
+	}
+	clients := app.lspClients()
+	if len(clients) == 0 {
+		return nil, fmt.Errorf("no LSP clients available")
+	}
+
+	var lenses []CodeLens
+	for name, client := range clients {
+		if err := client.OpenFileOnDemand(ctx, path); err != nil {
+			continue
+		}
+		found, err := client.CodeLens(ctx, protocol.CodeLensParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(path)},
+		})
+		if err != nil {
+			continue
+		}
+		for _, lens := range found {
+			if lens.Command == nil {
+				resolved, err := client.ResolveCodeLens(ctx, lens)
+				if err != nil || resolved.Command == nil {
+					continue
+				}
+				lens = resolved
+			}
+			lenses = append(lenses, CodeLens{
+				Client:  name,
+				Path:    path,
+				Line:    int(lens.Range.Start.Line) + 1,
+				Title:   lens.Command.Title,
+				Command: *lens.Command,
+				Range:   lens.Range,
+			})
+		}
+	}
+	sortCodeLenses(lenses)
+	return lenses, nil
+}
+
+// RunCodeLens triggers a code lens and returns its outcome as text for the
+// session context. References lenses list the references, other lenses run
+// their command on the server, with the messages it shows while running.
+func (app *App) RunCodeLens(ctx context.Context, lens CodeLens) (string, error) {
+	client, ok := app.lspClients()[lens.Client]
+	if !ok {
+		return "", fmt.Errorf("LSP client %s is not running", lens.Client)
+	}
+	if isReferencesLens(lens) {
+		return lensReferences(ctx, client, lens)
+	}
+
+	var mu sync.Mutex
+	var messages []string
+	client.RegisterNotificationHandler("window/showMessage", func(params json.RawMessage) {
+		lsp.HandleServerMessage(params)
+		var msg protocol.ShowMessageParams
+		if err := json.Unmarshal(params, &msg); err == nil && msg.Message != "" {
+			mu.Lock()
+			messages = append(messages, msg.Message)
+			mu.Unlock()
+		}
+	})
+	defer client.RegisterNotificationHandler("window/showMessage", lsp.HandleServerMessage)
+
+	result, err := client.ExecuteCommand(ctx, protocol.ExecuteCommandParams{
+		Command:   lens.Command.Command,
+		Arguments: lens.Command.Arguments,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s can't be run by %s: %w", lens.Command.Command, lens.Client, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return formatLensResult(lens, result, messages), nil
+}
+
+func (app *App) lspClients() map[string]*lsp.Client {
+	app.clientsMutex.RLock()
+	defer app.clientsMutex.RUnlock()
+	clients := make(map[string]*lsp.Client, len(app.LSPClients))
+	maps.Copy(clients, app.LSPClients)
+	return clients
+}
+
+func lensReferences(ctx context.Context, client *lsp.Client, lens CodeLens) (string, error) {
+	locations, err := client.References(ctx, protocol.ReferenceParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(lens.Path)},
+			Position:     lens.Range.Start,
+		},
+		Context: protocol.ReferenceContext{IncludeDeclaration: false},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to find references: %w", err)
+	}
+	lines := make([]string, 0, len(locations))
+	for _, loc := range locations {
+		lines = append(lines, fmt.Sprintf("%s:%d", relativePath(loc.URI.Path()), loc.Range.Start.Line+1))
+	}
+	return formatLensResult(lens, nil, lines), nil
+}
+
+// isReferencesLens reports whether a lens counts references, those are
+// client side commands servers can't execute
+func isReferencesLens(lens CodeLens) bool {
+	return referencesLensPattern.MatchString(lens.Title) ||
+		strings.Contains(strings.ToLower(lens.Command.Command), "references")
+}
+
+// formatLensResult formats the outcome of a lens for the session context
+func formatLensResult(lens CodeLens, result any, lines []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Code lens %q at %s:%d (%s):\n", lens.Title, relativePath(lens.Path), lens.Line, lens.Client)
+	if len(lines) > maxLensReferences {
+		lines = append(lines[:maxLensReferences:maxLensReferences], fmt.Sprintf("... and %d more", len(lines)-maxLensReferences))
+	}
+	for _, line := range lines {
+		sb.WriteString(line + "\n")
+	}
+	if result != nil {
+		if output, err := json.MarshalIndent(result, "", "  "); err == nil {
+			sb.WriteString(string(output) + "\n")
+		}
+	}
+	if len(lines) == 0 && result == nil {
+		sb.WriteString("Done, no output\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func sortCodeLenses(lenses []CodeLens) {
+	sort.SliceStable(lenses, func(i, j int) bool {
+		if lenses[i].Line != lenses[j].Line {
+			return lenses[i].Line < lenses[j].Line
+		}
+		if lenses[i].Client != lenses[j].Client {
+			return lenses[i].Client < lenses[j].Client
+		}
+		return lenses[i].Title < lenses[j].Title
+	})
+}
+
+// relativePath returns path relative to the working directory when it's
+// inside it
+func relativePath(path string) string {
+	cfg := config.Get()
+	if cfg == nil {
+		return path
+	}
+	if rel, err := filepath.Rel(cfg.WorkingDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
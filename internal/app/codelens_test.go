@@ -0,0 +1,41 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/lsp/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsReferencesLens(t *testing.T) {
+	assert.True(t, isReferencesLens(CodeLens{Title: "3 references"}))
+	assert.True(t, isReferencesLens(CodeLens{Title: "1 implementation"}))
+	assert.True(t, isReferencesLens(CodeLens{Title: "refs", Command: protocol.Command{Command: "rust-analyzer.showReferences"}}))
+	assert.False(t, isReferencesLens(CodeLens{Title: "run test", Command: protocol.Command{Command: "gopls.run_tests"}}))
+}
+
+func TestSortCodeLenses(t *testing.T) {
+	lenses := []CodeLens{
+		{Client: "gopls", Line: 12, Title: "run test"},
+		{Client: "gopls", Line: 3, Title: "run test"},
+		{Client: "gopls", Line: 12, Title: "debug test"},
+	}
+	sortCodeLenses(lenses)
+	assert.Equal(t, []CodeLens{
+		{Client: "gopls", Line: 3, Title: "run test"},
+		{Client: "gopls", Line: 12, Title: "debug test"},
+		{Client: "gopls", Line: 12, Title: "run test"},
+	}, lenses)
+}
+
+func TestFormatLensResult(t *testing.T) {
+	lens := CodeLens{Client: "gopls", Path: "/src/a_test.go", Line: 7, Title: "run test"}
+
+	assert.Equal(t, "Code lens \"run test\" at /src/a_test.go:7 (gopls):\nPASS: TestA\n{\n  \"ok\": true\n}",
+		formatLensResult(lens, map[string]any{"ok": true}, []string{"PASS: TestA"}))
+	assert.Equal(t, "Code lens \"run test\" at /src/a_test.go:7 (gopls):\nDone, no output",
+		formatLensResult(lens, nil, nil))
+
+	lines := make([]string, maxLensReferences+2)
+	assert.Contains(t, formatLensResult(lens, nil, lines), "... and 2 more")
+}
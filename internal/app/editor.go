@@ -0,0 +1,228 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/diff"
+	"github.com/kirmad/superopencode/internal/editor"
+	"github.com/kirmad/superopencode/internal/history"
+	"github.com/kirmad/superopencode/internal/llm/prompt"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/version"
+)
+
+// editorEngine runs the requests of editor plugins. Plugins can't answer
+// permission requests, so their sessions run with all permissions granted
+// like non-interactive prompts.
+type editorEngine struct {
+	app *App
+
+	mu       sync.Mutex
+	approved map[string]bool // Sessions with all permissions granted
+}
+
+// EditorEngine returns the engine serving editor plugins
+func (app *App) EditorEngine() editor.Engine {
+	return &editorEngine{app: app, approved: make(map[string]bool)}
+}
+
+func (e *editorEngine) Info() editor.Info {
+	return editor.Info{
+		Version:          version.Version,
+		WorkingDirectory: config.WorkingDirectory(),
+		Model:            string(e.app.CoderAgent.Model().ID),
+		Methods:          editor.Methods,
+	}
+}
+
+func (e *editorEngine) NewSession(ctx context.Context, title string) (string, error) {
+	if title == "" {
+		title = "Editor session"
+	}
+	sess, err := e.app.Sessions.Create(ctx, title)
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	e.approve(sess.ID)
+	return sess.ID, nil
+}
+
+func (e *editorEngine) approve(sessionID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.approved[sessionID] {
+		e.approved[sessionID] = true
+		e.app.Permissions.AutoApproveSession(sessionID)
+	}
+}
+
+// AddContext pins the buffer to the session, a buffer without text pins the
+// file itself so it's re-read when it changes
+func (e *editorEngine) AddContext(ctx context.Context, sessionID string, buffer editor.Buffer) (editor.AddContextResult, error) {
+	if _, err := e.app.Sessions.Get(ctx, sessionID); err != nil {
+		return editor.AddContextResult{}, fmt.Errorf("unknown session %s", sessionID)
+	}
+	target := buffer.Path
+	if buffer.Text != "" {
+		target = editor.FormatBuffer(buffer)
+	}
+	item, err := prompt.Pin(sessionID, target)
+	if err != nil {
+		return editor.AddContextResult{}, err
+	}
+	return editor.AddContextResult{ID: item.ID, Tokens: int(item.Tokens)}, nil
+}
+
+// Prompt runs a prompt and returns the changes of the agent as diffs. Unless
+// params.Apply is set the changed files are restored, so the plugin applies
+// the diffs to its buffers itself.
+func (e *editorEngine) Prompt(ctx context.Context, params editor.PromptParams) (editor.PromptResult, error) {
+	sessionID := params.SessionID
+	if sessionID == "" {
+		const maxTitleLength = 100
+		title := params.Prompt
+		if runes := []rune(title); len(runes) > maxTitleLength {
+			title = string(runes[:maxTitleLength]) + "..."
+		}
+		id, err := e.NewSession(ctx, "Editor: "+title)
+		if err != nil {
+			return editor.PromptResult{}, err
+		}
+		sessionID = id
+	} else if _, err := e.app.Sessions.Get(ctx, sessionID); err != nil {
+		return editor.PromptResult{}, fmt.Errorf("unknown session %s", sessionID)
+	}
+	e.approve(sessionID)
+
+	content := params.Prompt
+	for _, buffer := range params.Context {
+		content += "\n\n" + editor.FormatBuffer(buffer)
+	}
+
+	before, err := e.latestFiles(ctx, sessionID)
+	if err != nil {
+		return editor.PromptResult{}, err
+	}
+	existed := make(map[string]bool, len(before))
+	for path := range before {
+		_, err := os.Lstat(path)
+		existed[path] = err == nil
+	}
+	done, err := e.app.CoderAgent.Run(ctx, sessionID, content)
+	if err != nil {
+		return editor.PromptResult{}, err
+	}
+	result := <-done
+	if result.Error != nil {
+		return editor.PromptResult{}, result.Error
+	}
+
+	diffs, err := e.changes(ctx, sessionID, before, existed, params.Apply)
+	if err != nil {
+		return editor.PromptResult{}, err
+	}
+	run := editor.PromptResult{
+		SessionID: sessionID,
+		Response:  result.Message.Content().String(),
+		Diffs:     diffs,
+		Applied:   params.Apply,
+	}
+	if sess, err := e.app.Sessions.Get(ctx, sessionID); err == nil {
+		run.PromptTokens = sess.PromptTokens
+		run.CompletionTokens = sess.CompletionTokens
+		run.Cost = sess.Cost
+	}
+	return run, nil
+}
+
+func (e *editorEngine) Cancel(sessionID string) {
+	e.app.CoderAgent.Cancel(sessionID)
+}
+
+// latestFiles returns the latest content of the files in the history of the
+// session by path
+func (e *editorEngine) latestFiles(ctx context.Context, sessionID string) (map[string]string, error) {
+	files, err := e.app.History.ListLatestSessionFiles(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[string]string, len(files))
+	for _, file := range files {
+		latest[file.Path] = file.Content
+	}
+	return latest, nil
+}
+
+// changes diffs the files of the session changed since before, the content of
+// the files before the run, existed tells which of them were on disk. Unless
+// apply is set they are restored on disk and in the history of the session.
+func (e *editorEngine) changes(ctx context.Context, sessionID string, before map[string]string, existed map[string]bool, apply bool) ([]editor.FileDiff, error) {
+	latest, err := e.latestFiles(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	files, err := e.app.History.ListBySession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	original := make(map[string]string)
+	for _, file := range files {
+		if file.Version == history.InitialVersion {
+			original[file.Path] = file.Content
+		}
+	}
+	paths := slices.Sorted(maps.Keys(latest))
+
+	wd := config.WorkingDirectory()
+	diffs := []editor.FileDiff{}
+	var errs []error
+	for _, path := range paths {
+		content := latest[path]
+		old, ok := before[path]
+		onDisk := existed[path]
+		if !ok {
+			// First changed in this run
+			old = original[path]
+			var known bool
+			if onDisk, known = e.app.History.Existed(sessionID, path); !known {
+				onDisk = old != ""
+			}
+		}
+		if old == content {
+			continue
+		}
+		unified, additions, removals := diff.GenerateDiff(old, content, path)
+		rel, err := filepath.Rel(wd, path)
+		if err != nil {
+			rel = path
+		}
+		diffs = append(diffs, editor.FileDiff{
+			Path:      rel,
+			Diff:      unified,
+			Content:   content,
+			Additions: additions,
+			Removals:  removals,
+		})
+
+		if apply {
+			continue
+		}
+		// A file not on disk was created in this run
+		restored, err := e.app.restoreFile(ctx, sessionID, path, old, !onDisk)
+		switch {
+		case !restored:
+			errs = append(errs, fmt.Errorf("failed to restore %s: %w", rel, err))
+		case err != nil:
+			logging.Warn("Failed to record the restored file", "path", path, "error", err)
+		}
+	}
+	return diffs, errors.Join(errs...)
+}
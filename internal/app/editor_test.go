@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/history"
+	"github.com/kirmad/superopencode/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditorChangesRestoresFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	_, err := config.Load(dir, false)
+	require.NoError(t, err)
+	conn, err := db.ConnectEphemeral()
+	require.NoError(t, err)
+	defer conn.Close()
+	q := db.New(conn)
+	app := &App{Sessions: session.NewService(q), History: history.NewService(q, conn, nil)}
+	sess, err := app.Sessions.Create(ctx, "editor")
+	require.NoError(t, err)
+
+	// The run fills an empty file and creates a new one, the way the file
+	// tools do
+	empty, created := filepath.Join(dir, "empty.txt"), filepath.Join(dir, "new.txt")
+	require.NoError(t, os.WriteFile(empty, nil, 0o644))
+	for _, path := range []string{empty, created} {
+		_, err := app.History.Snapshot(ctx, sess.ID, path)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, []byte("written\n"), 0o644))
+		_, err = app.History.Create(ctx, sess.ID, path, "")
+		require.NoError(t, err)
+		_, err = app.History.CreateVersion(ctx, sess.ID, path, "written\n")
+		require.NoError(t, err)
+	}
+
+	e := &editorEngine{app: app}
+	diffs, err := e.changes(ctx, sess.ID, map[string]string{}, map[string]bool{}, false)
+	require.NoError(t, err)
+	assert.Len(t, diffs, 2)
+
+	content, err := os.ReadFile(empty)
+	require.NoError(t, err, "the empty file is restored, not deleted")
+	assert.Empty(t, content)
+	assert.NoFileExists(t, created)
+}
@@ -0,0 +1,347 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/errorpattern"
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+const (
+	// maxResolutionActions is the number of actions between an error and the
+	// success of its retry kept as what fixed it
+	maxResolutionActions = 5
+	// maxPendingCalls is the number of tool calls after which an error whose
+	// retry never succeeded is dropped
+	maxPendingCalls = 30
+)
+
+// Tool results that aren't failures of the tool
+var ignoredToolErrors = []string{
+	"Permission denied",
+	"Tool execution canceled by user",
+}
+
+// exitCodePattern matches the exit code bash appends to the output of
+// commands that failed
+var exitCodePattern = regexp.MustCompile(`(?m)^(Exit code [1-9]\d*|Command was aborted before completion)$`)
+
+// pendingError is an error waiting for the retry that fixes it
+type pendingError struct {
+	id      string
+	name    string
+	key     string
+	calls   int      // Tool calls made since the error
+	actions []string // What was done since the error, like the files edited
+}
+
+// learningMessages learns from the tool results of the sessions which tool
+// errors recur and what fixed them, and tells the agent when an error
+// recurs
+type learningMessages struct {
+	message.Service
+	patterns errorpattern.Service
+
+	mu        sync.Mutex
+	toolCalls map[string]message.ToolCall // Tool call ID -> call, until its result
+	pending   map[string][]*pendingError  // Session ID -> errors not fixed yet
+}
+
+func newLearningMessages(messages message.Service, patterns errorpattern.Service) *learningMessages {
+	return &learningMessages{
+		Service:   messages,
+		patterns:  patterns,
+		toolCalls: make(map[string]message.ToolCall),
+		pending:   make(map[string][]*pendingError),
+	}
+}
+
+func (m *learningMessages) Update(ctx context.Context, msg message.Message) error {
+	if msg.Role == message.Assistant && config.Get().ErrorPatterns.Enabled {
+		m.mu.Lock()
+		for _, call := range msg.ToolCalls() {
+			if call.Finished {
+				m.toolCalls[call.ID] = call
+			}
+		}
+		m.mu.Unlock()
+	}
+	return m.Service.Update(ctx, msg)
+}
+
+func (m *learningMessages) Create(ctx context.Context, sessionID string, params message.CreateMessageParams) (message.Message, error) {
+	if params.Role == message.Tool && config.Get().ErrorPatterns.Enabled {
+		for i, part := range params.Parts {
+			result, ok := part.(message.ToolResult)
+			if !ok {
+				continue
+			}
+			m.mu.Lock()
+			call, ok := m.toolCalls[result.ToolCallID]
+			delete(m.toolCalls, result.ToolCallID)
+			m.mu.Unlock()
+			if !ok {
+				continue
+			}
+			if note := m.learn(ctx, sessionID, call, result); note != "" {
+				result.Content += "\n\n<system-reminder>" + note + "</system-reminder>"
+				params.Parts[i] = result
+			}
+		}
+	}
+	return m.Service.Create(ctx, sessionID, params)
+}
+
+// learn records a failed tool call, or the fix of an earlier failure when the
+// call succeeds, and returns the note for the agent when the error recurs
+func (m *learningMessages) learn(ctx context.Context, sessionID string, call message.ToolCall, result message.ToolResult) string {
+	key := toolCallKey(call)
+	failure, failed := toolFailure(result)
+	if !failed {
+		m.succeeded(ctx, sessionID, call, key)
+		return ""
+	}
+
+	id := uuid.New().String()
+	pattern, err := m.patterns.Record(ctx, errorpattern.Occurrence{
+		ID:        id,
+		SessionID: sessionID,
+		Kind:      errorpattern.KindTool,
+		Name:      call.Name,
+		Key:       key,
+		Message:   failure,
+	})
+	if err != nil {
+		logging.Warn("Failed to record the tool error", "tool", call.Name, "error", err)
+		return ""
+	}
+	m.mu.Lock()
+	pending := m.pending[sessionID]
+	// Only the last failure of a call is fixed by its retry
+	for i, p := range pending {
+		if p.name == call.Name && p.key == key {
+			pending = append(pending[:i], pending[i+1:]...)
+			break
+		}
+	}
+	m.pending[sessionID] = append(pending, &pendingError{id: id, name: call.Name, key: key})
+	m.mu.Unlock()
+
+	if pattern.Occurrences < config.Get().ErrorPatterns.MinOccurrences {
+		return ""
+	}
+	logging.Info("Recurring tool error", "tool", call.Name, "occurrences", pattern.Occurrences, "resolved", pattern.Resolution != "")
+	if pattern.Resolution == "" {
+		return pattern.Suggestion() + " Don't retry the same thing, look for the root cause."
+	}
+	return pattern.Suggestion()
+}
+
+// succeeded stores what fixed the failures of the same call, and counts the
+// call as an action for the others
+func (m *learningMessages) succeeded(ctx context.Context, sessionID string, call message.ToolCall, key string) {
+	m.mu.Lock()
+	var fixed *pendingError
+	var kept []*pendingError
+	for _, p := range m.pending[sessionID] {
+		switch {
+		case fixed == nil && p.name == call.Name && p.key == key:
+			fixed = p
+		case p.calls < maxPendingCalls:
+			p.calls++
+			if action := toolAction(call); action != "" && len(p.actions) < maxResolutionActions {
+				p.actions = append(p.actions, action)
+			}
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == 0 {
+		delete(m.pending, sessionID)
+	} else {
+		m.pending[sessionID] = kept
+	}
+	m.mu.Unlock()
+
+	if fixed == nil || len(fixed.actions) == 0 {
+		// A retry working by itself fixed nothing worth suggesting
+		return
+	}
+	resolution := strings.Join(fixed.actions, ", ")
+	if action := toolAction(call); action != "" {
+		resolution += ", after which " + strings.TrimPrefix(action, "ran ") + " worked"
+	}
+	if err := m.patterns.Resolve(ctx, fixed.id, resolution); err != nil {
+		logging.Warn("Failed to record the fix of the tool error", "tool", call.Name, "error", err)
+	}
+}
+
+// toolFailure returns the line saying what went wrong of a failed tool call
+func toolFailure(result message.ToolResult) (string, bool) {
+	content := result.Content
+	if i := strings.Index(content, "\n\n<system-reminder>"); i >= 0 {
+		content = content[:i]
+	}
+	if result.IsError {
+		for _, ignored := range ignoredToolErrors {
+			if strings.HasPrefix(content, ignored) {
+				return "", false
+			}
+		}
+		var metadata struct {
+			Kind string `json:"kind"`
+		}
+		if json.Unmarshal([]byte(result.Metadata), &metadata) == nil && metadata.Kind == "paused" {
+			return "", false
+		}
+		return errorpattern.Salient(content), true
+	}
+	if loc := exitCodePattern.FindStringIndex(content); loc != nil {
+		failure := errorpattern.Salient(content[:loc[0]])
+		if failure == "" {
+			failure = content[loc[0]:loc[1]]
+		}
+		return failure, true
+	}
+	return "", false
+}
+
+// toolCallKey returns what a tool call works on, telling its errors apart
+// from the errors of the other calls of the tool
+func toolCallKey(call message.ToolCall) string {
+	var params struct {
+		Command  string `json:"command"`
+		FilePath string `json:"file_path"`
+	}
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return ""
+	}
+	if call.Name == tools.BashToolName {
+		return errorpattern.CommandKey(params.Command)
+	}
+	return params.FilePath
+}
+
+// toolAction describes a tool call changing something, as part of a fix
+func toolAction(call message.ToolCall) string {
+	var params struct {
+		Command  string `json:"command"`
+		FilePath string `json:"file_path"`
+	}
+	_ = json.Unmarshal([]byte(call.Input), &params)
+	switch call.Name {
+	case tools.EditToolName, tools.WriteToolName:
+		if params.FilePath != "" {
+			return "edited " + params.FilePath
+		}
+	case tools.PatchToolName:
+		return "applied a patch"
+	case tools.BashToolName:
+		if params.Command != "" {
+			return fmt.Sprintf("ran `%s`", firstLine(params.Command, 80))
+		}
+	}
+	return ""
+}
+
+func firstLine(s string, n int) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return s
+}
+
+// learningAgent records the errors of the agent runs, such as provider
+// errors, and what model the run that worked after them used
+type learningAgent struct {
+	agent.Service
+	patterns errorpattern.Service
+
+	mu      sync.Mutex
+	pending map[string]pendingLLMError // Session ID -> error of its last run
+}
+
+type pendingLLMError struct {
+	id    string
+	model string
+}
+
+func newLearningAgent(service agent.Service, patterns errorpattern.Service) *learningAgent {
+	return &learningAgent{
+		Service:  service,
+		patterns: patterns,
+		pending:  make(map[string]pendingLLMError),
+	}
+}
+
+func (a *learningAgent) Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan agent.AgentEvent, error) {
+	events, err := a.Service.Run(ctx, sessionID, content, attachments...)
+	if err != nil || !config.Get().ErrorPatterns.Enabled {
+		return events, err
+	}
+	model := string(a.Model().ID)
+	relayed := make(chan agent.AgentEvent)
+	go func() {
+		defer close(relayed)
+		for event := range events {
+			a.learn(sessionID, model, event)
+			relayed <- event
+		}
+	}()
+	return relayed, nil
+}
+
+func (a *learningAgent) learn(sessionID, model string, event agent.AgentEvent) {
+	// Records and fixes outlive the run they are about
+	ctx := context.Background()
+	switch {
+	case event.Type == agent.AgentEventTypeError:
+		if errors.Is(event.Error, agent.ErrRequestCancelled) || errors.Is(event.Error, context.Canceled) {
+			return
+		}
+		id := uuid.New().String()
+		pattern, err := a.patterns.Record(ctx, errorpattern.Occurrence{
+			ID:        id,
+			SessionID: sessionID,
+			Kind:      errorpattern.KindLLM,
+			Name:      model,
+			Message:   errorpattern.Salient(event.Error.Error()),
+		})
+		if err != nil {
+			logging.Warn("Failed to record the agent error", "error", err)
+			return
+		}
+		a.mu.Lock()
+		a.pending[sessionID] = pendingLLMError{id: id, model: model}
+		a.mu.Unlock()
+		if pattern.Occurrences >= config.Get().ErrorPatterns.MinOccurrences {
+			logging.WarnPersist(fmt.Sprintf("%s: %s", pattern.Message, pattern.Suggestion()))
+		}
+	case event.Type == agent.AgentEventTypeResponse && event.Done && event.Error == nil:
+		a.mu.Lock()
+		failed, ok := a.pending[sessionID]
+		delete(a.pending, sessionID)
+		a.mu.Unlock()
+		if !ok {
+			return
+		}
+		resolution := "retrying worked"
+		if failed.model != model {
+			resolution = fmt.Sprintf("switching from %s to %s worked", failed.model, model)
+		}
+		if err := a.patterns.Resolve(ctx, failed.id, resolution); err != nil {
+			logging.Warn("Failed to record the fix of the agent error", "error", err)
+		}
+	}
+}
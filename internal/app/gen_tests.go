@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/coverage"
+	"github.com/kirmad/superopencode/internal/logging"
+)
+
+// maxUncoveredListed keeps the prompt focused on the least covered code
+const maxUncoveredListed = 25
+
+// TestGenReport is the outcome of the test generation workflow
+type TestGenReport struct {
+	Before   float64
+	After    float64
+	Goal     float64
+	Attempts int
+	Failed   bool // Whether tests still fail
+}
+
+func (r TestGenReport) String() string {
+	status := "goal reached"
+	switch {
+	case r.Failed:
+		status = "tests are failing"
+	case r.After < r.Goal:
+		status = fmt.Sprintf("goal of %.1f%% not reached", r.Goal)
+	}
+	return fmt.Sprintf("Coverage %.1f%% → %.1f%% (%+.1f) after %d attempt(s), %s", r.Before, r.After, r.After-r.Before, r.Attempts, status)
+}
+
+// GenerateTests lets the coder agent write tests for the uncovered code of
+// target, measuring the coverage after every round. It stops once the
+// configured coverage gain is reached or the attempts are used up.
+func (app *App) GenerateTests(ctx context.Context, sessionID, target string) (TestGenReport, error) {
+	cfg := config.Get().GenTests
+	dir := config.WorkingDirectory()
+
+	current, err := coverage.Measure(ctx, dir, target)
+	if err != nil {
+		return TestGenReport{}, err
+	}
+	report := TestGenReport{
+		Before: current.Total,
+		After:  current.Total,
+		Goal:   min(100, current.Total+cfg.TargetDelta),
+		Failed: current.Failed,
+	}
+	logging.Info("Generating tests", "target", target, "coverage", current.Total, "goal", report.Goal)
+
+	for report.Attempts < cfg.MaxAttempts {
+		if !current.Failed && (current.Total >= report.Goal || len(current.Uncovered) == 0) {
+			break
+		}
+		report.Attempts++
+		done, err := app.CoderAgent.Run(ctx, sessionID, testGenPrompt(target, current, report))
+		if err != nil {
+			return report, err
+		}
+		if result := <-done; result.Error != nil {
+			return report, result.Error
+		}
+
+		if current, err = coverage.Measure(ctx, dir, target); err != nil {
+			return report, err
+		}
+		report.After = current.Total
+		report.Failed = current.Failed
+	}
+	return report, nil
+}
+
+// testGenPrompt asks the agent for the tests of one round
+func testGenPrompt(target string, current coverage.Report, report TestGenReport) string {
+	var sb strings.Builder
+	scope := "the project"
+	if target != "" {
+		scope = target
+	}
+	fmt.Fprintf(&sb, "<gen-tests>\nTest coverage of %s is %.1f%%, the goal is %.1f%% (attempt %d).\n", scope, current.Total, report.Goal, report.Attempts)
+
+	if current.Failed {
+		fmt.Fprintf(&sb, "\nThe tests are failing:\n%s\n", current.Output)
+	}
+	if len(current.Uncovered) > 0 {
+		sb.WriteString("\nLeast covered code:\n")
+		for i, fn := range current.Uncovered {
+			if i == maxUncoveredListed {
+				fmt.Fprintf(&sb, "... and %d more\n", len(current.Uncovered)-i)
+				break
+			}
+			name := fn.Name
+			if name == "" {
+				name = "missing lines from here"
+			}
+			fmt.Fprintf(&sb, "- %s:%d %s (%.1f%%)\n", fn.File, fn.Line, name, fn.Percent)
+		}
+	}
+	sb.WriteString(`
+Write tests for the least covered code first, prioritizing behavior that matters over trivial getters. Follow the test conventions of the project: read the existing tests of the package first and put the new tests where the project keeps them. Run the tests you wrote and make them pass.
+Only change test files. If a test reveals a bug in the code, leave the code alone, skip the test with a note and report the bug in your answer.
+</gen-tests>`)
+	return sb.String()
+}
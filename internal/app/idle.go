@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/prompt"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/lsp"
+)
+
+// idleCheckInterval is how often idle sessions and LSP servers are looked for
+const idleCheckInterval = time.Minute
+
+// idleMonitor tracks the interactions with the app and its sessions to
+// release the memory they hold while they aren't used
+type idleMonitor struct {
+	mu           sync.Mutex
+	ctx          context.Context
+	lastActive   time.Time
+	current      string               // Session the user works in, never released
+	sessions     map[string]time.Time // Session -> last interaction, until released
+	suspendedLSP []string
+}
+
+func newIdleMonitor() *idleMonitor {
+	return &idleMonitor{
+		ctx:        context.Background(),
+		lastActive: time.Now(),
+		sessions:   make(map[string]time.Time),
+	}
+}
+
+// Touch records an interaction of the user with a session. LSP servers
+// suspended while the app was idle are started again.
+func (app *App) Touch(sessionID string) {
+	m := app.idle
+	m.mu.Lock()
+	m.lastActive = time.Now()
+	switched := sessionID != "" && sessionID != m.current
+	if sessionID != "" {
+		m.current = sessionID
+		m.sessions[sessionID] = m.lastActive
+	}
+	suspended := m.suspendedLSP
+	m.suspendedLSP = nil
+	ctx := m.ctx
+	m.mu.Unlock()
+
+	// The TUI shows whether another process drives the session
+	if switched {
+		app.Locks.Watch(sessionID)
+	}
+
+	if len(suspended) > 0 {
+		go app.resumeLSPClients(ctx, suspended)
+	}
+}
+
+func (app *App) watchIdle(ctx context.Context) {
+	defer logging.RecoverPanic("idle-monitor", nil)
+	app.idle.mu.Lock()
+	app.idle.ctx = ctx
+	app.idle.mu.Unlock()
+
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			app.checkIdle(now)
+		}
+	}
+}
+
+// checkIdle moves the pinned context of the background sessions idle for
+// idle.releaseSessionsMinutes to disk, and stops the LSP servers when the
+// app was idle for idle.suspendLSPMinutes
+func (app *App) checkIdle(now time.Time) {
+	cfg := config.Get().Idle
+	releaseAfter := time.Duration(cfg.ReleaseSessionsMinutes) * time.Minute
+	suspendAfter := time.Duration(cfg.SuspendLSPMinutes) * time.Minute
+
+	m := app.idle
+	m.mu.Lock()
+	if app.CoderAgent.IsBusy() {
+		m.lastActive = now
+	}
+	var release []string
+	for sessionID, last := range m.sessions {
+		switch {
+		case app.CoderAgent.IsSessionBusy(sessionID):
+			m.sessions[sessionID] = now
+		case sessionID != m.current && releaseAfter > 0 && now.Sub(last) >= releaseAfter:
+			release = append(release, sessionID)
+			delete(m.sessions, sessionID)
+		}
+	}
+	suspend := suspendAfter > 0 && len(m.suspendedLSP) == 0 && now.Sub(m.lastActive) >= suspendAfter
+	m.mu.Unlock()
+
+	dir := filepath.Join(config.Get().Data.Directory, "released")
+	for _, sessionID := range release {
+		if err := prompt.ReleasePins(sessionID, dir); err != nil {
+			logging.Warn("Failed to release idle session", "session_id", sessionID, "error", err)
+			continue
+		}
+		logging.Debug("Released idle session", "session_id", sessionID)
+	}
+
+	if suspend {
+		if names := app.suspendLSPClients(); len(names) > 0 {
+			m.mu.Lock()
+			m.suspendedLSP = names
+			m.mu.Unlock()
+		}
+	}
+}
+
+// suspendLSPClients stops the LSP servers of the config and returns their
+// names. Copilot is left running, it takes long to start.
+func (app *App) suspendLSPClients() []string {
+	cfg := config.Get()
+	clients := make(map[string]*lsp.Client)
+	app.clientsMutex.Lock()
+	for name, client := range app.LSPClients {
+		if _, ok := cfg.LSP[name]; ok {
+			clients[name] = client
+			delete(app.LSPClients, name)
+		}
+	}
+	app.clientsMutex.Unlock()
+
+	var names []string
+	for name, client := range clients {
+		app.cancelFuncsMutex.Lock()
+		if cancel, ok := app.watcherCancelFuncs[name]; ok {
+			cancel()
+			delete(app.watcherCancelFuncs, name)
+		}
+		app.cancelFuncsMutex.Unlock()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := client.Shutdown(shutdownCtx); err != nil {
+			logging.Debug("Failed to shutdown idle LSP client", "name", name, "error", err)
+		}
+		cancel()
+		if err := client.Close(); err != nil {
+			logging.Debug("Failed to close idle LSP client", "name", name, "error", err)
+		}
+		names = append(names, name)
+	}
+	if len(names) > 0 {
+		logging.Info("Suspended idle LSP servers", "servers", names)
+	}
+	return names
+}
+
+// resumeLSPClients starts the suspended LSP servers again
+func (app *App) resumeLSPClients(ctx context.Context, names []string) {
+	cfg := config.Get()
+	for _, name := range names {
+		clientConfig, ok := cfg.LSP[name]
+		if !ok {
+			continue
+		}
+		logging.Info("Resuming LSP server", "name", name)
+		go app.createAndStartLSPClient(ctx, name, clientConfig.Command, clientConfig.Args...)
+	}
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+// IssueDraft is an issue written from the conversation of a session
+type IssueDraft struct {
+	Title string
+	Body  string
+	Path  string // Markdown file the draft was saved to
+	URL   string // Set once the issue was filed
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ExportIssue drafts an issue from the conversation of a session and saves
+// it to the issues directory of the data directory. With file set, the issue
+// is also created in the GitHub repository of the working directory through
+// the gh CLI.
+func (app *App) ExportIssue(ctx context.Context, sessionID, notes string, file bool) (IssueDraft, error) {
+	text, err := app.CoderAgent.DraftIssue(ctx, sessionID, notes)
+	if err != nil {
+		return IssueDraft{}, err
+	}
+	draft := ParseIssueDraft(text)
+
+	dir := filepath.Join(config.Get().Data.Directory, "issues")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return draft, fmt.Errorf("failed to create issues directory: %w", err)
+	}
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(draft.Title), "-"), "-")
+	if len(slug) > 50 {
+		slug = strings.TrimRight(slug[:50], "-")
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.md", time.Now().Format("20060102-150405"), slug))
+	if err := os.WriteFile(path, []byte("# "+draft.Title+"\n\n"+draft.Body+"\n"), 0o644); err != nil {
+		return draft, fmt.Errorf("failed to save issue draft: %w", err)
+	}
+	draft.Path = path
+
+	if !file {
+		return draft, nil
+	}
+	draft.URL, err = fileGitHubIssue(ctx, draft)
+	return draft, err
+}
+
+// ParseIssueDraft splits a markdown draft into the title, taken from its
+// first heading or line, and the body
+func ParseIssueDraft(text string) IssueDraft {
+	text = strings.TrimSpace(text)
+	first, body, _ := strings.Cut(text, "\n")
+	title := strings.TrimSpace(strings.TrimLeft(first, "# "))
+	if title == "" {
+		title = "Untitled issue"
+	}
+	return IssueDraft{Title: title, Body: strings.TrimSpace(body)}
+}
+
+// fileGitHubIssue creates the issue with the gh CLI and returns its URL
+func fileGitHubIssue(ctx context.Context, draft IssueDraft) (string, error) {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return "", fmt.Errorf("filing issues needs the GitHub CLI (gh), the draft was saved to %s", draft.Path)
+	}
+	cmd := exec.CommandContext(ctx, "gh", "issue", "create", "--title", draft.Title, "--body", draft.Body)
+	cmd.Dir = config.WorkingDirectory()
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("gh issue create: %s", strings.TrimSpace(string(out)))
+	}
+	// gh prints the URL of the new issue last
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1]), nil
+}
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIssueDraft(t *testing.T) {
+	draft := ParseIssueDraft("\n# Crash when the session is deleted\n\n## Problem\nThe TUI panics.\n")
+	assert.Equal(t, "Crash when the session is deleted", draft.Title)
+	assert.Equal(t, "## Problem\nThe TUI panics.", draft.Body)
+
+	draft = ParseIssueDraft("Title without heading\nBody")
+	assert.Equal(t, "Title without heading", draft.Title)
+	assert.Equal(t, "Body", draft.Body)
+
+	assert.Equal(t, "Untitled issue", ParseIssueDraft("#\nBody").Title)
+}
+
+func TestParsePullRequestDraft(t *testing.T) {
+	draft := ParsePullRequestDraft("# Retry failed tasks\n\n## What\nRetries tasks.\n\n## Testing\ngo test ./...")
+	assert.Equal(t, "Retry failed tasks", draft.Title)
+	assert.Equal(t, "## What\nRetries tasks.\n\n## Testing\ngo test ./...", draft.Body)
+
+	assert.Equal(t, "Untitled pull request", ParsePullRequestDraft("").Title)
+}
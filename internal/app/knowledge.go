@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/knowledge"
+	"github.com/kirmad/superopencode/internal/llm/embedding"
+	"github.com/kirmad/superopencode/internal/logging"
+)
+
+// knowledgeCheckInterval is how often the knowledge sources due for a sync
+// are looked for
+const knowledgeCheckInterval = time.Hour
+
+// SyncKnowledge syncs the knowledge sources every knowledge.refreshHours in
+// the background until ctx is done, starting with the ones never synced
+func (app *App) SyncKnowledge(ctx context.Context) {
+	go func() {
+		defer logging.RecoverPanic("knowledge-sync", nil)
+		ticker := time.NewTicker(knowledgeCheckInterval)
+		defer ticker.Stop()
+		for {
+			app.syncDueKnowledge(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (app *App) syncDueKnowledge(ctx context.Context) {
+	cfg := config.Get().Knowledge
+	if len(cfg.Sources) == 0 || cfg.RefreshHours == 0 {
+		return
+	}
+	refresh := time.Duration(cfg.RefreshHours) * time.Hour
+	var due []string
+	for name := range cfg.Sources {
+		if knowledge.Due(name, refresh) {
+			due = append(due, name)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+	index, err := knowledge.Shared()
+	if err != nil {
+		if errors.Is(err, embedding.ErrDisabled) {
+			logging.Warn("Knowledge sources need embeddings, set embedding.provider in the config")
+		} else {
+			logging.Warn("Failed to open the docs index", "error", err)
+		}
+		return
+	}
+	slices.Sort(due)
+	for _, name := range due {
+		report, err := index.Sync(ctx, name, cfg.Sources[name])
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logging.Warn("Failed to sync knowledge source", "source", name, "error", err)
+			continue
+		}
+		logging.Info("Synced knowledge source", "source", name, "pages", report.Pages,
+			"updated", report.Updated, "removed", report.Removed, "duration", report.Duration.Round(time.Second))
+	}
+}
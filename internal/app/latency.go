@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/metrics"
+)
+
+// recordLatencies stores the time to the first token of the turns of the
+// coder agent in the metrics
+func (app *App) recordLatencies(ctx context.Context) {
+	defer logging.RecoverPanic("latency-recorder", nil)
+	for event := range app.CoderAgent.Subscribe(ctx) {
+		payload := event.Payload
+		if payload.Type != agent.AgentEventTypeLatency {
+			continue
+		}
+		err := app.Metrics.RecordLatency(ctx, metrics.Latency{
+			ID:         payload.MessageID,
+			SessionID:  payload.SessionID,
+			Model:      string(payload.Model),
+			FirstToken: payload.FirstToken,
+		})
+		if err != nil {
+			logging.Warn("Failed to record the first token latency", "error", err)
+		}
+	}
+}
+
+// Warmup connects to the provider of the coder agent ahead of the next turn,
+// when latency.warmup is enabled
+func (app *App) Warmup() {
+	go func() {
+		defer logging.RecoverPanic("provider-warmup", nil)
+		app.CoderAgent.Warmup(context.Background())
+	}()
+}
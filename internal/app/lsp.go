@@ -81,7 +81,7 @@ func (app *App) createAndStartLSPClient(ctx context.Context, name string, comman
 
 	// Store the cancel function to be called during cleanup
 	app.cancelFuncsMutex.Lock()
-	app.watcherCancelFuncs = append(app.watcherCancelFuncs, cancelFunc)
+	app.watcherCancelFuncs[name] = cancelFunc
 	app.cancelFuncsMutex.Unlock()
 
 	// Add the watcher to a WaitGroup to track active goroutines
@@ -197,7 +197,7 @@ func (app *App) createAndStartCopilotClient(ctx context.Context) {
 
 	// Store the cancel function to be called during cleanup
 	app.cancelFuncsMutex.Lock()
-	app.watcherCancelFuncs = append(app.watcherCancelFuncs, cancelFunc)
+	app.watcherCancelFuncs["copilot"] = cancelFunc
 	app.cancelFuncsMutex.Unlock()
 
 	// Add the watcher to a WaitGroup to track active goroutines
@@ -0,0 +1,295 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/fileutil"
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/metrics"
+	"github.com/kirmad/superopencode/internal/migration"
+)
+
+// maxVerifyOutput is the tail of a failing verify command shown to the agent
+const maxVerifyOutput = 4000
+
+// MigrationsDir is where the migration plans are kept
+func MigrationsDir() string {
+	return filepath.Join(config.Get().Data.Directory, "migrations")
+}
+
+// PlanMigration splits the files matching pattern into batches and saves the
+// plan. Each batch becomes a todo of the session.
+func (app *App) PlanMigration(sessionID, pattern, instructions string) (migration.Plan, error) {
+	wd := config.WorkingDirectory()
+	matches, _, err := fileutil.GlobWithDoublestar(pattern, wd, 0)
+	if err != nil {
+		return migration.Plan{}, err
+	}
+	files := make([]string, 0, len(matches))
+	for _, match := range matches {
+		rel, err := filepath.Rel(wd, match)
+		if err != nil {
+			continue
+		}
+		files = append(files, filepath.ToSlash(rel))
+	}
+	if len(files) == 0 {
+		return migration.Plan{}, fmt.Errorf("no files match %s", pattern)
+	}
+	// Files of a directory go to the same batch where possible
+	sort.Strings(files)
+
+	cfg := config.Get().Migration
+	id := time.Now().Format("20060102-150405")
+	plan := migration.New(id, pattern, instructions, cfg.VerifyCommand, files, cfg.BatchSize)
+	if err := migration.Save(MigrationsDir(), plan); err != nil {
+		return plan, err
+	}
+	addMigrationTodos(sessionID, plan)
+	return plan, nil
+}
+
+// RunMigration migrates the pending and failed batches of a plan, several at
+// a time, each by a migrator subagent in its own git worktree. The changes
+// of a batch are applied to the working directory once its verify command
+// passes. Progress is saved after every batch, so an interrupted migration
+// resumes where it stopped.
+func (app *App) RunMigration(ctx context.Context, sessionID, id string) (migration.Plan, error) {
+	dir := MigrationsDir()
+	plan, err := migration.Load(dir, id)
+	if err != nil {
+		return plan, err
+	}
+	wd := config.WorkingDirectory()
+	if _, err := exec.LookPath("git"); err != nil {
+		return plan, errors.New("migrations need git for their worktrees")
+	}
+	addMigrationTodos(sessionID, plan)
+
+	var pending []int
+	for i, batch := range plan.Batches {
+		if batch.Status != migration.StatusDone {
+			pending = append(pending, i)
+		}
+	}
+
+	var mu sync.Mutex // Guards the plan and the working directory
+	update := func(i int, apply func(*migration.Batch)) {
+		mu.Lock()
+		defer mu.Unlock()
+		apply(&plan.Batches[i])
+		if err := migration.Save(dir, plan); err != nil {
+			logging.Error("Failed to save migration progress", "migration", plan.ID, "error", err)
+		}
+	}
+
+	sem := make(chan struct{}, max(config.Get().Migration.Parallel, 1))
+	var wg sync.WaitGroup
+	for _, i := range pending {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		// The task session is created and auto approved up front: approvals
+		// aren't safe to add concurrently
+		taskSession, err := app.Sessions.CreateTaskSession(ctx, uuid.New().String(), sessionID, fmt.Sprintf("Migration batch %d", plan.Batches[i].Number))
+		if err != nil {
+			<-sem
+			wg.Wait()
+			return plan, fmt.Errorf("error creating migration session: %w", err)
+		}
+		app.Permissions.AutoApproveSession(taskSession.ID)
+
+		update(i, func(b *migration.Batch) { b.Status, b.Error = migration.StatusRunning, "" })
+		tools.SetTodoStatus(sessionID, batchTodoID(plan, plan.Batches[i]), "in_progress")
+		wg.Add(1)
+		go func(i int, batch migration.Batch) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			summary, patch, err := app.migrateBatch(ctx, plan, batch, taskSession.ID, sessionID)
+			if err == nil && patch != "" {
+				mu.Lock()
+				err = migration.Apply(ctx, wd, patch)
+				mu.Unlock()
+				if err != nil {
+					err = fmt.Errorf("the changes conflict with the working directory, the patch was saved to the plan: %w", err)
+				}
+			}
+			update(i, func(b *migration.Batch) {
+				b.Summary = summary
+				if err != nil {
+					b.Status, b.Error, b.Patch = migration.StatusFailed, err.Error(), patch
+					return
+				}
+				b.Status, b.Patch = migration.StatusDone, ""
+			})
+
+			todoStatus := "completed"
+			if err != nil {
+				todoStatus = "pending"
+				logging.Warn("Migration batch failed", "migration", plan.ID, "batch", batch.Number, "error", err)
+			}
+			tools.SetTodoStatus(sessionID, batchTodoID(plan, batch), todoStatus)
+			mu.Lock()
+			done, _ := plan.Progress()
+			mu.Unlock()
+			logging.InfoPersist(fmt.Sprintf("Migration %s: batch %d finished, %d/%d done", plan.ID, batch.Number, done, len(plan.Batches)))
+		}(i, plan.Batches[i])
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		// Batches that didn't finish are picked up again on resume
+		for i := range plan.Batches {
+			if plan.Batches[i].Status == migration.StatusRunning {
+				plan.Batches[i].Status = migration.StatusPending
+			}
+		}
+		migration.Save(dir, plan)
+		return plan, ctx.Err()
+	}
+	return plan, nil
+}
+
+// migrateBatch runs a migrator subagent over a batch in a fresh worktree and
+// returns its report and the changes it made
+func (app *App) migrateBatch(ctx context.Context, plan migration.Plan, batch migration.Batch, taskSessionID, sessionID string) (string, string, error) {
+	wd := config.WorkingDirectory()
+	worktree, err := os.MkdirTemp("", fmt.Sprintf("opencode-migration-%s-%d-*", plan.ID, batch.Number))
+	if err != nil {
+		return "", "", err
+	}
+	os.Remove(worktree) // git creates the directory
+	if err := migration.AddWorktree(ctx, wd, worktree); err != nil {
+		return "", "", err
+	}
+	defer migration.RemoveWorktree(wd, worktree)
+
+	migrator, err := agent.NewAgent(config.AgentMigrator, app.Sessions, app.Messages, app.Quotas, agent.MigratorAgentTools(app.Permissions, app.History, app.LSPClients))
+	if err != nil {
+		return "", "", fmt.Errorf("error creating migrator: %w", err)
+	}
+
+	started := time.Now()
+	status := metrics.StatusFailed
+	defer func() { app.recordSubagentTask(agent.SubagentMigration, taskSessionID, sessionID, status, started) }()
+
+	prompt := migrationPrompt(plan, batch, worktree)
+	var summary string
+	for attempt := 0; ; attempt++ {
+		done, err := migrator.Run(ctx, taskSessionID, prompt)
+		if err != nil {
+			return summary, "", fmt.Errorf("error running migrator: %w", err)
+		}
+		result := <-done
+		if ctx.Err() != nil {
+			status = metrics.StatusCanceled
+			return summary, "", ctx.Err()
+		}
+		if result.Error != nil {
+			return summary, "", fmt.Errorf("error running migrator: %w", result.Error)
+		}
+		if result.Message.Role == message.Assistant {
+			summary = strings.TrimSpace(result.Message.Content().String())
+		}
+
+		output, err := verifyBatch(ctx, plan.Verify, worktree)
+		if err == nil {
+			break
+		}
+		if attempt >= config.Get().Migration.MaxFixAttempts {
+			return summary, "", fmt.Errorf("%s failed: %s", plan.Verify, output)
+		}
+		prompt = fmt.Sprintf("The verify command `%s` fails in the worktree after your changes:\n\n```\n%s\n```\n\nFix the failures within the files of your batch, then report again.", plan.Verify, output)
+	}
+
+	if err := app.addSessionCost(ctx, taskSessionID, sessionID); err != nil {
+		return summary, "", err
+	}
+	patch, err := migration.Diff(ctx, worktree)
+	if err != nil {
+		return summary, "", err
+	}
+	status = metrics.StatusDone
+	return summary, patch, nil
+}
+
+// verifyBatch runs the verify command of a plan in a worktree
+func verifyBatch(ctx context.Context, command, worktree string) (string, error) {
+	if command == "" {
+		return "", nil
+	}
+	shell := config.Get().Shell.Path
+	if shell == "" {
+		shell = "/bin/bash"
+	}
+	cmd := exec.CommandContext(ctx, shell, "-c", command)
+	cmd.Dir = worktree
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if len(output) > maxVerifyOutput {
+		output = "..." + output[len(output)-maxVerifyOutput:]
+	}
+	return output, err
+}
+
+func migrationPrompt(plan migration.Plan, batch migration.Batch, worktree string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Migration: %s\n\n", plan.Instructions)
+	fmt.Fprintf(&sb, "Your worktree is %s. Migrate these files of batch %d of %d:\n", worktree, batch.Number, len(plan.Batches))
+	for _, file := range batch.Files {
+		fmt.Fprintf(&sb, "- %s\n", filepath.Join(worktree, file))
+	}
+	if plan.Verify != "" {
+		fmt.Fprintf(&sb, "\nAfter your changes, `%s` must pass in the worktree.\n", plan.Verify)
+	}
+	return sb.String()
+}
+
+// addMigrationTodos adds the unfinished batches of a plan to the todo list of
+// a session, unless they are on it already
+func addMigrationTodos(sessionID string, plan migration.Plan) {
+	var todos []tools.TodoItem
+	for _, batch := range plan.Batches {
+		id := batchTodoID(plan, batch)
+		if batch.Status == migration.StatusDone || tools.SetTodoStatus(sessionID, id, "pending") {
+			continue
+		}
+		todos = append(todos, tools.TodoItem{
+			ID:       id,
+			Content:  fmt.Sprintf("Migrate batch %d/%d: %s", batch.Number, len(plan.Batches), summarizeFiles(batch.Files)),
+			Status:   "pending",
+			Priority: "medium",
+		})
+	}
+	tools.AddTodos(sessionID, todos)
+}
+
+func batchTodoID(plan migration.Plan, batch migration.Batch) string {
+	return fmt.Sprintf("migration-%s-%d", plan.ID, batch.Number)
+}
+
+func summarizeFiles(files []string) string {
+	if len(files) <= 3 {
+		return strings.Join(files, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(files[:3], ", "), len(files)-3)
+}
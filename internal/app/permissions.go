@@ -0,0 +1,37 @@
+package app
+
+import (
+	"time"
+
+	"github.com/kirmad/superopencode/internal/detailed_logging"
+	"github.com/kirmad/superopencode/internal/permission"
+)
+
+// minPermissionWait tells the requests the user answered from the ones
+// approved right away by the session or a persistent grant
+const minPermissionWait = 50 * time.Millisecond
+
+// loggedPermissions records in the detailed logs how long tools waited for
+// the user to answer their permission requests
+type loggedPermissions struct {
+	permission.Service
+	logger *detailed_logging.DetailedLogger
+}
+
+func (p loggedPermissions) Request(opts permission.CreatePermissionRequest) bool {
+	start := time.Now()
+	granted := p.Service.Request(opts)
+	end := time.Now()
+	if end.Sub(start) >= minPermissionWait {
+		p.logger.LogPermissionWait(&detailed_logging.PermissionWaitLog{
+			ID:            detailed_logging.NewID(),
+			ChatSessionID: opts.SessionID,
+			ToolName:      opts.ToolName,
+			StartTime:     start,
+			EndTime:       end,
+			DurationMs:    end.Sub(start).Milliseconds(),
+			Granted:       granted,
+		})
+	}
+	return granted
+}
@@ -0,0 +1,344 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/diff"
+	"github.com/kirmad/superopencode/internal/history"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+// memoryFiles are the names of the project memory file, the first one is
+// created when none exists
+var memoryFiles = []string{"OpenCode.md", "opencode.md", "OPENCODE.md"}
+
+// postMortemsHeading is the section of the memory file post-mortems are
+// appended to
+const postMortemsHeading = "## Post-mortems"
+
+// FileChange is a file changed by a turn
+type FileChange struct {
+	Path      string
+	Before    string // Content before the turn
+	After     string // Content after the turn
+	Created   bool   // The file didn't exist before the turn
+	Changed   bool   // The file changed on disk since the turn
+	Deleted   bool   // The file doesn't exist after the change
+	Additions int
+	Removals  int
+}
+
+// TurnCommand is a command run by a turn
+type TurnCommand struct {
+	Command string
+	Failed  bool
+}
+
+// TurnReport is everything a turn of a session changed: the files it wrote
+// and the commands it ran
+type TurnReport struct {
+	SessionID string
+	Turn      int // From 1
+	Turns     int // Turns of the session
+	Prompt    string
+	Time      time.Time
+	Files     []FileChange
+	Commands  []TurnCommand
+}
+
+// ReportTurn lists what a turn of a session changed, the last turn when turn
+// is 0. Files are compared to their versions in the history of the session,
+// commands are taken from the bash calls of the turn.
+func (app *App) ReportTurn(ctx context.Context, sessionID string, turn int) (TurnReport, error) {
+	if sessionID == "" {
+		return TurnReport{}, errors.New("no session selected")
+	}
+	msgs, err := app.Messages.List(ctx, sessionID)
+	if err != nil {
+		return TurnReport{}, err
+	}
+	var turns []int // Index of the user message starting each turn
+	for i, msg := range msgs {
+		if msg.Role == message.User {
+			turns = append(turns, i)
+		}
+	}
+	if len(turns) == 0 {
+		return TurnReport{}, errors.New("the session has no turns yet")
+	}
+	if turn <= 0 {
+		turn = len(turns)
+	}
+	if turn > len(turns) {
+		return TurnReport{}, fmt.Errorf("the session has %d turns", len(turns))
+	}
+
+	first := turns[turn-1]
+	last := len(msgs)
+	end := int64(math.MaxInt64)
+	if turn < len(turns) {
+		last = turns[turn]
+		end = msgs[last].CreatedAt
+	}
+	start := msgs[first].CreatedAt
+	report := TurnReport{
+		SessionID: sessionID,
+		Turn:      turn,
+		Turns:     len(turns),
+		Prompt:    msgs[first].Content().String(),
+		Time:      time.Unix(start, 0),
+		Commands:  turnCommands(msgs[first:last]),
+	}
+
+	files, err := app.History.ListBySession(ctx, sessionID)
+	if err != nil {
+		return report, err
+	}
+	report.Files = turnFileChanges(files, start, end)
+	return report, nil
+}
+
+// turnCommands returns the commands run by the bash calls of the messages
+// of a turn
+func turnCommands(msgs []message.Message) []TurnCommand {
+	results := make(map[string]message.ToolResult)
+	for _, msg := range msgs {
+		for _, result := range msg.ToolResults() {
+			results[result.ToolCallID] = result
+		}
+	}
+	var commands []TurnCommand
+	for _, msg := range msgs {
+		for _, call := range msg.ToolCalls() {
+			if call.Name != tools.BashToolName {
+				continue
+			}
+			var params tools.BashParams
+			if json.Unmarshal([]byte(call.Input), &params) != nil || params.Command == "" {
+				continue
+			}
+			result, ok := results[call.ID]
+			_, failed := toolFailure(result)
+			commands = append(commands, TurnCommand{
+				Command: params.Command,
+				Failed:  ok && failed,
+			})
+		}
+	}
+	return commands
+}
+
+// turnFileChanges compares the content of the files before and after the
+// versions of the history created between start and end
+func turnFileChanges(files []history.File, start, end int64) []FileChange {
+	var changes []FileChange
+	for path, versions := range versionsByPath(files) {
+		var before, after *history.File
+		inTurn := false
+		for i := range versions {
+			version := &versions[i]
+			switch {
+			case version.CreatedAt < start:
+				before = version
+			case version.CreatedAt < end:
+				if !inTurn && before == nil {
+					// The first version holds the content before the
+					// first change of the session
+					before = version
+				}
+				inTurn = true
+				after = version
+			}
+		}
+		if !inTurn || before.Content == after.Content {
+			continue
+		}
+		change := FileChange{
+			Path:    path,
+			Before:  before.Content,
+			After:   after.Content,
+			Created: before.Version == history.InitialVersion && before.Content == "" && before.CreatedAt >= start,
+		}
+		change.Changed = changedOnDisk(path, change.After)
+		_, change.Additions, change.Removals = diff.GenerateDiff(change.Before, change.After, path)
+		changes = append(changes, change)
+	}
+	slices.SortFunc(changes, func(a, b FileChange) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+	return changes
+}
+
+// changedOnDisk tells if the file at path no longer holds content, a missing
+// file holding no content
+func changedOnDisk(path, content string) bool {
+	current, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return content != ""
+	case err == nil:
+		return string(current) != content
+	}
+	return false
+}
+
+// versionsByPath groups the versions of the files of a session by path, in
+// the order they were created
+func versionsByPath(files []history.File) map[string][]history.File {
+	byPath := make(map[string][]history.File)
+	for _, file := range files {
+		byPath[file.Path] = append(byPath[file.Path], file)
+	}
+	for _, versions := range byPath {
+		// Versions created within the same second are ordered by number
+		slices.SortStableFunc(versions, func(a, b history.File) int {
+			return cmp.Or(cmp.Compare(a.CreatedAt, b.CreatedAt), cmp.Compare(versionNumber(a.Version), versionNumber(b.Version)))
+		})
+	}
+	return byPath
+}
+
+// versionNumber orders the versions of a file: the initial version, then v1,
+// v2 and so on
+func versionNumber(version string) int {
+	if version == history.InitialVersion {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return math.MaxInt
+	}
+	return n
+}
+
+// ErrChangedSinceTurn is returned for the files changed on disk since the
+// turn, which are only rolled back when the caller confirms it
+var ErrChangedSinceTurn = errors.New("changed since the turn, confirm to overwrite the changes")
+
+// RollbackTurn brings the files of a turn at paths back to their content
+// before the turn, and removes the ones it created. The files changed on
+// disk since the turn are skipped unless they're in overwrite, as the
+// rollback would lose the changes. The restored content becomes the latest
+// version of the files in the history of the session. It returns the files
+// rolled back.
+func (app *App) RollbackTurn(ctx context.Context, report TurnReport, paths, overwrite []string) ([]string, error) {
+	var rolledBack []string
+	var errs []error
+	for _, change := range report.Files {
+		if !slices.Contains(paths, change.Path) {
+			continue
+		}
+		// The report may be older than the last changes to the file
+		if (change.Changed || changedOnDisk(change.Path, change.After)) && !slices.Contains(overwrite, change.Path) {
+			errs = append(errs, fmt.Errorf("%s: %w", change.Path, ErrChangedSinceTurn))
+			continue
+		}
+		restored, err := app.restoreFile(ctx, report.SessionID, change.Path, change.Before, change.Created)
+		if restored {
+			rolledBack = append(rolledBack, change.Path)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", change.Path, err))
+		}
+	}
+	return rolledBack, errors.Join(errs...)
+}
+
+// WritePostMortem has the agent summarize what went wrong in a turn and
+// appends the summary to the project memory file, so the next sessions learn
+// from it. It returns the summary and the path of the memory file.
+func (app *App) WritePostMortem(ctx context.Context, report TurnReport, rolledBack []string) (string, string, error) {
+	summary, err := app.CoderAgent.PostMortem(ctx, report.SessionID, report.Describe(rolledBack))
+	if err != nil {
+		return "", "", err
+	}
+	path := ProjectMemoryFile()
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return summary, path, err
+	}
+
+	var sb strings.Builder
+	if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+		sb.WriteString("\n")
+	}
+	if !strings.Contains(string(content), postMortemsHeading+"\n") {
+		if len(content) > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(postMortemsHeading + "\n")
+	}
+	sb.WriteString("\n" + summary + "\n")
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return summary, path, err
+	}
+	defer f.Close()
+	_, err = f.WriteString(sb.String())
+	return summary, path, err
+}
+
+// ProjectMemoryFile returns the path of the memory file of the project in
+// the working directory, OpenCode.md unless another spelling exists
+func ProjectMemoryFile() string {
+	wd := config.WorkingDirectory()
+	for _, name := range memoryFiles {
+		path := filepath.Join(wd, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(wd, memoryFiles[0])
+}
+
+// Describe lists what a turn changed and which files were rolled back
+func (r TurnReport) Describe(rolledBack []string) string {
+	wd := config.WorkingDirectory()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Turn %d of %d, asked: %s\n", r.Turn, r.Turns, firstLine(r.Prompt, 200))
+	if len(r.Files) > 0 {
+		sb.WriteString("\nFiles changed:\n")
+	}
+	for _, change := range r.Files {
+		path, err := filepath.Rel(wd, change.Path)
+		if err != nil {
+			path = change.Path
+		}
+		fmt.Fprintf(&sb, "- %s (+%d -%d)", path, change.Additions, change.Removals)
+		if change.Created {
+			sb.WriteString(", created")
+		}
+		if slices.Contains(rolledBack, change.Path) {
+			sb.WriteString(", rolled back")
+		}
+		sb.WriteString("\n")
+	}
+	if len(r.Commands) > 0 {
+		sb.WriteString("\nCommands run:\n")
+	}
+	for _, command := range r.Commands {
+		fmt.Fprintf(&sb, "- `%s`", firstLine(command.Command, 200))
+		if command.Failed {
+			sb.WriteString(", failed")
+		}
+		sb.WriteString("\n")
+	}
+	if len(r.Files) == 0 && len(r.Commands) == 0 {
+		sb.WriteString("\nThe turn changed no files and ran no commands.\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
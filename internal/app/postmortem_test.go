@@ -0,0 +1,188 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/history"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTurnFileChanges(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	edited := filepath.Join(dir, "edited.go")
+	created := filepath.Join(dir, "created.go")
+	later := filepath.Join(dir, "later.go")
+	require.NoError(t, os.WriteFile(edited, []byte("b\n"), 0o644))
+	require.NoError(t, os.WriteFile(created, []byte("new\n"), 0o644))
+	require.NoError(t, os.WriteFile(later, []byte("changed again\n"), 0o644))
+
+	files := []history.File{
+		// Edited in an earlier turn and in the turn, within the same second
+		{Path: edited, Version: history.InitialVersion, Content: "", CreatedAt: 10},
+		{Path: edited, Version: "v1", Content: "a\n", CreatedAt: 10},
+		{Path: edited, Version: "v3", Content: "b\n", CreatedAt: 20},
+		{Path: edited, Version: "v2", Content: "a\nx\n", CreatedAt: 20},
+		// Created in the turn
+		{Path: created, Version: history.InitialVersion, Content: "", CreatedAt: 21},
+		{Path: created, Version: "v1", Content: "new\n", CreatedAt: 21},
+		// Edited in the turn and changed on disk since
+		{Path: later, Version: history.InitialVersion, Content: "old\n", CreatedAt: 22},
+		{Path: later, Version: "v1", Content: "new\n", CreatedAt: 22},
+		// Edited after the turn only
+		{Path: filepath.Join(dir, "next.go"), Version: history.InitialVersion, Content: "", CreatedAt: 30},
+		{Path: filepath.Join(dir, "next.go"), Version: "v1", Content: "next\n", CreatedAt: 30},
+	}
+
+	changes := turnFileChanges(files, 20, 30)
+	require.Len(t, changes, 3)
+
+	assert.Equal(t, created, changes[0].Path)
+	assert.True(t, changes[0].Created)
+	assert.False(t, changes[0].Changed)
+
+	assert.Equal(t, edited, changes[1].Path)
+	assert.Equal(t, "a\n", changes[1].Before)
+	assert.Equal(t, "b\n", changes[1].After, "versions of the same second are ordered by number")
+	assert.False(t, changes[1].Created)
+	assert.False(t, changes[1].Changed)
+
+	assert.Equal(t, later, changes[2].Path)
+	assert.Equal(t, "old\n", changes[2].Before)
+	assert.True(t, changes[2].Changed)
+}
+
+func TestTurnCommands(t *testing.T) {
+	msgs := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "fix the build"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			message.ToolCall{ID: "1", Name: "bash", Input: `{"command": "go build ./..."}`},
+			message.ToolCall{ID: "2", Name: "view", Input: `{"file_path": "main.go"}`},
+			message.ToolCall{ID: "3", Name: "bash", Input: `{"command": "go test ./..."}`},
+		}},
+		{Role: message.Tool, Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: "1", Content: "main.go:3: undefined: foo\nExit code 1"},
+			message.ToolResult{ToolCallID: "2", Content: "package main"},
+			message.ToolResult{ToolCallID: "3", Content: "ok"},
+		}},
+	}
+	assert.Equal(t, []TurnCommand{
+		{Command: "go build ./...", Failed: true},
+		{Command: "go test ./..."},
+	}, turnCommands(msgs))
+}
+
+func TestRollbackTurn(t *testing.T) {
+	dir := t.TempDir()
+	edited := filepath.Join(dir, "edited.go")
+	created := filepath.Join(dir, "created.go")
+	kept := filepath.Join(dir, "kept.go")
+	for _, path := range []string{edited, created, kept} {
+		require.NoError(t, os.WriteFile(path, []byte("after\n"), 0o644))
+	}
+
+	files := &fakeHistory{}
+	app := &App{History: files}
+	report := TurnReport{Files: []FileChange{
+		{Path: created, After: "after\n", Created: true},
+		{Path: edited, Before: "before\n", After: "after\n"},
+		{Path: kept, Before: "before\n", After: "after\n"},
+	}}
+	rolledBack, err := app.RollbackTurn(t.Context(), report, []string{edited, created}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{created, edited}, rolledBack)
+	assert.Equal(t, rolledBack, files.taken)
+	assert.Equal(t, rolledBack, files.released)
+
+	assert.NoFileExists(t, created)
+	content, err := os.ReadFile(edited)
+	require.NoError(t, err)
+	assert.Equal(t, "before\n", string(content))
+	content, err = os.ReadFile(kept)
+	require.NoError(t, err)
+	assert.Equal(t, "after\n", string(content))
+}
+
+func TestRollbackTurnChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	flagged := filepath.Join(dir, "flagged.go")
+	edited := filepath.Join(dir, "edited.go")
+	for _, path := range []string{flagged, edited} {
+		require.NoError(t, os.WriteFile(path, []byte("edited since\n"), 0o644))
+	}
+
+	app := &App{History: &fakeHistory{}}
+	report := TurnReport{Files: []FileChange{
+		{Path: flagged, Before: "before\n", After: "after\n", Changed: true},
+		// Edited after the report was made
+		{Path: edited, Before: "before\n", After: "edited since\n"},
+	}}
+	require.NoError(t, os.WriteFile(edited, []byte("edited later\n"), 0o644))
+
+	paths := []string{flagged, edited}
+	rolledBack, err := app.RollbackTurn(t.Context(), report, paths, nil)
+	assert.ErrorIs(t, err, ErrChangedSinceTurn)
+	assert.Empty(t, rolledBack)
+	for path, want := range map[string]string{flagged: "edited since\n", edited: "edited later\n"} {
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(content), "not overwritten without confirmation")
+	}
+
+	rolledBack, err = app.RollbackTurn(t.Context(), report, paths, paths)
+	require.NoError(t, err)
+	assert.Equal(t, []string{flagged, edited}, rolledBack)
+	content, err := os.ReadFile(flagged)
+	require.NoError(t, err)
+	assert.Equal(t, "before\n", string(content))
+}
+
+func TestRollbackTurnHistoryError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "edited.go")
+	require.NoError(t, os.WriteFile(path, []byte("after\n"), 0o644))
+
+	files := &fakeHistory{err: errors.New("history unavailable")}
+	app := &App{History: files}
+	report := TurnReport{Files: []FileChange{{Path: path, Before: "before\n", After: "after\n"}}}
+	rolledBack, err := app.RollbackTurn(t.Context(), report, []string{path}, nil)
+	assert.ErrorContains(t, err, "failed to record the version: history unavailable")
+	assert.Equal(t, []string{path}, rolledBack)
+	assert.Equal(t, []string{path}, files.taken)
+	assert.Empty(t, files.released, "the snapshot is kept for recovery")
+}
+
+func TestVersionNumber(t *testing.T) {
+	assert.Equal(t, 0, versionNumber(history.InitialVersion))
+	assert.Equal(t, 12, versionNumber("v12"))
+	assert.Less(t, versionNumber("v12"), versionNumber("unexpected"))
+}
+
+// fakeHistory records no file versions, failing to when err is set, and
+// keeps the paths of the snapshots taken and released
+type fakeHistory struct {
+	history.Service
+	err      error
+	taken    []string
+	released []string
+}
+
+func (h *fakeHistory) CreateVersion(ctx context.Context, sessionID, path, content string) (history.File, error) {
+	return history.File{}, h.err
+}
+
+func (h *fakeHistory) Snapshot(ctx context.Context, sessionID, path string) (history.Snapshot, error) {
+	h.taken = append(h.taken, path)
+	return history.Snapshot{ID: path, Path: path}, nil
+}
+
+func (h *fakeHistory) Release(snapshot history.Snapshot) {
+	h.released = append(h.released, snapshot.Path)
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/sessionbranch"
+)
+
+// PullRequestDraft is a pull request written from the changes and the
+// conversation of a session
+type PullRequestDraft struct {
+	Title  string
+	Body   string
+	Path   string // Markdown file the draft was saved to
+	Branch string // Set once the branch was pushed
+	URL    string // Set once the pull request was opened
+}
+
+// DraftPullRequest drafts a pull request from the changes of a session and
+// its conversation, and saves it to the pull_requests directory of the data
+// directory. With open set, the current branch is also pushed and the pull
+// request opened in the GitHub repository of the working directory through
+// the gh CLI.
+func (app *App) DraftPullRequest(ctx context.Context, sessionID, notes string, open bool) (PullRequestDraft, error) {
+	points, err := app.SessionPoints(ctx, sessionID)
+	if err != nil {
+		return PullRequestDraft{}, err
+	}
+	start, _ := FindSessionPoint(points, StartPoint)
+	end, _ := FindSessionPoint(points, EndPoint)
+	changes, err := app.DiffSessionPoints(ctx, sessionID, start, end)
+	if err != nil {
+		return PullRequestDraft{}, err
+	}
+	if len(changes) == 0 {
+		return PullRequestDraft{}, errors.New("the session changed no files")
+	}
+
+	text, err := app.CoderAgent.DraftPullRequest(ctx, sessionID, Patch(changes), notes)
+	if err != nil {
+		return PullRequestDraft{}, err
+	}
+	draft := ParsePullRequestDraft(text)
+
+	dir := filepath.Join(config.Get().Data.Directory, "pull_requests")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return draft, fmt.Errorf("failed to create pull requests directory: %w", err)
+	}
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(draft.Title), "-"), "-")
+	if len(slug) > 50 {
+		slug = strings.TrimRight(slug[:50], "-")
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.md", time.Now().Format("20060102-150405"), slug))
+	if err := os.WriteFile(path, []byte("# "+draft.Title+"\n\n"+draft.Body+"\n"), 0o644); err != nil {
+		return draft, fmt.Errorf("failed to save pull request draft: %w", err)
+	}
+	draft.Path = path
+
+	if !open {
+		return draft, nil
+	}
+	err = openGitHubPullRequest(ctx, &draft)
+	return draft, err
+}
+
+// ParsePullRequestDraft splits a markdown draft into the title, taken from
+// its first heading or line, and the body
+func ParsePullRequestDraft(text string) PullRequestDraft {
+	issue := ParseIssueDraft(text)
+	if issue.Title == "Untitled issue" {
+		issue.Title = "Untitled pull request"
+	}
+	return PullRequestDraft{Title: issue.Title, Body: issue.Body}
+}
+
+// openGitHubPullRequest pushes the current branch and opens the pull request
+// with the gh CLI. Only committed changes are part of a pull request, the
+// push is refused while some aren't committed.
+func openGitHubPullRequest(ctx context.Context, draft *PullRequestDraft) error {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return fmt.Errorf("opening pull requests needs the GitHub CLI (gh), the draft was saved to %s", draft.Path)
+	}
+	dir := config.WorkingDirectory()
+	branch, err := sessionbranch.Current(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if branch == "" {
+		return errors.New("HEAD is detached, check out a branch to open a pull request from")
+	}
+	uncommitted, err := sessionbranch.Uncommitted(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if uncommitted {
+		return errors.New("commit the changes first, e.g. with /checkpoint, they aren't part of the pull request otherwise")
+	}
+	base := sessionbranch.Base(ctx, dir, branch)
+	if base == "" {
+		base = sessionbranch.RemoteDefault(ctx, dir)
+	}
+	if branch == base || branch == sessionbranch.RemoteDefault(ctx, dir) {
+		return fmt.Errorf("%s is a base branch, switch to a feature branch to open a pull request", branch)
+	}
+
+	if err := sessionbranch.Push(ctx, dir, branch); err != nil {
+		return err
+	}
+	draft.Branch = branch
+
+	args := []string{"pr", "create", "--title", draft.Title, "--body", draft.Body, "--head", branch}
+	if base != "" {
+		args = append(args, "--base", base)
+	}
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("gh pr create: %s", strings.TrimSpace(string(out)))
+	}
+	// gh prints the URL of the new pull request last
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	draft.URL = strings.TrimSpace(lines[len(lines)-1])
+	return nil
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/logging"
+)
+
+// reminderRetryInterval is how often a due reminder checks whether its
+// session finished its current request
+const reminderRetryInterval = 5 * time.Second
+
+// DeliverReminders sends the reminders the agent set to their session as
+// they fire, once the session isn't busy, until ctx is done. Only the
+// interactive mode delivers reminders, the reminder tool refuses to set them
+// otherwise.
+func (app *App) DeliverReminders(ctx context.Context) {
+	events := tools.SubscribeReminders(ctx)
+	go func() {
+		defer logging.RecoverPanic("reminders", nil)
+		for event := range events {
+			go app.deliverReminder(ctx, event.Payload)
+		}
+	}()
+}
+
+func (app *App) deliverReminder(ctx context.Context, reminder tools.Reminder) {
+	defer logging.RecoverPanic("reminder", nil)
+	ticker := time.NewTicker(reminderRetryInterval)
+	defer ticker.Stop()
+	for {
+		done, err := app.CoderAgent.Run(ctx, reminder.SessionID, reminder.Message())
+		if err == nil {
+			logging.InfoPersist("Reminder: " + reminder.Note)
+			<-done
+			return
+		}
+		if !errors.Is(err, agent.ErrSessionBusy) {
+			logging.Warn("Failed to deliver reminder", "session", reminder.SessionID, "error", err)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
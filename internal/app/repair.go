@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+// An instance of OpenCode may still be working on a message, only messages
+// left alone for this long are repaired
+const (
+	staleResponseAfter = 2 * time.Minute  // Responses are updated while they stream
+	staleToolCallAfter = 15 * time.Minute // Tools may run for a while
+)
+
+// interruptedToolResult is the result of tool calls that never finished
+const interruptedToolResult = "Tool execution was interrupted, OpenCode exited before it finished"
+
+// RepairReport lists what the startup integrity pass fixed
+type RepairReport struct {
+	OrphanMessages     int64 // Messages of deleted sessions
+	OrphanFiles        int64 // File versions of deleted sessions
+	OrphanSessions     int64 // Task and title sessions of deleted sessions
+	MessageCounts      int64 // Sessions with a wrong message count
+	InterruptedReplies int   // Responses that never finished streaming
+	MissingToolResults int   // Tool calls that never got a result
+}
+
+func (r RepairReport) Empty() bool {
+	return r == RepairReport{}
+}
+
+func (r RepairReport) String() string {
+	var fixed []string
+	add := func(n int64, what string) {
+		if n > 0 {
+			fixed = append(fixed, fmt.Sprintf("%d %s", n, what))
+		}
+	}
+	add(r.OrphanMessages, "orphaned messages removed")
+	add(r.OrphanFiles, "orphaned file versions removed")
+	add(r.OrphanSessions, "orphaned task sessions removed")
+	add(r.MessageCounts, "message counts corrected")
+	add(int64(r.InterruptedReplies), "interrupted responses finished")
+	add(int64(r.MissingToolResults), "interrupted tool calls closed")
+	return strings.Join(fixed, ", ")
+}
+
+// repairSessions detects and repairs the database state a crash or an older
+// version may leave behind, so sessions don't stay stuck or broken
+func (app *App) repairSessions(ctx context.Context, q db.Querier) (RepairReport, error) {
+	var report RepairReport
+	var err error
+
+	// Removing a session may orphan its own task sessions
+	for {
+		n, err := q.DeleteOrphanSessions(ctx)
+		if err != nil {
+			return report, fmt.Errorf("removing orphaned sessions: %w", err)
+		}
+		if n == 0 {
+			break
+		}
+		report.OrphanSessions += n
+	}
+	if report.OrphanMessages, err = q.DeleteOrphanMessages(ctx); err != nil {
+		return report, fmt.Errorf("removing orphaned messages: %w", err)
+	}
+	if report.OrphanFiles, err = q.DeleteOrphanFiles(ctx); err != nil {
+		return report, fmt.Errorf("removing orphaned files: %w", err)
+	}
+
+	// Responses interrupted while streaming keep their session busy
+	unfinished, err := q.ListUnfinishedMessages(ctx, time.Now().Add(-staleResponseAfter).Unix())
+	if err != nil {
+		return report, fmt.Errorf("listing unfinished messages: %w", err)
+	}
+	for _, item := range unfinished {
+		msg, err := app.Messages.Get(ctx, item.ID)
+		if err != nil {
+			return report, err
+		}
+		msg.AddFinish(message.FinishReasonCanceled)
+		if err := app.Messages.Update(ctx, msg); err != nil {
+			return report, err
+		}
+		report.InterruptedReplies++
+	}
+
+	// Tool calls without results make the next request of the session fail
+	last, err := q.ListLastSessionMessages(ctx)
+	if err != nil {
+		return report, fmt.Errorf("listing last messages: %w", err)
+	}
+	staleBefore := time.Now().Add(-staleToolCallAfter).Unix()
+	for _, item := range last {
+		if item.Role != string(message.Assistant) || item.UpdatedAt >= staleBefore {
+			continue
+		}
+		msg, err := app.Messages.Get(ctx, item.ID)
+		if err != nil {
+			return report, err
+		}
+		calls := msg.ToolCalls()
+		if len(calls) == 0 || msg.FinishReason() != message.FinishReasonToolUse {
+			continue
+		}
+		parts := make([]message.ContentPart, len(calls))
+		for i, call := range calls {
+			parts[i] = message.ToolResult{
+				ToolCallID: call.ID,
+				Content:    interruptedToolResult,
+				IsError:    true,
+			}
+		}
+		if _, err := app.Messages.Create(ctx, msg.SessionID, message.CreateMessageParams{
+			Role:  message.Tool,
+			Parts: parts,
+		}); err != nil {
+			return report, err
+		}
+		report.MissingToolResults += len(calls)
+	}
+
+	if report.MessageCounts, err = q.RepairSessionMessageCounts(ctx); err != nil {
+		return report, fmt.Errorf("repairing message counts: %w", err)
+	}
+	return report, nil
+}
+
+// checkSessions runs the integrity pass and reports what it fixed
+func (app *App) checkSessions(ctx context.Context, q db.Querier) {
+	report, err := app.repairSessions(ctx, q)
+	if err != nil {
+		logging.Error("Session integrity check failed", "error", err)
+	}
+	if report.Empty() {
+		return
+	}
+	logging.InfoPersist("Repaired sessions: " + report.String())
+}
@@ -0,0 +1,171 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRepairApp returns an app on an empty in-memory database
+func newRepairApp(t *testing.T) (*App, *sql.DB) {
+	t.Helper()
+	conn, err := db.ConnectEphemeral()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	return &App{Sessions: session.NewService(q), Messages: message.NewService(q)}, conn
+}
+
+// addMessage creates a message, finished with reason unless it's empty
+func addMessage(t *testing.T, app *App, sessionID string, role message.MessageRole, reason message.FinishReason, parts ...message.ContentPart) message.Message {
+	t.Helper()
+	ctx := context.Background()
+	msg, err := app.Messages.Create(ctx, sessionID, message.CreateMessageParams{Role: role, Parts: parts})
+	require.NoError(t, err)
+	if role == message.Assistant && reason != "" {
+		msg.AddFinish(reason)
+		require.NoError(t, app.Messages.Update(ctx, msg))
+	}
+	return msg
+}
+
+// dateBack sets when messages were last updated, the trigger would set it to
+// now
+func dateBack(t *testing.T, conn *sql.DB, age time.Duration, ids ...string) {
+	t.Helper()
+	_, err := conn.Exec("DROP TRIGGER IF EXISTS update_messages_updated_at")
+	require.NoError(t, err)
+	for _, id := range ids {
+		_, err := conn.Exec("UPDATE messages SET updated_at = ? WHERE id = ?", time.Now().Add(-age).Unix(), id)
+		require.NoError(t, err)
+	}
+}
+
+func TestRepairSessions(t *testing.T) {
+	ctx := context.Background()
+	app, conn := newRepairApp(t)
+	q := db.New(conn)
+
+	// Rows of a deleted session, left behind without foreign keys
+	c, err := conn.Conn(ctx)
+	require.NoError(t, err)
+	for _, stmt := range []string{
+		"PRAGMA foreign_keys = OFF",
+		"INSERT INTO sessions (id, parent_session_id, title, updated_at, created_at) VALUES ('task', 'gone', 'task', 0, 0)",
+		"INSERT INTO sessions (id, parent_session_id, title, updated_at, created_at) VALUES ('subtask', 'task', 'subtask', 0, 0)",
+		"INSERT INTO messages (id, session_id, role, parts, created_at, updated_at) VALUES ('orphan', 'gone', 'user', '[]', 0, 0)",
+		"INSERT INTO files (id, session_id, path, content, version, created_at, updated_at) VALUES ('file', 'gone', '/a.go', '', 'initial', 0, 0)",
+		"PRAGMA foreign_keys = ON",
+	} {
+		_, err := c.ExecContext(ctx, stmt)
+		require.NoError(t, err, stmt)
+	}
+	require.NoError(t, c.Close())
+
+	interrupted, err := app.Sessions.Create(ctx, "interrupted")
+	require.NoError(t, err)
+	addMessage(t, app, interrupted.ID, message.User, "", message.TextContent{Text: "fix it"})
+	streaming := addMessage(t, app, interrupted.ID, message.Assistant, "", message.TextContent{Text: "Looking"})
+
+	stuck, err := app.Sessions.Create(ctx, "stuck")
+	require.NoError(t, err)
+	addMessage(t, app, stuck.ID, message.User, "", message.TextContent{Text: "run the tests"})
+	calling := addMessage(t, app, stuck.ID, message.Assistant, message.FinishReasonToolUse,
+		message.ToolCall{ID: "call-1", Name: "bash", Finished: true},
+		message.ToolCall{ID: "call-2", Name: "view", Finished: true})
+	dateBack(t, conn, time.Hour, streaming.ID, calling.ID)
+
+	miscounted, err := app.Sessions.Create(ctx, "miscounted")
+	require.NoError(t, err)
+	_, err = conn.Exec("UPDATE sessions SET message_count = 5 WHERE id = ?", miscounted.ID)
+	require.NoError(t, err)
+
+	report, err := app.repairSessions(ctx, q)
+	require.NoError(t, err)
+	assert.Equal(t, RepairReport{
+		OrphanMessages:     1,
+		OrphanFiles:        1,
+		OrphanSessions:     2,
+		MessageCounts:      1,
+		InterruptedReplies: 1,
+		MissingToolResults: 2,
+	}, report)
+	assert.Equal(t, "1 orphaned messages removed, 1 orphaned file versions removed, 2 orphaned task sessions removed, 1 message counts corrected, 1 interrupted responses finished, 2 interrupted tool calls closed", report.String())
+
+	_, err = app.Sessions.Get(ctx, "subtask")
+	assert.ErrorIs(t, err, sql.ErrNoRows, "task sessions of removed task sessions are removed")
+	msg, err := app.Messages.Get(ctx, streaming.ID)
+	require.NoError(t, err)
+	assert.Equal(t, message.FinishReasonCanceled, msg.FinishReason())
+
+	msgs, err := app.Messages.List(ctx, stuck.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	results := msgs[2].ToolResults()
+	require.Len(t, results, 2)
+	assert.Equal(t, "call-1", results[0].ToolCallID)
+	assert.Equal(t, interruptedToolResult, results[0].Content)
+	assert.True(t, results[0].IsError)
+
+	sess, err := app.Sessions.Get(ctx, miscounted.ID)
+	require.NoError(t, err)
+	assert.Zero(t, sess.MessageCount)
+	sess, err = app.Sessions.Get(ctx, stuck.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, sess.MessageCount)
+
+	report, err = app.repairSessions(ctx, q)
+	require.NoError(t, err)
+	assert.True(t, report.Empty(), "repaired sessions are left alone")
+}
+
+func TestRepairSessionsHealthy(t *testing.T) {
+	ctx := context.Background()
+	app, conn := newRepairApp(t)
+	q := db.New(conn)
+
+	parent, err := app.Sessions.Create(ctx, "parent")
+	require.NoError(t, err)
+	task, err := app.Sessions.CreateTaskSession(ctx, "call-1", parent.ID, "task")
+	require.NoError(t, err)
+	addMessage(t, app, parent.ID, message.User, "", message.TextContent{Text: "run a task"})
+	calling := addMessage(t, app, parent.ID, message.Assistant, message.FinishReasonToolUse,
+		message.ToolCall{ID: "call-1", Name: "agent", Finished: true})
+	result := addMessage(t, app, parent.ID, message.Tool, "", message.ToolResult{ToolCallID: "call-1", Content: "done"})
+	done := addMessage(t, app, parent.ID, message.Assistant, message.FinishReasonEndTurn, message.TextContent{Text: "Done"})
+	addMessage(t, app, task.ID, message.User, "", message.TextContent{Text: "the task"})
+	dateBack(t, conn, time.Hour, calling.ID, result.ID, done.ID)
+
+	// An instance still streaming a response, or running tools
+	busy, err := app.Sessions.Create(ctx, "busy")
+	require.NoError(t, err)
+	addMessage(t, app, busy.ID, message.User, "", message.TextContent{Text: "go"})
+	streaming := addMessage(t, app, busy.ID, message.Assistant, "", message.TextContent{Text: "Working"})
+	running, err := app.Sessions.Create(ctx, "running")
+	require.NoError(t, err)
+	addMessage(t, app, running.ID, message.User, "", message.TextContent{Text: "go"})
+	addMessage(t, app, running.ID, message.Assistant, message.FinishReasonToolUse, message.ToolCall{ID: "call-2", Name: "bash", Finished: true})
+
+	before, err := app.Sessions.ListAll(ctx)
+	require.NoError(t, err)
+	report, err := app.repairSessions(ctx, q)
+	require.NoError(t, err)
+	assert.True(t, report.Empty(), report.String())
+
+	after, err := app.Sessions.ListAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+	msg, err := app.Messages.Get(ctx, streaming.ID)
+	require.NoError(t, err)
+	assert.Empty(t, msg.FinishReason(), "the response is still streaming")
+	msgs, err := app.Messages.List(ctx, running.ID)
+	require.NoError(t, err)
+	assert.Len(t, msgs, 2, "the tools are still running")
+}
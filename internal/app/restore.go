@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kirmad/superopencode/internal/history"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+)
+
+// restoreFile brings a file a session changed back to content, or deletes it
+// when remove is set, and records the content as its latest version in the
+// history of the session. The file is snapshotted before it changes and the
+// snapshot is kept when the version isn't recorded, so a crash or a history
+// failure is recovered on the next start. It reports whether the file was
+// restored, which it is despite an error recording the version.
+func (app *App) restoreFile(ctx context.Context, sessionID, path, content string, remove bool) (bool, error) {
+	if !remove {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return false, err
+		}
+	}
+	snapshot, err := app.History.Snapshot(ctx, sessionID, path)
+	if err != nil {
+		return false, fmt.Errorf("failed to snapshot: %w", err)
+	}
+	if remove {
+		err = os.Remove(path)
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	} else {
+		err = history.WriteFile(path, []byte(content), 0o644)
+	}
+	if err != nil {
+		app.History.Release(snapshot)
+		return false, err
+	}
+	tools.RecordFileWrite(sessionID, path)
+	_, err = app.History.CreateVersion(ctx, sessionID, path, content)
+	history.ReleaseRecorded(app.History, snapshot, err)
+	if err != nil {
+		return true, fmt.Errorf("failed to record the version: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/metrics"
+)
+
+// maxSecondOpinionDiff keeps the diff sent to the reviewer within its context
+const maxSecondOpinionDiff = 40000
+
+// ErrNoPendingChanges is returned when there is nothing to review
+var ErrNoPendingChanges = errors.New("no pending changes to review")
+
+// SecondOpinion runs the reviewer agent over the uncommitted changes of the
+// working directory and returns its findings. The review runs in a task
+// session of sessionID, focus optionally narrows down what to look at.
+func (app *App) SecondOpinion(ctx context.Context, sessionID, focus string) (string, error) {
+	changes, err := pendingChanges(ctx)
+	if err != nil {
+		return "", err
+	}
+	if changes == "" {
+		return "", ErrNoPendingChanges
+	}
+
+	reviewer, err := agent.NewAgent(config.AgentReviewer, app.Sessions, app.Messages, app.Quotas, agent.ReviewerAgentTools(app.LSPClients))
+	if err != nil {
+		return "", fmt.Errorf("error creating reviewer: %w", err)
+	}
+	reviewSession, err := app.Sessions.CreateTaskSession(ctx, uuid.New().String(), sessionID, "Second Opinion")
+	if err != nil {
+		return "", fmt.Errorf("error creating review session: %w", err)
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("Review these pending changes:\n\n")
+	prompt.WriteString(changes)
+	if focus != "" {
+		fmt.Fprintf(&prompt, "\n\nFocus your review on: %s", focus)
+	}
+
+	started := time.Now()
+	done, err := reviewer.Run(ctx, reviewSession.ID, prompt.String())
+	if err != nil {
+		app.recordSubagentTask(agent.SubagentReview, reviewSession.ID, sessionID, metrics.StatusFailed, started)
+		return "", fmt.Errorf("error running reviewer: %w", err)
+	}
+	result := <-done
+
+	status := metrics.StatusDone
+	switch {
+	case ctx.Err() != nil:
+		status = metrics.StatusCanceled
+	case result.Error != nil || result.Message.Role != message.Assistant:
+		status = metrics.StatusFailed
+	}
+	app.recordSubagentTask(agent.SubagentReview, reviewSession.ID, sessionID, status, started)
+
+	if result.Error != nil {
+		return "", fmt.Errorf("error running reviewer: %w", result.Error)
+	}
+	if err := app.addSessionCost(ctx, reviewSession.ID, sessionID); err != nil {
+		return "", err
+	}
+	review := strings.TrimSpace(result.Message.Content().String())
+	if review == "" {
+		return "", errors.New("the reviewer returned no response")
+	}
+	return review, nil
+}
+
+// pendingChanges returns the diff of the uncommitted changes and lists the
+// untracked files, which the diff doesn't include
+func pendingChanges(ctx context.Context) (string, error) {
+	cwd := config.WorkingDirectory()
+	git := func(args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = cwd
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("git %s: %w", args[0], err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	diff, err := git("diff", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if len(diff) > maxSecondOpinionDiff {
+		diff = diff[:maxSecondOpinionDiff] + "\n[diff truncated, read the changed files for the rest]"
+	}
+	untracked, err := git("ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if diff != "" {
+		fmt.Fprintf(&sb, "```diff\n%s\n```\n", diff)
+	}
+	if untracked != "" {
+		fmt.Fprintf(&sb, "\nNew files, read them with your tools:\n%s\n", untracked)
+	}
+	return sb.String(), nil
+}
+
+// addSessionCost adds the cost of a task session to its parent session
+func (app *App) addSessionCost(ctx context.Context, taskSessionID, parentSessionID string) error {
+	taskSession, err := app.Sessions.Get(ctx, taskSessionID)
+	if err != nil {
+		return fmt.Errorf("error getting session: %w", err)
+	}
+	parentSession, err := app.Sessions.Get(ctx, parentSessionID)
+	if err != nil {
+		return fmt.Errorf("error getting parent session: %w", err)
+	}
+	parentSession.Cost += taskSession.Cost
+	if _, err := app.Sessions.Save(ctx, parentSession); err != nil {
+		return fmt.Errorf("error saving parent session: %w", err)
+	}
+	return nil
+}
+
+// recordSubagentTask stores the outcome of a review or audit for the metrics
+// trends
+func (app *App) recordSubagentTask(kind agent.SubagentKind, reviewSessionID, parentSessionID, status string, started time.Time) {
+	ctx := context.Background()
+	task := metrics.Task{
+		ID:        reviewSessionID,
+		SessionID: parentSessionID,
+		Subagent:  string(kind),
+		Status:    status,
+		Duration:  time.Since(started),
+	}
+	if reviewSession, err := app.Sessions.Get(ctx, reviewSessionID); err == nil {
+		task.PromptTokens = reviewSession.PromptTokens
+		task.CompletionTokens = reviewSession.CompletionTokens
+		task.Cost = reviewSession.Cost
+	}
+	if err := app.Metrics.RecordTask(ctx, task); err != nil {
+		logging.Warn("Failed to record subagent metrics", "session", reviewSessionID, "error", err)
+	}
+}
+
+// NoFindings reports whether the reviewer found nothing worth fixing
+func NoFindings(review string) bool {
+	return strings.EqualFold(strings.TrimSuffix(review, "."), "no findings")
+}
+
+// SecondOpinionPrompt asks the coder to resolve every finding of a review
+// before it considers the changes done
+func SecondOpinionPrompt(review string) string {
+	return fmt.Sprintf(`<second-opinion>
+A reviewer agent looked at the pending changes and reported these findings:
+
+%s
+</second-opinion>
+
+Resolve every finding before you consider the changes done: either fix it, or dismiss it explicitly with the reason it doesn't apply. Finish with one line per finding, "N. Addressed: <what you changed>" or "N. Dismissed: <why>".`, review)
+}
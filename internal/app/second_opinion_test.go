@@ -0,0 +1,104 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newReviewRepo returns a git repository with a committed file, used as the
+// working directory
+func newReviewRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644))
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "test"},
+		{"add", "-A"},
+		{"commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	cfg := config.Get()
+	wd := cfg.WorkingDir
+	cfg.WorkingDir = dir
+	t.Cleanup(func() { cfg.WorkingDir = wd })
+	return dir
+}
+
+func TestPendingChanges(t *testing.T) {
+	ctx := context.Background()
+	dir := newReviewRepo(t)
+
+	changes, err := pendingChanges(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	_, err = (&App{}).SecondOpinion(ctx, "session", "")
+	assert.ErrorIs(t, err, ErrNoPendingChanges, "nothing to review")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "util.go"), []byte("package main\n"), 0o644))
+	changes, err = pendingChanges(ctx)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(changes, "```diff\ndiff --git a/main.go b/main.go\n"))
+	assert.Contains(t, changes, "+func main() {}")
+	assert.Contains(t, changes, "New files, read them with your tools:\nutil.go\n")
+
+	// Large diffs are cut to fit the context of the reviewer
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(strings.Repeat("// comment\n", maxSecondOpinionDiff/10)), 0o644))
+	changes, err = pendingChanges(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, changes, "[diff truncated, read the changed files for the rest]")
+	assert.Less(t, len(changes), maxSecondOpinionDiff+200)
+}
+
+func TestNoFindings(t *testing.T) {
+	assert.True(t, NoFindings("No findings."))
+	assert.True(t, NoFindings("no findings"))
+	assert.False(t, NoFindings("1. [high] main.go:3 - no findings of tests - add them"))
+}
+
+func TestSecondOpinionPrompt(t *testing.T) {
+	prompt := SecondOpinionPrompt("1. [low] main.go:1 - missing doc - add it")
+	assert.Contains(t, prompt, "<second-opinion>\nA reviewer agent looked at the pending changes and reported these findings:\n\n1. [low] main.go:1 - missing doc - add it\n</second-opinion>")
+	assert.Contains(t, prompt, "Resolve every finding")
+}
+
+func TestAddSessionCost(t *testing.T) {
+	ctx := context.Background()
+	app, _ := newRepairApp(t)
+	parent, err := app.Sessions.Create(ctx, "parent")
+	require.NoError(t, err)
+	parent.Cost = 1
+	_, err = app.Sessions.Save(ctx, parent)
+	require.NoError(t, err)
+	review, err := app.Sessions.CreateTaskSession(ctx, "review", parent.ID, "Second Opinion")
+	require.NoError(t, err)
+	review.Cost = 0.5
+	_, err = app.Sessions.Save(ctx, review)
+	require.NoError(t, err)
+
+	require.NoError(t, app.addSessionCost(ctx, review.ID, parent.ID))
+	parent, err = app.Sessions.Get(ctx, parent.ID)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.5, parent.Cost, 1e-9)
+	assert.Error(t, app.addSessionCost(ctx, "missing", parent.ID))
+}
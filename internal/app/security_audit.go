@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/metrics"
+)
+
+// SecurityAudit is the outcome of a security audit
+type SecurityAudit struct {
+	Report string
+	Tasks  []tools.TodoItem // Remediation tasks added to the todo list
+}
+
+// findingPattern matches the findings of the security agent, lines like
+// "1. [high] path:line - problem - fix"
+var findingPattern = regexp.MustCompile(`^\s*\d+\.\s*\[(high|medium|low)\]\s*(.+)$`)
+
+var priorityOrder = map[string]int{"high": 0, "medium": 1, "low": 2}
+
+// SecurityAudit runs the security agent with the SAST scanners over scope,
+// the whole project when empty, in a task session of sessionID. Its confirmed
+// findings are added to the todo list of the session as remediation tasks,
+// most severe first.
+func (app *App) SecurityAudit(ctx context.Context, sessionID, scope string) (SecurityAudit, error) {
+	auditor, err := agent.NewAgent(config.AgentSecurity, app.Sessions, app.Messages, app.Quotas, agent.SecurityAgentTools(app.LSPClients))
+	if err != nil {
+		return SecurityAudit{}, fmt.Errorf("error creating security agent: %w", err)
+	}
+	auditSession, err := app.Sessions.CreateTaskSession(ctx, uuid.New().String(), sessionID, "Security Audit")
+	if err != nil {
+		return SecurityAudit{}, fmt.Errorf("error creating audit session: %w", err)
+	}
+
+	prompt := "Audit the security of the project."
+	if scope != "" {
+		prompt = fmt.Sprintf("Audit the security of %s.", scope)
+	}
+
+	started := time.Now()
+	done, err := auditor.Run(ctx, auditSession.ID, prompt)
+	if err != nil {
+		app.recordSubagentTask(agent.SubagentSecurity, auditSession.ID, sessionID, metrics.StatusFailed, started)
+		return SecurityAudit{}, fmt.Errorf("error running security agent: %w", err)
+	}
+	result := <-done
+
+	status := metrics.StatusDone
+	switch {
+	case ctx.Err() != nil:
+		status = metrics.StatusCanceled
+	case result.Error != nil || result.Message.Role != message.Assistant:
+		status = metrics.StatusFailed
+	}
+	app.recordSubagentTask(agent.SubagentSecurity, auditSession.ID, sessionID, status, started)
+
+	if result.Error != nil {
+		return SecurityAudit{}, fmt.Errorf("error running security agent: %w", result.Error)
+	}
+	if err := app.addSessionCost(ctx, auditSession.ID, sessionID); err != nil {
+		return SecurityAudit{}, err
+	}
+	report := strings.TrimSpace(result.Message.Content().String())
+	if report == "" {
+		return SecurityAudit{}, errors.New("the security agent returned no response")
+	}
+
+	audit := SecurityAudit{Report: report, Tasks: RemediationTasks(report, auditSession.ID)}
+	tools.AddTodos(sessionID, audit.Tasks)
+	return audit, nil
+}
+
+// RemediationTasks turns the findings of a security report into pending
+// todos ordered by priority. idPrefix keeps the ids of separate audits apart.
+func RemediationTasks(report, idPrefix string) []tools.TodoItem {
+	var tasks []tools.TodoItem
+	for _, line := range strings.Split(report, "\n") {
+		m := findingPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		tasks = append(tasks, tools.TodoItem{
+			ID:       fmt.Sprintf("%s-security-%d", idPrefix, len(tasks)+1),
+			Content:  "Fix security finding: " + strings.TrimSpace(m[2]),
+			Status:   "pending",
+			Priority: m[1],
+		})
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return priorityOrder[tasks[i].Priority] < priorityOrder[tasks[j].Priority]
+	})
+	return tasks
+}
+
+// SecurityAuditPrompt hands the audit to the coder, which presents it and
+// waits for the user to choose what to fix
+func SecurityAuditPrompt(audit SecurityAudit) string {
+	return fmt.Sprintf(`<security-audit>
+A security audit of the project reported these findings:
+
+%s
+</security-audit>
+
+%d remediation task(s) were added to the todo list, most severe first. Summarize the findings for the user in a few lines, grouped by severity, and ask which ones to fix. Don't change any code before the user answers.`, audit.Report, len(audit.Tasks))
+}
@@ -0,0 +1,22 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemediationTasks(t *testing.T) {
+	tasks := RemediationTasks(`1. [low] app/log.go:10 - logs the request body - redact the password field
+2. [high] app/db.go:42 - SQL injection (CWE-89) - use a prepared statement
+Some trailing note`, "audit")
+
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, "audit-security-2", tasks[0].ID)
+	assert.Equal(t, "high", tasks[0].Priority)
+	assert.Equal(t, "pending", tasks[0].Status)
+	assert.Equal(t, "Fix security finding: app/db.go:42 - SQL injection (CWE-89) - use a prepared statement", tasks[0].Content)
+	assert.Equal(t, "low", tasks[1].Priority)
+
+	assert.Empty(t, RemediationTasks("No findings.", "audit"))
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/sessionbranch"
+)
+
+// SessionBranchesEnabled reports whether sessions work on their own branches
+func SessionBranchesEnabled() bool {
+	cfg := config.Get()
+	return cfg != nil && cfg.SessionBranches.Enabled
+}
+
+// EnterSessionBranch checks out the branch of a session when session
+// branches are enabled, it returns the branch
+func (app *App) EnterSessionBranch(ctx context.Context, sessionID string) (string, error) {
+	if !SessionBranchesEnabled() || sessionID == "" {
+		return "", nil
+	}
+	return sessionbranch.Enter(ctx, config.WorkingDirectory(), config.Get().SessionBranches.Prefix, sessionID)
+}
+
+// CheckpointSession commits the changes of a session to its branch, the
+// message defaults to the session title
+func (app *App) CheckpointSession(ctx context.Context, sessionID, message string) (string, error) {
+	branch, err := app.currentSessionBranch(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+	if message == "" {
+		sess, err := app.Sessions.Get(ctx, sessionID)
+		if err != nil {
+			return "", err
+		}
+		message = "Checkpoint: " + sess.Title
+	}
+	hash, err := sessionbranch.Checkpoint(ctx, config.WorkingDirectory(), config.Get().SessionBranches.Prefix, message)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s on %s", hash, branch), nil
+}
+
+// CompareSessionBranch summarizes the commits and changes of a session branch
+// against the branch it started from
+func (app *App) CompareSessionBranch(ctx context.Context, sessionID string) (string, error) {
+	branch, err := app.currentSessionBranch(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+	return sessionbranch.Compare(ctx, config.WorkingDirectory(), branch)
+}
+
+// CleanupSessionBranches deletes the branches of deleted sessions, and with
+// idle above zero the branches without commits for that long. Branches with
+// commits that aren't merged into their base branch are kept, they are
+// returned with the deleted ones.
+func (app *App) CleanupSessionBranches(ctx context.Context, idle time.Duration) (deleted, kept []string, err error) {
+	if !SessionBranchesEnabled() {
+		return nil, nil, errors.New("session branches are disabled")
+	}
+	wd := config.WorkingDirectory()
+	branches, err := sessionbranch.List(ctx, wd, config.Get().SessionBranches.Prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, _ := sessionbranch.Current(ctx, wd)
+
+	for _, branch := range branches {
+		if branch.Name == current {
+			continue
+		}
+		_, err := app.Sessions.Get(ctx, branch.SessionID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return deleted, kept, fmt.Errorf("looking up the session of %s: %w", branch.Name, err)
+		}
+		abandoned := err != nil || (idle > 0 && time.Since(branch.Updated) > idle)
+		if !abandoned {
+			continue
+		}
+		var unmerged *sessionbranch.UnmergedError
+		if err := sessionbranch.Delete(ctx, wd, branch.Name); errors.As(err, &unmerged) {
+			logging.Warn("Keeping session branch with unmerged commits", "branch", branch.Name, "base", unmerged.Base, "commits", unmerged.Commits)
+			kept = append(kept, branch.Name)
+			continue
+		} else if err != nil {
+			return deleted, kept, fmt.Errorf("deleting %s: %w", branch.Name, err)
+		}
+		deleted = append(deleted, branch.Name)
+	}
+	return deleted, kept, nil
+}
+
+// currentSessionBranch returns the branch of a session, which must be checked
+// out
+func (app *App) currentSessionBranch(ctx context.Context, sessionID string) (string, error) {
+	if !SessionBranchesEnabled() {
+		return "", errors.New("session branches are disabled, enable sessionBranches in the config")
+	}
+	if sessionID == "" {
+		return "", errors.New("no session selected")
+	}
+	branch := sessionbranch.Name(config.Get().SessionBranches.Prefix, sessionID)
+	current, err := sessionbranch.Current(ctx, config.WorkingDirectory())
+	if err != nil {
+		return "", err
+	}
+	if current != branch {
+		return "", fmt.Errorf("%s is checked out instead of the session branch %s", current, branch)
+	}
+	return branch, nil
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/session"
+	"github.com/kirmad/superopencode/internal/sessionbranch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const branchPrefix = "opencode/session-"
+
+// failingSessions fails to look sessions up, like a locked database
+type failingSessions struct{ session.Service }
+
+func (failingSessions) Get(context.Context, string) (session.Session, error) {
+	return session.Session{}, errors.New("database is locked")
+}
+
+func newSessionBranchRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "test"},
+		{"commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	cfg := config.Get()
+	wd, branches := cfg.WorkingDir, cfg.SessionBranches
+	cfg.WorkingDir = dir
+	cfg.SessionBranches = config.SessionBranchesConfig{Enabled: true, Prefix: branchPrefix}
+	t.Cleanup(func() { cfg.WorkingDir, cfg.SessionBranches = wd, branches })
+	return dir
+}
+
+func TestCleanupSessionBranches(t *testing.T) {
+	ctx := context.Background()
+	dir := newSessionBranchRepo(t)
+	conn, err := db.ConnectEphemeral()
+	require.NoError(t, err)
+	defer conn.Close()
+	app := &App{Sessions: session.NewService(db.New(conn))}
+
+	live, err := app.Sessions.Create(ctx, "live")
+	require.NoError(t, err)
+	for _, id := range []string{live.ID, "deleted", "unmerged", "current"} {
+		_, err := sessionbranch.Enter(ctx, dir, branchPrefix, id)
+		require.NoError(t, err)
+		if id == "unmerged" {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "work.txt"), []byte("work\n"), 0o644))
+			_, err = sessionbranch.Checkpoint(ctx, dir, branchPrefix, "work")
+			require.NoError(t, err)
+		}
+	}
+
+	// Looking the sessions up failing deletes nothing
+	failing := &App{Sessions: failingSessions{}}
+	_, _, err = failing.CleanupSessionBranches(ctx, 0)
+	assert.ErrorContains(t, err, "database is locked")
+	branches, err := sessionbranch.List(ctx, dir, branchPrefix)
+	require.NoError(t, err)
+	assert.Len(t, branches, 4)
+
+	deleted, kept, err := app.CleanupSessionBranches(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{branchPrefix + "deleted"}, deleted)
+	assert.Equal(t, []string{branchPrefix + "unmerged"}, kept)
+
+	branches, err = sessionbranch.List(ctx, dir, branchPrefix)
+	require.NoError(t, err)
+	var names []string
+	for _, branch := range branches {
+		names = append(names, branch.SessionID)
+	}
+	assert.ElementsMatch(t, []string{live.ID, "unmerged", "current"}, names, "the branches of live sessions and the checked out one stay")
+}
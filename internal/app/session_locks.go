@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/sessionlock"
+)
+
+// lostSessionWarningTime is how long the warning about a session taken over
+// by another process stays in the status bar
+const lostSessionWarningTime = 15 * time.Second
+
+// lockedAgent holds the sessions the coder agent works on for this process,
+// so another OpenCode process doesn't write to them at the same time
+type lockedAgent struct {
+	agent.Service
+	locks *sessionlock.Manager
+}
+
+// lock holds a session before the agent writes to it. Only another process
+// holding it stops the agent, a lock that can't be written, e.g. on a
+// read-only filesystem, doesn't.
+func (a lockedAgent) lock(sessionID string) error {
+	err := a.locks.Acquire(sessionID)
+	var held *sessionlock.HeldError
+	if errors.As(err, &held) {
+		return err
+	}
+	if err != nil {
+		logging.Warn("Failed to lock the session", "session_id", sessionID, "error", err)
+	}
+	return nil
+}
+
+func (a lockedAgent) Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan agent.AgentEvent, error) {
+	if err := a.lock(sessionID); err != nil {
+		return nil, err
+	}
+	return a.Service.Run(ctx, sessionID, content, attachments...)
+}
+
+func (a lockedAgent) Summarize(ctx context.Context, sessionID string) error {
+	if err := a.lock(sessionID); err != nil {
+		return err
+	}
+	return a.Service.Summarize(ctx, sessionID)
+}
+
+// TakeOver makes this process drive a session another process drives, which
+// stops its agent at its next heartbeat. It returns the previous holder.
+func (app *App) TakeOver(sessionID string) (sessionlock.Owner, bool, error) {
+	previous, held, err := app.Locks.Takeover(sessionID)
+	if err != nil {
+		return sessionlock.Owner{}, false, err
+	}
+	app.idle.mu.Lock()
+	current := app.idle.current
+	app.idle.mu.Unlock()
+	if current == sessionID {
+		app.Locks.Watch(sessionID)
+	}
+	logging.Info("Took the session over", "session_id", sessionID, "from", previous.String(), "held", held)
+	return previous, held, nil
+}
+
+func (app *App) watchLocks(ctx context.Context) {
+	defer logging.RecoverPanic("session-locks", nil)
+	ticker := time.NewTicker(sessionlock.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			app.refreshLocks()
+		}
+	}
+}
+
+// refreshLocks refreshes the sessions held by this process, stops the agent
+// in the sessions other processes took over, and releases the sessions the
+// agent is done with unless the user works in them
+func (app *App) refreshLocks() {
+	for _, sessionID := range app.Locks.Refresh() {
+		app.CoderAgent.Cancel(sessionID)
+		msg := "Another process took the session over, stopped working on it"
+		if holder, ok := app.Locks.Holder(sessionID); ok {
+			msg = fmt.Sprintf("%s took the session over, stopped working on it", holder)
+		}
+		logging.WarnPersist(msg, logging.PersistTimeArg, lostSessionWarningTime)
+	}
+
+	app.idle.mu.Lock()
+	current := app.idle.current
+	app.idle.mu.Unlock()
+	for _, sessionID := range app.Locks.Held() {
+		if sessionID != current && !app.CoderAgent.IsSessionBusy(sessionID) {
+			app.Locks.Release(sessionID)
+		}
+	}
+}
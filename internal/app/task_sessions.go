@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/session"
+)
+
+// ListPickerSessions returns the sessions of the session picker, task
+// sessions only when taskSessions.showInPicker is set
+func (app *App) ListPickerSessions(ctx context.Context) ([]session.Session, error) {
+	if config.Get().TaskSessions.ShowInPicker {
+		return app.Sessions.ListAll(ctx)
+	}
+	return app.Sessions.List(ctx)
+}
+
+// collectTaskSessions archives or deletes the ephemeral sessions past their
+// retention. Their cost was added to their parent session when they
+// finished, so the cost of the parent is kept either way.
+func (app *App) collectTaskSessions(ctx context.Context) (int, error) {
+	cfg := config.Get().TaskSessions
+	if cfg.RetentionDays <= 0 {
+		return 0, nil
+	}
+	expired, err := app.Sessions.ListExpired(ctx, time.Now().AddDate(0, 0, -cfg.RetentionDays))
+	if err != nil {
+		return 0, fmt.Errorf("listing expired task sessions: %w", err)
+	}
+
+	collected := 0
+	for _, sess := range expired {
+		if cfg.Action == config.TaskSessionsArchive {
+			if sess.MessageCount == 0 {
+				continue // Archived already
+			}
+			if err := app.Messages.DeleteSessionMessages(ctx, sess.ID); err != nil {
+				return collected, fmt.Errorf("archiving task session %s: %w", sess.ID, err)
+			}
+			if err := app.History.DeleteSessionFiles(ctx, sess.ID); err != nil {
+				return collected, fmt.Errorf("archiving task session %s: %w", sess.ID, err)
+			}
+		} else if err := app.Sessions.Delete(ctx, sess.ID); err != nil {
+			return collected, fmt.Errorf("deleting task session %s: %w", sess.ID, err)
+		}
+		collected++
+	}
+	return collected, nil
+}
+
+// checkTaskSessions collects the expired task sessions and reports how many
+func (app *App) checkTaskSessions(ctx context.Context) {
+	n, err := app.collectTaskSessions(ctx)
+	if err != nil {
+		logging.Error("Task session cleanup failed", "error", err)
+	}
+	if n == 0 {
+		return
+	}
+	action := "Deleted"
+	if config.Get().TaskSessions.Action == config.TaskSessionsArchive {
+		action = "Archived"
+	}
+	logging.Info(fmt.Sprintf("%s %d task sessions older than %d days", action, n, config.Get().TaskSessions.RetentionDays))
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/history"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectTaskSessions(t *testing.T) {
+	ctx := context.Background()
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	cfg := config.Get()
+	taskSessions := cfg.TaskSessions
+	t.Cleanup(func() { cfg.TaskSessions = taskSessions })
+
+	conn, err := db.ConnectEphemeral()
+	require.NoError(t, err)
+	defer conn.Close()
+	q := db.New(conn)
+	app := &App{
+		Sessions: session.NewService(q),
+		Messages: message.NewService(q),
+		History:  history.NewService(q, conn, nil),
+	}
+
+	parent, err := app.Sessions.Create(ctx, "parent")
+	require.NoError(t, err)
+	for _, id := range []string{"old", "older", "recent"} {
+		_, err := app.Sessions.CreateTaskSession(ctx, id, parent.ID, id)
+		require.NoError(t, err)
+		_, err = app.Messages.Create(ctx, id, message.CreateMessageParams{
+			Role:  message.User,
+			Parts: []message.ContentPart{message.TextContent{Text: "work"}},
+		})
+		require.NoError(t, err)
+	}
+	// The sessions are dated back, the trigger would set updated_at to now
+	_, err = conn.Exec("DROP TRIGGER update_sessions_updated_at")
+	require.NoError(t, err)
+	for id, days := range map[string]int{parent.ID: 60, "old": 10, "older": 20, "recent": 1} {
+		_, err := conn.Exec("UPDATE sessions SET updated_at = ? WHERE id = ?", time.Now().AddDate(0, 0, -days).Unix(), id)
+		require.NoError(t, err)
+	}
+
+	assert.Zero(t, config.Get().TaskSessions.RetentionDays, "task sessions are kept by default")
+	n, err := app.collectTaskSessions(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	cfg.TaskSessions = config.TaskSessionsConfig{RetentionDays: 15, Action: config.TaskSessionsArchive}
+	n, err = app.collectTaskSessions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	older, err := app.Sessions.Get(ctx, "older")
+	require.NoError(t, err)
+	assert.Zero(t, older.MessageCount, "archived sessions keep no messages")
+	recent, err := app.Sessions.Get(ctx, "recent")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, recent.MessageCount)
+
+	cfg.TaskSessions = config.TaskSessionsConfig{RetentionDays: 7, Action: config.TaskSessionsDelete}
+	n, err = app.collectTaskSessions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	all, err := app.Sessions.ListAll(ctx)
+	require.NoError(t, err)
+	var ids []string
+	for _, sess := range all {
+		ids = append(ids, sess.ID)
+	}
+	assert.ElementsMatch(t, []string{parent.ID, "recent"}, ids, "sessions that aren't ephemeral are never collected")
+}
@@ -0,0 +1,231 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/checkpoint"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/diff"
+	"github.com/kirmad/superopencode/internal/history"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+const (
+	// StartPoint is the point before the session changed anything
+	StartPoint = "start"
+	// EndPoint is the latest point of the session
+	EndPoint = "end"
+)
+
+// SessionPoint is a point in a session the workspace can be compared at: its
+// start, the end of a turn or a named checkpoint
+type SessionPoint struct {
+	Name  string // StartPoint, the number of a turn or the name of a checkpoint
+	Label string // e.g. "after turn 3" or "checkpoint risky-refactor"
+	Time  time.Time
+	end   int64 // The versions of the history created before end are part of the point
+}
+
+// SessionPoints returns the points of a session in order, from its messages
+// and named checkpoints
+func SessionPoints(msgs []message.Message, checkpoints []checkpoint.Checkpoint) []SessionPoint {
+	var turns []int // Index of the user message starting each turn
+	for i, msg := range msgs {
+		if msg.Role == message.User {
+			turns = append(turns, i)
+		}
+	}
+	points := []SessionPoint{{Name: StartPoint, Label: "start of the session", end: math.MinInt64}}
+	if len(turns) > 0 {
+		points[0].Time = time.Unix(msgs[turns[0]].CreatedAt, 0)
+	}
+	for i, first := range turns {
+		point := SessionPoint{
+			Name:  strconv.Itoa(i + 1),
+			Label: fmt.Sprintf("after turn %d", i+1),
+			end:   math.MaxInt64,
+		}
+		last := len(msgs)
+		if i+1 < len(turns) {
+			last = turns[i+1]
+			point.end = msgs[last].CreatedAt
+		}
+		var updated int64
+		for _, msg := range msgs[first:last] {
+			updated = max(updated, msg.CreatedAt, msg.UpdatedAt)
+		}
+		point.Time = time.Unix(updated, 0)
+		points = append(points, point)
+	}
+	for _, c := range checkpoints {
+		points = append(points, SessionPoint{
+			Name:  c.Name,
+			Label: "checkpoint " + c.Name,
+			Time:  c.Created,
+			// Versions have a one-second granularity, the ones of the
+			// second the checkpoint was saved are part of it
+			end: c.Created.Unix() + 1,
+		})
+	}
+	// The start comes first, the checkpoints saved before the first turn
+	// included
+	slices.SortStableFunc(points[1:], func(a, b SessionPoint) int {
+		return a.Time.Compare(b.Time)
+	})
+	return points
+}
+
+// FindSessionPoint returns the point called name: StartPoint, EndPoint, the
+// number of a turn, optionally as "turn N", or the name of a checkpoint
+func FindSessionPoint(points []SessionPoint, name string) (SessionPoint, error) {
+	name = strings.TrimSpace(name)
+	if name == EndPoint && len(points) > 0 {
+		// The latest state of the files
+		return slices.MaxFunc(points, func(a, b SessionPoint) int {
+			return cmp.Compare(a.end, b.end)
+		}), nil
+	}
+	turn := strings.TrimSpace(strings.TrimPrefix(name, "turn"))
+	for _, point := range points {
+		if point.Name == name {
+			return point, nil
+		}
+	}
+	for _, point := range points {
+		if _, err := strconv.Atoi(turn); err == nil && point.Name == turn {
+			return point, nil
+		}
+	}
+	return SessionPoint{}, fmt.Errorf("unknown point %q, use %s, %s, the number of a turn or the name of a checkpoint", name, StartPoint, EndPoint)
+}
+
+// DiffSessionPoints compares the files of a session between two points, from
+// the versions in its history. The history has no trace of deleted files, a
+// file is missing at a point when it is empty.
+func DiffSessionPoints(files []history.File, from, to SessionPoint) []FileChange {
+	var changes []FileChange
+	for path, versions := range versionsByPath(files) {
+		before := contentAt(versions, from.end)
+		after := contentAt(versions, to.end)
+		if before == after {
+			continue
+		}
+		change := FileChange{
+			Path:    path,
+			Before:  before,
+			After:   after,
+			Created: before == "",
+			Deleted: after == "",
+		}
+		_, change.Additions, change.Removals = diff.GenerateDiff(change.Before, change.After, path)
+		changes = append(changes, change)
+	}
+	slices.SortFunc(changes, func(a, b FileChange) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+	return changes
+}
+
+// contentAt returns the content of a file at the point of end, from its
+// versions in order
+func contentAt(versions []history.File, end int64) string {
+	if len(versions) == 0 {
+		return ""
+	}
+	// The first version holds the content before the session changed the
+	// file
+	content := versions[0].Content
+	for _, version := range versions {
+		if version.CreatedAt < end {
+			content = version.Content
+		}
+	}
+	return content
+}
+
+// Patch returns the changes as a patch that applies with git apply or patch
+// -p1 in the working directory
+func Patch(changes []FileChange) string {
+	wd := config.WorkingDirectory()
+	var sb strings.Builder
+	for _, change := range changes {
+		path, err := filepath.Rel(wd, change.Path)
+		if err != nil || strings.HasPrefix(path, "..") {
+			path = strings.TrimPrefix(change.Path, "/")
+		}
+		path = filepath.ToSlash(path)
+		unified, _, _ := diff.GenerateDiff(change.Before, change.After, change.Path)
+		if unified == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "diff --git a/%s b/%s\n", path, path)
+		switch {
+		case change.Created:
+			sb.WriteString("new file mode 100644\n")
+			unified = strings.Replace(unified, "--- a/"+path+"\n", "--- /dev/null\n", 1)
+		case change.Deleted:
+			sb.WriteString("deleted file mode 100644\n")
+			unified = strings.Replace(unified, "+++ b/"+path+"\n", "+++ /dev/null\n", 1)
+		}
+		sb.WriteString(unified)
+		if !strings.HasSuffix(unified, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// SessionPoints returns the points of a session the workspace can be
+// compared at
+func (app *App) SessionPoints(ctx context.Context, sessionID string) ([]SessionPoint, error) {
+	if sessionID == "" {
+		return nil, errors.New("no session selected")
+	}
+	msgs, err := app.Messages.List(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	checkpoints, err := app.ListCheckpoints(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return SessionPoints(msgs, checkpoints), nil
+}
+
+// DiffSessionPoints compares the files a session changed between two of its
+// points
+func (app *App) DiffSessionPoints(ctx context.Context, sessionID string, from, to SessionPoint) ([]FileChange, error) {
+	files, err := app.History.ListBySession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return DiffSessionPoints(files, from, to), nil
+}
+
+// ExportPatch writes the changes between two points of a session as a patch
+// file in the working directory and returns its path
+func ExportPatch(changes []FileChange, from, to SessionPoint) (string, error) {
+	if len(changes) == 0 {
+		return "", errors.New("no changes between the two points")
+	}
+	path := filepath.Join(config.WorkingDirectory(), fmt.Sprintf("opencode-%s-%s.patch", patchName(from), patchName(to)))
+	return path, os.WriteFile(path, []byte(Patch(changes)), 0o644)
+}
+
+// patchName names a point in a patch file name
+func patchName(point SessionPoint) string {
+	if _, err := strconv.Atoi(point.Name); err == nil {
+		return "turn" + point.Name
+	}
+	return point.Name
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/checkpoint"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/history"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionPoints(t *testing.T) {
+	msgs := []message.Message{
+		{Role: message.User, CreatedAt: 10},
+		{Role: message.Assistant, CreatedAt: 11, UpdatedAt: 15},
+		{Role: message.User, CreatedAt: 20},
+		{Role: message.Assistant, CreatedAt: 21, UpdatedAt: 25},
+	}
+	checkpoints := []checkpoint.Checkpoint{{Name: "risky", Created: time.Unix(17, 0)}}
+
+	points := SessionPoints(msgs, checkpoints)
+	var names []string
+	for _, point := range points {
+		names = append(names, point.Name)
+	}
+	assert.Equal(t, []string{StartPoint, "1", "risky", "2"}, names, "checkpoints are ordered by time among the turns")
+	assert.Equal(t, "after turn 1", points[1].Label)
+	assert.Equal(t, time.Unix(25, 0), points[3].Time)
+
+	for name, want := range map[string]string{"start": StartPoint, "end": "2", "turn 1": "1", "2": "2", "risky": "risky"} {
+		point, err := FindSessionPoint(points, name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, point.Name, name)
+	}
+	_, err := FindSessionPoint(points, "turn 3")
+	assert.Error(t, err)
+}
+
+func TestDiffSessionPoints(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	files := []history.File{
+		// Edited in turns 1 and 2
+		{Path: "/wd/edited.go", Version: history.InitialVersion, Content: "a\n", CreatedAt: 11},
+		{Path: "/wd/edited.go", Version: "v1", Content: "b\n", CreatedAt: 11},
+		{Path: "/wd/edited.go", Version: "v2", Content: "c\n", CreatedAt: 21},
+		// Created in turn 2
+		{Path: "/wd/created.go", Version: history.InitialVersion, Content: "", CreatedAt: 22},
+		{Path: "/wd/created.go", Version: "v1", Content: "new\n", CreatedAt: 22},
+		// Edited in turn 1 only
+		{Path: "/wd/early.go", Version: history.InitialVersion, Content: "x\n", CreatedAt: 12},
+		{Path: "/wd/early.go", Version: "v1", Content: "y\n", CreatedAt: 12},
+	}
+	points := SessionPoints([]message.Message{
+		{Role: message.User, CreatedAt: 10},
+		{Role: message.User, CreatedAt: 20},
+	}, nil)
+
+	// Between turns 1 and 2
+	changes := DiffSessionPoints(files, points[1], points[2])
+	require.Len(t, changes, 2)
+	assert.Equal(t, "/wd/created.go", changes[0].Path)
+	assert.True(t, changes[0].Created)
+	assert.Equal(t, "/wd/edited.go", changes[1].Path)
+	assert.Equal(t, "b\n", changes[1].Before)
+	assert.Equal(t, "c\n", changes[1].After)
+
+	// Backwards from the end to the start
+	changes = DiffSessionPoints(files, points[2], points[0])
+	require.Len(t, changes, 3)
+	assert.True(t, changes[0].Deleted)
+	assert.Equal(t, "x\n", changes[1].After)
+	assert.Equal(t, "c\n", changes[2].Before)
+	assert.Equal(t, "a\n", changes[2].After)
+}
+
+func TestPatch(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	wd := config.WorkingDirectory()
+	patch := Patch([]FileChange{
+		{Path: wd + "/new.go", After: "new\n", Created: true},
+		{Path: wd + "/old.go", Before: "old\n", Deleted: true},
+		{Path: wd + "/same.go", Before: "same\n", After: "same\n"},
+	})
+	assert.Equal(t, `diff --git a/new.go b/new.go
+new file mode 100644
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1 @@
++new
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-old
+`, patch)
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/tmux"
+)
+
+// tmuxRefreshInterval is how often the tmux window is updated
+const tmuxRefreshInterval = 2 * time.Second
+
+// AttachTmux names the tmux window OpenCode runs in after the current session
+// and keeps its @opencode_status option up to date until ctx is done. It
+// fails when OpenCode doesn't run inside tmux.
+func (app *App) AttachTmux(ctx context.Context) error {
+	window, err := tmux.Attach()
+	if err != nil {
+		return err
+	}
+	app.tmux = window
+	go app.watchTmux(ctx)
+	return nil
+}
+
+func (app *App) watchTmux(ctx context.Context) {
+	defer logging.RecoverPanic("tmux-status", nil)
+	ticker := time.NewTicker(tmuxRefreshInterval)
+	defer ticker.Stop()
+	failing := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := app.refreshTmux(ctx)
+			// Log once until it works again, tmux may have gone away
+			if err != nil && !failing {
+				logging.Warn("Failed to update the tmux window", "error", err)
+			}
+			failing = err != nil
+		}
+	}
+}
+
+// refreshTmux shows the title, state and cost of the session the user works
+// in on the tmux window
+func (app *App) refreshTmux(ctx context.Context) error {
+	app.idle.mu.Lock()
+	sessionID := app.idle.current
+	app.idle.mu.Unlock()
+	if sessionID == "" {
+		return app.tmux.SetStatus(ctx, tmux.Status(false, 0))
+	}
+
+	sess, err := app.Sessions.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if err := app.tmux.Rename(ctx, sess.Title); err != nil {
+		return err
+	}
+	return app.tmux.SetStatus(ctx, tmux.Status(app.CoderAgent.IsSessionBusy(sessionID), sess.Cost))
+}
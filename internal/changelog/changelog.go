@@ -0,0 +1,184 @@
+// Package changelog gathers the commits of a git range and groups them into
+// the sections of a changelog.
+package changelog
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// Commit is a commit of the range
+type Commit struct {
+	Hash     string
+	Type     string // Conventional commit type, e.g. "feat", empty if none
+	Scope    string
+	Subject  string // Subject without the type and scope
+	PR       string // Pull request number, e.g. "#123"
+	Breaking bool
+}
+
+// Section is a group of commits of the same type
+type Section struct {
+	Title   string
+	Commits []Commit
+}
+
+// sectionTitles are the sections of the changelog in order, commits of other
+// types end up in "Other Changes"
+var sectionTitles = []struct {
+	types []string
+	title string
+}{
+	{[]string{"feat"}, "Features"},
+	{[]string{"fix"}, "Bug Fixes"},
+	{[]string{"perf"}, "Performance"},
+	{[]string{"refactor"}, "Refactoring"},
+	{[]string{"docs"}, "Documentation"},
+	{[]string{"test", "build", "ci", "chore", "style"}, "Maintenance"},
+}
+
+const otherChanges = "Other Changes"
+
+var (
+	conventionalPattern = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+	prSuffixPattern     = regexp.MustCompile(`\s*\((#\d+)\)$`)
+	mergePattern        = regexp.MustCompile(`^Merge pull request (#\d+) from \S+`)
+)
+
+// Commits returns the commits of a range like "v1.0..v1.1" in dir, oldest
+// first. Merge commits of pull requests are listed under the PR title.
+func Commits(ctx context.Context, dir, rangeSpec string) ([]Commit, error) {
+	cmd := exec.CommandContext(ctx, "git", "log", "--reverse", "--format=%H%x1f%s%x1f%b%x1e", rangeSpec)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("git log %s: %s", rangeSpec, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("git log %s: %w", rangeSpec, err)
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(string(out), "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(record), "\x1f", 3)
+		if len(fields) < 2 {
+			continue
+		}
+		body := ""
+		if len(fields) == 3 {
+			body = fields[2]
+		}
+		commits = append(commits, Parse(fields[0], fields[1], body))
+	}
+	return commits, nil
+}
+
+// Parse parses the subject and body of a commit
+func Parse(hash, subject, body string) Commit {
+	commit := Commit{Hash: hash, Subject: strings.TrimSpace(subject)}
+	if m := mergePattern.FindStringSubmatch(commit.Subject); m != nil {
+		// The PR title is the first line of the merge commit body
+		commit.PR = m[1]
+		title, _, _ := strings.Cut(strings.TrimSpace(body), "\n")
+		commit.Subject = strings.TrimSpace(title)
+	}
+	if m := prSuffixPattern.FindStringSubmatch(commit.Subject); m != nil {
+		commit.PR = m[1]
+		commit.Subject = strings.TrimSuffix(commit.Subject, m[0])
+	}
+	if m := conventionalPattern.FindStringSubmatch(commit.Subject); m != nil {
+		commit.Type = strings.ToLower(m[1])
+		commit.Scope = m[2]
+		commit.Breaking = m[3] == "!"
+		commit.Subject = m[4]
+	}
+	if strings.Contains(body, "BREAKING CHANGE") {
+		commit.Breaking = true
+	}
+	return commit
+}
+
+// Group sorts the commits into sections, skipping empty ones
+func Group(commits []Commit) []Section {
+	byTitle := make(map[string][]Commit)
+	for _, commit := range commits {
+		if commit.Subject == "" {
+			continue
+		}
+		title := otherChanges
+		for _, section := range sectionTitles {
+			for _, typ := range section.types {
+				if commit.Type == typ {
+					title = section.title
+				}
+			}
+		}
+		byTitle[title] = append(byTitle[title], commit)
+	}
+
+	var sections []Section
+	for _, section := range sectionTitles {
+		if len(byTitle[section.title]) > 0 {
+			sections = append(sections, Section{Title: section.title, Commits: byTitle[section.title]})
+		}
+	}
+	if len(byTitle[otherChanges]) > 0 {
+		sections = append(sections, Section{Title: otherChanges, Commits: byTitle[otherChanges]})
+	}
+	return sections
+}
+
+// Format lists the grouped commits as markdown, used as the input of the
+// model and as the changelog when no model is used
+func Format(heading string, sections []Section) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## %s\n", heading)
+	for _, section := range sections {
+		fmt.Fprintf(&sb, "\n### %s\n\n", section.Title)
+		for _, commit := range section.Commits {
+			sb.WriteString("- ")
+			if commit.Breaking {
+				sb.WriteString("**Breaking:** ")
+			}
+			if commit.Scope != "" {
+				fmt.Fprintf(&sb, "**%s:** ", commit.Scope)
+			}
+			sb.WriteString(commit.Subject)
+			if commit.PR != "" {
+				fmt.Fprintf(&sb, " (%s)", commit.PR)
+			} else if len(commit.Hash) >= 7 {
+				fmt.Fprintf(&sb, " (%s)", commit.Hash[:7])
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// Insert adds a section to a changelog above its previous releases, keeping
+// the title and introduction at the top
+func Insert(changelog, section string) string {
+	section = strings.TrimSpace(section) + "\n"
+	if strings.TrimSpace(changelog) == "" {
+		return "# Changelog\n\n" + section
+	}
+	if !strings.HasPrefix(changelog, "# ") {
+		return section + "\n" + changelog
+	}
+
+	title, rest, _ := strings.Cut(changelog, "\n")
+	rest = strings.TrimLeft(rest, "\n")
+	i := strings.Index("\n"+rest, "\n## ")
+	switch {
+	case i == 0:
+		return title + "\n\n" + section + "\n" + rest
+	case i > 0:
+		return title + "\n\n" + strings.TrimRight(rest[:i], "\n") + "\n\n" + section + "\n" + rest[i:]
+	case rest == "":
+		return title + "\n\n" + section
+	}
+	return title + "\n\n" + strings.TrimRight(rest, "\n") + "\n\n" + section
+}
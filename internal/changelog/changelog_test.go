@@ -0,0 +1,57 @@
+package changelog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	commit := Parse("abc1234567", "feat(tui)!: add a metrics page (#42)", "")
+	assert.Equal(t, Commit{Hash: "abc1234567", Type: "feat", Scope: "tui", Subject: "add a metrics page", PR: "#42", Breaking: true}, commit)
+
+	commit = Parse("def", "Merge pull request #7 from user/branch", "fix: handle empty sessions\n\nDetails")
+	assert.Equal(t, "fix", commit.Type)
+	assert.Equal(t, "handle empty sessions", commit.Subject)
+	assert.Equal(t, "#7", commit.PR)
+
+	commit = Parse("123", "Update README", "BREAKING CHANGE: config moved")
+	assert.Empty(t, commit.Type)
+	assert.True(t, commit.Breaking)
+}
+
+func TestGroupAndFormat(t *testing.T) {
+	sections := Group([]Commit{
+		Parse("1111111aaa", "chore: bump deps", ""),
+		Parse("2222222bbb", "Tweak output", ""),
+		Parse("3333333ccc", "fix(db): close rows (#3)", ""),
+		Parse("4444444ddd", "feat: add export", ""),
+	})
+	assert.Equal(t, `## v1.1
+
+### Features
+
+- add export (4444444)
+
+### Bug Fixes
+
+- **db:** close rows (#3)
+
+### Maintenance
+
+- bump deps (1111111)
+
+### Other Changes
+
+- Tweak output (2222222)
+`, Format("v1.1", sections))
+}
+
+func TestInsert(t *testing.T) {
+	section := "## v1.1\n\n- new\n"
+	assert.Equal(t, "# Changelog\n\n## v1.1\n\n- new\n", Insert("", section))
+	assert.Equal(t, "# Changelog\n\n## v1.1\n\n- new\n\n## v1.0\n\n- old\n", Insert("# Changelog\n\n## v1.0\n\n- old\n", section))
+	assert.Equal(t, "# Changelog\n\nAll notable changes.\n\n## v1.1\n\n- new\n\n## v1.0\n", Insert("# Changelog\nAll notable changes.\n## v1.0\n", section))
+	assert.Equal(t, "# Changelog\n\nAll notable changes.\n\n## v1.1\n\n- new\n", Insert("# Changelog\n\nAll notable changes.\n", section))
+	assert.Equal(t, "## v1.1\n\n- new\n\n## v1.0\n", Insert("## v1.0\n", section))
+}
@@ -0,0 +1,344 @@
+// Package checkpoint saves named snapshots of the workspace of a session, so
+// a risky series of changes can be tried and abandoned by restoring the
+// snapshot. A checkpoint copies the files the session touched, and in a git
+// repository the modified and untracked files, with the commit they are
+// based on.
+package checkpoint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/history"
+	"github.com/kirmad/superopencode/internal/locale"
+)
+
+// ErrNotFound is returned for a checkpoint that wasn't saved
+var ErrNotFound = errors.New("checkpoint not found")
+
+// manifestFile is the file describing a checkpoint in its directory
+const manifestFile = "checkpoint.json"
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Checkpoint is a saved snapshot of the workspace of a session
+type Checkpoint struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	Commit  string    `json:"commit,omitempty"` // HEAD when saved, empty outside a git repository
+	Files   []File    `json:"files"`
+}
+
+// File is a file of a checkpoint, its content is copied next to the manifest
+type File struct {
+	Path    string `json:"path"`              // Relative to the working directory
+	Deleted bool   `json:"deleted,omitempty"` // The file didn't exist
+}
+
+// String describes the checkpoint in a line
+func (c Checkpoint) String() string {
+	return fmt.Sprintf("%s (%s, %d files)", c.Name, locale.DateTime(c.Created), len(c.Files))
+}
+
+// ValidateName checks that name can be used as a checkpoint directory name
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid checkpoint name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// Save snapshots the workspace wd as the checkpoint name in dir, replacing a
+// checkpoint with the same name. touched are the absolute paths of the files
+// the session changed.
+func Save(ctx context.Context, dir, wd, name string, touched []string) (Checkpoint, error) {
+	if err := ValidateName(name); err != nil {
+		return Checkpoint{}, err
+	}
+	commit, changed := gitState(ctx, wd)
+	checkpoint := Checkpoint{
+		Name:    name,
+		Created: time.Now(),
+		Commit:  commit,
+	}
+
+	// Copy into a new directory first, an existing checkpoint is only
+	// replaced once the copy is complete
+	tmp := filepath.Join(dir, "."+name+".tmp")
+	os.RemoveAll(tmp)
+	defer os.RemoveAll(tmp)
+	for _, path := range workspaceFiles(wd, touched, changed) {
+		content, err := os.ReadFile(filepath.Join(wd, path))
+		if errors.Is(err, os.ErrNotExist) {
+			checkpoint.Files = append(checkpoint.Files, File{Path: path, Deleted: true})
+			continue
+		}
+		if err != nil {
+			return Checkpoint{}, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if err := writeFile(filepath.Join(tmp, "files", path), content); err != nil {
+			return Checkpoint{}, fmt.Errorf("failed to copy %s: %w", path, err)
+		}
+		checkpoint.Files = append(checkpoint.Files, File{Path: path})
+	}
+
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	if err := writeFile(filepath.Join(tmp, manifestFile), data); err != nil {
+		return Checkpoint{}, fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+		return Checkpoint{}, fmt.Errorf("failed to replace checkpoint %s: %w", name, err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, name)); err != nil {
+		return Checkpoint{}, fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+// Load reads the checkpoint name from dir
+func Load(dir, name string) (Checkpoint, error) {
+	if err := ValidateName(name); err != nil {
+		return Checkpoint{}, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name, manifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return Checkpoint{}, ErrNotFound
+	}
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return Checkpoint{}, fmt.Errorf("failed to parse checkpoint %s: %w", name, err)
+	}
+	return checkpoint, nil
+}
+
+// List returns the checkpoints in dir, the most recent first
+func List(dir string) ([]Checkpoint, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var checkpoints []Checkpoint
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		checkpoint, err := Load(dir, entry.Name())
+		if err != nil {
+			continue
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].Created.After(checkpoints[j].Created) })
+	return checkpoints, nil
+}
+
+// Restore brings the workspace wd back to the checkpoint name in dir and
+// returns the relative paths of the files it changed. Files changed since
+// the checkpoint get their content back: from the checkpoint, from its
+// commit, or from original, the content of the files the session touched
+// before it first changed them, keyed by absolute path. Files created since
+// the checkpoint are deleted. before, when set, is called with the absolute
+// path of each file before it changes, and the file is left alone when it
+// fails.
+func Restore(ctx context.Context, dir, wd, name string, original map[string]string, before func(path string) error) ([]string, error) {
+	checkpoint, err := Load(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil {
+		before = func(string) error { return nil }
+	}
+	saved := make(map[string]bool, len(checkpoint.Files))
+	var restored []string
+	for _, file := range checkpoint.Files {
+		saved[file.Path] = true
+		target := filepath.Join(wd, file.Path)
+		if file.Deleted {
+			if err := removeFile(target, before); err == nil {
+				restored = append(restored, file.Path)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return restored, fmt.Errorf("failed to delete %s: %w", file.Path, err)
+			}
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, name, "files", file.Path))
+		if err != nil {
+			return restored, fmt.Errorf("failed to read %s from the checkpoint: %w", file.Path, err)
+		}
+		changed, err := restoreFile(target, content, before)
+		if err != nil {
+			return restored, fmt.Errorf("failed to restore %s: %w", file.Path, err)
+		}
+		if changed {
+			restored = append(restored, file.Path)
+		}
+	}
+
+	touched := make([]string, 0, len(original))
+	for path := range original {
+		touched = append(touched, path)
+	}
+	_, changed := gitState(ctx, wd)
+	for _, path := range workspaceFiles(wd, touched, changed) {
+		if saved[path] {
+			continue
+		}
+		// The file was unchanged when the checkpoint was saved
+		target := filepath.Join(wd, path)
+		content, ok := committedContent(ctx, wd, checkpoint.Commit, path)
+		if !ok {
+			content, ok = []byte(original[target]), original[target] != ""
+		}
+		if !ok {
+			if err := removeFile(target, before); err == nil {
+				restored = append(restored, path)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return restored, fmt.Errorf("failed to delete %s: %w", path, err)
+			}
+			continue
+		}
+		changed, err := restoreFile(target, content, before)
+		if err != nil {
+			return restored, fmt.Errorf("failed to restore %s: %w", path, err)
+		}
+		if changed {
+			restored = append(restored, path)
+		}
+	}
+	sort.Strings(restored)
+	return restored, nil
+}
+
+// Delete removes the checkpoint name from dir
+func Delete(dir, name string) error {
+	if _, err := Load(dir, name); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(dir, name))
+}
+
+// workspaceFiles returns the sorted relative paths of the touched and
+// changed files inside wd
+func workspaceFiles(wd string, touched, changed []string) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(path string) {
+		rel, err := filepath.Rel(wd, path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") || seen[rel] {
+			return
+		}
+		seen[rel] = true
+		paths = append(paths, rel)
+	}
+	for _, path := range touched {
+		add(path)
+	}
+	for _, path := range changed {
+		add(filepath.Join(wd, path))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// gitState returns the HEAD commit of the repository of wd and the paths,
+// relative to wd, of its modified and untracked files. Both are empty outside
+// a git repository.
+func gitState(ctx context.Context, wd string) (string, []string) {
+	head, err := git(ctx, wd, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil {
+		return "", nil
+	}
+	var changed []string
+	for _, args := range [][]string{
+		{"diff", "--name-only", "-z", "--relative", "HEAD"},
+		{"ls-files", "--others", "--exclude-standard", "-z"},
+	} {
+		out, err := git(ctx, wd, args...)
+		if err != nil {
+			continue
+		}
+		for _, path := range strings.Split(out, "\x00") {
+			if path != "" {
+				changed = append(changed, filepath.FromSlash(path))
+			}
+		}
+	}
+	return strings.TrimSpace(head), changed
+}
+
+// committedContent returns the content of path in commit
+func committedContent(ctx context.Context, wd, commit, path string) ([]byte, bool) {
+	if commit == "" {
+		return nil, false
+	}
+	out, err := git(ctx, wd, "show", commit+":./"+filepath.ToSlash(path))
+	if err != nil {
+		return nil, false
+	}
+	return []byte(out), true
+}
+
+// restoreFile writes content to path unless it already has it, it reports
+// whether the file changed. The content is written atomically, so a crash
+// leaves the file to recover from the snapshot taken by before.
+func restoreFile(path string, content []byte, before func(string) error) (bool, error) {
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, content) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return false, err
+	}
+	if err := before(path); err != nil {
+		return false, err
+	}
+	return true, history.WriteFile(path, content, 0o644)
+}
+
+// removeFile deletes path, it returns os.ErrNotExist for a missing file
+func removeFile(path string, before func(string) error) error {
+	if _, err := os.Lstat(path); err != nil {
+		return err
+	}
+	if err := before(path); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o644)
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
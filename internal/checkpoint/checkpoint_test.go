@@ -0,0 +1,160 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "test"},
+	} {
+		_, err := git(ctx, dir, args...)
+		require.NoError(t, err)
+	}
+	writeTestFile(t, dir, "main.go", "package main\n")
+	writeTestFile(t, dir, "util.go", "package util\n")
+	writeTestFile(t, dir, ".gitignore", "*.log\n")
+	_, err := git(ctx, dir, "add", "-A")
+	require.NoError(t, err)
+	_, err = git(ctx, dir, "commit", "-q", "-m", "init")
+	require.NoError(t, err)
+	return dir
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, writeFile(filepath.Join(dir, name), []byte(content)))
+}
+
+func readTestFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestSaveAndRestore(t *testing.T) {
+	ctx := context.Background()
+	wd := newRepo(t)
+	dir := t.TempDir()
+
+	writeTestFile(t, wd, "main.go", "package main\n\nfunc main() {}\n")
+	writeTestFile(t, wd, "pkg/new.go", "package pkg\n")
+	writeTestFile(t, wd, "notes.log", "kept\n")
+	original := map[string]string{
+		filepath.Join(wd, "main.go"):   "package main\n",
+		filepath.Join(wd, "notes.log"): "",
+	}
+	checkpoint, err := Save(ctx, dir, wd, "before-refactor", []string{filepath.Join(wd, "notes.log")})
+	require.NoError(t, err)
+	assert.NotEmpty(t, checkpoint.Commit)
+	assert.Equal(t, []File{{Path: "main.go"}, {Path: "notes.log"}, {Path: filepath.Join("pkg", "new.go")}}, checkpoint.Files)
+
+	// A risky change touching saved, committed and new files
+	writeTestFile(t, wd, "main.go", "broken")
+	writeTestFile(t, wd, "util.go", "broken")
+	writeTestFile(t, wd, "notes.log", "broken")
+	writeTestFile(t, wd, "other.go", "package other\n")
+	require.NoError(t, os.Remove(filepath.Join(wd, "pkg", "new.go")))
+	writeTestFile(t, wd, "debug.log", "created after")
+	original[filepath.Join(wd, "debug.log")] = ""
+
+	restored, err := Restore(ctx, dir, wd, "before-refactor", original, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"debug.log", "main.go", "notes.log", "other.go", filepath.Join("pkg", "new.go"), "util.go"}, restored)
+	assert.Equal(t, "package main\n\nfunc main() {}\n", readTestFile(t, wd, "main.go"))
+	assert.Equal(t, "package util\n", readTestFile(t, wd, "util.go"))
+	assert.Equal(t, "kept\n", readTestFile(t, wd, "notes.log"))
+	assert.Equal(t, "package pkg\n", readTestFile(t, wd, "pkg/new.go"))
+	assert.NoFileExists(t, filepath.Join(wd, "other.go"))
+	assert.NoFileExists(t, filepath.Join(wd, "debug.log"))
+
+	restored, err = Restore(ctx, dir, wd, "before-refactor", original, nil)
+	require.NoError(t, err)
+	assert.Empty(t, restored, "the workspace is at the checkpoint")
+}
+
+func TestRestoreWithoutGit(t *testing.T) {
+	ctx := context.Background()
+	wd := t.TempDir()
+	dir := t.TempDir()
+	main := filepath.Join(wd, "main.go")
+	writeTestFile(t, wd, "main.go", "package main\n")
+
+	_, err := Save(ctx, dir, wd, "start", nil)
+	require.NoError(t, err)
+	writeTestFile(t, wd, "main.go", "changed")
+	writeTestFile(t, wd, "new.go", "created")
+
+	created := filepath.Join(wd, "new.go")
+	original := map[string]string{
+		main:    "package main\n",
+		created: "",
+	}
+	var changing []string
+	restored, err := Restore(ctx, dir, wd, "start", original, func(path string) error {
+		changing = append(changing, path)
+		if path == created {
+			return errors.New("snapshot failed")
+		}
+		return nil
+	})
+	assert.ErrorContains(t, err, "snapshot failed")
+	assert.Equal(t, []string{"main.go"}, restored)
+	assert.Equal(t, []string{main, created}, changing)
+	assert.Equal(t, "package main\n", readTestFile(t, wd, "main.go"))
+	assert.FileExists(t, created, "left alone when before fails")
+
+	changing = nil
+	restored, err = Restore(ctx, dir, wd, "start", original, func(path string) error {
+		changing = append(changing, path)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new.go"}, restored)
+	assert.Equal(t, []string{created}, changing, "unchanged files aren't snapshotted")
+	assert.NoFileExists(t, created)
+}
+
+func TestListAndDelete(t *testing.T) {
+	ctx := context.Background()
+	wd := t.TempDir()
+	dir := t.TempDir()
+
+	checkpoints, err := List(dir)
+	require.NoError(t, err)
+	assert.Empty(t, checkpoints)
+
+	_, err = Save(ctx, dir, wd, "../escape", nil)
+	assert.Error(t, err)
+	_, err = Save(ctx, dir, wd, "first", nil)
+	require.NoError(t, err)
+	_, err = Save(ctx, dir, wd, "second", nil)
+	require.NoError(t, err)
+
+	checkpoints, err = List(dir)
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+	assert.Equal(t, "second", checkpoints[0].Name)
+
+	require.NoError(t, Delete(dir, "first"))
+	assert.ErrorIs(t, Delete(dir, "first"), ErrNotFound)
+	_, err = Restore(ctx, dir, wd, "first", nil, nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,80 @@
+package completions
+
+import (
+	"fmt"
+
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/kirmad/superopencode/internal/tui/components/dialog"
+	"github.com/lithammer/fuzzysearch/fuzzy"
+)
+
+type mcpResourcesContextGroup struct {
+	prefix string
+}
+
+func (cg *mcpResourcesContextGroup) GetId() string {
+	return cg.prefix
+}
+
+func (cg *mcpResourcesContextGroup) GetEntry() dialog.CompletionItemI {
+	return dialog.NewCompletionItem(dialog.CompletionItem{
+		Title: "MCP Resources",
+		Value: "mcp",
+	})
+}
+
+func (cg *mcpResourcesContextGroup) GetChildEntries(query string) ([]dialog.CompletionItemI, error) {
+	resources := agent.MCPResources()
+	items := make([]dialog.CompletionItemI, 0, len(resources))
+	for _, r := range resources {
+		title := fmt.Sprintf("%s: %s", r.Server, r.URI)
+		if r.Name != "" && r.Name != r.URI {
+			title = fmt.Sprintf("%s: %s (%s)", r.Server, r.Name, r.URI)
+		}
+		if query != "" && !fuzzy.MatchFold(query, title) {
+			continue
+		}
+		items = append(items, dialog.NewCompletionItem(dialog.CompletionItem{
+			Title: title,
+			Value: r.Mention(),
+		}))
+	}
+	return items, nil
+}
+
+func NewMCPResourcesContextGroup() dialog.CompletionProvider {
+	return &mcpResourcesContextGroup{
+		prefix: "mcp",
+	}
+}
+
+// multiContextGroup shows the entries of several groups in one list, in the
+// order of the groups
+type multiContextGroup struct {
+	groups []dialog.CompletionProvider
+}
+
+func (cg *multiContextGroup) GetId() string {
+	return cg.groups[0].GetId()
+}
+
+func (cg *multiContextGroup) GetEntry() dialog.CompletionItemI {
+	return cg.groups[0].GetEntry()
+}
+
+func (cg *multiContextGroup) GetChildEntries(query string) ([]dialog.CompletionItemI, error) {
+	var items []dialog.CompletionItemI
+	for _, group := range cg.groups {
+		entries, err := group.GetChildEntries(query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s entries: %w", group.GetId(), err)
+		}
+		items = append(items, entries...)
+	}
+	return items, nil
+}
+
+// NewContextGroups combines groups in one provider for the completion dialog
+func NewContextGroups(groups ...dialog.CompletionProvider) dialog.CompletionProvider {
+	return &multiContextGroup{groups: groups}
+}
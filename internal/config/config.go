@@ -5,13 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/locale"
 	"github.com/kirmad/superopencode/internal/logging"
 	"github.com/spf13/viper"
 )
@@ -23,6 +27,7 @@ type MCPType string
 const (
 	MCPStdio MCPType = "stdio"
 	MCPSse   MCPType = "sse"
+	MCPHttp  MCPType = "http"
 )
 
 // MCPServer defines the configuration for a Model Control Protocol server.
@@ -33,6 +38,25 @@ type MCPServer struct {
 	Type    MCPType           `json:"type"`
 	URL     string            `json:"url"`
 	Headers map[string]string `json:"headers"`
+	// OAuth authorizes the requests to remote servers with a bearer token
+	OAuth *MCPOAuth `json:"oauth,omitempty"`
+	// AllowedTools and DeniedTools are glob patterns of the tool names the
+	// server exposes, without the server prefix. Denied tools win.
+	AllowedTools []string `json:"allowedTools,omitempty"`
+	DeniedTools  []string `json:"deniedTools,omitempty"`
+}
+
+// MCPOAuth configures how a token for a remote MCP server is obtained. With an
+// authURL the user logs in with the browser (authorization code with PKCE),
+// without one the client credentials grant is used.
+type MCPOAuth struct {
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret,omitempty"`
+	AuthURL      string   `json:"authUrl,omitempty"`
+	TokenURL     string   `json:"tokenUrl"`
+	Scopes       []string `json:"scopes,omitempty"`
+	// RedirectPort is the local port the login listens on for the redirect
+	RedirectPort int `json:"redirectPort,omitempty"`
 }
 
 type AgentName string
@@ -42,6 +66,9 @@ const (
 	AgentSummarizer AgentName = "summarizer"
 	AgentTask       AgentName = "task"
 	AgentTitle      AgentName = "title"
+	AgentReviewer   AgentName = "reviewer"
+	AgentSecurity   AgentName = "security"
+	AgentMigrator   AgentName = "migrator"
 )
 
 // Agent defines configuration for different LLM models and their token limits.
@@ -53,9 +80,29 @@ type Agent struct {
 
 // Provider defines configuration for an LLM provider.
 type Provider struct {
-	APIKey   string `json:"apiKey"`
-	BaseURL  string `json:"baseURL,omitempty"`
-	Disabled bool   `json:"disabled"`
+	APIKey   string      `json:"apiKey"`
+	BaseURL  string      `json:"baseURL,omitempty"`
+	Disabled bool        `json:"disabled"`
+	Quota    QuotaConfig `json:"quota,omitempty"`
+}
+
+// Quota modes decide what happens once a monthly quota is used up.
+const (
+	QuotaWarn = "warn" // Keep going and warn about the overrun
+	QuotaStop = "stop" // Refuse new requests until the next month
+)
+
+// QuotaConfig limits the monthly usage of a provider API key. A zero limit
+// is not enforced.
+type QuotaConfig struct {
+	MonthlyTokens int64   `json:"monthlyTokens,omitempty"`
+	MonthlyCost   float64 `json:"monthlyCost,omitempty"` // In USD
+	Mode          string  `json:"mode,omitempty"`
+}
+
+// Enabled reports whether any limit is configured
+func (q QuotaConfig) Enabled() bool {
+	return q.MonthlyTokens > 0 || q.MonthlyCost > 0
 }
 
 // Data defines storage configuration.
@@ -63,6 +110,338 @@ type Data struct {
 	Directory string `json:"directory,omitempty"`
 }
 
+// ResponseCacheConfig defines the response cache used by non-interactive runs.
+type ResponseCacheConfig struct {
+	Enabled  bool `json:"enabled,omitempty"`
+	TTLHours int  `json:"ttlHours,omitempty"` // 0 keeps entries until the cache is cleared
+}
+
+// OutputProcessor shapes the result of a non-interactive run before it's
+// printed. Exactly one of its fields is set.
+type OutputProcessor struct {
+	JQ       string `json:"jq,omitempty"`       // jq filter run on the result as JSON, strings are printed raw
+	Template string `json:"template,omitempty"` // Go template executed with the result
+	Command  string `json:"command,omitempty"`  // Shell command reading the output on stdin and printing the new one
+}
+
+// OutputConfig defines the post-processors of non-interactive runs.
+type OutputConfig struct {
+	Processors map[string]OutputProcessor `json:"processors,omitempty"` // Name -> processor
+	Default    []string                   `json:"default,omitempty"`    // Processors applied in order when --post isn't given
+}
+
+// Speculative actions decide how a drafted tool call is handled.
+const (
+	SpeculativeDraft  = "draft"  // Run the drafted call as is
+	SpeculativeVerify = "verify" // Let the agent model approve the call first
+)
+
+// SpeculativeConfig enables two-tier generation for the coder agent: a cheap
+// draft model generates every step and the coder model verifies risky actions.
+type SpeculativeConfig struct {
+	Enabled    bool              `json:"enabled,omitempty"`
+	DraftModel models.ModelID    `json:"draftModel,omitempty"`
+	Rules      map[string]string `json:"rules,omitempty"` // Tool name ("*" for any) -> action
+}
+
+// ActionFor returns the action configured for a tool. Tools without a rule
+// fall back to the "*" rule and then to drafting.
+func (s SpeculativeConfig) ActionFor(toolName string) string {
+	if action, ok := s.Rules[toolName]; ok {
+		return action
+	}
+	if action, ok := s.Rules["*"]; ok {
+		return action
+	}
+	return SpeculativeDraft
+}
+
+// SelfReviewConfig defines the verification pass run after the coder agent
+// changed files during a turn.
+type SelfReviewConfig struct {
+	Enabled        bool     `json:"enabled,omitempty"`
+	Checks         []string `json:"checks,omitempty"`         // Shell commands that must succeed, e.g. "go build ./..."
+	MaxFixAttempts int      `json:"maxFixAttempts,omitempty"` // Fix rounds allowed when checks fail
+	TimeoutSeconds int      `json:"timeoutSeconds,omitempty"` // Timeout of each check
+}
+
+// GenTestsConfig defines when the /gen-tests workflow stops generating tests.
+type GenTestsConfig struct {
+	TargetDelta float64 `json:"targetDelta,omitempty"` // Coverage gain to reach, in percentage points
+	MaxAttempts int     `json:"maxAttempts,omitempty"` // Rounds of test generation at most
+}
+
+// DocsConfig defines where the generate_docs tool writes Markdown docs and
+// which files and symbols it leaves alone.
+type DocsConfig struct {
+	Dir     string   `json:"dir,omitempty"`     // Directory of the Markdown docs, relative to the working directory
+	Exclude []string `json:"exclude,omitempty"` // Glob patterns of file paths or symbol names, e.g. "internal/db/*" or "Test*"
+}
+
+// DependencyAuditConfig defines the licenses the dependency_audit tool accepts.
+type DependencyAuditConfig struct {
+	AllowedLicenses []string `json:"allowedLicenses,omitempty"` // SPDX identifiers, e.g. "MIT", no license check when empty
+}
+
+// MigrationConfig defines how the /migrate workflow splits and checks the
+// files of a migration.
+type MigrationConfig struct {
+	BatchSize      int    `json:"batchSize,omitempty"`      // Files per subagent
+	Parallel       int    `json:"parallel,omitempty"`       // Batches migrated at the same time
+	VerifyCommand  string `json:"verifyCommand,omitempty"`  // Command that must pass in the worktree of each batch, e.g. "go build ./..."
+	MaxFixAttempts int    `json:"maxFixAttempts,omitempty"` // Rounds to fix a failing verify command
+}
+
+// FileDetectionConfig defines the files the file tools don't read, search and
+// edit like source files.
+type FileDetectionConfig struct {
+	Lockfiles          []string `json:"lockfiles,omitempty"`          // Glob patterns of file paths or names, e.g. "package-lock.json"
+	Generated          []string `json:"generated,omitempty"`          // Glob patterns of generated files, e.g. "*.pb.go"
+	MinifiedLineLength int      `json:"minifiedLineLength,omitempty"` // Average line length above which a file counts as minified
+}
+
+// SessionBranchesConfig defines whether each session works on its own git
+// branch.
+type SessionBranchesConfig struct {
+	Enabled bool   `json:"enabled,omitempty"`
+	Prefix  string `json:"prefix,omitempty"` // Branch name before the session ID
+}
+
+// Actions on task sessions past their retention.
+const (
+	TaskSessionsArchive = "archive" // Drop their messages and file versions, keep their usage and cost
+	TaskSessionsDelete  = "delete"  // Delete them, their cost stays in the parent session
+)
+
+// TaskSessionsConfig defines how long the ephemeral sessions created by
+// tasks, reviews and title generation are kept.
+type TaskSessionsConfig struct {
+	RetentionDays int    `json:"retentionDays,omitempty"` // Days since their last update after which they're collected, never when 0 (the default)
+	Action        string `json:"action,omitempty"`        // "archive" or "delete"
+	ShowInPicker  bool   `json:"showInPicker,omitempty"`  // List them in the session picker
+}
+
+// ToolBudget limits the time and the failures of a tool. A tool whose recent
+// runs in a session failed too often is paused for a while, so the agent
+// changes its approach instead of retrying.
+type ToolBudget struct {
+	TimeoutSeconds  int `json:"timeoutSeconds,omitempty"`  // Time after which a run is aborted, no limit when 0
+	MaxFailures     int `json:"maxFailures,omitempty"`     // Failed runs within the window that pause the tool, never when 0
+	FailureWindow   int `json:"failureWindow,omitempty"`   // Latest runs of the tool in a session counted for MaxFailures
+	CooldownSeconds int `json:"cooldownSeconds,omitempty"` // Pause after which the tool may run again
+}
+
+// ToolBudgetsConfig maps tool names, "*" for any other tool, to their budget.
+type ToolBudgetsConfig map[string]ToolBudget
+
+// For returns the budget of a tool. The limits it doesn't set come from the
+// "*" budget.
+func (t ToolBudgetsConfig) For(toolName string) ToolBudget {
+	budget, ok := t[toolName]
+	if !ok {
+		// Keys of the config files are lowercased
+		budget = t[strings.ToLower(toolName)]
+	}
+	fallback := t["*"]
+	if budget.TimeoutSeconds == 0 {
+		budget.TimeoutSeconds = fallback.TimeoutSeconds
+	}
+	if budget.MaxFailures == 0 {
+		budget.MaxFailures = fallback.MaxFailures
+	}
+	if budget.FailureWindow == 0 {
+		budget.FailureWindow = fallback.FailureWindow
+	}
+	if budget.CooldownSeconds == 0 {
+		budget.CooldownSeconds = fallback.CooldownSeconds
+	}
+	return budget
+}
+
+// ToolOutputSummaryConfig defines when a cheap model summarizes long tool
+// outputs for the agents. The full output is kept in a file the summary
+// points to.
+type ToolOutputSummaryConfig struct {
+	Enabled         bool           `json:"enabled,omitempty"`
+	Model           models.ModelID `json:"model,omitempty"`           // Model writing the summaries, the title model when empty
+	ThresholdTokens int            `json:"thresholdTokens,omitempty"` // Outputs longer than this are summarized
+	Tools           []string       `json:"tools,omitempty"`           // Tools whose outputs are summarized, "*" for all
+}
+
+// Summarizes reports whether the outputs of a tool are summarized
+func (t ToolOutputSummaryConfig) Summarizes(toolName string) bool {
+	for _, name := range t.Tools {
+		if name == "*" || name == toolName {
+			return true
+		}
+	}
+	return false
+}
+
+// LargePromptsConfig defines when a prompt too large for a turn is saved in
+// chunks the agent pages through with the read_prompt tool, instead of being
+// sent whole and rejected by the provider.
+type LargePromptsConfig struct {
+	Enabled         bool `json:"enabled,omitempty"`
+	ThresholdTokens int  `json:"thresholdTokens,omitempty"` // Prompts longer than this are chunked, half the input budget of the model when 0
+	ChunkTokens     int  `json:"chunkTokens,omitempty"`     // Size of a chunk
+}
+
+// LocaleConfig defines how dates, times and numbers are written in the
+// prompts and the UI, and the language the agents answer in. The locale of
+// the environment and the local timezone are used when they are empty, the
+// agents answer in the language of the user's messages.
+type LocaleConfig struct {
+	Format   string `json:"format,omitempty"`   // Locale of the formats, e.g. "de-DE", or "iso"
+	Timezone string `json:"timezone,omitempty"` // IANA timezone, e.g. "Europe/Berlin"
+	Language string `json:"language,omitempty"` // Response language, e.g. "German" or "de", overridden per session with /language
+}
+
+// Embedding providers
+const (
+	EmbeddingOpenAI = "openai"
+	EmbeddingVoyage = "voyage"
+	EmbeddingOllama = "ollama"
+	EmbeddingGGUF   = "gguf" // A GGUF model served by the llama.cpp server
+)
+
+// EmbeddingConfig defines the provider of the embeddings shared by the
+// features ranking text by meaning. Embeddings are disabled when the provider
+// is empty.
+type EmbeddingConfig struct {
+	Provider  string `json:"provider,omitempty"`  // openai, voyage, ollama or gguf
+	Model     string `json:"model,omitempty"`     // Model of the provider, its default one when empty
+	BaseURL   string `json:"baseURL,omitempty"`   // Endpoint of the provider, e.g. of a local server
+	APIKey    string `json:"apiKey,omitempty"`    // Key of the provider, the one of the OpenAI provider or $VOYAGE_API_KEY when empty
+	BatchSize int    `json:"batchSize,omitempty"` // Texts embedded per request
+}
+
+// Vector store backends
+const (
+	VectorStoreSQLite   = "sqlite"
+	VectorStoreQdrant   = "qdrant"
+	VectorStorePGVector = "pgvector"
+)
+
+// VectorStoreConfig defines where embeddings are stored and searched. The
+// SQLite file of the data directory suits most projects, Qdrant and
+// PostgreSQL with pgvector scale to large monorepos.
+type VectorStoreConfig struct {
+	Backend string `json:"backend,omitempty"` // sqlite, qdrant or pgvector
+	URL     string `json:"url,omitempty"`     // Endpoint of Qdrant, or connection string of PostgreSQL
+	APIKey  string `json:"apiKey,omitempty"`  // Key of Qdrant, $QDRANT_API_KEY when empty
+	Prefix  string `json:"prefix,omitempty"`  // Prefix of the collections or tables, to share a server between projects
+}
+
+// Knowledge source types
+const (
+	KnowledgeConfluence = "confluence"
+	KnowledgeNotion     = "notion"
+	KnowledgeSite       = "site"
+)
+
+// KnowledgeSource is external documentation indexed for the research
+// subagent
+type KnowledgeSource struct {
+	Type     string `json:"type"`               // confluence, notion or site
+	URL      string `json:"url,omitempty"`      // Base URL of Confluence, or the start page of a docs site
+	Space    string `json:"space,omitempty"`    // Key of the Confluence space
+	Path     string `json:"path,omitempty"`     // Directory of a Notion export in Markdown
+	Username string `json:"username,omitempty"` // Confluence Cloud user the token belongs to, a personal access token is used alone when empty
+	Token    string `json:"token,omitempty"`    // Confluence API token, $CONFLUENCE_TOKEN when empty
+	MaxPages int    `json:"maxPages,omitempty"` // Pages crawled from a docs site
+}
+
+// KnowledgeConfig defines the external documentation indexed in the semantic
+// index, searched by the research subagent with the knowledge_search tool.
+type KnowledgeConfig struct {
+	Sources      map[string]KnowledgeSource `json:"sources,omitempty"`      // Name -> source
+	RefreshHours int                        `json:"refreshHours,omitempty"` // Age of the index of a source before it's synced again, only by hand when 0
+}
+
+// ErrorPatternsConfig defines the learning of the tool and LLM errors that
+// recur across sessions, suggesting what fixed them before.
+type ErrorPatternsConfig struct {
+	Enabled        bool `json:"enabled,omitempty"`
+	MinOccurrences int  `json:"minOccurrences,omitempty"` // Occurrences of an error before suggestions are made
+}
+
+// IdleConfig defines when memory held for idle sessions and LSP servers is
+// released.
+type IdleConfig struct {
+	ReleaseSessionsMinutes int `json:"releaseSessionsMinutes,omitempty"` // Idle time after which the pinned context of background sessions moves to disk, never when 0
+	SuspendLSPMinutes      int `json:"suspendLSPMinutes,omitempty"`      // Idle time after which the LSP servers stop until the next interaction, never when 0
+}
+
+// HTTPConfig tunes the connections to the providers. A timeout of 0
+// disables it.
+type HTTPConfig struct {
+	ConnectTimeoutSeconds   int  `json:"connectTimeoutSeconds,omitempty"`   // Dialing and the TLS handshake
+	FirstByteTimeoutSeconds int  `json:"firstByteTimeoutSeconds,omitempty"` // From sending the request to the response headers
+	TotalTimeoutSeconds     int  `json:"totalTimeoutSeconds,omitempty"`     // The whole request, streamed responses included
+	MaxIdleConnsPerHost     int  `json:"maxIdleConnsPerHost,omitempty"`     // Kept alive connections per provider host
+	IdleConnTimeoutSeconds  int  `json:"idleConnTimeoutSeconds,omitempty"`  // How long an unused connection is kept alive
+	CompressRequests        bool `json:"compressRequests,omitempty"`        // Gzip request bodies, for endpoints that accept them
+}
+
+// LatencyConfig defines how the time to the first token of a turn is kept low.
+type LatencyConfig struct {
+	Warmup             bool `json:"warmup,omitempty"`             // Open a connection to the provider when a session opens or the model changes
+	FirstTokenTargetMs int  `json:"firstTokenTargetMs,omitempty"` // Turns slower to their first token are flagged, never when 0
+}
+
+// Analytics categories that can be shared.
+const (
+	AnalyticsSessions = "sessions" // Sessions started per day
+	AnalyticsTurns    = "turns"    // Turns and their success per model
+	AnalyticsTools    = "tools"    // Tool calls and their success per built-in tool
+)
+
+// AnalyticsConfig defines the anonymous usage stats. They are kept in the
+// local database, nothing is sent unless an endpoint and categories to share
+// are set.
+type AnalyticsConfig struct {
+	Disabled bool     `json:"disabled,omitempty"` // Don't record usage stats
+	Endpoint string   `json:"endpoint,omitempty"` // URL the daily reports are posted to
+	Share    []string `json:"share,omitempty"`    // Categories sent to the endpoint
+}
+
+// ToolPruningConfig trims the tools a model rarely uses successfully from
+// the tools sent to it, by the tool calls recorded in the usage stats.
+type ToolPruningConfig struct {
+	Enabled       bool             `json:"enabled,omitempty"`
+	Models        []models.ModelID `json:"models,omitempty"`        // Models whose tools are pruned, usually the weaker ones
+	MinCalls      int              `json:"minCalls,omitempty"`      // Tool calls recorded for a model before its tools are pruned
+	MinShare      float64          `json:"minShare,omitempty"`      // Share of the successful calls of the model a tool needs to be kept
+	Days          int              `json:"days,omitempty"`          // Days of usage stats considered
+	AlwaysInclude []string         `json:"alwaysInclude,omitempty"` // Tools never pruned
+}
+
+// Router tiers classify a request by the work it needs.
+const (
+	RouterTrivial  = "trivial"  // Short questions without code changes
+	RouterStandard = "standard" // Everything else
+	RouterComplex  = "complex"  // Changes spanning several files
+)
+
+// RouterConfig picks the coder model of each request by its complexity.
+type RouterConfig struct {
+	Enabled         bool                      `json:"enabled,omitempty"`
+	Tiers           map[string]models.ModelID `json:"tiers,omitempty"`           // Tier -> model, the coder model for missing tiers
+	TrivialMaxChars int                       `json:"trivialMaxChars,omitempty"` // Longer requests are never trivial
+	ComplexMinFiles int                       `json:"complexMinFiles,omitempty"` // File references that make a request complex
+	ComplexKeywords []string                  `json:"complexKeywords,omitempty"` // Words that make a request complex
+}
+
+// LogConfig defines the log levels and the structured log file.
+type LogConfig struct {
+	Level     string            `json:"level,omitempty"`     // Default level: debug, info, warn or error
+	Modules   map[string]string `json:"modules,omitempty"`   // Module -> level, e.g. "llm/agent": "debug"
+	File      bool              `json:"file,omitempty"`      // Write JSON logs to logs/opencode.log in the data directory
+	MaxSizeMB int               `json:"maxSizeMB,omitempty"` // Size after which the log file is rotated
+	MaxFiles  int               `json:"maxFiles,omitempty"`  // Rotated log files to keep
+}
+
 // LSPConfig defines configuration for Language Server Protocol integration.
 type LSPConfig struct {
 	Disabled bool     `json:"enabled"`
@@ -73,13 +452,17 @@ type LSPConfig struct {
 
 // TUIConfig defines the configuration for the Terminal User Interface.
 type TUIConfig struct {
-	Theme string `json:"theme,omitempty"`
+	Theme     string `json:"theme,omitempty"`
+	HideUsage bool   `json:"hideUsage,omitempty"` // Hide the tokens and cost under assistant messages
 }
 
 // ShellConfig defines the configuration for the shell used by the bash tool.
 type ShellConfig struct {
 	Path string   `json:"path,omitempty"`
 	Args []string `json:"args,omitempty"`
+	// Env are KEY=VALUE variables injected into the bash commands, the value
+	// may reference environment variables like ${NPM_TOKEN}
+	Env []string `json:"env,omitempty"`
 }
 
 // CopilotConfig holds all Copilot-related configuration
@@ -152,10 +535,40 @@ type Config struct {
 	Debug        bool                              `json:"debug,omitempty"`
 	DebugLSP     bool                              `json:"debugLSP,omitempty"`
 	ContextPaths []string                          `json:"contextPaths,omitempty"`
+	AllowedTools []string                          `json:"allowedTools,omitempty"` // Tools the agents may use, all when empty
 	TUI          TUIConfig                         `json:"tui"`
 	Shell        ShellConfig                       `json:"shell,omitempty"`
 	AutoCompact  bool                              `json:"autoCompact,omitempty"`
 	DetailedLogs bool                              `json:"detailedLogs,omitempty"`
+	Log          LogConfig                         `json:"log,omitempty"`
+
+	ResponseCache ResponseCacheConfig `json:"responseCache,omitempty"`
+	Output        OutputConfig        `json:"output,omitempty"`
+	Speculative   SpeculativeConfig   `json:"speculative,omitempty"`
+	SelfReview    SelfReviewConfig    `json:"selfReview,omitempty"`
+	Router        RouterConfig        `json:"router,omitempty"`
+	Docs          DocsConfig          `json:"docs,omitempty"`
+	GenTests      GenTestsConfig      `json:"genTests,omitempty"`
+
+	DependencyAudit DependencyAuditConfig `json:"dependencyAudit,omitempty"`
+	Migration       MigrationConfig       `json:"migration,omitempty"`
+	SessionBranches SessionBranchesConfig `json:"sessionBranches,omitempty"`
+	TaskSessions    TaskSessionsConfig    `json:"taskSessions,omitempty"`
+
+	ToolOutputSummary ToolOutputSummaryConfig `json:"toolOutputSummary,omitempty"`
+	ToolBudgets       ToolBudgetsConfig       `json:"toolBudgets,omitempty"`
+	LargePrompts      LargePromptsConfig      `json:"largePrompts,omitempty"`
+	Locale            LocaleConfig            `json:"locale,omitempty"`
+	Embedding         EmbeddingConfig         `json:"embedding,omitempty"`
+	VectorStore       VectorStoreConfig       `json:"vectorStore,omitempty"`
+	Knowledge         KnowledgeConfig         `json:"knowledge,omitempty"`
+	ErrorPatterns     ErrorPatternsConfig     `json:"errorPatterns,omitempty"`
+	FileDetection   FileDetectionConfig   `json:"fileDetection,omitempty"`
+	Idle            IdleConfig            `json:"idle,omitempty"`
+	HTTP            HTTPConfig            `json:"http,omitempty"`
+	Latency         LatencyConfig         `json:"latency,omitempty"`
+	Analytics       AnalyticsConfig       `json:"analytics,omitempty"`
+	ToolPruning     ToolPruningConfig     `json:"toolPruning,omitempty"`
 }
 
 // Application constants
@@ -230,47 +643,48 @@ func getDefaultContextPaths() []string {
 	return contextPaths
 }
 
-// Global configuration instance
-var cfg *Config
+var (
+	// current is the global configuration. Once loaded it's replaced as a
+	// whole instead of being changed in place, so a config returned by Get can
+	// be read while it's updated.
+	current atomic.Pointer[Config]
+	// updateMu serializes the updates of the configuration
+	updateMu sync.Mutex
+)
 
 // Load initializes the configuration from environment variables and config files.
 // If debug is true, debug mode is enabled and log level is set to debug.
 // It returns an error if configuration loading fails.
 func Load(workingDir string, debug bool) (*Config, error) {
-	if cfg != nil {
+	if cfg := current.Load(); cfg != nil {
 		return cfg, nil
 	}
 
-	cfg = &Config{
+	cfg := &Config{
 		WorkingDir: workingDir,
 		MCPServers: make(map[string]MCPServer),
 		Providers:  make(map[models.ModelProvider]Provider),
 		LSP:        make(map[string]LSPConfig),
 	}
+	current.Store(cfg)
 
-	configureViper()
-	setDefaults(debug)
-
-	// Read global config
-	if err := readConfig(viper.ReadInConfig()); err != nil {
+	debugFlag = debug
+	if err := readConfigFiles(workingDir, debug); err != nil {
 		return cfg, err
 	}
 
-	// Load and merge local config
-	mergeLocalConfig(workingDir)
-
-	setProviderDefaults()
-
 	// Apply configuration to the struct
 	if err := viper.Unmarshal(cfg); err != nil {
 		return cfg, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
-
-	applyDefaultValues()
-	defaultLevel := slog.LevelInfo
-	if cfg.Debug {
-		defaultLevel = slog.LevelDebug
+	// The files as read, before validation, for reloads to compare against
+	loaded = &Config{}
+	if err := viper.Unmarshal(loaded); err != nil {
+		return cfg, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+
+	applyDefaultValues(cfg)
+	var console slog.Handler
 	if os.Getenv("OPENCODE_DEV_DEBUG") == "true" {
 		loggingFile := fmt.Sprintf("%s/%s", cfg.Data.Directory, "debug.log")
 		messagesPath := fmt.Sprintf("%s/%s", cfg.Data.Directory, "messages")
@@ -296,21 +710,29 @@ func Load(workingDir string, debug bool) (*Config, error) {
 		if err != nil {
 			return cfg, fmt.Errorf("failed to open log file: %w", err)
 		}
-		// Configure logger
-		logger := slog.New(slog.NewTextHandler(sloggingFileWriter, &slog.HandlerOptions{
-			Level: defaultLevel,
-		}))
-		slog.SetDefault(logger)
+		console = slog.NewTextHandler(sloggingFileWriter, &slog.HandlerOptions{
+			Level:       slog.LevelDebug,
+			ReplaceAttr: logging.ZonedTime,
+		})
 	} else {
-		// Configure logger
-		logger := slog.New(slog.NewTextHandler(logging.NewWriter(), &slog.HandlerOptions{
-			Level: defaultLevel,
-		}))
-		slog.SetDefault(logger)
+		console = slog.NewTextHandler(logging.NewWriter(), &slog.HandlerOptions{
+			Level:       slog.LevelDebug,
+			ReplaceAttr: logging.ZonedTime,
+		})
+	}
+	// Configure logger
+	logging.CrashDir = filepath.Join(dataDirectory(cfg), "crashes")
+	logging.SetCrashInfo("config", Snapshot)
+	configureLogLevels(cfg)
+	slog.SetDefault(slog.New(logging.NewHandler(console, openLogFile(cfg))))
+	for _, layer := range layers {
+		for _, key := range layer.Unknown {
+			logging.Warn("Unknown config key", "key", key, "file", layer.Source)
+		}
 	}
 
 	// Validate configuration
-	if err := Validate(); err != nil {
+	if err := validate(cfg); err != nil {
 		return cfg, fmt.Errorf("config validation failed: %w", err)
 	}
 
@@ -323,9 +745,44 @@ func Load(workingDir string, debug bool) (*Config, error) {
 		Model:     cfg.Agents[AgentTitle].Model,
 		MaxTokens: 80,
 	}
+
+	// The reviewer, the security auditor and the migrator use the coder's
+	// model unless another one is configured
+	for _, name := range []AgentName{AgentReviewer, AgentSecurity, AgentMigrator} {
+		if _, ok := cfg.Agents[name]; !ok {
+			cfg.Agents[name] = cfg.Agents[AgentCoder]
+		}
+	}
 	return cfg, nil
 }
 
+// readConfigFiles reads the user, local and project config files into viper
+// over the defaults
+func readConfigFiles(workingDir string, debug bool) error {
+	layers = nil
+	configureViper()
+	setDefaults(debug)
+
+	// Read global config
+	if err := readConfig(viper.ReadInConfig()); err != nil {
+		return err
+	}
+	if file := viper.ConfigFileUsed(); file != "" {
+		if layer, err := readLayer(file); err == nil {
+			layers = append(layers, layer)
+		}
+	}
+
+	// Load and merge local config
+	mergeLocalConfig(workingDir)
+	if err := mergeProjectConfig(workingDir); err != nil {
+		return err
+	}
+
+	setProviderDefaults()
+	return nil
+}
+
 // configureViper sets up viper's configuration paths and environment variables.
 func configureViper() {
 	viper.SetConfigName(fmt.Sprintf(".%s", appName))
@@ -356,6 +813,29 @@ func setDefaults(debug bool) {
 	viper.SetDefault("detailedLogs", false)
 	viper.SetDefault("detailedLogsPort", 8080)
 
+	// Response caching is opt-in
+	viper.SetDefault("responseCache.enabled", false)
+	viper.SetDefault("responseCache.ttlHours", 24*7)
+
+	// Speculative drafting is opt-in, file changes and shell commands are
+	// verified by default
+	viper.SetDefault("speculative.enabled", false)
+	viper.SetDefault("speculative.rules", map[string]string{
+		"*":     SpeculativeDraft,
+		"bash":  SpeculativeVerify,
+		"edit":  SpeculativeVerify,
+		"write": SpeculativeVerify,
+		"patch": SpeculativeVerify,
+	})
+
+	// Model routing is opt-in
+	viper.SetDefault("router.enabled", false)
+	viper.SetDefault("router.trivialMaxChars", 200)
+	viper.SetDefault("router.complexMinFiles", 3)
+	viper.SetDefault("router.complexKeywords", []string{
+		"refactor", "migrate", "rewrite", "rename", "redesign", "across", "every file", "whole codebase",
+	})
+
 	// Set Copilot defaults
 	viper.SetDefault("copilot.enable_copilot", false)
 	viper.SetDefault("copilot.chat_enabled", true)
@@ -366,6 +846,79 @@ func setDefaults(debug bool) {
 	viper.SetDefault("copilot.retry_attempts", 3)
 	viper.SetDefault("copilot.log_level", "info")
 
+	// Structured JSON logs are written to the data directory
+	viper.SetDefault("log.file", false)
+	viper.SetDefault("log.maxSizeMB", 10)
+	viper.SetDefault("log.maxFiles", 5)
+
+	// Self review is opt-in
+	viper.SetDefault("selfReview.enabled", false)
+	viper.SetDefault("selfReview.maxFixAttempts", 2)
+	viper.SetDefault("selfReview.timeoutSeconds", 300)
+
+	viper.SetDefault("docs.dir", "docs")
+
+	viper.SetDefault("genTests.targetDelta", 10)
+	viper.SetDefault("genTests.maxAttempts", 3)
+
+	viper.SetDefault("migration.batchSize", 10)
+	viper.SetDefault("migration.parallel", 3)
+	viper.SetDefault("migration.maxFixAttempts", 2)
+
+	viper.SetDefault("sessionBranches.prefix", "opencode/session-")
+
+	viper.SetDefault("taskSessions.action", TaskSessionsDelete)
+
+	// A tool failing half of its latest runs is paused for two minutes, and
+	// searches that take long are better narrowed down
+	viper.SetDefault("toolBudgets.*.maxFailures", 5)
+	viper.SetDefault("toolBudgets.*.failureWindow", 10)
+	viper.SetDefault("toolBudgets.*.cooldownSeconds", 120)
+	viper.SetDefault("toolBudgets.bash.timeoutSeconds", 600)
+	viper.SetDefault("toolBudgets.grep.timeoutSeconds", 30)
+	viper.SetDefault("toolBudgets.glob.timeoutSeconds", 30)
+
+	// Summarizing long tool outputs is opt-in
+	viper.SetDefault("toolOutputSummary.enabled", false)
+	viper.SetDefault("toolOutputSummary.thresholdTokens", 8000)
+	viper.SetDefault("toolOutputSummary.tools", []string{
+		"bash", "fetch", "sourcegraph", "flaky_test", "profile", "dependency_audit",
+	})
+
+	viper.SetDefault("largePrompts.enabled", true)
+	viper.SetDefault("largePrompts.thresholdTokens", 0)
+	viper.SetDefault("largePrompts.chunkTokens", 4000)
+
+	viper.SetDefault("embedding.batchSize", 64)
+	viper.SetDefault("vectorStore.backend", VectorStoreSQLite)
+	viper.SetDefault("vectorStore.prefix", "opencode_")
+	viper.SetDefault("knowledge.refreshHours", 24)
+	viper.SetDefault("errorPatterns.minOccurrences", 3)
+
+	viper.SetDefault("fileDetection.lockfiles", []string{
+		"package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "bun.lockb",
+		"go.sum", "Cargo.lock", "poetry.lock", "Pipfile.lock", "uv.lock", "Gemfile.lock",
+		"composer.lock", "mix.lock", "pubspec.lock", "packages.lock.json", "flake.lock",
+	})
+	viper.SetDefault("fileDetection.generated", []string{
+		"*.pb.go", "*_pb2.py", "*.pb.cc", "*.pb.h", "*.min.js", "*.min.css", "*.map", "*_generated.go", "*.gen.go",
+	})
+	viper.SetDefault("fileDetection.minifiedLineLength", 1000)
+
+	viper.SetDefault("idle.releaseSessionsMinutes", 30)
+	viper.SetDefault("idle.suspendLSPMinutes", 15)
+
+	viper.SetDefault("http.connectTimeoutSeconds", 10)
+	viper.SetDefault("http.maxIdleConnsPerHost", 16)
+	viper.SetDefault("http.idleConnTimeoutSeconds", 90)
+
+	viper.SetDefault("latency.firstTokenTargetMs", 2000)
+
+	viper.SetDefault("toolPruning.minCalls", 50)
+	viper.SetDefault("toolPruning.minShare", 0.01)
+	viper.SetDefault("toolPruning.days", 30)
+	viper.SetDefault("toolPruning.alwaysInclude", []string{"view", "edit", "write", "bash"})
+
 	if debug {
 		viper.SetDefault("debug", true)
 		viper.Set("log.level", "debug")
@@ -582,12 +1135,14 @@ func mergeLocalConfig(workingDir string) {
 
 	// Merge local config if it exists
 	if err := local.ReadInConfig(); err == nil {
-		viper.MergeConfigMap(local.AllSettings())
+		layer := newLayer(local.ConfigFileUsed(), local.AllSettings())
+		layers = append(layers, layer)
+		viper.MergeConfigMap(layer.Settings)
 	}
 }
 
 // applyDefaultValues sets default values for configuration fields that need processing.
-func applyDefaultValues() {
+func applyDefaultValues(cfg *Config) {
 	// Set default MCP type if not specified
 	for k, v := range cfg.MCPServers {
 		if v.Type == "" {
@@ -610,7 +1165,7 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 			"configured_model", agent.Model)
 
 		// Set default model based on available providers
-		if setDefaultModelForAgent(name) {
+		if setDefaultModelForAgent(cfg, name) {
 			logging.Info("set default model for agent", "agent", name, "model", cfg.Agents[name].Model)
 		} else {
 			return fmt.Errorf("no valid provider available for agent %s", name)
@@ -632,7 +1187,7 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 				"provider", provider)
 
 			// Set default model based on available providers
-			if setDefaultModelForAgent(name) {
+			if setDefaultModelForAgent(cfg, name) {
 				logging.Info("set default model for agent", "agent", name, "model", cfg.Agents[name].Model)
 			} else {
 				return fmt.Errorf("no valid provider available for agent %s", name)
@@ -652,7 +1207,7 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 			"provider", provider)
 
 		// Set default model based on available providers
-		if setDefaultModelForAgent(name) {
+		if setDefaultModelForAgent(cfg, name) {
 			logging.Info("set default model for agent", "agent", name, "model", cfg.Agents[name].Model)
 		} else {
 			return fmt.Errorf("no valid provider available for agent %s", name)
@@ -732,10 +1287,254 @@ func validateAgent(cfg *Config, name AgentName, agent Agent) error {
 }
 
 // Validate checks if the configuration is valid and applies defaults where needed.
+// validateSpeculative checks the draft model and the routing rules
+func validateSpeculative(cfg *Config) error {
+	model, ok := models.SupportedModels[cfg.Speculative.DraftModel]
+	if !ok {
+		return fmt.Errorf("unsupported draft model %q", cfg.Speculative.DraftModel)
+	}
+	if providerCfg, ok := cfg.Providers[model.Provider]; !ok || providerCfg.Disabled {
+		return fmt.Errorf("provider %s of draft model %s is not configured", model.Provider, model.ID)
+	}
+	for tool, action := range cfg.Speculative.Rules {
+		if action != SpeculativeDraft && action != SpeculativeVerify {
+			return fmt.Errorf("invalid action %q for tool %s, use %q or %q", action, tool, SpeculativeDraft, SpeculativeVerify)
+		}
+	}
+	return nil
+}
+
+// validateOutput drops the post-processors that don't set exactly one of
+// their fields, and the default ones that aren't defined
+func validateOutput(cfg *Config) {
+	for name, processor := range cfg.Output.Processors {
+		set := 0
+		for _, field := range []string{processor.JQ, processor.Template, processor.Command} {
+			if field != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			logging.Warn("ignoring output processor, set one of jq, template or command", "processor", name)
+			delete(cfg.Output.Processors, name)
+		}
+	}
+	var valid []string
+	for _, name := range cfg.Output.Default {
+		if _, ok := cfg.Output.Processors[name]; !ok {
+			logging.Warn("ignoring unknown default output processor", "processor", name)
+			continue
+		}
+		valid = append(valid, name)
+	}
+	cfg.Output.Default = valid
+}
+
+// validateToolOutputSummary checks the summary model, falling back to the
+// model of the title agent
+func validateToolOutputSummary(cfg *Config) error {
+	if cfg.ToolOutputSummary.Model == "" {
+		cfg.ToolOutputSummary.Model = cfg.Agents[AgentTitle].Model
+	}
+	model, ok := models.SupportedModels[cfg.ToolOutputSummary.Model]
+	if !ok {
+		return fmt.Errorf("unsupported summary model %q", cfg.ToolOutputSummary.Model)
+	}
+	if providerCfg, ok := cfg.Providers[model.Provider]; !ok || providerCfg.Disabled {
+		return fmt.Errorf("provider %s of summary model %s is not configured", model.Provider, model.ID)
+	}
+	if cfg.ToolOutputSummary.ThresholdTokens <= 0 {
+		return fmt.Errorf("invalid threshold of %d tokens", cfg.ToolOutputSummary.ThresholdTokens)
+	}
+	return nil
+}
+
+// validateLargePrompts resets the sizes of large prompts that can't be used
+func validateLargePrompts(cfg *Config) {
+	if cfg.LargePrompts.ThresholdTokens < 0 {
+		logging.Warn("invalid large prompt threshold, using half the input budget of the model", "thresholdTokens", cfg.LargePrompts.ThresholdTokens)
+		cfg.LargePrompts.ThresholdTokens = 0
+	}
+	if cfg.LargePrompts.ChunkTokens <= 0 {
+		logging.Warn("invalid large prompt chunk size, using 4000 tokens", "chunkTokens", cfg.LargePrompts.ChunkTokens)
+		cfg.LargePrompts.ChunkTokens = 4000
+	}
+}
+
+// validateLocale applies the locale and timezone, falling back to the ones of
+// the environment when they can't be used
+func validateLocale(cfg *Config) {
+	if err := locale.Set(cfg.Locale.Format, cfg.Locale.Timezone); err != nil {
+		logging.Warn("ignoring invalid locale", "error", err)
+		cfg.Locale = LocaleConfig{}
+		locale.Set("", "")
+	}
+}
+
+// validateEmbedding disables the embeddings of an unknown provider
+func validateEmbedding(cfg *Config) {
+	switch cfg.Embedding.Provider {
+	case "", EmbeddingOpenAI, EmbeddingVoyage, EmbeddingOllama, EmbeddingGGUF:
+	default:
+		logging.Warn("disabling embeddings of unknown provider", "provider", cfg.Embedding.Provider)
+		cfg.Embedding.Provider = ""
+	}
+	if cfg.Embedding.BatchSize <= 0 {
+		logging.Warn("invalid embedding batch size, using 64", "batchSize", cfg.Embedding.BatchSize)
+		cfg.Embedding.BatchSize = 64
+	}
+}
+
+// validateVectorStore falls back to the SQLite vector store when the
+// configured backend can't be used
+func validateVectorStore(cfg *Config) {
+	switch cfg.VectorStore.Backend {
+	case VectorStoreSQLite, VectorStoreQdrant:
+	case VectorStorePGVector:
+		if cfg.VectorStore.URL == "" {
+			logging.Warn("pgvector needs the connection string of PostgreSQL in vectorStore.url, using SQLite")
+			cfg.VectorStore.Backend = VectorStoreSQLite
+		}
+	default:
+		logging.Warn("unknown vector store backend, using SQLite", "backend", cfg.VectorStore.Backend)
+		cfg.VectorStore.Backend = VectorStoreSQLite
+	}
+}
+
+// validateKnowledge drops the knowledge sources missing what they're read
+// from
+func validateKnowledge(cfg *Config) {
+	for name, source := range cfg.Knowledge.Sources {
+		var missing string
+		switch source.Type {
+		case KnowledgeConfluence:
+			if source.URL == "" || source.Space == "" {
+				missing = "url and space"
+			}
+		case KnowledgeNotion:
+			if source.Path == "" {
+				missing = "path"
+			}
+		case KnowledgeSite:
+			if source.URL == "" {
+				missing = "url"
+			}
+		default:
+			logging.Warn("ignoring knowledge source of unknown type", "source", name, "type", source.Type)
+			delete(cfg.Knowledge.Sources, name)
+			continue
+		}
+		if missing != "" {
+			logging.Warn("ignoring knowledge source without "+missing, "source", name)
+			delete(cfg.Knowledge.Sources, name)
+		}
+	}
+	if cfg.Knowledge.RefreshHours < 0 {
+		logging.Warn("invalid knowledge refresh interval, syncing by hand only", "refreshHours", cfg.Knowledge.RefreshHours)
+		cfg.Knowledge.RefreshHours = 0
+	}
+}
+
+// validateErrorPatterns resets an invalid number of occurrences before
+// suggestions
+func validateErrorPatterns(cfg *Config) {
+	if cfg.ErrorPatterns.MinOccurrences < 2 {
+		logging.Warn("invalid errorPatterns.minOccurrences, using 3", "minOccurrences", cfg.ErrorPatterns.MinOccurrences)
+		cfg.ErrorPatterns.MinOccurrences = 3
+	}
+}
+
+// validateRouter drops the tiers whose model cannot be used
+func validateRouter(cfg *Config) {
+	for tier, modelID := range cfg.Router.Tiers {
+		if tier != RouterTrivial && tier != RouterStandard && tier != RouterComplex {
+			logging.Warn("ignoring unknown router tier", "tier", tier)
+			delete(cfg.Router.Tiers, tier)
+			continue
+		}
+		model, ok := models.SupportedModels[modelID]
+		if !ok {
+			logging.Warn("ignoring router tier with unsupported model", "tier", tier, "model", modelID)
+			delete(cfg.Router.Tiers, tier)
+			continue
+		}
+		if providerCfg, ok := cfg.Providers[model.Provider]; !ok || providerCfg.Disabled {
+			logging.Warn("ignoring router tier with unconfigured provider", "tier", tier, "model", modelID)
+			delete(cfg.Router.Tiers, tier)
+		}
+	}
+}
+
+// configureLogLevels applies the configured default and module log levels
+func configureLogLevels(cfg *Config) {
+	level := slog.LevelInfo
+	if cfg.Log.Level != "" {
+		if parsed, err := logging.ParseLevel(cfg.Log.Level); err == nil {
+			level = parsed
+		}
+	}
+	if cfg.Debug {
+		level = slog.LevelDebug
+	}
+	logging.SetLevel("", level)
+	for module, name := range cfg.Log.Modules {
+		if parsed, err := logging.ParseLevel(name); err == nil {
+			logging.SetLevel(module, parsed)
+		}
+	}
+}
+
+// openLogFile returns the handler of the structured log file, nil when the
+// file is disabled or cannot be opened
+func openLogFile(cfg *Config) slog.Handler {
+	if !cfg.Log.File {
+		return nil
+	}
+	path := filepath.Join(dataDirectory(cfg), "logs", "opencode.log")
+	file, err := logging.OpenRotatingFile(path, int64(cfg.Log.MaxSizeMB)*1024*1024, cfg.Log.MaxFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return nil
+	}
+	return slog.NewJSONHandler(file, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: logging.ZonedTime})
+}
+
+// dataDirectory returns the data directory, relative to the working directory
+// when it isn't absolute
+func dataDirectory(cfg *Config) string {
+	if filepath.IsAbs(cfg.Data.Directory) {
+		return cfg.Data.Directory
+	}
+	return filepath.Join(cfg.WorkingDir, cfg.Data.Directory)
+}
+
+// validateLog drops invalid log levels
+func validateLog(cfg *Config) {
+	if cfg.Log.Level != "" {
+		if _, err := logging.ParseLevel(cfg.Log.Level); err != nil {
+			logging.Warn("ignoring invalid log level", "error", err)
+			cfg.Log.Level = ""
+		}
+	}
+	for module, name := range cfg.Log.Modules {
+		if _, err := logging.ParseLevel(name); err != nil {
+			logging.Warn("ignoring invalid module log level", "module", module, "error", err)
+			delete(cfg.Log.Modules, module)
+		}
+	}
+}
+
 func Validate() error {
+	cfg := Get()
 	if cfg == nil {
 		return fmt.Errorf("config not loaded")
 	}
+	return validate(cfg)
+}
+
+// validate fixes or drops the invalid settings of a config, it fails when the
+// config can't be used
+func validate(cfg *Config) error {
 
 	// Validate agent models
 	for name, agent := range cfg.Agents {
@@ -744,6 +1543,43 @@ func Validate() error {
 		}
 	}
 
+	// Validate speculative drafting
+	if cfg.Speculative.Enabled {
+		if err := validateSpeculative(cfg); err != nil {
+			logging.Warn("disabling speculative drafting", "error", err)
+			cfg.Speculative.Enabled = false
+		}
+	}
+
+	// Validate model routing
+	if cfg.Router.Enabled {
+		validateRouter(cfg)
+	}
+
+	validateLog(cfg)
+	validateOutput(cfg)
+
+	if cfg.ToolOutputSummary.Enabled {
+		if err := validateToolOutputSummary(cfg); err != nil {
+			logging.Warn("disabling tool output summaries", "error", err)
+			cfg.ToolOutputSummary.Enabled = false
+		}
+	}
+
+	validateLargePrompts(cfg)
+	validateLocale(cfg)
+	validateEmbedding(cfg)
+	validateVectorStore(cfg)
+	validateKnowledge(cfg)
+	validateErrorPatterns(cfg)
+
+	switch cfg.TaskSessions.Action {
+	case TaskSessionsArchive, TaskSessionsDelete:
+	default:
+		logging.Warn("invalid task sessions action, deleting them", "action", cfg.TaskSessions.Action)
+		cfg.TaskSessions.Action = TaskSessionsDelete
+	}
+
 	// Validate providers
 	for provider, providerCfg := range cfg.Providers {
 		if providerCfg.APIKey == "" && !providerCfg.Disabled {
@@ -752,6 +1588,16 @@ func Validate() error {
 			providerCfg.Disabled = true
 			cfg.Providers[provider] = providerCfg
 		}
+		switch providerCfg.Quota.Mode {
+		case QuotaWarn, QuotaStop:
+		case "":
+			providerCfg.Quota.Mode = QuotaWarn
+			cfg.Providers[provider] = providerCfg
+		default:
+			logging.Warn("invalid quota mode, warning only", "provider", provider, "mode", providerCfg.Quota.Mode)
+			providerCfg.Quota.Mode = QuotaWarn
+			cfg.Providers[provider] = providerCfg
+		}
 	}
 
 	// Validate LSP configurations
@@ -794,7 +1640,7 @@ func getProviderAPIKey(provider models.ModelProvider) string {
 }
 
 // setDefaultModelForAgent sets a default model for an agent based on available providers
-func setDefaultModelForAgent(agent AgentName) bool {
+func setDefaultModelForAgent(cfg *Config, agent AgentName) bool {
 	if hasCopilotCredentials() {
 		maxTokens := int64(5000)
 		if agent == AgentTitle {
@@ -943,7 +1789,7 @@ func setDefaultModelForAgent(agent AgentName) bool {
 }
 
 func updateCfgFile(updateCfg func(config *Config)) error {
-	if cfg == nil {
+	if Get() == nil {
 		return fmt.Errorf("config not loaded")
 	}
 
@@ -991,18 +1837,47 @@ func updateCfgFile(updateCfg func(config *Config)) error {
 // Get returns the current configuration.
 // It's safe to call this function multiple times.
 func Get() *Config {
-	return cfg
+	return current.Load()
+}
+
+// cloneAgents copies a config with its own agents and providers, which
+// validating an agent changes
+func cloneAgents(cfg *Config) *Config {
+	next := *cfg
+	next.Agents = maps.Clone(cfg.Agents)
+	next.Providers = maps.Clone(cfg.Providers)
+	return &next
 }
 
 // WorkingDirectory returns the current working directory from the configuration.
 func WorkingDirectory() string {
+	cfg := Get()
 	if cfg == nil {
 		panic("config not loaded")
 	}
 	return cfg.WorkingDir
 }
 
+// SetWorkingDirectory moves the working directory of the tools to another
+// directory, like the scratch worktree of a dry run. The data directory stays
+// where it is.
+func SetWorkingDirectory(dir string) {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+	cfg := Get()
+	if cfg == nil {
+		panic("config not loaded")
+	}
+	next := *cfg
+	next.Data.Directory = dataDirectory(cfg)
+	next.WorkingDir = dir
+	current.Store(&next)
+}
+
 func UpdateAgentModel(agentName AgentName, modelID models.ModelID) error {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+	cfg := Get()
 	if cfg == nil {
 		panic("config not loaded")
 	}
@@ -1024,13 +1899,13 @@ func UpdateAgentModel(agentName AgentName, modelID models.ModelID) error {
 		MaxTokens:       maxTokens,
 		ReasoningEffort: existingAgentCfg.ReasoningEffort,
 	}
-	cfg.Agents[agentName] = newAgentCfg
+	next := cloneAgents(cfg)
+	next.Agents[agentName] = newAgentCfg
 
-	if err := validateAgent(cfg, agentName, newAgentCfg); err != nil {
-		// revert config update on failure
-		cfg.Agents[agentName] = existingAgentCfg
+	if err := validateAgent(next, agentName, newAgentCfg); err != nil {
 		return fmt.Errorf("failed to update agent model: %w", err)
 	}
+	current.Store(next)
 
 	return updateCfgFile(func(config *Config) {
 		if config.Agents == nil {
@@ -1040,14 +1915,52 @@ func UpdateAgentModel(agentName AgentName, modelID models.ModelID) error {
 	})
 }
 
+// UpdateAgents replaces the configuration of the given agents and, when
+// allowedTools isn't nil, the allowed tools, then writes them to the config file.
+func UpdateAgents(agents map[AgentName]Agent, allowedTools []string) error {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+	cfg := Get()
+	if cfg == nil {
+		panic("config not loaded")
+	}
+
+	next := cloneAgents(cfg)
+	for name, agent := range agents {
+		next.Agents[name] = agent
+		if err := validateAgent(next, name, agent); err != nil {
+			return fmt.Errorf("failed to update agent %s: %w", name, err)
+		}
+	}
+	if allowedTools != nil {
+		next.AllowedTools = allowedTools
+	}
+	current.Store(next)
+
+	return updateCfgFile(func(config *Config) {
+		if config.Agents == nil {
+			config.Agents = make(map[AgentName]Agent)
+		}
+		maps.Copy(config.Agents, agents)
+		if allowedTools != nil {
+			config.AllowedTools = allowedTools
+		}
+	})
+}
+
 // UpdateTheme updates the theme in the configuration and writes it to the config file.
 func UpdateTheme(themeName string) error {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+	cfg := Get()
 	if cfg == nil {
 		return fmt.Errorf("config not loaded")
 	}
 
 	// Update the in-memory config
-	cfg.TUI.Theme = themeName
+	next := *cfg
+	next.TUI.Theme = themeName
+	current.Store(&next)
 
 	// Update the file config
 	return updateCfgFile(func(config *Config) {
@@ -1055,6 +1968,26 @@ func UpdateTheme(themeName string) error {
 	})
 }
 
+// UpdateHideUsage shows or hides the usage footers of assistant messages
+func UpdateHideUsage(hide bool) error {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+	cfg := Get()
+	if cfg == nil {
+		return fmt.Errorf("config not loaded")
+	}
+
+	// Update the in-memory config
+	next := *cfg
+	next.TUI.HideUsage = hide
+	current.Store(&next)
+
+	// Update the file config
+	return updateCfgFile(func(config *Config) {
+		config.TUI.HideUsage = hide
+	})
+}
+
 // Tries to load Github token from all possible locations
 func LoadGitHubToken() (string, error) {
 	// First check environment variable
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLogFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Cleanup(func() {
+		current.Store(nil)
+		loaded, layers = nil, nil
+		viper.Reset()
+	})
+
+	// Loading the config writes nothing by default
+	dir := t.TempDir()
+	current.Store(nil)
+	loaded, layers = nil, nil
+	viper.Reset()
+	_, err := Load(dir, false)
+	require.NoError(t, err)
+	assert.False(t, Get().Log.File)
+	assert.NoDirExists(t, filepath.Join(dir, ".opencode", "logs"))
+
+	dir = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".opencode.json"), []byte(`{"log": {"file": true}}`), 0o644))
+	current.Store(nil)
+	loaded, layers = nil, nil
+	viper.Reset()
+	_, err = Load(dir, false)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, ".opencode", "logs", "opencode.log"))
+}
+
+func TestLoadReviewerAgent(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Cleanup(func() {
+		current.Store(nil)
+		loaded, layers = nil, nil
+		viper.Reset()
+	})
+
+	// The reviewer uses the coder model by default
+	current.Store(nil)
+	loaded, layers = nil, nil
+	viper.Reset()
+	_, err := Load(t.TempDir(), false)
+	require.NoError(t, err)
+	assert.NotEmpty(t, Get().Agents[AgentCoder].Model)
+	assert.Equal(t, Get().Agents[AgentCoder], Get().Agents[AgentReviewer])
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".opencode.json"), []byte(`{"agents": {"reviewer": {"model": "claude-3-haiku", "maxTokens": 1234}}}`), 0o644))
+	current.Store(nil)
+	loaded, layers = nil, nil
+	viper.Reset()
+	_, err = Load(dir, false)
+	require.NoError(t, err)
+	assert.Equal(t, Agent{Model: "claude-3-haiku", MaxTokens: 1234}, Get().Agents[AgentReviewer])
+}
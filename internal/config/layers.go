@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// projectConfigDir holds the project config, config.json or config.yaml
+const projectConfigDir = ".opencode"
+
+// configLayer is a config file merged into the config. Later layers take
+// precedence.
+type configLayer struct {
+	Source   string
+	Settings map[string]any
+	// Unknown are the keys of the file the config doesn't have
+	Unknown []string
+}
+
+// layers are the config files in order of precedence, lowest first
+var layers []configLayer
+
+// readLayer reads a config file as a layer, the type is taken from the
+// extension
+func readLayer(path string) (configLayer, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return configLayer{}, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	return newLayer(path, v.AllSettings()), nil
+}
+
+func newLayer(source string, settings map[string]any) configLayer {
+	return configLayer{
+		Source:   source,
+		Settings: settings,
+		Unknown:  unknownKeys(settings, reflect.TypeOf(Config{}), ""),
+	}
+}
+
+// projectConfigFile returns the project config of a working directory, empty
+// without one
+func projectConfigFile(workingDir string) string {
+	for _, ext := range []string{"json", "yaml", "yml"} {
+		path := filepath.Join(workingDir, projectConfigDir, "config."+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// mergeProjectConfig merges .opencode/config.(json|yaml) of the working
+// directory over the user config and .opencode.json
+func mergeProjectConfig(workingDir string) error {
+	path := projectConfigFile(workingDir)
+	if path == "" {
+		return nil
+	}
+	layer, err := readLayer(path)
+	if err != nil {
+		return err
+	}
+	layers = append(layers, layer)
+	return viper.MergeConfigMap(layer.Settings)
+}
+
+// Which returns the value of a config key, e.g. "tui.theme", and where it came
+// from: an environment variable, the config file that set it last or the
+// defaults. ok is false for keys that aren't set.
+func Which(key string) (value any, source string, ok bool) {
+	key = strings.ToLower(key)
+	if !viper.IsSet(key) {
+		return nil, "", false
+	}
+	value = viper.Get(key)
+
+	env := strings.ToUpper(appName) + "_" + strings.ToUpper(key)
+	if _, set := os.LookupEnv(env); set {
+		return value, "environment variable " + env, true
+	}
+	for i := len(layers) - 1; i >= 0; i-- {
+		if _, found := lookupKey(layers[i].Settings, key); found {
+			return value, layers[i].Source, true
+		}
+	}
+	return value, "defaults", true
+}
+
+// ConfigFiles returns the config files that were read, lowest precedence
+// first
+func ConfigFiles() []string {
+	files := make([]string, len(layers))
+	for i, layer := range layers {
+		files[i] = layer.Source
+	}
+	return files
+}
+
+// lookupKey looks up a dotted key in nested settings
+func lookupKey(settings map[string]any, key string) (any, bool) {
+	var current any = settings
+	for _, part := range strings.Split(key, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// unknownKeys returns the keys of settings that don't match a field of the
+// config. Like the unmarshalling, it matches the mapstructure tag or else the
+// field name, ignoring case.
+func unknownKeys(settings map[string]any, t reflect.Type, prefix string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var unknown []string
+	for key, value := range settings {
+		// Editors use $schema to find the schema of the file
+		if key == "$schema" && prefix == "" {
+			continue
+		}
+		path := prefix + key
+		var fieldType reflect.Type
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := configField(t, key)
+			if !ok {
+				unknown = append(unknown, path)
+				continue
+			}
+			fieldType = field.Type
+		case reflect.Map:
+			fieldType = t.Elem()
+		default:
+			continue
+		}
+		if nested, ok := value.(map[string]any); ok {
+			unknown = append(unknown, unknownKeys(nested, fieldType, path+".")...)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+func configField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("mapstructure"); ok {
+			name, _, _ = strings.Cut(tag, ",")
+		}
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
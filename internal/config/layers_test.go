@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	assert.Empty(t, projectConfigFile(dir))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, projectConfigDir), 0o755))
+	path := filepath.Join(dir, projectConfigDir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("contextPaths: [CLAUDE.md]\n"), 0o644))
+	assert.Equal(t, path, projectConfigFile(dir))
+}
+
+func TestReadLayer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+$schema: ./opencode-schema.json
+allowedTools: [view, "github_*"]
+tui:
+  theme: dracula
+  colour: red
+lsp:
+  gopls:
+    command: gopls
+    flags: [-v]
+agents:
+  coder:
+    model: claude-4-sonnet
+    temperature: 0.2
+autoCompactt: true
+`), 0o644))
+
+	layer, err := readLayer(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, layer.Source)
+	assert.Equal(t, []string{"agents.coder.temperature", "autocompactt", "lsp.gopls.flags", "tui.colour"}, layer.Unknown)
+
+	value, ok := lookupKey(layer.Settings, "tui.theme")
+	assert.True(t, ok)
+	assert.Equal(t, "dracula", value)
+	_, ok = lookupKey(layer.Settings, "tui.theme.name")
+	assert.False(t, ok)
+	_, ok = lookupKey(layer.Settings, "debug")
+	assert.False(t, ok)
+}
+
+func TestReadLayerInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"debug": `), 0o644))
+	_, err := readLayer(path)
+	assert.ErrorContains(t, err, path)
+}
+
+func TestUnknownKeysMatchesFieldNames(t *testing.T) {
+	settings := map[string]any{
+		"copilot":         map[string]any{"enable_copilot": true, "enablecopilot": true},
+		"sessionbranches": map[string]any{},
+	}
+	assert.Equal(t, []string{"copilot.enablecopilot"}, unknownKeys(settings, reflect.TypeOf(Config{}), ""))
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// reloadableKeys are the config keys read when they're used, changes to them
+// apply without a restart. Other changes are reported as needing one.
+var reloadableKeys = []string{
+	"tui",
+	"shell.env",
+	"log",
+	"autoCompact",
+	"selfReview",
+	"docs",
+	"genTests",
+	"dependencyAudit",
+	"migration",
+	"sessionBranches",
+	"taskSessions",
+	"toolBudgets",
+	"largePrompts",
+	"embedding",
+	"knowledge",
+	"errorPatterns",
+	"fileDetection",
+	"idle",
+	"latency",
+	"analytics",
+}
+
+var (
+	// loaded is the config as read from the files by the last load, before
+	// validation
+	loaded *Config
+	// debugFlag is the debug flag the config was loaded with
+	debugFlag bool
+)
+
+// ReloadResult lists the config keys a reload changed
+type ReloadResult struct {
+	// Applied are the changed keys in effect now
+	Applied []string
+	// Restart are the changed keys that need a restart
+	Restart []string
+}
+
+// WatchedFiles returns the config files a reload reads, including the ones
+// that don't exist yet
+func WatchedFiles() []string {
+	var files []string
+	if file := viper.ConfigFileUsed(); file != "" {
+		files = append(files, file)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, fmt.Sprintf(".%s.json", appName)))
+	}
+	cfg := Get()
+	if cfg == nil {
+		return files
+	}
+	files = append(files, filepath.Join(cfg.WorkingDir, fmt.Sprintf(".%s.json", appName)))
+	for _, ext := range []string{"json", "yaml", "yml"} {
+		files = append(files, filepath.Join(cfg.WorkingDir, projectConfigDir, "config."+ext))
+	}
+	return files
+}
+
+// Reload reads the config files again and applies the changes to the
+// reloadable keys. The changed config is validated and replaces the current
+// one, which is left as it is when a file is invalid.
+func Reload() (ReloadResult, error) {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+	cfg := Get()
+	if cfg == nil || loaded == nil {
+		return ReloadResult{}, fmt.Errorf("config not loaded")
+	}
+	// Files being edited may not parse, check them before replacing the
+	// settings
+	for _, file := range WatchedFiles() {
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		if _, err := readLayer(file); err != nil {
+			return ReloadResult{}, err
+		}
+	}
+
+	viper.Reset()
+	if err := readConfigFiles(cfg.WorkingDir, debugFlag); err != nil {
+		return ReloadResult{}, err
+	}
+	fresh := &Config{}
+	if err := viper.Unmarshal(fresh); err != nil {
+		return ReloadResult{}, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	var result ReloadResult
+	next := cloneAgents(cfg)
+	next.LSP = maps.Clone(cfg.LSP)
+	next.Router.Tiers = maps.Clone(cfg.Router.Tiers)
+	applyChanges(reflect.ValueOf(next).Elem(), reflect.ValueOf(loaded).Elem(), reflect.ValueOf(fresh).Elem(), "", &result)
+	if len(result.Applied) > 0 {
+		if err := validate(next); err != nil {
+			return ReloadResult{}, fmt.Errorf("config validation failed: %w", err)
+		}
+		current.Store(next)
+	}
+	loaded = fresh
+
+	if slices.Contains(result.Applied, "log") {
+		configureLogLevels(next)
+	}
+	return result, nil
+}
+
+// applyChanges copies the reloadable fields that changed between old and
+// fresh to dst, and records the others. Changed fields are replaced rather
+// than updated, dst can share its maps and slices with the current config.
+func applyChanges(dst, old, fresh reflect.Value, prefix string, result *ReloadResult) {
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := prefix + jsonName(t.Field(i))
+		if key == prefix+"wd" || reflect.DeepEqual(old.Field(i).Interface(), fresh.Field(i).Interface()) {
+			continue
+		}
+		switch {
+		case slices.Contains(reloadableKeys, key):
+			dst.Field(i).Set(fresh.Field(i))
+			result.Applied = append(result.Applied, key)
+		case t.Field(i).Type.Kind() == reflect.Struct && hasReloadableKeys(key+"."):
+			applyChanges(dst.Field(i), old.Field(i), fresh.Field(i), key+".", result)
+		default:
+			result.Restart = append(result.Restart, key)
+		}
+	}
+}
+
+func hasReloadableKeys(prefix string) bool {
+	return slices.ContainsFunc(reloadableKeys, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// jsonName returns the name of a field in the config files
+func jsonName(field reflect.StructField) string {
+	if tag, ok := field.Tag.Lookup("json"); ok {
+		if name, _, _ := strings.Cut(tag, ","); name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	dir := t.TempDir()
+	t.Cleanup(func() {
+		current.Store(nil)
+		loaded, layers = nil, nil
+		viper.Reset()
+	})
+	current.Store(nil)
+	loaded, layers = nil, nil
+	viper.Reset()
+
+	local := filepath.Join(dir, ".opencode.json")
+	require.NoError(t, os.WriteFile(local, []byte(`{"tui": {"theme": "dracula"}, "contextPaths": ["a.md"]}`), 0o644))
+	_, err := Load(dir, false)
+	require.NoError(t, err)
+	assert.Contains(t, WatchedFiles(), local)
+
+	require.NoError(t, os.WriteFile(local, []byte(`{"tui": {"theme": "tokyonight"}, "contextPaths": ["b.md"], "shell": {"env": ["A=1"]}}`), 0o644))
+	result, err := Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tui", "shell.env"}, result.Applied)
+	assert.Equal(t, []string{"contextPaths"}, result.Restart)
+	assert.Equal(t, "tokyonight", Get().TUI.Theme)
+	assert.Equal(t, []string{"A=1"}, Get().Shell.Env)
+	assert.Equal(t, []string{"a.md"}, Get().ContextPaths)
+
+	// Nothing changed since the last reload
+	result, err = Reload()
+	require.NoError(t, err)
+	assert.Empty(t, result.Applied)
+	assert.Empty(t, result.Restart)
+
+	// A file being edited leaves the config as it is
+	require.NoError(t, os.WriteFile(local, []byte(`{"tui": {"theme": `), 0o644))
+	_, err = Reload()
+	assert.ErrorContains(t, err, local)
+	assert.Equal(t, "tokyonight", Get().TUI.Theme)
+	assert.Equal(t, local, ConfigFiles()[0])
+}
+
+func TestReloadReplacesConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	dir := t.TempDir()
+	t.Cleanup(func() {
+		current.Store(nil)
+		loaded, layers = nil, nil
+		viper.Reset()
+	})
+	current.Store(nil)
+	loaded, layers = nil, nil
+	viper.Reset()
+
+	local := filepath.Join(dir, ".opencode.json")
+	require.NoError(t, os.WriteFile(local, []byte(`{"tui": {"theme": "dracula"}}`), 0o644))
+	_, err := Load(dir, false)
+	require.NoError(t, err)
+	before := Get()
+
+	// Readers of the config run while it's reloaded
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			_ = Get().TUI.Theme
+			_ = Get().Log.Level
+		}
+	}()
+	require.NoError(t, os.WriteFile(local, []byte(`{"tui": {"theme": "tokyonight"}, "log": {"level": "loud"}}`), 0o644))
+	result, err := Reload()
+	require.NoError(t, err)
+	<-done
+	assert.Equal(t, []string{"tui", "log"}, result.Applied)
+
+	assert.Equal(t, "dracula", before.TUI.Theme, "the previous config is left as it was")
+	assert.Equal(t, "tokyonight", Get().TUI.Theme)
+	assert.Empty(t, Get().Log.Level, "the reloaded config is validated")
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// secretKeys are config keys whose values are never included in a snapshot
+var secretKeys = []string{"apikey", "token", "secret", "password"}
+
+// Snapshot returns the configuration as a map with API keys, tokens, headers
+// and environment values redacted, safe to include in crash reports.
+func Snapshot() any {
+	cfg := Get()
+	if cfg == nil {
+		return nil
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil
+	}
+	var snapshot map[string]any
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil
+	}
+	return redactValue("", snapshot)
+}
+
+func redactValue(key string, value any) any {
+	lower := strings.ToLower(key)
+	switch v := value.(type) {
+	case map[string]any:
+		for k, item := range v {
+			if strings.ToLower(k) == "headers" {
+				v[k] = redactMap(item)
+				continue
+			}
+			v[k] = redactValue(k, item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			if lower == "env" {
+				v[i] = redactEnv(item)
+				continue
+			}
+			v[i] = redactValue(key, item)
+		}
+		return v
+	case string:
+		for _, secret := range secretKeys {
+			if v != "" && strings.Contains(lower, secret) {
+				return redacted
+			}
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// redactMap keeps the keys of a map, e.g. header names, and drops the values
+func redactMap(value any) any {
+	m, ok := value.(map[string]any)
+	if !ok {
+		return value
+	}
+	for k := range m {
+		m[k] = redacted
+	}
+	return m
+}
+
+// redactEnv keeps the name of a NAME=value environment entry
+func redactEnv(value any) any {
+	entry, ok := value.(string)
+	if !ok {
+		return value
+	}
+	name, _, _ := strings.Cut(entry, "=")
+	return name + "=" + redacted
+}
@@ -0,0 +1,224 @@
+// Package coverage measures the test coverage of Go and Python projects and
+// lists the code the tests don't reach.
+package coverage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxFailureOutput is the tail of the test output kept when tests fail
+const maxFailureOutput = 4000
+
+// ErrUnsupported is returned for projects that are neither Go nor Python
+var ErrUnsupported = errors.New("coverage is only supported for Go (go.mod) and Python (pytest with coverage.py) projects")
+
+// Func is a function, or for Python a file, that isn't fully covered
+type Func struct {
+	File    string
+	Line    int
+	Name    string // Empty for Python files
+	Percent float64
+}
+
+// Report is the coverage of a test run
+type Report struct {
+	Total     float64 // Percentage of covered statements
+	Uncovered []Func  // Sorted from least to most covered
+	Failed    bool    // Whether tests failed, the coverage is then incomplete
+	Output    string  // Tail of the test output when tests failed
+}
+
+// Measure runs the tests of target in dir with coverage. target is a Go
+// package pattern like "./internal/..." or a Python test path, empty for the
+// whole project.
+func Measure(ctx context.Context, dir, target string) (Report, error) {
+	switch {
+	case exists(filepath.Join(dir, "go.mod")):
+		return measureGo(ctx, dir, target)
+	case exists(filepath.Join(dir, "pyproject.toml")), exists(filepath.Join(dir, "setup.py")), exists(filepath.Join(dir, "pytest.ini")):
+		return measurePython(ctx, dir, target)
+	}
+	return Report{}, ErrUnsupported
+}
+
+func measureGo(ctx context.Context, dir, target string) (Report, error) {
+	if target == "" {
+		target = "./..."
+	}
+	profile, err := os.CreateTemp("", "opencode-cover-*.out")
+	if err != nil {
+		return Report{}, err
+	}
+	profile.Close()
+	defer os.Remove(profile.Name())
+
+	var report Report
+	out, err := run(ctx, dir, "go", "test", "-coverprofile="+profile.Name(), target)
+	if err != nil {
+		report.Failed = true
+		report.Output = tail(out)
+	}
+	funcs, err := run(ctx, dir, "go", "tool", "cover", "-func="+profile.Name())
+	if err != nil {
+		if report.Failed {
+			// Packages that don't build leave no profile
+			return report, nil
+		}
+		return report, fmt.Errorf("go tool cover: %s", tail(funcs))
+	}
+	report.Total, report.Uncovered = parseGoFuncs(funcs, dir)
+	return report, nil
+}
+
+// parseGoFuncs parses the output of go tool cover -func, lines like
+// "github.com/org/repo/pkg/file.go:12:\tName\t\t85.7%"
+func parseGoFuncs(output, dir string) (float64, []Func) {
+	var total float64
+	var uncovered []Func
+	module := goModule(dir)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(fields[len(fields)-1], "%"), 64)
+		if err != nil {
+			continue
+		}
+		if fields[0] == "total:" {
+			total = percent
+			continue
+		}
+		if percent >= 100 {
+			continue
+		}
+		location := strings.TrimSuffix(fields[0], ":")
+		file, lineText, _ := strings.Cut(location, ":")
+		line, _ := strconv.Atoi(lineText)
+		if module != "" {
+			file = strings.TrimPrefix(strings.TrimPrefix(file, module), "/")
+		}
+		uncovered = append(uncovered, Func{File: file, Line: line, Name: fields[1], Percent: percent})
+	}
+	sortUncovered(uncovered)
+	return total, uncovered
+}
+
+func measurePython(ctx context.Context, dir, target string) (Report, error) {
+	data, err := os.CreateTemp("", "opencode-cover-*.json")
+	if err != nil {
+		return Report{}, err
+	}
+	data.Close()
+	defer os.Remove(data.Name())
+
+	var report Report
+	args := []string{"-m", "coverage", "run", "-m", "pytest"}
+	if target != "" {
+		args = append(args, target)
+	}
+	out, err := run(ctx, dir, "python3", args...)
+	if err != nil {
+		if strings.Contains(out, "No module named coverage") {
+			return report, errors.New("coverage.py is not installed, install it with pip install coverage")
+		}
+		report.Failed = true
+		report.Output = tail(out)
+	}
+	if out, err := run(ctx, dir, "python3", "-m", "coverage", "json", "-o", data.Name()); err != nil {
+		if report.Failed {
+			return report, nil
+		}
+		return report, fmt.Errorf("coverage json: %s", tail(out))
+	}
+	content, err := os.ReadFile(data.Name())
+	if err != nil {
+		return report, err
+	}
+	report.Total, report.Uncovered, err = parsePythonJSON(content)
+	return report, err
+}
+
+// parsePythonJSON parses the report of coverage json
+func parsePythonJSON(content []byte) (float64, []Func, error) {
+	var data struct {
+		Files map[string]struct {
+			Summary struct {
+				PercentCovered float64 `json:"percent_covered"`
+			} `json:"summary"`
+			MissingLines []int `json:"missing_lines"`
+		} `json:"files"`
+		Totals struct {
+			PercentCovered float64 `json:"percent_covered"`
+		} `json:"totals"`
+	}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return 0, nil, fmt.Errorf("failed to parse the coverage report: %w", err)
+	}
+	var uncovered []Func
+	for file, info := range data.Files {
+		if len(info.MissingLines) == 0 {
+			continue
+		}
+		uncovered = append(uncovered, Func{File: file, Line: info.MissingLines[0], Percent: info.Summary.PercentCovered})
+	}
+	sortUncovered(uncovered)
+	return data.Totals.PercentCovered, uncovered, nil
+}
+
+func sortUncovered(funcs []Func) {
+	sort.SliceStable(funcs, func(i, j int) bool {
+		if funcs[i].Percent != funcs[j].Percent {
+			return funcs[i].Percent < funcs[j].Percent
+		}
+		if funcs[i].File != funcs[j].File {
+			return funcs[i].File < funcs[j].File
+		}
+		return funcs[i].Line < funcs[j].Line
+	})
+}
+
+// goModule returns the module path declared in the go.mod of dir
+func goModule(dir string) string {
+	content, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`)
+		}
+	}
+	return ""
+}
+
+func run(ctx context.Context, dir, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func tail(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > maxFailureOutput {
+		return "..." + s[len(s)-maxFailureOutput:]
+	}
+	return s
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
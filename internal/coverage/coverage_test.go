@@ -0,0 +1,40 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGoFuncs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\ngo 1.24\n"), 0o644))
+
+	total, uncovered := parseGoFuncs(`example.com/app/store/store.go:10:	New		100.0%
+example.com/app/store/store.go:15:	Get		50.0%
+example.com/app/store/store.go:30:	delete		0.0%
+total:						(statements)	62.5%
+`, dir)
+
+	assert.Equal(t, 62.5, total)
+	assert.Equal(t, []Func{
+		{File: "store/store.go", Line: 30, Name: "delete", Percent: 0},
+		{File: "store/store.go", Line: 15, Name: "Get", Percent: 50},
+	}, uncovered)
+}
+
+func TestParsePythonJSON(t *testing.T) {
+	total, uncovered, err := parsePythonJSON([]byte(`{
+  "files": {
+    "app/store.py": {"summary": {"percent_covered": 75.0}, "missing_lines": [12, 13]},
+    "app/util.py": {"summary": {"percent_covered": 100.0}, "missing_lines": []}
+  },
+  "totals": {"percent_covered": 80.5}
+}`))
+	require.NoError(t, err)
+	assert.Equal(t, 80.5, total)
+	assert.Equal(t, []Func{{File: "app/store.py", Line: 12, Percent: 75}}, uncovered)
+}
@@ -0,0 +1,257 @@
+// Package daemon keeps a warmed up app running in the background to serve
+// the prompts of non-interactive invocations over a unix socket, so they
+// don't pay for loading the config, the database and the LSP servers.
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/fileutil"
+	"github.com/kirmad/superopencode/internal/format"
+	"github.com/kirmad/superopencode/internal/logging"
+)
+
+// SocketName is the name of the socket in the data directory of a project
+const SocketName = "daemon.sock"
+
+// Commands of a request
+const (
+	CommandRun    = "run"
+	CommandStatus = "status"
+	CommandStop   = "stop"
+)
+
+// ErrNotRunning is returned by the client when no daemon listens on the socket
+var ErrNotRunning = errors.New("daemon not running")
+
+// SocketPath returns the socket of the daemon of the project with this data
+// directory
+func SocketPath(dataDir string) string {
+	return filepath.Join(dataDir, SocketName)
+}
+
+// Request is sent by the client as one JSON line
+type Request struct {
+	Command string `json:"command"`
+	Prompt  string `json:"prompt,omitempty"`
+}
+
+// Response is sent by the daemon as one JSON line
+type Response struct {
+	Output string         `json:"output,omitempty"`
+	Result *format.Result `json:"result,omitempty"` // Output with the usage and cost of the run
+	Error  string         `json:"error,omitempty"`
+	Status *Status        `json:"status,omitempty"`
+}
+
+// Status describes a running daemon
+type Status struct {
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	Runs      int64     `json:"runs"`
+	Running   int64     `json:"running"`
+}
+
+// Runner runs a prompt in a new session and returns the answer
+type Runner interface {
+	RunPrompt(ctx context.Context, prompt string) (format.Result, error)
+}
+
+// Server serves the requests of the clients
+type Server struct {
+	runner    Runner
+	listener  net.Listener
+	path      string
+	startedAt time.Time
+	runs      atomic.Int64
+	running   atomic.Int64
+	stop      chan struct{}
+	stopOnce  sync.Once
+	wg        sync.WaitGroup
+}
+
+// Listen creates the socket of the daemon. A socket left behind by a daemon
+// that exited is replaced, a running daemon is an error.
+func Listen(path string, runner Runner) (*Server, error) {
+	if _, err := Send(path, Request{Command: CommandStatus}); err == nil {
+		return nil, fmt.Errorf("a daemon is already running on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove the stale socket: %w", err)
+	}
+	// Prompts run with every permission granted, only the user may connect
+	listener, err := fileutil.ListenUnix(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		runner:    runner,
+		listener:  listener,
+		path:      path,
+		startedAt: time.Now(),
+		stop:      make(chan struct{}),
+	}, nil
+}
+
+// Serve handles connections until ctx is done or a client asks the daemon to
+// stop. Running prompts are canceled and the socket is removed.
+func (s *Server) Serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.stop:
+		}
+		s.listener.Close()
+	}()
+
+	var err error
+	for {
+		conn, acceptErr := s.listener.Accept()
+		if acceptErr != nil {
+			if ctx.Err() == nil && !s.stopped() {
+				err = fmt.Errorf("failed to accept a connection: %w", acceptErr)
+			}
+			break
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer logging.RecoverPanic("daemon-connection", nil)
+			s.handle(ctx, conn)
+		}()
+	}
+
+	cancel()
+	s.wg.Wait()
+	os.Remove(s.path)
+	return err
+}
+
+func (s *Server) stopped() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Server) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	var request Request
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(line, &request); err != nil {
+		writeResponse(conn, Response{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+
+	switch request.Command {
+	case CommandStatus:
+		writeResponse(conn, Response{Status: &Status{
+			PID:       os.Getpid(),
+			StartedAt: s.startedAt,
+			Runs:      s.runs.Load(),
+			Running:   s.running.Load(),
+		}})
+	case CommandStop:
+		writeResponse(conn, Response{})
+		s.stopOnce.Do(func() { close(s.stop) })
+	case CommandRun:
+		writeResponse(conn, s.run(ctx, reader, request.Prompt))
+	default:
+		writeResponse(conn, Response{Error: fmt.Sprintf("unknown command %q", request.Command)})
+	}
+}
+
+// run runs a prompt, canceled when the client hangs up
+func (s *Server) run(ctx context.Context, reader *bufio.Reader, prompt string) Response {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// The client sends nothing more, reading ends when it hangs up
+		_, _ = reader.ReadByte()
+		cancel()
+	}()
+
+	s.runs.Add(1)
+	s.running.Add(1)
+	defer s.running.Add(-1)
+	result, err := s.runner.RunPrompt(ctx, prompt)
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+	return Response{Output: result.Response, Result: &result}
+}
+
+func writeResponse(conn net.Conn, response Response) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		logging.Error("Failed to encode daemon response", "error", err)
+		return
+	}
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		logging.Debug("Failed to write daemon response", "error", err)
+	}
+}
+
+// Send sends a request to the daemon listening on path and waits for its
+// response, ErrNotRunning when there is none
+func Send(path string, request Request) (Response, error) {
+	return SendContext(context.Background(), path, request)
+}
+
+// SendContext is Send with a context, the daemon cancels the request when
+// ctx is done
+func SendContext(ctx context.Context, path string, request Request) (Response, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return Response{}, ErrNotRunning
+	}
+	defer conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return Response{}, err
+	}
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return Response{}, fmt.Errorf("failed to send the request: %w", err)
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		if ctx.Err() != nil {
+			return Response{}, ctx.Err()
+		}
+		return Response{}, fmt.Errorf("failed to read the response: %w", err)
+	}
+	var response Response
+	if err := json.Unmarshal(line, &response); err != nil {
+		return Response{}, fmt.Errorf("invalid response: %w", err)
+	}
+	return response, nil
+}
@@ -0,0 +1,87 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRunner struct {
+	started chan struct{}
+}
+
+func (r *fakeRunner) RunPrompt(ctx context.Context, prompt string) (format.Result, error) {
+	if prompt == "wait" {
+		close(r.started)
+		<-ctx.Done()
+		return format.Result{}, ctx.Err()
+	}
+	if prompt == "fail" {
+		return format.Result{}, errors.New("no provider")
+	}
+	return format.Result{Response: "answer to " + prompt, Cost: 0.01}, nil
+}
+
+func TestServer(t *testing.T) {
+	path := SocketPath(t.TempDir())
+	_, err := Send(path, Request{Command: CommandStatus})
+	assert.ErrorIs(t, err, ErrNotRunning)
+
+	runner := &fakeRunner{started: make(chan struct{})}
+	server, err := Listen(path, runner)
+	require.NoError(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "only the user may connect")
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the private directory of the socket is removed")
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(context.Background()) }()
+
+	_, err = Listen(path, runner)
+	assert.Error(t, err, "a second daemon should not replace the running one")
+
+	response, err := Send(path, Request{Command: CommandRun, Prompt: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "answer to hello", response.Output)
+	require.NotNil(t, response.Result)
+	assert.Equal(t, 0.01, response.Result.Cost)
+
+	response, err = Send(path, Request{Command: CommandRun, Prompt: "fail"})
+	require.NoError(t, err)
+	assert.Equal(t, "no provider", response.Error)
+
+	// Hanging up cancels the prompt
+	ctx, cancel := context.WithCancel(context.Background())
+	sent := make(chan error, 1)
+	go func() {
+		_, err := SendContext(ctx, path, Request{Command: CommandRun, Prompt: "wait"})
+		sent <- err
+	}()
+	<-runner.started
+	cancel()
+	assert.ErrorIs(t, <-sent, context.Canceled)
+
+	response, err = Send(path, Request{Command: CommandStatus})
+	require.NoError(t, err)
+	require.NotNil(t, response.Status)
+	assert.Equal(t, int64(3), response.Status.Runs)
+
+	_, err = Send(path, Request{Command: CommandStop})
+	require.NoError(t, err)
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the daemon did not stop")
+	}
+	assert.NoFileExists(t, path)
+}
@@ -8,6 +8,7 @@ import (
 
 	_ "github.com/ncruces/go-sqlite3/driver"
 	_ "github.com/ncruces/go-sqlite3/embed"
+	_ "github.com/ncruces/go-sqlite3/vfs/memdb"
 
 	"github.com/kirmad/superopencode/internal/config"
 	"github.com/kirmad/superopencode/internal/logging"
@@ -15,6 +16,14 @@ import (
 	"github.com/pressly/goose/v3"
 )
 
+// ephemeralName is the name of the in-memory database used when the database
+// of the data directory can't be opened. The memdb VFS shares it between the
+// connections of the pool.
+const (
+	ephemeralName = "/opencode-ephemeral.db"
+	ephemeralDSN  = "file:" + ephemeralName + "?vfs=memdb"
+)
+
 func Connect() (*sql.DB, error) {
 	dataDir := config.Get().Data.Directory
 	if dataDir == "" {
@@ -24,8 +33,39 @@ func Connect() (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to create data directory: %w", err)
 	}
 	dbPath := filepath.Join(dataDir, "opencode.db")
+	return open(dbPath, []string{
+		"PRAGMA foreign_keys = ON;",
+		"PRAGMA journal_mode = WAL;",
+		"PRAGMA page_size = 4096;",
+		"PRAGMA cache_size = -8000;",
+		"PRAGMA synchronous = NORMAL;",
+	})
+}
+
+// ConnectEphemeral opens an empty in-memory database, for when the database
+// of the data directory can't be used. Everything stored in it is lost on
+// exit.
+func ConnectEphemeral() (*sql.DB, error) {
+	return open(ephemeralDSN, []string{
+		"PRAGMA foreign_keys = ON;",
+	})
+}
+
+// InMemory reports whether conn is the in-memory database of
+// ConnectEphemeral
+func InMemory(conn *sql.DB) bool {
+	var seq int
+	var name, file string
+	if err := conn.QueryRow("PRAGMA database_list").Scan(&seq, &name, &file); err != nil {
+		return false
+	}
+	return file == ephemeralName
+}
+
+// open opens a database, sets its pragmas and applies the migrations
+func open(dsn string, pragmas []string) (*sql.DB, error) {
 	// Open the SQLite database
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -36,15 +76,6 @@ func Connect() (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Set pragmas for better performance
-	pragmas := []string{
-		"PRAGMA foreign_keys = ON;",
-		"PRAGMA journal_mode = WAL;",
-		"PRAGMA page_size = 4096;",
-		"PRAGMA cache_size = -8000;",
-		"PRAGMA synchronous = NORMAL;",
-	}
-
 	for _, pragma := range pragmas {
 		if _, err = db.Exec(pragma); err != nil {
 			logging.Error("Failed to set pragma", pragma, err)
@@ -57,11 +88,13 @@ func Connect() (*sql.DB, error) {
 
 	if err := goose.SetDialect("sqlite3"); err != nil {
 		logging.Error("Failed to set dialect", "error", err)
+		db.Close()
 		return nil, fmt.Errorf("failed to set dialect: %w", err)
 	}
 
 	if err := goose.Up(db, "migrations"); err != nil {
 		logging.Error("Failed to apply migrations", "error", err)
+		db.Close()
 		return nil, fmt.Errorf("failed to apply migrations: %w", err)
 	}
 	return db, nil
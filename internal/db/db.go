@@ -24,21 +24,48 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.addProviderUsageStmt, err = db.PrepareContext(ctx, addProviderUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query AddProviderUsage: %w", err)
+	}
+	if q.createErrorOccurrenceStmt, err = db.PrepareContext(ctx, createErrorOccurrence); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateErrorOccurrence: %w", err)
+	}
 	if q.createFileStmt, err = db.PrepareContext(ctx, createFile); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateFile: %w", err)
 	}
 	if q.createMessageStmt, err = db.PrepareContext(ctx, createMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateMessage: %w", err)
 	}
+	if q.createParallelRunStmt, err = db.PrepareContext(ctx, createParallelRun); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateParallelRun: %w", err)
+	}
 	if q.createSessionStmt, err = db.PrepareContext(ctx, createSession); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateSession: %w", err)
 	}
+	if q.createTaskMetricStmt, err = db.PrepareContext(ctx, createTaskMetric); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateTaskMetric: %w", err)
+	}
+	if q.createTurnLatencyStmt, err = db.PrepareContext(ctx, createTurnLatency); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateTurnLatency: %w", err)
+	}
+	if q.createUsageEventStmt, err = db.PrepareContext(ctx, createUsageEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateUsageEvent: %w", err)
+	}
 	if q.deleteFileStmt, err = db.PrepareContext(ctx, deleteFile); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteFile: %w", err)
 	}
 	if q.deleteMessageStmt, err = db.PrepareContext(ctx, deleteMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteMessage: %w", err)
 	}
+	if q.deleteOrphanFilesStmt, err = db.PrepareContext(ctx, deleteOrphanFiles); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOrphanFiles: %w", err)
+	}
+	if q.deleteOrphanMessagesStmt, err = db.PrepareContext(ctx, deleteOrphanMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOrphanMessages: %w", err)
+	}
+	if q.deleteOrphanSessionsStmt, err = db.PrepareContext(ctx, deleteOrphanSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOrphanSessions: %w", err)
+	}
 	if q.deleteSessionStmt, err = db.PrepareContext(ctx, deleteSession); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteSession: %w", err)
 	}
@@ -48,24 +75,48 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.deleteSessionMessagesStmt, err = db.PrepareContext(ctx, deleteSessionMessages); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteSessionMessages: %w", err)
 	}
+	if q.getErrorPatternStmt, err = db.PrepareContext(ctx, getErrorPattern); err != nil {
+		return nil, fmt.Errorf("error preparing query GetErrorPattern: %w", err)
+	}
 	if q.getFileStmt, err = db.PrepareContext(ctx, getFile); err != nil {
 		return nil, fmt.Errorf("error preparing query GetFile: %w", err)
 	}
 	if q.getFileByPathAndSessionStmt, err = db.PrepareContext(ctx, getFileByPathAndSession); err != nil {
 		return nil, fmt.Errorf("error preparing query GetFileByPathAndSession: %w", err)
 	}
+	if q.getLastErrorResolutionStmt, err = db.PrepareContext(ctx, getLastErrorResolution); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLastErrorResolution: %w", err)
+	}
 	if q.getMessageStmt, err = db.PrepareContext(ctx, getMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query GetMessage: %w", err)
 	}
+	if q.getProviderUsageStmt, err = db.PrepareContext(ctx, getProviderUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query GetProviderUsage: %w", err)
+	}
 	if q.getSessionByIDStmt, err = db.PrepareContext(ctx, getSessionByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetSessionByID: %w", err)
 	}
+	if q.importMessageStmt, err = db.PrepareContext(ctx, importMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query ImportMessage: %w", err)
+	}
+	if q.listAllSessionsStmt, err = db.PrepareContext(ctx, listAllSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAllSessions: %w", err)
+	}
+	if q.listErrorPatternsStmt, err = db.PrepareContext(ctx, listErrorPatterns); err != nil {
+		return nil, fmt.Errorf("error preparing query ListErrorPatterns: %w", err)
+	}
+	if q.listExpiredEphemeralSessionsStmt, err = db.PrepareContext(ctx, listExpiredEphemeralSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListExpiredEphemeralSessions: %w", err)
+	}
 	if q.listFilesByPathStmt, err = db.PrepareContext(ctx, listFilesByPath); err != nil {
 		return nil, fmt.Errorf("error preparing query ListFilesByPath: %w", err)
 	}
 	if q.listFilesBySessionStmt, err = db.PrepareContext(ctx, listFilesBySession); err != nil {
 		return nil, fmt.Errorf("error preparing query ListFilesBySession: %w", err)
 	}
+	if q.listLastSessionMessagesStmt, err = db.PrepareContext(ctx, listLastSessionMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query ListLastSessionMessages: %w", err)
+	}
 	if q.listLatestSessionFilesStmt, err = db.PrepareContext(ctx, listLatestSessionFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListLatestSessionFiles: %w", err)
 	}
@@ -75,9 +126,36 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.listNewFilesStmt, err = db.PrepareContext(ctx, listNewFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListNewFiles: %w", err)
 	}
+	if q.listParallelRunsSinceStmt, err = db.PrepareContext(ctx, listParallelRunsSince); err != nil {
+		return nil, fmt.Errorf("error preparing query ListParallelRunsSince: %w", err)
+	}
+	if q.listProviderUsageByMonthStmt, err = db.PrepareContext(ctx, listProviderUsageByMonth); err != nil {
+		return nil, fmt.Errorf("error preparing query ListProviderUsageByMonth: %w", err)
+	}
 	if q.listSessionsStmt, err = db.PrepareContext(ctx, listSessions); err != nil {
 		return nil, fmt.Errorf("error preparing query ListSessions: %w", err)
 	}
+	if q.listTaskMetricsSinceStmt, err = db.PrepareContext(ctx, listTaskMetricsSince); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTaskMetricsSince: %w", err)
+	}
+	if q.listTurnLatenciesSinceStmt, err = db.PrepareContext(ctx, listTurnLatenciesSince); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTurnLatenciesSince: %w", err)
+	}
+	if q.listUnfinishedMessagesStmt, err = db.PrepareContext(ctx, listUnfinishedMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUnfinishedMessages: %w", err)
+	}
+	if q.listUsageEventsSinceStmt, err = db.PrepareContext(ctx, listUsageEventsSince); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUsageEventsSince: %w", err)
+	}
+	if q.repairSessionMessageCountsStmt, err = db.PrepareContext(ctx, repairSessionMessageCounts); err != nil {
+		return nil, fmt.Errorf("error preparing query RepairSessionMessageCounts: %w", err)
+	}
+	if q.resolveErrorOccurrenceStmt, err = db.PrepareContext(ctx, resolveErrorOccurrence); err != nil {
+		return nil, fmt.Errorf("error preparing query ResolveErrorOccurrence: %w", err)
+	}
+	if q.searchMessagesStmt, err = db.PrepareContext(ctx, searchMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchMessages: %w", err)
+	}
 	if q.updateFileStmt, err = db.PrepareContext(ctx, updateFile); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateFile: %w", err)
 	}
@@ -92,6 +170,16 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 
 func (q *Queries) Close() error {
 	var err error
+	if q.addProviderUsageStmt != nil {
+		if cerr := q.addProviderUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addProviderUsageStmt: %w", cerr)
+		}
+	}
+	if q.createErrorOccurrenceStmt != nil {
+		if cerr := q.createErrorOccurrenceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createErrorOccurrenceStmt: %w", cerr)
+		}
+	}
 	if q.createFileStmt != nil {
 		if cerr := q.createFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createFileStmt: %w", cerr)
@@ -102,11 +190,31 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing createMessageStmt: %w", cerr)
 		}
 	}
+	if q.createParallelRunStmt != nil {
+		if cerr := q.createParallelRunStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createParallelRunStmt: %w", cerr)
+		}
+	}
 	if q.createSessionStmt != nil {
 		if cerr := q.createSessionStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createSessionStmt: %w", cerr)
 		}
 	}
+	if q.createTaskMetricStmt != nil {
+		if cerr := q.createTaskMetricStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createTaskMetricStmt: %w", cerr)
+		}
+	}
+	if q.createTurnLatencyStmt != nil {
+		if cerr := q.createTurnLatencyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createTurnLatencyStmt: %w", cerr)
+		}
+	}
+	if q.createUsageEventStmt != nil {
+		if cerr := q.createUsageEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createUsageEventStmt: %w", cerr)
+		}
+	}
 	if q.deleteFileStmt != nil {
 		if cerr := q.deleteFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteFileStmt: %w", cerr)
@@ -117,6 +225,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteMessageStmt: %w", cerr)
 		}
 	}
+	if q.deleteOrphanFilesStmt != nil {
+		if cerr := q.deleteOrphanFilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOrphanFilesStmt: %w", cerr)
+		}
+	}
+	if q.deleteOrphanMessagesStmt != nil {
+		if cerr := q.deleteOrphanMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOrphanMessagesStmt: %w", cerr)
+		}
+	}
+	if q.deleteOrphanSessionsStmt != nil {
+		if cerr := q.deleteOrphanSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOrphanSessionsStmt: %w", cerr)
+		}
+	}
 	if q.deleteSessionStmt != nil {
 		if cerr := q.deleteSessionStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteSessionStmt: %w", cerr)
@@ -132,6 +255,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteSessionMessagesStmt: %w", cerr)
 		}
 	}
+	if q.getErrorPatternStmt != nil {
+		if cerr := q.getErrorPatternStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getErrorPatternStmt: %w", cerr)
+		}
+	}
 	if q.getFileStmt != nil {
 		if cerr := q.getFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getFileStmt: %w", cerr)
@@ -142,16 +270,46 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getFileByPathAndSessionStmt: %w", cerr)
 		}
 	}
+	if q.getLastErrorResolutionStmt != nil {
+		if cerr := q.getLastErrorResolutionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLastErrorResolutionStmt: %w", cerr)
+		}
+	}
 	if q.getMessageStmt != nil {
 		if cerr := q.getMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getMessageStmt: %w", cerr)
 		}
 	}
+	if q.getProviderUsageStmt != nil {
+		if cerr := q.getProviderUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getProviderUsageStmt: %w", cerr)
+		}
+	}
 	if q.getSessionByIDStmt != nil {
 		if cerr := q.getSessionByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getSessionByIDStmt: %w", cerr)
 		}
 	}
+	if q.importMessageStmt != nil {
+		if cerr := q.importMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing importMessageStmt: %w", cerr)
+		}
+	}
+	if q.listAllSessionsStmt != nil {
+		if cerr := q.listAllSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAllSessionsStmt: %w", cerr)
+		}
+	}
+	if q.listErrorPatternsStmt != nil {
+		if cerr := q.listErrorPatternsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listErrorPatternsStmt: %w", cerr)
+		}
+	}
+	if q.listExpiredEphemeralSessionsStmt != nil {
+		if cerr := q.listExpiredEphemeralSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listExpiredEphemeralSessionsStmt: %w", cerr)
+		}
+	}
 	if q.listFilesByPathStmt != nil {
 		if cerr := q.listFilesByPathStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listFilesByPathStmt: %w", cerr)
@@ -162,6 +320,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing listFilesBySessionStmt: %w", cerr)
 		}
 	}
+	if q.listLastSessionMessagesStmt != nil {
+		if cerr := q.listLastSessionMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listLastSessionMessagesStmt: %w", cerr)
+		}
+	}
 	if q.listLatestSessionFilesStmt != nil {
 		if cerr := q.listLatestSessionFilesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listLatestSessionFilesStmt: %w", cerr)
@@ -177,11 +340,56 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing listNewFilesStmt: %w", cerr)
 		}
 	}
+	if q.listParallelRunsSinceStmt != nil {
+		if cerr := q.listParallelRunsSinceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listParallelRunsSinceStmt: %w", cerr)
+		}
+	}
+	if q.listProviderUsageByMonthStmt != nil {
+		if cerr := q.listProviderUsageByMonthStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listProviderUsageByMonthStmt: %w", cerr)
+		}
+	}
 	if q.listSessionsStmt != nil {
 		if cerr := q.listSessionsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listSessionsStmt: %w", cerr)
 		}
 	}
+	if q.listTaskMetricsSinceStmt != nil {
+		if cerr := q.listTaskMetricsSinceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTaskMetricsSinceStmt: %w", cerr)
+		}
+	}
+	if q.listTurnLatenciesSinceStmt != nil {
+		if cerr := q.listTurnLatenciesSinceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTurnLatenciesSinceStmt: %w", cerr)
+		}
+	}
+	if q.listUnfinishedMessagesStmt != nil {
+		if cerr := q.listUnfinishedMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUnfinishedMessagesStmt: %w", cerr)
+		}
+	}
+	if q.listUsageEventsSinceStmt != nil {
+		if cerr := q.listUsageEventsSinceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUsageEventsSinceStmt: %w", cerr)
+		}
+	}
+	if q.repairSessionMessageCountsStmt != nil {
+		if cerr := q.repairSessionMessageCountsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing repairSessionMessageCountsStmt: %w", cerr)
+		}
+	}
+	if q.resolveErrorOccurrenceStmt != nil {
+		if cerr := q.resolveErrorOccurrenceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing resolveErrorOccurrenceStmt: %w", cerr)
+		}
+	}
+	if q.searchMessagesStmt != nil {
+		if cerr := q.searchMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchMessagesStmt: %w", cerr)
+		}
+	}
 	if q.updateFileStmt != nil {
 		if cerr := q.updateFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateFileStmt: %w", cerr)
@@ -234,55 +442,107 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                          DBTX
-	tx                          *sql.Tx
-	createFileStmt              *sql.Stmt
-	createMessageStmt           *sql.Stmt
-	createSessionStmt           *sql.Stmt
-	deleteFileStmt              *sql.Stmt
-	deleteMessageStmt           *sql.Stmt
-	deleteSessionStmt           *sql.Stmt
-	deleteSessionFilesStmt      *sql.Stmt
-	deleteSessionMessagesStmt   *sql.Stmt
-	getFileStmt                 *sql.Stmt
-	getFileByPathAndSessionStmt *sql.Stmt
-	getMessageStmt              *sql.Stmt
-	getSessionByIDStmt          *sql.Stmt
-	listFilesByPathStmt         *sql.Stmt
-	listFilesBySessionStmt      *sql.Stmt
-	listLatestSessionFilesStmt  *sql.Stmt
-	listMessagesBySessionStmt   *sql.Stmt
-	listNewFilesStmt            *sql.Stmt
-	listSessionsStmt            *sql.Stmt
-	updateFileStmt              *sql.Stmt
-	updateMessageStmt           *sql.Stmt
-	updateSessionStmt           *sql.Stmt
+	db                               DBTX
+	tx                               *sql.Tx
+	addProviderUsageStmt             *sql.Stmt
+	createErrorOccurrenceStmt        *sql.Stmt
+	createFileStmt                   *sql.Stmt
+	createMessageStmt                *sql.Stmt
+	createParallelRunStmt            *sql.Stmt
+	createSessionStmt                *sql.Stmt
+	createTaskMetricStmt             *sql.Stmt
+	createTurnLatencyStmt            *sql.Stmt
+	createUsageEventStmt             *sql.Stmt
+	deleteFileStmt                   *sql.Stmt
+	deleteMessageStmt                *sql.Stmt
+	deleteOrphanFilesStmt            *sql.Stmt
+	deleteOrphanMessagesStmt         *sql.Stmt
+	deleteOrphanSessionsStmt         *sql.Stmt
+	deleteSessionStmt                *sql.Stmt
+	deleteSessionFilesStmt           *sql.Stmt
+	deleteSessionMessagesStmt        *sql.Stmt
+	getErrorPatternStmt              *sql.Stmt
+	getFileStmt                      *sql.Stmt
+	getFileByPathAndSessionStmt      *sql.Stmt
+	getLastErrorResolutionStmt       *sql.Stmt
+	getMessageStmt                   *sql.Stmt
+	getProviderUsageStmt             *sql.Stmt
+	getSessionByIDStmt               *sql.Stmt
+	importMessageStmt                *sql.Stmt
+	listAllSessionsStmt              *sql.Stmt
+	listErrorPatternsStmt            *sql.Stmt
+	listExpiredEphemeralSessionsStmt *sql.Stmt
+	listFilesByPathStmt              *sql.Stmt
+	listFilesBySessionStmt           *sql.Stmt
+	listLastSessionMessagesStmt      *sql.Stmt
+	listLatestSessionFilesStmt       *sql.Stmt
+	listMessagesBySessionStmt        *sql.Stmt
+	listNewFilesStmt                 *sql.Stmt
+	listParallelRunsSinceStmt        *sql.Stmt
+	listProviderUsageByMonthStmt     *sql.Stmt
+	listSessionsStmt                 *sql.Stmt
+	listTaskMetricsSinceStmt         *sql.Stmt
+	listTurnLatenciesSinceStmt       *sql.Stmt
+	listUnfinishedMessagesStmt       *sql.Stmt
+	listUsageEventsSinceStmt         *sql.Stmt
+	repairSessionMessageCountsStmt   *sql.Stmt
+	resolveErrorOccurrenceStmt       *sql.Stmt
+	searchMessagesStmt               *sql.Stmt
+	updateFileStmt                   *sql.Stmt
+	updateMessageStmt                *sql.Stmt
+	updateSessionStmt                *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                          tx,
-		tx:                          tx,
-		createFileStmt:              q.createFileStmt,
-		createMessageStmt:           q.createMessageStmt,
-		createSessionStmt:           q.createSessionStmt,
-		deleteFileStmt:              q.deleteFileStmt,
-		deleteMessageStmt:           q.deleteMessageStmt,
-		deleteSessionStmt:           q.deleteSessionStmt,
-		deleteSessionFilesStmt:      q.deleteSessionFilesStmt,
-		deleteSessionMessagesStmt:   q.deleteSessionMessagesStmt,
-		getFileStmt:                 q.getFileStmt,
-		getFileByPathAndSessionStmt: q.getFileByPathAndSessionStmt,
-		getMessageStmt:              q.getMessageStmt,
-		getSessionByIDStmt:          q.getSessionByIDStmt,
-		listFilesByPathStmt:         q.listFilesByPathStmt,
-		listFilesBySessionStmt:      q.listFilesBySessionStmt,
-		listLatestSessionFilesStmt:  q.listLatestSessionFilesStmt,
-		listMessagesBySessionStmt:   q.listMessagesBySessionStmt,
-		listNewFilesStmt:            q.listNewFilesStmt,
-		listSessionsStmt:            q.listSessionsStmt,
-		updateFileStmt:              q.updateFileStmt,
-		updateMessageStmt:           q.updateMessageStmt,
-		updateSessionStmt:           q.updateSessionStmt,
+		db:                               tx,
+		tx:                               tx,
+		addProviderUsageStmt:             q.addProviderUsageStmt,
+		createErrorOccurrenceStmt:        q.createErrorOccurrenceStmt,
+		createFileStmt:                   q.createFileStmt,
+		createMessageStmt:                q.createMessageStmt,
+		createParallelRunStmt:            q.createParallelRunStmt,
+		createSessionStmt:                q.createSessionStmt,
+		createTaskMetricStmt:             q.createTaskMetricStmt,
+		createTurnLatencyStmt:            q.createTurnLatencyStmt,
+		createUsageEventStmt:             q.createUsageEventStmt,
+		deleteFileStmt:                   q.deleteFileStmt,
+		deleteMessageStmt:                q.deleteMessageStmt,
+		deleteOrphanFilesStmt:            q.deleteOrphanFilesStmt,
+		deleteOrphanMessagesStmt:         q.deleteOrphanMessagesStmt,
+		deleteOrphanSessionsStmt:         q.deleteOrphanSessionsStmt,
+		deleteSessionStmt:                q.deleteSessionStmt,
+		deleteSessionFilesStmt:           q.deleteSessionFilesStmt,
+		deleteSessionMessagesStmt:        q.deleteSessionMessagesStmt,
+		getErrorPatternStmt:              q.getErrorPatternStmt,
+		getFileStmt:                      q.getFileStmt,
+		getFileByPathAndSessionStmt:      q.getFileByPathAndSessionStmt,
+		getLastErrorResolutionStmt:       q.getLastErrorResolutionStmt,
+		getMessageStmt:                   q.getMessageStmt,
+		getProviderUsageStmt:             q.getProviderUsageStmt,
+		getSessionByIDStmt:               q.getSessionByIDStmt,
+		importMessageStmt:                q.importMessageStmt,
+		listAllSessionsStmt:              q.listAllSessionsStmt,
+		listErrorPatternsStmt:            q.listErrorPatternsStmt,
+		listExpiredEphemeralSessionsStmt: q.listExpiredEphemeralSessionsStmt,
+		listFilesByPathStmt:              q.listFilesByPathStmt,
+		listFilesBySessionStmt:           q.listFilesBySessionStmt,
+		listLastSessionMessagesStmt:      q.listLastSessionMessagesStmt,
+		listLatestSessionFilesStmt:       q.listLatestSessionFilesStmt,
+		listMessagesBySessionStmt:        q.listMessagesBySessionStmt,
+		listNewFilesStmt:                 q.listNewFilesStmt,
+		listParallelRunsSinceStmt:        q.listParallelRunsSinceStmt,
+		listProviderUsageByMonthStmt:     q.listProviderUsageByMonthStmt,
+		listSessionsStmt:                 q.listSessionsStmt,
+		listTaskMetricsSinceStmt:         q.listTaskMetricsSinceStmt,
+		listTurnLatenciesSinceStmt:       q.listTurnLatenciesSinceStmt,
+		listUnfinishedMessagesStmt:       q.listUnfinishedMessagesStmt,
+		listUsageEventsSinceStmt:         q.listUsageEventsSinceStmt,
+		repairSessionMessageCountsStmt:   q.repairSessionMessageCountsStmt,
+		resolveErrorOccurrenceStmt:       q.resolveErrorOccurrenceStmt,
+		searchMessagesStmt:               q.searchMessagesStmt,
+		updateFileStmt:                   q.updateFileStmt,
+		updateMessageStmt:                q.updateMessageStmt,
+		updateSessionStmt:                q.updateSessionStmt,
 	}
 }
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS task_metrics (
+    id TEXT PRIMARY KEY, -- Task session ID
+    session_id TEXT NOT NULL, -- Session that launched the task
+    subagent TEXT NOT NULL,
+    status TEXT NOT NULL, -- done, failed or canceled
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    cost REAL NOT NULL DEFAULT 0.0,
+    created_at INTEGER NOT NULL -- Unix timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_metrics_created_at ON task_metrics (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_task_metrics_created_at;
+DROP TABLE IF EXISTS task_metrics;
+-- +goose StatementEnd
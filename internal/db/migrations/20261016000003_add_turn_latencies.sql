@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS turn_latencies (
+    id TEXT PRIMARY KEY, -- First assistant message of the turn
+    session_id TEXT NOT NULL,
+    model TEXT NOT NULL,
+    first_token_ms INTEGER NOT NULL DEFAULT 0, -- From the user prompt to the first streamed token
+    created_at INTEGER NOT NULL -- Unix timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_turn_latencies_created_at ON turn_latencies (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_turn_latencies_created_at;
+DROP TABLE IF EXISTS turn_latencies;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS usage_events (
+    id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL, -- session, turn or tool
+    name TEXT NOT NULL DEFAULT '', -- Model of a turn, name of a tool
+    success INTEGER NOT NULL DEFAULT 1,
+    created_at INTEGER NOT NULL -- Unix timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_events_created_at ON usage_events (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_usage_events_created_at;
+DROP TABLE IF EXISTS usage_events;
+-- +goose StatementEnd
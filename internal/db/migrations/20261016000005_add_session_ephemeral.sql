@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN ephemeral INTEGER NOT NULL DEFAULT 0;
+-- Task and title sessions created before the column are ephemeral
+UPDATE sessions SET ephemeral = 1 WHERE parent_session_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_sessions_ephemeral_updated_at ON sessions (ephemeral, updated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_sessions_ephemeral_updated_at;
+ALTER TABLE sessions DROP COLUMN ephemeral;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS error_occurrences (
+    id TEXT PRIMARY KEY,
+    signature TEXT NOT NULL, -- Hash of the normalized error, the same for recurrences
+    kind TEXT NOT NULL, -- tool or llm
+    name TEXT NOT NULL, -- Tool or model
+    message TEXT NOT NULL, -- The line of the error that matters
+    session_id TEXT NOT NULL,
+    resolution TEXT NOT NULL DEFAULT '', -- What fixed it, empty until fixed
+    resolved_at INTEGER NOT NULL DEFAULT 0, -- Unix timestamp
+    created_at INTEGER NOT NULL -- Unix timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_error_occurrences_signature ON error_occurrences (signature, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_error_occurrences_signature;
+DROP TABLE IF EXISTS error_occurrences;
+-- +goose StatementEnd
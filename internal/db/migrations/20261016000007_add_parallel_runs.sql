@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS parallel_runs (
+    id TEXT PRIMARY KEY, -- Tool call ID, the tasks of the run have it as prefix
+    session_id TEXT NOT NULL, -- Session that launched the run
+    task_count INTEGER NOT NULL DEFAULT 0,
+    succeeded INTEGER NOT NULL DEFAULT 0,
+    level_count INTEGER NOT NULL DEFAULT 0,
+    max_parallel INTEGER NOT NULL DEFAULT 0,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    tokens INTEGER NOT NULL DEFAULT 0,
+    cost REAL NOT NULL DEFAULT 0.0,
+    budget_exceeded INTEGER NOT NULL DEFAULT 0, -- 1 when the run was stopped by its budget
+    dag TEXT NOT NULL DEFAULT '', -- JSON of the levels and the outcome of every task
+    created_at INTEGER NOT NULL -- Unix timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_parallel_runs_created_at ON parallel_runs (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_parallel_runs_created_at;
+DROP TABLE IF EXISTS parallel_runs;
+-- +goose StatementEnd
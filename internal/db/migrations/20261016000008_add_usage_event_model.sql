@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Model that made a tool call, empty for the other events
+ALTER TABLE usage_events ADD COLUMN model TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE usage_events DROP COLUMN model;
+-- +goose StatementEnd
@@ -0,0 +1,59 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Full-text index of the text of the messages and the input of their tool
+-- calls, tool results and reasoning are left out
+CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
+    content,
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS index_messages_on_insert
+AFTER INSERT ON messages
+BEGIN
+INSERT INTO messages_fts (rowid, content)
+SELECT new.rowid, coalesce(group_concat(
+    CASE json_extract(p.value, '$.type')
+        WHEN 'text' THEN json_extract(p.value, '$.data.text')
+        WHEN 'tool_call' THEN json_extract(p.value, '$.data.input')
+    END, ' '), '')
+FROM json_each(new.parts) AS p;
+END;
+
+CREATE TRIGGER IF NOT EXISTS index_messages_on_update
+AFTER UPDATE OF parts ON messages
+BEGIN
+DELETE FROM messages_fts WHERE rowid = old.rowid;
+INSERT INTO messages_fts (rowid, content)
+SELECT new.rowid, coalesce(group_concat(
+    CASE json_extract(p.value, '$.type')
+        WHEN 'text' THEN json_extract(p.value, '$.data.text')
+        WHEN 'tool_call' THEN json_extract(p.value, '$.data.input')
+    END, ' '), '')
+FROM json_each(new.parts) AS p;
+END;
+
+CREATE TRIGGER IF NOT EXISTS unindex_messages_on_delete
+AFTER DELETE ON messages
+BEGIN
+DELETE FROM messages_fts WHERE rowid = old.rowid;
+END;
+
+INSERT INTO messages_fts (rowid, content)
+SELECT m.rowid, coalesce((
+    SELECT group_concat(
+        CASE json_extract(p.value, '$.type')
+            WHEN 'text' THEN json_extract(p.value, '$.data.text')
+            WHEN 'tool_call' THEN json_extract(p.value, '$.data.input')
+        END, ' ')
+    FROM json_each(m.parts) AS p
+), '')
+FROM messages m;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS index_messages_on_insert;
+DROP TRIGGER IF EXISTS index_messages_on_update;
+DROP TRIGGER IF EXISTS unindex_messages_on_delete;
+DROP TABLE IF EXISTS messages_fts;
+-- +goose StatementEnd
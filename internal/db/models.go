@@ -8,6 +8,18 @@ import (
 	"database/sql"
 )
 
+type ErrorOccurrence struct {
+	ID         string `json:"id"`
+	Signature  string `json:"signature"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Message    string `json:"message"`
+	SessionID  string `json:"session_id"`
+	Resolution string `json:"resolution"`
+	ResolvedAt int64  `json:"resolved_at"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
 type File struct {
 	ID        string `json:"id"`
 	SessionID string `json:"session_id"`
@@ -29,6 +41,31 @@ type Message struct {
 	FinishedAt sql.NullInt64  `json:"finished_at"`
 }
 
+type ParallelRun struct {
+	ID             string  `json:"id"`
+	SessionID      string  `json:"session_id"`
+	TaskCount      int64   `json:"task_count"`
+	Succeeded      int64   `json:"succeeded"`
+	LevelCount     int64   `json:"level_count"`
+	MaxParallel    int64   `json:"max_parallel"`
+	DurationMs     int64   `json:"duration_ms"`
+	Tokens         int64   `json:"tokens"`
+	Cost           float64 `json:"cost"`
+	BudgetExceeded int64   `json:"budget_exceeded"`
+	Dag            string  `json:"dag"`
+	CreatedAt      int64   `json:"created_at"`
+}
+
+type ProviderUsage struct {
+	Provider         string  `json:"provider"`
+	KeyID            string  `json:"key_id"`
+	Month            string  `json:"month"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	UpdatedAt        int64   `json:"updated_at"`
+}
+
 type Session struct {
 	ID               string         `json:"id"`
 	ParentSessionID  sql.NullString `json:"parent_session_id"`
@@ -40,4 +77,37 @@ type Session struct {
 	UpdatedAt        int64          `json:"updated_at"`
 	CreatedAt        int64          `json:"created_at"`
 	SummaryMessageID sql.NullString `json:"summary_message_id"`
+	SystemPrompt     sql.NullString `json:"system_prompt"`
+	Ephemeral        int64          `json:"ephemeral"`
+	Language         string         `json:"language"`
+}
+
+type TaskMetric struct {
+	ID               string  `json:"id"`
+	SessionID        string  `json:"session_id"`
+	Subagent         string  `json:"subagent"`
+	Status           string  `json:"status"`
+	DurationMs       int64   `json:"duration_ms"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	CreatedAt        int64   `json:"created_at"`
+	RetryAttempts    int64   `json:"retry_attempts"`
+}
+
+type TurnLatency struct {
+	ID           string `json:"id"`
+	SessionID    string `json:"session_id"`
+	Model        string `json:"model"`
+	FirstTokenMs int64  `json:"first_token_ms"`
+	CreatedAt    int64  `json:"created_at"`
+}
+
+type UsageEvent struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Success   int64  `json:"success"`
+	CreatedAt int64  `json:"created_at"`
+	Model     string `json:"model"`
 }
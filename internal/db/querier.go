@@ -9,24 +9,50 @@ import (
 )
 
 type Querier interface {
+	AddProviderUsage(ctx context.Context, arg AddProviderUsageParams) error
+	CreateErrorOccurrence(ctx context.Context, arg CreateErrorOccurrenceParams) error
 	CreateFile(ctx context.Context, arg CreateFileParams) (File, error)
 	CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error)
+	CreateParallelRun(ctx context.Context, arg CreateParallelRunParams) error
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateTaskMetric(ctx context.Context, arg CreateTaskMetricParams) error
+	CreateTurnLatency(ctx context.Context, arg CreateTurnLatencyParams) error
+	CreateUsageEvent(ctx context.Context, arg CreateUsageEventParams) error
 	DeleteFile(ctx context.Context, id string) error
 	DeleteMessage(ctx context.Context, id string) error
+	DeleteOrphanFiles(ctx context.Context) (int64, error)
+	DeleteOrphanMessages(ctx context.Context) (int64, error)
+	DeleteOrphanSessions(ctx context.Context) (int64, error)
 	DeleteSession(ctx context.Context, id string) error
 	DeleteSessionFiles(ctx context.Context, sessionID string) error
 	DeleteSessionMessages(ctx context.Context, sessionID string) error
+	GetErrorPattern(ctx context.Context, signature string) (GetErrorPatternRow, error)
 	GetFile(ctx context.Context, id string) (File, error)
 	GetFileByPathAndSession(ctx context.Context, arg GetFileByPathAndSessionParams) (File, error)
+	GetLastErrorResolution(ctx context.Context, signature string) (ErrorOccurrence, error)
 	GetMessage(ctx context.Context, id string) (Message, error)
+	GetProviderUsage(ctx context.Context, arg GetProviderUsageParams) (ProviderUsage, error)
 	GetSessionByID(ctx context.Context, id string) (Session, error)
+	ImportMessage(ctx context.Context, arg ImportMessageParams) error
+	ListAllSessions(ctx context.Context) ([]Session, error)
+	ListErrorPatterns(ctx context.Context, arg ListErrorPatternsParams) ([]ListErrorPatternsRow, error)
+	ListExpiredEphemeralSessions(ctx context.Context, updatedAt int64) ([]Session, error)
 	ListFilesByPath(ctx context.Context, path string) ([]File, error)
 	ListFilesBySession(ctx context.Context, sessionID string) ([]File, error)
+	ListLastSessionMessages(ctx context.Context) ([]Message, error)
 	ListLatestSessionFiles(ctx context.Context, sessionID string) ([]File, error)
 	ListMessagesBySession(ctx context.Context, sessionID string) ([]Message, error)
 	ListNewFiles(ctx context.Context) ([]File, error)
+	ListParallelRunsSince(ctx context.Context, createdAt int64) ([]ParallelRun, error)
+	ListProviderUsageByMonth(ctx context.Context, month string) ([]ProviderUsage, error)
 	ListSessions(ctx context.Context) ([]Session, error)
+	ListTaskMetricsSince(ctx context.Context, createdAt int64) ([]TaskMetric, error)
+	ListTurnLatenciesSince(ctx context.Context, createdAt int64) ([]TurnLatency, error)
+	ListUnfinishedMessages(ctx context.Context, updatedAt int64) ([]Message, error)
+	ListUsageEventsSince(ctx context.Context, createdAt int64) ([]UsageEvent, error)
+	RepairSessionMessageCounts(ctx context.Context) (int64, error)
+	ResolveErrorOccurrence(ctx context.Context, arg ResolveErrorOccurrenceParams) error
+	SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]SearchMessagesRow, error)
 	UpdateFile(ctx context.Context, arg UpdateFileParams) (File, error)
 	UpdateMessage(ctx context.Context, arg UpdateMessageParams) error
 	UpdateSession(ctx context.Context, arg UpdateSessionParams) (Session, error)
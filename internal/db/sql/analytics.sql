@@ -0,0 +1,17 @@
+-- name: CreateUsageEvent :exec
+INSERT INTO usage_events (
+    id,
+    kind,
+    name,
+    success,
+    model,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, ?, strftime('%s', 'now')
+);
+
+-- name: ListUsageEventsSince :many
+SELECT *
+FROM usage_events
+WHERE created_at >= ?
+ORDER BY created_at ASC;
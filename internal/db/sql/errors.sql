@@ -0,0 +1,48 @@
+-- name: CreateErrorOccurrence :exec
+INSERT INTO error_occurrences (
+    id,
+    signature,
+    kind,
+    name,
+    message,
+    session_id,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, strftime('%s', 'now')
+);
+
+-- name: ResolveErrorOccurrence :exec
+UPDATE error_occurrences
+SET
+    resolution = ?,
+    resolved_at = strftime('%s', 'now')
+WHERE id = ?;
+
+-- name: GetErrorPattern :one
+SELECT
+    COUNT(*) AS occurrences,
+    COUNT(DISTINCT session_id) AS sessions
+FROM error_occurrences
+WHERE signature = ?;
+
+-- name: GetLastErrorResolution :one
+SELECT *
+FROM error_occurrences
+WHERE signature = ? AND resolution != ''
+ORDER BY resolved_at DESC
+LIMIT 1;
+
+-- name: ListErrorPatterns :many
+SELECT
+    signature,
+    kind,
+    name,
+    message,
+    COUNT(*) AS occurrences,
+    COUNT(DISTINCT session_id) AS sessions,
+    CAST(MAX(created_at) AS INTEGER) AS last_seen
+FROM error_occurrences
+GROUP BY signature
+HAVING COUNT(*) >= ?
+ORDER BY occurrences DESC, last_seen DESC
+LIMIT ?;
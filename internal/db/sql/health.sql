@@ -0,0 +1,36 @@
+-- name: DeleteOrphanMessages :execrows
+DELETE FROM messages
+WHERE session_id NOT IN (SELECT id FROM sessions);
+
+-- name: DeleteOrphanFiles :execrows
+DELETE FROM files
+WHERE session_id NOT IN (SELECT id FROM sessions);
+
+-- name: DeleteOrphanSessions :execrows
+DELETE FROM sessions
+WHERE parent_session_id IS NOT NULL
+  AND parent_session_id NOT IN (SELECT id FROM sessions);
+
+-- name: RepairSessionMessageCounts :execrows
+UPDATE sessions
+SET message_count = (SELECT COUNT(*) FROM messages WHERE messages.session_id = sessions.id)
+WHERE message_count != (SELECT COUNT(*) FROM messages WHERE messages.session_id = sessions.id);
+
+-- name: ListUnfinishedMessages :many
+SELECT *
+FROM messages
+WHERE role = 'assistant'
+  AND finished_at IS NULL
+  AND updated_at < ?
+ORDER BY created_at ASC;
+
+-- name: ListLastSessionMessages :many
+SELECT *
+FROM messages AS m
+WHERE m.rowid = (
+    SELECT rowid
+    FROM messages
+    WHERE session_id = m.session_id
+    ORDER BY created_at DESC, rowid DESC
+    LIMIT 1
+);
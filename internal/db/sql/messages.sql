@@ -23,6 +23,20 @@ INSERT INTO messages (
 )
 RETURNING *;
 
+-- name: ImportMessage :exec
+INSERT INTO messages (
+    id,
+    session_id,
+    role,
+    parts,
+    model,
+    created_at,
+    updated_at,
+    finished_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?
+);
+
 -- name: UpdateMessage :exec
 UPDATE messages
 SET
@@ -0,0 +1,62 @@
+-- name: CreateTaskMetric :exec
+INSERT INTO task_metrics (
+    id,
+    session_id,
+    subagent,
+    status,
+    duration_ms,
+    prompt_tokens,
+    completion_tokens,
+    cost,
+    retry_attempts,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, strftime('%s', 'now')
+);
+
+-- name: ListTaskMetricsSince :many
+SELECT *
+FROM task_metrics
+WHERE created_at >= ?
+ORDER BY created_at ASC;
+
+-- name: CreateTurnLatency :exec
+INSERT INTO turn_latencies (
+    id,
+    session_id,
+    model,
+    first_token_ms,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, strftime('%s', 'now')
+);
+
+-- name: ListTurnLatenciesSince :many
+SELECT *
+FROM turn_latencies
+WHERE created_at >= ?
+ORDER BY created_at ASC;
+
+-- name: CreateParallelRun :exec
+INSERT INTO parallel_runs (
+    id,
+    session_id,
+    task_count,
+    succeeded,
+    level_count,
+    max_parallel,
+    duration_ms,
+    tokens,
+    cost,
+    budget_exceeded,
+    dag,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, strftime('%s', 'now')
+);
+
+-- name: ListParallelRunsSince :many
+SELECT *
+FROM parallel_runs
+WHERE created_at >= ?
+ORDER BY created_at ASC;
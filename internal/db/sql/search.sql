@@ -0,0 +1,14 @@
+-- name: SearchMessages :many
+SELECT
+    m.id AS message_id,
+    m.session_id,
+    CAST(snippet(messages_fts, 0, sqlc.arg(mark_start), sqlc.arg(mark_end), '…', 16) AS TEXT) AS snippet,
+    CAST(bm25(messages_fts) AS REAL) AS rank
+FROM messages_fts
+JOIN messages m ON m.rowid = messages_fts.rowid
+JOIN sessions s ON s.id = m.session_id
+WHERE messages_fts MATCH sqlc.arg(query)
+  AND s.id NOT LIKE 'title-%'
+  AND (s.ephemeral = 0 OR s.ephemeral = sqlc.arg(ephemeral))
+ORDER BY rank
+LIMIT sqlc.arg(max_results);
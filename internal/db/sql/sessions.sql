@@ -7,6 +7,7 @@ INSERT INTO sessions (
     prompt_tokens,
     completion_tokens,
     cost,
+    ephemeral,
     summary_message_id,
     updated_at,
     created_at
@@ -18,6 +19,7 @@ INSERT INTO sessions (
     ?,
     ?,
     ?,
+    ?,
     null,
     strftime('%s', 'now'),
     strftime('%s', 'now')
@@ -34,6 +36,18 @@ FROM sessions
 WHERE parent_session_id is NULL
 ORDER BY created_at DESC;
 
+-- name: ListAllSessions :many
+SELECT *
+FROM sessions
+ORDER BY created_at DESC;
+
+-- name: ListExpiredEphemeralSessions :many
+SELECT *
+FROM sessions
+WHERE ephemeral = 1
+  AND updated_at < ?
+ORDER BY updated_at ASC;
+
 -- name: UpdateSession :one
 UPDATE sessions
 SET
@@ -41,7 +55,9 @@ SET
     prompt_tokens = ?,
     completion_tokens = ?,
     summary_message_id = ?,
-    cost = ?
+    cost = ?,
+    system_prompt = ?,
+    language = ?
 WHERE id = ?
 RETURNING *;
 
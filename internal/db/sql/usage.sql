@@ -0,0 +1,29 @@
+-- name: AddProviderUsage :exec
+INSERT INTO provider_usage (
+    provider,
+    key_id,
+    month,
+    prompt_tokens,
+    completion_tokens,
+    cost,
+    updated_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, strftime('%s', 'now')
+)
+ON CONFLICT (provider, key_id, month) DO UPDATE SET
+    prompt_tokens = prompt_tokens + excluded.prompt_tokens,
+    completion_tokens = completion_tokens + excluded.completion_tokens,
+    cost = cost + excluded.cost,
+    updated_at = excluded.updated_at;
+
+-- name: GetProviderUsage :one
+SELECT *
+FROM provider_usage
+WHERE provider = ? AND key_id = ? AND month = ?
+LIMIT 1;
+
+-- name: ListProviderUsageByMonth :many
+SELECT *
+FROM provider_usage
+WHERE month = ?
+ORDER BY provider ASC, key_id ASC;
@@ -3,7 +3,7 @@ package detailed_logging
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 )
@@ -27,13 +27,11 @@ func NewDetailedLogger(enabled bool) (*DetailedLogger, error) {
 	}
 
 	// Create data directory
-	homeDir, err := os.UserHomeDir()
+	dataDir, err := DefaultDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
 	}
 
-	dataDir := filepath.Join(homeDir, ".opencode", "detailed_logs")
-	
 	// Initialize storage
 	storage, err := NewStorage(dataDir)
 	if err != nil {
@@ -144,6 +142,52 @@ func (dl *DetailedLogger) LogHTTP(call *HTTPLog) {
 	go dl.saveSession()
 }
 
+// LogUserTurn logs a message the user sent in a chat session
+func (dl *DetailedLogger) LogUserTurn(turn *UserTurnLog) {
+	if !dl.IsEnabled() {
+		return
+	}
+
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+
+	dl.session.UserTurns = append(dl.session.UserTurns, *turn)
+
+	// Save session asynchronously
+	go dl.saveSession()
+}
+
+// LogPermissionWait logs the wait for the answer to a permission request
+func (dl *DetailedLogger) LogPermissionWait(wait *PermissionWaitLog) {
+	if !dl.IsEnabled() {
+		return
+	}
+
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+
+	dl.session.PermissionWaits = append(dl.session.PermissionWaits, *wait)
+
+	// Save session asynchronously
+	go dl.saveSession()
+}
+
+// Snapshot returns a copy of the session logged so far
+func (dl *DetailedLogger) Snapshot() *SessionLog {
+	if !dl.IsEnabled() {
+		return nil
+	}
+
+	dl.mu.RLock()
+	defer dl.mu.RUnlock()
+	session := *dl.session
+	session.LLMCalls = slices.Clone(session.LLMCalls)
+	session.ToolCalls = slices.Clone(session.ToolCalls)
+	session.UserTurns = slices.Clone(session.UserTurns)
+	session.PermissionWaits = slices.Clone(session.PermissionWaits)
+	return &session
+}
+
 // StartToolCall begins tracking a tool call
 func (dl *DetailedLogger) StartToolCall(name string, input map[string]interface{}) string {
 	if !dl.IsEnabled() {
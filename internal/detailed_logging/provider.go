@@ -53,6 +53,11 @@ func (lp *LoggingProvider) Model() models.Model {
 	return lp.wrapped.Model()
 }
 
+// Warmup warms up the underlying provider
+func (lp *LoggingProvider) Warmup(ctx context.Context) error {
+	return provider.Warmup(ctx, lp.wrapped)
+}
+
 // SendMessages implements the Provider interface
 func (lp *LoggingProvider) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*provider.ProviderResponse, error) {
 	if lp.logger == nil || !lp.logger.IsEnabled() {
@@ -63,6 +68,7 @@ func (lp *LoggingProvider) SendMessages(ctx context.Context, messages []message.
 	llmLog := &LLMCallLog{
 		ID:        NewID(),
 		SessionID: lp.logger.sessionID,
+		ChatSessionID: chatSessionID(ctx),
 		Provider:  lp.provider,
 		Model:     string(lp.wrapped.Model().ID),
 		StartTime: time.Now(),
@@ -117,6 +123,7 @@ func (lp *LoggingProvider) StreamResponse(ctx context.Context, messages []messag
 	llmLog := &LLMCallLog{
 		ID:           NewID(),
 		SessionID:    lp.logger.sessionID,
+		ChatSessionID: chatSessionID(ctx),
 		Provider:     lp.provider,
 		Model:        string(lp.wrapped.Model().ID),
 		StartTime:    time.Now(),
@@ -179,6 +186,12 @@ func (lp *LoggingProvider) StreamResponse(ctx context.Context, messages []messag
 
 // Helper methods
 
+// chatSessionID returns the chat session a request is made for
+func chatSessionID(ctx context.Context) string {
+	sessionID, _ := ctx.Value(tools.SessionIDContextKey).(string)
+	return sessionID
+}
+
 func (lp *LoggingProvider) messagesToMap(messages []message.Message) map[string]interface{} {
 	// Convert messages to a simple map format
 	result := make([]map[string]interface{}, len(messages))
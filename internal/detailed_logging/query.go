@@ -0,0 +1,253 @@
+package detailed_logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EntryKind is the kind of a logged call
+type EntryKind string
+
+const (
+	KindLLM        EntryKind = "llm"
+	KindTool       EntryKind = "tool"
+	KindHTTP       EntryKind = "http"
+	KindPermission EntryKind = "permission"
+	KindUser       EntryKind = "user"
+)
+
+// EntryKinds are the kinds of entries a query can select
+var EntryKinds = []EntryKind{KindLLM, KindTool, KindHTTP, KindPermission, KindUser}
+
+// Query selects the entries of the detailed logs. Zero fields match
+// everything.
+type Query struct {
+	ChatSessionID string    // Chat session the calls were made for
+	Run           string    // ID of the logged run, or a prefix of it
+	Kind          EntryKind // Kind of call
+	Name          string    // Tool name, model or URL, matched as a substring
+	ErrorsOnly    bool
+	Contains      string // Text in the input, output or error, case insensitive
+	Since         time.Time
+	Until         time.Time
+	Limit         int // Number of entries, the most recent ones
+}
+
+// Entry is a logged call matching a query
+type Entry struct {
+	Run           string
+	ChatSessionID string
+	Kind          EntryKind
+	ID            string
+	Name          string
+	Time          time.Time
+	DurationMs    int64
+	Error         string
+	Input         any
+	Output        any
+	Parent        string // Tool or LLM call the entry was made in
+}
+
+// DefaultDir returns the directory the detailed logs are stored in
+func DefaultDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".opencode", "detailed_logs"), nil
+}
+
+// QueryDir returns the entries of the logs stored in dir matching a query,
+// the most recent first
+func QueryDir(dir string, q Query) ([]Entry, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, path := range paths {
+		run := strings.TrimSuffix(filepath.Base(path), ".json")
+		if q.Run != "" && !strings.HasPrefix(run, q.Run) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session file: %w", err)
+		}
+		var session SessionLog
+		if err := json.Unmarshal(data, &session); err != nil {
+			// A log being written by another process can be cut short
+			continue
+		}
+		if !q.Until.IsZero() && session.StartTime.After(q.Until) {
+			continue
+		}
+		if !q.Since.IsZero() && session.EndTime != nil && session.EndTime.Before(q.Since) {
+			continue
+		}
+		entries = append(entries, Search(&session, q)...)
+	}
+	sortEntries(entries)
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+	}
+	return entries, nil
+}
+
+// Search returns the entries of a session log matching a query, the most
+// recent first. The limit of the query is not applied.
+func Search(session *SessionLog, q Query) []Entry {
+	if session == nil {
+		return nil
+	}
+
+	// HTTP calls only know the call they were made in
+	chatSessions := make(map[string]string)
+	for _, call := range session.LLMCalls {
+		chatSessions[call.ID] = call.ChatSessionID
+	}
+	for _, call := range session.ToolCalls {
+		chatSessions[call.ID] = call.ChatSessionID
+	}
+
+	var entries []Entry
+	for _, call := range session.LLMCalls {
+		entries = append(entries, Entry{
+			ChatSessionID: call.ChatSessionID,
+			Kind:          KindLLM,
+			ID:            call.ID,
+			Name:          call.Model,
+			Time:          call.StartTime,
+			DurationMs:    call.DurationMs,
+			Error:         call.Error,
+			Input:         call.Request,
+			Output:        call.Response,
+			Parent:        call.ParentToolCall,
+		})
+	}
+	for _, call := range session.ToolCalls {
+		entries = append(entries, Entry{
+			ChatSessionID: call.ChatSessionID,
+			Kind:          KindTool,
+			ID:            call.ID,
+			Name:          call.Name,
+			Time:          call.StartTime,
+			DurationMs:    call.DurationMs,
+			Error:         call.Error,
+			Input:         call.Input,
+			Output:        call.Output,
+			Parent:        call.ParentLLMCall,
+		})
+	}
+	for _, call := range session.HTTPCalls {
+		// Headers are left out, they hold the API keys
+		entries = append(entries, Entry{
+			ChatSessionID: chatSessions[call.ParentToolCall],
+			Kind:          KindHTTP,
+			ID:            call.ID,
+			Name:          call.Method + " " + call.URL,
+			Time:          call.StartTime,
+			DurationMs:    call.DurationMs,
+			Error:         httpError(call),
+			Input:         call.Body,
+			Output:        call.ResponseBody,
+			Parent:        call.ParentToolCall,
+		})
+	}
+	for _, wait := range session.PermissionWaits {
+		entry := Entry{
+			ChatSessionID: wait.ChatSessionID,
+			Kind:          KindPermission,
+			ID:            wait.ID,
+			Name:          wait.ToolName,
+			Time:          wait.StartTime,
+			DurationMs:    wait.DurationMs,
+			Output:        map[string]bool{"granted": wait.Granted},
+		}
+		if !wait.Granted {
+			entry.Error = "permission denied"
+		}
+		entries = append(entries, entry)
+	}
+	for _, turn := range session.UserTurns {
+		entries = append(entries, Entry{
+			ChatSessionID: turn.ChatSessionID,
+			Kind:          KindUser,
+			ID:            turn.ID,
+			Time:          turn.Time,
+			Input:         turn.Preview,
+		})
+	}
+
+	matching := entries[:0]
+	for _, entry := range entries {
+		entry.Run = session.ID
+		if q.matches(entry) {
+			matching = append(matching, entry)
+		}
+	}
+	sortEntries(matching)
+	return matching
+}
+
+// httpError returns the error of an HTTP call, failed status codes included
+func httpError(call HTTPLog) string {
+	if call.Error != "" {
+		return call.Error
+	}
+	if call.StatusCode >= 400 {
+		return fmt.Sprintf("HTTP %d", call.StatusCode)
+	}
+	return ""
+}
+
+func (q Query) matches(entry Entry) bool {
+	switch {
+	case q.ChatSessionID != "" && entry.ChatSessionID != q.ChatSessionID:
+		return false
+	case q.Kind != "" && entry.Kind != q.Kind:
+		return false
+	case q.Name != "" && !strings.Contains(strings.ToLower(entry.Name), strings.ToLower(q.Name)):
+		return false
+	case q.ErrorsOnly && entry.Error == "":
+		return false
+	case !q.Since.IsZero() && entry.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && entry.Time.After(q.Until):
+		return false
+	}
+	if q.Contains == "" {
+		return true
+	}
+	text := strings.ToLower(q.Contains)
+	return strings.Contains(strings.ToLower(entry.Error), text) ||
+		strings.Contains(strings.ToLower(EntryText(entry.Input)), text) ||
+		strings.Contains(strings.ToLower(EntryText(entry.Output)), text)
+}
+
+// EntryText returns the input or output of an entry as text, JSON unless
+// it's a string
+func EntryText(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func sortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+}
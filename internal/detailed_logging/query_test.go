@@ -0,0 +1,105 @@
+package detailed_logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func querySession(id string, start time.Time) *SessionLog {
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+	return &SessionLog{
+		ID:        id,
+		StartTime: start,
+		UserTurns: []UserTurnLog{
+			{ID: "turn", ChatSessionID: "chat", Time: at(0), Preview: "run the tests"},
+		},
+		LLMCalls: []LLMCallLog{
+			{ID: "llm", ChatSessionID: "chat", Model: "claude-sonnet", StartTime: at(1)},
+		},
+		ToolCalls: []ToolCallLog{
+			{ID: "bash", ChatSessionID: "chat", Name: "bash", StartTime: at(2), Input: map[string]any{"command": "go test ./..."}, Error: "exit status 1"},
+			{ID: "view", ChatSessionID: "other", Name: "view", StartTime: at(3), Output: "package main"},
+		},
+		HTTPCalls: []HTTPLog{
+			{ID: "http", Method: "GET", URL: "https://example.com", StartTime: at(4), StatusCode: 503, ParentToolCall: "bash",
+				Headers: map[string][]string{"Authorization": {"Bearer secret"}}},
+		},
+		PermissionWaits: []PermissionWaitLog{
+			{ID: "wait", ChatSessionID: "chat", ToolName: "bash", StartTime: at(5), EndTime: at(6)},
+		},
+	}
+}
+
+func TestSearch(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	session := querySession("run", start)
+
+	ids := func(entries []Entry) []string {
+		var ids []string
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+		}
+		return ids
+	}
+
+	all := Search(session, Query{})
+	assert.Equal(t, []string{"wait", "http", "view", "bash", "llm", "turn"}, ids(all))
+	assert.Equal(t, "run", all[0].Run)
+
+	assert.Equal(t, []string{"wait", "http", "bash", "llm", "turn"}, ids(Search(session, Query{ChatSessionID: "chat"})), "HTTP calls belong to the session of their tool call")
+	assert.Equal(t, []string{"wait", "http", "bash"}, ids(Search(session, Query{ChatSessionID: "chat", ErrorsOnly: true})))
+	assert.Equal(t, []string{"bash"}, ids(Search(session, Query{Kind: KindTool, ErrorsOnly: true})))
+	assert.Equal(t, []string{"llm"}, ids(Search(session, Query{Name: "Sonnet"})))
+	assert.Equal(t, []string{"bash"}, ids(Search(session, Query{Contains: "GO TEST"})))
+	assert.Equal(t, []string{"view", "bash"}, ids(Search(session, Query{Since: start.Add(2 * time.Second), Until: start.Add(3 * time.Second)})))
+
+	http := Search(session, Query{Kind: KindHTTP})
+	require.Len(t, http, 1)
+	assert.Equal(t, "HTTP 503", http[0].Error)
+	assert.Equal(t, "GET https://example.com", http[0].Name)
+	assert.Empty(t, Search(session, Query{Contains: "secret"}), "headers are not searched")
+}
+
+func TestQueryDir(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, id := range []string{"aaa-run", "bbb-run"} {
+		data, err := json.Marshal(querySession(id, start.Add(time.Duration(i)*time.Hour)))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, id+".json"), data, 0o644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "partial.json"), []byte(`{"id": "par`), 0o644))
+
+	entries, err := QueryDir(dir, Query{Kind: KindTool, ErrorsOnly: true})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "bbb-run", entries[0].Run, "most recent first")
+
+	entries, err = QueryDir(dir, Query{Kind: KindTool, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "view", entries[0].ID)
+
+	entries, err = QueryDir(dir, Query{Run: "aaa", Kind: KindUser})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "aaa-run", entries[0].Run)
+
+	entries, err = QueryDir(filepath.Join(dir, "missing"), Query{})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestEntryText(t *testing.T) {
+	assert.Equal(t, "", EntryText(nil))
+	assert.Equal(t, "text", EntryText("text"))
+	assert.Equal(t, `{"a":1}`, EntryText(map[string]int{"a": 1}))
+}
@@ -0,0 +1,206 @@
+package detailed_logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces the text redacted from the logs
+const Redacted = "[redacted]"
+
+// Redaction is the content removed from the logs, such as a secret pasted by
+// mistake
+type Redaction struct {
+	Texts      []string // Text replaced wherever it's logged
+	MessageIDs []string // Chat messages whose logged copies are redacted as a whole
+}
+
+func (r Redaction) empty() bool {
+	return len(r.Texts) == 0 && len(r.MessageIDs) == 0
+}
+
+// RedactText replaces the texts in s, as they are and JSON encoded for the
+// bodies logged as text
+func RedactText(s string, texts ...string) string {
+	for _, text := range texts {
+		if text == "" {
+			continue
+		}
+		s = strings.ReplaceAll(s, text, Redacted)
+		if encoded := jsonEscape(text); encoded != text {
+			s = strings.ReplaceAll(s, encoded, Redacted)
+		}
+	}
+	return s
+}
+
+// jsonEscape returns a text as it's encoded in a JSON string
+func jsonEscape(text string) string {
+	data, _ := json.Marshal(text)
+	return string(data[1 : len(data)-1])
+}
+
+// Redact removes content from the session being logged and the stored logs of
+// earlier runs. It returns the number of logs changed.
+func (dl *DetailedLogger) Redact(r Redaction) (int, error) {
+	if !dl.IsEnabled() || r.empty() {
+		return 0, nil
+	}
+
+	changed := 0
+	dl.mu.Lock()
+	redacted, ok, err := redactSession(dl.session, r)
+	if err == nil && ok {
+		*dl.session = *redacted
+		changed++
+	}
+	dl.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		dl.saveSession()
+	}
+
+	stored, err := dl.storage.Redact(r, dl.sessionID)
+	return changed + stored, err
+}
+
+// Redact removes content from the stored logs, except the log of the given
+// session which is still being written. It returns the number of logs
+// changed.
+func (s *Storage) Redact(r Redaction, except string) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dataDir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	var errs []error
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), ".json")
+		if id == except {
+			continue
+		}
+		session, err := s.LoadSession(id)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		redacted, ok, err := redactSession(session, r)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to redact %s: %w", id, err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := s.SaveSession(redacted); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		changed++
+	}
+	if len(errs) > 0 {
+		return changed, fmt.Errorf("failed to redact %d logs: %w", len(errs), errs[0])
+	}
+	return changed, nil
+}
+
+// redactSession returns a redacted copy of a session log and whether anything
+// was redacted. The copy goes through JSON so the values logged with their Go
+// types are redacted like the ones read back from storage.
+func redactSession(session *SessionLog, r Redaction) (*SessionLog, bool, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, false, err
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, false, err
+	}
+	// Encoded again to compare with the redacted value, the keys of a map
+	// are sorted unlike the fields of a struct
+	if data, err = json.Marshal(generic); err != nil {
+		return nil, false, err
+	}
+	redactedData, err := json.Marshal(r.redactValue(generic))
+	if err != nil {
+		return nil, false, err
+	}
+	var redacted SessionLog
+	if err := json.Unmarshal(redactedData, &redacted); err != nil {
+		return nil, false, err
+	}
+	streamed := false
+	for i := range redacted.LLMCalls {
+		for _, key := range []string{"content", "thinking"} {
+			streamed = r.redactStream(redacted.LLMCalls[i].StreamEvents, key) || streamed
+		}
+	}
+	return &redacted, streamed || string(redactedData) != string(data), nil
+}
+
+// redactValue redacts the strings of a value decoded from JSON. The logged
+// copies of the redacted messages, the messages of LLM requests and the turns
+// of the user, keep their ID but lose their content.
+func (r Redaction) redactValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		return RedactText(v, r.Texts...)
+	case []any:
+		for i, item := range v {
+			v[i] = r.redactValue(item)
+		}
+		return v
+	case map[string]any:
+		if id, ok := v["id"].(string); ok && slices.Contains(r.MessageIDs, id) {
+			if _, ok := v["parts"]; ok {
+				v["parts"] = []any{map[string]any{"type": "text", "text": Redacted}}
+			}
+			if _, ok := v["preview"]; ok {
+				v["preview"] = Redacted
+			}
+		}
+		for key, item := range v {
+			v[key] = r.redactValue(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// redactStream redacts a text split across the deltas of a stream: when the
+// deltas joined contain it, the redacted text goes in the first delta and the
+// others are emptied
+func (r Redaction) redactStream(events []StreamEvent, key string) bool {
+	var joined strings.Builder
+	first := -1
+	for i, event := range events {
+		if text, ok := event.Data[key].(string); ok {
+			joined.WriteString(text)
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	redacted := RedactText(joined.String(), r.Texts...)
+	if redacted == joined.String() {
+		return false
+	}
+	for i, event := range events {
+		if _, ok := event.Data[key].(string); ok {
+			event.Data[key] = ""
+			if i == first {
+				event.Data[key] = redacted
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package detailed_logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactText(t *testing.T) {
+	assert.Equal(t, "key=[redacted] and [redacted]", RedactText("key=sk-123 and sk-123", "sk-123"))
+	assert.Equal(t, `{"text":"[redacted]"}`, RedactText(`{"text":"line\n\"quoted\""}`, "line\n\"quoted\""))
+	assert.Equal(t, "unchanged", RedactText("unchanged", ""))
+}
+
+func TestRedactSession(t *testing.T) {
+	session := &SessionLog{
+		ID: "log",
+		LLMCalls: []LLMCallLog{{
+			ID: "call",
+			Request: map[string]interface{}{"messages": []map[string]interface{}{
+				{"id": "msg-1", "role": "user", "parts": []interface{}{map[string]interface{}{"type": "text", "text": "my address is 1 Main St"}}},
+				{"id": "msg-2", "role": "user", "parts": []interface{}{map[string]interface{}{"type": "text", "text": "token sk-123"}}},
+			}},
+			StreamEvents: []StreamEvent{
+				{Type: "content_delta", Data: map[string]interface{}{"content": "your token sk-"}},
+				{Type: "content_delta", Data: map[string]interface{}{"content": "123 works"}},
+			},
+		}},
+		HTTPCalls: []HTTPLog{{ID: "http", Body: `{"content":"token sk-123"}`}},
+		UserTurns: []UserTurnLog{{ID: "msg-1", Preview: "my address is 1 Main St"}},
+	}
+
+	redacted, ok, err := redactSession(session, Redaction{Texts: []string{"sk-123"}, MessageIDs: []string{"msg-1"}})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	messages := redacted.LLMCalls[0].Request["messages"].([]any)
+	assert.Equal(t, []any{map[string]any{"type": "text", "text": Redacted}}, messages[0].(map[string]any)["parts"])
+	assert.Equal(t, "msg-1", messages[0].(map[string]any)["id"])
+	assert.Equal(t, "token [redacted]", messages[1].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"])
+	assert.Equal(t, "your token [redacted] works", redacted.LLMCalls[0].StreamEvents[0].Data["content"])
+	assert.Equal(t, "", redacted.LLMCalls[0].StreamEvents[1].Data["content"])
+	assert.Equal(t, `{"content":"token [redacted]"}`, redacted.HTTPCalls[0].Body)
+	assert.Equal(t, Redacted, redacted.UserTurns[0].Preview)
+
+	// The original is left as it is
+	assert.Equal(t, "123 works", session.LLMCalls[0].StreamEvents[1].Data["content"])
+
+	_, ok, err = redactSession(redacted, Redaction{Texts: []string{"sk-123"}})
+	require.NoError(t, err)
+	assert.False(t, ok, "nothing is left to redact")
+}
@@ -0,0 +1,173 @@
+package detailed_logging
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// TimelineLane is a row of the timeline, one per kind of activity
+type TimelineLane string
+
+const (
+	LaneUser       TimelineLane = "user"
+	LaneLLM        TimelineLane = "llm"
+	LaneTool       TimelineLane = "tool"
+	LanePermission TimelineLane = "permission"
+)
+
+// TimelineLanes are the lanes in the order they are shown
+var TimelineLanes = []TimelineLane{LaneUser, LaneLLM, LaneTool, LanePermission}
+
+// TimelineSpan is an activity of a session, user turns are spans without a
+// duration
+type TimelineSpan struct {
+	Lane    TimelineLane
+	Label   string
+	Start   time.Time
+	End     time.Time
+	Error   bool
+	Running bool
+}
+
+// Duration returns how long the activity took
+func (s TimelineSpan) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Timeline is the chronological activity of a chat session
+type Timeline struct {
+	Start time.Time
+	End   time.Time
+	Spans []TimelineSpan
+}
+
+// BuildTimeline collects the activity of a chat session from a session log.
+// Calls that haven't ended yet run until now.
+func BuildTimeline(session *SessionLog, chatSessionID string, now time.Time) Timeline {
+	var timeline Timeline
+	if session == nil {
+		return timeline
+	}
+
+	for _, turn := range session.UserTurns {
+		if turn.ChatSessionID == chatSessionID {
+			timeline.add(TimelineSpan{Lane: LaneUser, Label: turn.Preview, Start: turn.Time, End: turn.Time})
+		}
+	}
+	for _, call := range session.LLMCalls {
+		if call.ChatSessionID == chatSessionID {
+			span := TimelineSpan{Lane: LaneLLM, Label: call.Model, Start: call.StartTime, Error: call.Error != ""}
+			span.End, span.Running = spanEnd(call.StartTime, call.EndTime, now)
+			timeline.add(span)
+		}
+	}
+	for _, call := range session.ToolCalls {
+		if call.ChatSessionID == chatSessionID {
+			span := TimelineSpan{Lane: LaneTool, Label: call.Name, Start: call.StartTime, Error: call.Error != ""}
+			span.End, span.Running = spanEnd(call.StartTime, call.EndTime, now)
+			timeline.add(span)
+		}
+	}
+	for _, wait := range session.PermissionWaits {
+		if wait.ChatSessionID == chatSessionID {
+			timeline.add(TimelineSpan{Lane: LanePermission, Label: wait.ToolName, Start: wait.StartTime, End: wait.EndTime, Error: !wait.Granted})
+		}
+	}
+
+	sort.SliceStable(timeline.Spans, func(i, j int) bool {
+		return timeline.Spans[i].Start.Before(timeline.Spans[j].Start)
+	})
+	return timeline
+}
+
+func spanEnd(start time.Time, end *time.Time, now time.Time) (time.Time, bool) {
+	if end == nil {
+		return now, true
+	}
+	if end.Before(start) {
+		return start, false
+	}
+	return *end, false
+}
+
+func (t *Timeline) add(span TimelineSpan) {
+	if len(t.Spans) == 0 || span.Start.Before(t.Start) {
+		t.Start = span.Start
+	}
+	if len(t.Spans) == 0 || span.End.After(t.End) {
+		t.End = span.End
+	}
+	t.Spans = append(t.Spans, span)
+}
+
+// Duration returns the time from the first to the last activity
+func (t Timeline) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// Busy returns the time a lane was busy, overlapping spans counted once
+func (t Timeline) Busy(lane TimelineLane) time.Duration {
+	var busy time.Duration
+	var until time.Time
+	for _, span := range t.Spans {
+		if span.Lane != lane {
+			continue
+		}
+		start := span.Start
+		if start.Before(until) {
+			start = until
+		}
+		if span.End.After(start) {
+			busy += span.End.Sub(start)
+			until = span.End
+		}
+	}
+	return busy
+}
+
+// Slowest returns the longest activities, the likely bottlenecks
+func (t Timeline) Slowest(n int) []TimelineSpan {
+	var spans []TimelineSpan
+	for _, span := range t.Spans {
+		if span.Lane != LaneUser {
+			spans = append(spans, span)
+		}
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].Duration() > spans[j].Duration()
+	})
+	if len(spans) > n {
+		spans = spans[:n]
+	}
+	return spans
+}
+
+// Chart draws a lane as a row of width cells over the duration of the
+// timeline: █ when busy, │ for user turns and · when idle
+func (t Timeline) Chart(lane TimelineLane, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	cells := []rune(strings.Repeat("·", width))
+	total := t.Duration()
+	cell := func(at time.Time) int {
+		if total <= 0 {
+			return 0
+		}
+		return min(width-1, int(int64(at.Sub(t.Start))*int64(width)/int64(total)))
+	}
+	for _, span := range t.Spans {
+		if span.Lane != lane {
+			continue
+		}
+		if lane == LaneUser {
+			cells[cell(span.Start)] = '│'
+			continue
+		}
+		for i := cell(span.Start); i <= cell(span.End); i++ {
+			cells[i] = '█'
+		}
+	}
+	return string(cells)
+}
@@ -0,0 +1,76 @@
+package detailed_logging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+	end := func(seconds int) *time.Time {
+		t := at(seconds)
+		return &t
+	}
+
+	session := &SessionLog{
+		UserTurns: []UserTurnLog{
+			{ChatSessionID: "chat", Time: at(0), Preview: "fix the tests"},
+			{ChatSessionID: "other", Time: at(1), Preview: "unrelated"},
+		},
+		LLMCalls: []LLMCallLog{
+			{ChatSessionID: "chat", Model: "claude", StartTime: at(1), EndTime: end(4)},
+			{ChatSessionID: "chat", Model: "claude", StartTime: at(8), Error: "overloaded"},
+		},
+		ToolCalls: []ToolCallLog{
+			{ChatSessionID: "chat", Name: "bash", StartTime: at(4), EndTime: end(7)},
+		},
+		PermissionWaits: []PermissionWaitLog{
+			{ChatSessionID: "chat", ToolName: "bash", StartTime: at(4), EndTime: at(6), Granted: true},
+		},
+	}
+
+	timeline := BuildTimeline(session, "chat", at(10))
+	require.Len(t, timeline.Spans, 5)
+	assert.Equal(t, at(0), timeline.Start)
+	assert.Equal(t, at(10), timeline.End)
+
+	assert.Equal(t, LaneUser, timeline.Spans[0].Lane)
+	assert.Equal(t, "fix the tests", timeline.Spans[0].Label)
+	running := timeline.Spans[4]
+	assert.Equal(t, LaneLLM, running.Lane)
+	assert.True(t, running.Running)
+	assert.True(t, running.Error)
+	assert.Equal(t, 2*time.Second, running.Duration())
+
+	assert.Equal(t, 5*time.Second, timeline.Busy(LaneLLM))
+	assert.Equal(t, 2*time.Second, timeline.Busy(LanePermission))
+
+	slowest := timeline.Slowest(2)
+	require.Len(t, slowest, 2)
+	assert.Equal(t, LaneLLM, slowest[0].Lane)
+	assert.Equal(t, 3*time.Second, slowest[0].Duration())
+}
+
+func TestTimelineChart(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeline := Timeline{
+		Start: start,
+		End:   start.Add(10 * time.Second),
+		Spans: []TimelineSpan{
+			{Lane: LaneUser, Start: start, End: start},
+			{Lane: LaneLLM, Start: start.Add(2 * time.Second), End: start.Add(4 * time.Second)},
+		},
+	}
+
+	assert.Equal(t, "│·········", timeline.Chart(LaneUser, 10))
+	assert.Equal(t, "··███·····", timeline.Chart(LaneLLM, 10))
+	assert.Equal(t, "··········", timeline.Chart(LaneTool, 10))
+	assert.Empty(t, timeline.Chart(LaneLLM, 0))
+	assert.Nil(t, BuildTimeline(nil, "chat", start).Spans)
+}
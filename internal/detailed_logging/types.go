@@ -15,6 +15,8 @@ type SessionLog struct {
 	LLMCalls    []LLMCallLog      `json:"llm_calls"`
 	ToolCalls   []ToolCallLog     `json:"tool_calls"`
 	HTTPCalls   []HTTPLog         `json:"http_calls"`
+	UserTurns   []UserTurnLog     `json:"user_turns,omitempty"`
+	PermissionWaits []PermissionWaitLog `json:"permission_waits,omitempty"`
 	CommandArgs []string          `json:"command_args"`
 	UserID      string            `json:"user_id,omitempty"`
 }
@@ -23,6 +25,7 @@ type SessionLog struct {
 type LLMCallLog struct {
 	ID             string                 `json:"id"`
 	SessionID      string                 `json:"session_id"`
+	ChatSessionID  string                 `json:"chat_session_id,omitempty"`
 	Provider       string                 `json:"provider"`
 	Model          string                 `json:"model"`
 	StartTime      time.Time              `json:"start_time"`
@@ -55,6 +58,7 @@ type TokenUsage struct {
 type ToolCallLog struct {
 	ID           string                 `json:"id"`
 	SessionID    string                 `json:"session_id"`
+	ChatSessionID string                `json:"chat_session_id,omitempty"`
 	Name         string                 `json:"name"`
 	StartTime    time.Time              `json:"start_time"`
 	EndTime      *time.Time             `json:"end_time,omitempty"`
@@ -67,6 +71,26 @@ type ToolCallLog struct {
 	ParentLLMCall string                `json:"parent_llm_call,omitempty"`
 }
 
+// UserTurnLog represents a message the user sent to the agent
+type UserTurnLog struct {
+	ID            string    `json:"id"`
+	ChatSessionID string    `json:"chat_session_id"`
+	Time          time.Time `json:"time"`
+	Preview       string    `json:"preview"`
+}
+
+// PermissionWaitLog represents the time a tool waited for the user to answer
+// a permission request
+type PermissionWaitLog struct {
+	ID            string    `json:"id"`
+	ChatSessionID string    `json:"chat_session_id"`
+	ToolName      string    `json:"tool_name"`
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+	DurationMs    int64     `json:"duration_ms"`
+	Granted       bool      `json:"granted"`
+}
+
 // HTTPLog represents an HTTP request/response
 type HTTPLog struct {
 	ID           string                 `json:"id"`
@@ -0,0 +1,169 @@
+// Package dryrun runs the agent on a scratch worktree of the repository, so the
+// changes it proposes can be printed as a diff instead of being applied to the
+// working tree of the user.
+package dryrun
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Workspace is a scratch worktree holding the working tree as it was when the
+// run started, uncommitted changes and untracked files included
+type Workspace struct {
+	Dir        string // Root of the scratch worktree
+	WorkingDir string // The working directory of the run, in the scratch worktree
+
+	repo string // Root of the repository
+	base string // Tree of the scratch worktree when it was created
+}
+
+// New creates the scratch worktree of the repository of a directory in a
+// temporary directory. Ignored files, like dependencies and build outputs,
+// aren't copied.
+func New(ctx context.Context, workingDir string) (*Workspace, error) {
+	if resolved, err := filepath.EvalSymlinks(workingDir); err == nil {
+		workingDir = resolved
+	}
+	out, err := git(ctx, workingDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("a dry run needs a git repository: %w", err)
+	}
+	repo := strings.TrimSpace(out)
+	rel, err := filepath.Rel(repo, workingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// A commit of the uncommitted changes, empty when there are none. It
+	// isn't stored in a ref, the stash of the user is left alone.
+	out, err = git(ctx, repo, "stash", "create")
+	if err != nil {
+		return nil, err
+	}
+	start := strings.TrimSpace(out)
+	if start == "" {
+		start = "HEAD"
+	}
+
+	dir, err := os.MkdirTemp("", "opencode-dryrun-*")
+	if err != nil {
+		return nil, err
+	}
+	w := &Workspace{Dir: dir, WorkingDir: filepath.Join(dir, rel), repo: repo}
+	if _, err := git(ctx, repo, "worktree", "add", "--detach", "--quiet", dir, start); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	if err := w.copyUntracked(ctx); err != nil {
+		w.Remove()
+		return nil, err
+	}
+	if w.base, err = w.tree(ctx); err != nil {
+		w.Remove()
+		return nil, err
+	}
+	return w, nil
+}
+
+// copyUntracked copies the untracked files that aren't ignored into the
+// scratch worktree
+func (w *Workspace) copyUntracked(ctx context.Context) error {
+	out, err := git(ctx, w.repo, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return err
+	}
+	for _, name := range strings.Split(out, "\x00") {
+		if name == "" {
+			continue
+		}
+		if err := copyFile(filepath.Join(w.repo, name), filepath.Join(w.Dir, name)); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// tree stages everything in the scratch worktree and returns its tree
+func (w *Workspace) tree(ctx context.Context) (string, error) {
+	if _, err := git(ctx, w.Dir, "add", "--all"); err != nil {
+		return "", err
+	}
+	out, err := git(ctx, w.Dir, "write-tree")
+	return strings.TrimSpace(out), err
+}
+
+// Diff returns the changes made in the scratch worktree since it was created
+// as a unified diff with paths relative to the root of the repository, as
+// "git apply" reads it. Binary files are included.
+func (w *Workspace) Diff(ctx context.Context) (string, error) {
+	tree, err := w.tree(ctx)
+	if err != nil {
+		return "", err
+	}
+	return git(ctx, w.Dir, "diff", "--binary", "--no-color", "--no-ext-diff", "--no-textconv",
+		"--src-prefix=a/", "--dst-prefix=b/", w.base, tree)
+}
+
+// Remove deletes the scratch worktree
+func (w *Workspace) Remove() error {
+	_, err := git(context.Background(), w.repo, "worktree", "remove", "--force", w.Dir)
+	if err != nil {
+		os.RemoveAll(w.Dir)
+		git(context.Background(), w.repo, "worktree", "prune")
+	}
+	return err
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,94 @@
+package dryrun
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestWorkspaceDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	writeFile(t, repo, "src/main.go", "package main\n")
+	writeFile(t, repo, "README.md", "readme\n")
+	writeFile(t, repo, ".gitignore", "build/\n")
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "test"},
+		{"add", "-A"},
+		{"commit", "-q", "-m", "init"},
+	} {
+		_, err := git(ctx, repo, args...)
+		require.NoError(t, err)
+	}
+	// Uncommitted, untracked and ignored files of the user
+	writeFile(t, repo, "README.md", "readme\nchanged\n")
+	writeFile(t, repo, "src/notes.txt", "notes\n")
+	writeFile(t, repo, "build/out", "binary\n")
+
+	w, err := New(ctx, filepath.Join(repo, "src"))
+	require.NoError(t, err)
+	defer w.Remove()
+	assert.Equal(t, filepath.Join(w.Dir, "src"), w.WorkingDir)
+	data, err := os.ReadFile(filepath.Join(w.Dir, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "readme\nchanged\n", string(data), "uncommitted changes are copied")
+	assert.FileExists(t, filepath.Join(w.Dir, "src", "notes.txt"))
+	assert.NoFileExists(t, filepath.Join(w.Dir, "build", "out"))
+
+	diff, err := w.Diff(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+
+	writeFile(t, w.WorkingDir, "main.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, w.WorkingDir, "util/util.go", "package util\n")
+	require.NoError(t, os.Remove(filepath.Join(w.WorkingDir, "notes.txt")))
+	writeFile(t, w.Dir, "build/other", "ignored\n")
+	diff, err = w.Diff(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, diff, "diff --git a/src/main.go b/src/main.go\n")
+	assert.Contains(t, diff, "+func main() {}\n")
+	assert.Contains(t, diff, "new file mode 100644")
+	assert.Contains(t, diff, "deleted file mode 100644")
+	assert.NotContains(t, diff, "build/other")
+
+	// The working tree of the user is untouched, and the diff applies to it
+	data, err = os.ReadFile(filepath.Join(repo, "src", "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n", string(data))
+	cmd := exec.Command("git", "apply")
+	cmd.Dir = repo
+	cmd.Stdin = strings.NewReader(diff)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	assert.FileExists(t, filepath.Join(repo, "src", "util", "util.go"))
+	assert.NoFileExists(t, filepath.Join(repo, "src", "notes.txt"))
+
+	require.NoError(t, w.Remove())
+	assert.NoDirExists(t, w.Dir)
+}
+
+func TestNewOutsideRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	_, err := New(context.Background(), t.TempDir())
+	assert.ErrorContains(t, err, "needs a git repository")
+}
@@ -0,0 +1,369 @@
+// Package editor serves a small JSON protocol for editor plugins over a unix
+// socket or stdio: plugins send the current buffer or selection as context,
+// run prompts in sessions and receive the changes of the agent as diffs to
+// apply.
+//
+// Every message is one JSON line. Requests carry an id, echoed by their
+// response, and run concurrently, so a plugin can cancel a running prompt on
+// the same connection. Closing the connection cancels its running prompts.
+package editor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kirmad/superopencode/internal/fileutil"
+	"github.com/kirmad/superopencode/internal/logging"
+)
+
+// SocketName is the name of the socket in the data directory of a project
+const SocketName = "editor.sock"
+
+// Methods of a request
+const (
+	MethodInitialize = "initialize"
+	MethodNewSession = "session/new"
+	MethodAddContext = "context/add"
+	MethodPrompt     = "prompt"
+	MethodCancel     = "cancel"
+)
+
+// Methods lists the methods the server handles
+var Methods = []string{MethodInitialize, MethodNewSession, MethodAddContext, MethodPrompt, MethodCancel}
+
+// SocketPath returns the socket of the bridge of the project with this data
+// directory
+func SocketPath(dataDir string) string {
+	return filepath.Join(dataDir, SocketName)
+}
+
+// Request is sent by the plugin
+type Request struct {
+	ID     int64           `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response answers the request with the same id, with either a result or an
+// error
+type Response struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Buffer is a file open in the editor, or a selection of it when the lines
+// are set. The text is what the editor shows, saved or not.
+type Buffer struct {
+	Path      string `json:"path"`
+	Text      string `json:"text,omitempty"`
+	StartLine int    `json:"start_line,omitempty"` // 1-based, inclusive
+	EndLine   int    `json:"end_line,omitempty"`
+	Language  string `json:"language,omitempty"`
+}
+
+// Info describes the server, the result of initialize
+type Info struct {
+	Version          string   `json:"version"`
+	WorkingDirectory string   `json:"working_directory"`
+	Model            string   `json:"model"`
+	Methods          []string `json:"methods"`
+}
+
+// NewSessionParams are the params of session/new
+type NewSessionParams struct {
+	Title string `json:"title,omitempty"`
+}
+
+// NewSessionResult is the result of session/new
+type NewSessionResult struct {
+	SessionID string `json:"session_id"`
+}
+
+// AddContextParams are the params of context/add, which pins the buffer to
+// the session so every later prompt sees it
+type AddContextParams struct {
+	SessionID string `json:"session_id"`
+	Buffer    Buffer `json:"buffer"`
+}
+
+// AddContextResult is the result of context/add
+type AddContextResult struct {
+	ID     int `json:"id"` // Number of the pin, for /unpin
+	Tokens int `json:"tokens"`
+}
+
+// PromptParams are the params of prompt. Without a session ID the prompt
+// runs in a new session. The changes of the agent are reverted on disk and
+// only returned as diffs, unless Apply is set.
+type PromptParams struct {
+	SessionID string   `json:"session_id,omitempty"`
+	Prompt    string   `json:"prompt"`
+	Context   []Buffer `json:"context,omitempty"` // Sent with this prompt only
+	Apply     bool     `json:"apply,omitempty"`
+}
+
+// FileDiff is the change of a file during a prompt
+type FileDiff struct {
+	Path      string `json:"path"`    // Relative to the working directory
+	Diff      string `json:"diff"`    // Unified diff
+	Content   string `json:"content"` // Content after the change, empty for deleted files
+	Additions int    `json:"additions"`
+	Removals  int    `json:"removals"`
+}
+
+// PromptResult is the result of prompt
+type PromptResult struct {
+	SessionID        string     `json:"session_id"`
+	Response         string     `json:"response"`
+	Diffs            []FileDiff `json:"diffs"`
+	Applied          bool       `json:"applied"` // Whether the changes were kept on disk
+	PromptTokens     int64      `json:"prompt_tokens"`
+	CompletionTokens int64      `json:"completion_tokens"`
+	Cost             float64    `json:"cost"` // Of the session so far
+}
+
+// CancelParams are the params of cancel
+type CancelParams struct {
+	SessionID string `json:"session_id"`
+}
+
+// Engine runs the requests of the plugins
+type Engine interface {
+	Info() Info
+	NewSession(ctx context.Context, title string) (string, error)
+	AddContext(ctx context.Context, sessionID string, buffer Buffer) (AddContextResult, error)
+	Prompt(ctx context.Context, params PromptParams) (PromptResult, error)
+	Cancel(sessionID string)
+}
+
+// FormatBuffer formats a buffer as context for the model
+func FormatBuffer(buffer Buffer) string {
+	var sb strings.Builder
+	switch {
+	case buffer.StartLine > 0 && buffer.EndLine > buffer.StartLine:
+		fmt.Fprintf(&sb, "Selection in %s (lines %d-%d):\n", buffer.Path, buffer.StartLine, buffer.EndLine)
+	case buffer.StartLine > 0:
+		fmt.Fprintf(&sb, "Selection in %s (line %d):\n", buffer.Path, buffer.StartLine)
+	case buffer.Text == "":
+		return fmt.Sprintf("File open in the editor: %s", buffer.Path)
+	default:
+		fmt.Fprintf(&sb, "Buffer of %s as shown in the editor:\n", buffer.Path)
+	}
+	fence := "```"
+	for strings.Contains(buffer.Text, fence) {
+		fence += "`"
+	}
+	sb.WriteString(fence + buffer.Language + "\n")
+	sb.WriteString(buffer.Text)
+	if !strings.HasSuffix(buffer.Text, "\n") {
+		sb.WriteString("\n")
+	}
+	sb.WriteString(fence)
+	return sb.String()
+}
+
+// Server serves the plugins connected to a socket or to stdio
+type Server struct {
+	engine   Engine
+	listener net.Listener
+	path     string
+	wg       sync.WaitGroup
+}
+
+// NewServer creates a server for plugins connecting with ServeConn, such as
+// a plugin running OpenCode and talking to it over stdio
+func NewServer(engine Engine) *Server {
+	return &Server{engine: engine}
+}
+
+// Listen creates the socket of the bridge. A socket left behind by a bridge
+// that exited is replaced, a running bridge is an error.
+func Listen(path string, engine Engine) (*Server, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("an editor bridge is already running on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove the stale socket: %w", err)
+	}
+	// Sessions of plugins run with every permission granted, only the user
+	// may connect
+	listener, err := fileutil.ListenUnix(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{engine: engine, listener: listener, path: path}, nil
+}
+
+// Serve handles the connections to the socket until ctx is done. Running
+// prompts are canceled and the socket is removed.
+func (s *Server) Serve(ctx context.Context) error {
+	if s.listener == nil {
+		return errors.New("the server doesn't listen on a socket")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		s.listener.Close()
+	}()
+
+	var err error
+	for {
+		conn, acceptErr := s.listener.Accept()
+		if acceptErr != nil {
+			if ctx.Err() == nil {
+				err = fmt.Errorf("failed to accept a connection: %w", acceptErr)
+			}
+			break
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer logging.RecoverPanic("editor-connection", nil)
+			s.ServeConn(ctx, conn)
+		}()
+	}
+
+	cancel()
+	s.wg.Wait()
+	os.Remove(s.path)
+	return err
+}
+
+// ServeConn handles the requests of one plugin until it closes the
+// connection or ctx is done
+func (s *Server) ServeConn(ctx context.Context, conn io.ReadWriteCloser) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	var (
+		mu       sync.Mutex // Serializes the responses
+		requests sync.WaitGroup
+	)
+	respond := func(response Response) {
+		data, err := json.Marshal(response)
+		if err != nil {
+			logging.Error("Failed to encode editor response", "error", err)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := conn.Write(append(data, '\n')); err != nil {
+			logging.Debug("Failed to write editor response", "error", err)
+		}
+	}
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var request Request
+			if jsonErr := json.Unmarshal(line, &request); jsonErr != nil {
+				respond(Response{Error: fmt.Sprintf("invalid request: %v", jsonErr)})
+			} else {
+				requests.Add(1)
+				go func() {
+					defer requests.Done()
+					defer logging.RecoverPanic("editor-request", func() {
+						respond(Response{ID: request.ID, Error: "the request crashed"})
+					})
+					respond(s.handle(ctx, request))
+				}()
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	// The plugin hung up, its prompts are of no use anymore
+	cancel()
+	requests.Wait()
+}
+
+func (s *Server) handle(ctx context.Context, request Request) Response {
+	result, err := s.call(ctx, request)
+	if err != nil {
+		return Response{ID: request.ID, Error: err.Error()}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return Response{ID: request.ID, Error: fmt.Sprintf("failed to encode the result: %v", err)}
+	}
+	return Response{ID: request.ID, Result: data}
+}
+
+func (s *Server) call(ctx context.Context, request Request) (any, error) {
+	switch request.Method {
+	case MethodInitialize:
+		return s.engine.Info(), nil
+	case MethodNewSession:
+		var params NewSessionParams
+		if err := decodeParams(request, &params); err != nil {
+			return nil, err
+		}
+		id, err := s.engine.NewSession(ctx, params.Title)
+		if err != nil {
+			return nil, err
+		}
+		return NewSessionResult{SessionID: id}, nil
+	case MethodAddContext:
+		var params AddContextParams
+		if err := decodeParams(request, &params); err != nil {
+			return nil, err
+		}
+		if params.SessionID == "" {
+			return nil, errors.New("session_id is required")
+		}
+		if params.Buffer.Path == "" && params.Buffer.Text == "" {
+			return nil, errors.New("the buffer needs a path or a text")
+		}
+		return s.engine.AddContext(ctx, params.SessionID, params.Buffer)
+	case MethodPrompt:
+		var params PromptParams
+		if err := decodeParams(request, &params); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(params.Prompt) == "" {
+			return nil, errors.New("prompt is required")
+		}
+		return s.engine.Prompt(ctx, params)
+	case MethodCancel:
+		var params CancelParams
+		if err := decodeParams(request, &params); err != nil {
+			return nil, err
+		}
+		if params.SessionID == "" {
+			return nil, errors.New("session_id is required")
+		}
+		s.engine.Cancel(params.SessionID)
+		return struct{}{}, nil
+	default:
+		return nil, fmt.Errorf("unknown method %q", request.Method)
+	}
+}
+
+func decodeParams(request Request, params any) error {
+	if len(request.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(request.Params, params); err != nil {
+		return fmt.Errorf("invalid params of %s: %w", request.Method, err)
+	}
+	return nil
+}
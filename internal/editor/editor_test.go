@@ -0,0 +1,142 @@
+package editor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEngine struct {
+	started  chan struct{}
+	canceled chan string
+}
+
+func (e *fakeEngine) Info() Info {
+	return Info{Version: "test", Methods: Methods}
+}
+
+func (e *fakeEngine) NewSession(ctx context.Context, title string) (string, error) {
+	return "session-" + title, nil
+}
+
+func (e *fakeEngine) AddContext(ctx context.Context, sessionID string, buffer Buffer) (AddContextResult, error) {
+	return AddContextResult{ID: 1, Tokens: len(buffer.Text) / 4}, nil
+}
+
+func (e *fakeEngine) Prompt(ctx context.Context, params PromptParams) (PromptResult, error) {
+	if params.Prompt == "wait" {
+		close(e.started)
+		<-ctx.Done()
+		return PromptResult{}, ctx.Err()
+	}
+	return PromptResult{
+		SessionID: params.SessionID,
+		Response:  "answer to " + params.Prompt,
+		Diffs:     []FileDiff{{Path: "main.go", Additions: 1}},
+	}, nil
+}
+
+func (e *fakeEngine) Cancel(sessionID string) {
+	e.canceled <- sessionID
+}
+
+type client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (c *client) send(t *testing.T, id int64, method string, params any) {
+	data, err := json.Marshal(params)
+	require.NoError(t, err)
+	line, err := json.Marshal(Request{ID: id, Method: method, Params: data})
+	require.NoError(t, err)
+	_, err = c.conn.Write(append(line, '\n'))
+	require.NoError(t, err)
+}
+
+func (c *client) receive(t *testing.T) Response {
+	require.NoError(t, c.conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	line, err := c.reader.ReadBytes('\n')
+	require.NoError(t, err)
+	var response Response
+	require.NoError(t, json.Unmarshal(line, &response))
+	return response
+}
+
+func TestServer(t *testing.T) {
+	path := SocketPath(t.TempDir())
+	engine := &fakeEngine{started: make(chan struct{}), canceled: make(chan string, 1)}
+	server, err := Listen(path, engine)
+	require.NoError(t, err)
+	stat, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), stat.Mode().Perm(), "only the user may connect")
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx) }()
+
+	_, err = Listen(path, engine)
+	assert.Error(t, err, "a second bridge should not replace the running one")
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	c := &client{conn: conn, reader: bufio.NewReader(conn)}
+
+	c.send(t, 1, MethodInitialize, nil)
+	response := c.receive(t)
+	assert.Equal(t, int64(1), response.ID)
+	var info Info
+	require.NoError(t, json.Unmarshal(response.Result, &info))
+	assert.Equal(t, "test", info.Version)
+
+	c.send(t, 2, MethodPrompt, PromptParams{SessionID: "s1", Prompt: "fix it"})
+	response = c.receive(t)
+	assert.Equal(t, int64(2), response.ID)
+	assert.Empty(t, response.Error)
+	var result PromptResult
+	require.NoError(t, json.Unmarshal(response.Result, &result))
+	assert.Equal(t, "answer to fix it", result.Response)
+	assert.Equal(t, "main.go", result.Diffs[0].Path)
+
+	c.send(t, 3, MethodPrompt, PromptParams{Prompt: " "})
+	assert.Equal(t, "prompt is required", c.receive(t).Error)
+	c.send(t, 4, MethodAddContext, AddContextParams{Buffer: Buffer{Path: "main.go"}})
+	assert.Equal(t, "session_id is required", c.receive(t).Error)
+	c.send(t, 5, "shutdown", nil)
+	assert.Equal(t, `unknown method "shutdown"`, c.receive(t).Error)
+
+	// Requests run concurrently: a cancel is answered while a prompt runs
+	c.send(t, 6, MethodPrompt, PromptParams{SessionID: "s1", Prompt: "wait"})
+	<-engine.started
+	c.send(t, 7, MethodCancel, CancelParams{SessionID: "s1"})
+	response = c.receive(t)
+	assert.Equal(t, int64(7), response.ID)
+	assert.Equal(t, "s1", <-engine.canceled)
+
+	// Hanging up cancels the running prompt
+	conn.Close()
+	cancel()
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server did not stop")
+	}
+	_, err = net.Dial("unix", path)
+	assert.Error(t, err, "the socket should be removed")
+}
+
+func TestFormatBuffer(t *testing.T) {
+	assert.Equal(t, "File open in the editor: main.go", FormatBuffer(Buffer{Path: "main.go"}))
+	assert.Equal(t, "Selection in main.go (lines 3-4):\n```go\nfunc main() {\n}\n```",
+		FormatBuffer(Buffer{Path: "main.go", Text: "func main() {\n}", StartLine: 3, EndLine: 4, Language: "go"}))
+	assert.Equal(t, "Buffer of README.md as shown in the editor:\n````\n```sh\nmake\n```\n````",
+		FormatBuffer(Buffer{Path: "README.md", Text: "```sh\nmake\n```\n"}))
+}
@@ -0,0 +1,246 @@
+// Package errorpattern learns the tool and LLM errors that recur across
+// sessions, such as the same failing command or the same lint error, and what
+// fixed them, so a recurrence comes with the fix of the last time.
+package errorpattern
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kirmad/superopencode/internal/db"
+)
+
+// Kinds of errors
+const (
+	KindTool = "tool"
+	KindLLM  = "llm"
+)
+
+// maxMessageLength is the length of the error messages kept
+const maxMessageLength = 300
+
+// Occurrence is an error hit in a session
+type Occurrence struct {
+	ID        string
+	SessionID string
+	Kind      string
+	Name      string // Tool or model
+	Key       string // What failed within the tool, e.g. the command of bash
+	Message   string // The line of the error that matters, see Salient
+}
+
+// Pattern is an error recurring across sessions
+type Pattern struct {
+	Signature   string
+	Kind        string
+	Name        string
+	Message     string
+	Occurrences int
+	Sessions    int
+	LastSeen    time.Time
+	Resolution  string // What fixed it the last time, empty if it never was
+}
+
+// Suggestion tells how often an error was hit and what fixed it last time
+func (p Pattern) Suggestion() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "This error was hit %d times", p.Occurrences)
+	if p.Sessions > 1 {
+		fmt.Fprintf(&sb, " across %d sessions", p.Sessions)
+	}
+	if p.Resolution == "" {
+		sb.WriteString(" and was never fixed in a way OpenCode noticed.")
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, ". Here's what fixed it last time: %s.", p.Resolution)
+	return sb.String()
+}
+
+type Service interface {
+	// Record stores an occurrence and returns its pattern, with the
+	// occurrence counted. The occurrence is given an ID unless it has one.
+	Record(ctx context.Context, occurrence Occurrence) (Pattern, error)
+	// Resolve stores what fixed an occurrence
+	Resolve(ctx context.Context, id, resolution string) error
+	// List returns the patterns hit at least minOccurrences times, the most
+	// frequent first
+	List(ctx context.Context, minOccurrences, limit int) ([]Pattern, error)
+}
+
+type service struct {
+	q db.Querier
+}
+
+func NewService(q db.Querier) Service {
+	return &service{q: q}
+}
+
+func (s *service) Record(ctx context.Context, occurrence Occurrence) (Pattern, error) {
+	if occurrence.ID == "" {
+		occurrence.ID = uuid.New().String()
+	}
+	message := truncate(occurrence.Message, maxMessageLength)
+	signature := Signature(occurrence.Kind, occurrence.Name, occurrence.Key, occurrence.Message)
+	err := s.q.CreateErrorOccurrence(ctx, db.CreateErrorOccurrenceParams{
+		ID:        occurrence.ID,
+		Signature: signature,
+		Kind:      occurrence.Kind,
+		Name:      occurrence.Name,
+		Message:   message,
+		SessionID: occurrence.SessionID,
+	})
+	if err != nil {
+		return Pattern{}, err
+	}
+
+	counts, err := s.q.GetErrorPattern(ctx, signature)
+	if err != nil {
+		return Pattern{}, err
+	}
+	pattern := Pattern{
+		Signature:   signature,
+		Kind:        occurrence.Kind,
+		Name:        occurrence.Name,
+		Message:     message,
+		Occurrences: int(counts.Occurrences),
+		Sessions:    int(counts.Sessions),
+		LastSeen:    time.Now(),
+	}
+	last, err := s.q.GetLastErrorResolution(ctx, signature)
+	switch {
+	case err == nil:
+		pattern.Resolution = last.Resolution
+	case !errors.Is(err, sql.ErrNoRows):
+		return Pattern{}, err
+	}
+	return pattern, nil
+}
+
+func (s *service) Resolve(ctx context.Context, id, resolution string) error {
+	return s.q.ResolveErrorOccurrence(ctx, db.ResolveErrorOccurrenceParams{
+		ID:         id,
+		Resolution: resolution,
+	})
+}
+
+func (s *service) List(ctx context.Context, minOccurrences, limit int) ([]Pattern, error) {
+	rows, err := s.q.ListErrorPatterns(ctx, db.ListErrorPatternsParams{
+		Occurrences: int64(minOccurrences),
+		Limit:       int64(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	patterns := make([]Pattern, len(rows))
+	for i, row := range rows {
+		patterns[i] = Pattern{
+			Signature:   row.Signature,
+			Kind:        row.Kind,
+			Name:        row.Name,
+			Message:     row.Message,
+			Occurrences: int(row.Occurrences),
+			Sessions:    int(row.Sessions),
+			LastSeen:    time.Unix(row.LastSeen, 0),
+		}
+		last, err := s.q.GetLastErrorResolution(ctx, row.Signature)
+		if err == nil {
+			patterns[i].Resolution = last.Resolution
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+	return patterns, nil
+}
+
+// Signature identifies an error across sessions: the same tool failing on the
+// same key with the same normalized message
+func Signature(kind, name, key, message string) string {
+	sum := sha256.Sum256([]byte(kind + "\x00" + name + "\x00" + Normalize(key) + "\x00" + Normalize(message)))
+	return hex.EncodeToString(sum[:16])
+}
+
+var (
+	uuidPattern   = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	hexPattern    = regexp.MustCompile(`\b0x[0-9a-fA-F]+\b|\b[0-9a-f]{7,}\b`)
+	pathPattern   = regexp.MustCompile(`(?:[A-Za-z]:)?(?:\.{0,2}[/\\][\w.@+-]+)+[/\\]?`)
+	numberPattern = regexp.MustCompile(`\d+(?:\.\d+)*`)
+	spacePattern  = regexp.MustCompile(`\s+`)
+)
+
+// Normalize removes what changes between the recurrences of an error, like
+// paths, line numbers, IDs and durations, so they compare equal
+func Normalize(message string) string {
+	message = uuidPattern.ReplaceAllString(message, "<id>")
+	message = hexPattern.ReplaceAllString(message, "<hex>")
+	message = pathPattern.ReplaceAllStringFunc(message, func(path string) string {
+		// The file name tells errors apart, its directory doesn't
+		name := path[strings.LastIndexAny(path, `/\`)+1:]
+		if name == "" {
+			return "<dir>"
+		}
+		return "<path>/" + name
+	})
+	message = numberPattern.ReplaceAllString(message, "<n>")
+	message = spacePattern.ReplaceAllString(message, " ")
+	return strings.ToLower(strings.TrimSpace(message))
+}
+
+// salientWords mark the lines of an output that say what went wrong
+var salientWords = regexp.MustCompile(`(?i)\b(error|fail(ed|ure)?|fatal|panic|cannot|can't|could not|couldn't|undefined|not found|no such|denied|invalid|unexpected|missing|refused|timed? ?out|exception)\b`)
+
+// Salient returns the line of an error output that says what went wrong: the
+// first line with an error word, or the last line
+func Salient(output string) string {
+	var last string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if salientWords.MatchString(line) {
+			return truncate(line, maxMessageLength)
+		}
+		last = line
+	}
+	return truncate(last, maxMessageLength)
+}
+
+// CommandKey returns what identifies a shell command across runs: its
+// program and subcommand, e.g. "go test" or "npm run lint"
+func CommandKey(command string) string {
+	fields := strings.Fields(command)
+	// Variables set for the command aren't part of it
+	for len(fields) > 1 && strings.Contains(fields[0], "=") {
+		fields = fields[1:]
+	}
+	var key []string
+	for _, field := range fields {
+		if strings.HasPrefix(field, "-") || strings.ContainsAny(field, `/\.=$"'&|;<>`) || len(key) == 3 {
+			break
+		}
+		key = append(key, field)
+	}
+	if len(key) == 0 && len(fields) > 0 {
+		key = fields[:1]
+	}
+	return strings.Join(key, " ")
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
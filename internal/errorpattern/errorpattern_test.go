@@ -0,0 +1,138 @@
+package errorpattern
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memQuerier keeps error occurrences in memory
+type memQuerier struct {
+	db.Querier
+	occurrences []db.ErrorOccurrence
+	resolved    int64
+}
+
+func (q *memQuerier) CreateErrorOccurrence(ctx context.Context, arg db.CreateErrorOccurrenceParams) error {
+	q.occurrences = append(q.occurrences, db.ErrorOccurrence{
+		ID:        arg.ID,
+		Signature: arg.Signature,
+		Kind:      arg.Kind,
+		Name:      arg.Name,
+		Message:   arg.Message,
+		SessionID: arg.SessionID,
+	})
+	return nil
+}
+
+func (q *memQuerier) ResolveErrorOccurrence(ctx context.Context, arg db.ResolveErrorOccurrenceParams) error {
+	for i := range q.occurrences {
+		if q.occurrences[i].ID == arg.ID {
+			q.resolved++
+			q.occurrences[i].Resolution = arg.Resolution
+			q.occurrences[i].ResolvedAt = q.resolved
+		}
+	}
+	return nil
+}
+
+func (q *memQuerier) GetErrorPattern(ctx context.Context, signature string) (db.GetErrorPatternRow, error) {
+	var row db.GetErrorPatternRow
+	sessions := make(map[string]bool)
+	for _, occurrence := range q.occurrences {
+		if occurrence.Signature == signature {
+			row.Occurrences++
+			sessions[occurrence.SessionID] = true
+		}
+	}
+	row.Sessions = int64(len(sessions))
+	return row, nil
+}
+
+func (q *memQuerier) GetLastErrorResolution(ctx context.Context, signature string) (db.ErrorOccurrence, error) {
+	var last db.ErrorOccurrence
+	for _, occurrence := range q.occurrences {
+		if occurrence.Signature == signature && occurrence.Resolution != "" && occurrence.ResolvedAt > last.ResolvedAt {
+			last = occurrence
+		}
+	}
+	if last.ID == "" {
+		return last, sql.ErrNoRows
+	}
+	return last, nil
+}
+
+func TestRecord(t *testing.T) {
+	q := &memQuerier{}
+	service := NewService(q)
+	ctx := context.Background()
+
+	record := func(id, sessionID, message string) Pattern {
+		pattern, err := service.Record(ctx, Occurrence{
+			ID:        id,
+			SessionID: sessionID,
+			Kind:      KindTool,
+			Name:      "bash",
+			Key:       "go test",
+			Message:   message,
+		})
+		require.NoError(t, err)
+		return pattern
+	}
+
+	pattern := record("1", "a", "/home/me/project/main.go:12:3: undefined: foo")
+	assert.Equal(t, 1, pattern.Occurrences)
+	assert.Empty(t, pattern.Resolution)
+
+	require.NoError(t, service.Resolve(ctx, "1", "edited main.go, after which `go test` worked"))
+	pattern = record("2", "b", "/tmp/checkout/main.go:40:9: undefined: foo")
+	assert.Equal(t, 2, pattern.Occurrences, "paths and positions don't tell errors apart")
+	assert.Equal(t, 2, pattern.Sessions)
+	assert.Equal(t, "edited main.go, after which `go test` worked", pattern.Resolution)
+
+	pattern = record("3", "b", "/tmp/checkout/main.go:40:9: undefined: bar")
+	assert.Equal(t, 1, pattern.Occurrences)
+
+	_, err := service.Record(ctx, Occurrence{SessionID: "c", Kind: KindTool, Name: "bash", Message: "failed"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, q.occurrences[len(q.occurrences)-1].ID)
+}
+
+func TestSuggestion(t *testing.T) {
+	assert.Equal(t, "This error was hit 3 times and was never fixed in a way OpenCode noticed.",
+		Pattern{Occurrences: 3, Sessions: 1}.Suggestion())
+	assert.Equal(t, "This error was hit 5 times across 2 sessions. Here's what fixed it last time: edited go.mod.",
+		Pattern{Occurrences: 5, Sessions: 2, Resolution: "edited go.mod"}.Suggestion())
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "<path>/main.go:<n>:<n>: undefined: foo", Normalize("/home/me/main.go:12:3:  undefined: foo"))
+	assert.Equal(t, "session <id> not found", Normalize("Session 0b6e6f43-5c3e-4b7a-9d55-6f4fd0c2b1e1 not found"))
+	assert.Equal(t, "commit <hex> is missing", Normalize("commit 3fa9c1e2b is missing"))
+	assert.Equal(t, "timed out after <n>s", Normalize("timed out after 2.5s"))
+}
+
+func TestSalient(t *testing.T) {
+	assert.Equal(t, "main.go:3: undefined: foo", Salient("# example\n  main.go:3: undefined: foo\nFAIL\n"))
+	assert.Equal(t, "last line", Salient("first line\n\nlast line\n"))
+	assert.Empty(t, Salient(""))
+}
+
+func TestCommandKey(t *testing.T) {
+	for command, key := range map[string]string{
+		"go test ./...":                 "go test",
+		"npm run lint -- --fix":         "npm run lint",
+		"git commit -m 'message'":       "git commit",
+		"make && make install":          "make",
+		"cat main.go | grep func":       "cat",
+		"./scripts/build.sh":            "./scripts/build.sh",
+		"docker compose up web --build": "docker compose up",
+		"CGO_ENABLED=0 go build":        "go build",
+	} {
+		assert.Equal(t, key, CommandKey(command), command)
+	}
+}
@@ -0,0 +1,41 @@
+package fileutil
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// ListenUnix listens on a unix socket at path that only the current user can
+// connect to. The socket is bound in a private directory and restricted
+// before it's moved to path, so no other user can reach it in between. The
+// caller removes path once it stops listening.
+func ListenUnix(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	private, err := os.MkdirTemp(dir, ".socket-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the socket directory: %w", err)
+	}
+	defer os.RemoveAll(private)
+
+	bound := filepath.Join(private, filepath.Base(path))
+	listener, err := net.Listen("unix", bound)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	// The listener would remove the bound path, which is gone once moved
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(bound, 0o600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict the socket: %w", err)
+	}
+	if err := os.Rename(bound, path); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to move the socket to %s: %w", path, err)
+	}
+	return listener, nil
+}
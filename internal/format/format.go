@@ -15,6 +15,10 @@ const (
 
 	// JSON format outputs the AI response wrapped in a JSON object.
 	JSON OutputFormat = "json"
+
+	// Diff format outputs the changes proposed by the agent as a unified
+	// diff, without applying them.
+	Diff OutputFormat = "diff"
 )
 
 // String returns the string representation of the OutputFormat
@@ -26,6 +30,7 @@ func (f OutputFormat) String() string {
 var SupportedFormats = []string{
 	string(Text),
 	string(JSON),
+	string(Diff),
 }
 
 // Parse converts a string to an OutputFormat
@@ -37,6 +42,8 @@ func Parse(s string) (OutputFormat, error) {
 		return Text, nil
 	case string(JSON):
 		return JSON, nil
+	case string(Diff):
+		return Diff, nil
 	default:
 		return "", fmt.Errorf("invalid format: %s", s)
 	}
@@ -52,8 +59,9 @@ func IsValid(s string) bool {
 func GetHelpText() string {
 	return fmt.Sprintf(`Supported output formats:
 - %s: Plain text output (default)
-- %s: Output wrapped in a JSON object`,
-		Text, JSON)
+- %s: Output wrapped in a JSON object
+- %s: Changes proposed by the agent as a unified diff, not applied`,
+		Text, JSON, Diff)
 }
 
 // FormatOutput formats the AI response according to the specified format
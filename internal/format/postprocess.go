@@ -0,0 +1,141 @@
+package format
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+// Result is the result of a non-interactive run as the post-processors see
+// it. Output starts as the formatted response and each processor replaces it
+// with what it printed.
+type Result struct {
+	Response         string  `json:"response"`
+	Output           string  `json:"output"`
+	SessionID        string  `json:"session_id"`
+	Model            string  `json:"model"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	DurationMs       int64   `json:"duration_ms"`
+	Diff             string  `json:"diff,omitempty"` // Changes proposed by a run with the diff format
+}
+
+// Processors looks up the post-processors with these names in the config
+func Processors(names []string) ([]config.OutputProcessor, error) {
+	defined := config.Get().Output.Processors
+	processors := make([]config.OutputProcessor, 0, len(names))
+	for _, name := range names {
+		processor, ok := defined[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown output processor %q, define it in output.processors", name)
+		}
+		processors = append(processors, processor)
+	}
+	return processors, nil
+}
+
+// PostProcess runs the processors in order on the result and returns the
+// final output
+func PostProcess(ctx context.Context, result Result, processors []config.OutputProcessor) (string, error) {
+	for i, processor := range processors {
+		var (
+			output string
+			err    error
+		)
+		switch {
+		case processor.JQ != "":
+			output, err = runJQ(ctx, processor.JQ, result)
+		case processor.Template != "":
+			output, err = runTemplate(processor.Template, result)
+		case processor.Command != "":
+			output, err = runCommand(ctx, processor.Command, result)
+		}
+		if err != nil {
+			return "", fmt.Errorf("output processor %d: %w", i+1, err)
+		}
+		result.Output = output
+	}
+	return result.Output, nil
+}
+
+// Render formats the response of a result and runs the post-processors on it.
+// The output of the diff format is the diff of the result.
+func Render(ctx context.Context, result Result, formatStr string, processors []config.OutputProcessor) (string, error) {
+	if f, _ := Parse(formatStr); f == Diff {
+		result.Output = result.Diff
+	} else {
+		result.Output = FormatOutput(result.Response, formatStr)
+	}
+	return PostProcess(ctx, result, processors)
+}
+
+// runJQ runs a jq filter on the result as JSON
+func runJQ(ctx context.Context, filter string, result Result) (string, error) {
+	input, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	if _, err := exec.LookPath("jq"); err != nil {
+		return "", fmt.Errorf("jq is not installed")
+	}
+	cmd := exec.CommandContext(ctx, "jq", "-r", filter)
+	cmd.Stdin = bytes.NewReader(input)
+	return run(cmd)
+}
+
+// runTemplate executes a Go template with the result
+func runTemplate(text string, result Result) (string, error) {
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, result); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// runCommand runs a shell command with the output on stdin and the other
+// fields of the result in OPENCODE_* environment variables
+func runCommand(ctx context.Context, command string, result Result) (string, error) {
+	shell := config.Get().Shell.Path
+	if shell == "" {
+		shell = "/bin/bash"
+	}
+	cmd := exec.CommandContext(ctx, shell, "-c", command)
+	cmd.Dir = config.WorkingDirectory()
+	cmd.Stdin = strings.NewReader(result.Output)
+	cmd.Env = append(os.Environ(),
+		"OPENCODE_SESSION_ID="+result.SessionID,
+		"OPENCODE_MODEL="+result.Model,
+		fmt.Sprintf("OPENCODE_PROMPT_TOKENS=%d", result.PromptTokens),
+		fmt.Sprintf("OPENCODE_COMPLETION_TOKENS=%d", result.CompletionTokens),
+		fmt.Sprintf("OPENCODE_COST=%f", result.Cost),
+		fmt.Sprintf("OPENCODE_DURATION_MS=%d", result.DurationMs),
+	)
+	return run(cmd)
+}
+
+// run runs a processor command and returns its output without the trailing
+// newline, which is printed again after the last processor
+func run(cmd *exec.Cmd) (string, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
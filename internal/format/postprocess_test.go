@@ -0,0 +1,56 @@
+package format
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostProcess(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	config.Get().Output.Processors = map[string]config.OutputProcessor{
+		"summary": {Template: "{{.Output}} (${{printf \"%.2f\" .Cost}}, {{.PromptTokens}} tokens)"},
+		"upper":   {Command: "tr a-z A-Z; printf ' %s' \"$OPENCODE_SESSION_ID\""},
+	}
+	ctx := context.Background()
+	result := Result{Response: "done", SessionID: "s1", PromptTokens: 1200, Cost: 0.0421}
+
+	processors, err := Processors([]string{"summary"})
+	require.NoError(t, err)
+	output, err := Render(ctx, result, "text", processors)
+	require.NoError(t, err)
+	assert.Equal(t, "done ($0.04, 1200 tokens)", output)
+
+	if _, err := exec.LookPath("tr"); err == nil {
+		processors, err = Processors([]string{"summary", "upper"})
+		require.NoError(t, err)
+		output, err = Render(ctx, result, "text", processors)
+		require.NoError(t, err)
+		assert.Equal(t, "DONE ($0.04, 1200 TOKENS) s1", output)
+	}
+
+	if _, err := exec.LookPath("jq"); err == nil {
+		output, err = Render(ctx, result, "json", []config.OutputProcessor{{JQ: ".output | fromjson | .response"}})
+		require.NoError(t, err)
+		assert.Equal(t, "done", output)
+	}
+
+	output, err = Render(ctx, result, "text", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "done", output)
+
+	result.Diff = "diff --git a/main.go b/main.go\n"
+	output, err = Render(ctx, result, "diff", nil)
+	require.NoError(t, err)
+	assert.Equal(t, result.Diff, output)
+
+	_, err = Processors([]string{"missing"})
+	assert.Error(t, err)
+	_, err = PostProcess(ctx, result, []config.OutputProcessor{{Command: "exit 3"}})
+	assert.Error(t, err)
+}
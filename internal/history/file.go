@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -38,19 +39,39 @@ type Service interface {
 	Update(ctx context.Context, file File) (File, error)
 	Delete(ctx context.Context, id string) error
 	DeleteSessionFiles(ctx context.Context, sessionID string) error
+	// Snapshot saves the pre-image of a file before a tool changes it, the
+	// snapshot is released once the change is recorded
+	Snapshot(ctx context.Context, sessionID, path string) (Snapshot, error)
+	Release(snapshot Snapshot)
+	Recover(ctx context.Context) (int, error)
+	// Existed reports whether a file existed on disk before a session first
+	// changed it, known for the files first changed since the service started
+	Existed(sessionID, path string) (existed bool, known bool)
 }
 
 type service struct {
 	*pubsub.Broker[File]
-	db *sql.DB
-	q  *db.Queries
+	db      *sql.DB
+	q       *db.Queries
+	journal *Journal // nil when changes aren't snapshotted
+
+	mu      sync.Mutex
+	existed map[fileKey]bool
+}
+
+// fileKey identifies a file changed by a session
+type fileKey struct {
+	sessionID string
+	path      string
 }
 
-func NewService(q *db.Queries, db *sql.DB) Service {
+func NewService(q *db.Queries, db *sql.DB, journal *Journal) Service {
 	return &service{
-		Broker: pubsub.NewBroker[File](),
-		q:      q,
-		db:     db,
+		Broker:  pubsub.NewBroker[File](),
+		q:       q,
+		db:      db,
+		journal: journal,
+		existed: make(map[fileKey]bool),
 	}
 }
 
@@ -236,6 +257,13 @@ func (s *service) DeleteSessionFiles(ctx context.Context, sessionID string) erro
 			return err
 		}
 	}
+	s.mu.Lock()
+	for key := range s.existed {
+		if key.sessionID == sessionID {
+			delete(s.existed, key)
+		}
+	}
+	s.mu.Unlock()
 	return nil
 }
 
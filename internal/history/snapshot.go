@@ -0,0 +1,328 @@
+package history
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kirmad/superopencode/internal/logging"
+)
+
+// snapshotInterval spaces the snapshots written to the journal, so a burst of
+// writes from parallel tasks doesn't flood the disk with syncs
+const snapshotInterval = 10 * time.Millisecond
+
+// Snapshot is the pre-image of a file, saved to the journal before a tool
+// changes the file and released once the change is recorded in the history.
+// A zero Snapshot stands for a pre-image the history already holds.
+type Snapshot struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id"`
+	Path      string `json:"path"`
+	Existed   bool   `json:"existed"`
+	Content   []byte `json:"content"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// Journal keeps the pre-images of the files being changed in a directory, in
+// one file per change. The pre-images left by a crash are recorded in the
+// history on the next start.
+type Journal struct {
+	dir string
+
+	mu   sync.Mutex
+	next time.Time // Earliest time of the next snapshot
+}
+
+func NewJournal(dir string) *Journal {
+	return &Journal{dir: dir}
+}
+
+// Save writes the pre-image of a file to the journal. The pre-image is synced
+// to disk before Save returns, so the change of the file can follow.
+func (j *Journal) Save(sessionID, path string) (Snapshot, error) {
+	snapshot := Snapshot{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+		Path:      path,
+		CreatedAt: time.Now().Unix(),
+	}
+	content, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		snapshot.Existed = true
+		snapshot.Content = content
+	case !errors.Is(err, fs.ErrNotExist):
+		return Snapshot{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	j.wait()
+	if err := os.MkdirAll(j.dir, 0o700); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	if err := writeSynced(filepath.Join(j.dir, snapshot.ID+".json"), data, 0o600); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to save snapshot of %s: %w", path, err)
+	}
+	return snapshot, nil
+}
+
+// wait blocks until the next snapshot may be written
+func (j *Journal) wait() {
+	j.mu.Lock()
+	now := time.Now()
+	at := j.next
+	if at.Before(now) {
+		at = now
+	}
+	j.next = at.Add(snapshotInterval)
+	j.mu.Unlock()
+	time.Sleep(time.Until(at))
+}
+
+// Release removes a pre-image from the journal
+func (j *Journal) Release(snapshot Snapshot) error {
+	if snapshot.ID == "" {
+		return nil
+	}
+	err := os.Remove(filepath.Join(j.dir, snapshot.ID+".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Pending returns the pre-images in the journal, the oldest first. Files a
+// crash left half written are removed.
+func (j *Journal) Pending() ([]Snapshot, error) {
+	entries, err := os.ReadDir(j.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		path := filepath.Join(j.dir, entry.Name())
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			// The change never started, its pre-image wasn't complete
+			os.Remove(path)
+			continue
+		}
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var snapshot Snapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil || snapshot.ID == "" {
+			logging.Warn("Removing an unreadable snapshot", "path", path, "error", err)
+			os.Remove(path)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	slices.SortStableFunc(snapshots, func(a, b Snapshot) int {
+		return cmp.Compare(a.CreatedAt, b.CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// WriteFile replaces the content of a file so a crash leaves either the old
+// or the new content: the content is written and synced to a file next to
+// it, which is renamed over the file. The mode of an existing file is kept
+// and symbolic links are written through.
+func WriteFile(path string, data []byte, perm fs.FileMode) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	return writeSynced(path, data, perm)
+}
+
+// writeSynced writes a file through a synced temporary file, and syncs the
+// directory so the rename is durable
+func writeSynced(path string, data []byte, perm fs.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir syncs a directory, making the files created or renamed in it
+// durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return err
+	}
+	return nil
+}
+
+// Snapshot saves the pre-image of a file to the journal before a tool changes
+// it. No snapshot is taken when the latest version of the file in the
+// history of the session is its content, the history holds the pre-image.
+func (s *service) Snapshot(ctx context.Context, sessionID, path string) (Snapshot, error) {
+	file, err := s.GetByPathAndSession(ctx, path, sessionID)
+	if err != nil {
+		// First change of the file by the session
+		s.rememberExisted(sessionID, path)
+	}
+	if s.journal == nil {
+		return Snapshot{}, nil
+	}
+	if err == nil {
+		content, err := os.ReadFile(path)
+		if (err == nil && string(content) == file.Content) || (errors.Is(err, fs.ErrNotExist) && file.Content == "") {
+			return Snapshot{}, nil
+		}
+	}
+	return s.journal.Save(sessionID, path)
+}
+
+// rememberExisted records whether a file exists before the session first
+// changes it, its empty content in the history doesn't tell
+func (s *service) rememberExisted(sessionID, path string) {
+	_, err := os.Lstat(path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := fileKey{sessionID: sessionID, path: path}
+	if _, ok := s.existed[key]; !ok {
+		s.existed[key] = err == nil
+	}
+}
+
+func (s *service) Existed(sessionID, path string) (bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existed, ok := s.existed[fileKey{sessionID: sessionID, path: path}]
+	return existed, ok
+}
+
+// Release drops the pre-image of a change recorded in the history
+func (s *service) Release(snapshot Snapshot) {
+	if s.journal == nil {
+		return
+	}
+	if err := s.journal.Release(snapshot); err != nil {
+		logging.Warn("Failed to release file snapshot", "path", snapshot.Path, "error", err)
+	}
+}
+
+// ReleaseRecorded drops the pre-image of a change once its versions are
+// saved in the history, err being the failure to save them. A pre-image the
+// history misses stays in the journal, Recover records the change on the
+// next start.
+func ReleaseRecorded(files Service, snapshot Snapshot, err error) {
+	if err != nil {
+		logging.Warn("Failed to save the file history, keeping the snapshot for recovery", "path", snapshot.Path, "error", err)
+		return
+	}
+	files.Release(snapshot)
+}
+
+// Recover records the changes a crash interrupted in the history, from the
+// pre-images left in the journal, and returns their number. The pre-image
+// becomes a version of the file followed by its content on disk, so the
+// change can be undone.
+func (s *service) Recover(ctx context.Context) (int, error) {
+	if s.journal == nil {
+		return 0, nil
+	}
+	snapshots, err := s.journal.Pending()
+	if err != nil {
+		return 0, err
+	}
+	recovered := 0
+	for _, snapshot := range snapshots {
+		if err := s.recoverSnapshot(ctx, snapshot); err != nil {
+			// Most likely the session was deleted, the pre-image has nowhere
+			// to go
+			logging.Warn("Dropping a file snapshot that can't be recovered", "path", snapshot.Path, "session", snapshot.SessionID, "error", err)
+		} else {
+			recovered++
+		}
+		if err := s.journal.Release(snapshot); err != nil {
+			logging.Warn("Failed to release file snapshot", "path", snapshot.Path, "error", err)
+		}
+	}
+	return recovered, nil
+}
+
+func (s *service) recoverSnapshot(ctx context.Context, snapshot Snapshot) error {
+	before := string(snapshot.Content)
+	after := ""
+	if content, err := os.ReadFile(snapshot.Path); err == nil {
+		after = string(content)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	latest := ""
+	file, err := s.GetByPathAndSession(ctx, snapshot.Path, snapshot.SessionID)
+	if err != nil {
+		if _, err := s.Create(ctx, snapshot.SessionID, snapshot.Path, before); err != nil {
+			return err
+		}
+		latest = before
+	} else {
+		latest = file.Content
+	}
+	if latest == after {
+		// The change was recorded before the crash, or never happened
+		return nil
+	}
+	if latest != before {
+		if _, err := s.CreateVersion(ctx, snapshot.SessionID, snapshot.Path, before); err != nil {
+			return err
+		}
+	}
+	if before == after {
+		return nil
+	}
+	_, err = s.CreateVersion(ctx, snapshot.SessionID, snapshot.Path, after)
+	return err
+}
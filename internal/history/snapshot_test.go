@@ -0,0 +1,84 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	dir := t.TempDir()
+	journal := NewJournal(filepath.Join(dir, "snapshots"))
+	path := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+
+	existing, err := journal.Save("session", path)
+	require.NoError(t, err)
+	assert.True(t, existing.Existed)
+	assert.Equal(t, "package main\n", string(existing.Content))
+	created, err := journal.Save("session", filepath.Join(dir, "new.go"))
+	require.NoError(t, err)
+	assert.False(t, created.Existed)
+
+	// A crash left a half written snapshot
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "snapshots", ".x.json.123.tmp"), []byte("{"), 0o600))
+
+	pending, err := journal.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.ElementsMatch(t, []string{existing.ID, created.ID}, []string{pending[0].ID, pending[1].ID})
+	_, err = os.Stat(filepath.Join(dir, "snapshots", ".x.json.123.tmp"))
+	assert.True(t, os.IsNotExist(err), "the half written snapshot is removed")
+
+	require.NoError(t, journal.Release(existing))
+	require.NoError(t, journal.Release(existing), "released twice")
+	require.NoError(t, journal.Release(Snapshot{}))
+	pending, err = journal.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, created.ID, pending[0].ID)
+}
+
+func TestReleaseRecorded(t *testing.T) {
+	dir := t.TempDir()
+	journal := NewJournal(filepath.Join(dir, "snapshots"))
+	files := &service{journal: journal}
+	recorded, err := journal.Save("session", filepath.Join(dir, "recorded.go"))
+	require.NoError(t, err)
+	failed, err := journal.Save("session", filepath.Join(dir, "failed.go"))
+	require.NoError(t, err)
+
+	ReleaseRecorded(files, recorded, nil)
+	ReleaseRecorded(files, failed, errors.New("database is locked"))
+	pending, err := journal.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, failed.ID, pending[0].ID, "kept for recovery")
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run.sh")
+	require.NoError(t, os.WriteFile(path, []byte("echo old\n"), 0o755))
+	link := filepath.Join(dir, "link.sh")
+	require.NoError(t, os.Symlink(path, link))
+
+	require.NoError(t, WriteFile(link, []byte("echo new\n"), 0o644))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "echo new\n", string(content))
+	info, err := os.Lstat(link)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink, "the link is kept")
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm(), "the mode is kept")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "no temporary file is left")
+}
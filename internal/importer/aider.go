@@ -0,0 +1,89 @@
+package importer
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// aiderSessionStart starts each run of aider in the chat history
+	aiderSessionStart = "# aider chat started at "
+	aiderTimeLayout   = "2006-01-02 15:04:05"
+	aiderPrompt       = "####"
+	aiderOutput       = ">"
+)
+
+var (
+	aiderAddedFile   = regexp.MustCompile(`^Added (.+?) to the chat`)
+	aiderAppliedEdit = regexp.MustCompile(`^Applied edit to (.+)$`)
+)
+
+// parseAider reads an aider chat history. Each run of aider is a
+// conversation: the prompts are the lines starting with ####, the output of
+// aider itself is quoted with > and the rest is the answer of the model.
+func parseAider(data []byte) []Conversation {
+	var conversations []Conversation
+	var conversation *Conversation // Run being read
+	var prompt, answer []string
+	var pendingFiles []string // Files added before the next prompt
+
+	flush := func() {
+		if conversation == nil {
+			return
+		}
+		if len(prompt) > 0 {
+			text := strings.Join(prompt, "\n")
+			// aider commands like /add or /run aren't prompts
+			if !strings.HasPrefix(strings.TrimSpace(text), "/") {
+				conversation.add(RoleUser, text, pendingFiles...)
+				pendingFiles = nil
+			}
+		}
+		if len(answer) > 0 {
+			conversation.add(RoleAssistant, strings.Join(answer, "\n"))
+		}
+		prompt, answer = nil, nil
+	}
+	finish := func() {
+		flush()
+		if conversation != nil {
+			conversations = append(conversations, *conversation)
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, aiderSessionStart):
+			finish()
+			conversation = &Conversation{}
+			pendingFiles = nil
+			started, err := time.ParseInLocation(aiderTimeLayout, strings.TrimSpace(strings.TrimPrefix(line, aiderSessionStart)), time.Local)
+			if err == nil {
+				conversation.Started = started
+			}
+		case conversation == nil:
+			// Text before the first run isn't part of any conversation
+		case strings.HasPrefix(line, aiderPrompt):
+			if len(answer) > 0 {
+				flush()
+			}
+			prompt = append(prompt, strings.TrimPrefix(strings.TrimPrefix(line, aiderPrompt), " "))
+		case strings.HasPrefix(line, aiderOutput):
+			output := strings.TrimSpace(strings.TrimPrefix(line, aiderOutput))
+			if match := aiderAddedFile.FindStringSubmatch(output); match != nil {
+				pendingFiles = append(pendingFiles, match[1])
+			} else if match := aiderAppliedEdit.FindStringSubmatch(output); match != nil {
+				flush()
+				conversation.add(RoleAssistant, "", match[1])
+			}
+		default:
+			if len(prompt) == 0 && len(answer) == 0 && strings.TrimSpace(line) == "" {
+				continue
+			}
+			answer = append(answer, line)
+		}
+	}
+	finish()
+	return conversations
+}
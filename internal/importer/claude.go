@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// claudeEntry is a line of a Claude Code session transcript
+type claudeEntry struct {
+	Type        string          `json:"type"` // user, assistant or summary
+	Summary     string          `json:"summary"`
+	IsMeta      bool            `json:"isMeta"`
+	IsSidechain bool            `json:"isSidechain"` // Conversations of subagents
+	Timestamp   time.Time       `json:"timestamp"`
+	Message     json.RawMessage `json:"message"`
+}
+
+type claudeMessage struct {
+	Content json.RawMessage `json:"content"` // A string or content blocks
+}
+
+type claudeBlock struct {
+	Type  string         `json:"type"` // text, thinking, tool_use, tool_result, image
+	Text  string         `json:"text"`
+	Name  string         `json:"name"`
+	Input map[string]any `json:"input"`
+}
+
+// claudeToolArguments are the inputs that describe a tool call best, in order
+var claudeToolArguments = []string{"file_path", "notebook_path", "command", "pattern", "path", "url", "query", "description"}
+
+// claudeFileArguments are the inputs that name a file
+var claudeFileArguments = []string{"file_path", "notebook_path"}
+
+// parseClaudeCode reads a session transcript, a JSON object per line. Tool
+// calls are summed up in the text of the assistant, their results are left
+// out.
+func parseClaudeCode(data []byte) ([]Conversation, error) {
+	var conversation Conversation
+	parsed := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry claudeEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			// A transcript being written may end with a partial line
+			continue
+		}
+		parsed = true
+		if entry.Type == "summary" {
+			if conversation.Title == "" {
+				conversation.Title = entry.Summary
+			}
+			continue
+		}
+		if entry.IsMeta || entry.IsSidechain || (entry.Type != "user" && entry.Type != "assistant") {
+			continue
+		}
+		if conversation.Started.IsZero() {
+			conversation.Started = entry.Timestamp
+		}
+
+		var message claudeMessage
+		if err := json.Unmarshal(entry.Message, &message); err != nil {
+			continue
+		}
+		text, files := claudeContent(message.Content)
+		if entry.Type == "user" {
+			if isClaudeCommandOutput(text) {
+				continue
+			}
+			conversation.add(RoleUser, text, files...)
+		} else {
+			conversation.add(RoleAssistant, text, files...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the transcript: %w", err)
+	}
+	if !parsed {
+		return nil, fmt.Errorf("not a Claude Code session transcript")
+	}
+	return []Conversation{conversation}, nil
+}
+
+// claudeContent returns the text of the content of a message and the files
+// its tool calls refer to
+func claudeContent(content json.RawMessage) (string, []string) {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text, nil
+	}
+	var blocks []claudeBlock
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return "", nil
+	}
+
+	var parts, files []string
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			parts = append(parts, block.Text)
+		case "tool_use":
+			parts = append(parts, claudeToolSummary(block))
+			for _, argument := range claudeFileArguments {
+				if file, ok := block.Input[argument].(string); ok {
+					files = append(files, file)
+				}
+			}
+		}
+	}
+	return strings.Join(parts, "\n"), files
+}
+
+// claudeToolSummary describes a tool call in a line, e.g. [Bash: go test ./...]
+func claudeToolSummary(block claudeBlock) string {
+	for _, argument := range claudeToolArguments {
+		if value, ok := block.Input[argument].(string); ok && value != "" {
+			value = strings.Join(strings.Fields(value), " ")
+			if runes := []rune(value); len(runes) > maxTitleLength {
+				value = string(runes[:maxTitleLength]) + "..."
+			}
+			return fmt.Sprintf("[%s: %s]", block.Name, value)
+		}
+	}
+	return fmt.Sprintf("[%s]", block.Name)
+}
+
+// isClaudeCommandOutput tells whether a user message is the trace of a slash
+// command rather than a prompt
+func isClaudeCommandOutput(text string) bool {
+	text = strings.TrimSpace(text)
+	return strings.HasPrefix(text, "<command-") || strings.HasPrefix(text, "<local-command-")
+}
@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	cursorUser      = "**User**"
+	cursorAssistant = "**Cursor**"
+	cursorSeparator = "---"
+	cursorFence     = "```"
+)
+
+// cursorCodeReference is the fence of a code block quoting a file, e.g.
+// ```12:30:internal/app/app.go
+var cursorCodeReference = regexp.MustCompile("^```\\d+:\\d+:(.+)$")
+
+// parseCursor reads the markdown of a chat exported from Cursor: a title,
+// then the messages under **User** and **Cursor** separated by ---.
+func parseCursor(data []byte) []Conversation {
+	var conversation Conversation
+	var role Role
+	var text []string
+	var files []string
+	inFence := false
+
+	flush := func() {
+		if role != "" {
+			body := strings.TrimSpace(strings.Join(text, "\n"))
+			body = strings.TrimSpace(strings.TrimSuffix(body, cursorSeparator))
+			conversation.add(role, body, files...)
+		}
+		text, files = nil, nil
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, cursorFence) {
+			if match := cursorCodeReference.FindStringSubmatch(trimmed); match != nil && !inFence {
+				files = append(files, match[1])
+			}
+			inFence = !inFence
+		}
+		switch {
+		case inFence || strings.HasPrefix(trimmed, cursorFence):
+			text = append(text, line)
+		case trimmed == cursorUser:
+			flush()
+			role = RoleUser
+		case trimmed == cursorAssistant:
+			flush()
+			role = RoleAssistant
+		case role == "" && conversation.Title == "" && strings.HasPrefix(trimmed, "# "):
+			conversation.Title = strings.TrimSpace(strings.TrimPrefix(trimmed, "# "))
+		case role != "":
+			text = append(text, line)
+		}
+	}
+	flush()
+	return []Conversation{conversation}
+}
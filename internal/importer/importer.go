@@ -0,0 +1,152 @@
+// Package importer converts the conversations exported by other coding agents
+// into sessions, so their history can be continued in OpenCode.
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Format is the export format of an agent tool
+type Format string
+
+const (
+	FormatClaudeCode Format = "claude-code" // Session transcript, ~/.claude/projects/<project>/<session>.jsonl
+	FormatAider      Format = "aider"       // Chat history, .aider.chat.history.md
+	FormatCursor     Format = "cursor"      // Markdown of "Export Chat"
+)
+
+// Formats lists the supported formats
+var Formats = []Format{FormatClaudeCode, FormatAider, FormatCursor}
+
+// maxTitleLength is the length of a title taken from the first prompt
+const maxTitleLength = 80
+
+// Role is the author of a message
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+// Conversation is a conversation read from an export
+type Conversation struct {
+	Title    string
+	Started  time.Time // Zero when the export doesn't tell
+	Messages []Message
+}
+
+// Message is a message of a conversation. Tool calls are summed up in the
+// text, the tools of other agents don't exist here.
+type Message struct {
+	Role  Role
+	Text  string
+	Files []string // Files the message read, edited or mentioned
+}
+
+// Detect guesses the format of an export from its file name and content
+func Detect(path string, data []byte) (Format, error) {
+	switch {
+	case strings.EqualFold(filepath.Ext(path), ".jsonl"):
+		return FormatClaudeCode, nil
+	case bytes.Contains(data, []byte(aiderSessionStart)):
+		return FormatAider, nil
+	case bytes.Contains(data, []byte(cursorUser)) && bytes.Contains(data, []byte(cursorAssistant)):
+		return FormatCursor, nil
+	}
+	return "", fmt.Errorf("unknown export format of %s, pass one of %v", path, Formats)
+}
+
+// Parse reads the conversations of an export
+func Parse(format Format, data []byte) ([]Conversation, error) {
+	var conversations []Conversation
+	var err error
+	switch format {
+	case FormatClaudeCode:
+		conversations, err = parseClaudeCode(data)
+	case FormatAider:
+		conversations = parseAider(data)
+	case FormatCursor:
+		conversations = parseCursor(data)
+	default:
+		return nil, fmt.Errorf("unknown export format %q, pass one of %v", format, Formats)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Exports without any exchange have nothing to continue
+	kept := conversations[:0]
+	for _, conversation := range conversations {
+		if len(conversation.Messages) == 0 {
+			continue
+		}
+		if conversation.Title == "" {
+			conversation.Title = titleFrom(conversation.Messages)
+		}
+		kept = append(kept, conversation)
+	}
+	return kept, nil
+}
+
+// add appends text to the conversation, merging it into the last message when
+// it has the same author
+func (c *Conversation) add(role Role, text string, files ...string) {
+	text = strings.TrimSpace(text)
+	if text == "" && len(files) == 0 {
+		return
+	}
+	if n := len(c.Messages); n > 0 && c.Messages[n-1].Role == role {
+		last := &c.Messages[n-1]
+		if text != "" {
+			last.Text = strings.TrimSpace(last.Text + "\n\n" + text)
+		}
+		last.addFiles(files...)
+		return
+	}
+	message := Message{Role: role, Text: text}
+	message.addFiles(files...)
+	c.Messages = append(c.Messages, message)
+}
+
+func (m *Message) addFiles(files ...string) {
+	for _, file := range files {
+		file = strings.TrimSpace(file)
+		if file == "" || slices.Contains(m.Files, file) {
+			continue
+		}
+		m.Files = append(m.Files, file)
+	}
+}
+
+// Content returns the text of the message followed by the files it refers to
+func (m Message) Content() string {
+	if len(m.Files) == 0 {
+		return m.Text
+	}
+	files := "Files: " + strings.Join(m.Files, ", ")
+	if m.Text == "" {
+		return files
+	}
+	return m.Text + "\n\n" + files
+}
+
+// titleFrom uses the first prompt of the conversation as its title
+func titleFrom(messages []Message) string {
+	for _, message := range messages {
+		if message.Role != RoleUser || message.Text == "" {
+			continue
+		}
+		title := []rune(strings.Join(strings.Fields(message.Text), " "))
+		if len(title) > maxTitleLength {
+			return string(title[:maxTitleLength]) + "..."
+		}
+		return string(title)
+	}
+	return "Imported conversation"
+}
@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const claudeTranscript = `{"type":"summary","summary":"Fix the flaky cache test","leafUuid":"a"}
+{"type":"user","isMeta":true,"timestamp":"2026-10-01T10:00:00Z","message":{"role":"user","content":"Caveat: the messages below were generated by the user"}}
+{"type":"user","timestamp":"2026-10-01T10:00:01Z","message":{"role":"user","content":"The cache test fails sometimes, can you fix it?"}}
+{"type":"assistant","timestamp":"2026-10-01T10:00:05Z","message":{"role":"assistant","content":[{"type":"thinking","thinking":"..."},{"type":"text","text":"Let me look at the test."},{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"internal/cache/cache_test.go"}}]}}
+{"type":"user","timestamp":"2026-10-01T10:00:06Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"package cache"}]}}
+{"type":"assistant","timestamp":"2026-10-01T10:00:09Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"go test ./internal/cache"}}]}}
+{"type":"assistant","isSidechain":true,"message":{"role":"assistant","content":"subagent notes"}}
+{"type":"user","timestamp":"2026-10-01T10:01:00Z","message":{"role":"user","content":"<command-name>/clear</command-name>"}}
+{"type":"assistant","timestamp":"2026-10-01T10:01:05Z","message":{"role":"assistant","content":[{"type":"text","text":"The test depended on the clock, it is fixed."}]}}
+{"type":"user","message":`
+
+const aiderHistory = `
+# aider chat started at 2026-10-01 10:00:00
+
+> /usr/local/bin/aider --model sonnet
+> Aider v0.80.0
+> Added main.go to the chat.
+
+#### add a hello function
+#### that prints hello
+
+Here is the change:
+
+main.go
+` + "```go\nfunc hello() {}\n```" + `
+
+> Applied edit to main.go
+> Commit abc1234 feat: add hello
+
+#### /run go test
+
+# aider chat started at 2026-10-02 09:00:00
+
+> Aider v0.80.0
+`
+
+const cursorExport = `# Explain the daemon
+_Exported on 10/1/2026 at 10:00:00 GMT from Cursor (1.5.0)_
+
+---
+
+**User**
+
+How does the daemon work?
+
+---
+
+**Cursor**
+
+It listens on a socket:
+
+` + "```12:20:internal/daemon/daemon.go\nfunc Listen() {}\n```" + `
+
+Config files use separators:
+
+` + "```yaml\n---\n**User**\n```" + `
+
+---
+
+**User**
+
+Thanks
+`
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		path string
+		data string
+		want Format
+	}{
+		{"session.jsonl", claudeTranscript, FormatClaudeCode},
+		{".aider.chat.history.md", aiderHistory, FormatAider},
+		{"cursor_chat.md", cursorExport, FormatCursor},
+	}
+	for _, tt := range tests {
+		format, err := Detect(tt.path, []byte(tt.data))
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, format, tt.path)
+	}
+
+	_, err := Detect("notes.md", []byte("# Notes"))
+	assert.Error(t, err)
+}
+
+func TestParseClaudeCode(t *testing.T) {
+	conversations, err := Parse(FormatClaudeCode, []byte(claudeTranscript))
+	require.NoError(t, err)
+	require.Len(t, conversations, 1)
+
+	conversation := conversations[0]
+	assert.Equal(t, "Fix the flaky cache test", conversation.Title)
+	assert.Equal(t, 2026, conversation.Started.Year())
+	assert.Equal(t, []Message{
+		{Role: RoleUser, Text: "The cache test fails sometimes, can you fix it?"},
+		{
+			Role:  RoleAssistant,
+			Text:  "Let me look at the test.\n[Read: internal/cache/cache_test.go]\n\n[Bash: go test ./internal/cache]\n\nThe test depended on the clock, it is fixed.",
+			Files: []string{"internal/cache/cache_test.go"},
+		},
+	}, conversation.Messages)
+
+	_, err = Parse(FormatClaudeCode, []byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestParseAider(t *testing.T) {
+	conversations, err := Parse(FormatAider, []byte(aiderHistory))
+	require.NoError(t, err)
+	require.Len(t, conversations, 1, "runs without messages are skipped")
+
+	conversation := conversations[0]
+	assert.Equal(t, "add a hello function that prints hello", conversation.Title)
+	assert.Equal(t, 10, conversation.Started.Hour())
+	require.Len(t, conversation.Messages, 2)
+	assert.Equal(t, Message{Role: RoleUser, Text: "add a hello function\nthat prints hello", Files: []string{"main.go"}}, conversation.Messages[0])
+	assert.Equal(t, RoleAssistant, conversation.Messages[1].Role)
+	assert.Contains(t, conversation.Messages[1].Text, "func hello() {}")
+	assert.Equal(t, []string{"main.go"}, conversation.Messages[1].Files)
+	assert.Equal(t, conversation.Messages[1].Text+"\n\nFiles: main.go", conversation.Messages[1].Content())
+}
+
+func TestParseCursor(t *testing.T) {
+	conversations, err := Parse(FormatCursor, []byte(cursorExport))
+	require.NoError(t, err)
+	require.Len(t, conversations, 1)
+
+	conversation := conversations[0]
+	assert.Equal(t, "Explain the daemon", conversation.Title)
+	require.Len(t, conversation.Messages, 3)
+	assert.Equal(t, Message{Role: RoleUser, Text: "How does the daemon work?"}, conversation.Messages[0])
+	assert.Equal(t, RoleAssistant, conversation.Messages[1].Role)
+	assert.Equal(t, []string{"internal/daemon/daemon.go"}, conversation.Messages[1].Files)
+	assert.Contains(t, conversation.Messages[1].Text, "---\n**User**\n```", "code blocks are kept as they are")
+	assert.Equal(t, Message{Role: RoleUser, Text: "Thanks"}, conversation.Messages[2])
+}
@@ -0,0 +1,36 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/session"
+)
+
+// Save creates a session holding the messages of a conversation
+func Save(ctx context.Context, sessions session.Service, messages message.Service, conversation Conversation) (session.Session, error) {
+	sess, err := sessions.Create(ctx, conversation.Title)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to create the session: %w", err)
+	}
+	for _, msg := range conversation.Messages {
+		params := message.CreateMessageParams{
+			Role:  message.User,
+			Parts: []message.ContentPart{message.TextContent{Text: msg.Content()}},
+		}
+		if msg.Role == RoleAssistant {
+			// Only user messages get their finish part when created
+			params.Role = message.Assistant
+			params.Parts = append(params.Parts, message.Finish{
+				Reason: message.FinishReasonEndTurn,
+				Time:   time.Now().Unix(),
+			})
+		}
+		if _, err := messages.Create(ctx, sess.ID, params); err != nil {
+			return sess, fmt.Errorf("failed to create a message: %w", err)
+		}
+	}
+	return sess, nil
+}
@@ -0,0 +1,127 @@
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+// confluencePageSize is how many pages are read per request
+const confluencePageSize = 50
+
+// confluence reads the current pages of a Confluence space with its REST API
+type confluence struct {
+	baseURL  string
+	space    string
+	username string
+	token    string
+	client   *http.Client
+}
+
+func newConfluence(source config.KnowledgeSource, client *http.Client) *confluence {
+	token := source.Token
+	if token == "" {
+		token = os.Getenv("CONFLUENCE_TOKEN")
+	}
+	return &confluence{
+		baseURL:  strings.TrimSuffix(source.URL, "/"),
+		space:    source.Space,
+		username: source.Username,
+		token:    token,
+		client:   client,
+	}
+}
+
+type confluencePage struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Body  struct {
+		Storage struct {
+			Value string `json:"value"`
+		} `json:"storage"`
+	} `json:"body"`
+	Links struct {
+		WebUI string `json:"webui"`
+	} `json:"_links"`
+}
+
+func (c *confluence) Fetch(ctx context.Context) ([]Document, error) {
+	var docs []Document
+	for start := 0; ; start += confluencePageSize {
+		query := url.Values{
+			"spaceKey": {c.space},
+			"type":     {"page"},
+			"status":   {"current"},
+			"expand":   {"body.storage"},
+			"limit":    {fmt.Sprint(confluencePageSize)},
+			"start":    {fmt.Sprint(start)},
+		}
+		var response struct {
+			Results []confluencePage `json:"results"`
+			Links   struct {
+				Base string `json:"base"`
+				Next string `json:"next"`
+			} `json:"_links"`
+		}
+		if err := c.get(ctx, "/rest/api/content?"+query.Encode(), &response); err != nil {
+			return nil, err
+		}
+
+		// Cloud returns the base of the links of the pages, e.g. with /wiki
+		base := c.baseURL
+		if response.Links.Base != "" {
+			base = strings.TrimSuffix(response.Links.Base, "/")
+		}
+		for _, page := range response.Results {
+			text, err := htmlToMarkdown(page.Body.Storage.Value, base)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert page %q: %w", page.Title, err)
+			}
+			docs = append(docs, Document{
+				ID:    page.ID,
+				Title: page.Title,
+				URL:   base + page.Links.WebUI,
+				Text:  text,
+			})
+		}
+		if response.Links.Next == "" || len(response.Results) < confluencePageSize {
+			return docs, nil
+		}
+	}
+}
+
+func (c *confluence) get(ctx context.Context, path string, response any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case c.username != "":
+		// Confluence Cloud authenticates with the email and an API token
+		req.SetBasicAuth(c.username, c.token)
+	case c.token != "":
+		// Personal access token of Confluence Data Center
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("confluence: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("confluence: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("confluence: invalid response: %w", err)
+	}
+	return nil
+}
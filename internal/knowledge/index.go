@@ -0,0 +1,302 @@
+package knowledge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/embedding"
+	"github.com/kirmad/superopencode/internal/vectorstore"
+)
+
+// chunkSize is the size in bytes of the chunks of a page embedded apart,
+// about 500 tokens
+const chunkSize = 2000
+
+// Metadata keys of the chunks in the vector store
+const (
+	metaSource = "source"
+	metaDoc    = "doc"
+	metaTitle  = "title"
+	metaURL    = "url"
+	metaText   = "text"
+)
+
+// Index is the semantic index of the docs
+type Index struct {
+	store    vectorstore.Store
+	embedder embedding.Provider
+}
+
+// Result is a chunk of a page found by a search
+type Result struct {
+	Source string
+	Title  string
+	URL    string
+	Text   string
+	Score  float64
+}
+
+// SyncReport tells what a sync changed in the index of a source
+type SyncReport struct {
+	Pages     int // Pages of the source
+	Updated   int // Pages indexed again, new or changed
+	Removed   int // Pages gone from the source
+	Chunks    int // Chunks embedded
+	Duration  time.Duration
+	CreatedAt time.Time
+}
+
+// sourceState is what was indexed from a source, kept between syncs
+type sourceState struct {
+	LastSync time.Time            `json:"last_sync"`
+	Pages    map[string]pageState `json:"pages"` // Document ID -> state
+}
+
+type pageState struct {
+	Hash   string `json:"hash"`
+	Chunks int    `json:"chunks"`
+}
+
+var shared struct {
+	sync.Mutex
+	index *Index
+}
+
+// Shared returns the index of the process, opened with the embedding provider
+// and the vector store of the config
+func Shared() (*Index, error) {
+	shared.Lock()
+	defer shared.Unlock()
+	if shared.index != nil {
+		return shared.index, nil
+	}
+	embedder, err := embedding.New()
+	if err != nil {
+		return nil, err
+	}
+	store, err := vectorstore.New()
+	if err != nil {
+		return nil, err
+	}
+	shared.index = &Index{store: store, embedder: embedder}
+	return shared.index, nil
+}
+
+// stateDir is where the state of the sources is kept
+func stateDir() string {
+	return filepath.Join(config.Get().Data.Directory, "knowledge")
+}
+
+func loadState(name string) sourceState {
+	state := sourceState{Pages: map[string]pageState{}}
+	data, err := os.ReadFile(filepath.Join(stateDir(), name+".json"))
+	if err == nil {
+		json.Unmarshal(data, &state)
+	}
+	if state.Pages == nil {
+		state.Pages = map[string]pageState{}
+	}
+	return state
+}
+
+func saveState(name string, state sourceState) error {
+	if err := os.MkdirAll(stateDir(), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(stateDir(), name+".json")
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// LastSync returns when a source was last synced, zero if never
+func LastSync(name string) time.Time {
+	return loadState(name).LastSync
+}
+
+// Due reports whether a source should be synced again after refresh
+func Due(name string, refresh time.Duration) bool {
+	last := LastSync(name)
+	return last.IsZero() || (refresh > 0 && time.Since(last) >= refresh)
+}
+
+func chunkID(source, doc string, n int) string {
+	return source + ":" + doc + ":" + strconv.Itoa(n)
+}
+
+// Sync indexes the pages of a source that changed since its last sync and
+// removes the ones gone from it
+func (i *Index) Sync(ctx context.Context, name string, source config.KnowledgeSource) (SyncReport, error) {
+	start := time.Now()
+	connector, err := NewConnector(source)
+	if err != nil {
+		return SyncReport{}, err
+	}
+	docs, err := connector.Fetch(ctx)
+	if err != nil {
+		return SyncReport{}, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	state := loadState(name)
+	report := SyncReport{Pages: len(docs)}
+	current := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		current[doc.ID] = true
+		sum := sha256.Sum256([]byte(doc.Title + "\n" + doc.URL + "\n" + doc.Text))
+		hash := hex.EncodeToString(sum[:])
+		previous, ok := state.Pages[doc.ID]
+		if ok && previous.Hash == hash {
+			continue
+		}
+		chunks, err := i.indexPage(ctx, name, doc, previous.Chunks)
+		if err != nil {
+			return report, err
+		}
+		state.Pages[doc.ID] = pageState{Hash: hash, Chunks: chunks}
+		report.Updated++
+		report.Chunks += chunks
+		// Progress survives an interrupted sync
+		if err := saveState(name, state); err != nil {
+			return report, err
+		}
+	}
+
+	for id, page := range state.Pages {
+		if current[id] {
+			continue
+		}
+		if err := i.store.Delete(ctx, Collection, chunkIDs(name, id, 0, page.Chunks)); err != nil {
+			return report, err
+		}
+		delete(state.Pages, id)
+		report.Removed++
+	}
+
+	state.LastSync = time.Now()
+	report.CreatedAt = state.LastSync
+	report.Duration = time.Since(start)
+	return report, saveState(name, state)
+}
+
+// indexPage embeds the chunks of a page and removes the chunks left from a
+// longer previous version. It returns the number of chunks.
+func (i *Index) indexPage(ctx context.Context, name string, doc Document, previousChunks int) (int, error) {
+	chunks := chunk(doc.Text, chunkSize)
+	texts := make([]string, len(chunks))
+	for n, text := range chunks {
+		// The title gives the chunks of a page their context
+		texts[n] = doc.Title + "\n\n" + text
+	}
+	vectors, err := i.embedder.Embed(ctx, texts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to embed %q: %w", doc.Title, err)
+	}
+	records := make([]vectorstore.Record, len(chunks))
+	for n, text := range chunks {
+		records[n] = vectorstore.Record{
+			ID:     chunkID(name, doc.ID, n),
+			Vector: vectors[n],
+			Metadata: map[string]string{
+				metaSource: name,
+				metaDoc:    doc.ID,
+				metaTitle:  doc.Title,
+				metaURL:    doc.URL,
+				metaText:   text,
+			},
+		}
+	}
+	if err := i.store.Upsert(ctx, Collection, records); err != nil {
+		return 0, err
+	}
+	if previousChunks > len(chunks) {
+		if err := i.store.Delete(ctx, Collection, chunkIDs(name, doc.ID, len(chunks), previousChunks)); err != nil {
+			return 0, err
+		}
+	}
+	return len(chunks), nil
+}
+
+func chunkIDs(source, doc string, from, to int) []string {
+	ids := make([]string, 0, to-from)
+	for n := from; n < to; n++ {
+		ids = append(ids, chunkID(source, doc, n))
+	}
+	return ids
+}
+
+// Search returns the chunks of the docs closest to a query, of a source or
+// of all of them
+func (i *Index) Search(ctx context.Context, query string, limit int, source string) ([]Result, error) {
+	vectors, err := i.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	var filter map[string]string
+	if source != "" {
+		filter = map[string]string{metaSource: source}
+	}
+	found, err := i.store.Search(ctx, Collection, vectors[0], limit, filter)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, len(found))
+	for n, match := range found {
+		results[n] = Result{
+			Source: match.Metadata[metaSource],
+			Title:  match.Metadata[metaTitle],
+			URL:    match.Metadata[metaURL],
+			Text:   match.Metadata[metaText],
+			Score:  match.Score,
+		}
+	}
+	return results, nil
+}
+
+// chunk splits a page in chunks of about size bytes at paragraph breaks,
+// cutting the paragraphs longer than a chunk
+func chunk(text string, size int) []string {
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		current.Reset()
+	}
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if current.Len() > 0 && current.Len()+len(paragraph)+2 > size {
+			flush()
+		}
+		for len(paragraph) > size {
+			cut := size
+			for cut > 0 && !utf8.RuneStart(paragraph[cut]) {
+				cut--
+			}
+			current.WriteString(paragraph[:cut])
+			flush()
+			paragraph = paragraph[cut:]
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(paragraph)
+	}
+	flush()
+	return chunks
+}
@@ -0,0 +1,60 @@
+// Package knowledge indexes the external documentation of the team, such as
+// a Confluence space, a Notion export or a docs site, in the semantic index,
+// so the research subagent can search it and cite where its answers come
+// from.
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+// Collection is the collection of the vector store the docs are indexed in
+const Collection = "knowledge"
+
+// fetchTimeout bounds a request of a connector
+const fetchTimeout = 30 * time.Second
+
+// Document is a page of a source
+type Document struct {
+	ID    string // Stable ID of the page in its source
+	Title string
+	URL   string // Where the page is read, or its path in an export
+	Text  string // Markdown
+}
+
+// Connector reads the pages of a source
+type Connector interface {
+	Fetch(ctx context.Context) ([]Document, error)
+}
+
+// NewConnector returns the connector of a source
+func NewConnector(source config.KnowledgeSource) (Connector, error) {
+	client := &http.Client{Timeout: fetchTimeout}
+	switch source.Type {
+	case config.KnowledgeConfluence:
+		return newConfluence(source, client), nil
+	case config.KnowledgeNotion:
+		return newNotion(source.Path), nil
+	case config.KnowledgeSite:
+		return newSite(source.URL, source.MaxPages, client)
+	}
+	return nil, fmt.Errorf("unknown knowledge source type %q", source.Type)
+}
+
+// htmlToMarkdown converts the HTML of a page, relative links are resolved
+// against base
+func htmlToMarkdown(html, base string) (string, error) {
+	converter := md.NewConverter(base, true, nil)
+	markdown, err := converter.ConvertString(html)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(markdown), nil
+}
@@ -0,0 +1,162 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunk(t *testing.T) {
+	t.Run("keeps short text whole", func(t *testing.T) {
+		assert.Equal(t, []string{"one\n\ntwo"}, chunk("one\n\ntwo", 100))
+	})
+
+	t.Run("splits at paragraphs", func(t *testing.T) {
+		text := strings.Repeat("a", 6) + "\n\n" + strings.Repeat("b", 6) + "\n\n" + strings.Repeat("c", 6)
+		assert.Equal(t, []string{"aaaaaa\n\nbbbbbb", "cccccc"}, chunk(text, 14))
+	})
+
+	t.Run("cuts long paragraphs on runes", func(t *testing.T) {
+		chunks := chunk(strings.Repeat("é", 5), 4)
+		assert.Equal(t, []string{"éé", "éé", "é"}, chunks)
+	})
+
+	t.Run("drops blank text", func(t *testing.T) {
+		assert.Empty(t, chunk("\n\n  \n\n", 10))
+	})
+}
+
+func TestNotionDocument(t *testing.T) {
+	doc := notionDocument("Team/Onboarding 0123456789abcdef0123456789abcdef.md", "text")
+	assert.Equal(t, "Onboarding", doc.Title)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", doc.ID)
+	assert.Equal(t, "https://www.notion.so/0123456789abcdef0123456789abcdef", doc.URL)
+
+	doc = notionDocument("Notes.md", "text")
+	assert.Equal(t, "Notes", doc.Title)
+	assert.Equal(t, "Notes.md", doc.ID)
+}
+
+func TestNotionFetch(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "Team"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Team", "Deploys 0123456789abcdef0123456789abcdef.md"), []byte("# Deploys\n\nShip on Tuesdays."), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Oncall.html"), []byte("<h1>Oncall</h1><p>Page the <b>primary</b>.</p>"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "diagram.png"), []byte("png"), 0o644))
+
+	docs, err := newNotion(dir).Fetch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "Oncall", docs[0].Title)
+	assert.Contains(t, docs[0].Text, "**primary**")
+	assert.Equal(t, "Deploys", docs[1].Title)
+	assert.Contains(t, docs[1].Text, "Ship on Tuesdays.")
+}
+
+func TestSiteFetch(t *testing.T) {
+	pages := map[string]string{
+		"/docs/":        `<nav><a href="/docs/setup">Setup</a></nav><main><h1>Home</h1><p>Welcome.</p><a href="guide#intro">Guide</a><a href="/blog/">Blog</a><a href="https://example.com/docs/">Out</a></main>`,
+		"/docs/setup":   `<title>Setup | Docs</title><body><p>Install it.</p><a href="/docs/missing">Missing</a></body>`,
+		"/docs/guide":   `<main><h1>Guide</h1><p>Read it.</p><a href="/docs/">Home</a></main>`,
+		"/blog/":        `<main><h1>Blog</h1></main>`,
+		"/docs/img.png": `png`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<html>"+page+"</html>")
+	}))
+	defer server.Close()
+
+	crawler, err := newSite(server.URL+"/docs/", 0, server.Client())
+	require.NoError(t, err)
+	docs, err := crawler.Fetch(context.Background())
+	require.NoError(t, err)
+
+	titles := map[string]string{}
+	for _, doc := range docs {
+		titles[strings.TrimPrefix(doc.URL, server.URL)] = doc.Title
+	}
+	assert.Equal(t, map[string]string{
+		"/docs/":      "Home",
+		"/docs/setup": "Setup | Docs",
+		"/docs/guide": "Guide",
+	}, titles)
+	for _, doc := range docs {
+		assert.NotContains(t, doc.Text, "Setup](", "navigation is dropped")
+	}
+
+	t.Run("limits the pages", func(t *testing.T) {
+		crawler, err := newSite(server.URL+"/docs/", 1, server.Client())
+		require.NoError(t, err)
+		docs, err := crawler.Fetch(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, docs, 1)
+	})
+
+	t.Run("fails when the start page does", func(t *testing.T) {
+		crawler, err := newSite(server.URL+"/nowhere/", 0, server.Client())
+		require.NoError(t, err)
+		_, err = crawler.Fetch(context.Background())
+		assert.Error(t, err)
+	})
+}
+
+func TestConfluenceFetch(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, token, ok := r.BasicAuth()
+		if !ok || user != "me@example.com" || token != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "ENG", r.URL.Query().Get("spaceKey"))
+		assert.Equal(t, "body.storage", r.URL.Query().Get("expand"))
+		if r.URL.Query().Get("start") != "0" {
+			fmt.Fprint(w, `{"results": [], "_links": {}}`)
+			return
+		}
+		fmt.Fprintf(w, `{
+			"results": [{
+				"id": "42",
+				"title": "Runbook",
+				"body": {"storage": {"value": "<p>Restart the <code>worker</code>.</p>"}},
+				"_links": {"webui": "/spaces/ENG/pages/42"}
+			}],
+			"_links": {"base": "%s/wiki"}
+		}`, server.URL)
+	}))
+	defer server.Close()
+
+	source := config.KnowledgeSource{
+		Type:     config.KnowledgeConfluence,
+		URL:      server.URL,
+		Space:    "ENG",
+		Username: "me@example.com",
+		Token:    "secret",
+	}
+	docs, err := newConfluence(source, server.Client()).Fetch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "42", docs[0].ID)
+	assert.Equal(t, "Runbook", docs[0].Title)
+	assert.Equal(t, server.URL+"/wiki/spaces/ENG/pages/42", docs[0].URL)
+	assert.Equal(t, "Restart the `worker`.", docs[0].Text)
+
+	source.Token = "wrong"
+	_, err = newConfluence(source, server.Client()).Fetch(context.Background())
+	assert.ErrorContains(t, err, "401")
+}
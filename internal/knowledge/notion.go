@@ -0,0 +1,68 @@
+package knowledge
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// notionID matches the ID Notion appends to the names of exported pages,
+// e.g. "Onboarding 0123456789abcdef0123456789abcdef.md"
+var notionID = regexp.MustCompile(`\s+([0-9a-f]{32})$`)
+
+// notion reads the pages of a Notion workspace exported in Markdown or HTML
+type notion struct {
+	dir string
+}
+
+func newNotion(dir string) *notion {
+	return &notion{dir: dir}
+}
+
+func (n *notion) Fetch(ctx context.Context) ([]Document, error) {
+	var docs []Document
+	err := filepath.WalkDir(n.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if d.IsDir() || (ext != ".md" && ext != ".html") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(n.dir, path)
+		doc := notionDocument(filepath.ToSlash(rel), string(data))
+		if ext == ".html" {
+			if doc.Text, err = htmlToMarkdown(doc.Text, ""); err != nil {
+				return err
+			}
+		}
+		if strings.TrimSpace(doc.Text) != "" {
+			docs = append(docs, doc)
+		}
+		return nil
+	})
+	return docs, err
+}
+
+// notionDocument names an exported page after its file, and links to the
+// page in Notion when the file name has its ID
+func notionDocument(rel, text string) Document {
+	name := strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel))
+	doc := Document{ID: rel, Title: name, URL: rel, Text: text}
+	if m := notionID.FindStringSubmatch(name); m != nil {
+		doc.Title = strings.TrimSpace(name[:len(name)-len(m[0])])
+		doc.ID = m[1]
+		doc.URL = "https://www.notion.so/" + m[1]
+	}
+	return doc
+}
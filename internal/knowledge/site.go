@@ -0,0 +1,153 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/kirmad/superopencode/internal/logging"
+)
+
+const (
+	// defaultMaxPages is how many pages of a docs site are crawled by default
+	defaultMaxPages = 200
+	// maxPageSize is the size of the largest page read
+	maxPageSize = 5 * 1024 * 1024
+)
+
+// site crawls a docs site from its start page, following the links to the
+// pages under the directory of the start page
+type site struct {
+	start    *url.URL
+	prefix   string // Path the pages of the site start with
+	maxPages int
+	client   *http.Client
+}
+
+func newSite(start string, maxPages int, client *http.Client) (*site, error) {
+	u, err := url.Parse(start)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid docs site URL %q", start)
+	}
+	prefix := u.Path
+	if !strings.HasSuffix(prefix, "/") {
+		prefix = prefix[:strings.LastIndex(prefix, "/")+1]
+	}
+	if maxPages <= 0 {
+		maxPages = defaultMaxPages
+	}
+	return &site{start: u, prefix: prefix, maxPages: maxPages, client: client}, nil
+}
+
+// normalize returns the URL of a page of the site a link points to, or
+// false when it's outside of the site
+func (s *site) normalize(link *url.URL) (string, bool) {
+	if link.Host != s.start.Host || (link.Scheme != "http" && link.Scheme != "https") {
+		return "", false
+	}
+	if !strings.HasPrefix(link.Path, s.prefix) {
+		return "", false
+	}
+	u := *link
+	u.Fragment = ""
+	u.RawQuery = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String(), true
+}
+
+func (s *site) Fetch(ctx context.Context) ([]Document, error) {
+	first, _ := s.normalize(s.start)
+	queue := []string{first}
+	seen := map[string]bool{first: true}
+	var docs []Document
+	for len(queue) > 0 && len(docs) < s.maxPages {
+		page := queue[0]
+		queue = queue[1:]
+		doc, links, err := s.fetchPage(ctx, page)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			// The start page must load, a broken link only loses its page
+			if page == first {
+				return nil, err
+			}
+			logging.Debug("Skipping docs page", "url", page, "error", err)
+			continue
+		}
+		if doc.Text != "" {
+			docs = append(docs, doc)
+		}
+		for _, link := range links {
+			if !seen[link] {
+				seen[link] = true
+				queue = append(queue, link)
+			}
+		}
+	}
+	return docs, nil
+}
+
+// fetchPage reads a page and returns its document and the pages of the site
+// it links to
+func (s *site) fetchPage(ctx context.Context, page string) (Document, []string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, page, nil)
+	if err != nil {
+		return Document{}, nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Document{}, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Document{}, nil, fmt.Errorf("%s: %s", page, resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return Document{}, nil, nil
+	}
+	html, err := goquery.NewDocumentFromReader(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return Document{}, nil, err
+	}
+
+	base := resp.Request.URL
+	var links []string
+	html.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		link, err := base.Parse(href)
+		if err != nil {
+			return
+		}
+		if normalized, ok := s.normalize(link); ok {
+			links = append(links, normalized)
+		}
+	})
+
+	title := strings.TrimSpace(html.Find("title").First().Text())
+	if h1 := strings.TrimSpace(html.Find("h1").First().Text()); h1 != "" {
+		title = h1
+	}
+	// The content of the page without the navigation repeated on every page
+	content := html.Find("main, article, [role=main]").First()
+	if content.Length() == 0 {
+		content = html.Find("body")
+	}
+	content.Find("script, style, nav, header, footer, aside").Remove()
+	contentHTML, err := content.Html()
+	if err != nil {
+		return Document{}, nil, err
+	}
+	text, err := htmlToMarkdown(contentHTML, base.String())
+	if err != nil {
+		return Document{}, nil, err
+	}
+	return Document{ID: page, Title: title, URL: page, Text: text}, links, nil
+}
@@ -4,18 +4,29 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/kirmad/superopencode/internal/config"
 	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/logging"
 	"github.com/kirmad/superopencode/internal/lsp"
 	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/metrics"
+	"github.com/kirmad/superopencode/internal/quota"
 	"github.com/kirmad/superopencode/internal/session"
 )
 
 type agentTool struct {
 	sessions   session.Service
 	messages   message.Service
+	quotas     quota.Service
+	metrics    metrics.Service
 	lspClients map[string]*lsp.Client
+
+	// newAgent creates the agent running the tasks, the task agent when nil
+	newAgent func() (Service, error)
 }
 
 const (
@@ -23,18 +34,57 @@ const (
 )
 
 type AgentParams struct {
-	Prompt string `json:"prompt"`
+	Prompt       string       `json:"prompt"`
+	Priority     string       `json:"priority,omitempty"`     // high, normal or low
+	Continuation string       `json:"continuation,omitempty"` // Token of a finished task to continue
+	Retry        *RetryPolicy `json:"retry,omitempty"`        // Retries of the task when it fails with a transient error
+	TaskBudget
+}
+
+// TaskSessionID returns the session a call of the agent tool runs in: the
+// session of the task it continues, or a new one with the ID of the call
+func TaskSessionID(call message.ToolCall) string {
+	var params AgentParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err == nil && params.Continuation != "" {
+		return params.Continuation
+	}
+	return call.ID
 }
 
+// continuationNote ends the report of a task with the token to continue it
+const continuationNote = "\n\n<continuation>%s</continuation>\nTo ask this agent follow-up questions or give it more work with everything it already learned, call the agent tool again with this continuation token."
+
+// resumeNote ends the result of a task that was canceled or failed with the
+// token to resume it
+const resumeNote = "\n\n<continuation>%s</continuation>\nTo resume this agent where it stopped, with everything it already read and found, call the agent tool again with this continuation token."
+
 func (b *agentTool) Info() tools.ToolInfo {
 	return tools.ToolInfo{
 		Name:        AgentToolName,
-		Description: "Launch a new agent that has access to the following tools: GlobTool, GrepTool, LS, View. When you are searching for a keyword or file and are not confident that you will find the right match on the first try, use the Agent tool to perform the search for you. For example:\n\n- If you are searching for a keyword like \"config\" or \"logger\", or for questions like \"which file does X?\", the Agent tool is strongly recommended\n- If you want to read a specific file path, use the View or GlobTool tool instead of the Agent tool, to find the match more quickly\n- If you are searching for a specific class definition like \"class Foo\", use the GlobTool tool instead, to find the match more quickly\n\nUsage notes:\n1. Launch multiple agents concurrently whenever possible, to maximize performance; to do that, use a single message with multiple tool uses\n2. When the agent is done, it will return a single message back to you. The result returned by the agent is not visible to the user. To show the user the result, you should send a text message back to the user with a concise summary of the result.\n3. Each agent invocation is stateless. You will not be able to send additional messages to the agent, nor will the agent be able to communicate with you outside of its final report. Therefore, your prompt should contain a highly detailed task description for the agent to perform autonomously and you should specify exactly what information the agent should return back to you in its final and only message to you.\n4. The agent's outputs should generally be trusted\n5. IMPORTANT: The agent can not use Bash, Replace, Edit, so can not modify files. If you want to use these tools, use them directly instead of going through the agent.",
+		Description: "Launch a new agent that has access to the following tools: GlobTool, GrepTool, LS, View. When you are searching for a keyword or file and are not confident that you will find the right match on the first try, use the Agent tool to perform the search for you. For example:\n\n- If you are searching for a keyword like \"config\" or \"logger\", or for questions like \"which file does X?\", the Agent tool is strongly recommended\n- If you want to read a specific file path, use the View or GlobTool tool instead of the Agent tool, to find the match more quickly\n- If you are searching for a specific class definition like \"class Foo\", use the GlobTool tool instead, to find the match more quickly\n\nUsage notes:\n1. Launch multiple agents concurrently whenever possible, to maximize performance; to do that, use a single message with multiple tool uses\n2. When the agent is done, it will return a single message back to you. The result returned by the agent is not visible to the user. To show the user the result, you should send a text message back to the user with a concise summary of the result.\n3. The agent can not communicate with you outside of its final report. Therefore, your prompt should contain a highly detailed task description for the agent to perform autonomously and you should specify exactly what information the agent should return back to you in its report. The report ends with a continuation token: pass it as continuation in a new call to continue the same agent with everything it already read and found, e.g. for follow-up questions or the next step of a multi-step investigation, instead of starting over with a new agent. Limit what an agent may spend with max_cost_usd and max_tokens, it is stopped once it exceeds them. Set retry to resume an agent that failed with a transient error such as a timeout or a rate limit, instead of handling the failure yourself. Agents that were canceled, failed or stopped by their budget return a continuation token too, continuing them resumes their work where it stopped. Agents launched from the same message share a blackboard: they can post intermediate findings and read the findings of the agents launched before them, so for cooperative work launch a discovery agent first and ask the others to build on its findings.\n4. The agent's outputs should generally be trusted\n5. IMPORTANT: The agent can not use Bash, Replace, Edit, so can not modify files. If you want to use these tools, use them directly instead of going through the agent.",
 		Parameters: map[string]any{
 			"prompt": map[string]any{
 				"type":        "string",
 				"description": "The task for the agent to perform",
 			},
+			"priority": map[string]any{
+				"type":        "string",
+				"description": "Priority of the task among the agents launched in the same message. High priority tasks run first, e.g. a discovery task whose findings the others build on",
+				"enum":        []string{TaskPriorityHigh, TaskPriorityNormal, TaskPriorityLow},
+			},
+			"continuation": map[string]any{
+				"type":        "string",
+				"description": "The continuation token of a finished agent to continue, the prompt is sent to it as a new message",
+			},
+			"max_cost_usd": map[string]any{
+				"type":        "number",
+				"description": "Stop the agent once it spent this much, in USD",
+			},
+			"max_tokens": map[string]any{
+				"type":        "integer",
+				"description": "Stop the agent once its requests used this many tokens",
+			},
+			"retry": retryPolicySchema("Resume the agent when it fails with a transient error"),
 		},
 		Required: []string{"prompt"},
 	}
@@ -48,62 +98,210 @@ func (b *agentTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolRes
 	if params.Prompt == "" {
 		return tools.NewTextErrorResponse("prompt is required"), nil
 	}
+	if params.Retry != nil {
+		if err := params.Retry.validate(); err != nil {
+			return tools.NewTextErrorResponse(err.Error()), nil
+		}
+	}
+	response, _, err := b.runTask(ctx, call, params)
+	return response, err
+}
 
+// runTask runs a task of the agent tool and returns its response and the
+// number of times it was retried
+func (b *agentTool) runTask(ctx context.Context, call tools.ToolCall, params AgentParams) (tools.ToolResponse, int, error) {
 	sessionID, messageID := tools.GetContextValues(ctx)
 	if sessionID == "" || messageID == "" {
-		return tools.ToolResponse{}, fmt.Errorf("session_id and message_id are required")
+		return tools.ToolResponse{}, 0, fmt.Errorf("session_id and message_id are required")
 	}
 
-	agent, err := NewAgent(config.AgentTask, b.sessions, b.messages, TaskAgentTools(b.lspClients))
+	agent, err := b.taskAgent()
 	if err != nil {
-		return tools.ToolResponse{}, fmt.Errorf("error creating agent: %s", err)
+		return tools.ToolResponse{}, 0, fmt.Errorf("error creating agent: %s", err)
 	}
 
-	session, err := b.sessions.CreateTaskSession(ctx, call.ID, sessionID, "New Agent Session")
-	if err != nil {
-		return tools.ToolResponse{}, fmt.Errorf("error creating session: %s", err)
+	// Tasks launched from the same message share a blackboard for their findings
+	ctx = tools.WithBlackboard(ctx, messageID)
+
+	var session session.Session
+	if params.Continuation != "" {
+		session, err = b.continuedSession(ctx, params.Continuation)
+		if err != nil {
+			return tools.NewTextErrorResponse(err.Error()), 0, nil
+		}
+	} else {
+		session, err = b.sessions.CreateTaskSession(ctx, call.ID, sessionID, "New Agent Session")
+		if err != nil {
+			return tools.ToolResponse{}, 0, fmt.Errorf("error creating session: %s", err)
+		}
 	}
 
-	done, err := agent.Run(ctx, session.ID, params.Prompt)
+	started := time.Now()
+	taskCtx, finish := startTask(ctx, session.ID)
+	// The task is stopped once it exceeds its budget or the one of its run
+	taskCtx, budget, finishBudget := startTaskBudget(taskCtx, session.ID, params.TaskBudget)
+	defer finishBudget()
+	// The parent session follows what the task does until it reports
+	taskCtx, finishRelay := withTaskRelay(taskCtx, sessionID, call.ID, session.ID)
+	defer finishRelay()
+	var policy RetryPolicy
+	if params.Retry != nil {
+		policy = *params.Retry
+	}
+	result, retries, err := runTaskAttempts(taskCtx, policy, params.Prompt, func(prompt string) (AgentEvent, error) {
+		done, err := agent.Run(taskCtx, session.ID, prompt)
+		if err != nil {
+			return AgentEvent{}, err
+		}
+		return <-done, nil
+	})
 	if err != nil {
-		return tools.ToolResponse{}, fmt.Errorf("error generating agent: %s", err)
+		finish()
+		b.recordTask(call.ID, session, sessionID, metrics.StatusFailed, started, retries)
+		return tools.ToolResponse{}, retries, fmt.Errorf("error generating agent: %s", err)
+	}
+	canceled := finish()
+	var exceeded BudgetExceeded
+	overBudget := false
+	if budget != nil {
+		exceeded, overBudget = budget.exceededBy()
+		exceeded.SessionID = session.ID
+	}
+
+	status := metrics.StatusDone
+	switch {
+	case overBudget:
+		status = metrics.StatusBudgetExceeded
+	case canceled || ctx.Err() != nil:
+		status = metrics.StatusCanceled
+	case result.Error != nil || result.Message.Role != message.Assistant:
+		status = metrics.StatusFailed
+	}
+	b.recordTask(call.ID, session, sessionID, status, started, retries)
+
+	if overBudget {
+		// The run may be canceled with its budget, the cost is added anyway
+		if err := b.addTaskCost(context.WithoutCancel(ctx), session, sessionID); err != nil {
+			return tools.ToolResponse{}, retries, err
+		}
+		response := tools.NewTextErrorResponse(fmt.Sprintf("%s %s.", TaskBudgetExceededResult, exceeded) + fmt.Sprintf(resumeNote, session.ID))
+		return tools.WithResponseMetadata(response, exceeded), retries, nil
+	}
+	if canceled && ctx.Err() == nil {
+		// Only this task was stopped, the parent request goes on
+		if err := b.addTaskCost(ctx, session, sessionID); err != nil {
+			return tools.ToolResponse{}, retries, err
+		}
+		return tools.NewTextErrorResponse(TaskCanceledResult + fmt.Sprintf(resumeNote, session.ID)), retries, nil
 	}
-	result := <-done
 	if result.Error != nil {
-		return tools.ToolResponse{}, fmt.Errorf("error generating agent: %s", result.Error)
+		if ctx.Err() != nil {
+			return tools.ToolResponse{}, retries, fmt.Errorf("error generating agent: %s", result.Error)
+		}
+		// The task keeps what it did before it failed, e.g. on a provider
+		// error, so it can be resumed instead of started over
+		if err := b.addTaskCost(ctx, session, sessionID); err != nil {
+			return tools.ToolResponse{}, retries, err
+		}
+		return tools.NewTextErrorResponse(fmt.Sprintf("error generating agent: %s", result.Error) + retriedNote(retries) + fmt.Sprintf(resumeNote, session.ID)), retries, nil
 	}
 
 	response := result.Message
 	if response.Role != message.Assistant {
-		return tools.NewTextErrorResponse("no response"), nil
+		return tools.NewTextErrorResponse("no response"), retries, nil
+	}
+
+	if err := b.addTaskCost(ctx, session, sessionID); err != nil {
+		return tools.ToolResponse{}, retries, err
+	}
+	return tools.NewTextResponse(response.Content().String() + fmt.Sprintf(continuationNote, session.ID)), retries, nil
+}
+
+// taskAgent creates the agent running a task
+func (b *agentTool) taskAgent() (Service, error) {
+	if b.newAgent != nil {
+		return b.newAgent()
+	}
+	return NewAgent(config.AgentTask, b.sessions, b.messages, b.quotas, TaskAgentTools(b.lspClients))
+}
+
+// continuedSession returns the session of a finished task to continue
+func (b *agentTool) continuedSession(ctx context.Context, token string) (session.Session, error) {
+	taskSession, err := b.sessions.Get(ctx, token)
+	if err != nil || !taskSession.Ephemeral || taskSession.ParentSessionID == "" || strings.HasPrefix(taskSession.ID, "title-") {
+		return session.Session{}, fmt.Errorf("unknown continuation token %q, launch a new agent instead", token)
+	}
+	if taskSession.MessageCount == 0 {
+		return session.Session{}, fmt.Errorf("the task of continuation token %q was archived, launch a new agent instead", token)
+	}
+	if isTaskRunning(taskSession.ID) {
+		return session.Session{}, fmt.Errorf("the task of continuation token %q is still running, wait for its report", token)
 	}
+	return taskSession, nil
+}
+
+// taskCostMu serializes the updates of the parent session cost, tasks of a
+// parallel_tasks call finish at the same time
+var taskCostMu sync.Mutex
 
-	updatedSession, err := b.sessions.Get(ctx, session.ID)
+// addTaskCost adds the cost a task session had since it started to its
+// parent session. Continued tasks already added what they cost before.
+func (b *agentTool) addTaskCost(ctx context.Context, taskSession session.Session, parentSessionID string) error {
+	taskCostMu.Lock()
+	defer taskCostMu.Unlock()
+	updatedSession, err := b.sessions.Get(ctx, taskSession.ID)
 	if err != nil {
-		return tools.ToolResponse{}, fmt.Errorf("error getting session: %s", err)
+		return fmt.Errorf("error getting session: %s", err)
 	}
-	parentSession, err := b.sessions.Get(ctx, sessionID)
+	parentSession, err := b.sessions.Get(ctx, parentSessionID)
 	if err != nil {
-		return tools.ToolResponse{}, fmt.Errorf("error getting parent session: %s", err)
+		return fmt.Errorf("error getting parent session: %s", err)
 	}
 
-	parentSession.Cost += updatedSession.Cost
+	parentSession.Cost += updatedSession.Cost - taskSession.Cost
 
 	_, err = b.sessions.Save(ctx, parentSession)
 	if err != nil {
-		return tools.ToolResponse{}, fmt.Errorf("error saving parent session: %s", err)
+		return fmt.Errorf("error saving parent session: %s", err)
+	}
+	return nil
+}
+
+// recordTask stores the outcome of a task run for the metrics trends, with
+// the usage of the task session since the run started. It runs outside the
+// request context so canceled tasks are recorded as well.
+func (b *agentTool) recordTask(callID string, taskSession session.Session, parentSessionID, status string, started time.Time, retries int) {
+	ctx := context.Background()
+	task := metrics.Task{
+		ID:            callID,
+		SessionID:     parentSessionID,
+		Subagent:      string(agentSubagents[config.AgentTask]),
+		Status:        status,
+		Duration:      time.Since(started),
+		RetryAttempts: retries,
+	}
+	if updatedSession, err := b.sessions.Get(ctx, taskSession.ID); err == nil {
+		task.PromptTokens = updatedSession.PromptTokens - taskSession.PromptTokens
+		task.CompletionTokens = updatedSession.CompletionTokens - taskSession.CompletionTokens
+		task.Cost = updatedSession.Cost - taskSession.Cost
+	}
+	if err := b.metrics.RecordTask(ctx, task); err != nil {
+		logging.Warn("Failed to record task metrics", "task", callID, "error", err)
 	}
-	return tools.NewTextResponse(response.Content().String()), nil
 }
 
 func NewAgentTool(
 	Sessions session.Service,
 	Messages message.Service,
+	Quotas quota.Service,
+	Metrics metrics.Service,
 	LspClients map[string]*lsp.Client,
 ) tools.BaseTool {
 	return &agentTool{
 		sessions:   Sessions,
 		messages:   Messages,
+		quotas:     Quotas,
+		metrics:    Metrics,
 		lspClients: LspClients,
 	}
 }
@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/llm/provider"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskSessionID(t *testing.T) {
+	assert.Equal(t, "call-1", TaskSessionID(message.ToolCall{ID: "call-1", Name: AgentToolName, Input: `{"prompt":"p"}`}))
+	assert.Equal(t, "call-0", TaskSessionID(message.ToolCall{ID: "call-2", Name: AgentToolName, Input: `{"prompt":"p","continuation":"call-0"}`}))
+	assert.Equal(t, "call-3", TaskSessionID(message.ToolCall{ID: "call-3", Name: AgentToolName, Input: `{"prompt":`}), "input still streaming")
+}
+
+func TestTaskRunning(t *testing.T) {
+	assert.False(t, isTaskRunning("task"))
+	_, finish := startTask(context.Background(), "task")
+	assert.True(t, isTaskRunning("task"))
+	finish()
+	assert.False(t, isTaskRunning("task"))
+}
+
+func TestCancelTask(t *testing.T) {
+	assert.False(t, CancelTask("task"), "the task is not running")
+
+	parent, cancelParent := context.WithCancel(context.Background())
+	defer cancelParent()
+	taskCtx, finish := startTask(parent, "task")
+	assert.True(t, CancelTask("task"))
+	assert.Error(t, taskCtx.Err())
+	assert.NoError(t, parent.Err(), "the request that launched the task goes on")
+	assert.True(t, finish(), "the task reports it was canceled")
+	assert.False(t, CancelTask("task"))
+
+	_, finish = startTask(parent, "other")
+	assert.False(t, finish(), "tasks that ran to the end weren't canceled")
+}
+
+// newScriptedTaskTool returns an agent tool running its tasks with an agent of
+// the script, and the context of a call from the parent session
+func newScriptedTaskTool(t *testing.T, p *scriptedProvider, agentTools ...tools.BaseTool) (*agentTool, context.Context, string) {
+	t.Helper()
+	q := newTestQueries(t)
+	a, parentID := newScriptedAgent(t, q, p, agentTools...)
+	b := &agentTool{
+		sessions: a.sessions,
+		messages: a.messages,
+		quotas:   a.quotas,
+		metrics:  metrics.NewService(q),
+		newAgent: func() (Service, error) { return a, nil },
+	}
+	ctx := context.WithValue(context.Background(), tools.SessionIDContextKey, parentID)
+	ctx = context.WithValue(ctx, tools.MessageIDContextKey, "message")
+	return b, ctx, parentID
+}
+
+// scriptedTurn is a response ending the turn, costing $1
+func scriptedTurn(content string) provider.ProviderResponse {
+	return provider.ProviderResponse{
+		Content:      content,
+		FinishReason: message.FinishReasonEndTurn,
+		Usage:        provider.TokenUsage{InputTokens: 1_000_000},
+	}
+}
+
+func TestResumeCanceledTask(t *testing.T) {
+	block, count := newBlockingTool(), &countingTool{}
+	turn := toolCallsTurn()
+	turn.Usage = provider.TokenUsage{InputTokens: 1_000_000}
+	p := &scriptedProvider{responses: []provider.ProviderResponse{turn}}
+	b, ctx, parentID := newScriptedTaskTool(t, p, block, count)
+
+	responses := make(chan tools.ToolResponse, 1)
+	go func() {
+		response, err := b.Run(ctx, tools.ToolCall{ID: "call-task", Name: AgentToolName, Input: `{"prompt":"look around"}`})
+		assert.NoError(t, err)
+		responses <- response
+	}()
+	<-block.started
+	require.True(t, CancelTask("call-task"))
+	response := <-responses
+	assert.True(t, response.IsError)
+	assert.True(t, strings.HasPrefix(response.Content, TaskCanceledResult))
+	assert.Contains(t, response.Content, "<continuation>call-task</continuation>")
+
+	p.mu.Lock()
+	p.responses = []provider.ProviderResponse{scriptedTurn("found it")}
+	p.mu.Unlock()
+	response, err := b.Run(ctx, tools.ToolCall{ID: "call-resume", Name: AgentToolName, Input: `{"prompt":"go on","continuation":"call-task"}`})
+	require.NoError(t, err)
+	assert.False(t, response.IsError)
+	assert.True(t, strings.HasPrefix(response.Content, "found it"))
+	assert.Contains(t, response.Content, "<continuation>call-task</continuation>")
+	assert.Zero(t, count.runs.Load(), "the canceled tool calls aren't run again")
+
+	// The resumed task gets what it did before it was canceled, with every
+	// tool call answered
+	p.mu.Lock()
+	request := p.requests[len(p.requests)-1]
+	p.mu.Unlock()
+	var roles []message.MessageRole
+	for _, msg := range request {
+		roles = append(roles, msg.Role)
+	}
+	require.Equal(t, []message.MessageRole{message.User, message.Assistant, message.Tool, message.User}, roles)
+	assert.Equal(t, "look around", request[0].Content().String())
+	assert.Len(t, request[1].ToolCalls(), 2)
+	assert.Equal(t, message.FinishReasonCanceled, request[1].FinishReason())
+	for _, result := range request[2].ToolResults() {
+		assert.True(t, result.IsError, result.ToolCallID)
+	}
+	assert.Len(t, request[2].ToolResults(), 2)
+	assert.Equal(t, "go on", request[3].Content().String())
+
+	// Both runs are added to the parent once
+	parent, err := b.sessions.Get(ctx, parentID)
+	require.NoError(t, err)
+	assert.InDelta(t, 2, parent.Cost, 1e-9)
+}
+
+func TestResumeFinishedTask(t *testing.T) {
+	count := &countingTool{}
+	p := &scriptedProvider{responses: []provider.ProviderResponse{scriptedTurn("the report")}}
+	b, ctx, parentID := newScriptedTaskTool(t, p, count)
+
+	response, err := b.Run(ctx, tools.ToolCall{ID: "call-task", Name: AgentToolName, Input: `{"prompt":"look around"}`})
+	require.NoError(t, err)
+	assert.Equal(t, "the report"+strings.ReplaceAll(continuationNote, "%s", "call-task"), response.Content)
+	finished, err := b.messages.List(ctx, "call-task")
+	require.NoError(t, err)
+
+	// A running task can't be resumed
+	_, finish := startTask(ctx, "call-task")
+	response, err = b.Run(ctx, tools.ToolCall{ID: "call-again", Name: AgentToolName, Input: `{"prompt":"go on","continuation":"call-task"}`})
+	finish()
+	require.NoError(t, err)
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "still running")
+
+	// Continuing the finished task only adds the new turn, what it did is
+	// left as it was
+	p.mu.Lock()
+	p.responses = []provider.ProviderResponse{scriptedTurn("nothing else")}
+	p.mu.Unlock()
+	response, err = b.Run(ctx, tools.ToolCall{ID: "call-again", Name: AgentToolName, Input: `{"prompt":"anything else?","continuation":"call-task"}`})
+	require.NoError(t, err)
+	assert.False(t, response.IsError)
+	assert.True(t, strings.HasPrefix(response.Content, "nothing else"))
+	assert.NotContains(t, response.Content, "where it stopped", "finished tasks are continued, not resumed")
+	assert.Zero(t, count.runs.Load())
+
+	msgs, err := b.messages.List(ctx, "call-task")
+	require.NoError(t, err)
+	require.Len(t, msgs, len(finished)+2)
+	for i, msg := range finished {
+		assert.Equal(t, msg.ID, msgs[i].ID)
+		assert.Equal(t, msg.Content().String(), msgs[i].Content().String())
+		assert.Equal(t, msg.FinishReason(), msgs[i].FinishReason())
+	}
+
+	parent, err := b.sessions.Get(ctx, parentID)
+	require.NoError(t, err)
+	assert.InDelta(t, 2, parent.Cost, 1e-9)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +19,7 @@ import (
 	"github.com/kirmad/superopencode/internal/message"
 	"github.com/kirmad/superopencode/internal/permission"
 	"github.com/kirmad/superopencode/internal/pubsub"
+	"github.com/kirmad/superopencode/internal/quota"
 	"github.com/kirmad/superopencode/internal/session"
 )
 
@@ -33,6 +35,8 @@ const (
 	AgentEventTypeError     AgentEventType = "error"
 	AgentEventTypeResponse  AgentEventType = "response"
 	AgentEventTypeSummarize AgentEventType = "summarize"
+	AgentEventTypePreflight AgentEventType = "preflight"
+	AgentEventTypeLatency   AgentEventType = "latency"
 )
 
 type AgentEvent struct {
@@ -44,6 +48,15 @@ type AgentEvent struct {
 	SessionID string
 	Progress  string
 	Done      bool
+
+	// Estimated prompt size before a request is sent
+	PromptTokens int64
+
+	// Time to the first token of a turn, with its first assistant message
+	// and the model that answered
+	FirstToken time.Duration
+	MessageID  string
+	Model      models.ModelID
 }
 
 type Service interface {
@@ -51,31 +64,70 @@ type Service interface {
 	Model() models.Model
 	Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan AgentEvent, error)
 	Cancel(sessionID string)
+	Stop(sessionID string) bool
 	IsSessionBusy(sessionID string) bool
 	IsBusy() bool
 	Update(agentName config.AgentName, modelID models.ModelID) (models.Model, error)
 	Summarize(ctx context.Context, sessionID string) error
+	DraftIssue(ctx context.Context, sessionID, notes string) (string, error)
+	DraftPullRequest(ctx context.Context, sessionID, patch, notes string) (string, error)
+	PostMortem(ctx context.Context, sessionID, report string) (string, error)
+	InspectContext(ctx context.Context, sessionID string) (ContextReport, error)
+	DropContext(ctx context.Context, sessionID, ref string) error
+	ForgetMessage(ctx context.Context, sessionID, messageID string) error
+	Redact(ctx context.Context, sessionID, text string) (int, error)
+	Warmup(ctx context.Context)
 }
 
 type agent struct {
 	*pubsub.Broker[AgentEvent]
+	name     config.AgentName
 	sessions session.Service
 	messages message.Service
+	quotas   quota.Service
 
 	tools    []tools.BaseTool
 	provider provider.Provider
 
+	// capabilities restrict the tools of subagents, nil for top level agents
+	capabilities *SubagentCapabilities
+
 	titleProvider     provider.Provider
 	summarizeProvider provider.Provider
 
+	// draftProvider generates the steps when speculative drafting is enabled,
+	// provider then only verifies risky tool calls
+	draftProvider provider.Provider
+
+	// outputSummaryProvider summarizes long tool outputs when enabled
+	outputSummaryProvider provider.Provider
+
+	// budgets pauses the tools failing too often in a session
+	budgets *tools.BudgetTracker
+
+	// routedProviders holds the providers of the router tier models
+	routedProviders sync.Map // models.ModelID -> provider.Provider
+
 	activeRequests sync.Map
+	// stopRequests are the sessions whose request stops after the running
+	// tool
+	stopRequests   sync.Map
 	detailedLogger *detailed_logging.DetailedLogger
+
+	systemTokens   int64
+	systemTokensMu sync.Mutex
+
+	// Model of the last warmup of the provider connection
+	warmupMu    sync.Mutex
+	warmedModel models.ModelID
+	warmedAt    time.Time
 }
 
 func NewAgent(
 	agentName config.AgentName,
 	sessions session.Service,
 	messages message.Service,
+	quotas quota.Service,
 	agentTools []tools.BaseTool,
 	detailedLogger ...*detailed_logging.DetailedLogger,
 ) (Service, error) {
@@ -103,17 +155,54 @@ func NewAgent(
 			return nil, err
 		}
 	}
+	var draftProvider provider.Provider
+	if speculative := config.Get().Speculative; agentName == config.AgentCoder && speculative.Enabled {
+		draftProvider, err = createProvider(agentName, speculative.DraftModel, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var outputSummaryProvider provider.Provider
+	if summary := config.Get().ToolOutputSummary; summary.Enabled {
+		outputSummaryProvider, err = createProvider(config.AgentSummarizer, summary.Model, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var capabilities *SubagentCapabilities
+	if kind, ok := agentSubagents[agentName]; ok {
+		caps := subagentCapabilities[kind]
+		capabilities = &caps
+		agentTools = capabilities.filter(agentName, agentTools)
+	}
+
+	agentTools = filterAllowedTools(agentName, agentTools, config.Get().AllowedTools)
+
+	// Tools the provider would reject are left out instead of failing requests
+	agentTools, toolErrs := provider.ValidateTools(agentProvider.Model().Provider, agentTools)
+	for _, err := range toolErrs {
+		logging.Warn("Skipping tool", "agent", agentName, "error", err)
+	}
 
 	agent := &agent{
 		Broker:            pubsub.NewBroker[AgentEvent](),
+		name:              agentName,
 		provider:          agentProvider,
 		messages:          messages,
 		sessions:          sessions,
+		quotas:            quotas,
 		tools:             agentTools,
 		titleProvider:     titleProvider,
 		summarizeProvider: summarizeProvider,
+		draftProvider:     draftProvider,
+		capabilities:      capabilities,
 		activeRequests:    sync.Map{},
 		detailedLogger:    logger,
+		budgets:           tools.NewBudgetTracker(),
+
+		outputSummaryProvider: outputSummaryProvider,
 	}
 
 	return agent, nil
@@ -215,6 +304,7 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 
 	genCtx, cancel := context.WithCancel(ctx)
 
+	a.stopRequests.Delete(sessionID)
 	a.activeRequests.Store(sessionID, cancel)
 	go func() {
 		logging.Debug("Request started", "sessionID", sessionID)
@@ -231,6 +321,7 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 		}
 		logging.Debug("Request completed", "sessionID", sessionID)
 		a.activeRequests.Delete(sessionID)
+		a.stopRequests.Delete(sessionID)
 		cancel()
 		a.Publish(pubsub.CreatedEvent, result)
 		events <- result
@@ -240,6 +331,7 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 }
 
 func (a *agent) processGeneration(ctx context.Context, sessionID, content string, attachmentParts []message.ContentPart) AgentEvent {
+	turnStart := time.Now()
 	cfg := config.Get()
 	// List existing messages; if none, start title generation asynchronously.
 	msgs, err := a.messages.List(ctx, sessionID)
@@ -247,7 +339,8 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		return a.err(fmt.Errorf("failed to list messages: %w", err))
 	}
 	if len(msgs) == 0 {
-		go func() {
+		// The title is generated from the prompt as typed, before it's chunked
+		go func(content string) {
 			defer logging.RecoverPanic("agent.Run", func() {
 				logging.ErrorPersist("panic while generating title")
 			})
@@ -255,12 +348,16 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 			if titleErr != nil {
 				logging.ErrorPersist(fmt.Sprintf("failed to generate title: %v", titleErr))
 			}
-		}()
+		}(content)
 	}
 	session, err := a.sessions.Get(ctx, sessionID)
 	if err != nil {
 		return a.err(fmt.Errorf("failed to get session: %w", err))
 	}
+	// Apply the session instructions and language to every request of this turn
+	ctx, instructions := sessionInstructions(ctx, session)
+	ctx = provider.WithSystemPromptAdditions(ctx, instructions)
+	ctx = a.route(ctx, sessionID, content)
 	if session.SummaryMessageID != "" {
 		summaryMsgInex := -1
 		for i, msg := range msgs {
@@ -275,12 +372,29 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		}
 	}
 
+	if note := fileUpdatesNote(sessionID); note != "" {
+		noteMsg, err := a.createUserMessage(ctx, sessionID, note, nil)
+		if err != nil {
+			return a.err(fmt.Errorf("failed to create file updates message: %w", err))
+		}
+		msgs = append(msgs, noteMsg)
+	}
+
+	content = a.chunkLargePrompt(ctx, sessionID, content)
 	userMsg, err := a.createUserMessage(ctx, sessionID, content, attachmentParts)
 	if err != nil {
 		return a.err(fmt.Errorf("failed to create user message: %w", err))
 	}
+	a.detailedLogger.LogUserTurn(&detailed_logging.UserTurnLog{
+		ID:            userMsg.ID,
+		ChatSessionID: sessionID,
+		Time:          time.Now(),
+		Preview:       turnPreview(content),
+	})
 	// Append the new user message to the conversation history.
 	msgHistory := append(msgs, userMsg)
+	review := &selfReview{start: len(msgHistory)}
+	stopReported := false
 
 	for {
 		// Check for cancellation before each iteration
@@ -290,7 +404,17 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		default:
 			// Continue processing
 		}
-		agentMessage, toolResults, err := a.streamAndHandleEvents(ctx, sessionID, msgHistory)
+		agentMessage, toolResults, err := a.streamAndHandleEvents(ctx, sessionID, msgHistory, turnStart)
+		if err == nil && ctx.Err() != nil {
+			// Canceled while the tools ran, their results are saved
+			err = ctx.Err()
+		}
+		// Only the first request of the turn is waited for by the user
+		turnStart = time.Time{}
+		if err == nil && truncatedResponse(agentMessage) {
+			// Responses cut off by the output token limit are continued
+			agentMessage, toolResults, err = a.continueTruncated(ctx, sessionID, msgHistory, agentMessage)
+		}
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				agentMessage.AddFinish(message.FinishReasonCanceled)
@@ -309,8 +433,45 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		if (agentMessage.FinishReason() == message.FinishReasonToolUse) && toolResults != nil {
 			// We are not done, we need to respond with the tool response
 			msgHistory = append(msgHistory, agentMessage, *toolResults)
+			if a.isStopping(sessionID) {
+				if stopReported {
+					// The report called tools again, they were skipped
+					return AgentEvent{
+						Type:    AgentEventTypeResponse,
+						Message: agentMessage,
+						Done:    true,
+					}
+				}
+				// The user asked to stop, the agent reports where it stopped
+				stopMsg, err := a.createUserMessage(ctx, sessionID, stopReportPrompt, nil)
+				if err != nil {
+					return a.err(fmt.Errorf("failed to create stop message: %w", err))
+				}
+				msgHistory = append(msgHistory, stopMsg)
+				stopReported = true
+			}
 			continue
 		}
+		if agentMessage.FinishReason() == message.FinishReasonEndTurn {
+			if a.isStopping(sessionID) {
+				// A stopped request ends with the report of the agent
+				review.reviewed = true
+			}
+			if followUp := a.selfReviewFollowUp(ctx, review, msgHistory); followUp != "" {
+				reviewMsg, err := a.createUserMessage(ctx, sessionID, followUp, nil)
+				if err != nil {
+					return a.err(fmt.Errorf("failed to create self-review message: %w", err))
+				}
+				msgHistory = append(msgHistory, agentMessage, reviewMsg)
+				continue
+			}
+			if citations := extractCitations(agentMessage.Content().String(), readEvidence(msgHistory[review.start:])); len(citations) > 0 {
+				agentMessage.SetCitations(citations)
+				if err := a.messages.Update(ctx, agentMessage); err != nil {
+					logging.Warn("Failed to save citations", "error", err)
+				}
+			}
+		}
 		return AgentEvent{
 			Type:    AgentEventTypeResponse,
 			Message: agentMessage,
@@ -328,14 +489,21 @@ func (a *agent) createUserMessage(ctx context.Context, sessionID, content string
 	})
 }
 
-func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msgHistory []message.Message) (message.Message, *message.Message, error) {
+// streamAndHandleEvents sends a request of the turn and runs the tools it
+// calls. The time to the first token is published when turnStart is set.
+func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msgHistory []message.Message, turnStart time.Time) (message.Message, *message.Message, error) {
 	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
-	eventChan := a.provider.StreamResponse(ctx, msgHistory, a.tools)
+	generator := a.generator(ctx)
+	agentTools := prunedTools(generator.Model().ID, a.toolsFor(sessionID))
+	if err := a.quotas.Check(ctx, generator.Model().Provider); err != nil {
+		return message.Message{}, nil, err
+	}
+	eventChan := generator.StreamResponse(ctx, a.preflight(ctx, sessionID, withPinnedContext(sessionID, msgHistory)), agentTools)
 
 	assistantMsg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
 		Role:  message.Assistant,
-		Parts: []message.ContentPart{},
-		Model: a.provider.Model().ID,
+		Parts: routePart(ctx),
+		Model: generator.Model().ID,
 	})
 	if err != nil {
 		return assistantMsg, nil, fmt.Errorf("failed to create assistant message: %w", err)
@@ -346,7 +514,11 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 
 	// Process each event in the stream.
 	for event := range eventChan {
-		if processErr := a.processEvent(ctx, sessionID, &assistantMsg, event); processErr != nil {
+		if !turnStart.IsZero() && firstTokenEvent(event) {
+			a.publishFirstToken(sessionID, assistantMsg.ID, generator.Model().ID, time.Since(turnStart))
+			turnStart = time.Time{}
+		}
+		if processErr := a.processEvent(ctx, sessionID, generator.Model(), &assistantMsg, event); processErr != nil {
 			a.finishMessage(ctx, &assistantMsg, message.FinishReasonCanceled)
 			return assistantMsg, nil, processErr
 		}
@@ -358,12 +530,25 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 
 	toolResults := make([]message.ToolResult, len(assistantMsg.ToolCalls()))
 	toolCalls := assistantMsg.ToolCalls()
-	for i, toolCall := range toolCalls {
+	order := scheduleToolCalls(toolCalls)
+	for n, i := range order {
+		toolCall := toolCalls[i]
+		if a.isStopping(sessionID) {
+			// The user asked to stop after the tool that was running
+			for _, j := range order[n:] {
+				toolResults[j] = message.ToolResult{
+					ToolCallID: toolCalls[j].ID,
+					Content:    toolStoppedResult,
+					IsError:    true,
+				}
+			}
+			break
+		}
 		select {
 		case <-ctx.Done():
 			a.finishMessage(context.Background(), &assistantMsg, message.FinishReasonCanceled)
 			// Make all future tool calls cancelled
-			for j := i; j < len(toolCalls); j++ {
+			for _, j := range order[n:] {
 				toolResults[j] = message.ToolResult{
 					ToolCallID: toolCalls[j].ID,
 					Content:    "Tool execution canceled by user",
@@ -374,7 +559,7 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 		default:
 			// Continue processing
 			var tool tools.BaseTool
-			for _, availableTool := range a.tools {
+			for _, availableTool := range agentTools {
 				if availableTool.Info().Name == toolCall.Name {
 					tool = availableTool
 					break
@@ -396,11 +581,62 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 				}
 				continue
 			}
-			toolResult, toolErr := tool.Run(ctx, tools.ToolCall{
+			// Subagents may only run the tools they declare
+			if a.capabilities != nil && !a.capabilities.Allows(tool) {
+				toolResults[i] = message.ToolResult{
+					ToolCallID: toolCall.ID,
+					Content:    fmt.Sprintf("Tool %s is not allowed for this agent", toolCall.Name),
+					IsError:    true,
+				}
+				continue
+			}
+			// Arguments are checked against the tool schema, the model gets
+			// the problems it has to correct instead of a failed run
+			input, repairs, validationErr := tools.ValidateInput(tool.Info(), toolCall.Input)
+			var invalid *tools.ValidationError
+			if errors.As(validationErr, &invalid) {
+				response := invalid.Response()
+				toolResults[i] = message.ToolResult{
+					ToolCallID: toolCall.ID,
+					Content:    response.Content,
+					Metadata:   response.Metadata,
+					IsError:    true,
+				}
+				continue
+			}
+			if len(repairs) > 0 {
+				logging.Debug("Repaired tool call arguments", "tool", toolCall.Name, "repairs", repairs)
+				toolCall.Input = input
+			}
+			// Drafted risky calls need the approval of the agent model
+			if rejection := a.verifyToolCall(ctx, sessionID, msgHistory, assistantMsg, toolCall); rejection != "" {
+				toolResults[i] = message.ToolResult{
+					ToolCallID: toolCall.ID,
+					Content:    rejection,
+					IsError:    true,
+				}
+				continue
+			}
+			// Tools that failed too often recently are paused
+			var paused *tools.PausedError
+			if errors.As(a.budgets.Check(sessionID, toolCall.Name), &paused) {
+				response := paused.Response()
+				toolResults[i] = message.ToolResult{
+					ToolCallID: toolCall.ID,
+					Content:    response.Content,
+					Metadata:   response.Metadata,
+					IsError:    true,
+				}
+				continue
+			}
+			relayToolCall(ctx, toolCall)
+			started := time.Now()
+			toolResult, toolErr := tools.RunWithBudget(ctx, tool, tools.ToolCall{
 				ID:    toolCall.ID,
 				Name:  toolCall.Name,
 				Input: toolCall.Input,
 			})
+			a.logToolRun(sessionID, toolCall, started, toolResult, toolErr)
 			if toolErr != nil {
 				if errors.Is(toolErr, permission.ErrorPermissionDenied) {
 					toolResults[i] = message.ToolResult{
@@ -408,7 +644,7 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 						Content:    "Permission denied",
 						IsError:    true,
 					}
-					for j := i + 1; j < len(toolCalls); j++ {
+					for _, j := range order[n+1:] {
 						toolResults[j] = message.ToolResult{
 							ToolCallID: toolCalls[j].ID,
 							Content:    "Tool execution canceled by user",
@@ -418,7 +654,18 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 					a.finishMessage(ctx, &assistantMsg, message.FinishReasonPermissionDenied)
 					break
 				}
+				if ctx.Err() != nil {
+					// Interrupted by Cancel, the output is incomplete
+					toolResult = tools.NewTextErrorResponse("Tool execution canceled by user")
+				}
+			}
+			if ctx.Err() == nil {
+				failed := toolErr != nil || toolResult.IsError
+				if errors.As(a.budgets.Record(sessionID, toolCall.Name, failed), &paused) {
+					toolResult.Content += "\n\n<system-reminder>" + paused.Error() + "</system-reminder>"
+				}
 			}
+			toolResult = a.summarizeToolOutput(ctx, sessionID, toolCall, toolResult)
 			toolResults[i] = message.ToolResult{
 				ToolCallID: toolCall.ID,
 				Content:    toolResult.Content,
@@ -428,6 +675,8 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 		}
 	}
 out:
+	// The tasks of this message are done, their findings are in the results
+	tools.ClearBlackboard(assistantMsg.ID)
 	if len(toolResults) == 0 {
 		return assistantMsg, nil, nil
 	}
@@ -446,12 +695,74 @@ out:
 	return assistantMsg, &msg, err
 }
 
+// withPinnedContext prepends the session's pinned context to the history sent
+// to the provider. The pinned message is never persisted.
+func withPinnedContext(sessionID string, msgHistory []message.Message) []message.Message {
+	pinned := prompt.PinnedContext(sessionID)
+	if pinned == "" {
+		return msgHistory
+	}
+	pinnedMsg := message.Message{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: pinned}},
+	}
+	return append([]message.Message{pinnedMsg}, msgHistory...)
+}
+
+// fileUpdatesNote tells the agent how the files it read and the pinned files
+// changed on disk since its last turn, so it doesn't work from stale content
+func fileUpdatesNote(sessionID string) string {
+	var pinned []string
+	for _, item := range prompt.ListPins(sessionID) {
+		if item.Kind == prompt.PinKindFile {
+			pinned = append(pinned, item.Path)
+		}
+	}
+	return tools.FileUpdatesNote(tools.FileUpdates(sessionID, pinned))
+}
+
+// turnPreviewLength is the length of the user turns kept in detailed logs
+const turnPreviewLength = 80
+
+func turnPreview(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if runes := []rune(content); len(runes) > turnPreviewLength {
+		return string(runes[:turnPreviewLength-1]) + "…"
+	}
+	return content
+}
+
+// logToolRun records a tool run of a chat session in the detailed logs
+func (a *agent) logToolRun(sessionID string, call message.ToolCall, started time.Time, result tools.ToolResponse, err error) {
+	if !a.detailedLogger.IsEnabled() {
+		return
+	}
+	ended := time.Now()
+	log := &detailed_logging.ToolCallLog{
+		ID:            detailed_logging.NewID(),
+		ChatSessionID: sessionID,
+		Name:          call.Name,
+		StartTime:     started,
+		EndTime:       &ended,
+		Input:         map[string]interface{}{"input": call.Input},
+		Output:        result.Content,
+		DurationMs:    ended.Sub(started).Milliseconds(),
+	}
+	switch {
+	case err != nil:
+		log.Error = err.Error()
+	case result.IsError:
+		log.Error = result.Content
+	}
+	a.detailedLogger.LogToolCall(log)
+}
+
 func (a *agent) finishMessage(ctx context.Context, msg *message.Message, finishReson message.FinishReason) {
 	msg.AddFinish(finishReson)
 	_ = a.messages.Update(ctx, *msg)
 }
 
-func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg *message.Message, event provider.ProviderEvent) error {
+func (a *agent) processEvent(ctx context.Context, sessionID string, model models.Model, assistantMsg *message.Message, event provider.ProviderEvent) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -465,6 +776,7 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 		return a.messages.Update(ctx, *assistantMsg)
 	case provider.EventContentDelta:
 		assistantMsg.AppendContent(event.Content)
+		relayContent(ctx, assistantMsg.Content().Text)
 		return a.messages.Update(ctx, *assistantMsg)
 	case provider.EventToolUseStart:
 		assistantMsg.AddToolCall(*event.ToolCall)
@@ -491,30 +803,48 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 	case provider.EventComplete:
 		assistantMsg.SetToolCalls(event.Response.ToolCalls)
 		assistantMsg.AddFinish(event.Response.FinishReason)
+		usage := event.Response.Usage
+		assistantMsg.SetUsage(message.Usage{
+			InputTokens:         usage.InputTokens,
+			OutputTokens:        usage.OutputTokens,
+			CacheCreationTokens: usage.CacheCreationTokens,
+			CacheReadTokens:     usage.CacheReadTokens,
+			Cost:                usageCost(model, usage),
+		})
 		if err := a.messages.Update(ctx, *assistantMsg); err != nil {
 			return fmt.Errorf("failed to update message: %w", err)
 		}
-		return a.TrackUsage(ctx, sessionID, a.provider.Model(), event.Response.Usage)
+		return a.TrackUsage(ctx, sessionID, model, event.Response.Usage)
 	}
 
 	return nil
 }
 
+// usageCost returns the cost of the tokens of a request
+func usageCost(model models.Model, usage provider.TokenUsage) float64 {
+	return model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
+		model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
+		model.CostPer1MIn/1e6*float64(usage.InputTokens) +
+		model.CostPer1MOut/1e6*float64(usage.OutputTokens)
+}
+
 func (a *agent) TrackUsage(ctx context.Context, sessionID string, model models.Model, usage provider.TokenUsage) error {
 	sess, err := a.sessions.Get(ctx, sessionID)
 	if err != nil {
 		return fmt.Errorf("failed to get session: %w", err)
 	}
 
-	cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-		model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-		model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-		model.CostPer1MOut/1e6*float64(usage.OutputTokens)
+	cost := usageCost(model, usage)
+	recordTaskUsage(sessionID, cost, usage.InputTokens+usage.OutputTokens+usage.CacheCreationTokens+usage.CacheReadTokens)
 
 	sess.Cost += cost
 	sess.CompletionTokens = usage.OutputTokens + usage.CacheReadTokens
 	sess.PromptTokens = usage.InputTokens + usage.CacheCreationTokens
 
+	if err := a.quotas.Record(ctx, model.Provider, sess.PromptTokens, sess.CompletionTokens, cost); err != nil {
+		logging.Warn("failed to record provider usage", "provider", model.Provider, "error", err)
+	}
+
 	_, err = a.sessions.Save(ctx, sess)
 	if err != nil {
 		return fmt.Errorf("failed to save session: %w", err)
@@ -537,6 +867,10 @@ func (a *agent) Update(agentName config.AgentName, modelID models.ModelID) (mode
 	}
 
 	a.provider = provider
+	a.systemTokensMu.Lock()
+	a.systemTokens = 0
+	a.systemTokensMu.Unlock()
+	go a.Warmup(context.Background())
 
 	return a.provider.Model(), nil
 }
@@ -682,13 +1016,7 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 		oldSession.SummaryMessageID = msg.ID
 		oldSession.CompletionTokens = response.Usage.OutputTokens
 		oldSession.PromptTokens = 0
-		model := a.summarizeProvider.Model()
-		usage := response.Usage
-		cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-			model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-			model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-			model.CostPer1MOut/1e6*float64(usage.OutputTokens)
-		oldSession.Cost += cost
+		oldSession.Cost += usageCost(a.summarizeProvider.Model(), response.Usage)
 		_, err = a.sessions.Save(summarizeCtx, oldSession)
 		if err != nil {
 			event = AgentEvent{
@@ -712,15 +1040,44 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 	return nil
 }
 
+// Complete sends a single prompt to the model of an agent, without tools and
+// outside of any session
+func Complete(ctx context.Context, agentName config.AgentName, content string) (string, error) {
+	agentProvider, err := createAgentProvider(agentName, nil)
+	if err != nil {
+		return "", err
+	}
+	response, err := agentProvider.SendMessages(ctx, []message.Message{
+		{
+			Role:  message.User,
+			Parts: []message.ContentPart{message.TextContent{Text: content}},
+		},
+	}, make([]tools.BaseTool, 0))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response.Content), nil
+}
+
 func createAgentProvider(agentName config.AgentName, detailedLogger *detailed_logging.DetailedLogger) (provider.Provider, error) {
+	agentConfig, ok := config.Get().Agents[agentName]
+	if !ok {
+		return nil, fmt.Errorf("agent %s not found", agentName)
+	}
+	return createProvider(agentName, agentConfig.Model, detailedLogger)
+}
+
+// createProvider creates the provider of an agent using modelID instead of the
+// model configured for the agent
+func createProvider(agentName config.AgentName, modelID models.ModelID, detailedLogger *detailed_logging.DetailedLogger) (provider.Provider, error) {
 	cfg := config.Get()
 	agentConfig, ok := cfg.Agents[agentName]
 	if !ok {
 		return nil, fmt.Errorf("agent %s not found", agentName)
 	}
-	model, ok := models.SupportedModels[agentConfig.Model]
+	model, ok := models.SupportedModels[modelID]
 	if !ok {
-		return nil, fmt.Errorf("model %s not supported", agentConfig.Model)
+		return nil, fmt.Errorf("model %s not supported", modelID)
 	}
 
 	providerCfg, ok := cfg.Providers[model.Provider]
@@ -781,6 +1138,16 @@ func createAgentProvider(agentName config.AgentName, detailedLogger *detailed_lo
 		return nil, fmt.Errorf("could not create provider: %v", err)
 	}
 
+	// Replay identical requests from the response cache if enabled
+	if cfg.ResponseCache.Enabled {
+		agentProvider = provider.NewCachingProvider(
+			agentProvider,
+			prompt.GetAgentPrompt(agentName, model.Provider),
+			filepath.Join(cfg.Data.Directory, "cache", "responses"),
+			time.Duration(cfg.ResponseCache.TTLHours)*time.Hour,
+		)
+	}
+
 	// Wrap with detailed logging if enabled
 	if detailedLogger != nil {
 		agentProvider = detailed_logging.NewLoggingProvider(agentProvider, string(model.Provider), detailedLogger)
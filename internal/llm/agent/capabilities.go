@@ -0,0 +1,251 @@
+package agent
+
+import (
+	"context"
+	"path"
+	"slices"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/history"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/lsp"
+	"github.com/kirmad/superopencode/internal/permission"
+)
+
+// SubagentKind names a kind of subagent
+type SubagentKind string
+
+const (
+	SubagentTask      SubagentKind = "task"
+	SubagentResearch  SubagentKind = "research"
+	SubagentCoding    SubagentKind = "coding"
+	SubagentAnalysis  SubagentKind = "analysis"
+	SubagentReview    SubagentKind = "review"
+	SubagentSecurity  SubagentKind = "security"
+	SubagentMigration SubagentKind = "migration"
+)
+
+// SubagentCapabilities declares what a subagent may do. Tools is the single
+// source of truth for the tools the subagent receives and may run.
+type SubagentCapabilities struct {
+	Description string
+	Tools       []string
+	MCP         bool // Whether the tools of the MCP servers are included
+}
+
+var subagentCapabilities = map[SubagentKind]SubagentCapabilities{
+	SubagentTask: {
+		Description: "Read-only search of the codebase",
+		Tools: []string{
+			tools.GlobToolName,
+			tools.GrepToolName,
+			tools.LSToolName,
+			tools.SourcegraphToolName,
+			tools.ViewToolName,
+			tools.BlackboardReadToolName,
+			tools.BlackboardWriteToolName,
+			tools.GitHistoryToolName,
+		},
+	},
+	SubagentResearch: {
+		Description: "Read-only research of the codebase and the web",
+		Tools: []string{
+			tools.ViewToolName,
+			tools.GrepToolName,
+			tools.GlobToolName,
+			tools.SourcegraphToolName,
+			tools.FetchToolName,
+			tools.LSToolName,
+			tools.TodoReadToolName,
+			tools.TodoWriteToolName,
+			tools.GitHistoryToolName,
+			tools.KnowledgeSearchToolName,
+		},
+		MCP: true,
+	},
+	SubagentCoding: {
+		Description: "Changes to the code",
+		Tools: []string{
+			tools.ViewToolName,
+			tools.WriteToolName,
+			tools.EditToolName,
+			tools.BashToolName,
+			tools.GrepToolName,
+			tools.GlobToolName,
+			tools.PatchToolName,
+			tools.LSToolName,
+			tools.TodoReadToolName,
+			tools.TodoWriteToolName,
+			tools.DiagnosticsToolName,
+			tools.ProfileToolName,
+		},
+		MCP: true,
+	},
+	SubagentAnalysis: {
+		Description: "Analysis of code and data",
+		Tools: []string{
+			tools.ViewToolName,
+			tools.GrepToolName,
+			tools.GlobToolName,
+			tools.BashToolName,
+			tools.LSToolName,
+			tools.SourcegraphToolName,
+			tools.TodoReadToolName,
+			tools.TodoWriteToolName,
+			tools.FetchToolName,
+			tools.ProfileToolName,
+			tools.GitHistoryToolName,
+		},
+		MCP: true,
+	},
+	SubagentReview: {
+		Description: "Read-only review of pending changes",
+		Tools: []string{
+			tools.ViewToolName,
+			tools.GrepToolName,
+			tools.GlobToolName,
+			tools.LSToolName,
+			tools.DiagnosticsToolName,
+			tools.GitHistoryToolName,
+		},
+	},
+	SubagentSecurity: {
+		Description: "Read-only security audit with SAST scanners",
+		Tools: []string{
+			tools.SastScanToolName,
+			tools.DependencyAuditToolName,
+			tools.ViewToolName,
+			tools.GrepToolName,
+			tools.GlobToolName,
+			tools.LSToolName,
+			tools.DiagnosticsToolName,
+		},
+	},
+	SubagentMigration: {
+		// No bash: the shell is shared, while migrations run in parallel in
+		// their own worktrees
+		Description: "Changes to a batch of files in an isolated worktree",
+		Tools: []string{
+			tools.ViewToolName,
+			tools.EditToolName,
+			tools.WriteToolName,
+			tools.PatchToolName,
+			tools.GrepToolName,
+			tools.GlobToolName,
+			tools.LSToolName,
+		},
+	},
+}
+
+// agentSubagents maps the agents that run as subagents to their kind
+var agentSubagents = map[config.AgentName]SubagentKind{
+	config.AgentTask:     SubagentTask,
+	config.AgentReviewer: SubagentReview,
+	config.AgentSecurity: SubagentSecurity,
+	config.AgentMigrator: SubagentMigration,
+}
+
+// Capabilities returns the capabilities declared for a subagent kind
+func Capabilities(kind SubagentKind) (SubagentCapabilities, bool) {
+	caps, ok := subagentCapabilities[kind]
+	return caps, ok
+}
+
+// Allows reports whether the subagent may run tool
+func (c SubagentCapabilities) Allows(tool tools.BaseTool) bool {
+	if slices.Contains(c.Tools, tool.Info().Name) {
+		return true
+	}
+	_, isMCP := tool.(*mcpTool)
+	return c.MCP && isMCP
+}
+
+// filter drops the tools a subagent does not declare
+func (c SubagentCapabilities) filter(agentName config.AgentName, agentTools []tools.BaseTool) []tools.BaseTool {
+	allowed := make([]tools.BaseTool, 0, len(agentTools))
+	for _, tool := range agentTools {
+		if !c.Allows(tool) {
+			logging.Error("dropping tool not declared in the subagent capabilities", "agent", agentName, "tool", tool.Info().Name)
+			continue
+		}
+		allowed = append(allowed, tool)
+	}
+	return allowed
+}
+
+// filterAllowedTools leaves out the tools that don't match the allowed tool
+// patterns of the config, e.g. "view" or "github_*". All tools are allowed
+// without patterns.
+func filterAllowedTools(agentName config.AgentName, agentTools []tools.BaseTool, patterns []string) []tools.BaseTool {
+	if len(patterns) == 0 {
+		return agentTools
+	}
+	allowed := make([]tools.BaseTool, 0, len(agentTools))
+	for _, tool := range agentTools {
+		name := tool.Info().Name
+		if matchesAnyPattern(patterns, name) {
+			allowed = append(allowed, tool)
+			continue
+		}
+		logging.Debug("Leaving out tool not in allowedTools", "agent", agentName, "tool", name)
+	}
+	return allowed
+}
+
+// matchesAnyPattern reports whether name matches one of the glob patterns
+func matchesAnyPattern(patterns []string, name string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	})
+}
+
+// subagentTools builds the tools declared for a subagent kind. Tools that need
+// a missing dependency, like diagnostics without language servers or the docs
+// search without knowledge sources, are left out.
+func subagentTools(
+	kind SubagentKind,
+	permissions permission.Service,
+	history history.Service,
+	lspClients map[string]*lsp.Client,
+) []tools.BaseTool {
+	caps := subagentCapabilities[kind]
+	constructors := map[string]func() tools.BaseTool{
+		tools.BashToolName:            func() tools.BaseTool { return tools.NewBashTool(permissions) },
+		tools.BlackboardReadToolName:  tools.NewBlackboardReadTool,
+		tools.BlackboardWriteToolName: tools.NewBlackboardWriteTool,
+		tools.DependencyAuditToolName: tools.NewDependencyAuditTool,
+		tools.EditToolName:            func() tools.BaseTool { return tools.NewEditTool(lspClients, permissions, history) },
+		tools.FetchToolName:           func() tools.BaseTool { return tools.NewFetchTool(permissions) },
+		tools.GitHistoryToolName:      tools.NewGitHistoryTool,
+		tools.GlobToolName:            tools.NewGlobTool,
+		tools.GrepToolName:            tools.NewGrepTool,
+		tools.LSToolName:              tools.NewLsTool,
+		tools.PatchToolName:           func() tools.BaseTool { return tools.NewPatchTool(lspClients, permissions, history) },
+		tools.SastScanToolName:        tools.NewSastScanTool,
+		tools.ProfileToolName:         func() tools.BaseTool { return tools.NewProfileTool(permissions) },
+		tools.SourcegraphToolName:     tools.NewSourcegraphTool,
+		tools.TodoReadToolName:        func() tools.BaseTool { return tools.NewTodoReadTool() },
+		tools.TodoWriteToolName:       func() tools.BaseTool { return tools.NewTodoWriteTool() },
+		tools.ViewToolName:            func() tools.BaseTool { return tools.NewViewTool(lspClients) },
+		tools.WriteToolName:           func() tools.BaseTool { return tools.NewWriteTool(lspClients, permissions, history) },
+	}
+	if len(config.Get().Knowledge.Sources) > 0 {
+		constructors[tools.KnowledgeSearchToolName] = tools.NewKnowledgeSearchTool
+	}
+	if len(lspClients) > 0 {
+		constructors[tools.DiagnosticsToolName] = func() tools.BaseTool { return tools.NewDiagnosticsTool(lspClients) }
+	}
+
+	var subagentTools []tools.BaseTool
+	for _, name := range caps.Tools {
+		if constructor, ok := constructors[name]; ok {
+			subagentTools = append(subagentTools, constructor())
+		}
+	}
+	if caps.MCP {
+		subagentTools = append(subagentTools, GetMcpTools(context.Background(), permissions)...)
+	}
+	return subagentTools
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/lsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubagentToolsMatchCapabilities(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	config.Get().Knowledge.Sources = map[string]config.KnowledgeSource{
+		"docs": {Type: config.KnowledgeSite, URL: "https://docs.example.com/"},
+	}
+
+	lspClients := map[string]*lsp.Client{"go": nil}
+	for kind, caps := range subagentCapabilities {
+		t.Run(string(kind), func(t *testing.T) {
+			var names []string
+			for _, tool := range subagentTools(kind, nil, nil, lspClients) {
+				assert.True(t, caps.Allows(tool), "%s received undeclared tool %s", kind, tool.Info().Name)
+				names = append(names, tool.Info().Name)
+			}
+			// Every declared tool can be built
+			assert.ElementsMatch(t, caps.Tools, names)
+		})
+	}
+}
+
+func TestCapabilitiesFilter(t *testing.T) {
+	caps := subagentCapabilities[SubagentTask]
+	filtered := caps.filter(config.AgentTask, []tools.BaseTool{
+		tools.NewGlobTool(),
+		tools.NewBashTool(nil),
+	})
+	require.Len(t, filtered, 1)
+	assert.Equal(t, tools.GlobToolName, filtered[0].Info().Name)
+}
+
+func TestFilterAllowedTools(t *testing.T) {
+	all := []tools.BaseTool{tools.NewGlobTool(), tools.NewGrepTool(), tools.NewLsTool()}
+	assert.Equal(t, all, filterAllowedTools(config.AgentCoder, all, nil))
+
+	filtered := filterAllowedTools(config.AgentCoder, all, []string{"g*", "missing"})
+	require.Len(t, filtered, 2)
+	assert.Equal(t, tools.GlobToolName, filtered[0].Info().Name)
+	assert.Equal(t, tools.GrepToolName, filtered[1].Info().Name)
+}
+
+func TestReviewerAgentToolsReadOnly(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+
+	assert.Equal(t, SubagentReview, agentSubagents[config.AgentReviewer])
+	var names []string
+	for _, tool := range ReviewerAgentTools(nil) {
+		names = append(names, tool.Info().Name)
+	}
+	assert.Contains(t, names, tools.ViewToolName)
+	assert.NotContains(t, names, tools.DiagnosticsToolName, "diagnostics need an LSP client")
+	for _, name := range []string{tools.BashToolName, tools.EditToolName, tools.WriteToolName, tools.PatchToolName, AgentToolName} {
+		assert.NotContains(t, names, name, "the reviewer only reads")
+	}
+}
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+// maxCitations limits the references attached to a single response
+const maxCitations = 20
+
+// citationPattern matches references like internal/app/app.go:42 or
+// main.go:10-20. The path must contain an extension so times and ports
+// are not mistaken for references.
+var citationPattern = regexp.MustCompile(`([\w./\\-]*\w\.\w+):(\d+)(?:-(\d+))?`)
+
+// lineRange is a range of lines the agent has seen in a file
+type lineRange struct {
+	start, end int
+}
+
+// readEvidence collects the lines the agent read or matched through the view
+// and grep tools, keyed by absolute path
+func readEvidence(msgs []message.Message) map[string][]lineRange {
+	calls := make(map[string]message.ToolCall)
+	evidence := make(map[string][]lineRange)
+	for _, msg := range msgs {
+		for _, call := range msg.ToolCalls() {
+			calls[call.ID] = call
+		}
+		for _, result := range msg.ToolResults() {
+			call, ok := calls[result.ToolCallID]
+			if !ok || result.IsError {
+				continue
+			}
+			switch call.Name {
+			case tools.ViewToolName:
+				var params tools.ViewParams
+				if err := json.Unmarshal([]byte(call.Input), &params); err != nil || params.FilePath == "" {
+					continue
+				}
+				path := absPath(params.FilePath)
+				// The metadata has the lines actually read, e.g. of a symbol
+				var meta tools.ViewResponseMetadata
+				if json.Unmarshal([]byte(result.Metadata), &meta) == nil && meta.EndLine > 0 {
+					evidence[path] = append(evidence[path], lineRange{meta.StartLine, meta.EndLine})
+					continue
+				}
+				limit := params.Limit
+				if limit <= 0 {
+					limit = tools.DefaultReadLimit
+				}
+				evidence[path] = append(evidence[path], lineRange{params.Offset + 1, params.Offset + limit})
+			case tools.GrepToolName:
+				path := ""
+				for _, line := range strings.Split(result.Content, "\n") {
+					if rest, ok := strings.CutPrefix(line, "  Line "); ok && path != "" {
+						num, _, _ := strings.Cut(rest, ":")
+						if n, err := strconv.Atoi(num); err == nil {
+							evidence[path] = append(evidence[path], lineRange{n, n})
+						}
+					} else if strings.HasSuffix(line, ":") && !strings.HasPrefix(line, " ") {
+						path = absPath(strings.TrimSuffix(line, ":"))
+					}
+				}
+			}
+		}
+	}
+	return evidence
+}
+
+// extractCitations finds the file references in a response. References to
+// lines covered by the evidence are verified, references to other existing
+// files are kept unverified and references to missing files are dropped.
+func extractCitations(text string, evidence map[string][]lineRange) []message.Citation {
+	var citations []message.Citation
+	seen := make(map[string]bool)
+	for _, match := range citationPattern.FindAllStringSubmatch(text, -1) {
+		if len(citations) >= maxCitations {
+			break
+		}
+		if seen[match[0]] {
+			continue
+		}
+		seen[match[0]] = true
+
+		line, err := strconv.Atoi(match[2])
+		if err != nil || line == 0 {
+			continue
+		}
+		endLine := 0
+		if match[3] != "" {
+			if endLine, err = strconv.Atoi(match[3]); err != nil || endLine < line {
+				endLine = 0
+			}
+		}
+
+		path := absPath(match[1])
+		ranges, read := evidence[path]
+		if !read {
+			if info, err := os.Stat(path); err != nil || info.IsDir() {
+				continue
+			}
+		}
+		citations = append(citations, message.Citation{
+			Path:     path,
+			Line:     line,
+			EndLine:  endLine,
+			Verified: covers(ranges, line, max(line, endLine)),
+		})
+	}
+	return citations
+}
+
+func covers(ranges []lineRange, start, end int) bool {
+	for _, r := range ranges {
+		if r.start <= start && end <= r.end {
+			return true
+		}
+	}
+	// Grep matches are single lines, a range is verified if both ends were seen
+	if start != end {
+		return covers(ranges, start, start) && covers(ranges, end, end)
+	}
+	return false
+}
+
+func absPath(path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(config.WorkingDirectory(), path)
+	}
+	return filepath.Clean(path)
+}
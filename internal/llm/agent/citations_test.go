@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractCitations(t *testing.T) {
+	dir := t.TempDir()
+	app := filepath.Join(dir, "app.go")
+	util := filepath.Join(dir, "util.go")
+	other := filepath.Join(dir, "other.go")
+	for _, path := range []string{app, util, other} {
+		require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+	}
+
+	msgs := []message.Message{
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			message.ToolCall{ID: "1", Name: tools.ViewToolName, Input: `{"file_path":"` + app + `","offset":10,"limit":20}`},
+			message.ToolCall{ID: "2", Name: tools.GrepToolName, Input: `{"pattern":"func"}`},
+			message.ToolCall{ID: "3", Name: tools.ViewToolName, Input: `{"file_path":"` + other + `"}`},
+		}},
+		{Role: message.Tool, Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: "1", Content: "file content"},
+			message.ToolResult{ToolCallID: "2", Content: "Found 2 matches\n" + util + ":\n  Line 7: func a() {}\n  Line 9: func b() {}\n"},
+			message.ToolResult{ToolCallID: "3", Content: "File not found", IsError: true},
+		}},
+	}
+
+	text := "See " + app + ":12-15, " + util + ":7 and " + util + ":8.\n" +
+		"Also " + other + ":3, " + app + ":12-15 again and " + filepath.Join(dir, "missing.go") + ":1."
+	citations := extractCitations(text, readEvidence(msgs))
+
+	assert.Equal(t, []message.Citation{
+		{Path: app, Line: 12, EndLine: 15, Verified: true},
+		{Path: util, Line: 7, Verified: true},
+		{Path: util, Line: 8},
+		{Path: other, Line: 3},
+	}, citations)
+}
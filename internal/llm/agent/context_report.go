@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/llm/prompt"
+	"github.com/kirmad/superopencode/internal/llm/tokenizer"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+// droppedToolOutput replaces the tool outputs the user dropped from the
+// context
+const droppedToolOutput = "[tool output dropped from the context by the user]"
+
+// ContextItemKind is the part of the prompt a context item belongs to
+type ContextItemKind string
+
+const (
+	ContextSystem       ContextItemKind = "system"       // Instructions of the agent
+	ContextProject      ContextItemKind = "project"      // Project instruction and memory files
+	ContextInstructions ContextItemKind = "instructions" // Session instructions set with /system
+	ContextTool         ContextItemKind = "tool"         // Tool definitions
+	ContextPin          ContextItemKind = "pin"
+	ContextSummary      ContextItemKind = "summary" // Summary of the conversation before it
+	ContextMessage      ContextItemKind = "message"
+	ContextToolOutput   ContextItemKind = "tool-output"
+)
+
+// ContextItem is a part of the prompt sent on the next turn of a session
+type ContextItem struct {
+	Kind   ContextItemKind
+	Label  string
+	Tokens int64
+	Ref    string // Identifies the item for DropContext, empty when it can't be dropped
+}
+
+// ContextReport lists what the next turn of a session sends to the model
+type ContextReport struct {
+	SessionID string
+	Model     models.Model
+	Items     []ContextItem
+	Total     int64 // Estimated tokens of the items
+	Reserved  int64 // Tokens reserved for the response
+}
+
+// InspectContext estimates what the next turn of a session sends to the
+// model, item by item. Notes on files changed since the last turn are
+// added when the turn starts and aren't included.
+func (a *agent) InspectContext(ctx context.Context, sessionID string) (ContextReport, error) {
+	sess, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return ContextReport{}, fmt.Errorf("failed to get session: %w", err)
+	}
+	model := a.provider.Model()
+	tk := tokenizer.ForModel(model)
+	report := ContextReport{SessionID: sessionID, Model: model, Reserved: a.maxTokens()}
+	add := func(item ContextItem) {
+		report.Items = append(report.Items, item)
+		report.Total += item.Tokens
+	}
+
+	system := tk.Count(prompt.GetAgentPrompt(a.name, model.Provider))
+	var project []ContextItem
+	for _, file := range prompt.ProjectContextFiles() {
+		item := ContextItem{Kind: ContextProject, Label: file.Path, Tokens: tk.Count(file.Content)}
+		system -= item.Tokens
+		project = append(project, item)
+	}
+	add(ContextItem{Kind: ContextSystem, Label: "Instructions of the " + string(a.name) + " agent", Tokens: max(0, system)})
+	for _, item := range project {
+		add(item)
+	}
+	if sess.SystemPrompt != "" {
+		add(ContextItem{Kind: ContextInstructions, Label: "Session instructions", Tokens: tk.Count(sess.SystemPrompt), Ref: "instructions"})
+	}
+	if language := responseLanguage(ctx, sess); language != "" {
+		add(ContextItem{Kind: ContextInstructions, Label: "Response language: " + prompt.LanguageName(language), Tokens: tk.Count(prompt.ResponseLanguagePrompt(language))})
+	}
+
+	for _, tool := range a.toolsFor(sessionID) {
+		name := tool.Info().Name
+		add(ContextItem{Kind: ContextTool, Label: name, Tokens: tokenizer.CountTools(tk, []tools.BaseTool{tool}), Ref: "tool:" + name})
+	}
+
+	for _, pin := range prompt.ListPins(sessionID) {
+		add(ContextItem{Kind: ContextPin, Label: fmt.Sprintf("#%d %s", pin.ID, pin.Label()), Tokens: tk.Count(pin.Content), Ref: "pin:" + strconv.Itoa(pin.ID)})
+	}
+
+	msgs, err := a.messages.List(ctx, sessionID)
+	if err != nil {
+		return ContextReport{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	if i := slices.IndexFunc(msgs, func(msg message.Message) bool { return msg.ID == sess.SummaryMessageID }); i >= 0 {
+		add(ContextItem{Kind: ContextSummary, Label: fmt.Sprintf("Summary of %d earlier messages", i), Tokens: tokenizer.CountMessages(tk, msgs[i:i+1])})
+		msgs = msgs[i+1:]
+	}
+	for _, msg := range msgs {
+		switch msg.Role {
+		case message.Tool:
+			for _, result := range msg.ToolResults() {
+				item := ContextItem{
+					Kind:   ContextToolOutput,
+					Label:  fmt.Sprintf("%s output: %s", result.Name, contextPreview(result.Content)),
+					Tokens: tokenizer.CountMessages(tk, []message.Message{{Parts: []message.ContentPart{result}}}),
+				}
+				if result.Content != droppedToolOutput {
+					item.Ref = "output:" + msg.ID + ":" + result.ToolCallID
+				}
+				add(item)
+			}
+		default:
+			text := msg.Content().String()
+			if text == "" {
+				var names []string
+				for _, call := range msg.ToolCalls() {
+					names = append(names, call.Name)
+				}
+				text = "calls " + strings.Join(names, ", ")
+			}
+			label := "User: "
+			if msg.Role == message.Assistant {
+				label = "Assistant: "
+			}
+			add(ContextItem{Kind: ContextMessage, Label: label + contextPreview(text), Tokens: tokenizer.CountMessages(tk, []message.Message{msg}), Ref: "message:" + msg.ID})
+		}
+	}
+	return report, nil
+}
+
+// DropContext removes an item of the context of a session for the next
+// turns: it unpins a pin, clears the session instructions, disables a tool
+// for the session, replaces a tool output with a placeholder or forgets a
+// message
+func (a *agent) DropContext(ctx context.Context, sessionID, ref string) error {
+	if a.IsSessionBusy(sessionID) {
+		return errors.New("wait for the agent to finish before changing the context")
+	}
+	kind, arg, _ := strings.Cut(ref, ":")
+	switch kind {
+	case "pin":
+		_, err := prompt.Unpin(sessionID, "#"+arg)
+		return err
+	case "instructions":
+		sess, err := a.sessions.Get(ctx, sessionID)
+		if err != nil {
+			return err
+		}
+		sess.SystemPrompt = ""
+		_, err = a.sessions.Save(ctx, sess)
+		return err
+	case "tool":
+		var names []string
+		for _, tool := range a.toolsFor(sessionID) {
+			if name := tool.Info().Name; name != arg {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return errors.New("the last tool of a session can't be dropped")
+		}
+		SetSessionTools(sessionID, names)
+		return nil
+	case "output":
+		messageID, callID, _ := strings.Cut(arg, ":")
+		msg, err := a.messages.Get(ctx, messageID)
+		if err != nil {
+			return err
+		}
+		if msg.SessionID != sessionID {
+			return fmt.Errorf("message %s is not part of the session", messageID)
+		}
+		for i, part := range msg.Parts {
+			if result, ok := part.(message.ToolResult); ok && result.ToolCallID == callID {
+				result.Content = droppedToolOutput
+				msg.Parts[i] = result
+				return a.messages.Update(ctx, msg)
+			}
+		}
+		return fmt.Errorf("no tool output %s in message %s", callID, messageID)
+	case "message":
+		return a.ForgetMessage(ctx, sessionID, arg)
+	default:
+		return fmt.Errorf("can't drop %q", ref)
+	}
+}
+
+// contextPreview returns the start of a text on a single line
+func contextPreview(text string) string {
+	const maxPreview = 60
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > maxPreview {
+		text = string(runes[:maxPreview-3]) + "..."
+	}
+	return text
+}
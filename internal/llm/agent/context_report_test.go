@@ -0,0 +1,13 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextPreview(t *testing.T) {
+	assert.Equal(t, "Fix the flaky test in the permission service", contextPreview("Fix the  flaky test\nin the permission service\n"))
+	assert.Equal(t, "ééééééééééééééééééééééééééééééééééééééééééééééééééééééééé...", contextPreview(strings.Repeat("é", 80)))
+}
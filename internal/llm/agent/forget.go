@@ -0,0 +1,250 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/detailed_logging"
+	"github.com/kirmad/superopencode/internal/llm/prompt"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+// ForgetMessage deletes a message from a session and its copies in the
+// detailed logs, the next turns are sent without it. The outputs answering
+// the tool calls of an assistant message are deleted with it. A tool message
+// alone would leave its calls unanswered, so its outputs are redacted
+// instead. The full outputs saved for summarized tool calls are deleted.
+func (a *agent) ForgetMessage(ctx context.Context, sessionID, messageID string) error {
+	if a.IsSessionBusy(sessionID) {
+		return errors.New("wait for the agent to finish before forgetting a message")
+	}
+	msgs, err := a.messages.List(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to list messages: %w", err)
+	}
+	i := slices.IndexFunc(msgs, func(msg message.Message) bool { return msg.ID == messageID })
+	if i < 0 {
+		return fmt.Errorf("message %s is not part of the session", messageID)
+	}
+	msg := msgs[i]
+	redaction := detailed_logging.Redaction{Texts: partTexts(msg.Parts), MessageIDs: []string{msg.ID}}
+
+	if msg.Role == message.Tool {
+		var callIDs []string
+		for j, part := range msg.Parts {
+			if result, ok := part.(message.ToolResult); ok {
+				result.Content = detailed_logging.Redacted
+				result.Metadata = ""
+				msg.Parts[j] = result
+				callIDs = append(callIDs, result.ToolCallID)
+			}
+		}
+		if err := a.messages.Update(ctx, msg); err != nil {
+			return err
+		}
+		if err := deleteToolOutputs(sessionID, callIDs); err != nil {
+			return err
+		}
+		return a.redactLogs(redaction)
+	}
+
+	forgotten := []message.Message{msg}
+	var callIDs []string
+	for _, call := range msg.ToolCalls() {
+		callIDs = append(callIDs, call.ID)
+	}
+	for _, next := range msgs[i+1:] {
+		if next.Role != message.Tool {
+			continue
+		}
+		if slices.ContainsFunc(next.ToolResults(), func(result message.ToolResult) bool {
+			return slices.Contains(callIDs, result.ToolCallID)
+		}) {
+			forgotten = append(forgotten, next)
+		}
+	}
+	for _, msg := range forgotten {
+		if err := a.messages.Delete(ctx, msg.ID); err != nil {
+			return fmt.Errorf("failed to delete message: %w", err)
+		}
+		if msg.ID != messageID {
+			redaction.Texts = append(redaction.Texts, partTexts(msg.Parts)...)
+			redaction.MessageIDs = append(redaction.MessageIDs, msg.ID)
+		}
+	}
+
+	if err := deleteToolOutputs(sessionID, callIDs); err != nil {
+		return err
+	}
+
+	sess, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if sess.SummaryMessageID == messageID {
+		// The earlier messages are sent again in full
+		sess.SummaryMessageID = ""
+		if _, err := a.sessions.Save(ctx, sess); err != nil {
+			return err
+		}
+	}
+	return a.redactLogs(redaction)
+}
+
+// Redact replaces a text, such as a secret pasted by mistake, in the
+// messages, title, instructions and pinned context of a session, in the full
+// outputs saved for its summarized tool calls and in the detailed logs. It
+// returns the number of messages changed.
+func (a *agent) Redact(ctx context.Context, sessionID, text string) (int, error) {
+	if strings.TrimSpace(text) == "" {
+		return 0, errors.New("nothing to redact")
+	}
+	if a.IsSessionBusy(sessionID) {
+		return 0, errors.New("wait for the agent to finish before redacting")
+	}
+	msgs, err := a.messages.List(ctx, sessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list messages: %w", err)
+	}
+	changed := 0
+	for _, msg := range msgs {
+		if !redactParts(msg.Parts, text) {
+			continue
+		}
+		if err := a.messages.Update(ctx, msg); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+
+	sess, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return changed, err
+	}
+	title := detailed_logging.RedactText(sess.Title, text)
+	instructions := detailed_logging.RedactText(sess.SystemPrompt, text)
+	if title != sess.Title || instructions != sess.SystemPrompt {
+		sess.Title, sess.SystemPrompt = title, instructions
+		if _, err := a.sessions.Save(ctx, sess); err != nil {
+			return changed, err
+		}
+	}
+
+	for _, pin := range prompt.ListPins(sessionID) {
+		if strings.Contains(pin.Content, text) {
+			if _, err := prompt.Unpin(sessionID, "#"+strconv.Itoa(pin.ID)); err != nil {
+				return changed, err
+			}
+		}
+	}
+	if err := redactToolOutputs(sessionID, text); err != nil {
+		return changed, err
+	}
+	return changed, a.redactLogs(detailed_logging.Redaction{Texts: []string{text}})
+}
+
+// deleteToolOutputs deletes the full outputs saved for tool calls of a
+// session
+func deleteToolOutputs(sessionID string, callIDs []string) error {
+	for _, id := range callIDs {
+		err := os.Remove(filepath.Join(ToolOutputsDir(sessionID), id+".txt"))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete the saved tool output: %w", err)
+		}
+	}
+	return nil
+}
+
+// redactToolOutputs replaces a text in the full outputs saved for the tool
+// calls of a session
+func redactToolOutputs(sessionID, text string) error {
+	dir := ToolOutputsDir(sessionID)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to list the saved tool outputs: %w", err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read the saved tool output: %w", err)
+		}
+		redacted := detailed_logging.RedactText(string(content), text)
+		if redacted == string(content) {
+			continue
+		}
+		if err := os.WriteFile(path, []byte(redacted), 0o644); err != nil {
+			return fmt.Errorf("failed to redact the saved tool output: %w", err)
+		}
+	}
+	return nil
+}
+
+// redactLogs removes content from the detailed logs
+func (a *agent) redactLogs(redaction detailed_logging.Redaction) error {
+	logs, err := a.detailedLogger.Redact(redaction)
+	if err != nil {
+		return fmt.Errorf("failed to redact the detailed logs: %w", err)
+	}
+	if logs > 0 {
+		logging.Info("Redacted the detailed logs", "logs", logs)
+	}
+	return nil
+}
+
+// partTexts returns the texts of the parts of a message
+func partTexts(parts []message.ContentPart) []string {
+	var texts []string
+	for _, part := range parts {
+		switch p := part.(type) {
+		case message.TextContent:
+			texts = append(texts, p.Text)
+		case message.ReasoningContent:
+			texts = append(texts, p.Thinking)
+		case message.ToolCall:
+			texts = append(texts, p.Input)
+		case message.ToolResult:
+			texts = append(texts, p.Content, p.Metadata)
+		}
+	}
+	return slices.DeleteFunc(texts, func(text string) bool { return strings.TrimSpace(text) == "" })
+}
+
+// redactParts replaces a text in the parts of a message and reports whether
+// any changed. Tool inputs and metadata are JSON, the text is replaced there
+// as it's encoded.
+func redactParts(parts []message.ContentPart, text string) bool {
+	changed := false
+	redact := func(s string) string {
+		redacted := detailed_logging.RedactText(s, text)
+		changed = changed || redacted != s
+		return redacted
+	}
+	for i, part := range parts {
+		switch p := part.(type) {
+		case message.TextContent:
+			p.Text = redact(p.Text)
+			parts[i] = p
+		case message.ReasoningContent:
+			p.Thinking = redact(p.Thinking)
+			parts[i] = p
+		case message.ToolCall:
+			p.Input = redact(p.Input)
+			parts[i] = p
+		case message.ToolResult:
+			p.Content = redact(p.Content)
+			p.Metadata = redact(p.Metadata)
+			parts[i] = p
+		}
+	}
+	return changed
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactParts(t *testing.T) {
+	parts := []message.ContentPart{
+		message.TextContent{Text: "use the key sk-123"},
+		message.ToolCall{ID: "call", Name: "bash", Input: `{"command":"curl -H \"Authorization: sk-123\""}`},
+		message.ToolResult{ToolCallID: "call", Content: "ok"},
+	}
+	assert.True(t, redactParts(parts, "sk-123"))
+	assert.Equal(t, message.TextContent{Text: "use the key [redacted]"}, parts[0])
+	assert.Equal(t, `{"command":"curl -H \"Authorization: [redacted]\""}`, parts[1].(message.ToolCall).Input)
+	assert.Equal(t, "ok", parts[2].(message.ToolResult).Content)
+	assert.False(t, redactParts(parts, "sk-123"))
+}
+
+func TestPartTexts(t *testing.T) {
+	texts := partTexts([]message.ContentPart{
+		message.TextContent{Text: "hello"},
+		message.ToolResult{ToolCallID: "call", Content: "output"},
+		message.Finish{Reason: "stop"},
+	})
+	assert.Equal(t, []string{"hello", "output"}, texts)
+}
+
+func TestSavedToolOutputs(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	cfg := config.Get()
+	dataDir := cfg.Data.Directory
+	cfg.Data.Directory = t.TempDir()
+	t.Cleanup(func() { cfg.Data.Directory = dataDir })
+
+	dir := ToolOutputsDir("session")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	for id, output := range map[string]string{"one": "token sk-123 used", "two": "clean", "three": "sk-123"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, id+".txt"), []byte(output), 0o644))
+	}
+
+	require.NoError(t, redactToolOutputs("session", "sk-123"))
+	for id, want := range map[string]string{"one": "token [redacted] used", "two": "clean", "three": "[redacted]"} {
+		content, err := os.ReadFile(filepath.Join(dir, id+".txt"))
+		require.NoError(t, err)
+		assert.Equal(t, want, string(content), id)
+	}
+
+	require.NoError(t, deleteToolOutputs("session", []string{"one", "missing"}))
+	assert.NoFileExists(t, filepath.Join(dir, "one.txt"))
+	assert.FileExists(t, filepath.Join(dir, "two.txt"))
+	assert.NoError(t, redactToolOutputs("other", "sk-123"), "sessions without saved outputs")
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/llm/provider"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+const issuePrompt = `Turn our conversation above into an issue for the project's issue tracker, so someone who wasn't part of it can pick up the work.
+
+Use this markdown format and nothing else:
+
+# <short, specific title>
+
+## Problem
+What is wrong or missing, and why it matters.
+
+## Steps to Reproduce
+Numbered steps, commands and the observed versus expected behavior. Write "Not applicable" if the issue isn't a bug.
+
+## Findings
+What the investigation found, with file:line references and short code or log excerpts where they help.
+
+## Unresolved
+The open questions and the parts that were not fixed or verified.
+
+## Next Steps
+A short checklist of what to do next.
+
+Only include facts established in the conversation, don't invent details. Leave out secrets such as API keys and tokens.`
+
+// DraftIssue turns the conversation of a session into an issue draft in
+// markdown, with the title as the first heading. notes are extra
+// instructions from the user, e.g. what to focus on.
+func (a *agent) DraftIssue(ctx context.Context, sessionID, notes string) (string, error) {
+	if a.summarizeProvider == nil {
+		return "", fmt.Errorf("summarize provider not available")
+	}
+	msgs, err := a.messages.List(ctx, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list messages: %w", err)
+	}
+	if len(msgs) == 0 {
+		return "", errors.New("no messages to export")
+	}
+
+	prompt := issuePrompt
+	if notes != "" {
+		prompt += "\n\nAdditional instructions: " + notes
+	}
+	msgs = append(msgs, message.Message{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: prompt}},
+	})
+
+	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
+	response, err := a.summarizeProvider.SendMessages(ctx, msgs, make([]tools.BaseTool, 0))
+	if err != nil {
+		return "", fmt.Errorf("failed to draft issue: %w", err)
+	}
+	draft := strings.TrimSpace(response.Content)
+	if draft == "" {
+		return "", errors.New("empty issue draft returned")
+	}
+
+	// The draft doesn't change the context of the session, only its cost
+	a.trackSummarizeCost(ctx, sessionID, response.Usage)
+	return draft, nil
+}
+
+// trackSummarizeCost adds the cost of a request of the summarize provider
+// made for a session, outside of its conversation, to the session
+func (a *agent) trackSummarizeCost(ctx context.Context, sessionID string, usage provider.TokenUsage) {
+	sess, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return
+	}
+	model := a.summarizeProvider.Model()
+	cost := usageCost(model, usage)
+	sess.Cost += cost
+	if _, err := a.sessions.Save(ctx, sess); err != nil {
+		logging.Warn("failed to save session cost", "session", sessionID, "error", err)
+	}
+	if err := a.quotas.Record(ctx, model.Provider, usage.InputTokens, usage.OutputTokens, cost); err != nil {
+		logging.Warn("failed to record provider usage", "provider", model.Provider, "error", err)
+	}
+}
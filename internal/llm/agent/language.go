@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/prompt"
+	"github.com/kirmad/superopencode/internal/session"
+)
+
+type languageContextKey struct{}
+
+// responseLanguage returns the language the agent answers in a session: the
+// one set for the session, the one of the session that started the task, or
+// locale.language
+func responseLanguage(ctx context.Context, sess session.Session) string {
+	if sess.Language != "" {
+		return sess.Language
+	}
+	if language, ok := ctx.Value(languageContextKey{}).(string); ok && language != "" {
+		return language
+	}
+	if cfg := config.Get(); cfg != nil {
+		return cfg.Locale.Language
+	}
+	return ""
+}
+
+// sessionInstructions returns the additions to the system prompt of a turn:
+// the session instructions and the response language. The language is passed
+// on to the tasks the turn starts with the returned context.
+func sessionInstructions(ctx context.Context, sess session.Session) (context.Context, string) {
+	language := responseLanguage(ctx, sess)
+	if language == "" {
+		return ctx, sess.SystemPrompt
+	}
+	ctx = context.WithValue(ctx, languageContextKey{}, language)
+	sections := []string{prompt.ResponseLanguagePrompt(language)}
+	if sess.SystemPrompt != "" {
+		sections = append(sections, sess.SystemPrompt)
+	}
+	return ctx, strings.Join(sections, "\n\n")
+}
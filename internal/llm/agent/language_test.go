@@ -0,0 +1,22 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionInstructions(t *testing.T) {
+	ctx, instructions := sessionInstructions(context.Background(), session.Session{SystemPrompt: "Use tabs", Language: "de"})
+	assert.Contains(t, instructions, "for the user in German")
+	assert.Contains(t, instructions, "\n\nUse tabs")
+
+	// Tasks started by the turn answer in its language
+	_, instructions = sessionInstructions(ctx, session.Session{})
+	assert.Contains(t, instructions, "for the user in German")
+
+	_, instructions = sessionInstructions(ctx, session.Session{Language: "fr"})
+	assert.Contains(t, instructions, "for the user in French")
+}
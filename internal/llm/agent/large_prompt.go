@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/tokenizer"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/logging"
+)
+
+// largePromptNote replaces a prompt too large for a turn
+const largePromptNote = `[This message is ~%d tokens, too large to send in one turn. It's saved as prompt %s in %d chunks. Below are its start and end; read the chunks you need, in order from 1, with the %s tool before answering. The request is usually at the start or the end.]
+
+%s`
+
+// largePromptPreview is the size in bytes of the start and end of a large
+// prompt shown in the note replacing it
+const largePromptPreview = 4000
+
+// chunkLargePrompt saves a prompt larger than the configured threshold in
+// chunks the agent reads with the read_prompt tool, and returns the note
+// replacing it. The prompt is sent as is when it fits, or when it can't be
+// saved.
+func (a *agent) chunkLargePrompt(ctx context.Context, sessionID, content string) string {
+	cfg := config.Get().LargePrompts
+	if !cfg.Enabled || !a.hasTool(sessionID, tools.ReadPromptToolName) {
+		return content
+	}
+	model := a.generator(ctx).Model()
+	threshold := int64(cfg.ThresholdTokens)
+	if threshold == 0 {
+		if model.ContextWindow <= 0 {
+			return content
+		}
+		threshold = (model.ContextWindow - a.maxTokens()) / 2
+	}
+	tokens := tokenizer.ForModel(model).Count(content)
+	if tokens <= threshold {
+		return content
+	}
+
+	// The chunk size in bytes, at ~3 characters a token
+	chunks := tools.SplitChunks(content, cfg.ChunkTokens*3)
+	id, err := tools.SavePromptChunks(sessionID, chunks)
+	if err != nil {
+		logging.Warn("Failed to save the chunks of a large prompt", "error", err)
+		return content
+	}
+	logging.Info("Chunked a large prompt", "session", sessionID, "tokens", tokens, "chunks", len(chunks))
+	return fmt.Sprintf(largePromptNote, tokens, id, len(chunks), tools.ReadPromptToolName, elideMiddle(content, largePromptPreview))
+}
+
+// hasTool reports whether a tool is available in a session
+func (a *agent) hasTool(sessionID, name string) bool {
+	for _, tool := range a.toolsFor(sessionID) {
+		if tool.Info().Name == name {
+			return true
+		}
+	}
+	return false
+}
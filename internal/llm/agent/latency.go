@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/llm/provider"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/pubsub"
+)
+
+// warmupInterval is how long a warmed up connection is trusted to still be
+// open, pings in between are skipped
+const warmupInterval = 30 * time.Second
+
+// Warmup opens a connection to the provider of the agent when latency.warmup
+// is enabled, so the first request of the next turn doesn't wait for the
+// handshake
+func (a *agent) Warmup(ctx context.Context) {
+	if !config.Get().Latency.Warmup {
+		return
+	}
+	p := a.provider
+	a.warmupMu.Lock()
+	if a.warmedModel == p.Model().ID && time.Since(a.warmedAt) < warmupInterval {
+		a.warmupMu.Unlock()
+		return
+	}
+	a.warmedModel = p.Model().ID
+	a.warmedAt = time.Now()
+	a.warmupMu.Unlock()
+
+	started := time.Now()
+	if err := provider.Warmup(ctx, p); err != nil {
+		logging.Debug("Provider warmup failed", "model", p.Model().ID, "error", err)
+		return
+	}
+	logging.Debug("Warmed up provider", "model", p.Model().ID, "duration", time.Since(started))
+}
+
+// firstTokenEvent tells whether a provider event is the first output of the
+// model
+func firstTokenEvent(event provider.ProviderEvent) bool {
+	switch event.Type {
+	case provider.EventContentDelta, provider.EventThinkingDelta, provider.EventToolUseStart:
+		return true
+	}
+	return false
+}
+
+// publishFirstToken reports the time the user waited for the first token of
+// a turn
+func (a *agent) publishFirstToken(sessionID, messageID string, model models.ModelID, firstToken time.Duration) {
+	if target := config.Get().Latency.FirstTokenTargetMs; target > 0 && firstToken > time.Duration(target)*time.Millisecond {
+		logging.Debug("First token over the latency target", "model", model, "first_token", firstToken, "target_ms", target)
+	}
+	a.Publish(pubsub.CreatedEvent, AgentEvent{
+		Type:       AgentEventTypeLatency,
+		SessionID:  sessionID,
+		MessageID:  messageID,
+		Model:      model,
+		FirstToken: firstToken,
+	})
+}
@@ -0,0 +1,249 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kirmad/superopencode/internal/detailed_logging"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/locale"
+)
+
+const (
+	LogQueryToolName = "log_query"
+
+	defaultLogQueryLimit = 20
+	maxLogQueryLimit     = 100
+
+	// Size of the input and output shown per entry, and for an entry asked
+	// for by ID
+	logEntryPreview = 300
+	logEntryFull    = 20000
+
+	logQueryDescription = `Queries the detailed logs of OpenCode runs: the LLM calls, tool calls, HTTP requests, permission waits and user messages recorded when OpenCode runs with --detailed-logs.
+
+WHEN TO USE THIS TOOL:
+- Use to answer questions about what happened in a session, e.g. "which tool call failed in session X and why"
+- Use to debug failing tools, slow or failed model calls and HTTP errors of providers and MCP servers
+
+HOW TO USE:
+- Narrow down with the filters: session ("current" for this session), kind, name, errors_only, contains, since
+- The most recent entries come first, with their ID, time, duration, error and a preview of their input and output
+- Call again with id to read the full input and output of an entry
+
+LIMITATIONS:
+- Only runs started with --detailed-logs are logged, and logs are kept for 30 days
+- Inputs and outputs are shown as they were logged, HTTP headers are left out
+`
+)
+
+type LogQueryParams struct {
+	Session    string `json:"session"`
+	Run        string `json:"run"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	ErrorsOnly bool   `json:"errors_only"`
+	Contains   string `json:"contains"`
+	Since      string `json:"since"`
+	ID         string `json:"id"`
+	Limit      int    `json:"limit"`
+}
+
+type logQueryTool struct {
+	dir string
+}
+
+// NewLogQueryTool returns the tool querying the detailed logs
+func NewLogQueryTool() tools.BaseTool {
+	dir, _ := detailed_logging.DefaultDir()
+	return &logQueryTool{dir: dir}
+}
+
+func (l *logQueryTool) Info() tools.ToolInfo {
+	kinds := make([]string, len(detailed_logging.EntryKinds))
+	for i, kind := range detailed_logging.EntryKinds {
+		kinds[i] = string(kind)
+	}
+	return tools.ToolInfo{
+		Name:        LogQueryToolName,
+		Description: logQueryDescription,
+		Parameters: map[string]any{
+			"session": map[string]any{
+				"type":        "string",
+				"description": `ID of the chat session the calls were made for, or "current"`,
+			},
+			"run": map[string]any{
+				"type":        "string",
+				"description": "ID of a logged OpenCode run, or a prefix of it",
+			},
+			"kind": map[string]any{
+				"type":        "string",
+				"description": "Kind of entries to return",
+				"enum":        kinds,
+			},
+			"name": map[string]any{
+				"type":        "string",
+				"description": "Part of the tool name, model or URL of the entries",
+			},
+			"errors_only": map[string]any{
+				"type":        "boolean",
+				"description": "Only return the entries that failed",
+			},
+			"contains": map[string]any{
+				"type":        "string",
+				"description": "Text in the input, output or error of the entries, case insensitive",
+			},
+			"since": map[string]any{
+				"type":        "string",
+				"description": `Only return the entries after a time, as a duration ago like "2h" or in RFC 3339`,
+			},
+			"id": map[string]any{
+				"type":        "string",
+				"description": "ID of an entry to show with its full input and output",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "Number of entries to return (default 20, max 100)",
+			},
+		},
+	}
+}
+
+func (l *logQueryTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	var params LogQueryParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return tools.NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if l.dir == "" {
+		return tools.NewTextErrorResponse("the detailed logs directory is unknown"), nil
+	}
+	if params.Kind != "" && !slices.Contains(detailed_logging.EntryKinds, detailed_logging.EntryKind(params.Kind)) {
+		return tools.NewTextErrorResponse(fmt.Sprintf("unknown kind %q", params.Kind)), nil
+	}
+
+	query := detailed_logging.Query{
+		ChatSessionID: params.Session,
+		Run:           params.Run,
+		Kind:          detailed_logging.EntryKind(params.Kind),
+		Name:          params.Name,
+		ErrorsOnly:    params.ErrorsOnly,
+		Contains:      params.Contains,
+		Limit:         params.Limit,
+	}
+	if strings.EqualFold(query.ChatSessionID, "current") {
+		query.ChatSessionID, _ = tools.GetContextValues(ctx)
+	}
+	if params.Since != "" {
+		since, err := parseSince(params.Since, time.Now())
+		if err != nil {
+			return tools.NewTextErrorResponse(err.Error()), nil
+		}
+		query.Since = since
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultLogQueryLimit
+	}
+	query.Limit = min(query.Limit, maxLogQueryLimit)
+	if params.ID != "" {
+		// The entry is looked for among all of them
+		query.Limit = 0
+	}
+
+	entries, err := detailed_logging.QueryDir(l.dir, query)
+	if err != nil {
+		return tools.NewTextErrorResponse(fmt.Sprintf("failed to read the detailed logs: %s", err)), nil
+	}
+	if params.ID != "" {
+		for _, entry := range entries {
+			if entry.ID == params.ID {
+				return tools.NewTextResponse(formatLogEntry(entry, logEntryFull)), nil
+			}
+		}
+		return tools.NewTextErrorResponse(fmt.Sprintf("no entry %s matches the filters", params.ID)), nil
+	}
+	if len(entries) == 0 {
+		return tools.NewTextResponse("No entries found. Only runs started with --detailed-logs are logged."), nil
+	}
+
+	var sb strings.Builder
+	for i, entry := range entries {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(formatLogEntry(entry, logEntryPreview))
+	}
+	if len(entries) == query.Limit {
+		fmt.Fprintf(&sb, "\n\nShowing the %d most recent entries, narrow down the filters or raise the limit to see more.", query.Limit)
+	}
+	return tools.NewTextResponse(sb.String()), nil
+}
+
+// parseSince reads a duration ago or a time
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(since); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since %q, use a duration like 2h or a time in RFC 3339", since)
+	}
+	return t, nil
+}
+
+// formatLogEntry describes an entry, with its input and output cut to size
+func formatLogEntry(entry detailed_logging.Entry, size int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s] %s", entry.Kind, entry.ID)
+	if entry.Name != "" {
+		fmt.Fprintf(&sb, " %s", entry.Name)
+	}
+	fmt.Fprintf(&sb, "\ntime: %s", locale.DateTime(entry.Time))
+	if entry.DurationMs > 0 {
+		fmt.Fprintf(&sb, ", took %s", (time.Duration(entry.DurationMs) * time.Millisecond).String())
+	}
+	fmt.Fprintf(&sb, "\nrun: %s", entry.Run)
+	if entry.ChatSessionID != "" {
+		fmt.Fprintf(&sb, ", session: %s", entry.ChatSessionID)
+	}
+	if entry.Parent != "" {
+		fmt.Fprintf(&sb, ", in: %s", entry.Parent)
+	}
+	if entry.Error != "" {
+		fmt.Fprintf(&sb, "\nerror: %s", entry.Error)
+	}
+	if input := detailed_logging.EntryText(entry.Input); input != "" {
+		fmt.Fprintf(&sb, "\ninput: %s", truncateLogText(input, size))
+	}
+	if output := detailed_logging.EntryText(entry.Output); output != "" {
+		fmt.Fprintf(&sb, "\noutput: %s", truncateLogText(output, size))
+	}
+	return sb.String()
+}
+
+func truncateLogText(text string, size int) string {
+	if len(text) <= size {
+		return text
+	}
+	cut := size
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s... (%d more bytes)", text[:cut], len(text)-cut)
+}
+
+// LogsPrompt asks the agent to answer a question from the detailed logs
+func LogsPrompt(question, sessionID string) string {
+	var sb strings.Builder
+	sb.WriteString("Answer this question from the detailed logs of OpenCode, using the log_query tool:\n\n")
+	sb.WriteString(question)
+	if sessionID != "" {
+		fmt.Fprintf(&sb, "\n\nThis chat session is %s.", sessionID)
+	}
+	sb.WriteString(" Start with narrow filters, read the full input and output of the entries that matter by their id, and cite the IDs of the entries your answer is based on.")
+	return sb.String()
+}
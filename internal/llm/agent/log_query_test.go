@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/detailed_logging"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogQueryTool(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	session := detailed_logging.SessionLog{
+		ID:        "run-1",
+		StartTime: start,
+		ToolCalls: []detailed_logging.ToolCallLog{
+			{ID: "call-ok", ChatSessionID: "chat", Name: "view", StartTime: start, Output: "package main"},
+			{ID: "call-failed", ChatSessionID: "chat", Name: "bash", StartTime: start.Add(time.Minute), DurationMs: 1500,
+				Input: map[string]any{"command": "go test ./..."}, Output: strings.Repeat("x", 1000), Error: "exit status 1"},
+			{ID: "call-other", ChatSessionID: "other", Name: "bash", StartTime: start.Add(2 * time.Minute), Error: "timeout"},
+		},
+	}
+	data, err := json.Marshal(session)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "run-1.json"), data, 0o644))
+
+	tool := &logQueryTool{dir: dir}
+	run := func(ctx context.Context, params LogQueryParams) tools.ToolResponse {
+		input, err := json.Marshal(params)
+		require.NoError(t, err)
+		response, err := tool.Run(ctx, tools.ToolCall{Input: string(input)})
+		require.NoError(t, err)
+		return response
+	}
+
+	ctx := context.WithValue(context.Background(), tools.SessionIDContextKey, "chat")
+	response := run(ctx, LogQueryParams{Session: "current", ErrorsOnly: true})
+	assert.False(t, response.IsError)
+	assert.Contains(t, response.Content, "[tool] call-failed bash")
+	assert.Contains(t, response.Content, "error: exit status 1")
+	assert.Contains(t, response.Content, "took 1.5s")
+	assert.Contains(t, response.Content, "more bytes)")
+	assert.NotContains(t, response.Content, "call-other")
+	assert.NotContains(t, response.Content, "call-ok")
+
+	response = run(ctx, LogQueryParams{ID: "call-failed"})
+	assert.Contains(t, response.Content, strings.Repeat("x", 1000))
+
+	response = run(ctx, LogQueryParams{Kind: "tool", Limit: 1})
+	assert.Contains(t, response.Content, "call-other")
+	assert.Contains(t, response.Content, "Showing the 1 most recent entries")
+
+	assert.Contains(t, run(ctx, LogQueryParams{Name: "grep"}).Content, "No entries found")
+	assert.True(t, run(ctx, LogQueryParams{Kind: "disk"}).IsError)
+	assert.True(t, run(ctx, LogQueryParams{Since: "yesterday"}).IsError)
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	since, err := parseSince("2h", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-2*time.Hour), since)
+
+	since, err = parseSince("2025-01-01T08:00:00Z", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC), since)
+
+	_, err = parseSince("last week", now)
+	assert.Error(t, err)
+}
+
+func TestTruncateLogText(t *testing.T) {
+	assert.Equal(t, "short", truncateLogText("short", 10))
+	assert.Equal(t, "éé... (4 more bytes)", truncateLogText("éééé", 5))
+}
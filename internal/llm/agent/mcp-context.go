@@ -0,0 +1,246 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// mcpMentionPattern matches the mentions of MCP resources in a message,
+// @mcp:<server>:<uri>
+var mcpMentionPattern = regexp.MustCompile(`@mcp:([A-Za-z0-9_-]+):(\S+)`)
+
+// MCPResource is a resource an MCP server offers as context, like a file or
+// a database schema
+type MCPResource struct {
+	Server      string
+	URI         string
+	Name        string
+	Description string
+	MIMEType    string
+}
+
+// Mention returns the reference to the resource in a message, its content is
+// added to the message when it's sent
+func (r MCPResource) Mention() string {
+	return fmt.Sprintf("@mcp:%s:%s", r.Server, r.URI)
+}
+
+// MCPPrompt is a prompt template an MCP server offers
+type MCPPrompt struct {
+	Server      string
+	Name        string
+	Description string
+	Arguments   []MCPPromptArgument
+}
+
+// MCPPromptArgument is an argument of an MCP prompt
+type MCPPromptArgument struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// Usage returns the arguments of the prompt as name=<name>, optional ones
+// in brackets
+func (p MCPPrompt) Usage() string {
+	args := make([]string, len(p.Arguments))
+	for i, arg := range p.Arguments {
+		args[i] = arg.Name + "=<" + arg.Name + ">"
+		if !arg.Required {
+			args[i] = "[" + args[i] + "]"
+		}
+	}
+	return strings.Join(args, " ")
+}
+
+var mcpContext = struct {
+	mu        sync.Mutex
+	resources map[string][]MCPResource
+	prompts   map[string][]MCPPrompt
+}{
+	resources: make(map[string][]MCPResource),
+	prompts:   make(map[string][]MCPPrompt),
+}
+
+func setMCPContext(server string, resources []MCPResource, prompts []MCPPrompt) {
+	mcpContext.mu.Lock()
+	defer mcpContext.mu.Unlock()
+	mcpContext.resources[server] = resources
+	mcpContext.prompts[server] = prompts
+}
+
+// MCPResources returns the resources of the connected MCP servers
+func MCPResources() []MCPResource {
+	mcpContext.mu.Lock()
+	defer mcpContext.mu.Unlock()
+	var resources []MCPResource
+	for _, serverResources := range mcpContext.resources {
+		resources = append(resources, serverResources...)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Server != resources[j].Server {
+			return resources[i].Server < resources[j].Server
+		}
+		return resources[i].URI < resources[j].URI
+	})
+	return resources
+}
+
+// MCPPrompts returns the prompts of the connected MCP servers
+func MCPPrompts() []MCPPrompt {
+	mcpContext.mu.Lock()
+	defer mcpContext.mu.Unlock()
+	var prompts []MCPPrompt
+	for _, serverPrompts := range mcpContext.prompts {
+		prompts = append(prompts, serverPrompts...)
+	}
+	sort.Slice(prompts, func(i, j int) bool {
+		if prompts[i].Server != prompts[j].Server {
+			return prompts[i].Server < prompts[j].Server
+		}
+		return prompts[i].Name < prompts[j].Name
+	})
+	return prompts
+}
+
+// listMCPContext lists the resources and prompts of a connected server.
+// Servers without them answer with an error, which leaves the lists empty.
+func listMCPContext(ctx context.Context, server string, c MCPClient) ([]MCPResource, []MCPPrompt) {
+	var resources []MCPResource
+	if result, err := c.ListResources(ctx, mcp.ListResourcesRequest{}); err == nil {
+		for _, r := range result.Resources {
+			resources = append(resources, MCPResource{
+				Server:      server,
+				URI:         r.URI,
+				Name:        r.Name,
+				Description: r.Description,
+				MIMEType:    r.MIMEType,
+			})
+		}
+	}
+
+	var prompts []MCPPrompt
+	if result, err := c.ListPrompts(ctx, mcp.ListPromptsRequest{}); err == nil {
+		for _, p := range result.Prompts {
+			prompt := MCPPrompt{Server: server, Name: p.Name, Description: p.Description}
+			for _, arg := range p.Arguments {
+				prompt.Arguments = append(prompt.Arguments, MCPPromptArgument{
+					Name:        arg.Name,
+					Description: arg.Description,
+					Required:    arg.Required,
+				})
+			}
+			prompts = append(prompts, prompt)
+		}
+	}
+	return resources, prompts
+}
+
+func mcpServerConfig(server string) (config.MCPServer, error) {
+	m, ok := config.Get().MCPServers[server]
+	if !ok {
+		return config.MCPServer{}, fmt.Errorf("no MCP server %s", server)
+	}
+	return m, nil
+}
+
+// ReadMCPResource reads a resource of a server as text, binary contents are
+// described instead
+func ReadMCPResource(ctx context.Context, server, uri string) (string, error) {
+	m, err := mcpServerConfig(server)
+	if err != nil {
+		return "", err
+	}
+	c, err := connectMCPServer(ctx, server, m)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = uri
+	result, err := c.ReadResource(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", uri, err)
+	}
+	var parts []string
+	for _, content := range result.Contents {
+		switch content := content.(type) {
+		case mcp.TextResourceContents:
+			parts = append(parts, content.Text)
+		case mcp.BlobResourceContents:
+			parts = append(parts, fmt.Sprintf("[binary %s content of %s, %d bytes base64]", content.MIMEType, content.URI, len(content.Blob)))
+		}
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// GetMCPPrompt renders a prompt of a server with its arguments, the text of
+// its messages is joined
+func GetMCPPrompt(ctx context.Context, server, name string, args map[string]string) (string, error) {
+	m, err := mcpServerConfig(server)
+	if err != nil {
+		return "", err
+	}
+	c, err := connectMCPServer(ctx, server, m)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+
+	request := mcp.GetPromptRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+	result, err := c.GetPrompt(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("error getting prompt %s: %w", name, err)
+	}
+	var parts []string
+	for _, message := range result.Messages {
+		switch content := message.Content.(type) {
+		case mcp.TextContent:
+			parts = append(parts, content.Text)
+		case mcp.EmbeddedResource:
+			if text, ok := content.Resource.(mcp.TextResourceContents); ok {
+				parts = append(parts, fmt.Sprintf("Contents of %s\n\n%s", text.URI, text.Text))
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("prompt %s has no text", name)
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// ExpandMCPMentions appends the contents of the MCP resources mentioned in
+// text, like @file mentions of commands. Resources that can't be read are
+// logged and left as they are.
+func ExpandMCPMentions(ctx context.Context, text string) string {
+	var contents strings.Builder
+	seen := make(map[string]bool)
+	for _, match := range mcpMentionPattern.FindAllStringSubmatch(text, -1) {
+		if seen[match[0]] {
+			continue
+		}
+		seen[match[0]] = true
+		server, uri := match[1], match[2]
+		content, err := ReadMCPResource(ctx, server, uri)
+		if err != nil {
+			logging.Warn("Failed to read mentioned MCP resource", "server", server, "uri", uri, "error", err)
+			continue
+		}
+		fmt.Fprintf(&contents, "Contents of %s from the MCP server %s\n\n%s\n\n", uri, server, content)
+	}
+	if contents.Len() == 0 {
+		return text
+	}
+	return text + "\n\n" + strings.TrimSuffix(contents.String(), "\n\n")
+}
@@ -0,0 +1,291 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// mcpSessionHeader carries the session the server assigns on initialize
+const mcpSessionHeader = "Mcp-Session-Id"
+
+// httpMCPClient talks to an MCP server over the streamable HTTP transport:
+// every JSON-RPC message is a POST, answered with JSON or an event stream
+type httpMCPClient struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	nextID  atomic.Int64
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+func newHTTPMCPClient(url string, headers map[string]string) *httpMCPClient {
+	return &httpMCPClient{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+type jsonRPCMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  any             `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (c *httpMCPClient) Initialize(ctx context.Context, request mcp.InitializeRequest) (*mcp.InitializeResult, error) {
+	var result mcp.InitializeResult
+	if err := c.call(ctx, "initialize", request.Params, &result); err != nil {
+		return nil, err
+	}
+	if err := c.notify(ctx, "notifications/initialized"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *httpMCPClient) ListTools(ctx context.Context, request mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+	var result mcp.ListToolsResult
+	if err := c.call(ctx, "tools/list", request.Params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *httpMCPClient) CallTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Content is a list of interfaces, only text is kept as such
+	var raw struct {
+		Content []json.RawMessage `json:"content"`
+		IsError bool              `json:"isError"`
+	}
+	if err := c.call(ctx, "tools/call", request.Params, &raw); err != nil {
+		return nil, err
+	}
+	result := &mcp.CallToolResult{IsError: raw.IsError}
+	for _, content := range raw.Content {
+		var text struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(content, &text); err == nil && text.Type == "text" {
+			result.Content = append(result.Content, mcp.TextContent{Type: "text", Text: text.Text})
+		} else {
+			result.Content = append(result.Content, mcp.TextContent{Type: "text", Text: string(content)})
+		}
+	}
+	return result, nil
+}
+
+func (c *httpMCPClient) ListResources(ctx context.Context, request mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error) {
+	var result mcp.ListResourcesResult
+	if err := c.call(ctx, "resources/list", request.Params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *httpMCPClient) ReadResource(ctx context.Context, request mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	// Contents are text or base64 blobs, told apart by their field
+	var raw struct {
+		Contents []struct {
+			URI      string  `json:"uri"`
+			MIMEType string  `json:"mimeType"`
+			Text     *string `json:"text"`
+			Blob     string  `json:"blob"`
+		} `json:"contents"`
+	}
+	if err := c.call(ctx, "resources/read", request.Params, &raw); err != nil {
+		return nil, err
+	}
+	result := &mcp.ReadResourceResult{}
+	for _, content := range raw.Contents {
+		if content.Text != nil {
+			result.Contents = append(result.Contents, mcp.TextResourceContents{URI: content.URI, MIMEType: content.MIMEType, Text: *content.Text})
+		} else {
+			result.Contents = append(result.Contents, mcp.BlobResourceContents{URI: content.URI, MIMEType: content.MIMEType, Blob: content.Blob})
+		}
+	}
+	return result, nil
+}
+
+func (c *httpMCPClient) ListPrompts(ctx context.Context, request mcp.ListPromptsRequest) (*mcp.ListPromptsResult, error) {
+	var result mcp.ListPromptsResult
+	if err := c.call(ctx, "prompts/list", request.Params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *httpMCPClient) GetPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	var raw struct {
+		Description string `json:"description"`
+		Messages    []struct {
+			Role    string `json:"role"`
+			Content struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"messages"`
+	}
+	if err := c.call(ctx, "prompts/get", request.Params, &raw); err != nil {
+		return nil, err
+	}
+	result := &mcp.GetPromptResult{Description: raw.Description}
+	for _, message := range raw.Messages {
+		if message.Content.Type != "text" {
+			continue
+		}
+		result.Messages = append(result.Messages, mcp.PromptMessage{
+			Role:    mcp.Role(message.Role),
+			Content: mcp.TextContent{Type: "text", Text: message.Content.Text},
+		})
+	}
+	return result, nil
+}
+
+// Close ends the session on the server, if it assigned one
+func (c *httpMCPClient) Close() error {
+	c.mu.Lock()
+	sessionID := c.sessionID
+	c.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := c.newRequest(ctx, http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (c *httpMCPClient) call(ctx context.Context, method string, params any, result any) error {
+	id := c.nextID.Add(1)
+	resp, err := c.post(ctx, jsonRPCMessage{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response *jsonRPCMessage
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		response, err = readEventStreamResponse(resp.Body, id)
+	} else {
+		response = &jsonRPCMessage{}
+		err = json.NewDecoder(resp.Body).Decode(response)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid response to %s: %w", method, err)
+	}
+	if response.Error != nil {
+		return fmt.Errorf("%s failed: %s (%d)", method, response.Error.Message, response.Error.Code)
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("invalid result of %s: %w", method, err)
+	}
+	return nil
+}
+
+func (c *httpMCPClient) notify(ctx context.Context, method string) error {
+	resp, err := c.post(ctx, jsonRPCMessage{JSONRPC: "2.0", Method: method})
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (c *httpMCPClient) post(ctx context.Context, message jsonRPCMessage) (*http.Response, error) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%s: %s %s", message.Method, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	if sessionID := resp.Header.Get(mcpSessionHeader); sessionID != "" {
+		c.mu.Lock()
+		c.sessionID = sessionID
+		c.mu.Unlock()
+	}
+	return resp, nil
+}
+
+func (c *httpMCPClient) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.url, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+	c.mu.Lock()
+	if c.sessionID != "" {
+		req.Header.Set(mcpSessionHeader, c.sessionID)
+	}
+	c.mu.Unlock()
+	return req, nil
+}
+
+// readEventStreamResponse reads server sent events until the response to the
+// request id, skipping the notifications and requests the server sends
+// before it
+func readEventStreamResponse(r io.Reader, id int64) (*jsonRPCMessage, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if after, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(after, " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+		var message jsonRPCMessage
+		if err := json.Unmarshal([]byte(data.String()), &message); err == nil && message.ID != nil && *message.ID == id && message.Method == "" {
+			return &message, nil
+		}
+		data.Reset()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("stream ended without a response")
+}
@@ -0,0 +1,271 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+const (
+	// defaultMCPRedirectPort is the local port of the login redirect
+	defaultMCPRedirectPort = 19876
+	// mcpTokensFile holds the OAuth tokens of the MCP servers in the data
+	// directory
+	mcpTokensFile = "mcp-tokens.json"
+)
+
+// ErrMCPLoginRequired is returned for servers that need a browser login with
+// opencode mcp login before their tokens can be used
+var ErrMCPLoginRequired = errors.New("login required")
+
+// mcpToken is an OAuth token of a remote MCP server
+type mcpToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresIn    int       `json:"expires_in,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// valid reports whether the token can still be used for a minute
+func (t mcpToken) valid() bool {
+	return t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Add(time.Minute).Before(t.Expiry))
+}
+
+// mcpTokensMu serializes token requests so concurrent tool calls don't refresh
+// the same token twice
+var mcpTokensMu sync.Mutex
+
+func mcpTokensPath() string {
+	return filepath.Join(config.Get().Data.Directory, mcpTokensFile)
+}
+
+func loadMCPTokens() map[string]mcpToken {
+	tokens := make(map[string]mcpToken)
+	data, err := os.ReadFile(mcpTokensPath())
+	if err != nil {
+		return tokens
+	}
+	_ = json.Unmarshal(data, &tokens)
+	return tokens
+}
+
+func saveMCPToken(name string, token mcpToken) error {
+	tokens := loadMCPTokens()
+	tokens[name] = token
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := mcpTokensPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// mcpAccessToken returns an access token for a server: the stored one while
+// it's valid, else a refreshed one, else one from the client credentials
+// grant. Servers with a browser login return ErrMCPLoginRequired when they
+// have no token to refresh.
+func mcpAccessToken(ctx context.Context, name string, oauth config.MCPOAuth) (string, error) {
+	mcpTokensMu.Lock()
+	defer mcpTokensMu.Unlock()
+
+	stored, ok := loadMCPTokens()[name]
+	if ok && stored.valid() {
+		return stored.AccessToken, nil
+	}
+	if ok && stored.RefreshToken != "" {
+		token, err := requestMCPToken(ctx, oauth, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {stored.RefreshToken},
+		})
+		if err == nil {
+			if token.RefreshToken == "" {
+				token.RefreshToken = stored.RefreshToken
+			}
+			return token.AccessToken, saveMCPToken(name, token)
+		}
+		if oauth.AuthURL != "" {
+			return "", fmt.Errorf("%w: refreshing the token failed: %v", ErrMCPLoginRequired, err)
+		}
+	}
+	if oauth.AuthURL != "" {
+		return "", ErrMCPLoginRequired
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(oauth.Scopes) > 0 {
+		form.Set("scope", strings.Join(oauth.Scopes, " "))
+	}
+	token, err := requestMCPToken(ctx, oauth, form)
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, saveMCPToken(name, token)
+}
+
+// requestMCPToken posts a grant to the token endpoint
+func requestMCPToken(ctx context.Context, oauth config.MCPOAuth, form url.Values) (mcpToken, error) {
+	form.Set("client_id", oauth.ClientID)
+	if oauth.ClientSecret != "" {
+		form.Set("client_secret", oauth.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, oauth.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return mcpToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return mcpToken{}, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	var token struct {
+		mcpToken
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil && resp.StatusCode == http.StatusOK {
+		return mcpToken{}, fmt.Errorf("invalid token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return mcpToken{}, fmt.Errorf("token request failed: %s %s %s", resp.Status, token.Error, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return mcpToken{}, fmt.Errorf("token response without access_token")
+	}
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return token.mcpToken, nil
+}
+
+// MCPLogin runs the browser login of a server: it listens for the redirect on
+// localhost, hands the authorization URL to open and exchanges the code it
+// receives, with PKCE, for a token that is stored for later runs.
+func MCPLogin(ctx context.Context, name string, open func(authURL string)) error {
+	server, ok := config.Get().MCPServers[name]
+	if !ok {
+		return fmt.Errorf("no MCP server %s", name)
+	}
+	if server.OAuth == nil || server.OAuth.AuthURL == "" {
+		return fmt.Errorf("MCP server %s has no oauth.authUrl to log in with", name)
+	}
+	oauth := *server.OAuth
+	port := oauth.RedirectPort
+	if port == 0 {
+		port = defaultMCPRedirectPort
+	}
+
+	verifier, err := randomURLString(32)
+	if err != nil {
+		return err
+	}
+	state, err := randomURLString(16)
+	if err != nil {
+		return err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	redirectURI := fmt.Sprintf("http://127.0.0.1:%d/callback", port)
+
+	authURL, err := url.Parse(oauth.AuthURL)
+	if err != nil {
+		return fmt.Errorf("invalid authUrl: %w", err)
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", oauth.ClientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	if len(oauth.Scopes) > 0 {
+		query.Set("scope", strings.Join(oauth.Scopes, " "))
+	}
+	authURL.RawQuery = query.Encode()
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen for the login redirect: %w", err)
+	}
+	codes := make(chan string, 1)
+	errs := make(chan error, 1)
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		switch {
+		case q.Get("state") != state:
+			http.Error(w, "invalid state", http.StatusBadRequest)
+			return
+		case q.Get("error") != "":
+			fmt.Fprintln(w, "Login failed, you can close this window.")
+			select {
+			case errs <- fmt.Errorf("login failed: %s %s", q.Get("error"), q.Get("error_description")):
+			default:
+			}
+		default:
+			fmt.Fprintln(w, "Logged in, you can close this window.")
+			select {
+			case codes <- q.Get("code"):
+			default:
+			}
+		}
+	})}
+	go func() { _ = httpServer.Serve(listener) }()
+	defer httpServer.Close()
+
+	open(authURL.String())
+
+	var code string
+	select {
+	case code = <-codes:
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	mcpTokensMu.Lock()
+	defer mcpTokensMu.Unlock()
+	token, err := requestMCPToken(ctx, oauth, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return err
+	}
+	return saveMCPToken(name, token)
+}
+
+func randomURLString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+// mcpCheckTimeout bounds the health check of a server
+const mcpCheckTimeout = 15 * time.Second
+
+// MCPState is the health of an MCP server
+type MCPState string
+
+const (
+	MCPStateConnected     MCPState = "connected"
+	MCPStateFailed        MCPState = "failed"
+	MCPStateLoginRequired MCPState = "login required"
+)
+
+// MCPStatus is the outcome of the last connection to an MCP server
+type MCPStatus struct {
+	Name  string
+	Type  config.MCPType
+	State MCPState
+	Error string
+	// Tools is the number of tools exposed, Hidden the number left out by
+	// allowedTools and deniedTools
+	Tools     int
+	Hidden    int
+	Resources int
+	Prompts   int
+	CheckedAt time.Time
+}
+
+// String returns the status as one line, e.g.
+// "github (http): connected, 12 tools, 3 hidden"
+func (s MCPStatus) String() string {
+	line := fmt.Sprintf("%s (%s): %s", s.Name, s.Type, s.State)
+	switch s.State {
+	case MCPStateConnected:
+		line += fmt.Sprintf(", %d tools", s.Tools)
+		if s.Hidden > 0 {
+			line += fmt.Sprintf(", %d hidden", s.Hidden)
+		}
+		if s.Resources > 0 {
+			line += fmt.Sprintf(", %d resources", s.Resources)
+		}
+		if s.Prompts > 0 {
+			line += fmt.Sprintf(", %d prompts", s.Prompts)
+		}
+	case MCPStateLoginRequired:
+		line += ", run opencode mcp login " + s.Name
+	default:
+		line += ": " + s.Error
+	}
+	return line
+}
+
+var mcpStatuses = struct {
+	mu     sync.Mutex
+	byName map[string]MCPStatus
+}{byName: make(map[string]MCPStatus)}
+
+func setMCPStatus(status MCPStatus) {
+	mcpStatuses.mu.Lock()
+	defer mcpStatuses.mu.Unlock()
+	mcpStatuses.byName[status.Name] = status
+}
+
+// MCPStatuses returns the last known status of the MCP servers, by name
+func MCPStatuses() []MCPStatus {
+	mcpStatuses.mu.Lock()
+	defer mcpStatuses.mu.Unlock()
+	statuses := make([]MCPStatus, 0, len(mcpStatuses.byName))
+	for _, status := range mcpStatuses.byName {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// CheckMCPServers connects to every configured MCP server in parallel and
+// returns their statuses. Their resources and prompts are refreshed, tools a
+// server added since startup are only available after a restart.
+func CheckMCPServers(ctx context.Context) []MCPStatus {
+	var wg sync.WaitGroup
+	for name, m := range config.Get().MCPServers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, mcpCheckTimeout)
+			defer cancel()
+			loadMCPServer(checkCtx, name, m)
+		}()
+	}
+	wg.Wait()
+	return MCPStatuses()
+}
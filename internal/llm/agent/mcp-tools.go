@@ -3,7 +3,10 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"time"
 
 	"github.com/kirmad/superopencode/internal/config"
 	"github.com/kirmad/superopencode/internal/llm/tools"
@@ -29,6 +32,10 @@ type MCPClient interface {
 	) (*mcp.InitializeResult, error)
 	ListTools(ctx context.Context, request mcp.ListToolsRequest) (*mcp.ListToolsResult, error)
 	CallTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error)
+	ListResources(ctx context.Context, request mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error)
+	ReadResource(ctx context.Context, request mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error)
+	ListPrompts(ctx context.Context, request mcp.ListPromptsRequest) (*mcp.ListPromptsResult, error)
+	GetPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error)
 	Close() error
 }
 
@@ -103,29 +110,11 @@ func (b *mcpTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolRes
 		return tools.NewTextErrorResponse("permission denied"), nil
 	}
 
-	switch b.mcpConfig.Type {
-	case config.MCPStdio:
-		c, err := client.NewStdioMCPClient(
-			b.mcpConfig.Command,
-			b.mcpConfig.Env,
-			b.mcpConfig.Args...,
-		)
-		if err != nil {
-			return tools.NewTextErrorResponse(err.Error()), nil
-		}
-		return runTool(ctx, c, b.tool.Name, params.Input)
-	case config.MCPSse:
-		c, err := client.NewSSEMCPClient(
-			b.mcpConfig.URL,
-			client.WithHeaders(b.mcpConfig.Headers),
-		)
-		if err != nil {
-			return tools.NewTextErrorResponse(err.Error()), nil
-		}
-		return runTool(ctx, c, b.tool.Name, params.Input)
+	c, err := newMCPClient(ctx, b.mcpName, b.mcpConfig)
+	if err != nil {
+		return tools.NewTextErrorResponse(err.Error()), nil
 	}
-
-	return tools.NewTextErrorResponse("invalid mcp type"), nil
+	return runTool(ctx, c, b.tool.Name, params.Input)
 }
 
 func NewMcpTool(name string, tool mcp.Tool, permissions permission.Service, mcpConfig config.MCPServer) tools.BaseTool {
@@ -139,31 +128,114 @@ func NewMcpTool(name string, tool mcp.Tool, permissions permission.Service, mcpC
 
 var mcpTools []tools.BaseTool
 
-func getTools(ctx context.Context, name string, m config.MCPServer, permissions permission.Service, c MCPClient) []tools.BaseTool {
-	var stdioTools []tools.BaseTool
+// newMCPClient creates a client for a server. Remote servers with OAuth get
+// the bearer token in their headers.
+func newMCPClient(ctx context.Context, name string, m config.MCPServer) (MCPClient, error) {
+	switch m.Type {
+	case config.MCPStdio:
+		return client.NewStdioMCPClient(
+			m.Command,
+			m.Env,
+			m.Args...,
+		)
+	case config.MCPSse:
+		headers, err := mcpHeaders(ctx, name, m)
+		if err != nil {
+			return nil, err
+		}
+		return client.NewSSEMCPClient(
+			m.URL,
+			client.WithHeaders(headers),
+		)
+	case config.MCPHttp:
+		headers, err := mcpHeaders(ctx, name, m)
+		if err != nil {
+			return nil, err
+		}
+		return newHTTPMCPClient(m.URL, headers), nil
+	}
+	return nil, fmt.Errorf("invalid mcp type %q", m.Type)
+}
+
+func mcpHeaders(ctx context.Context, name string, m config.MCPServer) (map[string]string, error) {
+	headers := make(map[string]string, len(m.Headers)+1)
+	maps.Copy(headers, m.Headers)
+	if m.OAuth != nil {
+		token, err := mcpAccessToken(ctx, name, *m.OAuth)
+		if err != nil {
+			return nil, err
+		}
+		headers["Authorization"] = "Bearer " + token
+	}
+	return headers, nil
+}
+
+// mcpToolAllowed reports whether a server exposes a tool, by its name without
+// the server prefix
+func mcpToolAllowed(m config.MCPServer, name string) bool {
+	if matchesAnyPattern(m.DeniedTools, name) {
+		return false
+	}
+	return len(m.AllowedTools) == 0 || matchesAnyPattern(m.AllowedTools, name)
+}
+
+// connectMCPServer creates a client for a server and initializes it
+func connectMCPServer(ctx context.Context, name string, m config.MCPServer) (MCPClient, error) {
+	c, err := newMCPClient(ctx, name, m)
+	if err != nil {
+		return nil, err
+	}
 	initRequest := mcp.InitializeRequest{}
 	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
 	initRequest.Params.ClientInfo = mcp.Implementation{
 		Name:    "OpenCode",
 		Version: version.Version,
 	}
+	if _, err := c.Initialize(ctx, initRequest); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("error initializing: %w", err)
+	}
+	return c, nil
+}
 
-	_, err := c.Initialize(ctx, initRequest)
+// loadMCPServer connects to a server and lists the tools it exposes. Its
+// resources and prompts are kept for the TUI and the status of the server is
+// recorded for /mcp.
+func loadMCPServer(ctx context.Context, name string, m config.MCPServer) ([]mcp.Tool, MCPStatus) {
+	status := MCPStatus{Name: name, Type: m.Type, State: MCPStateFailed, CheckedAt: time.Now()}
+	defer func() { setMCPStatus(status) }()
+
+	c, err := connectMCPServer(ctx, name, m)
 	if err != nil {
-		logging.Error("error initializing mcp client", "error", err)
-		return stdioTools
+		if errors.Is(err, ErrMCPLoginRequired) {
+			status.State = MCPStateLoginRequired
+		}
+		status.Error = err.Error()
+		return nil, status
 	}
-	toolsRequest := mcp.ListToolsRequest{}
-	tools, err := c.ListTools(ctx, toolsRequest)
+	defer c.Close()
+
+	result, err := c.ListTools(ctx, mcp.ListToolsRequest{})
 	if err != nil {
-		logging.Error("error listing tools", "error", err)
-		return stdioTools
+		status.Error = fmt.Sprintf("error listing tools: %v", err)
+		return nil, status
 	}
-	for _, t := range tools.Tools {
-		stdioTools = append(stdioTools, NewMcpTool(name, t, permissions, m))
+
+	var allowed []mcp.Tool
+	for _, t := range result.Tools {
+		if mcpToolAllowed(m, t.Name) {
+			allowed = append(allowed, t)
+		}
 	}
-	defer c.Close()
-	return stdioTools
+	resources, prompts := listMCPContext(ctx, name, c)
+	setMCPContext(name, resources, prompts)
+
+	status.State = MCPStateConnected
+	status.Tools = len(allowed)
+	status.Hidden = len(result.Tools) - len(allowed)
+	status.Resources = len(resources)
+	status.Prompts = len(prompts)
+	return allowed, status
 }
 
 func GetMcpTools(ctx context.Context, permissions permission.Service) []tools.BaseTool {
@@ -171,29 +243,13 @@ func GetMcpTools(ctx context.Context, permissions permission.Service) []tools.Ba
 		return mcpTools
 	}
 	for name, m := range config.Get().MCPServers {
-		switch m.Type {
-		case config.MCPStdio:
-			c, err := client.NewStdioMCPClient(
-				m.Command,
-				m.Env,
-				m.Args...,
-			)
-			if err != nil {
-				logging.Error("error creating mcp client", "error", err)
-				continue
-			}
-
-			mcpTools = append(mcpTools, getTools(ctx, name, m, permissions, c)...)
-		case config.MCPSse:
-			c, err := client.NewSSEMCPClient(
-				m.URL,
-				client.WithHeaders(m.Headers),
-			)
-			if err != nil {
-				logging.Error("error creating mcp client", "error", err)
-				continue
-			}
-			mcpTools = append(mcpTools, getTools(ctx, name, m, permissions, c)...)
+		serverTools, status := loadMCPServer(ctx, name, m)
+		if status.State != MCPStateConnected {
+			logging.Error("error loading mcp tools", "server", name, "state", status.State, "error", status.Error)
+			continue
+		}
+		for _, t := range serverTools {
+			mcpTools = append(mcpTools, NewMcpTool(name, t, permissions, m))
 		}
 	}
 
@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPToolAllowed(t *testing.T) {
+	m := config.MCPServer{AllowedTools: []string{"issue_*", "search"}, DeniedTools: []string{"issue_delete"}}
+	assert.True(t, mcpToolAllowed(m, "issue_create"))
+	assert.True(t, mcpToolAllowed(m, "search"))
+	assert.False(t, mcpToolAllowed(m, "issue_delete"))
+	assert.False(t, mcpToolAllowed(m, "repo_delete"))
+
+	assert.True(t, mcpToolAllowed(config.MCPServer{}, "anything"))
+	assert.False(t, mcpToolAllowed(config.MCPServer{DeniedTools: []string{"*"}}, "anything"))
+}
+
+func TestHTTPMCPClient(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		if r.Method == http.MethodDelete {
+			assert.Equal(t, "session-1", r.Header.Get(mcpSessionHeader))
+			return
+		}
+		var request struct {
+			ID     *int64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		methods = append(methods, request.Method)
+		if request.Method != "initialize" {
+			assert.Equal(t, "session-1", r.Header.Get(mcpSessionHeader))
+		}
+
+		switch request.Method {
+		case "initialize":
+			w.Header().Set(mcpSessionHeader, "session-1")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":{"protocolVersion":"2025-03-26","serverInfo":{"name":"test","version":"1"}}}`, *request.ID)
+		case "notifications/initialized":
+			w.WriteHeader(http.StatusAccepted)
+		case "tools/list":
+			// Streamed, with a notification before the response
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\",\"params\":{}}\n\n")
+			fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":%d,\"result\":{\"tools\":[{\"name\":\"search\",\"description\":\"Search\",\"inputSchema\":{\"type\":\"object\",\"properties\":{\"q\":{\"type\":\"string\"}}}}]}}\n\n", *request.ID)
+		case "tools/call":
+			assert.JSONEq(t, `{"name":"search","arguments":{"q":"mcp"}}`, string(request.Params))
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":{"content":[{"type":"text","text":"found"}]}}`, *request.ID)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"method not found"}}`, *request.ID)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := newHTTPMCPClient(server.URL, map[string]string{"Authorization": "Bearer token"})
+	_, err := c.Initialize(ctx, mcp.InitializeRequest{})
+	require.NoError(t, err)
+
+	list, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.Tools, 1)
+	assert.Equal(t, "search", list.Tools[0].Name)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "search"
+	request.Params.Arguments = map[string]any{"q": "mcp"}
+	result, err := c.CallTool(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, result.Content, 1)
+	assert.Equal(t, "found", result.Content[0].(mcp.TextContent).Text)
+
+	require.NoError(t, c.Close())
+	assert.Equal(t, []string{"initialize", "notifications/initialized", "tools/list", "tools/call"}, methods)
+}
+
+func TestMCPAccessTokenClientCredentials(t *testing.T) {
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	config.Get().Data.Directory = t.TempDir()
+
+	requests := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "id", r.PostForm.Get("client_id"))
+		assert.Equal(t, "secret", r.PostForm.Get("client_secret"))
+		assert.Equal(t, "a b", r.PostForm.Get("scope"))
+		fmt.Fprint(w, `{"access_token":"token-1","expires_in":3600}`)
+	}))
+	defer tokenServer.Close()
+
+	oauth := config.MCPOAuth{ClientID: "id", ClientSecret: "secret", TokenURL: tokenServer.URL, Scopes: []string{"a", "b"}}
+	token, err := mcpAccessToken(context.Background(), "remote", oauth)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	// The stored token is used while it's valid
+	token, err = mcpAccessToken(context.Background(), "remote", oauth)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+	assert.Equal(t, 1, requests)
+
+	// Servers with a browser login need one first
+	oauth.AuthURL = "https://example.com/authorize"
+	_, err = mcpAccessToken(context.Background(), "other", oauth)
+	assert.ErrorIs(t, err, ErrMCPLoginRequired)
+}
+
+func TestMCPResourcesAndPrompts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			return
+		}
+		var request struct {
+			ID     *int64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		if request.ID == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		var result string
+		switch request.Method {
+		case "initialize":
+			result = `{"protocolVersion":"2025-03-26","serverInfo":{"name":"docs","version":"1"}}`
+		case "tools/list":
+			result = `{"tools":[]}`
+		case "resources/list":
+			result = `{"resources":[{"uri":"docs://schema","name":"Schema","mimeType":"text/plain"}]}`
+		case "resources/read":
+			assert.JSONEq(t, `{"uri":"docs://schema"}`, string(request.Params))
+			result = `{"contents":[{"uri":"docs://schema","mimeType":"text/plain","text":"CREATE TABLE users"},{"uri":"docs://schema","mimeType":"image/png","blob":"aGk="}]}`
+		case "prompts/list":
+			result = `{"prompts":[{"name":"review","description":"Review a file","arguments":[{"name":"file","required":true}]}]}`
+		case "prompts/get":
+			assert.JSONEq(t, `{"name":"review","arguments":{"file":"main.go"}}`, string(request.Params))
+			result = `{"messages":[{"role":"user","content":{"type":"text","text":"Review main.go"}}]}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":%s}`, *request.ID, result)
+	}))
+	defer server.Close()
+
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	config.Get().MCPServers = map[string]config.MCPServer{"docs": {Type: config.MCPHttp, URL: server.URL}}
+
+	ctx := context.Background()
+	_, status := loadMCPServer(ctx, "docs", config.Get().MCPServers["docs"])
+	require.Equal(t, MCPStateConnected, status.State, status.Error)
+	assert.Equal(t, 1, status.Resources)
+	assert.Equal(t, 1, status.Prompts)
+
+	resources := MCPResources()
+	require.Len(t, resources, 1)
+	assert.Equal(t, "@mcp:docs:docs://schema", resources[0].Mention())
+
+	prompts := MCPPrompts()
+	require.Len(t, prompts, 1)
+	assert.Equal(t, "file=<file>", prompts[0].Usage())
+
+	expanded := ExpandMCPMentions(ctx, "Explain @mcp:docs:docs://schema and @mcp:other:x://y")
+	assert.Equal(t, "Explain @mcp:docs:docs://schema and @mcp:other:x://y\n\n"+
+		"Contents of docs://schema from the MCP server docs\n\nCREATE TABLE users\n[binary image/png content of docs://schema, 4 bytes base64]", expanded)
+
+	text, err := GetMCPPrompt(ctx, "docs", "review", map[string]string{"file": "main.go"})
+	require.NoError(t, err)
+	assert.Equal(t, "Review main.go", text)
+}
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/tokenizer"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+// outputSummaryPrompt asks the summary model to condense a tool output
+const outputSummaryPrompt = `Summarize the output of the %s tool below for a coding agent that cannot see it. The summary replaces the output in the conversation, so it must be faithful: never guess or add anything that is not in the output.
+
+Keep verbatim:
+- errors, panics, stack frames and failing test names with their messages
+- file paths with line numbers, commands, versions, counts and exit codes
+- the final status, e.g. whether a build or test run passed
+
+Drop repeated lines, progress output and passing details, saying how many lines of which kind you left out. Answer with the summary only, as plain text.
+
+Tool input:
+%s
+
+Output:
+%s`
+
+// ToolOutputsDir is where the full outputs of the summarized tool calls of a
+// session are kept
+func ToolOutputsDir(sessionID string) string {
+	return filepath.Join(config.Get().Data.Directory, "tool-outputs", sessionID)
+}
+
+// summarizeToolOutput replaces a tool output longer than the configured
+// threshold by a summary of the summary model, so huge command and test
+// outputs don't push the earlier conversation out of the context window. The
+// full output is saved to a file the summary points to. The output is kept as
+// is when it cannot be summarized.
+func (a *agent) summarizeToolOutput(ctx context.Context, sessionID string, toolCall message.ToolCall, result tools.ToolResponse) tools.ToolResponse {
+	cfg := config.Get().ToolOutputSummary
+	if a.outputSummaryProvider == nil || result.Type != tools.ToolResponseTypeText || !cfg.Summarizes(toolCall.Name) {
+		return result
+	}
+	tokens := tokenizer.ForModel(a.provider.Model()).Count(result.Content)
+	if tokens <= int64(cfg.ThresholdTokens) {
+		return result
+	}
+
+	path := filepath.Join(ToolOutputsDir(sessionID), toolCall.ID+".txt")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		logging.Warn("Failed to save tool output", "tool", toolCall.Name, "error", err)
+		return result
+	}
+	if err := os.WriteFile(path, []byte(result.Content), 0o644); err != nil {
+		logging.Warn("Failed to save tool output", "tool", toolCall.Name, "error", err)
+		return result
+	}
+
+	model := a.outputSummaryProvider.Model()
+	output := result.Content
+	if model.ContextWindow > 0 {
+		// Leave room for the prompt and the summary, at ~3 characters a token
+		output = elideMiddle(output, int(model.ContextWindow-model.DefaultMaxTokens-1000)*3)
+	}
+	request := message.Message{
+		Role: message.User,
+		Parts: []message.ContentPart{message.TextContent{
+			Text: fmt.Sprintf(outputSummaryPrompt, toolCall.Name, toolCall.Input, output),
+		}},
+	}
+	resp, err := a.outputSummaryProvider.SendMessages(ctx, []message.Message{request}, nil)
+	if err != nil {
+		logging.Warn("Failed to summarize tool output", "tool", toolCall.Name, "error", err)
+		return result
+	}
+	if err := a.TrackUsage(ctx, sessionID, model, resp.Usage); err != nil {
+		logging.Warn("Failed to track tool output summary usage", "error", err)
+	}
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return result
+	}
+
+	result.Content = fmt.Sprintf(`[The output was ~%d tokens, this is a summary of it. The full output is in %s, read the parts you need with the view or grep tools.]
+
+%s`, tokens, path, summary)
+	return result
+}
+
+// elideMiddle shortens s to about limit characters, keeping its start and end
+// where commands usually report what they run and how it ended
+func elideMiddle(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	head := strings.ToValidUTF8(s[:limit/2], "")
+	tail := strings.ToValidUTF8(s[len(s)-limit/2:], "")
+	return fmt.Sprintf("%s\n[... %d characters left out ...]\n%s", head, len(s)-len(head)-len(tail), tail)
+}
@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElideMiddle(t *testing.T) {
+	assert.Equal(t, "short", elideMiddle("short", 10))
+	assert.Equal(t, "short", elideMiddle("short", 0))
+
+	output := strings.Repeat("a", 50) + strings.Repeat("b", 100) + strings.Repeat("c", 50)
+	elided := elideMiddle(output, 100)
+	assert.True(t, strings.HasPrefix(elided, strings.Repeat("a", 50)+"\n"))
+	assert.True(t, strings.HasSuffix(elided, "\n"+strings.Repeat("c", 50)))
+	assert.Contains(t, elided, "[... 100 characters left out ...]")
+
+	// Multi-byte characters cut in half are dropped
+	assert.Equal(t, "\n[... 4 characters left out ...]\n", elideMiddle("éé", 2))
+}
+
+func TestToolOutputSummarizes(t *testing.T) {
+	cfg := config.ToolOutputSummaryConfig{Tools: []string{"bash", "fetch"}}
+	assert.True(t, cfg.Summarizes("bash"))
+	assert.False(t, cfg.Summarizes("view"))
+	cfg.Tools = []string{"*"}
+	assert.True(t, cfg.Summarizes("view"))
+}
@@ -0,0 +1,474 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/lsp"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/metrics"
+	"github.com/kirmad/superopencode/internal/quota"
+	"github.com/kirmad/superopencode/internal/session"
+)
+
+const (
+	ParallelTasksToolName = "parallel_tasks"
+
+	// maxParallelTasks is the number of tasks of a run that run at once
+	maxParallelTasks = 4
+
+	// TaskStatusSkipped is the status of a task that did not run because
+	// one of its dependencies did not finish
+	TaskStatusSkipped = "skipped"
+
+	parallelTasksDescription = `Runs several agents as a pipeline: each task can depend on other tasks and use their reports in its prompt. The agents have the same read-only tools as the agent tool.
+
+WHEN TO USE THIS TOOL:
+- Use when some searches or investigations need the findings of others, e.g. find the implementations of an interface first, then analyze each of them
+- For independent tasks, call the agent tool several times in one message instead
+
+HOW TO USE:
+- Give every task an id and list the ids of the tasks it needs in depends_on
+- Use {{id}} in the prompt of a task to insert the report of a task it depends on, reports of dependencies that are not used are added at the end of the prompt
+- Tasks run as soon as their dependencies finished, up to 4 at a time
+- Limit what a task may spend with its max_cost_usd and max_tokens, and the whole run with the ones of the call
+- Set retry on the call to resume the tasks that fail with a transient error such as a timeout or a rate limit, or on a task to override it
+
+LIMITATIONS:
+- Dependencies must not form a cycle
+- A task whose dependency failed, was canceled or exceeded its budget is skipped
+- Once the run exceeds its budget, its running tasks are stopped and the others skipped
+- The report of every task ends with a continuation token for the agent tool, to continue that agent
+`
+)
+
+var (
+	taskIDPattern       = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	taskVariablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_-]+)\s*\}\}`)
+)
+
+// ParallelTask is a task of a parallel_tasks call
+type ParallelTask struct {
+	ID        string       `json:"id"`
+	Prompt    string       `json:"prompt"`
+	DependsOn []string     `json:"depends_on,omitempty"` // IDs of the tasks whose reports it needs
+	Retry     *RetryPolicy `json:"retry,omitempty"`      // Overrides the retry policy of the run
+	TaskBudget
+}
+
+type ParallelTaskParams struct {
+	Tasks      []ParallelTask `json:"tasks"`
+	Retry      *RetryPolicy   `json:"retry,omitempty"` // Retry policy of every task
+	TaskBudget                // Budget of the whole run
+}
+
+// ParallelTaskMetrics describes a run of parallel tasks and the DAG they
+// formed, it is the metadata of the tool response
+type ParallelTaskMetrics struct {
+	Levels      [][]string           `json:"levels"` // IDs of the tasks by depth in the DAG
+	Tasks       []ParallelTaskMetric `json:"tasks"`
+	MaxParallel int                  `json:"max_parallel"` // Most tasks that ran at once
+	Duration    time.Duration        `json:"duration"`
+	Budget      TaskBudget           `json:"budget"`
+	CostUSD     float64              `json:"cost_usd"` // Spent by the tasks of the run
+	Tokens      int64                `json:"tokens"`
+}
+
+// ParallelTaskMetric is the outcome of a task of the run
+type ParallelTaskMetric struct {
+	ID        string        `json:"id"`
+	DependsOn []string      `json:"depends_on,omitempty"`
+	Level     int           `json:"level"`
+	Status    string        `json:"status"` // metrics.StatusDone, StatusFailed, StatusCanceled, StatusBudgetExceeded or TaskStatusSkipped
+	SessionID string        `json:"session_id,omitempty"`
+	Started   time.Duration `json:"started"` // Since the start of the run
+	Duration  time.Duration `json:"duration"`
+	Retries   int           `json:"retries,omitempty"`
+	// Error is set when the task was stopped by its budget or the one of
+	// the run
+	Error *BudgetExceeded `json:"error,omitempty"`
+}
+
+// parallelTaskResult is the outcome of a task run
+type parallelTaskResult struct {
+	Report    string
+	Status    string
+	SessionID string
+	Retries   int
+	Error     *BudgetExceeded
+}
+
+// parallelTaskRun runs a task with its rendered prompt
+type parallelTaskRun func(ctx context.Context, task ParallelTask, prompt string) parallelTaskResult
+
+type parallelTasksTool struct {
+	tasks *agentTool
+}
+
+// NewParallelTasksTool returns the tool running agent tasks as a DAG
+func NewParallelTasksTool(
+	Sessions session.Service,
+	Messages message.Service,
+	Quotas quota.Service,
+	Metrics metrics.Service,
+	LspClients map[string]*lsp.Client,
+) tools.BaseTool {
+	return &parallelTasksTool{
+		tasks: NewAgentTool(Sessions, Messages, Quotas, Metrics, LspClients).(*agentTool),
+	}
+}
+
+func (p *parallelTasksTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{
+		Name:        ParallelTasksToolName,
+		Description: parallelTasksDescription,
+		Parameters: map[string]any{
+			"tasks": map[string]any{
+				"type":        "array",
+				"description": "The tasks to run",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"id": map[string]any{
+							"type":        "string",
+							"description": "Unique ID of the task, letters, digits, '_' and '-'",
+						},
+						"prompt": map[string]any{
+							"type":        "string",
+							"description": "The task for the agent to perform, {{id}} is replaced with the report of the task id",
+						},
+						"depends_on": map[string]any{
+							"type":        "array",
+							"description": "IDs of the tasks that must finish before this one",
+							"items":       map[string]any{"type": "string"},
+						},
+						"max_cost_usd": map[string]any{
+							"type":        "number",
+							"description": "Stop the task once it spent this much, in USD",
+						},
+						"max_tokens": map[string]any{
+							"type":        "integer",
+							"description": "Stop the task once its requests used this many tokens",
+						},
+						"retry": retryPolicySchema("Retry policy of this task instead of the one of the run"),
+					},
+					"required": []string{"id", "prompt"},
+				},
+			},
+			"max_cost_usd": map[string]any{
+				"type":        "number",
+				"description": "Stop the run once its tasks spent this much together, in USD",
+			},
+			"max_tokens": map[string]any{
+				"type":        "integer",
+				"description": "Stop the run once the requests of its tasks used this many tokens together",
+			},
+			"retry": retryPolicySchema("Resume the tasks that fail with a transient error"),
+		},
+		Required: []string{"tasks"},
+	}
+}
+
+func (p *parallelTasksTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	var params ParallelTaskParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return tools.NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	levels, err := planParallelTasks(params.Tasks)
+	if err != nil {
+		return tools.NewTextErrorResponse(err.Error()), nil
+	}
+	if params.Retry != nil {
+		if err := params.Retry.validate(); err != nil {
+			return tools.NewTextErrorResponse(err.Error()), nil
+		}
+	}
+
+	// The tasks count their usage toward the budget of the run, which
+	// cancels them all once it is exceeded
+	budget := newBudgetTracker(budgetScopeRun, params.TaskBudget)
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	budget.watch(func() { cancel(errBudgetExceeded) })
+	runCtx = withRunBudget(runCtx, budget)
+
+	run := func(ctx context.Context, task ParallelTask, prompt string) parallelTaskResult {
+		taskParams := AgentParams{Prompt: prompt, Retry: params.Retry, TaskBudget: task.TaskBudget}
+		if task.Retry != nil {
+			taskParams.Retry = task.Retry
+		}
+		input, _ := json.Marshal(taskParams)
+		taskCall := tools.ToolCall{ID: call.ID + "-" + task.ID, Name: AgentToolName, Input: string(input)}
+		response, retries, err := p.tasks.runTask(ctx, taskCall, taskParams)
+		result := parallelTaskResult{Report: response.Content, Status: metrics.StatusDone, SessionID: taskCall.ID, Retries: retries}
+		switch {
+		case err != nil:
+			return parallelTaskResult{Report: err.Error(), Status: metrics.StatusFailed, Retries: retries}
+		case strings.HasPrefix(response.Content, TaskBudgetExceededResult):
+			result.Status = metrics.StatusBudgetExceeded
+			result.Error = &BudgetExceeded{}
+			_ = json.Unmarshal([]byte(response.Metadata), result.Error)
+		case strings.HasPrefix(response.Content, TaskCanceledResult) || ctx.Err() != nil:
+			result.Status = metrics.StatusCanceled
+		case response.IsError:
+			result.Status = metrics.StatusFailed
+		}
+		return result
+	}
+	reports, taskMetrics := runParallelTasks(runCtx, params.Tasks, levels, run)
+	if ctx.Err() != nil {
+		return tools.ToolResponse{}, ctx.Err()
+	}
+	taskMetrics.Budget = params.TaskBudget
+	taskMetrics.CostUSD, taskMetrics.Tokens = budget.usage()
+	exceeded, overBudget := budget.exceededBy()
+	sessionID, _ := tools.GetContextValues(ctx)
+	p.recordRun(call.ID, sessionID, taskMetrics, overBudget)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Ran %d tasks in %d levels, up to %d at a time, in %s for $%.4f.\n", len(params.Tasks), len(levels), taskMetrics.MaxParallel, taskMetrics.Duration.Round(time.Second), taskMetrics.CostUSD)
+	if overBudget {
+		fmt.Fprintf(&sb, "The run was stopped: %s.\n", exceeded)
+	}
+	for _, metric := range taskMetrics.Tasks {
+		fmt.Fprintf(&sb, "\n<task id=%q status=%q>\n%s\n</task>\n", metric.ID, metric.Status, reports[metric.ID])
+	}
+	return tools.WithResponseMetadata(tools.NewTextResponse(sb.String()), taskMetrics), nil
+}
+
+// recordRun stores the outcome of a run for the metrics, its tasks are
+// recorded by the agent tool
+func (p *parallelTasksTool) recordRun(callID, sessionID string, runMetrics ParallelTaskMetrics, overBudget bool) {
+	run := metrics.ParallelRun{
+		ID:             callID,
+		SessionID:      sessionID,
+		Tasks:          len(runMetrics.Tasks),
+		Levels:         len(runMetrics.Levels),
+		MaxParallel:    runMetrics.MaxParallel,
+		Duration:       runMetrics.Duration,
+		Tokens:         runMetrics.Tokens,
+		Cost:           runMetrics.CostUSD,
+		BudgetExceeded: overBudget,
+	}
+	for _, task := range runMetrics.Tasks {
+		if task.Status == metrics.StatusDone {
+			run.Succeeded++
+		}
+	}
+	if dag, err := json.Marshal(runMetrics); err == nil {
+		run.DAG = string(dag)
+	}
+	if err := p.tasks.metrics.RecordParallelRun(context.Background(), run); err != nil {
+		logging.Warn("Failed to record parallel run metrics", "run", callID, "error", err)
+	}
+}
+
+// planParallelTasks checks the tasks and their dependencies and returns the
+// IDs of the tasks by level: the tasks of a level only depend on tasks of
+// the levels before it
+func planParallelTasks(tasks []ParallelTask) ([][]string, error) {
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("tasks is required")
+	}
+	byID := make(map[string]ParallelTask, len(tasks))
+	for _, task := range tasks {
+		if !taskIDPattern.MatchString(task.ID) {
+			return nil, fmt.Errorf("invalid task id %q, use letters, digits, '_' and '-'", task.ID)
+		}
+		if _, ok := byID[task.ID]; ok {
+			return nil, fmt.Errorf("task id %q is used twice", task.ID)
+		}
+		if strings.TrimSpace(task.Prompt) == "" {
+			return nil, fmt.Errorf("task %q has no prompt", task.ID)
+		}
+		if task.Retry != nil {
+			if err := task.Retry.validate(); err != nil {
+				return nil, fmt.Errorf("task %q: %w", task.ID, err)
+			}
+		}
+		byID[task.ID] = task
+	}
+	for _, task := range tasks {
+		for _, dep := range task.DependsOn {
+			if _, ok := byID[dep]; !ok {
+				return nil, fmt.Errorf("task %q depends on unknown task %q", task.ID, dep)
+			}
+			if dep == task.ID {
+				return nil, fmt.Errorf("task %q depends on itself", task.ID)
+			}
+		}
+		for _, match := range taskVariablePattern.FindAllStringSubmatch(task.Prompt, -1) {
+			if !slices.Contains(task.DependsOn, match[1]) {
+				return nil, fmt.Errorf("task %q uses {{%s}} without depending on it, add it to depends_on", task.ID, match[1])
+			}
+		}
+	}
+
+	// Kahn's algorithm, a level holds the tasks whose dependencies are all
+	// in the levels before it, in the order they were given
+	level := make(map[string]int, len(tasks))
+	var levels [][]string
+	for len(level) < len(tasks) {
+		var next []string
+		for _, task := range tasks {
+			if _, ok := level[task.ID]; ok {
+				continue
+			}
+			ready := true
+			for _, dep := range task.DependsOn {
+				if l, ok := level[dep]; !ok || l == len(levels) {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = append(next, task.ID)
+			}
+		}
+		if len(next) == 0 {
+			var cycle []string
+			for _, task := range tasks {
+				if _, ok := level[task.ID]; !ok {
+					cycle = append(cycle, task.ID)
+				}
+			}
+			return nil, fmt.Errorf("the dependencies of tasks %s form a cycle", strings.Join(cycle, ", "))
+		}
+		for _, id := range next {
+			level[id] = len(levels)
+		}
+		levels = append(levels, next)
+	}
+	return levels, nil
+}
+
+// renderTaskPrompt replaces the {{id}} variables of the prompt of a task with
+// the reports of its dependencies, and adds the reports it does not use at
+// the end
+func renderTaskPrompt(task ParallelTask, reports map[string]string) string {
+	used := map[string]bool{}
+	prompt := taskVariablePattern.ReplaceAllStringFunc(task.Prompt, func(variable string) string {
+		id := taskVariablePattern.FindStringSubmatch(variable)[1]
+		used[id] = true
+		return reports[id]
+	})
+	for _, dep := range task.DependsOn {
+		if !used[dep] {
+			prompt += fmt.Sprintf("\n\n<task id=%q>\n%s\n</task>", dep, reports[dep])
+		}
+	}
+	return prompt
+}
+
+// taskReport returns the report of a task without its continuation note, to
+// pass it on to the tasks depending on it
+func taskReport(result string) string {
+	if i := strings.LastIndex(result, "\n\n<continuation>"); i >= 0 {
+		return result[:i]
+	}
+	return result
+}
+
+// runParallelTasks runs every task as soon as its dependencies finished, up
+// to maxParallelTasks at once, and returns their reports by ID and the
+// metrics of the run. Tasks whose dependencies did not finish are skipped.
+func runParallelTasks(ctx context.Context, tasks []ParallelTask, levels [][]string, run parallelTaskRun) (map[string]string, ParallelTaskMetrics) {
+	level := make(map[string]int, len(tasks))
+	for l, ids := range levels {
+		for _, id := range ids {
+			level[id] = l
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		running  int
+		started  = time.Now()
+		reports  = make(map[string]string, len(tasks))
+		statuses = make(map[string]string, len(tasks))
+		done     = make(map[string]chan struct{}, len(tasks))
+		slots    = make(chan struct{}, maxParallelTasks)
+	)
+	runMetrics := ParallelTaskMetrics{Levels: levels, Tasks: make([]ParallelTaskMetric, len(tasks))}
+	for _, task := range tasks {
+		done[task.ID] = make(chan struct{})
+	}
+
+	for i, task := range tasks {
+		runMetrics.Tasks[i] = ParallelTaskMetric{ID: task.ID, DependsOn: task.DependsOn, Level: level[task.ID]}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[task.ID])
+			metric := &runMetrics.Tasks[i]
+			skip := func(reason string) {
+				mu.Lock()
+				defer mu.Unlock()
+				statuses[task.ID] = TaskStatusSkipped
+				reports[task.ID] = "Skipped, " + reason
+				metric.Status = TaskStatusSkipped
+			}
+
+			for _, dep := range task.DependsOn {
+				<-done[dep]
+			}
+			mu.Lock()
+			upstream := make(map[string]string, len(task.DependsOn))
+			var failed []string
+			for _, dep := range task.DependsOn {
+				upstream[dep] = taskReport(reports[dep])
+				if statuses[dep] != metrics.StatusDone {
+					failed = append(failed, dep)
+				}
+			}
+			mu.Unlock()
+			if len(failed) > 0 {
+				skip("the tasks it depends on did not finish: " + strings.Join(failed, ", "))
+				return
+			}
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				if cause := context.Cause(ctx); errors.Is(cause, errBudgetExceeded) {
+					skip(cause.Error())
+				} else {
+					skip("the request was canceled")
+				}
+				return
+			}
+			mu.Lock()
+			running++
+			runMetrics.MaxParallel = max(runMetrics.MaxParallel, running)
+			metric.Started = time.Since(started)
+			mu.Unlock()
+
+			result := run(ctx, task, renderTaskPrompt(task, upstream))
+			<-slots
+
+			mu.Lock()
+			running--
+			reports[task.ID] = result.Report
+			statuses[task.ID] = result.Status
+			metric.Status = result.Status
+			metric.SessionID = result.SessionID
+			metric.Retries = result.Retries
+			metric.Error = result.Error
+			metric.Duration = time.Since(started) - metric.Started
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	runMetrics.Duration = time.Since(started)
+	return reports, runMetrics
+}
@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanParallelTasks(t *testing.T) {
+	levels, err := planParallelTasks([]ParallelTask{
+		{ID: "report", Prompt: "Summarize {{impls}} and {{callers}}", DependsOn: []string{"impls", "callers"}},
+		{ID: "impls", Prompt: "Find the implementations"},
+		{ID: "callers", Prompt: "Find the callers of {{impls}}", DependsOn: []string{"impls"}},
+		{ID: "docs", Prompt: "Find the docs"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"impls", "docs"}, {"callers"}, {"report"}}, levels)
+
+	for name, tasks := range map[string][]ParallelTask{
+		"cycle":         {{ID: "a", Prompt: "a", DependsOn: []string{"b"}}, {ID: "b", Prompt: "b", DependsOn: []string{"a"}}},
+		"self":          {{ID: "a", Prompt: "a", DependsOn: []string{"a"}}},
+		"unknown":       {{ID: "a", Prompt: "a", DependsOn: []string{"b"}}},
+		"duplicate":     {{ID: "a", Prompt: "a"}, {ID: "a", Prompt: "b"}},
+		"invalid id":    {{ID: "a b", Prompt: "a"}},
+		"no prompt":     {{ID: "a"}},
+		"undeclared":    {{ID: "a", Prompt: "a"}, {ID: "b", Prompt: "{{a}}"}},
+		"invalid retry": {{ID: "a", Prompt: "a", Retry: &RetryPolicy{MaxAttempts: 9}}},
+		"without tasks": nil,
+	} {
+		_, err := planParallelTasks(tasks)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRenderTaskPrompt(t *testing.T) {
+	task := ParallelTask{ID: "c", Prompt: "Compare {{ a }} with the rest", DependsOn: []string{"a", "b"}}
+	prompt := renderTaskPrompt(task, map[string]string{"a": "A found", "b": "B found"})
+	assert.Equal(t, "Compare A found with the rest\n\n<task id=\"b\">\nB found\n</task>", prompt)
+
+	assert.Equal(t, "Found it", taskReport("Found it\n\n<continuation>call-1</continuation>\nTo ask this agent..."))
+}
+
+func TestRunParallelTasks(t *testing.T) {
+	tasks := []ParallelTask{
+		{ID: "a", Prompt: "a"},
+		{ID: "b", Prompt: "b"},
+		{ID: "c", Prompt: "after {{a}}", DependsOn: []string{"a"}},
+		{ID: "fails", Prompt: "fails"},
+		{ID: "d", Prompt: "d", DependsOn: []string{"c", "fails"}},
+	}
+	levels, err := planParallelTasks(tasks)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	prompts := map[string]string{}
+	run := func(ctx context.Context, task ParallelTask, prompt string) parallelTaskResult {
+		mu.Lock()
+		prompts[task.ID] = prompt
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		if task.ID == "fails" {
+			return parallelTaskResult{Report: "error generating agent", Status: metrics.StatusFailed, SessionID: "session-" + task.ID}
+		}
+		return parallelTaskResult{
+			Report:    "report " + task.ID + "\n\n<continuation>session-" + task.ID + "</continuation>",
+			Status:    metrics.StatusDone,
+			SessionID: "session-" + task.ID,
+		}
+	}
+	reports, runMetrics := runParallelTasks(context.Background(), tasks, levels, run)
+
+	assert.Equal(t, "after report a", prompts["c"], "upstream reports are passed without their continuation")
+	assert.NotContains(t, prompts, "d")
+	assert.Contains(t, reports["d"], "fails")
+	assert.Equal(t, 3, runMetrics.MaxParallel, "a, b and fails run at the same time")
+
+	statuses := map[string]string{}
+	for _, task := range runMetrics.Tasks {
+		statuses[task.ID] = task.Status
+	}
+	assert.Equal(t, map[string]string{
+		"a": metrics.StatusDone, "b": metrics.StatusDone, "c": metrics.StatusDone,
+		"fails": metrics.StatusFailed, "d": TaskStatusSkipped,
+	}, statuses)
+	assert.Equal(t, 1, runMetrics.Tasks[2].Level)
+	assert.GreaterOrEqual(t, runMetrics.Tasks[2].Started, runMetrics.Tasks[0].Duration, "c starts after a finished")
+}
+
+func TestRunParallelTasksOverBudget(t *testing.T) {
+	tasks := []ParallelTask{
+		{ID: "a", Prompt: "a"},
+		{ID: "b", Prompt: "b", DependsOn: []string{"a"}},
+	}
+	levels, err := planParallelTasks(tasks)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	run := func(ctx context.Context, task ParallelTask, prompt string) parallelTaskResult {
+		// The run exceeds its budget while a runs, b must not start
+		cancel(errBudgetExceeded)
+		return parallelTaskResult{Report: TaskBudgetExceededResult, Status: metrics.StatusBudgetExceeded, Error: &BudgetExceeded{Error: budgetExceededError, Scope: budgetScopeRun}}
+	}
+	reports, runMetrics := runParallelTasks(ctx, tasks, levels, run)
+	assert.Equal(t, metrics.StatusBudgetExceeded, runMetrics.Tasks[0].Status)
+	assert.Equal(t, budgetExceededError, runMetrics.Tasks[0].Error.Error)
+	assert.Equal(t, TaskStatusSkipped, runMetrics.Tasks[1].Status)
+	assert.Contains(t, reports["b"], "a")
+}
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+const postMortemPrompt = `One of your turns in our conversation above went wrong, and the user is rolling it back. Write a short post-mortem of that turn for the project memory file, so the next sessions don't repeat the mistake.
+
+What the turn changed and what the user rolled back:
+
+%s
+
+Use this markdown format and nothing else:
+
+### <short title of what went wrong>
+
+**What happened:** one or two sentences on what the turn did and why it was wrong.
+**Root cause:** the wrong assumption, missing context or bad approach behind it.
+**Next time:** one to three concrete rules for working on this project, e.g. a command to run first or a file not to touch.
+
+Only include facts established in the conversation, don't invent details. Leave out secrets such as API keys and tokens. Keep it under 150 words.`
+
+// PostMortem writes a "what went wrong" summary of a turn of a session in
+// markdown, for the project memory file. report lists what the turn changed
+// and what was rolled back.
+func (a *agent) PostMortem(ctx context.Context, sessionID, report string) (string, error) {
+	if a.summarizeProvider == nil {
+		return "", fmt.Errorf("summarize provider not available")
+	}
+	msgs, err := a.messages.List(ctx, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list messages: %w", err)
+	}
+	if len(msgs) == 0 {
+		return "", errors.New("no messages to review")
+	}
+	msgs = append(msgs, message.Message{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: fmt.Sprintf(postMortemPrompt, report)}},
+	})
+
+	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
+	response, err := a.summarizeProvider.SendMessages(ctx, msgs, make([]tools.BaseTool, 0))
+	if err != nil {
+		return "", fmt.Errorf("failed to write post-mortem: %w", err)
+	}
+	summary := strings.TrimSpace(response.Content)
+	if summary == "" {
+		return "", errors.New("empty post-mortem returned")
+	}
+	a.trackSummarizeCost(ctx, sessionID, response.Usage)
+	return summary, nil
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/prompt"
+	"github.com/kirmad/superopencode/internal/llm/provider"
+	"github.com/kirmad/superopencode/internal/llm/tokenizer"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/pubsub"
+)
+
+const (
+	// preflightWarnRatio is the share of the context window above which the
+	// user is warned before the request is sent
+	preflightWarnRatio = 0.9
+
+	truncatedToolResult = "[tool output truncated to fit the context window]"
+)
+
+// preflight estimates the prompt size before it is sent to the provider. It
+// publishes the estimate, warns when the prompt is close to the context window
+// and truncates old tool results when the prompt would not fit at all.
+func (a *agent) preflight(ctx context.Context, sessionID string, msgHistory []message.Message) []message.Message {
+	model := a.generator(ctx).Model()
+	tk := tokenizer.ForModel(model)
+
+	fixed := a.systemPromptTokens(tk) + tk.Count(provider.SystemPromptAdditions(ctx)) + tokenizer.CountTools(tk, a.toolsFor(sessionID))
+	estimate := fixed + tokenizer.CountMessages(tk, msgHistory)
+
+	if model.ContextWindow > 0 {
+		budget := model.ContextWindow - a.maxTokens()
+		if estimate > budget {
+			msgHistory = truncateToolResults(tk, msgHistory, budget-fixed)
+			truncated := fixed + tokenizer.CountMessages(tk, msgHistory)
+			logging.WarnPersist(fmt.Sprintf("Prompt is ~%d tokens, over the %d token budget of %s; truncated old tool output to ~%d tokens", estimate, budget, model.Name, truncated))
+			estimate = truncated
+		} else if float64(estimate) > float64(model.ContextWindow)*preflightWarnRatio {
+			logging.WarnPersist(fmt.Sprintf("Prompt is ~%d tokens, %d%% of the %s context window. Consider compacting the session.", estimate, estimate*100/model.ContextWindow, model.Name))
+		}
+	}
+
+	a.Publish(pubsub.CreatedEvent, AgentEvent{
+		Type:         AgentEventTypePreflight,
+		SessionID:    sessionID,
+		PromptTokens: estimate,
+	})
+	return msgHistory
+}
+
+// systemPromptTokens returns the cached token count of the system prompt
+func (a *agent) systemPromptTokens(tk tokenizer.Tokenizer) int64 {
+	a.systemTokensMu.Lock()
+	defer a.systemTokensMu.Unlock()
+	if a.systemTokens == 0 {
+		a.systemTokens = tk.Count(prompt.GetAgentPrompt(a.name, a.provider.Model().Provider))
+	}
+	return a.systemTokens
+}
+
+// maxTokens returns the number of tokens reserved for the response
+func (a *agent) maxTokens() int64 {
+	if agentCfg, ok := config.Get().Agents[a.name]; ok && agentCfg.MaxTokens > 0 {
+		return agentCfg.MaxTokens
+	}
+	return a.provider.Model().DefaultMaxTokens
+}
+
+// truncateToolResults replaces the content of the oldest tool results until
+// the history fits in budget. The latest two messages are never touched so the
+// model keeps the context of the current step.
+func truncateToolResults(tk tokenizer.Tokenizer, msgHistory []message.Message, budget int64) []message.Message {
+	truncated := make([]message.Message, len(msgHistory))
+	copy(truncated, msgHistory)
+
+	total := tokenizer.CountMessages(tk, truncated)
+	for i := 0; i < len(truncated)-2 && total > budget; i++ {
+		if truncated[i].Role != message.Tool {
+			continue
+		}
+		parts := make([]message.ContentPart, len(truncated[i].Parts))
+		for j, part := range truncated[i].Parts {
+			if result, ok := part.(message.ToolResult); ok && len(result.Content) > len(truncatedToolResult) {
+				total -= tk.Count(result.Content) - tk.Count(truncatedToolResult)
+				result.Content = truncatedToolResult
+				part = result
+			}
+			parts[j] = part
+		}
+		truncated[i].Parts = parts
+	}
+	return truncated
+}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+// maxPullRequestPatch is the size of the changes of a session sent to draft
+// a pull request, larger patches are cut
+const maxPullRequestPatch = 60000
+
+const pullRequestPrompt = `Write a pull request for the changes of our conversation above. The changes are below as a patch.
+
+Use this markdown format and nothing else:
+
+# <short title in the imperative mood, under 72 characters>
+
+## What
+What the change does, in plain words, with the main files or packages it touches.
+
+## Why
+The problem it solves or the need behind it.
+
+## Testing
+How the change was verified in the conversation: the commands run and what they showed. Name what was not verified.
+
+Only include facts established in the conversation or visible in the patch, don't invent details. Don't list every file, the reviewers see the diff. Leave out secrets such as API keys and tokens.
+
+Changes:
+%s`
+
+// DraftPullRequest writes a pull request from the conversation of a session
+// and the patch of its changes, in markdown with the title as the first
+// heading. notes are extra instructions from the user.
+func (a *agent) DraftPullRequest(ctx context.Context, sessionID, patch, notes string) (string, error) {
+	if a.summarizeProvider == nil {
+		return "", fmt.Errorf("summarize provider not available")
+	}
+	if strings.TrimSpace(patch) == "" {
+		return "", errors.New("no changes to describe")
+	}
+	sess, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get session: %w", err)
+	}
+	msgs, err := a.messages.List(ctx, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list messages: %w", err)
+	}
+	// The summary of a compacted session stands for the messages before it
+	if i := slices.IndexFunc(msgs, func(msg message.Message) bool { return msg.ID == sess.SummaryMessageID }); i >= 0 {
+		msgs = msgs[i:]
+	}
+
+	if len(patch) > maxPullRequestPatch {
+		patch = patch[:maxPullRequestPatch] + "\n[... the rest of the patch was cut]\n"
+	}
+	prompt := fmt.Sprintf(pullRequestPrompt, patch)
+	if notes != "" {
+		prompt += "\n\nAdditional instructions: " + notes
+	}
+	msgs = append(msgs, message.Message{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: prompt}},
+	})
+
+	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
+	response, err := a.summarizeProvider.SendMessages(ctx, msgs, make([]tools.BaseTool, 0))
+	if err != nil {
+		return "", fmt.Errorf("failed to draft pull request: %w", err)
+	}
+	draft := strings.TrimSpace(response.Content)
+	if draft == "" {
+		return "", errors.New("empty pull request draft returned")
+	}
+
+	a.trackSummarizeCost(ctx, sessionID, response.Usage)
+	return draft, nil
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/llm/provider"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+// route is the model routing decision for a user request
+type route struct {
+	tier     string
+	reason   string
+	provider provider.Provider // nil when the coder model handles the tier
+}
+
+type routeContextKey struct{}
+
+var (
+	// fileReferencePattern matches paths like main.go or internal/app/app.go
+	fileReferencePattern = regexp.MustCompile(`(?:[\w-]+/)*[\w-]{2,}\.[A-Za-z][A-Za-z0-9]{0,4}\b`)
+	questionWords        = []string{"what", "why", "how", "where", "when", "which", "who", "is", "are", "does", "do", "can", "explain", "describe"}
+)
+
+// classifyRequest returns the router tier of a user request and the reason it
+// was picked
+func classifyRequest(rules config.RouterConfig, content string) (string, string) {
+	lower := strings.ToLower(content)
+	for _, keyword := range rules.ComplexKeywords {
+		pattern := `\b` + regexp.QuoteMeta(strings.ToLower(keyword)) + `\b`
+		if regexp.MustCompile(pattern).MatchString(lower) {
+			return config.RouterComplex, fmt.Sprintf("mentions %q", keyword)
+		}
+	}
+
+	files := make(map[string]bool)
+	for _, ref := range fileReferencePattern.FindAllString(content, -1) {
+		files[ref] = true
+	}
+	if rules.ComplexMinFiles > 0 && len(files) >= rules.ComplexMinFiles {
+		return config.RouterComplex, fmt.Sprintf("references %d files", len(files))
+	}
+
+	if len(content) <= rules.TrivialMaxChars && len(files) == 0 && !strings.Contains(content, "```") && isQuestion(lower) {
+		return config.RouterTrivial, "short question"
+	}
+	return config.RouterStandard, "default"
+}
+
+func isQuestion(lower string) bool {
+	lower = strings.TrimSpace(lower)
+	if strings.HasSuffix(lower, "?") {
+		return true
+	}
+	first, _, _ := strings.Cut(lower, " ")
+	for _, word := range questionWords {
+		if first == word {
+			return true
+		}
+	}
+	return false
+}
+
+// route classifies a user request and returns a context that makes the
+// generator use the model of its tier. Requests of tiers without a model use
+// the coder model.
+func (a *agent) route(ctx context.Context, sessionID, content string) context.Context {
+	routerCfg := config.Get().Router
+	if a.name != config.AgentCoder || !routerCfg.Enabled {
+		return ctx
+	}
+
+	tier, reason := classifyRequest(routerCfg, content)
+	r := &route{tier: tier, reason: reason}
+	model := a.provider.Model()
+	if modelID, ok := routerCfg.Tiers[tier]; ok && modelID != model.ID {
+		p, err := a.routedProvider(modelID)
+		if err != nil {
+			logging.Warn("failed to create routed provider, using the coder model", "model", modelID, "error", err)
+		} else {
+			r.provider = p
+			model = p.Model()
+		}
+	}
+	logging.Info("routed request", "session", sessionID, "tier", tier, "model", model.ID, "reason", reason)
+	return context.WithValue(ctx, routeContextKey{}, r)
+}
+
+// routedProvider returns the provider of a router tier model, creating it on
+// first use
+func (a *agent) routedProvider(modelID models.ModelID) (provider.Provider, error) {
+	if p, ok := a.routedProviders.Load(modelID); ok {
+		return p.(provider.Provider), nil
+	}
+	p, err := createProvider(a.name, modelID, a.detailedLogger)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := a.routedProviders.LoadOrStore(modelID, p)
+	return actual.(provider.Provider), nil
+}
+
+// routePart returns the message part recording the routing decision of ctx
+func routePart(ctx context.Context) []message.ContentPart {
+	r, ok := ctx.Value(routeContextKey{}).(*route)
+	if !ok {
+		return []message.ContentPart{}
+	}
+	return []message.ContentPart{message.Route{Tier: r.tier, Reason: r.reason}}
+}
@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyRequest(t *testing.T) {
+	rules := config.RouterConfig{
+		TrivialMaxChars: 200,
+		ComplexMinFiles: 3,
+		ComplexKeywords: []string{"refactor", "every file"},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		tier    string
+		reason  string
+	}{
+		{"short question", "What does the --cwd flag do?", config.RouterTrivial, "short question"},
+		{"question word", "explain the difference between a slice and an array", config.RouterTrivial, "short question"},
+		{"question about a file", "what does main.go do?", config.RouterStandard, "default"},
+		{"keyword", "Refactor the session service to use generics", config.RouterComplex, `mentions "refactor"`},
+		{"keyword inside a word", "The refactoring broke the build, fix it", config.RouterStandard, "default"},
+		{"phrase keyword", "Add a license header to every file", config.RouterComplex, `mentions "every file"`},
+		{"many files", "Update internal/app/app.go, cmd/root.go and internal/db/db.go for the new flag", config.RouterComplex, "references 3 files"},
+		{"abbreviations are no files", "Add a flag, e.g. --verbose, i.e. more output, to main.go", config.RouterStandard, "default"},
+		{"task", "Add a --verbose flag to the run command", config.RouterStandard, "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tier, reason := classifyRequest(rules, tt.content)
+			assert.Equal(t, tt.tier, tier)
+			assert.Equal(t, tt.reason, reason)
+		})
+	}
+}
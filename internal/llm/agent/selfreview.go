@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+const (
+	maxCheckOutput = 4000
+	maxReviewDiff  = 20000
+)
+
+// selfReview tracks the verification pass of a single turn
+type selfReview struct {
+	start       int // Index of the first message of the turn in the history
+	fixAttempts int
+	reviewed    bool
+}
+
+type checkResult struct {
+	command  string
+	output   string
+	exitCode int
+}
+
+// selfReviewFollowUp runs the self-review pass once the agent finished a turn
+// that changed files. It returns the prompt sending the agent back to work:
+// the failing checks while fix attempts remain, then a request for a short
+// review of the diff. An empty string means the turn is complete.
+func (a *agent) selfReviewFollowUp(ctx context.Context, review *selfReview, msgHistory []message.Message) string {
+	cfg := config.Get().SelfReview
+	if !cfg.Enabled || a.name != config.AgentCoder || review.reviewed {
+		return ""
+	}
+
+	diff := turnChanges(msgHistory[review.start:])
+	if diff == "" {
+		return ""
+	}
+
+	var failed []checkResult
+	for _, check := range cfg.Checks {
+		if result := runCheck(ctx, check, time.Duration(cfg.TimeoutSeconds)*time.Second); result.exitCode != 0 {
+			failed = append(failed, result)
+		}
+	}
+
+	if len(failed) > 0 {
+		if review.fixAttempts >= cfg.MaxFixAttempts {
+			logging.WarnPersist(fmt.Sprintf("Self-review: %d check(s) still failing after %d fix attempt(s)", len(failed), review.fixAttempts))
+			return ""
+		}
+		review.fixAttempts++
+		logging.InfoPersist(fmt.Sprintf("Self-review: %d check(s) failed, starting fix attempt %d/%d", len(failed), review.fixAttempts, cfg.MaxFixAttempts))
+
+		var sb strings.Builder
+		sb.WriteString("<self-review>\nThe following checks failed after your changes:\n")
+		for _, result := range failed {
+			sb.WriteString(fmt.Sprintf("\n$ %s (exit code %d)\n%s\n", result.command, result.exitCode, result.output))
+		}
+		sb.WriteString("\nFix the failures. Only change what is needed to make the checks pass.\n</self-review>")
+		return sb.String()
+	}
+
+	review.reviewed = true
+	if len(diff) > maxReviewDiff {
+		diff = diff[:maxReviewDiff] + "\n[diff truncated]"
+	}
+	checks := "No checks are configured."
+	if len(cfg.Checks) > 0 {
+		checks = fmt.Sprintf("All checks passed: %s.", strings.Join(cfg.Checks, ", "))
+	}
+	return fmt.Sprintf(`<self-review>
+%s Review the changes you made during this task:
+
+%s
+
+Reply with a short self-review: what changed, anything that looks wrong or risky, and follow-ups the user should know about. Only make further changes if you find a bug.
+</self-review>`, checks, diff)
+}
+
+// turnChanges collects the diffs of the successful file changes in msgs
+func turnChanges(msgs []message.Message) string {
+	calls := make(map[string]message.ToolCall)
+	var sb strings.Builder
+	for _, msg := range msgs {
+		for _, call := range msg.ToolCalls() {
+			calls[call.ID] = call
+		}
+		for _, result := range msg.ToolResults() {
+			call, ok := calls[result.ToolCallID]
+			if !ok || result.IsError {
+				continue
+			}
+			switch call.Name {
+			case tools.EditToolName, tools.WriteToolName:
+				var metadata tools.EditResponseMetadata
+				if err := json.Unmarshal([]byte(result.Metadata), &metadata); err == nil && metadata.Diff != "" {
+					sb.WriteString(metadata.Diff)
+					sb.WriteString("\n")
+				}
+			case tools.PatchToolName:
+				var metadata tools.PatchResponseMetadata
+				if err := json.Unmarshal([]byte(result.Metadata), &metadata); err == nil && len(metadata.FilesChanged) > 0 {
+					sb.WriteString(fmt.Sprintf("Patched: %s\n", strings.Join(metadata.FilesChanged, ", ")))
+				}
+			}
+		}
+	}
+	return sb.String()
+}
+
+// runCheck runs a check command from the working directory
+func runCheck(ctx context.Context, command string, timeout time.Duration) checkResult {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	shellPath := config.Get().Shell.Path
+	if shellPath == "" {
+		shellPath = "/bin/bash"
+	}
+	cmd := exec.CommandContext(ctx, shellPath, "-c", command)
+	cmd.Dir = config.WorkingDirectory()
+	out, err := cmd.CombinedOutput()
+
+	result := checkResult{command: command, output: string(out)}
+	if err != nil {
+		result.exitCode = 1
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+			result.exitCode = exitErr.ExitCode()
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			result.output += fmt.Sprintf("\n[check timed out after %s]", timeout)
+		}
+	}
+	if len(result.output) > maxCheckOutput {
+		result.output = "[output truncated]\n" + result.output[len(result.output)-maxCheckOutput:]
+	}
+	return result
+}
@@ -0,0 +1,33 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTurnChanges(t *testing.T) {
+	msgs := []message.Message{
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			message.ToolCall{ID: "1", Name: tools.EditToolName},
+			message.ToolCall{ID: "2", Name: tools.WriteToolName},
+			message.ToolCall{ID: "3", Name: tools.ViewToolName},
+			message.ToolCall{ID: "4", Name: tools.PatchToolName},
+		}},
+		{Role: message.Tool, Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: "1", Metadata: `{"diff":"--- a/main.go\n+++ b/main.go"}`},
+			message.ToolResult{ToolCallID: "2", Metadata: `{"diff":"--- a/fail.go"}`, IsError: true},
+			message.ToolResult{ToolCallID: "3", Content: "file content"},
+			message.ToolResult{ToolCallID: "4", Metadata: `{"files_changed":["a.go","b.go"]}`},
+		}},
+	}
+
+	changes := turnChanges(msgs)
+	assert.Contains(t, changes, "+++ b/main.go")
+	assert.Contains(t, changes, "Patched: a.go, b.go")
+	assert.NotContains(t, changes, "fail.go")
+
+	assert.Empty(t, turnChanges(msgs[:1]), "tool calls without results are not changes")
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/kirmad/superopencode/internal/llm/tools"
+)
+
+// sessionToolStore keeps the tools enabled per session. Sessions without an
+// entry can use every tool of the agent.
+type sessionToolStore struct {
+	mu      sync.RWMutex
+	enabled map[string][]string // sessionID -> tool names
+}
+
+var sessionTools = &sessionToolStore{
+	enabled: make(map[string][]string),
+}
+
+// SetSessionTools restricts a session to the named tools. An empty list
+// enables every tool again.
+func SetSessionTools(sessionID string, names []string) {
+	sessionTools.mu.Lock()
+	defer sessionTools.mu.Unlock()
+	if len(names) == 0 {
+		delete(sessionTools.enabled, sessionID)
+		return
+	}
+	sessionTools.enabled[sessionID] = slices.Clone(names)
+}
+
+// SessionTools returns the tools enabled for a session, nil when every tool is enabled
+func SessionTools(sessionID string) []string {
+	sessionTools.mu.RLock()
+	defer sessionTools.mu.RUnlock()
+	return slices.Clone(sessionTools.enabled[sessionID])
+}
+
+// toolsFor returns the agent tools enabled for a session
+func (a *agent) toolsFor(sessionID string) []tools.BaseTool {
+	names := SessionTools(sessionID)
+	if names == nil {
+		return a.tools
+	}
+	enabled := make([]tools.BaseTool, 0, len(names))
+	for _, tool := range a.tools {
+		if slices.Contains(names, tool.Info().Name) {
+			enabled = append(enabled, tool)
+		}
+	}
+	return enabled
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/provider"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+const verifyPrompt = `A faster assistant drafted the next step of this conversation. Before it runs, review the tool call below as the senior engineer responsible for the change.
+
+Assistant text:
+%s
+
+Tool: %s
+Input:
+%s
+
+Approve the call if it is correct, safe and moves the task forward. Reject it if it is wrong, destructive, outside the scope of the request or would leave the code broken.
+Answer with APPROVE on the first line, or REJECT: followed by a short explanation of what to do instead. Do not call any tools.`
+
+// generator returns the provider used to generate the next step
+func (a *agent) generator(ctx context.Context) provider.Provider {
+	if a.draftProvider != nil {
+		return a.draftProvider
+	}
+	if r, ok := ctx.Value(routeContextKey{}).(*route); ok && r.provider != nil {
+		return r.provider
+	}
+	return a.provider
+}
+
+// verifyToolCall asks the agent model to approve a drafted tool call when the
+// routing rules require it. It returns the tool result to report to the draft
+// model when the call is rejected, or an empty string when it may run.
+func (a *agent) verifyToolCall(ctx context.Context, sessionID string, msgHistory []message.Message, assistantMsg message.Message, toolCall message.ToolCall) string {
+	if a.draftProvider == nil || config.Get().Speculative.ActionFor(toolCall.Name) != config.SpeculativeVerify {
+		return ""
+	}
+
+	request := message.Message{
+		Role: message.User,
+		Parts: []message.ContentPart{message.TextContent{
+			Text: fmt.Sprintf(verifyPrompt, assistantMsg.Content().String(), toolCall.Name, toolCall.Input),
+		}},
+	}
+	history := append(append([]message.Message{}, msgHistory...), request)
+
+	resp, err := a.provider.SendMessages(ctx, history, nil)
+	if err != nil {
+		logging.Warn("Failed to verify drafted tool call", "tool", toolCall.Name, "error", err)
+		return fmt.Sprintf("This action could not be verified (%v) and was not run. Try again.", err)
+	}
+	if err := a.TrackUsage(ctx, sessionID, a.provider.Model(), resp.Usage); err != nil {
+		logging.Warn("Failed to track verification usage", "error", err)
+	}
+
+	approved, reason := parseVerdict(resp.Content)
+	if approved {
+		return ""
+	}
+	logging.Info("Drafted tool call rejected", "tool", toolCall.Name, "reason", reason)
+	return fmt.Sprintf("This action was rejected during review and was not run: %s", reason)
+}
+
+// parseVerdict reads the verdict of a verification response. Anything that is
+// not an explicit approval is treated as a rejection.
+func parseVerdict(content string) (bool, string) {
+	content = strings.TrimSpace(content)
+	firstLine, rest, _ := strings.Cut(content, "\n")
+	firstLine = strings.Trim(strings.TrimSpace(firstLine), "*`#. ")
+	verdict := strings.ToUpper(firstLine)
+
+	switch {
+	case strings.HasPrefix(verdict, "APPROVE"):
+		return true, ""
+	case strings.HasPrefix(verdict, "REJECT"):
+		reason := strings.TrimSpace(strings.TrimLeft(firstLine[len("REJECT"):], "*`:.- "))
+		if reason == "" {
+			reason = strings.TrimSpace(rest)
+		}
+		if reason == "" {
+			reason = "no reason given"
+		}
+		return false, reason
+	}
+	return false, fmt.Sprintf("unclear review verdict: %s", content)
+}
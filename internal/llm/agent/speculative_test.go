@@ -0,0 +1,30 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVerdict(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		approved bool
+		reason   string
+	}{
+		{"approve", "APPROVE", true, ""},
+		{"approve with markdown", "**Approve**\nLooks good.", true, ""},
+		{"reject inline", "REJECT: this deletes the migrations directory", false, "this deletes the migrations directory"},
+		{"reject next line", "REJECT\nRun the tests first.", false, "Run the tests first."},
+		{"reject without reason", "reject", false, "no reason given"},
+		{"unclear", "I think this is fine", false, "unclear review verdict: I think this is fine"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approved, reason := parseVerdict(tt.content)
+			assert.Equal(t, tt.approved, approved)
+			assert.Equal(t, tt.reason, reason)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/kirmad/superopencode/internal/logging"
+)
+
+// toolStoppedResult is the result of the tool calls skipped once the user
+// asked to stop
+const toolStoppedResult = "Not run: the user asked to stop after the current tool."
+
+// stopReportPrompt asks the agent to report where it stopped instead of going
+// on with its plan
+const stopReportPrompt = `<system-reminder>The user asked you to stop after the current tool, the tool calls after it were not run. Don't call any more tools. Report briefly what you did, what is left to do and the state the work is in, e.g. files edited halfway or commands that didn't run, so the user can decide how to go on.</system-reminder>`
+
+// Stop asks the agent working in a session to finish the tool it runs, skip
+// the next ones and report where it stopped. Unlike Cancel, the running tool
+// is not interrupted and the work done so far is kept. It returns false when
+// the session isn't busy.
+func (a *agent) Stop(sessionID string) bool {
+	if !a.IsSessionBusy(sessionID) {
+		return false
+	}
+	logging.InfoPersist(fmt.Sprintf("Stop after the current tool requested for session: %s", sessionID))
+	a.stopRequests.Store(sessionID, true)
+	return true
+}
+
+// isStopping reports whether the user asked to stop the request of a session
+func (a *agent) isStopping(sessionID string) bool {
+	_, stopping := a.stopRequests.Load(sessionID)
+	return stopping
+}
@@ -0,0 +1,278 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/llm/provider"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/pubsub"
+	"github.com/kirmad/superopencode/internal/quota"
+	"github.com/kirmad/superopencode/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedProvider answers each request with the next response of a script
+type scriptedProvider struct {
+	mu        sync.Mutex
+	responses []provider.ProviderResponse
+	requests  [][]message.Message
+}
+
+func (p *scriptedProvider) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*provider.ProviderResponse, error) {
+	return nil, errors.New("not scripted")
+}
+
+func (p *scriptedProvider) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan provider.ProviderEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, messages)
+	events := make(chan provider.ProviderEvent, 2)
+	if len(p.responses) == 0 {
+		events <- provider.ProviderEvent{Type: provider.EventError, Error: errors.New("no more responses")}
+	} else {
+		response := p.responses[0]
+		p.responses = p.responses[1:]
+		if response.Content != "" {
+			events <- provider.ProviderEvent{Type: provider.EventContentDelta, Content: response.Content}
+		}
+		events <- provider.ProviderEvent{Type: provider.EventComplete, Response: &response}
+	}
+	close(events)
+	return events
+}
+
+func (p *scriptedProvider) Model() models.Model {
+	return models.Model{ID: "scripted", Provider: "scripted", CostPer1MIn: 1}
+}
+
+// blockingTool runs until it's released or its context is canceled
+type blockingTool struct {
+	started  chan struct{}
+	release  chan struct{}
+	canceled atomic.Bool
+}
+
+func newBlockingTool() *blockingTool {
+	return &blockingTool{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (b *blockingTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{Name: "block", Parameters: map[string]any{}}
+}
+
+func (b *blockingTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	close(b.started)
+	select {
+	case <-b.release:
+		return tools.NewTextResponse("released"), nil
+	case <-ctx.Done():
+		b.canceled.Store(true)
+		return tools.ToolResponse{}, ctx.Err()
+	}
+}
+
+// countingTool counts its runs
+type countingTool struct {
+	runs atomic.Int32
+}
+
+func (c *countingTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{Name: "count", Parameters: map[string]any{}}
+}
+
+func (c *countingTool) Run(ctx context.Context, call tools.ToolCall) (tools.ToolResponse, error) {
+	c.runs.Add(1)
+	return tools.NewTextResponse("counted"), nil
+}
+
+// newTestQueries loads the config and returns the queries of an empty
+// in-memory database
+func newTestQueries(t *testing.T) db.Querier {
+	t.Helper()
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	conn, err := db.ConnectEphemeral()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return db.New(conn)
+}
+
+// newScriptedAgent returns an agent of the script, and a session to run it in
+func newScriptedAgent(t *testing.T, q db.Querier, p provider.Provider, agentTools ...tools.BaseTool) (*agent, string) {
+	t.Helper()
+	a := &agent{
+		Broker:   pubsub.NewBroker[AgentEvent](),
+		name:     config.AgentTask,
+		sessions: session.NewService(q),
+		messages: message.NewService(q),
+		quotas:   quota.NewService(q),
+		tools:    agentTools,
+		provider: p,
+		budgets:  tools.NewBudgetTracker(),
+	}
+	sess, err := a.sessions.Create(context.Background(), "test")
+	require.NoError(t, err)
+	return a, sess.ID
+}
+
+// toolCallsTurn is a response calling the blocking tool, then the counting
+// tool
+func toolCallsTurn() provider.ProviderResponse {
+	return provider.ProviderResponse{
+		ToolCalls: []message.ToolCall{
+			{ID: "call-block", Name: "block", Input: "{}", Type: "function", Finished: true},
+			{ID: "call-count", Name: "count", Input: "{}", Type: "function", Finished: true},
+		},
+		FinishReason: message.FinishReasonToolUse,
+	}
+}
+
+// waitResult waits for the result of a request
+func waitResult(t *testing.T, events <-chan AgentEvent) AgentEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(10 * time.Second):
+		t.Fatal("the request didn't end")
+		return AgentEvent{}
+	}
+}
+
+// toolResults returns the tool results of a session by tool call, and checks
+// every tool call has exactly one
+func toolResults(t *testing.T, a *agent, sessionID string) map[string]message.ToolResult {
+	t.Helper()
+	msgs, err := a.messages.List(context.Background(), sessionID)
+	require.NoError(t, err)
+	results := make(map[string]message.ToolResult)
+	for _, msg := range msgs {
+		for _, result := range msg.ToolResults() {
+			_, duplicate := results[result.ToolCallID]
+			assert.False(t, duplicate, "tool call %s answered twice", result.ToolCallID)
+			results[result.ToolCallID] = result
+		}
+	}
+	for _, msg := range msgs {
+		if msg.Role != message.Assistant {
+			continue
+		}
+		assert.NotEmpty(t, msg.FinishReason(), "assistant message %s is finished", msg.ID)
+		for _, call := range msg.ToolCalls() {
+			assert.Contains(t, results, call.ID, "tool call %s is answered", call.ID)
+		}
+	}
+	return results
+}
+
+func TestCancelInterruptsRunningTool(t *testing.T) {
+	block, count := newBlockingTool(), &countingTool{}
+	p := &scriptedProvider{responses: []provider.ProviderResponse{toolCallsTurn()}}
+	a, sessionID := newScriptedAgent(t, newTestQueries(t), p, block, count)
+
+	events, err := a.Run(context.Background(), sessionID, "run the tools")
+	require.NoError(t, err)
+	<-block.started
+	a.Cancel(sessionID)
+	result := waitResult(t, events)
+
+	assert.ErrorIs(t, result.Error, ErrRequestCancelled)
+	assert.True(t, block.canceled.Load(), "the running tool is interrupted")
+	assert.Zero(t, count.runs.Load(), "the next tool doesn't run")
+	assert.False(t, a.IsSessionBusy(sessionID))
+
+	results := toolResults(t, a, sessionID)
+	assert.True(t, results["call-block"].IsError)
+	assert.True(t, results["call-count"].IsError)
+	msgs, err := a.messages.List(context.Background(), sessionID)
+	require.NoError(t, err)
+	for _, msg := range msgs {
+		if msg.Role == message.Assistant {
+			assert.Equal(t, message.FinishReasonCanceled, msg.FinishReason())
+		}
+	}
+
+	// The session takes new requests
+	p.mu.Lock()
+	p.responses = []provider.ProviderResponse{{Content: "done", FinishReason: message.FinishReasonEndTurn}}
+	p.mu.Unlock()
+	events, err = a.Run(context.Background(), sessionID, "go on")
+	require.NoError(t, err)
+	result = waitResult(t, events)
+	require.NoError(t, result.Error)
+	assert.Equal(t, "done", result.Message.Content().String())
+}
+
+func TestStopAfterRunningTool(t *testing.T) {
+	block, count := newBlockingTool(), &countingTool{}
+	p := &scriptedProvider{responses: []provider.ProviderResponse{
+		toolCallsTurn(),
+		{Content: "Stopped after the first tool.", FinishReason: message.FinishReasonEndTurn},
+	}}
+	a, sessionID := newScriptedAgent(t, newTestQueries(t), p, block, count)
+	assert.False(t, a.Stop(sessionID), "idle sessions have nothing to stop")
+
+	events, err := a.Run(context.Background(), sessionID, "run the tools")
+	require.NoError(t, err)
+	<-block.started
+	assert.True(t, a.Stop(sessionID))
+	close(block.release)
+	result := waitResult(t, events)
+
+	require.NoError(t, result.Error)
+	assert.True(t, result.Done)
+	assert.Equal(t, "Stopped after the first tool.", result.Message.Content().String())
+	assert.False(t, block.canceled.Load(), "the running tool finishes")
+	assert.Zero(t, count.runs.Load(), "the next tool doesn't run")
+	assert.False(t, a.IsSessionBusy(sessionID))
+	assert.False(t, a.isStopping(sessionID), "the stop request is cleared")
+
+	results := toolResults(t, a, sessionID)
+	assert.Equal(t, "released", results["call-block"].Content)
+	assert.Equal(t, toolStoppedResult, results["call-count"].Content)
+	assert.True(t, results["call-count"].IsError)
+
+	// The agent is asked for a report of where it stopped
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	require.Len(t, p.requests, 2)
+	report := p.requests[1][len(p.requests[1])-1]
+	assert.Equal(t, message.User, report.Role)
+	assert.Equal(t, stopReportPrompt, report.Content().String())
+}
+
+func TestStopReportCallingTools(t *testing.T) {
+	block, count := newBlockingTool(), &countingTool{}
+	p := &scriptedProvider{responses: []provider.ProviderResponse{
+		toolCallsTurn(),
+		{
+			ToolCalls:    []message.ToolCall{{ID: "call-again", Name: "count", Input: "{}", Type: "function", Finished: true}},
+			FinishReason: message.FinishReasonToolUse,
+		},
+	}}
+	a, sessionID := newScriptedAgent(t, newTestQueries(t), p, block, count)
+
+	events, err := a.Run(context.Background(), sessionID, "run the tools")
+	require.NoError(t, err)
+	<-block.started
+	a.Stop(sessionID)
+	close(block.release)
+	result := waitResult(t, events)
+
+	// The tools the report calls are skipped and the request ends
+	require.NoError(t, result.Error)
+	assert.True(t, result.Done)
+	assert.Zero(t, count.runs.Load())
+	results := toolResults(t, a, sessionID)
+	assert.Equal(t, toolStoppedResult, results["call-again"].Content)
+}
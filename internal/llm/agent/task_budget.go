@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// TaskBudgetExceededResult starts the agent tool result of a task stopped
+// because it exceeded its budget, followed by the budget and the token to
+// resume it
+const TaskBudgetExceededResult = "Task stopped: budget exceeded."
+
+// TaskBudget limits what a task or a parallel run may spend, a zero limit is
+// no limit
+type TaskBudget struct {
+	MaxCostUSD float64 `json:"max_cost_usd,omitempty"`
+	MaxTokens  int64   `json:"max_tokens,omitempty"`
+}
+
+func (b TaskBudget) isZero() bool {
+	return b.MaxCostUSD <= 0 && b.MaxTokens <= 0
+}
+
+// BudgetExceeded is the structured error of a task stopped by its budget or
+// the budget of its parallel run, it is the metadata of the tool response
+type BudgetExceeded struct {
+	Error     string     `json:"error"` // Always "budget_exceeded"
+	Scope     string     `json:"scope"` // "task" or "run"
+	Limit     TaskBudget `json:"limit"`
+	CostUSD   float64    `json:"cost_usd"` // Spent by the scope when it was stopped
+	Tokens    int64      `json:"tokens"`
+	SessionID string     `json:"session_id"`
+}
+
+func (e BudgetExceeded) String() string {
+	scope := "task"
+	if e.Scope == budgetScopeRun {
+		scope = "parallel run"
+	}
+	if e.Limit.MaxCostUSD > 0 && e.CostUSD >= e.Limit.MaxCostUSD {
+		return fmt.Sprintf("$%.4f spent of the $%.4f budget of the %s", e.CostUSD, e.Limit.MaxCostUSD, scope)
+	}
+	return fmt.Sprintf("%d tokens used of the %d token budget of the %s", e.Tokens, e.Limit.MaxTokens, scope)
+}
+
+const (
+	budgetExceededError = "budget_exceeded"
+	budgetScopeTask     = "task"
+	budgetScopeRun      = "run"
+)
+
+// errBudgetExceeded is the cause of the cancellation of the tasks of a
+// parallel run that exceeded its budget
+var errBudgetExceeded = errors.New("the budget of the parallel run was exceeded")
+
+// budgetTracker adds up the usage of a task, or of the tasks of a parallel
+// run, and cancels them once it exceeds the budget
+type budgetTracker struct {
+	mu       sync.Mutex
+	scope    string
+	limit    TaskBudget
+	cost     float64
+	tokens   int64
+	exceeded bool
+	cancels  []context.CancelFunc
+	parent   *budgetTracker // The budget of the parallel run of the task
+}
+
+func newBudgetTracker(scope string, limit TaskBudget) *budgetTracker {
+	return &budgetTracker{scope: scope, limit: limit}
+}
+
+// add records the usage of a request, in the budget of the parallel run of
+// the task as well
+func (t *budgetTracker) add(cost float64, tokens int64) {
+	t.mu.Lock()
+	t.cost += cost
+	t.tokens += tokens
+	if !t.exceeded && ((t.limit.MaxCostUSD > 0 && t.cost >= t.limit.MaxCostUSD) || (t.limit.MaxTokens > 0 && t.tokens >= t.limit.MaxTokens)) {
+		t.exceeded = true
+		for _, cancel := range t.cancels {
+			cancel()
+		}
+	}
+	t.mu.Unlock()
+	if t.parent != nil {
+		t.parent.add(cost, tokens)
+	}
+}
+
+// watch cancels the context of a task, or of a parallel run, when the budget
+// is exceeded
+func (t *budgetTracker) watch(cancel context.CancelFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.exceeded {
+		cancel()
+	}
+	t.cancels = append(t.cancels, cancel)
+}
+
+// usage returns the cost and the tokens recorded so far
+func (t *budgetTracker) usage() (float64, int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cost, t.tokens
+}
+
+// exceededBy returns the error of the budget that stopped a task: its own or
+// the one of its parallel run
+func (t *budgetTracker) exceededBy() (BudgetExceeded, bool) {
+	for tracker := t; tracker != nil; tracker = tracker.parent {
+		tracker.mu.Lock()
+		exceeded := BudgetExceeded{
+			Error:   budgetExceededError,
+			Scope:   tracker.scope,
+			Limit:   tracker.limit,
+			CostUSD: tracker.cost,
+			Tokens:  tracker.tokens,
+		}
+		ok := tracker.exceeded
+		tracker.mu.Unlock()
+		if ok {
+			return exceeded, true
+		}
+	}
+	return BudgetExceeded{}, false
+}
+
+type budgetContextKey struct{}
+
+// withRunBudget makes the tasks run with ctx count their usage toward the
+// budget of their parallel run as well
+func withRunBudget(ctx context.Context, run *budgetTracker) context.Context {
+	return context.WithValue(ctx, budgetContextKey{}, run)
+}
+
+func runBudget(ctx context.Context) *budgetTracker {
+	run, _ := ctx.Value(budgetContextKey{}).(*budgetTracker)
+	return run
+}
+
+// taskBudgets holds the budget trackers of the running tasks by task session
+// ID, the usage of the agent running a task is recorded in them
+var taskBudgets = struct {
+	mu       sync.Mutex
+	trackers map[string]*budgetTracker
+}{trackers: make(map[string]*budgetTracker)}
+
+// startTaskBudget makes the task running in the session stop with ctx once
+// it, or its parallel run, exceeds its budget. It returns the context the
+// task runs in and a function that must be called once it is done. Tasks
+// without a budget outside of a parallel run are not tracked.
+func startTaskBudget(ctx context.Context, taskSessionID string, limit TaskBudget) (context.Context, *budgetTracker, func()) {
+	run := runBudget(ctx)
+	if limit.isZero() && run == nil {
+		return ctx, nil, func() {}
+	}
+	tracker := newBudgetTracker(budgetScopeTask, limit)
+	tracker.parent = run
+	taskCtx, cancel := context.WithCancel(ctx)
+	// The context of a parallel run is canceled with its budget
+	tracker.watch(cancel)
+
+	taskBudgets.mu.Lock()
+	taskBudgets.trackers[taskSessionID] = tracker
+	taskBudgets.mu.Unlock()
+	return taskCtx, tracker, func() {
+		taskBudgets.mu.Lock()
+		delete(taskBudgets.trackers, taskSessionID)
+		taskBudgets.mu.Unlock()
+		cancel()
+	}
+}
+
+// recordTaskUsage adds the usage of a request made in a session to the
+// budget of the task running in it, if any
+func recordTaskUsage(sessionID string, cost float64, tokens int64) {
+	taskBudgets.mu.Lock()
+	tracker := taskBudgets.trackers[sessionID]
+	taskBudgets.mu.Unlock()
+	if tracker != nil {
+		tracker.add(cost, tokens)
+	}
+}
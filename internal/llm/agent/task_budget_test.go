@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskBudget(t *testing.T) {
+	ctx, tracker, finish := startTaskBudget(context.Background(), "task-1", TaskBudget{MaxCostUSD: 0.5})
+	defer finish()
+	require.NotNil(t, tracker)
+
+	recordTaskUsage("task-1", 0.3, 1000)
+	recordTaskUsage("other", 1, 1000)
+	assert.NoError(t, ctx.Err())
+	_, exceeded := tracker.exceededBy()
+	assert.False(t, exceeded)
+
+	recordTaskUsage("task-1", 0.25, 1000)
+	assert.Error(t, ctx.Err(), "the task is canceled once it exceeds its budget")
+	err, exceeded := tracker.exceededBy()
+	require.True(t, exceeded)
+	assert.Equal(t, budgetScopeTask, err.Scope)
+	assert.Equal(t, "$0.5500 spent of the $0.5000 budget of the task", err.String())
+
+	// Tasks without a budget outside of a parallel run are not tracked
+	_, tracker, finishUntracked := startTaskBudget(context.Background(), "task-2", TaskBudget{})
+	defer finishUntracked()
+	assert.Nil(t, tracker)
+}
+
+func TestRunBudget(t *testing.T) {
+	run := newBudgetTracker(budgetScopeRun, TaskBudget{MaxTokens: 3000})
+	runCtx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	run.watch(func() { cancel(errBudgetExceeded) })
+	runCtx = withRunBudget(runCtx, run)
+
+	ctxA, trackerA, finishA := startTaskBudget(runCtx, "task-a", TaskBudget{})
+	defer finishA()
+	ctxB, _, finishB := startTaskBudget(runCtx, "task-b", TaskBudget{MaxTokens: 10000})
+	defer finishB()
+
+	recordTaskUsage("task-a", 0.1, 2000)
+	assert.NoError(t, ctxB.Err())
+	recordTaskUsage("task-b", 0.1, 1500)
+	assert.Error(t, ctxA.Err(), "every task of the run stops once the run exceeds its budget")
+	assert.Error(t, ctxB.Err())
+	assert.ErrorIs(t, context.Cause(runCtx), errBudgetExceeded)
+
+	err, exceeded := trackerA.exceededBy()
+	require.True(t, exceeded)
+	assert.Equal(t, budgetScopeRun, err.Scope)
+	assert.Equal(t, "3500 tokens used of the 3000 token budget of the parallel run", err.String())
+	cost, tokens := run.usage()
+	assert.InDelta(t, 0.2, cost, 1e-9)
+	assert.Equal(t, int64(3500), tokens)
+}
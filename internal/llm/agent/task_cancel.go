@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"sync"
+)
+
+// TaskCanceledResult starts the agent tool result of a task canceled on its
+// own, followed by the token to resume it
+const TaskCanceledResult = "Task canceled by the user before it finished. The other tasks were not affected; continue without its result or resume it if it is still needed."
+
+// runningTasks holds the cancel functions of the running agent tasks by task
+// session ID, so a single task can be stopped without aborting its parent.
+type runningTaskStore struct {
+	mu       sync.Mutex
+	cancels  map[string]context.CancelFunc
+	canceled map[string]bool
+}
+
+var runningTasks = &runningTaskStore{
+	cancels:  make(map[string]context.CancelFunc),
+	canceled: make(map[string]bool),
+}
+
+// startTask returns the context a task runs in and a function that must be
+// called once it is done. The done function reports whether the task was
+// canceled with CancelTask.
+func startTask(ctx context.Context, taskID string) (context.Context, func() bool) {
+	taskCtx, cancel := context.WithCancel(ctx)
+	runningTasks.mu.Lock()
+	runningTasks.cancels[taskID] = cancel
+	runningTasks.mu.Unlock()
+
+	return taskCtx, func() bool {
+		runningTasks.mu.Lock()
+		defer runningTasks.mu.Unlock()
+		canceled := runningTasks.canceled[taskID]
+		delete(runningTasks.cancels, taskID)
+		delete(runningTasks.canceled, taskID)
+		cancel()
+		return canceled
+	}
+}
+
+// isTaskRunning reports whether a task runs in the session
+func isTaskRunning(taskID string) bool {
+	runningTasks.mu.Lock()
+	defer runningTasks.mu.Unlock()
+	_, ok := runningTasks.cancels[taskID]
+	return ok
+}
+
+// CancelTask stops a running task without canceling the request that launched
+// it. It returns false when the task is not running.
+func CancelTask(taskID string) bool {
+	runningTasks.mu.Lock()
+	defer runningTasks.mu.Unlock()
+	cancel, ok := runningTasks.cancels[taskID]
+	if !ok {
+		return false
+	}
+	runningTasks.canceled[taskID] = true
+	cancel()
+	return true
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+// Task priorities, tasks of a message with a higher priority run first
+const (
+	TaskPriorityHigh   = "high"
+	TaskPriorityNormal = "normal"
+	TaskPriorityLow    = "low"
+)
+
+func taskPriorityRank(priority string) int {
+	switch priority {
+	case TaskPriorityHigh:
+		return 0
+	case TaskPriorityLow:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// scheduleToolCalls returns the order to run the tool calls of a message in.
+// Agent tasks are reordered by priority among the positions they occupy,
+// every other tool call keeps its position so the steps around the tasks
+// still run in the order the model asked for.
+func scheduleToolCalls(toolCalls []message.ToolCall) []int {
+	order := make([]int, len(toolCalls))
+	var slots, tasks []int
+	for i, call := range toolCalls {
+		order[i] = i
+		if call.Name == AgentToolName {
+			slots = append(slots, i)
+			tasks = append(tasks, i)
+		}
+	}
+
+	ranks := make(map[int]int, len(tasks))
+	for _, i := range tasks {
+		var params AgentParams
+		_ = json.Unmarshal([]byte(toolCalls[i].Input), &params)
+		ranks[i] = taskPriorityRank(params.Priority)
+	}
+	sort.SliceStable(tasks, func(a, b int) bool {
+		return ranks[tasks[a]] < ranks[tasks[b]]
+	})
+	for n, slot := range slots {
+		order[slot] = tasks[n]
+	}
+	return order
+}
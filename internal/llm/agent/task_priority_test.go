@@ -0,0 +1,26 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleToolCalls(t *testing.T) {
+	task := func(priority string) message.ToolCall {
+		return message.ToolCall{Name: AgentToolName, Input: `{"prompt":"p","priority":"` + priority + `"}`}
+	}
+	toolCalls := []message.ToolCall{
+		{Name: "view"},
+		task(TaskPriorityLow),
+		task(""),
+		{Name: "edit"},
+		task(TaskPriorityHigh),
+		task(TaskPriorityNormal),
+	}
+
+	// Tasks swap places by priority, the view and edit calls keep theirs
+	assert.Equal(t, []int{0, 4, 2, 3, 5, 1}, scheduleToolCalls(toolCalls))
+	assert.Empty(t, scheduleToolCalls(nil))
+}
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/pubsub"
+)
+
+type TaskProgressKind string
+
+const (
+	TaskProgressStarted  TaskProgressKind = "started"
+	TaskProgressToolCall TaskProgressKind = "tool_call"
+	TaskProgressContent  TaskProgressKind = "content"
+	TaskProgressDone     TaskProgressKind = "done"
+)
+
+// TaskProgress is what a running task did last, relayed to the session that
+// launched it so its progress shows before the task reports
+type TaskProgress struct {
+	ParentSessionID string
+	CallID          string // ID of the agent tool call running the task
+	TaskSessionID   string
+	Kind            TaskProgressKind
+	ToolCall        message.ToolCall // The tool call of TaskProgressToolCall
+	Content         string           // The partial response of TaskProgressContent
+}
+
+// TaskActivity is what a running task did so far
+type TaskActivity struct {
+	Started   time.Time
+	ToolCalls []message.ToolCall
+	Content   string // Partial response of the current step
+}
+
+type taskProgressStore struct {
+	*pubsub.Broker[TaskProgress]
+	mu       sync.Mutex
+	activity map[string]*TaskActivity
+}
+
+var taskProgress = &taskProgressStore{
+	Broker:   pubsub.NewBroker[TaskProgress](),
+	activity: make(map[string]*TaskActivity),
+}
+
+// SubscribeTaskProgress returns the progress of the running tasks as they
+// call tools and stream their responses
+func SubscribeTaskProgress(ctx context.Context) <-chan pubsub.Event[TaskProgress] {
+	return taskProgress.Subscribe(ctx)
+}
+
+// RunningTaskActivity returns what the task of an agent tool call did so far,
+// false when it is not running
+func RunningTaskActivity(callID string) (TaskActivity, bool) {
+	taskProgress.mu.Lock()
+	defer taskProgress.mu.Unlock()
+	activity, ok := taskProgress.activity[callID]
+	if !ok {
+		return TaskActivity{}, false
+	}
+	snapshot := *activity
+	snapshot.ToolCalls = append([]message.ToolCall(nil), activity.ToolCalls...)
+	return snapshot, true
+}
+
+type taskRelayContextKey struct{}
+
+// withTaskRelay returns the context a task runs in, with its progress relayed
+// to the parent session. The returned function must be called once the task
+// is done.
+func withTaskRelay(ctx context.Context, parentSessionID, callID, taskSessionID string) (context.Context, func()) {
+	relay := TaskProgress{
+		ParentSessionID: parentSessionID,
+		CallID:          callID,
+		TaskSessionID:   taskSessionID,
+	}
+	taskProgress.mu.Lock()
+	taskProgress.activity[callID] = &TaskActivity{Started: time.Now()}
+	taskProgress.mu.Unlock()
+	relay.Kind = TaskProgressStarted
+	taskProgress.Publish(pubsub.CreatedEvent, relay)
+
+	return context.WithValue(ctx, taskRelayContextKey{}, relay), func() {
+		taskProgress.mu.Lock()
+		delete(taskProgress.activity, callID)
+		taskProgress.mu.Unlock()
+		relay.Kind = TaskProgressDone
+		taskProgress.Publish(pubsub.DeletedEvent, relay)
+	}
+}
+
+// relayToolCall tells the parent session of a task a tool is being called
+func relayToolCall(ctx context.Context, call message.ToolCall) {
+	relay, ok := ctx.Value(taskRelayContextKey{}).(TaskProgress)
+	if !ok {
+		return
+	}
+	// The input of a call is complete once it runs
+	call.Finished = true
+	taskProgress.mu.Lock()
+	activity, ok := taskProgress.activity[relay.CallID]
+	if ok {
+		activity.ToolCalls = append(activity.ToolCalls, call)
+		activity.Content = ""
+	}
+	taskProgress.mu.Unlock()
+	if !ok {
+		return
+	}
+	relay.Kind = TaskProgressToolCall
+	relay.ToolCall = call
+	taskProgress.Publish(pubsub.UpdatedEvent, relay)
+}
+
+// relayContent tells the parent session of a task the partial response of
+// its current step
+func relayContent(ctx context.Context, content string) {
+	relay, ok := ctx.Value(taskRelayContextKey{}).(TaskProgress)
+	if !ok {
+		return
+	}
+	taskProgress.mu.Lock()
+	activity, ok := taskProgress.activity[relay.CallID]
+	if ok {
+		activity.Content = content
+	}
+	taskProgress.mu.Unlock()
+	if !ok {
+		return
+	}
+	relay.Kind = TaskProgressContent
+	relay.Content = content
+	taskProgress.Publish(pubsub.UpdatedEvent, relay)
+}
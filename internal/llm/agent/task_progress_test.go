@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskRelay(t *testing.T) {
+	events := SubscribeTaskProgress(t.Context())
+	next := func() pubsub.Event[TaskProgress] {
+		select {
+		case event := <-events:
+			return event
+		default:
+			require.FailNow(t, "no task progress published")
+			return pubsub.Event[TaskProgress]{}
+		}
+	}
+
+	// Calls outside of a task are not relayed
+	relayToolCall(t.Context(), message.ToolCall{ID: "outside"})
+	relayContent(t.Context(), "outside")
+	assert.Empty(t, events)
+
+	ctx, finish := withTaskRelay(t.Context(), "parent", "call", "task")
+	event := next()
+	assert.Equal(t, pubsub.CreatedEvent, event.Type)
+	assert.Equal(t, TaskProgress{ParentSessionID: "parent", CallID: "call", TaskSessionID: "task", Kind: TaskProgressStarted}, event.Payload)
+
+	relayContent(ctx, "Looking at")
+	assert.Equal(t, "Looking at", next().Payload.Content)
+	relayToolCall(ctx, message.ToolCall{ID: "1", Name: "view"})
+	event = next()
+	assert.Equal(t, pubsub.UpdatedEvent, event.Type)
+	assert.Equal(t, TaskProgressToolCall, event.Payload.Kind)
+	assert.True(t, event.Payload.ToolCall.Finished)
+
+	activity, ok := RunningTaskActivity("call")
+	require.True(t, ok)
+	assert.Equal(t, []message.ToolCall{{ID: "1", Name: "view", Finished: true}}, activity.ToolCalls)
+	assert.Empty(t, activity.Content, "the partial response of a step ends with its tool calls")
+
+	finish()
+	event = next()
+	assert.Equal(t, pubsub.DeletedEvent, event.Type)
+	assert.Equal(t, TaskProgressDone, event.Payload.Kind)
+	_, ok = RunningTaskActivity("call")
+	assert.False(t, ok)
+
+	// Progress after the task finished is dropped
+	relayContent(ctx, "late")
+	assert.Empty(t, events)
+}
@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/quota"
+)
+
+// Failure classes of a task a retry policy can retry
+const (
+	RetryOnTimeout     = "timeout"
+	RetryOnRateLimit   = "rate_limit"
+	RetryOnServerError = "server_error" // 5xx responses and overloaded providers
+	RetryOnNetwork     = "network"
+)
+
+var retryClasses = []string{RetryOnTimeout, RetryOnRateLimit, RetryOnServerError, RetryOnNetwork}
+
+// retryStatusPattern matches the HTTP status codes of transient provider
+// errors in an error message
+var retryStatusPattern = regexp.MustCompile(`\b(429|500|502|503|504|529)\b`)
+
+const (
+	maxTaskAttempts     = 5
+	defaultRetryBackoff = 2 * time.Second
+	maxRetryBackoff     = 2 * time.Minute
+)
+
+// retryTaskPrompt resumes a task after an attempt failed
+const retryTaskPrompt = "Your previous attempt at this task failed with an error: %s. Continue the task where it stopped, using what you already found."
+
+// RetryPolicy retries a task that failed with a transient error. A retry
+// resumes the task in its session, with what it already did. Tasks are not
+// retried without a policy.
+type RetryPolicy struct {
+	MaxAttempts    int      `json:"max_attempts,omitempty"`    // Attempts including the first one, up to 5
+	BackoffSeconds float64  `json:"backoff_seconds,omitempty"` // Wait before the first retry, doubled before each next one, 2 when 0
+	RetryOn        []string `json:"retry_on,omitempty"`        // Failure classes retried, all of them when empty
+}
+
+func (p RetryPolicy) validate() error {
+	if p.MaxAttempts < 0 || p.MaxAttempts > maxTaskAttempts {
+		return fmt.Errorf("retry max_attempts must be between 1 and %d", maxTaskAttempts)
+	}
+	if p.BackoffSeconds < 0 {
+		return fmt.Errorf("retry backoff_seconds must not be negative")
+	}
+	for _, class := range p.RetryOn {
+		if !slices.Contains(retryClasses, class) {
+			return fmt.Errorf("unknown retry_on class %q, use %s", class, strings.Join(retryClasses, ", "))
+		}
+	}
+	return nil
+}
+
+// attempts returns the number of times a task may run
+func (p RetryPolicy) attempts() int {
+	return min(max(p.MaxAttempts, 1), maxTaskAttempts)
+}
+
+// backoff returns the wait before a retry, the first one being 1
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	wait := defaultRetryBackoff
+	if p.BackoffSeconds > 0 {
+		wait = time.Duration(p.BackoffSeconds * float64(time.Second))
+	}
+	for i := 1; i < retry && wait < maxRetryBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxRetryBackoff)
+}
+
+// retries reports whether the policy retries a failure of a class
+func (p RetryPolicy) retries(class string) bool {
+	return class != "" && (len(p.RetryOn) == 0 || slices.Contains(p.RetryOn, class))
+}
+
+// retryPolicySchema returns the JSON schema of a retry policy parameter
+func retryPolicySchema(description string) map[string]any {
+	return map[string]any{
+		"type":        "object",
+		"description": description,
+		"properties": map[string]any{
+			"max_attempts": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Attempts including the first one, up to %d", maxTaskAttempts),
+			},
+			"backoff_seconds": map[string]any{
+				"type":        "number",
+				"description": "Seconds to wait before the first retry, doubled before each next one, 2 by default",
+			},
+			"retry_on": map[string]any{
+				"type":        "array",
+				"description": "Failure classes to retry, all of them by default",
+				"items":       map[string]any{"type": "string", "enum": retryClasses},
+			},
+		},
+	}
+}
+
+// retriedNote tells how often a failed task was retried
+func retriedNote(retries int) string {
+	switch retries {
+	case 0:
+		return ""
+	case 1:
+		return " (after 1 retry)"
+	}
+	return fmt.Sprintf(" (after %d retries)", retries)
+}
+
+// failureClass returns the retry class of the error a task failed with,
+// empty for errors a retry won't fix
+func failureClass(err error) string {
+	if err == nil || errors.Is(err, ErrRequestCancelled) || errors.Is(err, context.Canceled) || errors.Is(err, quota.ErrQuotaExceeded) {
+		return ""
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return RetryOnTimeout
+	}
+	msg := strings.ToLower(err.Error())
+	contains := func(substrs ...string) bool {
+		return slices.ContainsFunc(substrs, func(s string) bool { return strings.Contains(msg, s) })
+	}
+	status := retryStatusPattern.FindString(msg)
+	switch {
+	case status == "429" || contains("rate limit", "rate_limit", "too many requests", "resource_exhausted"):
+		return RetryOnRateLimit
+	case contains("timeout", "timed out", "deadline exceeded"):
+		return RetryOnTimeout
+	case status != "" || contains("overloaded", "internal server error", "bad gateway", "service unavailable"):
+		return RetryOnServerError
+	case contains("connection reset", "connection refused", "broken pipe", "no such host", "unexpected eof", "network is unreachable"):
+		return RetryOnNetwork
+	}
+	return ""
+}
+
+// runTaskAttempts runs a task and resumes it by its retry policy while it
+// fails with an error the policy retries. It returns the result of the last
+// attempt and the number of retries. Errors starting an attempt end the
+// task.
+func runTaskAttempts(ctx context.Context, policy RetryPolicy, prompt string, run func(prompt string) (AgentEvent, error)) (AgentEvent, int, error) {
+	for retries := 0; ; retries++ {
+		result, err := run(prompt)
+		if err != nil {
+			return result, retries, err
+		}
+		class := failureClass(result.Error)
+		if ctx.Err() != nil || !policy.retries(class) || retries+1 >= policy.attempts() {
+			return result, retries, nil
+		}
+
+		wait := policy.backoff(retries + 1)
+		logging.Info("Retrying a failed task", "class", class, "retry", retries+1, "wait", wait, "error", result.Error)
+		select {
+		case <-ctx.Done():
+			return result, retries, nil
+		case <-time.After(wait):
+		}
+		prompt = fmt.Sprintf(retryTaskPrompt, result.Error)
+	}
+}
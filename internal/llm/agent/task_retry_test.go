@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/quota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureClass(t *testing.T) {
+	for err, class := range map[error]string{
+		errors.New("POST https://api.anthropic.com/v1/messages: 429 Too Many Requests"):  RetryOnRateLimit,
+		errors.New("maximum retry attempts reached for rate limit: 8 retries"):           RetryOnRateLimit,
+		fmt.Errorf("stream: %w", context.DeadlineExceeded):                               RetryOnTimeout,
+		errors.New("net/http: TLS handshake timeout"):                                    RetryOnTimeout,
+		errors.New("529 overloaded_error: Overloaded"):                                   RetryOnServerError,
+		errors.New("read tcp 10.0.0.2:51234: connection reset by peer"):                  RetryOnNetwork,
+		errors.New("prompt is too long: 250000 tokens > 200000 maximum"):                 "",
+		fmt.Errorf("%w: openai used 100%% of its monthly quota", quota.ErrQuotaExceeded): "",
+		ErrRequestCancelled: "",
+	} {
+		assert.Equal(t, class, failureClass(err), err.Error())
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BackoffSeconds: 1, RetryOn: []string{RetryOnRateLimit}}
+	require.NoError(t, policy.validate())
+	assert.Equal(t, time.Second, policy.backoff(1))
+	assert.Equal(t, 4*time.Second, policy.backoff(3))
+	assert.Equal(t, maxRetryBackoff, policy.backoff(20))
+	assert.True(t, policy.retries(RetryOnRateLimit))
+	assert.False(t, policy.retries(RetryOnTimeout))
+	assert.True(t, RetryPolicy{}.retries(RetryOnTimeout), "every class is retried by default")
+	assert.Equal(t, 1, RetryPolicy{}.attempts())
+
+	assert.Error(t, RetryPolicy{MaxAttempts: 10}.validate())
+	assert.Error(t, RetryPolicy{RetryOn: []string{"everything"}}.validate())
+}
+
+func TestRunTaskAttempts(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BackoffSeconds: 0.001}
+	var prompts []string
+	run := func(prompt string) (AgentEvent, error) {
+		prompts = append(prompts, prompt)
+		if len(prompts) < 3 {
+			return AgentEvent{Error: errors.New("503 service unavailable")}, nil
+		}
+		return AgentEvent{}, nil
+	}
+	result, retries, err := runTaskAttempts(context.Background(), policy, "find it", run)
+	require.NoError(t, err)
+	assert.NoError(t, result.Error)
+	assert.Equal(t, 2, retries)
+	assert.Equal(t, "find it", prompts[0])
+	assert.Contains(t, prompts[1], "503 service unavailable", "the task resumes knowing why it failed")
+
+	// Errors a retry won't fix end the task
+	prompts = nil
+	result, retries, _ = runTaskAttempts(context.Background(), policy, "find it", func(prompt string) (AgentEvent, error) {
+		prompts = append(prompts, prompt)
+		return AgentEvent{Error: errors.New("invalid api key")}, nil
+	})
+	assert.Error(t, result.Error)
+	assert.Zero(t, retries)
+
+	// The last attempt fails too
+	prompts = nil
+	result, retries, _ = runTaskAttempts(context.Background(), RetryPolicy{MaxAttempts: 2, BackoffSeconds: 0.001}, "find it", func(prompt string) (AgentEvent, error) {
+		prompts = append(prompts, prompt)
+		return AgentEvent{Error: errors.New("connection refused")}, nil
+	})
+	assert.Error(t, result.Error)
+	assert.Equal(t, 1, retries)
+	assert.Len(t, prompts, 2)
+}
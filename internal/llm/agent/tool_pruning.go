@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/kirmad/superopencode/internal/analytics"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/logging"
+)
+
+// toolUsage holds the tool calls recorded per model, the tools sent to the
+// models listed in the tool pruning config are picked by them
+var toolUsage = struct {
+	mu      sync.RWMutex
+	byModel map[string][]analytics.Usage
+}{byModel: make(map[string][]analytics.Usage)}
+
+// SetToolUsage replaces the tool calls per model the tools are pruned by
+func SetToolUsage(byModel map[string][]analytics.Usage) {
+	toolUsage.mu.Lock()
+	defer toolUsage.mu.Unlock()
+	toolUsage.byModel = byModel
+}
+
+// prunedTools returns the tools sent to a model, without the ones it rarely
+// uses successfully when its tools are pruned
+func prunedTools(model models.ModelID, agentTools []tools.BaseTool) []tools.BaseTool {
+	cfg := config.Get().ToolPruning
+	if !cfg.Enabled || !slices.Contains(cfg.Models, model) {
+		return agentTools
+	}
+	toolUsage.mu.RLock()
+	usages := toolUsage.byModel[string(model)]
+	toolUsage.mu.RUnlock()
+
+	pruned := pruneTools(cfg, usages, agentTools)
+	if len(pruned) < len(agentTools) {
+		logging.Debug("Pruned the tools of the request", "model", model, "tools", len(pruned), "pruned", len(agentTools)-len(pruned))
+	}
+	return pruned
+}
+
+// pruneTools keeps the tools that got at least the minimum share of the
+// successful calls of a model, and the ones always included. The tools are
+// kept until enough calls of the model are recorded to tell. Tools without
+// recorded calls are kept too: they're new, or their calls aged out of the
+// usage stats, so a pruned tool is offered again once its old calls are
+// forgotten.
+func pruneTools(cfg config.ToolPruningConfig, usages []analytics.Usage, agentTools []tools.BaseTool) []tools.BaseTool {
+	calls, succeeded := 0, 0
+	successful := make(map[string]int, len(usages))
+	for _, usage := range usages {
+		calls += usage.Count
+		succeeded += usage.Count - usage.Failed
+		successful[usage.Name] = usage.Count - usage.Failed
+	}
+	if calls < cfg.MinCalls || succeeded == 0 {
+		return agentTools
+	}
+
+	kept := make([]tools.BaseTool, 0, len(agentTools))
+	for _, tool := range agentTools {
+		name := tool.Info().Name
+		count, recorded := successful[name]
+		share := float64(count) / float64(succeeded)
+		if !recorded || slices.Contains(cfg.AlwaysInclude, name) || (count > 0 && share >= cfg.MinShare) {
+			kept = append(kept, tool)
+		}
+	}
+	return kept
+}
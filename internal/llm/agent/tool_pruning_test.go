@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/analytics"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+type namedTool string
+
+func (t namedTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{Name: string(t)}
+}
+
+func (t namedTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolResponse, error) {
+	return tools.NewTextResponse(""), nil
+}
+
+func toolNames(agentTools []tools.BaseTool) []string {
+	names := make([]string, len(agentTools))
+	for i, tool := range agentTools {
+		names[i] = tool.Info().Name
+	}
+	return names
+}
+
+func TestPruneTools(t *testing.T) {
+	agentTools := []tools.BaseTool{namedTool("view"), namedTool("grep"), namedTool("bash"), namedTool("sourcegraph"), namedTool("diagnostics"), namedTool("fetch")}
+	usages := []analytics.Usage{
+		{Name: "view", Count: 60},
+		{Name: "grep", Count: 30, Failed: 2},
+		{Name: "sourcegraph", Count: 10, Failed: 10},
+		{Name: "diagnostics", Count: 1},
+	}
+	cfg := config.ToolPruningConfig{MinCalls: 50, MinShare: 0.05, AlwaysInclude: []string{"bash"}}
+
+	assert.Equal(t, []string{"view", "grep", "bash", "fetch"}, toolNames(pruneTools(cfg, usages, agentTools)),
+		"sourcegraph always fails, diagnostics is rarely called, bash is always included, fetch has no calls yet")
+
+	// The calls of sourcegraph aged out of the usage stats
+	usages = slices.DeleteFunc(usages, func(usage analytics.Usage) bool { return usage.Name == "sourcegraph" })
+	assert.Equal(t, []string{"view", "grep", "bash", "sourcegraph", "fetch"}, toolNames(pruneTools(cfg, usages, agentTools)),
+		"a pruned tool is offered again")
+
+	cfg.MinCalls = 200
+	assert.Len(t, pruneTools(cfg, usages, agentTools), len(agentTools), "too few calls to tell")
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/kirmad/superopencode/internal/llm/tools"
 	"github.com/kirmad/superopencode/internal/lsp"
 	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/metrics"
 	"github.com/kirmad/superopencode/internal/permission"
+	"github.com/kirmad/superopencode/internal/quota"
 	"github.com/kirmad/superopencode/internal/session"
 )
 
@@ -16,6 +18,8 @@ func CoderAgentTools(
 	sessions session.Service,
 	messages message.Service,
 	history history.Service,
+	quotas quota.Service,
+	metrics metrics.Service,
 	lspClients map[string]*lsp.Client,
 ) []tools.BaseTool {
 	ctx := context.Background()
@@ -28,29 +32,55 @@ func CoderAgentTools(
 			tools.NewBashTool(permissions),
 			tools.NewEditTool(lspClients, permissions, history),
 			tools.NewFetchTool(permissions),
+			tools.NewFlakyTestTool(permissions),
+			tools.NewProfileTool(permissions),
+			tools.NewDependencyAuditTool(),
+			tools.NewMergeConflictsTool(),
+			tools.NewResolveConflictTool(permissions, history),
+			tools.NewGenerateDocsTool(),
+			tools.NewGitHistoryTool(),
 			tools.NewGlobTool(),
 			tools.NewGrepTool(),
 			tools.NewLsTool(),
+			tools.NewReadPromptTool(),
+			tools.NewReminderTool(),
 			tools.NewSourcegraphTool(),
+			tools.NewSparseCheckoutTool(permissions),
 			tools.NewTodoReadTool(),
 			tools.NewTodoWriteTool(),
 			tools.NewViewTool(lspClients),
 			tools.NewPatchTool(lspClients, permissions, history),
 			tools.NewWriteTool(lspClients, permissions, history),
-			NewAgentTool(sessions, messages, lspClients),
+			NewAgentTool(sessions, messages, quotas, metrics, lspClients),
+			NewParallelTasksTool(sessions, messages, quotas, metrics, lspClients),
+			NewLogQueryTool(),
 		}, otherTools...,
 	)
 }
 
 // TaskAgentTools provides limited read-only tools for task agents
 func TaskAgentTools(lspClients map[string]*lsp.Client) []tools.BaseTool {
-	return []tools.BaseTool{
-		tools.NewGlobTool(),
-		tools.NewGrepTool(),
-		tools.NewLsTool(),
-		tools.NewSourcegraphTool(),
-		tools.NewViewTool(lspClients),
-	}
+	return subagentTools(SubagentTask, nil, nil, lspClients)
+}
+
+// ReviewerAgentTools provides read-only tools for the reviewer agent
+func ReviewerAgentTools(lspClients map[string]*lsp.Client) []tools.BaseTool {
+	return subagentTools(SubagentReview, nil, nil, lspClients)
+}
+
+// SecurityAgentTools provides the SAST scanners and read-only tools for the
+// security agent
+func SecurityAgentTools(lspClients map[string]*lsp.Client) []tools.BaseTool {
+	return subagentTools(SubagentSecurity, nil, nil, lspClients)
+}
+
+// MigratorAgentTools provides the file tools for the migration subagents
+func MigratorAgentTools(
+	permissions permission.Service,
+	history history.Service,
+	lspClients map[string]*lsp.Client,
+) []tools.BaseTool {
+	return subagentTools(SubagentMigration, permissions, history, lspClients)
 }
 
 // ResearchAgentTools provides research-optimized tools
@@ -61,19 +91,7 @@ func ResearchAgentTools(
 	history history.Service,
 	lspClients map[string]*lsp.Client,
 ) []tools.BaseTool {
-	ctx := context.Background()
-	mcpTools := GetMcpTools(ctx, permissions)
-
-	return append([]tools.BaseTool{
-		tools.NewViewTool(lspClients),   // Read files
-		tools.NewGrepTool(),             // Search content
-		tools.NewGlobTool(),             // Find files
-		tools.NewSourcegraphTool(),      // Advanced search
-		tools.NewFetchTool(permissions), // Web research
-		tools.NewLsTool(),               // Directory exploration
-		tools.NewTodoReadTool(),         // Task tracking
-		tools.NewTodoWriteTool(),        // Task management
-	}, mcpTools...) // Include MCP tools for enhanced research capabilities
+	return subagentTools(SubagentResearch, permissions, history, lspClients)
 }
 
 // CodingAgentTools provides coding-optimized tools
@@ -84,26 +102,7 @@ func CodingAgentTools(
 	history history.Service,
 	lspClients map[string]*lsp.Client,
 ) []tools.BaseTool {
-	ctx := context.Background()
-	mcpTools := GetMcpTools(ctx, permissions)
-
-	var diagnosticTools []tools.BaseTool
-	if len(lspClients) > 0 {
-		diagnosticTools = append(diagnosticTools, tools.NewDiagnosticsTool(lspClients))
-	}
-
-	return append(append([]tools.BaseTool{
-		tools.NewViewTool(lspClients),                        // Read code
-		tools.NewWriteTool(lspClients, permissions, history), // Create files
-		tools.NewEditTool(lspClients, permissions, history),  // Edit code
-		tools.NewBashTool(permissions),                       // Execute commands
-		tools.NewGrepTool(),                                  // Search code
-		tools.NewGlobTool(),                                  // Find files
-		tools.NewPatchTool(lspClients, permissions, history), // Apply patches
-		tools.NewLsTool(),                                    // Directory navigation
-		tools.NewTodoReadTool(),                              // Task tracking
-		tools.NewTodoWriteTool(),                             // Task management
-	}, diagnosticTools...), mcpTools...) // Include MCP tools and diagnostics
+	return subagentTools(SubagentCoding, permissions, history, lspClients)
 }
 
 // AnalysisAgentTools provides analysis-optimized tools
@@ -114,18 +113,5 @@ func AnalysisAgentTools(
 	history history.Service,
 	lspClients map[string]*lsp.Client,
 ) []tools.BaseTool {
-	ctx := context.Background()
-	mcpTools := GetMcpTools(ctx, permissions)
-
-	return append([]tools.BaseTool{
-		tools.NewViewTool(lspClients),   // Read data files
-		tools.NewGrepTool(),             // Pattern analysis
-		tools.NewGlobTool(),             // File discovery
-		tools.NewBashTool(permissions),  // Data processing commands
-		tools.NewLsTool(),               // Directory analysis
-		tools.NewSourcegraphTool(),      // Advanced search
-		tools.NewTodoReadTool(),         // Task tracking
-		tools.NewTodoWriteTool(),        // Task management
-		tools.NewFetchTool(permissions), // External data access
-	}, mcpTools...) // Include MCP tools for enhanced analysis
+	return subagentTools(SubagentAnalysis, permissions, history, lspClients)
 }
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+// maxContinuations is the number of times a response cut off by the output
+// token limit is continued
+const maxContinuations = 3
+
+const (
+	continueTruncatedPrompt = "Your last response was cut off by the output token limit. Continue exactly where it stopped, mid-sentence or mid-line if needed, without repeating or summarizing what you already wrote."
+	continueCodeBlockNote   = " It stopped inside a code block: continue the code directly, do not open a new code block."
+)
+
+// truncatedResponse reports whether the text of a response was cut off by
+// the output token limit. Tool calls cut off in their input are not
+// continued.
+func truncatedResponse(msg message.Message) bool {
+	return msg.FinishReason() == message.FinishReasonMaxTokens && msg.Content().Text != "" && len(msg.ToolCalls()) == 0
+}
+
+// openCodeBlock reports whether text stops inside a fenced code block
+func openCodeBlock(text string) bool {
+	open := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimLeft(line, " \t"), "```") {
+			open = !open
+		}
+	}
+	return open
+}
+
+// stitchContinuation joins the text of a continuation to the truncated text
+// it continues. A code block the continuation opens again is left out.
+func stitchContinuation(truncated, continuation string) string {
+	if !openCodeBlock(truncated) {
+		return truncated + continuation
+	}
+	trimmed := strings.TrimLeft(continuation, "\n")
+	if !strings.HasPrefix(trimmed, "```") {
+		return truncated + continuation
+	}
+	_, code, _ := strings.Cut(trimmed, "\n")
+	if !strings.HasSuffix(truncated, "\n") {
+		// The continuation starts the cut off line over
+		truncated = truncated[:strings.LastIndex(truncated, "\n")+1]
+	}
+	return truncated + code
+}
+
+// mergeContinuation adds the text, tool calls and usage of a continuation to
+// the message it continues, which ends the way the continuation did
+func mergeContinuation(truncated, continuation message.Message) message.Message {
+	merged := truncated
+	merged.Parts = slices.Clone(truncated.Parts)
+	text := stitchContinuation(truncated.Content().Text, continuation.Content().Text)
+	for i, part := range merged.Parts {
+		if _, ok := part.(message.TextContent); ok {
+			merged.Parts[i] = message.TextContent{Text: text}
+		}
+	}
+	if calls := continuation.ToolCalls(); len(calls) > 0 {
+		merged.SetToolCalls(append(truncated.ToolCalls(), calls...))
+	}
+	if usage := continuation.Usage(); usage != nil {
+		total := *usage
+		if previous := truncated.Usage(); previous != nil {
+			total.InputTokens += previous.InputTokens
+			total.OutputTokens += previous.OutputTokens
+			total.CacheCreationTokens += previous.CacheCreationTokens
+			total.CacheReadTokens += previous.CacheReadTokens
+			total.Cost += previous.Cost
+		}
+		merged.SetUsage(total)
+	}
+	merged.AddFinish(continuation.FinishReason())
+	return merged
+}
+
+// continueTruncated asks for the rest of a response cut off by the output
+// token limit, up to maxContinuations times, and stitches every continuation
+// into the truncated message so the user sees a single response. It returns
+// the message and the results of the tools the continuations called.
+func (a *agent) continueTruncated(ctx context.Context, sessionID string, msgHistory []message.Message, truncated message.Message) (message.Message, *message.Message, error) {
+	var (
+		toolResults *message.Message
+		previous    string
+		n           int
+	)
+	for ; n < maxContinuations && truncatedResponse(truncated); n++ {
+		prompt := continueTruncatedPrompt
+		if openCodeBlock(truncated.Content().Text) {
+			prompt += continueCodeBlockNote
+		}
+		// The request to continue is only sent, it is not part of the session
+		request := message.Message{
+			Role:      message.User,
+			SessionID: sessionID,
+			Parts:     []message.ContentPart{message.TextContent{Text: "<system-reminder>" + prompt + "</system-reminder>"}},
+		}
+		logging.Info("Continuing a truncated response", "session", sessionID, "message", truncated.ID, "continuation", n+1)
+		continuation, results, err := a.streamAndHandleEvents(ctx, sessionID, append(slices.Clip(msgHistory), truncated, request), time.Time{})
+		if continuation.ID == "" {
+			return truncated, nil, err
+		}
+		if deleteErr := a.messages.Delete(context.Background(), continuation.ID); deleteErr != nil {
+			logging.Warn("Failed to delete the continuation of a truncated response", "error", deleteErr)
+		}
+
+		added := continuation.Content().Text
+		if err == nil && len(continuation.ToolCalls()) == 0 && (strings.TrimSpace(added) == "" || added == previous) {
+			// The model has nothing to add or repeats itself
+			logging.Warn("Stopped continuing a truncated response", "session", sessionID, "message", truncated.ID)
+			break
+		}
+		previous = added
+		truncated = mergeContinuation(truncated, continuation)
+		toolResults = results
+		if updateErr := a.messages.Update(context.Background(), truncated); updateErr != nil {
+			return truncated, toolResults, fmt.Errorf("failed to save the continued response: %w", updateErr)
+		}
+		if err != nil {
+			return truncated, toolResults, err
+		}
+	}
+	if truncatedResponse(truncated) {
+		logging.WarnPersist(fmt.Sprintf("The response is cut off by the output token limit, %d continuations did not complete it", n))
+	}
+	return truncated, toolResults, nil
+}
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenCodeBlock(t *testing.T) {
+	assert.False(t, openCodeBlock("Some text"))
+	assert.True(t, openCodeBlock("Here it is:\n```go\nfunc main() {"))
+	assert.False(t, openCodeBlock("```go\nx := 1\n```\nDone"))
+	assert.True(t, openCodeBlock("- step\n  ```bash\n  go build"))
+}
+
+func TestStitchContinuation(t *testing.T) {
+	assert.Equal(t, "Hello world", stitchContinuation("Hello wo", "rld"))
+	assert.Equal(t, "```go\nfunc main() {\n}\n```", stitchContinuation("```go\nfunc main() {\n", "}\n```"))
+	assert.Equal(t, "```go\nfunc main() {\n\tfmt.Println()\n}\n```",
+		stitchContinuation("```go\nfunc main() {\n\tfmt.Pri", "```go\n\tfmt.Println()\n}\n```"),
+		"a code block opened again replaces the cut off line")
+}
+
+func TestMergeContinuation(t *testing.T) {
+	truncated := message.Message{ID: "1", Role: message.Assistant, Parts: []message.ContentPart{
+		message.TextContent{Text: "Part one, "},
+		message.Usage{InputTokens: 100, OutputTokens: 50, Cost: 0.1},
+		message.Finish{Reason: message.FinishReasonMaxTokens},
+	}}
+	continuation := message.Message{ID: "2", Role: message.Assistant, Parts: []message.ContentPart{
+		message.TextContent{Text: "part two."},
+		message.ToolCall{ID: "call", Name: "view", Finished: true},
+		message.Usage{InputTokens: 160, OutputTokens: 20, Cost: 0.2},
+		message.Finish{Reason: message.FinishReasonToolUse},
+	}}
+
+	merged := mergeContinuation(truncated, continuation)
+	assert.Equal(t, "1", merged.ID)
+	assert.Equal(t, "Part one, part two.", merged.Content().Text)
+	assert.Equal(t, []message.ToolCall{{ID: "call", Name: "view", Finished: true}}, merged.ToolCalls())
+	assert.Equal(t, message.FinishReasonToolUse, merged.FinishReason())
+	assert.Equal(t, int64(260), merged.Usage().InputTokens)
+	assert.InDelta(t, 0.3, merged.Usage().Cost, 1e-9)
+	assert.Equal(t, "Part one, ", truncated.Content().Text, "the truncated message is left as it was")
+	assert.True(t, truncatedResponse(truncated))
+	assert.False(t, truncatedResponse(merged))
+}
@@ -0,0 +1,106 @@
+package embedding
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/logging"
+)
+
+// cached reuses the embeddings of texts already embedded with the same
+// provider and model
+type cached struct {
+	Provider
+	dir string
+}
+
+// Cached wraps p with an on-disk cache in dir. Entries are keyed by the hash
+// of the text, under a directory per provider and model.
+func Cached(p Provider, dir string) Provider {
+	model := strings.NewReplacer("/", "_", ":", "_", "\\", "_").Replace(p.Model())
+	return &cached{Provider: p, dir: filepath.Join(dir, p.Name(), model)}
+}
+
+func (c *cached) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+func (c *cached) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	keys := make([]string, len(texts))
+	missing := make(map[string][]int) // Key -> indexes of the texts not cached
+	var misses []string
+	for i, text := range texts {
+		sum := sha256.Sum256([]byte(text))
+		keys[i] = hex.EncodeToString(sum[:])
+		if embedding, ok := c.load(keys[i]); ok {
+			embeddings[i] = embedding
+			continue
+		}
+		// The same text is only embedded once
+		if _, ok := missing[keys[i]]; !ok {
+			misses = append(misses, text)
+		}
+		missing[keys[i]] = append(missing[keys[i]], i)
+	}
+	if len(misses) == 0 {
+		return embeddings, nil
+	}
+
+	computed, err := c.Provider.Embed(ctx, misses)
+	if err != nil {
+		return nil, err
+	}
+	if len(computed) != len(misses) {
+		return nil, fmt.Errorf("%s returned %d embeddings for %d texts", c.Name(), len(computed), len(misses))
+	}
+	for i, text := range misses {
+		sum := sha256.Sum256([]byte(text))
+		key := hex.EncodeToString(sum[:])
+		for _, index := range missing[key] {
+			embeddings[index] = computed[i]
+		}
+		c.store(key, computed[i])
+	}
+	return embeddings, nil
+}
+
+// load reads an embedding stored as little endian float32s
+func (c *cached) load(key string) ([]float32, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil || len(data) == 0 || len(data)%4 != 0 {
+		return nil, false
+	}
+	embedding := make([]float32, len(data)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return embedding, true
+}
+
+func (c *cached) store(key string, embedding []float32) {
+	data := make([]byte, len(embedding)*4)
+	for i, value := range embedding {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(value))
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		logging.Warn("Failed to create embedding cache directory", "error", err)
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		logging.Warn("Failed to write embedding cache entry", "error", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		logging.Warn("Failed to store embedding cache entry", "key", key, "error", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Package embedding turns texts into vectors whose distance reflects their
+// meaning, for the features ranking text by relevance rather than by words.
+// The provider is set in the embedding section of the config, and its
+// embeddings are cached on disk by the hash of the text.
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/models"
+)
+
+// ErrDisabled is returned when no embedding provider is configured
+var ErrDisabled = errors.New("embeddings are disabled, set embedding.provider in the config")
+
+// Provider embeds texts with a model
+type Provider interface {
+	Name() string
+	Model() string
+	// Embed returns the embeddings of the texts, in their order
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Defaults of the providers
+const (
+	openAIBaseURL = "https://api.openai.com/v1"
+	openAIModel   = "text-embedding-3-small"
+	voyageBaseURL = "https://api.voyageai.com/v1"
+	voyageModel   = "voyage-code-3"
+	ollamaBaseURL = "http://localhost:11434"
+	ollamaModel   = "nomic-embed-text"
+	ggufBaseURL   = "http://localhost:8080/v1"
+)
+
+// New returns the provider of the config, embedding in batches and caching
+// the embeddings in the data directory
+func New() (Provider, error) {
+	cfg := config.Get()
+	p, err := newProvider(cfg.Embedding)
+	if err != nil {
+		return nil, err
+	}
+	p = Batched(p, cfg.Embedding.BatchSize)
+	return Cached(p, filepath.Join(cfg.Data.Directory, "embeddings")), nil
+}
+
+func newProvider(cfg config.EmbeddingConfig) (Provider, error) {
+	baseURL := func(fallback string) string {
+		if cfg.BaseURL != "" {
+			return strings.TrimSuffix(cfg.BaseURL, "/")
+		}
+		return fallback
+	}
+	model := func(fallback string) string {
+		if cfg.Model != "" {
+			return cfg.Model
+		}
+		return fallback
+	}
+
+	switch cfg.Provider {
+	case "":
+		return nil, ErrDisabled
+	case config.EmbeddingOpenAI:
+		key := cfg.APIKey
+		if key == "" {
+			key = config.Get().Providers[models.ProviderOpenAI].APIKey
+		}
+		if key == "" {
+			return nil, fmt.Errorf("no API key for the OpenAI embeddings")
+		}
+		return newOpenAI(config.EmbeddingOpenAI, baseURL(openAIBaseURL), model(openAIModel), key), nil
+	case config.EmbeddingVoyage:
+		key := cfg.APIKey
+		if key == "" {
+			key = os.Getenv("VOYAGE_API_KEY")
+		}
+		if key == "" {
+			return nil, fmt.Errorf("no API key for the Voyage embeddings, set embedding.apiKey or VOYAGE_API_KEY")
+		}
+		// The API of Voyage is the one of OpenAI
+		return newOpenAI(config.EmbeddingVoyage, baseURL(voyageBaseURL), model(voyageModel), key), nil
+	case config.EmbeddingGGUF:
+		// The llama.cpp server serves the model it was started with
+		return newOpenAI(config.EmbeddingGGUF, baseURL(ggufBaseURL), model("default"), cfg.APIKey), nil
+	case config.EmbeddingOllama:
+		return newOllama(baseURL(ollamaBaseURL), model(ollamaModel)), nil
+	}
+	return nil, fmt.Errorf("unknown embedding provider %q", cfg.Provider)
+}
+
+// batched embeds texts in batches of at most size texts per request
+type batched struct {
+	Provider
+	size int
+}
+
+// Batched wraps p so it's sent at most size texts per request
+func Batched(p Provider, size int) Provider {
+	if size <= 0 {
+		return p
+	}
+	return &batched{Provider: p, size: size}
+}
+
+func (b *batched) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += b.size {
+		batch := texts[start:min(start+b.size, len(texts))]
+		batchEmbeddings, err := b.Provider.Embed(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(batchEmbeddings) != len(batch) {
+			return nil, fmt.Errorf("%s returned %d embeddings for %d texts", b.Name(), len(batchEmbeddings), len(batch))
+		}
+		embeddings = append(embeddings, batchEmbeddings...)
+	}
+	return embeddings, nil
+}
+
+// Similarity returns the cosine similarity of two embeddings, from -1 to 1,
+// or 0 when their dimensions differ
+func Similarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,114 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider embeds a text as its length and counts the texts it embedded
+type fakeProvider struct {
+	batches [][]string
+}
+
+func (f *fakeProvider) Name() string  { return "fake" }
+func (f *fakeProvider) Model() string { return "org/model:latest" }
+
+func (f *fakeProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	f.batches = append(f.batches, texts)
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i] = []float32{float32(len(text)), 1}
+	}
+	return embeddings, nil
+}
+
+func TestBatched(t *testing.T) {
+	fake := &fakeProvider{}
+	embeddings, err := Batched(fake, 2).Embed(context.Background(), []string{"a", "bb", "ccc", "dddd", "eeeee"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"a", "bb"}, {"ccc", "dddd"}, {"eeeee"}}, fake.batches)
+	assert.Len(t, embeddings, 5)
+	assert.Equal(t, float32(5), embeddings[4][0])
+}
+
+func TestCached(t *testing.T) {
+	fake := &fakeProvider{}
+	p := Cached(fake, t.TempDir())
+
+	embeddings, err := p.Embed(context.Background(), []string{"one", "three", "one"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"one", "three"}}, fake.batches, "the same text is embedded once")
+	assert.Equal(t, [][]float32{{3, 1}, {5, 1}, {3, 1}}, embeddings)
+
+	embeddings, err = p.Embed(context.Background(), []string{"three", "seven"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"seven"}, fake.batches[1], "cached texts aren't embedded again")
+	assert.Equal(t, [][]float32{{5, 1}, {5, 1}}, embeddings)
+}
+
+func TestOpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		var request struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "voyage-code-3", request.Model)
+		// Out of order, the index tells the text
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	p, err := newProvider(config.EmbeddingConfig{Provider: config.EmbeddingVoyage, BaseURL: server.URL + "/v1/", APIKey: "key"})
+	require.NoError(t, err)
+	embeddings, err := p.Embed(context.Background(), []string{"first", "second"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, embeddings)
+}
+
+func TestOllama(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			http.Error(w, "model not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"embeddings":[[0.5,0.5]]}`))
+	}))
+	defer server.Close()
+
+	p, err := newProvider(config.EmbeddingConfig{Provider: config.EmbeddingOllama, BaseURL: server.URL})
+	require.NoError(t, err)
+	assert.Equal(t, "nomic-embed-text", p.Model())
+	embeddings, err := p.Embed(context.Background(), []string{"text"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.5, 0.5}}, embeddings)
+
+	p, err = newProvider(config.EmbeddingConfig{Provider: config.EmbeddingGGUF, BaseURL: server.URL})
+	require.NoError(t, err)
+	_, err = p.Embed(context.Background(), []string{"text"})
+	assert.ErrorContains(t, err, "404 Not Found: model not found")
+}
+
+func TestNewProvider(t *testing.T) {
+	_, err := newProvider(config.EmbeddingConfig{})
+	assert.ErrorIs(t, err, ErrDisabled)
+	t.Setenv("VOYAGE_API_KEY", "")
+	_, err = newProvider(config.EmbeddingConfig{Provider: config.EmbeddingVoyage})
+	assert.ErrorContains(t, err, "no API key")
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.InDelta(t, 1, Similarity([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	assert.InDelta(t, 0, Similarity([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	assert.InDelta(t, -1, Similarity([]float32{1, 0}, []float32{-1, 0}), 1e-9)
+	assert.Zero(t, Similarity([]float32{1}, []float32{1, 0}))
+}
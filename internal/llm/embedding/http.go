@@ -0,0 +1,132 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// requestTimeout bounds a request of a batch, local models may be slow
+const requestTimeout = 2 * time.Minute
+
+// maxErrorBody is how much of the body of a failed request is reported
+const maxErrorBody = 512
+
+var httpClient = &http.Client{Timeout: requestTimeout}
+
+// postJSON sends a request to an embedding endpoint and decodes its response
+func postJSON(ctx context.Context, name, url, apiKey string, request, response any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s embeddings: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("%s embeddings: %s: %s", name, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("%s embeddings: invalid response: %w", name, err)
+	}
+	return nil
+}
+
+// openAI embeds texts with the API of OpenAI, also served by Voyage and the
+// llama.cpp server
+type openAI struct {
+	name    string
+	baseURL string
+	model   string
+	apiKey  string
+}
+
+type openAIEmbedding struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+func newOpenAI(name, baseURL, model, apiKey string) *openAI {
+	return &openAI{name: name, baseURL: baseURL, model: model, apiKey: apiKey}
+}
+
+func (o *openAI) Name() string  { return o.name }
+func (o *openAI) Model() string { return o.model }
+
+func (o *openAI) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	request := struct {
+		Model string   `json:"model"`
+		Input []string `json:"input"`
+	}{Model: o.model, Input: texts}
+	var response struct {
+		Data []openAIEmbedding `json:"data"`
+	}
+	if err := postJSON(ctx, o.name, o.baseURL+"/embeddings", o.apiKey, request, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("%s embeddings: got %d embeddings for %d texts", o.name, len(response.Data), len(texts))
+	}
+	// The order of the embeddings is given by their index
+	slices.SortFunc(response.Data, func(a, b openAIEmbedding) int {
+		return a.Index - b.Index
+	})
+	embeddings := make([][]float32, len(texts))
+	for i, data := range response.Data {
+		embeddings[i] = data.Embedding
+	}
+	return embeddings, nil
+}
+
+// ollama embeds texts with a model pulled in a local Ollama
+type ollama struct {
+	baseURL string
+	model   string
+}
+
+func newOllama(baseURL, model string) *ollama {
+	return &ollama{baseURL: baseURL, model: model}
+}
+
+func (o *ollama) Name() string  { return "ollama" }
+func (o *ollama) Model() string { return o.model }
+
+func (o *ollama) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	request := struct {
+		Model string   `json:"model"`
+		Input []string `json:"input"`
+	}{Model: o.model, Input: texts}
+	var response struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := postJSON(ctx, o.Name(), o.baseURL+"/api/embed", "", request, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama embeddings: got %d embeddings for %d texts", len(response.Embeddings), len(texts))
+	}
+	return response.Embeddings, nil
+}
@@ -6,11 +6,14 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/kirmad/superopencode/internal/config"
 	"github.com/kirmad/superopencode/internal/llm/models"
 	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/locale"
+	"github.com/kirmad/superopencode/internal/sparse"
 )
 
 func CoderPrompt(provider models.ModelProvider) string {
@@ -21,7 +24,7 @@ func CoderPrompt(provider models.ModelProvider) string {
 	}
 	envInfo := getEnvironmentInfo()
 
-	return fmt.Sprintf("%s\n\n%s\n%s", basePrompt, envInfo, lspInformation())
+	return fmt.Sprintf("%s\n\n%s\n%s%s\n%s", basePrompt, envInfo, sparseInformation(), lspInformation(), citationInformation)
 }
 
 const baseOpenAICoderPrompt = `
@@ -280,7 +283,7 @@ func getEnvironmentInfo() string {
 	cwd := config.WorkingDirectory()
 	isGit := isGitRepo(cwd)
 	platform := runtime.GOOS
-	date := time.Now().Format("1/2/2006")
+	now := locale.Now()
 	ls := tools.NewLsTool()
 	r, _ := ls.Run(context.Background(), tools.ToolCall{
 		Input: `{"path":"."}`,
@@ -291,11 +294,20 @@ Working directory: %s
 Is directory a git repo: %s
 Platform: %s
 Today's date: %s
+Timezone of the user: %s
+Formats of the user: %s
 </env>
 <project>
 %s
 </project>
-		`, cwd, boolToYesNo(isGit), platform, date, r.Content)
+		`, cwd, boolToYesNo(isGit), platform, now.Format(time.DateOnly), locale.Zone(now), localeFormats(now), r.Content)
+}
+
+// localeFormats shows how the user writes dates, times and numbers, for the
+// model to write them the same way in its answers
+func localeFormats(now time.Time) string {
+	return fmt.Sprintf("%s, dates like %s, times like %s, numbers like %s",
+		locale.Current().Tag, locale.Date(now), locale.Clock(now), locale.Float(1234.5, 1))
 }
 
 func isGitRepo(dir string) bool {
@@ -303,6 +315,35 @@ func isGitRepo(dir string) bool {
 	return err == nil
 }
 
+// sparseInformation tells the agent when the repository isn't all on disk, so
+// it widens the sparse checkout instead of taking missing files for absent
+// ones, and keeps git away from history it would have to download
+func sparseInformation() string {
+	checkout, err := sparse.Detect(context.Background(), config.WorkingDirectory())
+	if err != nil || !checkout.Enabled() {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("# Sparse Checkout\n")
+	if checkout.Sparse {
+		fmt.Fprintf(&sb, `This repository is a sparse checkout: only %s are on disk, the other tracked files are missing from the working tree.
+- A missing path may exist in the repository. The ls tool lists the entries that are not checked out.
+- Use the %s tool to add the directories you need before reading or editing them, never recreate their files.
+- glob, grep and git commands working on files only see the checked out files.
+`, strings.Join(checkout.Patterns, ", "), tools.SparseCheckoutToolName)
+	}
+	if checkout.PartialClone {
+		filter := ""
+		if checkout.Filter != "" {
+			filter = fmt.Sprintf(" (%s)", checkout.Filter)
+		}
+		fmt.Fprintf(&sb, `This repository is a partial clone%s: objects missing locally are downloaded from the remote when a git command needs them.
+- git log -p, git blame and diffs of old commits can be slow and need the network, limit them to paths and recent commits.
+`, filter)
+	}
+	return sb.String()
+}
+
 func lspInformation() string {
 	cfg := config.Get()
 	hasLSP := false
@@ -318,11 +359,19 @@ func lspInformation() string {
 	return `# LSP Information
 Tools that support it will also include useful diagnostics such as linting and typechecking.
 - These diagnostics will be automatically enabled when you run the tool, and will be displayed in the output at the bottom within the <file_diagnostics></file_diagnostics> and <project_diagnostics></project_diagnostics> tags.
-- Take necessary actions to fix the issues.
+- After an edit, the edit, write and patch tools only report what the edit changed within the <diagnostics_delta></diagnostics_delta> tags: the errors and warnings it introduced (+) and fixed (-), and the counts remaining in the project. Use the diagnostics tool for the full list.
+- Take necessary actions to fix the issues you introduced.
 - You should ignore diagnostics of files that you did not change or are not related or caused by your changes unless the user explicitly asks you to fix them.
 `
 }
 
+const citationInformation = `# Citations
+When you make claims about the codebase, cite the code you base them on as path:line or path:start-end, e.g. internal/app/app.go:42-57.
+- Only cite lines you have read or matched with your tools during this request.
+- Use paths relative to the working directory.
+- Citations are checked against your tool calls and shown to the user as links.
+`
+
 func boolToYesNo(b bool) string {
 	if b {
 		return "Yes"
@@ -0,0 +1,57 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// languageNames maps the codes of common languages to the name the models
+// are instructed with
+var languageNames = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"es": "Spanish",
+	"fr": "French",
+	"hi": "Hindi",
+	"id": "Indonesian",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"sv": "Swedish",
+	"tr": "Turkish",
+	"uk": "Ukrainian",
+	"vi": "Vietnamese",
+	"zh": "Chinese",
+}
+
+// LanguageName returns the name of a language given by name or by code, such
+// as "de" or "pt-BR". Unknown languages are returned as they are.
+func LanguageName(language string) string {
+	language = strings.TrimSpace(language)
+	code, region, _ := strings.Cut(strings.ReplaceAll(language, "_", "-"), "-")
+	name, ok := languageNames[strings.ToLower(code)]
+	if !ok {
+		return language
+	}
+	if region != "" {
+		return fmt.Sprintf("%s (%s)", name, strings.ToUpper(region))
+	}
+	return name
+}
+
+// ResponseLanguagePrompt returns the system prompt section that makes the
+// agents explain in a language while the code stays in English, empty for no
+// language
+func ResponseLanguagePrompt(language string) string {
+	if strings.TrimSpace(language) == "" {
+		return ""
+	}
+	name := LanguageName(language)
+	return fmt.Sprintf(`# Response Language
+Write your explanations, questions, plans and summaries for the user in %[1]s, whatever the language of the request or of these instructions.
+Keep code in English: identifiers, code comments, commit messages, file names and the input of your tools. Quote error messages, logs and command output as they are, and explain them in %[1]s.`, name)
+}
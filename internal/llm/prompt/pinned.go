@@ -0,0 +1,290 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/logging"
+)
+
+// PinKind identifies what a pinned context item refers to
+type PinKind string
+
+const (
+	PinKindFile    PinKind = "file"
+	PinKindSnippet PinKind = "snippet"
+)
+
+// PinnedItem is a file or text block that is included in every prompt of a session
+type PinnedItem struct {
+	ID      int
+	Kind    PinKind
+	Path    string // Absolute path for file pins
+	Content string // Latest content (re-read from disk for file pins)
+	ModTime time.Time
+	Tokens  int64
+}
+
+// Label returns a short human readable name for the pinned item
+func (p PinnedItem) Label() string {
+	if p.Kind == PinKindFile {
+		cfg := config.Get()
+		if cfg != nil {
+			if rel, err := filepath.Rel(cfg.WorkingDir, p.Path); err == nil && !strings.HasPrefix(rel, "..") {
+				return rel
+			}
+		}
+		return p.Path
+	}
+	label := strings.Join(strings.Fields(p.Content), " ")
+	if len(label) > 30 {
+		label = label[:27] + "..."
+	}
+	return fmt.Sprintf("%q", label)
+}
+
+type pinStore struct {
+	mu       sync.Mutex
+	nextID   map[string]int
+	pins     map[string][]PinnedItem // sessionID -> pinned items
+	released map[string]string       // sessionID -> file of the released pins
+}
+
+var pins = &pinStore{
+	nextID:   make(map[string]int),
+	pins:     make(map[string][]PinnedItem),
+	released: make(map[string]string),
+}
+
+// releasedPins are the pins of an idle session written to disk
+type releasedPins struct {
+	NextID int          `json:"next_id"`
+	Items  []PinnedItem `json:"items"`
+}
+
+// EstimateTokens returns a rough token count for the given text
+func EstimateTokens(text string) int64 {
+	return int64((len(text) + 3) / 4)
+}
+
+// Pin pins target for the session. If target names an existing file (relative
+// to the working directory or absolute) the file is pinned and re-read whenever
+// it changes, otherwise target is pinned as a text snippet.
+func Pin(sessionID, target string) (PinnedItem, error) {
+	target = strings.TrimSpace(target)
+	if sessionID == "" {
+		return PinnedItem{}, fmt.Errorf("no active session")
+	}
+	if target == "" {
+		return PinnedItem{}, fmt.Errorf("nothing to pin, provide a file path or a text snippet")
+	}
+
+	item := PinnedItem{Kind: PinKindSnippet, Content: target}
+	if path := resolvePinPath(target); path != "" {
+		item = PinnedItem{Kind: PinKindFile, Path: path}
+		if err := refreshPin(&item); err != nil {
+			return PinnedItem{}, err
+		}
+	}
+	item.Tokens = EstimateTokens(item.Content)
+
+	pins.mu.Lock()
+	defer pins.mu.Unlock()
+	restorePins(sessionID)
+	for _, existing := range pins.pins[sessionID] {
+		if item.Kind == PinKindFile && existing.Path == item.Path {
+			return existing, fmt.Errorf("%s is already pinned", existing.Label())
+		}
+	}
+	pins.nextID[sessionID]++
+	item.ID = pins.nextID[sessionID]
+	pins.pins[sessionID] = append(pins.pins[sessionID], item)
+	return item, nil
+}
+
+// Unpin removes pinned items matching ref, which can be a pin number, a file
+// path or "all". It returns the removed items.
+func Unpin(sessionID, ref string) ([]PinnedItem, error) {
+	ref = strings.TrimSpace(ref)
+	pins.mu.Lock()
+	defer pins.mu.Unlock()
+	restorePins(sessionID)
+
+	items := pins.pins[sessionID]
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no pinned context in this session")
+	}
+	if ref == "" || ref == "all" {
+		delete(pins.pins, sessionID)
+		return items, nil
+	}
+
+	id, idErr := strconv.Atoi(strings.TrimPrefix(ref, "#"))
+	path := resolvePinPath(ref)
+
+	var removed, kept []PinnedItem
+	for _, item := range items {
+		if (idErr == nil && item.ID == id) || (path != "" && item.Path == path) {
+			removed = append(removed, item)
+			continue
+		}
+		kept = append(kept, item)
+	}
+	if len(removed) == 0 {
+		return nil, fmt.Errorf("no pinned item matches %q", ref)
+	}
+	pins.pins[sessionID] = kept
+	return removed, nil
+}
+
+// ListPins returns the pinned items of a session, re-reading files that
+// changed on disk since they were last loaded.
+func ListPins(sessionID string) []PinnedItem {
+	pins.mu.Lock()
+	defer pins.mu.Unlock()
+	restorePins(sessionID)
+
+	items := pins.pins[sessionID]
+	for i := range items {
+		if items[i].Kind != PinKindFile {
+			continue
+		}
+		if err := refreshPin(&items[i]); err != nil {
+			// Keep the last known content if the file disappeared
+			continue
+		}
+		items[i].Tokens = EstimateTokens(items[i].Content)
+	}
+	return append([]PinnedItem(nil), items...)
+}
+
+// ReleasePins writes the pinned items of an idle session to a file in dir and
+// frees them, they are read back the next time the session uses them. File
+// pins are written without their content, which is read again from the file.
+func ReleasePins(sessionID, dir string) error {
+	pins.mu.Lock()
+	defer pins.mu.Unlock()
+	items := pins.pins[sessionID]
+	if len(items) == 0 {
+		return nil
+	}
+
+	released := releasedPins{NextID: pins.nextID[sessionID]}
+	for _, item := range items {
+		if item.Kind == PinKindFile {
+			item.Content = ""
+			item.ModTime = time.Time{}
+		}
+		released.Items = append(released.Items, item)
+	}
+	data, err := json.Marshal(released)
+	if err != nil {
+		return fmt.Errorf("failed to encode the pins: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, sessionID+"-pins.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	pins.released[sessionID] = path
+	delete(pins.pins, sessionID)
+	delete(pins.nextID, sessionID)
+	return nil
+}
+
+// restorePins reads back the pins of a released session, pins.mu must be
+// held
+func restorePins(sessionID string) {
+	path, ok := pins.released[sessionID]
+	if !ok {
+		return
+	}
+	delete(pins.released, sessionID)
+	defer os.Remove(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logging.Warn("Failed to read released pins", "session_id", sessionID, "error", err)
+		return
+	}
+	var released releasedPins
+	if err := json.Unmarshal(data, &released); err != nil {
+		logging.Warn("Failed to decode released pins", "session_id", sessionID, "error", err)
+		return
+	}
+	pins.nextID[sessionID] = released.NextID
+	pins.pins[sessionID] = released.Items
+}
+
+// PinnedContext renders the pinned items of a session as a prompt block.
+// It returns an empty string when nothing is pinned.
+func PinnedContext(sessionID string) string {
+	items := ListPins(sessionID)
+	if len(items) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<pinned-context>\nThe user pinned the following context for this session. It always reflects the latest content.\n")
+	for _, item := range items {
+		switch item.Kind {
+		case PinKindFile:
+			sb.WriteString(fmt.Sprintf("\n# File: %s\n%s\n", item.Path, item.Content))
+		default:
+			sb.WriteString(fmt.Sprintf("\n# Snippet %d\n%s\n", item.ID, item.Content))
+		}
+	}
+	sb.WriteString("</pinned-context>")
+	return sb.String()
+}
+
+// refreshPin re-reads a pinned file when its modification time changed
+func refreshPin(item *PinnedItem) error {
+	info, err := os.Stat(item.Path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", item.Path, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory, pin individual files instead", item.Path)
+	}
+	if !item.ModTime.IsZero() && info.ModTime().Equal(item.ModTime) {
+		return nil
+	}
+	content, err := os.ReadFile(item.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", item.Path, err)
+	}
+	item.Content = string(content)
+	item.ModTime = info.ModTime()
+	return nil
+}
+
+// resolvePinPath returns the absolute path for target if it names an existing
+// file, or an empty string otherwise.
+func resolvePinPath(target string) string {
+	if strings.ContainsAny(target, "\n") {
+		return ""
+	}
+	path := target
+	if !filepath.IsAbs(path) {
+		cfg := config.Get()
+		if cfg == nil {
+			return ""
+		}
+		path = filepath.Join(cfg.WorkingDir, path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return filepath.Clean(path)
+}
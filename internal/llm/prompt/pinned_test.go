@@ -0,0 +1,101 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinSnippetAndFile(t *testing.T) {
+	sessionID := "pin-session-" + t.Name()
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "notes.md")
+	require.NoError(t, os.WriteFile(filePath, []byte("first version"), 0o644))
+
+	snippet, err := Pin(sessionID, "we use tabs, never spaces")
+	require.NoError(t, err)
+	assert.Equal(t, PinKindSnippet, snippet.Kind)
+	assert.Equal(t, 1, snippet.ID)
+
+	file, err := Pin(sessionID, filePath)
+	require.NoError(t, err)
+	assert.Equal(t, PinKindFile, file.Kind)
+	assert.Equal(t, "first version", file.Content)
+	assert.Greater(t, file.Tokens, int64(0))
+
+	_, err = Pin(sessionID, filePath)
+	assert.Error(t, err, "pinning the same file twice should fail")
+
+	context := PinnedContext(sessionID)
+	assert.Contains(t, context, "we use tabs, never spaces")
+	assert.Contains(t, context, "first version")
+
+	// Pinned files are re-read when they change on disk
+	require.NoError(t, os.WriteFile(filePath, []byte("second version"), 0o644))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filePath, future, future))
+
+	context = PinnedContext(sessionID)
+	assert.Contains(t, context, "second version")
+	assert.False(t, strings.Contains(context, "first version"))
+}
+
+func TestUnpin(t *testing.T) {
+	sessionID := "pin-session-" + t.Name()
+
+	_, err := Unpin(sessionID, "")
+	assert.Error(t, err)
+
+	_, err = Pin(sessionID, "one")
+	require.NoError(t, err)
+	_, err = Pin(sessionID, "two")
+	require.NoError(t, err)
+
+	removed, err := Unpin(sessionID, "#1")
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, "one", removed[0].Content)
+
+	_, err = Unpin(sessionID, "42")
+	assert.Error(t, err)
+
+	removed, err = Unpin(sessionID, "all")
+	require.NoError(t, err)
+	assert.Len(t, removed, 1)
+	assert.Empty(t, PinnedContext(sessionID))
+}
+
+func TestReleasePins(t *testing.T) {
+	sessionID := "pin-session-" + t.Name()
+	dir := t.TempDir()
+	filePath := filepath.Join(t.TempDir(), "notes.md")
+	require.NoError(t, os.WriteFile(filePath, []byte("file content"), 0o644))
+
+	_, err := Pin(sessionID, "a snippet")
+	require.NoError(t, err)
+	_, err = Pin(sessionID, filePath)
+	require.NoError(t, err)
+
+	require.NoError(t, ReleasePins(sessionID, dir))
+	pins.mu.Lock()
+	_, inMemory := pins.pins[sessionID]
+	pins.mu.Unlock()
+	assert.False(t, inMemory)
+	assert.FileExists(t, filepath.Join(dir, sessionID+"-pins.json"))
+
+	// The pins are read back on next use, with the content of files
+	items := ListPins(sessionID)
+	require.Len(t, items, 2)
+	assert.Equal(t, "a snippet", items[0].Content)
+	assert.Equal(t, "file content", items[1].Content)
+	assert.NoFileExists(t, filepath.Join(dir, sessionID+"-pins.json"))
+
+	next, err := Pin(sessionID, "another snippet")
+	require.NoError(t, err)
+	assert.Equal(t, 3, next.ID)
+}
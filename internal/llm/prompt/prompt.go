@@ -24,11 +24,17 @@ func GetAgentPrompt(agentName config.AgentName, provider models.ModelProvider) s
 		basePrompt = TaskPrompt(provider)
 	case config.AgentSummarizer:
 		basePrompt = SummarizerPrompt(provider)
+	case config.AgentReviewer:
+		basePrompt = ReviewerPrompt(provider)
+	case config.AgentSecurity:
+		basePrompt = SecurityPrompt(provider)
+	case config.AgentMigrator:
+		basePrompt = MigratorPrompt(provider)
 	default:
 		basePrompt = "You are a helpful assistant"
 	}
 
-	if agentName == config.AgentCoder || agentName == config.AgentTask {
+	if agentName == config.AgentCoder || agentName == config.AgentTask || agentName == config.AgentReviewer || agentName == config.AgentSecurity || agentName == config.AgentMigrator {
 		// Add context from project-specific instruction files if they exist
 		contextContent := getContextFromPaths()
 		logging.Debug("Context content", "Context", contextContent)
@@ -39,6 +45,22 @@ func GetAgentPrompt(agentName config.AgentName, provider models.ModelProvider) s
 	return basePrompt
 }
 
+// ContextFile is a project instruction file added to the system prompt
+type ContextFile struct {
+	Path    string
+	Content string
+}
+
+// ProjectContextFiles returns the project instruction files added to the
+// system prompt of the coder and task agents, such as OpenCode.md
+func ProjectContextFiles() []ContextFile {
+	var files []ContextFile
+	for _, block := range strings.Split("\n"+getContextFromPaths(), "\n# From:")[1:] {
+		path, content, _ := strings.Cut(block, "\n")
+		files = append(files, ContextFile{Path: path, Content: content})
+	}
+	return files
+}
 
 var (
 	onceContext    sync.Once
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -37,6 +39,19 @@ func TestGetContextFromPaths(t *testing.T) {
 	context := getContextFromPaths()
 	expectedContext := fmt.Sprintf("# From:%s/file.txt\nfile.txt: test content\n# From:%s/directory/file_a.txt\ndirectory/file_a.txt: test content\n# From:%s/directory/file_b.txt\ndirectory/file_b.txt: test content\n# From:%s/directory/file_c.txt\ndirectory/file_c.txt: test content", tmpDir, tmpDir, tmpDir, tmpDir)
 	assert.Equal(t, expectedContext, context)
+
+	files := ProjectContextFiles()
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.Path
+		assert.Equal(t, file.Path[len(tmpDir)+1:]+": test content", file.Content)
+	}
+	assert.ElementsMatch(t, []string{
+		tmpDir + "/file.txt",
+		tmpDir + "/directory/file_a.txt",
+		tmpDir + "/directory/file_b.txt",
+		tmpDir + "/directory/file_c.txt",
+	}, paths)
 }
 
 func createTestFiles(t *testing.T, tmpDir string, testFiles []string) {
@@ -313,3 +328,26 @@ func TestFindFile_DotDotPaths(t *testing.T) {
 	abs, _ := filepath.Abs(parentFile)
 	assert.Equal(t, abs, result)
 }
+
+func TestResponseLanguagePrompt(t *testing.T) {
+	assert.Equal(t, "German", LanguageName("de"))
+	assert.Equal(t, "Portuguese (BR)", LanguageName("pt_br"))
+	assert.Equal(t, "Klingon", LanguageName(" Klingon "))
+
+	assert.Empty(t, ResponseLanguagePrompt(""))
+	section := ResponseLanguagePrompt("ja")
+	assert.Contains(t, section, "for the user in Japanese")
+	assert.Contains(t, section, "Keep code in English")
+}
+
+func TestReviewerPrompt(t *testing.T) {
+	tmpDir := t.TempDir()
+	_, err := config.Load(tmpDir, false)
+	require.NoError(t, err)
+
+	reviewer := ReviewerPrompt(models.ProviderAnthropic)
+	assert.True(t, strings.HasPrefix(reviewer, "You are a code reviewer for OpenCode"))
+	assert.Contains(t, reviewer, "1. [high|medium|low] path:line - the problem - the suggested fix")
+	assert.Contains(t, reviewer, "respond with exactly: No findings.")
+	assert.Contains(t, reviewer, tmpDir, "the reviewer knows the working directory")
+}
@@ -41,7 +41,10 @@ func newAnthropicClient(opts providerClientOptions) AnthropicClient {
 		o(&anthropicOpts)
 	}
 
-	anthropicClientOptions := []option.RequestOption{}
+	anthropicClientOptions := []option.RequestOption{
+		// Bedrock signs the request body
+		option.WithHTTPClient(providerHTTPClient(!anthropicOpts.useBedrock)),
+	}
 	if opts.apiKey != "" {
 		anthropicClientOptions = append(anthropicClientOptions, option.WithAPIKey(opts.apiKey))
 	}
@@ -139,12 +142,13 @@ func (a *anthropicClient) convertTools(tools []toolsPkg.BaseTool) []anthropic.To
 
 	for i, tool := range tools {
 		info := tool.Info()
+		schema := normalizeToolSchema(info, dialectAnthropic)
 		toolParam := anthropic.ToolParam{
 			Name:        info.Name,
 			Description: anthropic.String(info.Description),
 			InputSchema: anthropic.ToolInputSchemaParam{
-				Properties: info.Parameters,
-				// TODO: figure out how we can tell claude the required fields?
+				Properties: schema["properties"],
+				Required:   requiredOf(schema),
 			},
 		}
 
@@ -175,7 +179,7 @@ func (a *anthropicClient) finishReason(reason string) message.FinishReason {
 	}
 }
 
-func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, tools []anthropic.ToolUnionParam) anthropic.MessageNewParams {
+func (a *anthropicClient) preparedMessages(ctx context.Context, messages []anthropic.MessageParam, tools []anthropic.ToolUnionParam) anthropic.MessageNewParams {
 	var thinkingParam anthropic.ThinkingConfigParamUnion
 	lastMessage := messages[len(messages)-1]
 	isUser := lastMessage.Role == anthropic.MessageParamRoleUser
@@ -202,7 +206,7 @@ func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, to
 		Thinking:    thinkingParam,
 		System: []anthropic.TextBlockParam{
 			{
-				Text: a.providerOptions.systemMessage,
+				Text: a.providerOptions.systemPrompt(ctx),
 				CacheControl: anthropic.CacheControlEphemeralParam{
 					Type: "ephemeral",
 				},
@@ -212,7 +216,7 @@ func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, to
 }
 
 func (a *anthropicClient) send(ctx context.Context, messages []message.Message, tools []toolsPkg.BaseTool) (resposne *ProviderResponse, err error) {
-	preparedMessages := a.preparedMessages(a.convertMessages(ctx, messages), a.convertTools(tools))
+	preparedMessages := a.preparedMessages(ctx, a.convertMessages(ctx, messages), a.convertTools(tools))
 	cfg := config.Get()
 	if cfg.Debug {
 		jsonData, _ := json.Marshal(preparedMessages)
@@ -261,7 +265,7 @@ func (a *anthropicClient) send(ctx context.Context, messages []message.Message,
 }
 
 func (a *anthropicClient) stream(ctx context.Context, messages []message.Message, tools []toolsPkg.BaseTool) <-chan ProviderEvent {
-	preparedMessages := a.preparedMessages(a.convertMessages(ctx, messages), a.convertTools(tools))
+	preparedMessages := a.preparedMessages(ctx, a.convertMessages(ctx, messages), a.convertTools(tools))
 	cfg := config.Get()
 
 	var sessionId string
@@ -26,6 +26,7 @@ func newAzureClient(opts providerClientOptions) AzureClient {
 
 	reqOpts := []option.RequestOption{
 		azure.WithEndpoint(endpoint, apiVersion),
+		option.WithHTTPClient(providerHTTPClient(true)),
 	}
 
 	if opts.apiKey != "" || os.Getenv("AZURE_OPENAI_API_KEY") != "" {
@@ -0,0 +1,202 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+// cachingProvider replays responses for requests it has already seen. It is
+// used for non-interactive runs so repeated invocations on unchanged inputs
+// cost nothing.
+type cachingProvider struct {
+	wrapped    Provider
+	dir        string
+	systemHash string
+	ttl        time.Duration
+}
+
+type cacheEntry struct {
+	CreatedAt time.Time        `json:"created_at"`
+	Response  ProviderResponse `json:"response"`
+}
+
+// cacheKeyMessage is the part of a message that identifies a request. IDs and
+// timestamps are left out so identical conversations map to the same key.
+type cacheKeyMessage struct {
+	Role        message.MessageRole `json:"role"`
+	Content     string              `json:"content"`
+	ToolCalls   [][2]string         `json:"tool_calls,omitempty"`
+	ToolResults []cacheKeyResult    `json:"tool_results,omitempty"`
+	Binary      []string            `json:"binary,omitempty"`
+}
+
+type cacheKeyResult struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	IsError bool   `json:"is_error"`
+}
+
+// NewCachingProvider wraps p with an on-disk response cache stored in dir.
+// Entries older than ttl are ignored, a zero ttl keeps entries forever.
+func NewCachingProvider(p Provider, systemPrompt string, dir string, ttl time.Duration) Provider {
+	sum := sha256.Sum256([]byte(systemPrompt))
+	return &cachingProvider{
+		wrapped:    p,
+		dir:        dir,
+		systemHash: hex.EncodeToString(sum[:]),
+		ttl:        ttl,
+	}
+}
+
+func (c *cachingProvider) Model() models.Model {
+	return c.wrapped.Model()
+}
+
+func (c *cachingProvider) Warmup(ctx context.Context) error {
+	return Warmup(ctx, c.wrapped)
+}
+
+func (c *cachingProvider) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	key := c.key(ctx, messages, tools)
+	if resp, ok := c.load(key); ok {
+		return resp, nil
+	}
+	resp, err := c.wrapped.SendMessages(ctx, messages, tools)
+	if err == nil {
+		c.store(key, resp)
+	}
+	return resp, err
+}
+
+func (c *cachingProvider) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	key := c.key(ctx, messages, tools)
+	eventChan := make(chan ProviderEvent)
+
+	if resp, ok := c.load(key); ok {
+		go func() {
+			defer close(eventChan)
+			if resp.Content != "" {
+				eventChan <- ProviderEvent{Type: EventContentDelta, Content: resp.Content}
+			}
+			eventChan <- ProviderEvent{Type: EventComplete, Response: resp}
+		}()
+		return eventChan
+	}
+
+	go func() {
+		defer close(eventChan)
+		for event := range c.wrapped.StreamResponse(ctx, messages, tools) {
+			if event.Type == EventComplete && event.Response != nil {
+				c.store(key, event.Response)
+			}
+			eventChan <- event
+		}
+	}()
+	return eventChan
+}
+
+// key hashes the model, the system prompt, the available tools and the
+// conversation including tool results
+func (c *cachingProvider) key(ctx context.Context, messages []message.Message, agentTools []tools.BaseTool) string {
+	toolNames := make([]string, 0, len(agentTools))
+	for _, tool := range agentTools {
+		toolNames = append(toolNames, tool.Info().Name)
+	}
+
+	keyMessages := make([]cacheKeyMessage, 0, len(messages))
+	for _, msg := range messages {
+		km := cacheKeyMessage{
+			Role:    msg.Role,
+			Content: msg.Content().String(),
+		}
+		for _, call := range msg.ToolCalls() {
+			km.ToolCalls = append(km.ToolCalls, [2]string{call.Name, call.Input})
+		}
+		for _, result := range msg.ToolResults() {
+			km.ToolResults = append(km.ToolResults, cacheKeyResult{
+				Name:    result.Name,
+				Content: result.Content,
+				IsError: result.IsError,
+			})
+		}
+		for _, binary := range msg.BinaryContent() {
+			sum := sha256.Sum256(binary.Data)
+			km.Binary = append(km.Binary, hex.EncodeToString(sum[:]))
+		}
+		keyMessages = append(keyMessages, km)
+	}
+
+	data, _ := json.Marshal(struct {
+		Model    models.ModelID    `json:"model"`
+		System   string            `json:"system"`
+		Session  string            `json:"session_instructions,omitempty"`
+		Tools    []string          `json:"tools"`
+		Messages []cacheKeyMessage `json:"messages"`
+	}{
+		Model:    c.wrapped.Model().ID,
+		System:   c.systemHash,
+		Session:  SystemPromptAdditions(ctx),
+		Tools:    toolNames,
+		Messages: keyMessages,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *cachingProvider) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *cachingProvider) load(key string) (*ProviderResponse, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		logging.Debug("Ignoring corrupt response cache entry", "key", key, "error", err)
+		return nil, false
+	}
+	if c.ttl > 0 && time.Since(entry.CreatedAt) > c.ttl {
+		return nil, false
+	}
+	logging.Debug("Response cache hit", "key", key)
+
+	// Replayed responses are free
+	resp := entry.Response
+	resp.Usage = TokenUsage{}
+	return &resp, true
+}
+
+func (c *cachingProvider) store(key string, resp *ProviderResponse) {
+	if resp.FinishReason == message.FinishReasonCanceled || resp.FinishReason == message.FinishReasonError {
+		return
+	}
+	data, err := json.Marshal(cacheEntry{CreatedAt: time.Now(), Response: *resp})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		logging.Warn("Failed to create response cache directory", "error", err)
+		return
+	}
+	tmp := c.path(key) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		logging.Warn("Failed to write response cache entry", "error", err)
+		return
+	}
+	if err := os.Rename(tmp, c.path(key)); err != nil {
+		logging.Warn(fmt.Sprintf("Failed to store response cache entry %s", key), "error", err)
+	}
+}
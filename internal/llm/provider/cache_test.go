@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	p.calls++
+	return &ProviderResponse{
+		Content:      "answer",
+		FinishReason: message.FinishReasonEndTurn,
+		Usage:        TokenUsage{InputTokens: 10, OutputTokens: 5},
+	}, nil
+}
+
+func (p *countingProvider) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	ch := make(chan ProviderEvent, 2)
+	resp, _ := p.SendMessages(ctx, messages, tools)
+	ch <- ProviderEvent{Type: EventContentDelta, Content: resp.Content}
+	ch <- ProviderEvent{Type: EventComplete, Response: resp}
+	close(ch)
+	return ch
+}
+
+func (p *countingProvider) Model() models.Model {
+	return models.Model{ID: "test-model"}
+}
+
+func userMessage(text string) []message.Message {
+	return []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: text}},
+	}}
+}
+
+func TestCachingProvider(t *testing.T) {
+	wrapped := &countingProvider{}
+	p := NewCachingProvider(wrapped, "system", t.TempDir(), 0)
+
+	var events []ProviderEvent
+	for event := range p.StreamResponse(context.Background(), userMessage("question"), nil) {
+		events = append(events, event)
+	}
+	require.Len(t, events, 2)
+	assert.Equal(t, 1, wrapped.calls)
+
+	// The same request is replayed without calling the provider and at no cost
+	events = nil
+	for event := range p.StreamResponse(context.Background(), userMessage("question"), nil) {
+		events = append(events, event)
+	}
+	require.Len(t, events, 2)
+	assert.Equal(t, 1, wrapped.calls)
+	assert.Equal(t, "answer", events[0].Content)
+	assert.Equal(t, TokenUsage{}, events[1].Response.Usage)
+
+	resp, err := p.SendMessages(context.Background(), userMessage("question"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "answer", resp.Content)
+	assert.Equal(t, 1, wrapped.calls)
+
+	// Different inputs miss the cache
+	_, err = p.SendMessages(context.Background(), userMessage("another question"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, wrapped.calls)
+
+	// So does a different system prompt
+	other := NewCachingProvider(wrapped, "other system", p.(*cachingProvider).dir, 0)
+	_, err = other.SendMessages(context.Background(), userMessage("question"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, wrapped.calls)
+
+	// And different session instructions
+	ctx := WithSystemPromptAdditions(context.Background(), "we use tabs, never spaces")
+	_, err = p.SendMessages(ctx, userMessage("question"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, wrapped.calls)
+}
+
+func TestSystemPromptAdditions(t *testing.T) {
+	opts := providerClientOptions{systemMessage: "base prompt"}
+	assert.Equal(t, "base prompt", opts.systemPrompt(context.Background()))
+
+	ctx := WithSystemPromptAdditions(context.Background(), "we use tabs, never spaces")
+	prompt := opts.systemPrompt(ctx)
+	assert.True(t, strings.HasPrefix(prompt, "base prompt"))
+	assert.Contains(t, prompt, "we use tabs, never spaces")
+}
@@ -157,6 +157,7 @@ func newCopilotClient(opts providerClientOptions) CopilotClient {
 	openaiClientOptions := []option.RequestOption{
 		option.WithBaseURL(copilotOpts.baseURL),
 		option.WithAPIKey(bearerToken), // Use bearer token as API key
+		option.WithHTTPClient(providerHTTPClient(true)),
 	}
 
 	// Add GitHub Copilot specific headers
@@ -185,7 +186,7 @@ func newCopilotClient(opts providerClientOptions) CopilotClient {
 
 func (c *copilotClient) convertMessages(ctx context.Context, messages []message.Message) (copilotMessages []openai.ChatCompletionMessageParamUnion) {
 	// Add system message first
-	copilotMessages = append(copilotMessages, openai.SystemMessage(c.providerOptions.systemMessage))
+	copilotMessages = append(copilotMessages, openai.SystemMessage(c.providerOptions.systemPrompt(ctx)))
 
 	for _, msg := range messages {
 		switch msg.Role {
@@ -256,30 +257,13 @@ func (c *copilotClient) convertTools(tools []toolsPkg.BaseTool) []openai.ChatCom
 
 	for i, tool := range tools {
 		info := tool.Info()
-		
-		
-		// Handle different parameter formats
-		var parameters openai.FunctionParameters
-		if _, hasType := info.Parameters["type"]; hasType {
-			// This is a complete JSON schema (like TodoRead/TodoWrite)
-			parameters = openai.FunctionParameters(info.Parameters)
-		} else {
-			// This is just properties (like Bash, Edit, etc.)
-			parameters = openai.FunctionParameters{
-				"type":       "object",
-				"properties": info.Parameters,
-				"required":   info.Required,
-			}
-		}
-		
 		copilotTools[i] = openai.ChatCompletionToolParam{
 			Function: openai.FunctionDefinitionParam{
 				Name:        info.Name,
 				Description: openai.String(info.Description),
-				Parameters:  parameters,
+				Parameters:  openai.FunctionParameters(normalizeToolSchema(info, dialectOpenAI)),
 			},
 		}
-		
 	}
 
 	return copilotTools
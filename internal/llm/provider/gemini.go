@@ -37,7 +37,11 @@ func newGeminiClient(opts providerClientOptions) GeminiClient {
 		o(&geminiOpts)
 	}
 
-	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{APIKey: opts.apiKey, Backend: genai.BackendGeminiAPI})
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:     opts.apiKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: providerHTTPClient(true),
+	})
 	if err != nil {
 		logging.Error("Failed to create Gemini client", "error", err)
 		return nil
@@ -151,13 +155,15 @@ func (g *geminiClient) convertTools(tools []tools.BaseTool) []*genai.Tool {
 
 	for _, tool := range tools {
 		info := tool.Info()
+		schema := normalizeToolSchema(info, dialectGemini)
+		properties, _ := schema["properties"].(map[string]interface{})
 		declaration := &genai.FunctionDeclaration{
 			Name:        info.Name,
 			Description: info.Description,
 			Parameters: &genai.Schema{
 				Type:       genai.TypeObject,
-				Properties: convertSchemaProperties(info.Parameters),
-				Required:   info.Required,
+				Properties: convertSchemaProperties(properties),
+				Required:   requiredOf(schema),
 			},
 		}
 
@@ -193,7 +199,7 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 	config := &genai.GenerateContentConfig{
 		MaxOutputTokens: int32(g.providerOptions.maxTokens),
 		SystemInstruction: &genai.Content{
-			Parts: []*genai.Part{{Text: g.providerOptions.systemMessage}},
+			Parts: []*genai.Part{{Text: g.providerOptions.systemPrompt(ctx)}},
 		},
 	}
 	if len(tools) > 0 {
@@ -281,7 +287,7 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 	config := &genai.GenerateContentConfig{
 		MaxOutputTokens: int32(g.providerOptions.maxTokens),
 		SystemInstruction: &genai.Content{
-			Parts: []*genai.Part{{Text: g.providerOptions.systemMessage}},
+			Parts: []*genai.Part{{Text: g.providerOptions.systemPrompt(ctx)}},
 		},
 	}
 	if len(tools) > 0 {
@@ -516,6 +522,12 @@ func convertToSchema(param interface{}) *genai.Schema {
 	}
 
 	schema.Type = mapJSONTypeToGenAI(typeStr)
+	if enum, ok := paramMap["enum"].([]string); ok {
+		schema.Enum = enum
+	}
+	if nullable, ok := paramMap["nullable"].(bool); ok && nullable {
+		schema.Nullable = &nullable
+	}
 
 	switch typeStr {
 	case "array":
@@ -524,6 +536,7 @@ func convertToSchema(param interface{}) *genai.Schema {
 		if props, ok := paramMap["properties"].(map[string]interface{}); ok {
 			schema.Properties = convertSchemaProperties(props)
 		}
+		schema.Required = requiredOf(paramMap)
 	}
 
 	return schema
@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+// minCompressedBodySize is the smallest request body worth compressing
+const minCompressedBodySize = 1024
+
+var (
+	httpClientsMu  sync.Mutex
+	httpClients    = make(map[httpClientKey]*http.Client)
+	httpTransports = make(map[config.HTTPConfig]*http.Transport)
+)
+
+type httpClientKey struct {
+	config   config.HTTPConfig
+	compress bool
+}
+
+// providerHTTPClient returns the HTTP client of the providers. The clients
+// share their pool of kept alive connections so parallel requests, e.g. the
+// tasks of a batch, don't each pay for a new TLS handshake. compress is false
+// for the providers that sign the request body, it can't change once signed.
+func providerHTTPClient(compress bool) *http.Client {
+	cfg := config.HTTPConfig{}
+	if c := config.Get(); c != nil {
+		cfg = c.HTTP
+	}
+	key := httpClientKey{config: cfg, compress: compress && cfg.CompressRequests}
+
+	httpClientsMu.Lock()
+	defer httpClientsMu.Unlock()
+	if client, ok := httpClients[key]; ok {
+		return client
+	}
+	transport, ok := httpTransports[cfg]
+	if !ok {
+		transport = newHTTPTransport(cfg)
+		httpTransports[cfg] = transport
+	}
+	client := newHTTPClient(cfg, transport, key.compress)
+	httpClients[key] = client
+	return client
+}
+
+func newHTTPTransport(cfg config.HTTPConfig) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   seconds(cfg.ConnectTimeoutSeconds),
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   seconds(cfg.ConnectTimeoutSeconds),
+		ResponseHeaderTimeout: seconds(cfg.FirstByteTimeoutSeconds),
+		ExpectContinueTimeout: time.Second,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       seconds(cfg.IdleConnTimeoutSeconds),
+	}
+}
+
+func newHTTPClient(cfg config.HTTPConfig, transport *http.Transport, compress bool) *http.Client {
+	var roundTripper http.RoundTripper = transport
+	if compress {
+		roundTripper = &gzipRequestTransport{base: transport}
+	}
+	return &http.Client{
+		Transport: roundTripper,
+		Timeout:   seconds(cfg.TotalTimeoutSeconds),
+	}
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
+
+// gzipRequestTransport compresses the bodies of the requests. Responses are
+// decompressed by the transport already.
+type gzipRequestTransport struct {
+	base http.RoundTripper
+}
+
+func (t *gzipRequestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.Header.Get("Content-Encoding") != "" {
+		return t.base.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	if len(body) >= minCompressedBodySize {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		if _, err := writer.Write(body); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		body = compressed.Bytes()
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	req.ContentLength = int64(len(body))
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGzipRequestTransport(t *testing.T) {
+	type received struct {
+		encoding string
+		body     string
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			reader = gz
+		}
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		requests <- received{encoding: r.Header.Get("Content-Encoding"), body: string(body)}
+	}))
+	defer server.Close()
+
+	cfg := config.HTTPConfig{ConnectTimeoutSeconds: 5}
+	client := newHTTPClient(cfg, newHTTPTransport(cfg), true)
+	tests := []struct {
+		name     string
+		body     string
+		encoding string
+	}{
+		{name: "large body", body: strings.Repeat("message ", 500), encoding: "gzip"},
+		{name: "small body", body: `{"model":"small"}`, encoding: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Post(server.URL, "application/json", strings.NewReader(tt.body))
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			got := <-requests
+			assert.Equal(t, tt.encoding, got.encoding)
+			assert.Equal(t, tt.body, got.body)
+		})
+	}
+}
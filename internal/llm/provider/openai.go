@@ -43,7 +43,9 @@ func newOpenAIClient(opts providerClientOptions) OpenAIClient {
 		o(&openaiOpts)
 	}
 
-	openaiClientOptions := []option.RequestOption{}
+	openaiClientOptions := []option.RequestOption{
+		option.WithHTTPClient(providerHTTPClient(true)),
+	}
 	if opts.apiKey != "" {
 		openaiClientOptions = append(openaiClientOptions, option.WithAPIKey(opts.apiKey))
 	}
@@ -67,7 +69,7 @@ func newOpenAIClient(opts providerClientOptions) OpenAIClient {
 
 func (o *openaiClient) convertMessages(ctx context.Context, messages []message.Message) (openaiMessages []openai.ChatCompletionMessageParamUnion) {
 	// Add system message first
-	openaiMessages = append(openaiMessages, openai.SystemMessage(o.providerOptions.systemMessage))
+	openaiMessages = append(openaiMessages, openai.SystemMessage(o.providerOptions.systemPrompt(ctx)))
 
 	for _, msg := range messages {
 		switch msg.Role {
@@ -146,11 +148,7 @@ func (o *openaiClient) convertTools(tools []tools.BaseTool) []openai.ChatComplet
 			Function: openai.FunctionDefinitionParam{
 				Name:        info.Name,
 				Description: openai.String(info.Description),
-				Parameters: openai.FunctionParameters{
-					"type":       "object",
-					"properties": info.Parameters,
-					"required":   info.Required,
-				},
+				Parameters:  openai.FunctionParameters(normalizeToolSchema(info, dialectOpenAI)),
 			},
 		}
 	}
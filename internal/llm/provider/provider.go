@@ -189,7 +189,32 @@ func (p *baseProvider[C]) Model() models.Model {
 
 func (p *baseProvider[C]) StreamResponse(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
 	messages = p.cleanMessages(messages)
-	return p.client.stream(ctx, messages, tools)
+	return resumeStream(ctx, messages, func(request []message.Message) <-chan ProviderEvent {
+		return p.client.stream(ctx, request, tools)
+	})
+}
+
+type systemPromptContextKey struct{}
+
+// WithSystemPromptAdditions returns a context whose requests append additions
+// to the system prompt, e.g. the instructions of a session
+func WithSystemPromptAdditions(ctx context.Context, additions string) context.Context {
+	return context.WithValue(ctx, systemPromptContextKey{}, additions)
+}
+
+// SystemPromptAdditions returns the system prompt additions of a request
+func SystemPromptAdditions(ctx context.Context) string {
+	additions, _ := ctx.Value(systemPromptContextKey{}).(string)
+	return additions
+}
+
+// systemPrompt returns the system prompt of a request
+func (o providerClientOptions) systemPrompt(ctx context.Context) string {
+	additions := SystemPromptAdditions(ctx)
+	if additions == "" {
+		return o.systemMessage
+	}
+	return o.systemMessage + "\n\n<session-instructions>\nThe user added these instructions for this session. Follow them in addition to the instructions above.\n" + additions + "\n</session-instructions>"
 }
 
 func WithAPIKey(apiKey string) ProviderClientOption {
@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+// maxResumes is how often a dropped stream is resumed within one response
+const maxResumes = 3
+
+const resumePrompt = "Your previous response was cut off by a dropped connection. Continue exactly where it stopped, without repeating or summarizing what you already wrote."
+
+// resumeStream forwards the events of a streamed response. When the
+// connection drops mid-generation, the request is issued again with the
+// partial content and an instruction to continue, and the continuation is
+// stitched to the partial content. Responses that started a tool call are not
+// resumed, the input of the call can't be continued reliably.
+func resumeStream(ctx context.Context, messages []message.Message, stream func([]message.Message) <-chan ProviderEvent) <-chan ProviderEvent {
+	eventChan := make(chan ProviderEvent)
+	go func() {
+		defer close(eventChan)
+		var (
+			content   strings.Builder
+			prefix    string
+			toolCalls bool
+		)
+		request := messages
+		for resumes := 0; ; resumes++ {
+			var dropped error
+			for event := range stream(request) {
+				switch event.Type {
+				case EventContentDelta:
+					content.WriteString(event.Content)
+				case EventToolUseStart:
+					toolCalls = true
+				case EventError:
+					if resumes < maxResumes && !toolCalls && ctx.Err() == nil && isDroppedConnection(event.Error) {
+						dropped = event.Error
+						continue
+					}
+				case EventComplete:
+					if prefix != "" && event.Response != nil {
+						response := *event.Response
+						response.Content = prefix + response.Content
+						event.Response = &response
+					}
+				}
+				eventChan <- event
+			}
+			if dropped == nil {
+				return
+			}
+
+			logging.WarnPersist(fmt.Sprintf("Connection dropped, resuming the response... attempt %d of %d", resumes+1, maxResumes))
+			logging.Debug("Resuming dropped stream", "error", dropped, "partial", content.Len())
+			prefix = content.String()
+			request = continuation(messages, prefix)
+		}
+	}()
+	return eventChan
+}
+
+// continuation returns the request to resume a response from its partial
+// content. Without any content the request is simply issued again.
+func continuation(messages []message.Message, partial string) []message.Message {
+	if partial == "" {
+		return messages
+	}
+	request := make([]message.Message, 0, len(messages)+2)
+	request = append(request, messages...)
+	return append(request,
+		message.Message{
+			Role:  message.Assistant,
+			Parts: []message.ContentPart{message.TextContent{Text: partial}},
+		},
+		message.Message{
+			Role:  message.User,
+			Parts: []message.ContentPart{message.TextContent{Text: resumePrompt}},
+		},
+	)
+}
+
+// isDroppedConnection reports whether a stream failed because the connection
+// to the provider was lost rather than because the request was rejected
+func isDroppedConnection(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	// SDKs often flatten the transport error into their own
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{
+		"connection reset",
+		"broken pipe",
+		"unexpected eof",
+		"http2: stream closed",
+		"stream error",
+		"server sent goaway",
+		"use of closed network connection",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedStream replays one list of events per request
+type scriptedStream struct {
+	scripts  [][]ProviderEvent
+	requests [][]message.Message
+}
+
+func (s *scriptedStream) stream(request []message.Message) <-chan ProviderEvent {
+	events := s.scripts[len(s.requests)]
+	s.requests = append(s.requests, request)
+	ch := make(chan ProviderEvent, len(events))
+	for _, event := range events {
+		ch <- event
+	}
+	close(ch)
+	return ch
+}
+
+func collect(ch <-chan ProviderEvent) []ProviderEvent {
+	var events []ProviderEvent
+	for event := range ch {
+		events = append(events, event)
+	}
+	return events
+}
+
+func delta(text string) ProviderEvent {
+	return ProviderEvent{Type: EventContentDelta, Content: text}
+}
+
+func complete(content string) ProviderEvent {
+	return ProviderEvent{Type: EventComplete, Response: &ProviderResponse{Content: content, FinishReason: message.FinishReasonEndTurn}}
+}
+
+func userMessages(text string) []message.Message {
+	return []message.Message{{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: text}}}}
+}
+
+func TestResumeStreamStitchesContinuation(t *testing.T) {
+	s := &scriptedStream{scripts: [][]ProviderEvent{
+		{delta("Hello, "), {Type: EventError, Error: io.ErrUnexpectedEOF}},
+		{delta("world."), complete("world.")},
+	}}
+	events := collect(resumeStream(context.Background(), userMessages("greet"), s.stream))
+
+	require.Len(t, s.requests, 2)
+	resumed := s.requests[1]
+	require.Len(t, resumed, 3)
+	assert.Equal(t, message.Assistant, resumed[1].Role)
+	assert.Equal(t, "Hello, ", resumed[1].Content().String())
+	assert.Equal(t, resumePrompt, resumed[2].Content().String())
+
+	require.Len(t, events, 3)
+	for _, event := range events {
+		assert.NotEqual(t, EventError, event.Type)
+	}
+	assert.Equal(t, "Hello, world.", events[2].Response.Content)
+}
+
+func TestResumeStreamKeepsRequestErrors(t *testing.T) {
+	s := &scriptedStream{scripts: [][]ProviderEvent{
+		{delta("partial"), {Type: EventError, Error: errors.New("invalid request")}},
+	}}
+	events := collect(resumeStream(context.Background(), userMessages("hi"), s.stream))
+
+	assert.Len(t, s.requests, 1)
+	require.Len(t, events, 2)
+	assert.Equal(t, EventError, events[1].Type)
+}
+
+func TestResumeStreamSkipsToolCalls(t *testing.T) {
+	s := &scriptedStream{scripts: [][]ProviderEvent{
+		{{Type: EventToolUseStart, ToolCall: &message.ToolCall{ID: "1"}}, {Type: EventError, Error: io.ErrUnexpectedEOF}},
+	}}
+	events := collect(resumeStream(context.Background(), userMessages("hi"), s.stream))
+
+	assert.Len(t, s.requests, 1)
+	assert.Equal(t, EventError, events[len(events)-1].Type)
+}
+
+func TestResumeStreamGivesUp(t *testing.T) {
+	var scripts [][]ProviderEvent
+	for i := 0; i <= maxResumes; i++ {
+		scripts = append(scripts, []ProviderEvent{delta("x"), {Type: EventError, Error: io.ErrUnexpectedEOF}})
+	}
+	s := &scriptedStream{scripts: scripts}
+	events := collect(resumeStream(context.Background(), userMessages("hi"), s.stream))
+
+	assert.Len(t, s.requests, maxResumes+1)
+	assert.Equal(t, EventError, events[len(events)-1].Type)
+}
+
+func TestIsDroppedConnection(t *testing.T) {
+	assert.True(t, isDroppedConnection(io.ErrUnexpectedEOF))
+	assert.True(t, isDroppedConnection(fmt.Errorf("read: %w", io.ErrUnexpectedEOF)))
+	assert.True(t, isDroppedConnection(errors.New("read tcp: connection reset by peer")))
+	assert.False(t, isDroppedConnection(context.Canceled))
+	assert.False(t, isDroppedConnection(errors.New("400 Bad Request")))
+	assert.False(t, isDroppedConnection(nil))
+}
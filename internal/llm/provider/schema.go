@@ -0,0 +1,281 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+)
+
+// schemaDialect is the flavor of JSON schema a provider accepts for the
+// parameters of function calls
+type schemaDialect string
+
+const (
+	dialectAnthropic schemaDialect = "anthropic"
+	dialectOpenAI    schemaDialect = "openai"
+	dialectGemini    schemaDialect = "gemini"
+)
+
+var (
+	// toolNamePattern is the tool name format OpenAI and Anthropic accept
+	toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	// geminiToolNamePattern also allows dots but must start with a letter or
+	// an underscore
+	geminiToolNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.-]{0,63}$`)
+)
+
+var schemaTypes = []string{"string", "number", "integer", "boolean", "array", "object", "null"}
+
+// dialectFor returns the schema dialect of the client a provider uses
+func dialectFor(provider models.ModelProvider) schemaDialect {
+	switch provider {
+	case models.ProviderAnthropic, models.ProviderBedrock:
+		return dialectAnthropic
+	case models.ProviderGemini, models.ProviderVertexAI:
+		return dialectGemini
+	default:
+		return dialectOpenAI
+	}
+}
+
+// normalizeToolSchema returns the parameters of a tool in the schema dialect
+// of a provider
+func normalizeToolSchema(info tools.ToolInfo, dialect schemaDialect) map[string]any {
+	return normalizeSchema(info.Schema(), dialect)
+}
+
+// normalizeSchema returns a copy of a schema with the quirks providers reject
+// removed:
+//   - required is a list of existing properties and left out when empty, a
+//     null required fails OpenAI requests
+//   - objects have properties and arrays have items
+//   - enums aren't empty, Gemini only accepts enums of strings
+//   - Gemini doesn't accept a list of types, additionalProperties or keywords
+//     like $schema, which are dropped
+func normalizeSchema(schema map[string]any, dialect schemaDialect) map[string]any {
+	out := make(map[string]any, len(schema))
+	for key, value := range schema {
+		switch key {
+		case "$schema", "$id":
+			continue
+		case "additionalProperties":
+			if dialect == dialectGemini {
+				continue
+			}
+		}
+		out[key] = value
+	}
+
+	if types, ok := out["type"].([]any); ok {
+		out["type"] = normalizeTypeList(out, types, dialect)
+	}
+
+	if properties, ok := out["properties"].(map[string]any); ok {
+		normalized := make(map[string]any, len(properties))
+		for name, property := range properties {
+			if p, ok := property.(map[string]any); ok {
+				normalized[name] = normalizeSchema(p, dialect)
+			} else {
+				normalized[name] = property
+			}
+		}
+		out["properties"] = normalized
+	} else if out["type"] == "object" {
+		out["properties"] = map[string]any{}
+	}
+
+	if required := schemaRequired(out); len(required) > 0 {
+		out["required"] = required
+	} else {
+		delete(out, "required")
+	}
+
+	if items, ok := out["items"].(map[string]any); ok {
+		out["items"] = normalizeSchema(items, dialect)
+	} else if out["type"] == "array" {
+		if dialect == dialectGemini {
+			// Gemini has no schema for any value
+			out["items"] = map[string]any{"type": "string"}
+		} else {
+			out["items"] = map[string]any{}
+		}
+	}
+
+	if enum, ok := out["enum"]; ok {
+		normalizeEnum(out, enumValues(enum), dialect)
+	}
+	return out
+}
+
+// schemaRequired returns the required properties of an object schema that
+// exist, without duplicates
+func schemaRequired(schema map[string]any) []string {
+	var names []string
+	switch required := schema["required"].(type) {
+	case []string:
+		names = required
+	case []any:
+		for _, name := range required {
+			if s, ok := name.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+	properties, _ := schema["properties"].(map[string]any)
+	var result []string
+	for _, name := range names {
+		if _, ok := properties[name]; ok && !slices.Contains(result, name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// normalizeTypeList turns a list of types like ["string", "null"] into a
+// single type for Gemini, which marks the schema nullable instead
+func normalizeTypeList(schema map[string]any, types []any, dialect schemaDialect) any {
+	if dialect != dialectGemini {
+		return types
+	}
+	var result any = "string"
+	for _, t := range types {
+		if t == "null" {
+			schema["nullable"] = true
+		} else if s, ok := t.(string); ok && result == "string" {
+			result = s
+		}
+	}
+	return result
+}
+
+func enumValues(enum any) []any {
+	switch values := enum.(type) {
+	case []string:
+		result := make([]any, len(values))
+		for i, v := range values {
+			result[i] = v
+		}
+		return result
+	case []any:
+		return values
+	}
+	return nil
+}
+
+// normalizeEnum drops empty enums. Gemini only accepts enums of strings, the
+// values of other enums are listed in the description instead.
+func normalizeEnum(schema map[string]any, values []any, dialect schemaDialect) {
+	if len(values) == 0 {
+		delete(schema, "enum")
+		return
+	}
+	if dialect != dialectGemini {
+		return
+	}
+	strs := make([]string, len(values))
+	allStrings := true
+	for i, v := range values {
+		_, ok := v.(string)
+		allStrings = allStrings && ok
+		strs[i] = fmt.Sprint(v)
+	}
+	if allStrings && (schema["type"] == nil || schema["type"] == "string") {
+		schema["enum"] = strs
+		return
+	}
+	delete(schema, "enum")
+	description, _ := schema["description"].(string)
+	schema["description"] = strings.TrimSpace(fmt.Sprintf("%s One of: %s.", description, strings.Join(strs, ", ")))
+}
+
+// validateSchema returns the problems of a normalized tool schema that would
+// make a provider reject requests with the tool
+func validateSchema(name string, schema map[string]any, dialect schemaDialect) []string {
+	var problems []string
+	pattern := toolNamePattern
+	if dialect == dialectGemini {
+		pattern = geminiToolNamePattern
+	}
+	if !pattern.MatchString(name) {
+		problems = append(problems, fmt.Sprintf("the name must match %s", pattern))
+	}
+	if schema["type"] != "object" {
+		problems = append(problems, fmt.Sprintf("the parameters must be an object schema, not %v", schema["type"]))
+	}
+	return append(problems, validateSubschema("parameters", schema, dialect)...)
+}
+
+func validateSubschema(path string, schema map[string]any, dialect schemaDialect) []string {
+	var problems []string
+	if dialect == dialectGemini {
+		for _, key := range []string{"$ref", "anyOf", "oneOf", "allOf"} {
+			if _, ok := schema[key]; ok {
+				problems = append(problems, fmt.Sprintf("%s uses %s, which Gemini doesn't support", path, key))
+			}
+		}
+	}
+	switch t := schema["type"].(type) {
+	case nil:
+	case string:
+		if !slices.Contains(schemaTypes, t) {
+			problems = append(problems, fmt.Sprintf("%s has the unknown type %q", path, t))
+		}
+	case []any:
+		for _, item := range t {
+			if s, ok := item.(string); !ok || !slices.Contains(schemaTypes, s) {
+				problems = append(problems, fmt.Sprintf("%s has the unknown type %v", path, item))
+			}
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("%s has a type of %T", path, t))
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := properties[name].(map[string]any)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s.%s is not a schema", path, name))
+			continue
+		}
+		problems = append(problems, validateSubschema(path+"."+name, property, dialect)...)
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		problems = append(problems, validateSubschema(path+"[]", items, dialect)...)
+	}
+	return problems
+}
+
+// ValidateTools normalizes the tool schemas for a provider and checks that the
+// provider accepts them. It returns the tools that pass and an error for each
+// tool that doesn't, a single invalid tool would otherwise fail every request.
+func ValidateTools(provider models.ModelProvider, agentTools []tools.BaseTool) ([]tools.BaseTool, []error) {
+	dialect := dialectFor(provider)
+	valid := make([]tools.BaseTool, 0, len(agentTools))
+	var errs []error
+	for _, tool := range agentTools {
+		info := tool.Info()
+		problems := validateSchema(info.Name, normalizeToolSchema(info, dialect), dialect)
+		if len(problems) > 0 {
+			errs = append(errs, fmt.Errorf("tool %s is not supported by %s: %s", info.Name, provider, strings.Join(problems, "; ")))
+			continue
+		}
+		valid = append(valid, tool)
+	}
+	return valid, errs
+}
+
+// requiredOf returns the required properties of a normalized schema
+func requiredOf(schema map[string]any) []string {
+	required, _ := schema["required"].([]string)
+	return required
+}
@@ -0,0 +1,125 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type schemaTool struct {
+	info tools.ToolInfo
+}
+
+func (t schemaTool) Info() tools.ToolInfo { return t.info }
+
+func (t schemaTool) Run(context.Context, tools.ToolCall) (tools.ToolResponse, error) {
+	return tools.NewTextResponse(""), nil
+}
+
+func TestNormalizeToolSchema(t *testing.T) {
+	t.Run("properties only", func(t *testing.T) {
+		schema := normalizeToolSchema(tools.ToolInfo{
+			Parameters: map[string]any{
+				"path": map[string]any{"type": "string"},
+			},
+			Required: []string{"path", "missing", "path"},
+		}, dialectOpenAI)
+		assert.Equal(t, "object", schema["type"])
+		assert.Equal(t, []string{"path"}, schema["required"])
+	})
+
+	t.Run("nil required is left out", func(t *testing.T) {
+		schema := normalizeToolSchema(tools.ToolInfo{Parameters: map[string]any{}}, dialectOpenAI)
+		assert.NotContains(t, schema, "required")
+		assert.Equal(t, map[string]any{}, schema["properties"])
+	})
+
+	t.Run("full schema", func(t *testing.T) {
+		info := tools.NewTodoWriteTool().Info()
+		schema := normalizeToolSchema(info, dialectAnthropic)
+		properties := schema["properties"].(map[string]any)
+		assert.Contains(t, properties, "todos")
+		assert.NotContains(t, properties, "type")
+		assert.Equal(t, false, schema["additionalProperties"])
+	})
+
+	t.Run("gemini", func(t *testing.T) {
+		schema := normalizeToolSchema(tools.ToolInfo{
+			Parameters: map[string]any{
+				"$schema":              "http://json-schema.org/draft-07/schema#",
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties": map[string]any{
+					"level": map[string]any{"type": "integer", "enum": []any{1.0, 2.0}, "description": "Log level."},
+					"name":  map[string]any{"type": []any{"string", "null"}},
+					"tags":  map[string]any{"type": "array"},
+					"mode":  map[string]any{"type": "string", "enum": []string{}},
+				},
+			},
+		}, dialectGemini)
+		assert.NotContains(t, schema, "$schema")
+		assert.NotContains(t, schema, "additionalProperties")
+
+		properties := schema["properties"].(map[string]any)
+		level := properties["level"].(map[string]any)
+		assert.NotContains(t, level, "enum")
+		assert.Equal(t, "Log level. One of: 1, 2.", level["description"])
+
+		name := properties["name"].(map[string]any)
+		assert.Equal(t, "string", name["type"])
+		assert.Equal(t, true, name["nullable"])
+
+		assert.Equal(t, map[string]any{"type": "string"}, properties["tags"].(map[string]any)["items"])
+		assert.NotContains(t, properties["mode"], "enum")
+	})
+
+	t.Run("does not change the tool", func(t *testing.T) {
+		parameters := map[string]any{"$schema": "x", "type": "object"}
+		normalizeToolSchema(tools.ToolInfo{Parameters: parameters}, dialectGemini)
+		assert.Contains(t, parameters, "$schema")
+	})
+}
+
+func TestValidateTools(t *testing.T) {
+	valid := schemaTool{tools.ToolInfo{
+		Name:       "view",
+		Parameters: map[string]any{"file_path": map[string]any{"type": "string"}},
+		Required:   []string{"file_path"},
+	}}
+	badName := schemaTool{tools.ToolInfo{Name: "server tool", Parameters: map[string]any{}}}
+	ref := schemaTool{tools.ToolInfo{
+		Name:       "fs.read",
+		Parameters: map[string]any{"options": map[string]any{"$ref": "#/definitions/options"}},
+	}}
+	badType := schemaTool{tools.ToolInfo{
+		Name:       "count",
+		Parameters: map[string]any{"n": map[string]any{"type": "int"}},
+	}}
+
+	result, errs := ValidateTools(models.ProviderOpenAI, []tools.BaseTool{valid, badName, ref, badType})
+	assert.Equal(t, []tools.BaseTool{valid}, result)
+	require.Len(t, errs, 3)
+	assert.Contains(t, errs[0].Error(), "tool server tool is not supported by openai: the name must match")
+	assert.Contains(t, errs[1].Error(), "the name must match")
+	assert.Contains(t, errs[2].Error(), `parameters.n has the unknown type "int"`)
+
+	result, errs = ValidateTools(models.ProviderGemini, []tools.BaseTool{valid, ref})
+	assert.Equal(t, []tools.BaseTool{valid}, result)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "parameters.options uses $ref, which Gemini doesn't support")
+}
+
+func TestBuiltinToolSchemas(t *testing.T) {
+	builtin := []tools.BaseTool{
+		tools.NewTodoReadTool(),
+		tools.NewTodoWriteTool(),
+	}
+	for _, provider := range []models.ModelProvider{models.ProviderAnthropic, models.ProviderOpenAI, models.ProviderGemini} {
+		_, errs := ValidateTools(provider, builtin)
+		assert.Empty(t, errs, provider)
+	}
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/llm/models"
+)
+
+// warmupTimeout bounds a warmup ping
+const warmupTimeout = 10 * time.Second
+
+// Warmer is implemented by the providers that can connect to their endpoint
+// ahead of a request
+type Warmer interface {
+	Warmup(ctx context.Context) error
+}
+
+// Warmup opens a connection to the endpoint of p so the next request doesn't
+// wait for the DNS lookup and the TLS handshake. The ping costs no tokens.
+// Providers that can't be warmed up are left alone.
+func Warmup(ctx context.Context, p Provider) error {
+	if w, ok := p.(Warmer); ok {
+		return w.Warmup(ctx)
+	}
+	return nil
+}
+
+// warmupURL returns the endpoint the requests of a provider are sent to, empty
+// when they don't go through the shared HTTP client
+func (p *baseProvider[C]) warmupURL() string {
+	options := p.options
+	switch options.model.Provider {
+	case models.ProviderAnthropic:
+		anthropicOpts := anthropicOptions{}
+		for _, o := range options.anthropicOptions {
+			o(&anthropicOpts)
+		}
+		if anthropicOpts.useBedrock {
+			return ""
+		}
+		if url := os.Getenv("ANTHROPIC_BASE_URL"); url != "" {
+			return url
+		}
+		return "https://api.anthropic.com"
+	case models.ProviderGemini:
+		return "https://generativelanguage.googleapis.com"
+	case models.ProviderAzure:
+		return os.Getenv("AZURE_OPENAI_ENDPOINT")
+	case models.ProviderCopilot:
+		copilotOpts := copilotOptions{baseURL: "https://api.githubcopilot.com"}
+		for _, o := range options.copilotOptions {
+			o(&copilotOpts)
+		}
+		return copilotOpts.baseURL
+	case models.ProviderOpenAI, models.ProviderGROQ, models.ProviderOpenRouter, models.ProviderXAI, models.ProviderLocal:
+		openaiOpts := openaiOptions{baseURL: "https://api.openai.com/v1"}
+		for _, o := range options.openaiOptions {
+			o(&openaiOpts)
+		}
+		return openaiOpts.baseURL
+	}
+	return ""
+}
+
+func (p *baseProvider[C]) Warmup(ctx context.Context) error {
+	url := p.warmupURL()
+	if url == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, warmupTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %s: %w", url, err)
+	}
+	// Any response means the connection is open and back in the pool
+	resp, err := providerHTTPClient(false).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", url, err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
@@ -0,0 +1,187 @@
+// Package tokenizer estimates prompt sizes per model family before a request
+// is sent to a provider.
+package tokenizer
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// Family groups models that share a tokenizer
+type Family string
+
+const (
+	FamilyOpenAI  Family = "openai"
+	FamilyClaude  Family = "claude"
+	FamilyGemini  Family = "gemini"
+	FamilyGeneric Family = "generic"
+)
+
+const (
+	encodingO200k  = "o200k_base"
+	encodingCL100k = "cl100k_base"
+
+	// Claude and Gemini tokenizers are not distributed for local use. Their
+	// vocabularies produce slightly more tokens than cl100k for source code, so
+	// counts are scaled to stay on the safe side of the context window.
+	claudeScale  = 1.15
+	geminiScale  = 1.05
+	genericScale = 1.10
+
+	// Per message overhead for role markers and separators
+	messageOverhead = 4
+)
+
+// Tokenizer counts the tokens of a text
+type Tokenizer interface {
+	Count(text string) int64
+	Family() Family
+}
+
+type bpeTokenizer struct {
+	family Family
+	enc    *tiktoken.Tiktoken
+	scale  float64
+}
+
+func (t *bpeTokenizer) Count(text string) int64 {
+	if text == "" {
+		return 0
+	}
+	count := float64(len(t.enc.EncodeOrdinary(text)))
+	return int64(count*t.scale + 0.5)
+}
+
+func (t *bpeTokenizer) Family() Family {
+	return t.family
+}
+
+// heuristicTokenizer is used when no encoding can be loaded
+type heuristicTokenizer struct {
+	family Family
+}
+
+func (t *heuristicTokenizer) Count(text string) int64 {
+	return int64((len(text) + 3) / 4)
+}
+
+func (t *heuristicTokenizer) Family() Family {
+	return t.family
+}
+
+var (
+	loaderOnce sync.Once
+	encodings  sync.Map // encoding name -> *tiktoken.Tiktoken
+)
+
+func getEncoding(name string) (*tiktoken.Tiktoken, error) {
+	loaderOnce.Do(func() {
+		// Use the embedded BPE ranks so counting works offline
+		tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+	})
+	if enc, ok := encodings.Load(name); ok {
+		return enc.(*tiktoken.Tiktoken), nil
+	}
+	enc, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		return nil, err
+	}
+	encodings.Store(name, enc)
+	return enc, nil
+}
+
+// FamilyOf returns the tokenizer family of a model
+func FamilyOf(model models.Model) Family {
+	apiModel := strings.ToLower(model.APIModel)
+	switch {
+	case strings.Contains(apiModel, "claude"):
+		return FamilyClaude
+	case strings.Contains(apiModel, "gemini"):
+		return FamilyGemini
+	case strings.HasPrefix(apiModel, "gpt"), strings.HasPrefix(apiModel, "o1"),
+		strings.HasPrefix(apiModel, "o3"), strings.HasPrefix(apiModel, "o4"),
+		strings.HasPrefix(apiModel, "openai/"):
+		return FamilyOpenAI
+	}
+	switch model.Provider {
+	case models.ProviderAnthropic, models.ProviderBedrock:
+		return FamilyClaude
+	case models.ProviderGemini, models.ProviderVertexAI:
+		return FamilyGemini
+	case models.ProviderOpenAI, models.ProviderAzure:
+		return FamilyOpenAI
+	}
+	return FamilyGeneric
+}
+
+// ForModel returns the tokenizer matching the model family
+func ForModel(model models.Model) Tokenizer {
+	family := FamilyOf(model)
+
+	encoding, scale := encodingCL100k, genericScale
+	switch family {
+	case FamilyOpenAI:
+		encoding, scale = openAIEncoding(model.APIModel), 1
+	case FamilyClaude:
+		scale = claudeScale
+	case FamilyGemini:
+		scale = geminiScale
+	}
+
+	enc, err := getEncoding(encoding)
+	if err != nil {
+		logging.Warn("Failed to load tokenizer, falling back to estimation", "encoding", encoding, "error", err)
+		return &heuristicTokenizer{family: family}
+	}
+	return &bpeTokenizer{family: family, enc: enc, scale: scale}
+}
+
+// openAIEncoding returns the encoding used by an OpenAI model
+func openAIEncoding(apiModel string) string {
+	apiModel = strings.TrimPrefix(strings.ToLower(apiModel), "openai/")
+	if strings.HasPrefix(apiModel, "gpt-4o") || strings.HasPrefix(apiModel, "gpt-4.1") ||
+		strings.HasPrefix(apiModel, "gpt-4.5") || strings.HasPrefix(apiModel, "gpt-5") ||
+		strings.HasPrefix(apiModel, "o1") || strings.HasPrefix(apiModel, "o3") || strings.HasPrefix(apiModel, "o4") {
+		return encodingO200k
+	}
+	return encodingCL100k
+}
+
+// CountMessages estimates the tokens used by a message history
+func CountMessages(t Tokenizer, msgs []message.Message) int64 {
+	var total int64
+	for _, msg := range msgs {
+		total += messageOverhead
+		total += t.Count(msg.Content().String())
+		total += t.Count(msg.ReasoningContent().String())
+		for _, call := range msg.ToolCalls() {
+			total += t.Count(call.Name) + t.Count(call.Input)
+		}
+		for _, result := range msg.ToolResults() {
+			total += t.Count(result.Content)
+		}
+	}
+	return total
+}
+
+// CountTools estimates the tokens used by the tool definitions
+func CountTools(t Tokenizer, agentTools []tools.BaseTool) int64 {
+	var total int64
+	for _, tool := range agentTools {
+		info := tool.Info()
+		params, err := json.Marshal(info.Parameters)
+		if err != nil {
+			continue
+		}
+		total += t.Count(info.Name) + t.Count(info.Description) + t.Count(string(params))
+	}
+	return total
+}
@@ -0,0 +1,52 @@
+package tokenizer
+
+import (
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFamilyOf(t *testing.T) {
+	tests := []struct {
+		model models.Model
+		want  Family
+	}{
+		{models.Model{APIModel: "claude-3-7-sonnet-latest", Provider: models.ProviderAnthropic}, FamilyClaude},
+		{models.Model{APIModel: "anthropic/claude-3.5-haiku", Provider: models.ProviderOpenRouter}, FamilyClaude},
+		{models.Model{APIModel: "gemini-2.5-pro", Provider: models.ProviderGemini}, FamilyGemini},
+		{models.Model{APIModel: "gpt-4o", Provider: models.ProviderOpenAI}, FamilyOpenAI},
+		{models.Model{APIModel: "o3-mini", Provider: models.ProviderAzure}, FamilyOpenAI},
+		{models.Model{APIModel: "llama-3.3-70b-versatile", Provider: models.ProviderGROQ}, FamilyGeneric},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model.APIModel, func(t *testing.T) {
+			assert.Equal(t, tt.want, FamilyOf(tt.model))
+		})
+	}
+}
+
+func TestOpenAIEncoding(t *testing.T) {
+	assert.Equal(t, encodingO200k, openAIEncoding("gpt-4.1-mini"))
+	assert.Equal(t, encodingO200k, openAIEncoding("openai/o4-mini"))
+	assert.Equal(t, encodingCL100k, openAIEncoding("gpt-4-turbo"))
+	assert.Equal(t, encodingCL100k, openAIEncoding("gpt-3.5-turbo"))
+}
+
+func TestCountMessages(t *testing.T) {
+	tk := ForModel(models.Model{APIModel: "gpt-4o", Provider: models.ProviderOpenAI})
+	assert.Equal(t, FamilyOpenAI, tk.Family())
+	assert.Equal(t, int64(2), tk.Count("hello world"))
+
+	msgs := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "hello world"}}},
+		{Role: message.Tool, Parts: []message.ContentPart{message.ToolResult{Content: "hello world"}}},
+	}
+	assert.Equal(t, int64(2*messageOverhead+4), CountMessages(tk, msgs))
+
+	// Claude counts are scaled up from cl100k
+	claude := ForModel(models.Model{APIModel: "claude-3-7-sonnet-latest", Provider: models.ProviderAnthropic})
+	text := "func main() { fmt.Println(\"hello\") }"
+	assert.GreaterOrEqual(t, claude.Count(text), ForModel(models.Model{APIModel: "gpt-4"}).Count(text))
+}
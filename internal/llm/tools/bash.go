@@ -284,14 +284,17 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		}
 	}
 	startTime := time.Now()
-	shell := shell.GetPersistentShell(config.WorkingDirectory())
-	stdout, stderr, exitCode, interrupted, err := shell.Exec(ctx, params.Command, params.Timeout)
+	env := shell.SessionEnv(sessionID)
+	persistentShell := shell.GetPersistentShell(config.WorkingDirectory())
+	stdout, stderr, exitCode, interrupted, err := persistentShell.Exec(ctx, params.Command, env, params.Timeout)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error executing command: %w", err)
 	}
 
-	stdout = truncateOutput(stdout)
-	stderr = truncateOutput(stderr)
+	// Values of the session variables, like tokens, don't reach the model
+	// and the logs
+	stdout = truncateOutput(shell.MaskEnv(stdout, env))
+	stderr = truncateOutput(shell.MaskEnv(stderr, env))
 
 	errorMessage := stderr
 	if interrupted {
@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	BlackboardReadToolName  = "blackboard_read"
+	BlackboardWriteToolName = "blackboard_write"
+
+	// Limits that keep a runaway task from flooding its siblings
+	maxBlackboardNotes      = 50
+	maxBlackboardNoteLength = 10000
+)
+
+type blackboardContextKey string
+
+// BlackboardIDContextKey identifies the blackboard shared by the tasks
+// launched from the same assistant message
+const BlackboardIDContextKey blackboardContextKey = "blackboard_id"
+
+// BlackboardNote is a finding a task shared with its siblings
+type BlackboardNote struct {
+	Task    string `json:"task"` // Session ID of the posting task
+	Topic   string `json:"topic"`
+	Content string `json:"content"`
+	Time    int64  `json:"time"`
+}
+
+type blackboardStore struct {
+	mu     sync.RWMutex
+	boards map[string][]BlackboardNote // blackboard ID -> notes
+}
+
+var blackboards = &blackboardStore{
+	boards: make(map[string][]BlackboardNote),
+}
+
+// WithBlackboard returns a context whose tasks share the blackboard id
+func WithBlackboard(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, BlackboardIDContextKey, id)
+}
+
+// ClearBlackboard drops the notes of a blackboard once its tasks are done
+func ClearBlackboard(id string) {
+	blackboards.mu.Lock()
+	defer blackboards.mu.Unlock()
+	delete(blackboards.boards, id)
+}
+
+// BlackboardNotes returns the notes of a blackboard, optionally only the ones
+// with the given topic
+func BlackboardNotes(id, topic string) []BlackboardNote {
+	blackboards.mu.RLock()
+	defer blackboards.mu.RUnlock()
+	var notes []BlackboardNote
+	for _, note := range blackboards.boards[id] {
+		if topic == "" || strings.EqualFold(note.Topic, topic) {
+			notes = append(notes, note)
+		}
+	}
+	return notes
+}
+
+func blackboardID(ctx context.Context) string {
+	id, _ := ctx.Value(BlackboardIDContextKey).(string)
+	return id
+}
+
+type blackboardReadTool struct{}
+
+type BlackboardReadParams struct {
+	Topic string `json:"topic"`
+}
+
+func NewBlackboardReadTool() BaseTool {
+	return &blackboardReadTool{}
+}
+
+func (b *blackboardReadTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        BlackboardReadToolName,
+		Description: "Read the findings that sibling tasks of the same batch posted to the shared blackboard. Check it before starting discovery work, another task may already have mapped the code you need. Returns the notes as JSON with the posting task, topic and content.",
+		Parameters: map[string]any{
+			"topic": map[string]any{
+				"type":        "string",
+				"description": "Only return notes with this topic (optional)",
+			},
+		},
+		Required: []string{},
+	}
+}
+
+func (b *blackboardReadTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params BlackboardReadParams
+	if call.Input != "" {
+		if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+		}
+	}
+	id := blackboardID(ctx)
+	if id == "" {
+		return NewTextErrorResponse("no blackboard is shared with this task"), nil
+	}
+
+	notes := BlackboardNotes(id, params.Topic)
+	if len(notes) == 0 {
+		return NewTextResponse("The blackboard is empty."), nil
+	}
+	result, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return NewTextErrorResponse("failed to serialize notes"), nil
+	}
+	return NewTextResponse(string(result)), nil
+}
+
+type blackboardWriteTool struct{}
+
+type BlackboardWriteParams struct {
+	Topic   string `json:"topic"`
+	Content string `json:"content"`
+}
+
+func NewBlackboardWriteTool() BaseTool {
+	return &blackboardWriteTool{}
+}
+
+func (b *blackboardWriteTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        BlackboardWriteToolName,
+		Description: "Post an intermediate finding to the blackboard shared with sibling tasks of the same batch, e.g. a map of the relevant packages or the location of a definition. Post findings that other tasks can build on as soon as you have them, keep each note short and factual.",
+		Parameters: map[string]any{
+			"topic": map[string]any{
+				"type":        "string",
+				"description": "Short topic of the note, e.g. \"codebase map\"",
+			},
+			"content": map[string]any{
+				"type":        "string",
+				"description": "The finding to share",
+			},
+		},
+		Required: []string{"topic", "content"},
+	}
+}
+
+func (b *blackboardWriteTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params BlackboardWriteParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.Topic == "" || params.Content == "" {
+		return NewTextErrorResponse("topic and content are required"), nil
+	}
+	if len(params.Content) > maxBlackboardNoteLength {
+		return NewTextErrorResponse(fmt.Sprintf("content is too long, keep notes under %d characters", maxBlackboardNoteLength)), nil
+	}
+	id := blackboardID(ctx)
+	if id == "" {
+		return NewTextErrorResponse("no blackboard is shared with this task"), nil
+	}
+	sessionID, _ := GetContextValues(ctx)
+
+	blackboards.mu.Lock()
+	defer blackboards.mu.Unlock()
+	if len(blackboards.boards[id]) >= maxBlackboardNotes {
+		return NewTextErrorResponse("the blackboard is full"), nil
+	}
+	blackboards.boards[id] = append(blackboards.boards[id], BlackboardNote{
+		Task:    sessionID,
+		Topic:   params.Topic,
+		Content: params.Content,
+		Time:    time.Now().Unix(),
+	})
+	return NewTextResponse("Posted to the blackboard."), nil
+}
@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlackboard(t *testing.T) {
+	read := NewBlackboardReadTool()
+	write := NewBlackboardWriteTool()
+
+	// Tasks without a shared blackboard cannot use it
+	resp, err := write.Run(context.Background(), ToolCall{Input: `{"topic":"map","content":"x"}`})
+	require.NoError(t, err)
+	assert.True(t, resp.IsError)
+
+	mapper := context.WithValue(WithBlackboard(context.Background(), "msg-1"), SessionIDContextKey, "task-1")
+	resp, err = write.Run(mapper, ToolCall{Input: `{"topic":"codebase map","content":"internal/app wires the services"}`})
+	require.NoError(t, err)
+	assert.False(t, resp.IsError)
+
+	sibling := context.WithValue(WithBlackboard(context.Background(), "msg-1"), SessionIDContextKey, "task-2")
+	resp, err = read.Run(sibling, ToolCall{Input: `{"topic":"Codebase Map"}`})
+	require.NoError(t, err)
+	var notes []BlackboardNote
+	require.NoError(t, json.Unmarshal([]byte(resp.Content), &notes))
+	require.Len(t, notes, 1)
+	assert.Equal(t, "task-1", notes[0].Task)
+	assert.Equal(t, "internal/app wires the services", notes[0].Content)
+
+	// Other batches do not see the notes
+	other := WithBlackboard(context.Background(), "msg-2")
+	resp, err = read.Run(other, ToolCall{})
+	require.NoError(t, err)
+	assert.Equal(t, "The blackboard is empty.", resp.Content)
+
+	ClearBlackboard("msg-1")
+	assert.Empty(t, BlackboardNotes("msg-1", ""))
+}
@@ -0,0 +1,193 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/logging"
+)
+
+// TimeoutError is returned for tool runs that took longer than the time
+// budget of their tool
+type TimeoutError struct {
+	Tool           string `json:"tool"`
+	Kind           string `json:"kind"` // "timeout"
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("The %s tool exceeded its time budget of %s and was aborted. Do something faster instead, e.g. narrow the search down or run long commands in the background, or ask the user to raise toolBudgets.%s.timeoutSeconds.",
+		e.Tool, time.Duration(e.TimeoutSeconds)*time.Second, e.Tool)
+}
+
+// Response returns the error as a tool response for the model
+func (e *TimeoutError) Response() ToolResponse {
+	return WithResponseMetadata(NewTextErrorResponse(e.Error()), e)
+}
+
+// PausedError is returned for calls of a tool that failed too often in a
+// session recently
+type PausedError struct {
+	Tool             string `json:"tool"`
+	Kind             string `json:"kind"` // "paused"
+	Failed           int    `json:"failed"`
+	Runs             int    `json:"runs"`
+	RemainingSeconds int    `json:"remaining_seconds"`
+}
+
+func (e *PausedError) Error() string {
+	return fmt.Sprintf("The %s tool is paused for another %s because %d of its last %d runs in this session failed. Don't retry the same thing: read the errors, check your assumptions and change your approach, e.g. use another tool or ask the user.",
+		e.Tool, time.Duration(e.RemainingSeconds)*time.Second, e.Failed, e.Runs)
+}
+
+// Response returns the error as a tool response for the model
+func (e *PausedError) Response() ToolResponse {
+	return WithResponseMetadata(NewTextErrorResponse(e.Error()), e)
+}
+
+// stopGracePeriod is how long a tool that ran out of its time budget is given
+// to stop after its context is canceled
+const stopGracePeriod = 5 * time.Second
+
+// RunWithBudget runs a tool, aborting the run with a *TimeoutError response
+// when it takes longer than the time budget of the tool. The context of the
+// tool is canceled on timeout; a tool that doesn't stop within a grace period
+// is left to finish in the background.
+func RunWithBudget(ctx context.Context, tool BaseTool, call ToolCall) (ToolResponse, error) {
+	budget := config.Get().ToolBudgets.For(call.Name)
+	if budget.TimeoutSeconds <= 0 {
+		return tool.Run(ctx, call)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type run struct {
+		response ToolResponse
+		err      error
+	}
+	done := make(chan run, 1)
+	go func() {
+		defer logging.RecoverPanic("tool-"+call.Name, func() {
+			done <- run{err: fmt.Errorf("the %s tool crashed", call.Name)}
+		})
+		response, err := tool.Run(runCtx, call)
+		done <- run{response, err}
+	}()
+
+	timer := time.NewTimer(time.Duration(budget.TimeoutSeconds) * time.Second)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.response, r.err
+	case <-ctx.Done():
+		// Canceled by the user, the tool reports it
+		r := <-done
+		return r.response, r.err
+	case <-timer.C:
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(stopGracePeriod):
+		logging.Warn("Tool still running after its time budget", "tool", call.Name)
+	}
+	timeout := &TimeoutError{Tool: call.Name, Kind: "timeout", TimeoutSeconds: budget.TimeoutSeconds}
+	return timeout.Response(), nil
+}
+
+// toolHealth is the outcome of the latest runs of a tool in a session
+type toolHealth struct {
+	failures    []bool // The latest last, true for failed runs
+	failed      int    // Failed runs when the tool was paused
+	runs        int    // Runs counted when the tool was paused
+	pausedUntil time.Time
+	probing     bool // The pause ended, the next run decides whether it's over
+}
+
+// BudgetTracker pauses the tools that failed too often in a session, as set
+// by their failure budget
+type BudgetTracker struct {
+	mu     sync.Mutex
+	health map[string]*toolHealth // Session ID and tool name -> health
+}
+
+// NewBudgetTracker creates a tracker without any runs
+func NewBudgetTracker() *BudgetTracker {
+	return &BudgetTracker{health: make(map[string]*toolHealth)}
+}
+
+func (b *BudgetTracker) get(sessionID, toolName string) *toolHealth {
+	key := sessionID + "/" + toolName
+	h, ok := b.health[key]
+	if !ok {
+		h = &toolHealth{}
+		b.health[key] = h
+	}
+	return h
+}
+
+// Check returns a *PausedError when the tool is paused in the session. Once
+// the pause is over one run is allowed, and the tool is paused again if it
+// fails.
+func (b *BudgetTracker) Check(sessionID, toolName string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := b.get(sessionID, toolName)
+	if h.pausedUntil.IsZero() {
+		return nil
+	}
+	if remaining := time.Until(h.pausedUntil); remaining > 0 {
+		return &PausedError{Tool: toolName, Kind: "paused", Failed: h.failed, Runs: h.runs, RemainingSeconds: int(remaining.Round(time.Second).Seconds())}
+	}
+	h.pausedUntil = time.Time{}
+	h.probing = true
+	return nil
+}
+
+// Record counts a run of the tool in the session. It returns a *PausedError
+// when this run used up the failure budget and paused the tool.
+func (b *BudgetTracker) Record(sessionID, toolName string, failed bool) error {
+	budget := config.Get().ToolBudgets.For(toolName)
+	if budget.MaxFailures <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := b.get(sessionID, toolName)
+
+	if h.probing {
+		h.probing = false
+		if !failed {
+			h.failures = nil
+			return nil
+		}
+		return h.pause(toolName, budget, h.failed, h.runs)
+	}
+
+	h.failures = append(h.failures, failed)
+	if window := max(budget.FailureWindow, budget.MaxFailures); len(h.failures) > window {
+		h.failures = h.failures[len(h.failures)-window:]
+	}
+	count := 0
+	for _, f := range h.failures {
+		if f {
+			count++
+		}
+	}
+	if count < budget.MaxFailures {
+		return nil
+	}
+	return h.pause(toolName, budget, count, len(h.failures))
+}
+
+func (h *toolHealth) pause(toolName string, budget config.ToolBudget, failed, runs int) error {
+	h.failures = nil
+	h.failed = failed
+	h.runs = runs
+	h.pausedUntil = time.Now().Add(time.Duration(budget.CooldownSeconds) * time.Second)
+	return &PausedError{Tool: toolName, Kind: "paused", Failed: failed, Runs: runs, RemainingSeconds: budget.CooldownSeconds}
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type slowTool struct {
+	delay   time.Duration
+	stopped bool // The run was aborted by its context
+}
+
+func (s *slowTool) Info() ToolInfo { return ToolInfo{Name: "slow"} }
+
+func (s *slowTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	select {
+	case <-time.After(s.delay):
+		return NewTextResponse("done"), nil
+	case <-ctx.Done():
+		s.stopped = true
+		return NewTextErrorResponse("aborted"), nil
+	}
+}
+
+// useToolBudget sets the budget of a tool until the test ends
+func useToolBudget(t *testing.T, tool string, budget config.ToolBudget) {
+	t.Helper()
+	cfg := useWorkingDir(t, t.TempDir())
+	if cfg.ToolBudgets == nil {
+		cfg.ToolBudgets = make(map[string]config.ToolBudget)
+	}
+	previous, ok := cfg.ToolBudgets[tool]
+	cfg.ToolBudgets[tool] = budget
+	t.Cleanup(func() {
+		if ok {
+			cfg.ToolBudgets[tool] = previous
+		} else {
+			delete(cfg.ToolBudgets, tool)
+		}
+	})
+}
+
+func TestRunWithBudget(t *testing.T) {
+	useToolBudget(t, "slow", config.ToolBudget{TimeoutSeconds: 1})
+	ctx := context.Background()
+
+	response, err := RunWithBudget(ctx, &slowTool{}, ToolCall{Name: "slow"})
+	require.NoError(t, err)
+	assert.Equal(t, "done", response.Content)
+
+	slow := &slowTool{delay: time.Minute}
+	response, err = RunWithBudget(ctx, slow, ToolCall{Name: "slow"})
+	require.NoError(t, err)
+	assert.True(t, slow.stopped, "the tool is canceled on timeout")
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "exceeded its time budget of 1s")
+	assert.JSONEq(t, `{"tool":"slow","kind":"timeout","timeout_seconds":1}`, response.Metadata)
+
+	// Canceled by the user before the budget is used up
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	response, err = RunWithBudget(ctx, &slowTool{delay: time.Minute}, ToolCall{Name: "slow"})
+	require.NoError(t, err)
+	assert.Equal(t, "aborted", response.Content)
+}
+
+func TestBudgetTracker(t *testing.T) {
+	useToolBudget(t, "flaky", config.ToolBudget{MaxFailures: 2, FailureWindow: 3, CooldownSeconds: 60})
+	tracker := NewBudgetTracker()
+
+	assert.NoError(t, tracker.Record("s1", "flaky", true))
+	assert.NoError(t, tracker.Record("s1", "flaky", false))
+	assert.NoError(t, tracker.Record("s1", "flaky", false))
+	assert.NoError(t, tracker.Record("s1", "flaky", true), "the first failure left the window")
+	assert.NoError(t, tracker.Record("s2", "flaky", true), "sessions are counted apart")
+
+	var paused *PausedError
+	require.ErrorAs(t, tracker.Record("s1", "flaky", true), &paused)
+	assert.Equal(t, 2, paused.Failed)
+	assert.Equal(t, 3, paused.Runs)
+	require.ErrorAs(t, tracker.Check("s1", "flaky"), &paused)
+	assert.Contains(t, paused.Error(), "paused for another 1m0s because 2 of its last 3 runs in this session failed")
+	assert.NoError(t, tracker.Check("s2", "flaky"))
+	assert.NoError(t, tracker.Check("s1", "view"))
+
+	// One run is allowed after the pause, it pauses the tool again if it fails
+	tracker.health["s1/flaky"].pausedUntil = time.Now().Add(-time.Second)
+	assert.NoError(t, tracker.Check("s1", "flaky"))
+	assert.True(t, errors.As(tracker.Record("s1", "flaky", true), &paused))
+	tracker.health["s1/flaky"].pausedUntil = time.Now().Add(-time.Second)
+	assert.NoError(t, tracker.Check("s1", "flaky"))
+	assert.NoError(t, tracker.Record("s1", "flaky", false))
+	assert.NoError(t, tracker.Record("s1", "flaky", true))
+	assert.NoError(t, tracker.Check("s1", "flaky"))
+}
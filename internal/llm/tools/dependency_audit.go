@@ -0,0 +1,602 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+type DependencyAuditParams struct {
+	Path     string   `json:"path"`
+	Scanners []string `json:"scanners"`
+}
+
+type dependencyAuditTool struct{}
+
+const (
+	DependencyAuditToolName = "dependency_audit"
+
+	ScannerGovulncheck = "govulncheck"
+	ScannerNpmAudit    = "npm"
+	ScannerOSV         = "osv-scanner"
+
+	maxDependencyFindings = 100
+
+	dependencyAuditDescription = `Checks the dependencies of the project for known vulnerabilities and, when allowed licenses are configured, for incompatible licenses. Reports each affected dependency with the version that fixes it.
+
+WHEN TO USE THIS TOOL:
+- Use when asked about vulnerable or outdated dependencies, or before a release
+- Use after upgrading a dependency, to check that the vulnerability is gone
+
+HOW TO USE:
+- Optionally provide the directory of the project to check, defaults to the working directory
+- Optionally name the scanners to run: govulncheck (Go), npm (npm audit, needs package-lock.json) and osv-scanner (lockfiles of most ecosystems, and licenses). By default every installed scanner that applies runs
+
+OUTPUT:
+- One entry per dependency: the installed version, the version to upgrade to and the advisories with their severity
+- For Go, whether the vulnerable code is actually called by the project
+- The upgrade command for each dependency
+
+APPLYING UPGRADES SAFELY:
+- Upgrade one dependency at a time to the smallest version that fixes all of its advisories
+- Prefer patch and minor upgrades. A major upgrade can break the API: read the changelog of the dependency first and ask the user
+- Run the build and the tests after each upgrade, and run this tool again to confirm the fix
+- Vulnerabilities in code that is not called are less urgent, say so instead of upgrading blindly
+`
+)
+
+// DependencyFinding is a vulnerable or license-incompatible dependency
+type DependencyFinding struct {
+	Scanner  string `json:"scanner"`
+	Package  string `json:"package"`
+	Version  string `json:"version"`
+	ID       string `json:"id"`       // Advisory id, empty for license findings
+	Severity string `json:"severity"` // high, medium, low or empty when unknown
+	Summary  string `json:"summary"`
+	Fixed    string `json:"fixed"`   // Version fixing the advisory, empty when there is none
+	Called   bool   `json:"called"`  // Whether the vulnerable code is called, only known for govulncheck
+	License  string `json:"license"` // The incompatible license of a license finding
+	Major    bool   `json:"major"`   // Whether the fix needs a major upgrade, only known for npm
+}
+
+func NewDependencyAuditTool() BaseTool {
+	return &dependencyAuditTool{}
+}
+
+func (d *dependencyAuditTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        DependencyAuditToolName,
+		Description: dependencyAuditDescription,
+		Parameters: map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "The directory of the project to check, defaults to the working directory",
+			},
+			"scanners": map[string]any{
+				"type":        "array",
+				"description": "The scanners to run, defaults to all applicable installed scanners",
+				"items": map[string]any{
+					"type": "string",
+					"enum": []string{ScannerGovulncheck, ScannerNpmAudit, ScannerOSV},
+				},
+			},
+		},
+	}
+}
+
+func (d *dependencyAuditTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params DependencyAuditParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	dir := config.WorkingDirectory()
+	if params.Path != "" {
+		if filepath.IsAbs(params.Path) {
+			dir = params.Path
+		} else {
+			dir = filepath.Join(dir, params.Path)
+		}
+	}
+	allowed := config.Get().DependencyAudit.AllowedLicenses
+
+	scanners := params.Scanners
+	if len(scanners) == 0 {
+		scanners = applicableAuditors(dir)
+	}
+	var findings []DependencyFinding
+	var ran, missing, failed []string
+	for _, scanner := range scanners {
+		if _, err := exec.LookPath(scanner); err != nil {
+			missing = append(missing, scanner)
+			continue
+		}
+		result, err := runAuditor(ctx, scanner, dir, allowed)
+		if ctx.Err() != nil {
+			return ToolResponse{}, ctx.Err()
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", scanner, err))
+			continue
+		}
+		ran = append(ran, scanner)
+		findings = append(findings, result...)
+	}
+	if len(ran) == 0 && len(failed) == 0 {
+		return NewTextErrorResponse(fmt.Sprintf("no scanner installed, install one of: %s", strings.Join(missing, ", "))), nil
+	}
+	return NewTextResponse(formatDependencyFindings(dedupeFindings(findings), ran, missing, failed, len(allowed) > 0)), nil
+}
+
+// applicableAuditors returns the scanners for the package managers of the
+// project. osv-scanner reads the lockfiles of all ecosystems.
+func applicableAuditors(dir string) []string {
+	var scanners []string
+	if fileExists(filepath.Join(dir, "go.mod")) {
+		scanners = append(scanners, ScannerGovulncheck)
+	}
+	if fileExists(filepath.Join(dir, "package-lock.json")) {
+		scanners = append(scanners, ScannerNpmAudit)
+	}
+	return append(scanners, ScannerOSV)
+}
+
+func runAuditor(ctx context.Context, scanner, dir string, allowedLicenses []string) ([]DependencyFinding, error) {
+	run := func(name string, args ...string) ([]byte, error) {
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Dir = dir
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil && len(bytes.TrimSpace(out)) == 0 {
+			// The scanners exit with an error status when they find issues, it
+			// is only a failure without a report
+			return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return out, nil
+	}
+
+	switch scanner {
+	case ScannerGovulncheck:
+		out, err := run("govulncheck", "-json", "./...")
+		if err != nil {
+			return nil, err
+		}
+		return parseGovulncheck(out)
+	case ScannerNpmAudit:
+		out, err := run("npm", "audit", "--json")
+		if err != nil {
+			return nil, err
+		}
+		return parseNpmAudit(out)
+	case ScannerOSV:
+		args := []string{"scan", "source", "--format", "json"}
+		if len(allowedLicenses) > 0 {
+			args = append(args, "--licenses="+strings.Join(allowedLicenses, ","))
+		}
+		out, err := run("osv-scanner", append(args, "-r", ".")...)
+		if err == nil {
+			if findings, err := parseOSV(out); err == nil {
+				return findings, nil
+			}
+		}
+		// osv-scanner v1 has no scan subcommand and flags licenses as experimental
+		args = []string{"--format", "json"}
+		if len(allowedLicenses) > 0 {
+			args = append(args, "--experimental-licenses="+strings.Join(allowedLicenses, ","))
+		}
+		out, err = run("osv-scanner", append(args, "-r", ".")...)
+		if err != nil {
+			return nil, err
+		}
+		return parseOSV(out)
+	}
+	return nil, errors.New("unknown scanner")
+}
+
+// parseGovulncheck parses the stream of JSON messages of govulncheck -json.
+// A finding whose trace reaches a function means the vulnerable code is called.
+func parseGovulncheck(out []byte) ([]DependencyFinding, error) {
+	type message struct {
+		OSV *struct {
+			ID      string `json:"id"`
+			Summary string `json:"summary"`
+		} `json:"osv"`
+		Finding *struct {
+			OSV          string `json:"osv"`
+			FixedVersion string `json:"fixed_version"`
+			Trace        []struct {
+				Module   string `json:"module"`
+				Version  string `json:"version"`
+				Function string `json:"function"`
+			} `json:"trace"`
+		} `json:"finding"`
+	}
+
+	summaries := make(map[string]string)
+	findings := make(map[string]*DependencyFinding) // osv id + module -> finding
+	var order []string
+	decoder := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var msg message
+		if err := decoder.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse the govulncheck output: %w", err)
+		}
+		if msg.OSV != nil {
+			summaries[msg.OSV.ID] = msg.OSV.Summary
+		}
+		if msg.Finding == nil || len(msg.Finding.Trace) == 0 {
+			continue
+		}
+		frame := msg.Finding.Trace[0]
+		key := msg.Finding.OSV + " " + frame.Module
+		finding, ok := findings[key]
+		if !ok {
+			finding = &DependencyFinding{
+				Scanner: ScannerGovulncheck,
+				Package: frame.Module,
+				Version: frame.Version,
+				ID:      msg.Finding.OSV,
+				Fixed:   msg.Finding.FixedVersion,
+			}
+			findings[key] = finding
+			order = append(order, key)
+		}
+		for _, f := range msg.Finding.Trace {
+			if f.Function != "" {
+				finding.Called = true
+			}
+		}
+	}
+
+	result := make([]DependencyFinding, 0, len(order))
+	for _, key := range order {
+		finding := findings[key]
+		finding.Summary = summaries[finding.ID]
+		result = append(result, *finding)
+	}
+	return result, nil
+}
+
+// parseNpmAudit parses the report of npm audit --json (npm 7 and later)
+func parseNpmAudit(out []byte) ([]DependencyFinding, error) {
+	var report struct {
+		Vulnerabilities map[string]struct {
+			Name         string            `json:"name"`
+			Severity     string            `json:"severity"`
+			Range        string            `json:"range"`
+			Via          []json.RawMessage `json:"via"`
+			FixAvailable json.RawMessage   `json:"fixAvailable"`
+		} `json:"vulnerabilities"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse the npm audit report: %w", err)
+	}
+
+	var findings []DependencyFinding
+	for name, vuln := range report.Vulnerabilities {
+		var fix struct {
+			Name          string `json:"name"`
+			Version       string `json:"version"`
+			IsSemVerMajor bool   `json:"isSemVerMajor"`
+		}
+		// fixAvailable is false, true or the package upgrade fixing it
+		_ = json.Unmarshal(vuln.FixAvailable, &fix)
+
+		for _, via := range vuln.Via {
+			var advisory struct {
+				Source any    `json:"source"`
+				Title  string `json:"title"`
+				URL    string `json:"url"`
+			}
+			// Entries naming another package are vulnerable through it,
+			// that package has its own entry
+			if err := json.Unmarshal(via, &advisory); err != nil || advisory.Title == "" {
+				continue
+			}
+			id := advisory.URL
+			if i := strings.LastIndex(id, "/"); i >= 0 {
+				id = id[i+1:]
+			}
+			finding := DependencyFinding{
+				Scanner:  ScannerNpmAudit,
+				Package:  name,
+				Version:  vuln.Range,
+				ID:       id,
+				Severity: normalizeSeverity(vuln.Severity),
+				Summary:  advisory.Title,
+				Major:    fix.IsSemVerMajor,
+			}
+			if fix.Name == name {
+				finding.Fixed = fix.Version
+			} else if fix.Name != "" {
+				finding.Summary += fmt.Sprintf(" (fixed by upgrading %s to %s)", fix.Name, fix.Version)
+			}
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+// parseOSV parses the report of osv-scanner --format json
+func parseOSV(out []byte) ([]DependencyFinding, error) {
+	var report struct {
+		Results []struct {
+			Packages []struct {
+				Package struct {
+					Name      string `json:"name"`
+					Version   string `json:"version"`
+					Ecosystem string `json:"ecosystem"`
+				} `json:"package"`
+				Vulnerabilities []struct {
+					ID       string `json:"id"`
+					Summary  string `json:"summary"`
+					Affected []struct {
+						Package struct {
+							Name string `json:"name"`
+						} `json:"package"`
+						Ranges []struct {
+							Events []struct {
+								Fixed string `json:"fixed"`
+							} `json:"events"`
+						} `json:"ranges"`
+					} `json:"affected"`
+					DatabaseSpecific struct {
+						Severity string `json:"severity"`
+					} `json:"database_specific"`
+				} `json:"vulnerabilities"`
+				Groups []struct {
+					IDs         []string `json:"ids"`
+					MaxSeverity string   `json:"max_severity"` // CVSS score
+				} `json:"groups"`
+				LicenseViolations []string `json:"license_violations"`
+			} `json:"packages"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse the osv-scanner report: %w", err)
+	}
+
+	var findings []DependencyFinding
+	for _, result := range report.Results {
+		for _, pkg := range result.Packages {
+			groupSeverity := make(map[string]string)
+			for _, group := range pkg.Groups {
+				for _, id := range group.IDs {
+					groupSeverity[id] = cvssSeverity(group.MaxSeverity)
+				}
+			}
+			for _, vuln := range pkg.Vulnerabilities {
+				finding := DependencyFinding{
+					Scanner:  ScannerOSV,
+					Package:  pkg.Package.Name,
+					Version:  pkg.Package.Version,
+					ID:       vuln.ID,
+					Severity: normalizeSeverity(vuln.DatabaseSpecific.Severity),
+					Summary:  vuln.Summary,
+				}
+				if finding.Severity == "" {
+					finding.Severity = groupSeverity[vuln.ID]
+				}
+				// Advisories list a fixed version per release branch, the
+				// fix is the smallest one above the installed version
+				for _, affected := range vuln.Affected {
+					if affected.Package.Name != pkg.Package.Name {
+						continue
+					}
+					for _, r := range affected.Ranges {
+						for _, event := range r.Events {
+							if event.Fixed == "" || compareVersions(event.Fixed, pkg.Package.Version) <= 0 {
+								continue
+							}
+							if finding.Fixed == "" || compareVersions(event.Fixed, finding.Fixed) < 0 {
+								finding.Fixed = event.Fixed
+							}
+						}
+					}
+				}
+				findings = append(findings, finding)
+			}
+			for _, license := range pkg.LicenseViolations {
+				findings = append(findings, DependencyFinding{
+					Scanner: ScannerOSV,
+					Package: pkg.Package.Name,
+					Version: pkg.Package.Version,
+					License: license,
+					Summary: fmt.Sprintf("license %s is not allowed", license),
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// cvssSeverity maps a CVSS score to high, medium or low
+func cvssSeverity(score string) string {
+	value, err := strconv.ParseFloat(score, 64)
+	switch {
+	case err != nil:
+		return ""
+	case value >= 7:
+		return "high"
+	case value >= 4:
+		return "medium"
+	}
+	return "low"
+}
+
+// dedupeFindings drops advisories reported by several scanners, keeping the
+// first report, since govulncheck runs before osv-scanner and knows whether
+// the code is called
+func dedupeFindings(findings []DependencyFinding) []DependencyFinding {
+	seen := make(map[string]bool)
+	result := findings[:0]
+	for _, f := range findings {
+		key := f.Package + " " + f.ID + " " + f.License
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, f)
+	}
+	return result
+}
+
+func formatDependencyFindings(findings []DependencyFinding, ran, missing, failed []string, licenses bool) string {
+	// Group the findings by dependency, the dependencies with the most severe
+	// findings first
+	type dependency struct {
+		name, version, fixed, scanner string
+		major                         bool
+		rank                          int
+		findings                      []DependencyFinding
+	}
+	byPackage := make(map[string]*dependency)
+	var deps []*dependency
+	for _, f := range findings {
+		dep, ok := byPackage[f.Package]
+		if !ok {
+			dep = &dependency{name: f.Package, version: f.Version, scanner: f.Scanner, rank: len(severityRank)}
+			byPackage[f.Package] = dep
+			deps = append(deps, dep)
+		}
+		dep.findings = append(dep.findings, f)
+		if r, ok := severityRank[f.Severity]; ok && r < dep.rank {
+			dep.rank = r
+		}
+		if f.Major {
+			dep.major = true
+		}
+		// The fix of all advisories is the highest of their fix versions
+		if f.Fixed != "" && compareVersions(f.Fixed, dep.fixed) > 0 {
+			dep.fixed = f.Fixed
+		}
+	}
+	sort.SliceStable(deps, func(i, j int) bool { return deps[i].rank < deps[j].rank })
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d issue(s) in %d dependencies\n", len(findings), len(deps))
+	fmt.Fprintf(&sb, "Scanners run: %s\n", strings.Join(ran, ", "))
+	if len(missing) > 0 {
+		fmt.Fprintf(&sb, "Not installed: %s\n", strings.Join(missing, ", "))
+	}
+	for _, failure := range failed {
+		fmt.Fprintf(&sb, "Failed: %s\n", failure)
+	}
+	if !licenses {
+		sb.WriteString("Licenses not checked, configure dependencyAudit.allowedLicenses to check them\n")
+	}
+
+	shown := 0
+	for _, dep := range deps {
+		if shown >= maxDependencyFindings {
+			fmt.Fprintf(&sb, "\n... more dependencies omitted, check a narrower path to see them\n")
+			break
+		}
+		fmt.Fprintf(&sb, "\n%s %s", dep.name, dep.version)
+		switch {
+		case dep.fixed != "":
+			fmt.Fprintf(&sb, " -> upgrade to %s", dep.fixed)
+			if dep.major {
+				sb.WriteString(" (major upgrade)")
+			}
+			if command := upgradeCommand(dep.scanner, dep.name, dep.fixed); command != "" {
+				fmt.Fprintf(&sb, ": %s", command)
+			}
+		case dep.findings[0].License == "":
+			sb.WriteString(" -> no fixed version yet")
+		}
+		sb.WriteString("\n")
+		for _, f := range dep.findings {
+			shown++
+			labels := []string{}
+			if f.Severity != "" {
+				labels = append(labels, f.Severity)
+			}
+			if f.Scanner == ScannerGovulncheck {
+				if f.Called {
+					labels = append(labels, "called")
+				} else {
+					labels = append(labels, "not called")
+				}
+			}
+			id := f.ID
+			if id == "" {
+				id = "license"
+			}
+			if len(labels) > 0 {
+				id += " (" + strings.Join(labels, ", ") + ")"
+			}
+			fmt.Fprintf(&sb, "- %s: %s\n", id, f.Summary)
+		}
+	}
+	return sb.String()
+}
+
+// upgradeCommand returns the command upgrading a dependency, empty when the
+// package manager is unknown
+func upgradeCommand(scanner, name, version string) string {
+	switch scanner {
+	case ScannerGovulncheck:
+		if name == "stdlib" || name == "toolchain" {
+			return "upgrade the Go toolchain"
+		}
+		return fmt.Sprintf("go get %s@%s && go mod tidy", name, ensurePrefix(version, "v"))
+	case ScannerNpmAudit:
+		return fmt.Sprintf("npm install %s@%s", name, version)
+	}
+	return ""
+}
+
+func ensurePrefix(s, prefix string) string {
+	if strings.HasPrefix(s, prefix) {
+		return s
+	}
+	return prefix + s
+}
+
+// compareVersions compares dotted versions numerically, ignoring a "v" prefix
+// and pre-release suffixes
+func compareVersions(a, b string) int {
+	if b == "" {
+		return 1
+	}
+	split := func(v string) []int {
+		v = strings.TrimPrefix(v, "v")
+		v, _, _ = strings.Cut(v, "-")
+		var parts []int
+		for _, part := range strings.Split(v, ".") {
+			n, _ := strconv.Atoi(part)
+			parts = append(parts, n)
+		}
+		return parts
+	}
+	as, bs := split(a), split(b)
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		var x, y int
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGovulncheck(t *testing.T) {
+	findings, err := parseGovulncheck([]byte(`{"config": {"scanner_name": "govulncheck"}}
+{"osv": {"id": "GO-2023-2102", "summary": "HTTP/2 rapid reset can cause excessive work in net/http"}}
+{"finding": {"osv": "GO-2023-2102", "fixed_version": "v0.17.0", "trace": [{"module": "golang.org/x/net", "version": "v0.10.0"}]}}
+{"finding": {"osv": "GO-2023-2102", "fixed_version": "v0.17.0", "trace": [{"module": "golang.org/x/net", "version": "v0.10.0", "package": "golang.org/x/net/http2", "function": "ServeConn"}, {"module": "example.com/app", "function": "main"}]}}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []DependencyFinding{{
+		Scanner: ScannerGovulncheck,
+		Package: "golang.org/x/net",
+		Version: "v0.10.0",
+		ID:      "GO-2023-2102",
+		Summary: "HTTP/2 rapid reset can cause excessive work in net/http",
+		Fixed:   "v0.17.0",
+		Called:  true,
+	}}, findings)
+}
+
+func TestParseNpmAudit(t *testing.T) {
+	findings, err := parseNpmAudit([]byte(`{"vulnerabilities": {
+  "semver": {"name": "semver", "severity": "moderate", "range": "<7.5.2",
+    "via": [{"source": 1096482, "title": "semver vulnerable to ReDoS", "url": "https://github.com/advisories/GHSA-c2qf-rxjj-qqgw"}],
+    "fixAvailable": {"name": "semver", "version": "7.5.4", "isSemVerMajor": false}},
+  "make-dir": {"name": "make-dir", "severity": "moderate", "range": "2.0.0 - 3.1.0", "via": ["semver"], "fixAvailable": true}
+}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []DependencyFinding{{
+		Scanner:  ScannerNpmAudit,
+		Package:  "semver",
+		Version:  "<7.5.2",
+		ID:       "GHSA-c2qf-rxjj-qqgw",
+		Severity: "medium",
+		Summary:  "semver vulnerable to ReDoS",
+		Fixed:    "7.5.4",
+	}}, findings)
+}
+
+func TestParseOSV(t *testing.T) {
+	findings, err := parseOSV([]byte(`{"results": [{"packages": [{
+  "package": {"name": "requests", "version": "2.25.0", "ecosystem": "PyPI"},
+  "vulnerabilities": [{"id": "GHSA-j8r2-6x86-q33q", "summary": "Unintended leak of Proxy-Authorization header",
+    "affected": [{"package": {"name": "requests"}, "ranges": [{"events": [{"introduced": "0"}, {"fixed": "2.31.0"}]}, {"events": [{"introduced": "3.0.0"}, {"fixed": "3.0.1"}]}]}]}],
+  "groups": [{"ids": ["GHSA-j8r2-6x86-q33q"], "max_severity": "6.1"}],
+  "license_violations": ["GPL-3.0"]
+}]}]}`))
+	require.NoError(t, err)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "medium", findings[0].Severity)
+	assert.Equal(t, "2.31.0", findings[0].Fixed)
+	assert.Equal(t, "GPL-3.0", findings[1].License)
+
+	output := formatDependencyFindings(findings, []string{ScannerOSV}, nil, nil, true)
+	assert.Contains(t, output, "requests 2.25.0 -> upgrade to 2.31.0\n- GHSA-j8r2-6x86-q33q (medium): Unintended leak of Proxy-Authorization header\n- license: license GPL-3.0 is not allowed\n")
+}
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 1, compareVersions("v0.17.0", "v0.9.1"))
+	assert.Equal(t, -1, compareVersions("1.2", "1.2.1"))
+	assert.Equal(t, 0, compareVersions("v1.2.0", "1.2.0-rc1"))
+	assert.Equal(t, 1, compareVersions("1.0.0", ""))
+}
@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/lsp"
+	"github.com/kirmad/superopencode/internal/lsp/protocol"
+)
+
+// maxDeltaDiagnostics is the number of introduced diagnostics listed after an
+// edit, fixed ones are listed up to half of it
+const maxDeltaDiagnostics = 10
+
+// fileDiagnostic is an error or warning of a file, reported by an LSP client
+type fileDiagnostic struct {
+	Path       string
+	LSP        string
+	Diagnostic protocol.Diagnostic
+}
+
+// key identifies a diagnostic across an edit. The range is left out since
+// edits move the lines below them.
+func (d fileDiagnostic) key() string {
+	source := d.Diagnostic.Source
+	if source == "" {
+		source = d.LSP
+	}
+	return fmt.Sprintf("%s\x00%d\x00%s\x00%v\x00%s", d.Path, d.Diagnostic.Severity, source, d.Diagnostic.Code, d.Diagnostic.Message)
+}
+
+func (d fileDiagnostic) String() string {
+	return formatDiagnostic(d.Path, d.Diagnostic, d.LSP)
+}
+
+// snapshotDiagnostics returns the errors and warnings the LSP clients
+// currently report. Taken before an edit, it's the baseline of the delta;
+// files are opened by the view tool before they can be edited, so their
+// diagnostics are known.
+func snapshotDiagnostics(lsps map[string]*lsp.Client) []fileDiagnostic {
+	var snapshot []fileDiagnostic
+	for name, client := range lsps {
+		for uri, diags := range client.GetDiagnostics() {
+			for _, diag := range diags {
+				if diag.Severity != protocol.SeverityError && diag.Severity != protocol.SeverityWarning {
+					continue
+				}
+				snapshot = append(snapshot, fileDiagnostic{Path: uri.Path(), LSP: name, Diagnostic: diag})
+			}
+		}
+	}
+	return snapshot
+}
+
+// diffDiagnostics returns the diagnostics introduced and fixed between two
+// snapshots. Identical diagnostics are counted, so a second copy of an error
+// is reported as introduced.
+func diffDiagnostics(before, after []fileDiagnostic) (introduced, fixed []fileDiagnostic) {
+	remaining := make(map[string]int)
+	for _, d := range before {
+		remaining[d.key()]++
+	}
+	for _, d := range after {
+		if remaining[d.key()] > 0 {
+			remaining[d.key()]--
+			continue
+		}
+		introduced = append(introduced, d)
+	}
+	for _, d := range before {
+		if remaining[d.key()] > 0 {
+			remaining[d.key()]--
+			fixed = append(fixed, d)
+		}
+	}
+	sortDiagnostics(introduced)
+	sortDiagnostics(fixed)
+	return introduced, fixed
+}
+
+// sortDiagnostics orders errors first, then by location
+func sortDiagnostics(diags []fileDiagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i], diags[j]
+		if a.Diagnostic.Severity != b.Diagnostic.Severity {
+			return a.Diagnostic.Severity < b.Diagnostic.Severity
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Diagnostic.Range.Start.Line < b.Diagnostic.Range.Start.Line
+	})
+}
+
+// diagnosticsDelta summarizes how an edit changed the diagnostics: the counts
+// of introduced, fixed and remaining errors and warnings, and the introduced
+// and fixed diagnostics themselves. It's empty without LSP clients.
+func diagnosticsDelta(before []fileDiagnostic, lsps map[string]*lsp.Client) string {
+	if len(lsps) == 0 {
+		return ""
+	}
+	return formatDiagnosticsDelta(before, snapshotDiagnostics(lsps))
+}
+
+func formatDiagnosticsDelta(before, after []fileDiagnostic) string {
+	introduced, fixed := diffDiagnostics(before, after)
+
+	var sb strings.Builder
+	sb.WriteString("\n<diagnostics_delta>\n")
+	fmt.Fprintf(&sb, "Introduced: %s | Fixed: %s | Remaining in project: %s\n",
+		countDiagnostics(introduced), countDiagnostics(fixed), countDiagnostics(after))
+	writeDiagnostics(&sb, "+ ", introduced, maxDeltaDiagnostics)
+	writeDiagnostics(&sb, "- ", fixed, maxDeltaDiagnostics/2)
+	sb.WriteString("</diagnostics_delta>\n")
+	return sb.String()
+}
+
+func writeDiagnostics(sb *strings.Builder, prefix string, diags []fileDiagnostic, limit int) {
+	for i, d := range diags {
+		if i == limit {
+			fmt.Fprintf(sb, "%s... and %d more\n", prefix, len(diags)-limit)
+			break
+		}
+		sb.WriteString(prefix + d.String() + "\n")
+	}
+}
+
+func countDiagnostics(diags []fileDiagnostic) string {
+	var errors, warnings int
+	for _, d := range diags {
+		if d.Diagnostic.Severity == protocol.SeverityError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	return fmt.Sprintf("%s, %s", plural(errors, "error"), plural(warnings, "warning"))
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
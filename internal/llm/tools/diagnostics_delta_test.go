@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/lsp/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func diagnosticAt(path string, line uint32, severity protocol.DiagnosticSeverity, message string) fileDiagnostic {
+	return fileDiagnostic{
+		Path: path,
+		LSP:  "gopls",
+		Diagnostic: protocol.Diagnostic{
+			Range:    protocol.Range{Start: protocol.Position{Line: line}},
+			Severity: severity,
+			Source:   "compiler",
+			Message:  message,
+		},
+	}
+}
+
+func TestDiffDiagnosticsIgnoresMovedLines(t *testing.T) {
+	before := []fileDiagnostic{
+		diagnosticAt("/a.go", 3, protocol.SeverityError, "missing return"),
+		diagnosticAt("/a.go", 10, protocol.SeverityWarning, "unused x"),
+		diagnosticAt("/b.go", 1, protocol.SeverityError, "undefined: y"),
+	}
+	after := []fileDiagnostic{
+		// Moved by the lines added above it
+		diagnosticAt("/a.go", 14, protocol.SeverityWarning, "unused x"),
+		diagnosticAt("/b.go", 1, protocol.SeverityError, "undefined: y"),
+		diagnosticAt("/a.go", 12, protocol.SeverityError, "undefined: foo"),
+	}
+
+	introduced, fixed := diffDiagnostics(before, after)
+	assert.Equal(t, []fileDiagnostic{after[2]}, introduced)
+	assert.Equal(t, []fileDiagnostic{before[0]}, fixed)
+}
+
+func TestDiffDiagnosticsCountsDuplicates(t *testing.T) {
+	d := diagnosticAt("/a.go", 1, protocol.SeverityError, "undefined: foo")
+	introduced, fixed := diffDiagnostics([]fileDiagnostic{d}, []fileDiagnostic{d, d})
+	assert.Len(t, introduced, 1)
+	assert.Empty(t, fixed)
+}
+
+func TestFormatDiagnosticsDelta(t *testing.T) {
+	before := []fileDiagnostic{
+		diagnosticAt("/a.go", 2, protocol.SeverityError, "missing return"),
+		diagnosticAt("/b.go", 0, protocol.SeverityWarning, "unused y"),
+	}
+	after := []fileDiagnostic{
+		diagnosticAt("/b.go", 0, protocol.SeverityWarning, "unused y"),
+		diagnosticAt("/a.go", 4, protocol.SeverityWarning, "unused x"),
+		diagnosticAt("/a.go", 11, protocol.SeverityError, "undefined: foo"),
+	}
+
+	assert.Equal(t, `
+<diagnostics_delta>
+Introduced: 1 error, 1 warning | Fixed: 1 error, 0 warnings | Remaining in project: 1 error, 2 warnings
++ Error: /a.go:12:1 [compiler] undefined: foo
++ Warn: /a.go:5:1 [compiler] unused x
+- Error: /a.go:3:1 [compiler] missing return
+</diagnostics_delta>
+`, formatDiagnosticsDelta(before, after))
+}
+
+func TestFormatDiagnosticsDeltaLimitsList(t *testing.T) {
+	var after []fileDiagnostic
+	for i := 0; i < maxDeltaDiagnostics+3; i++ {
+		after = append(after, diagnosticAt("/a.go", uint32(i), protocol.SeverityError, "undefined"))
+	}
+	assert.Contains(t, formatDiagnosticsDelta(nil, after), "+ ... and 3 more\n")
+}
@@ -0,0 +1,302 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+type GenerateDocsParams struct {
+	Path   string `json:"path"`
+	Format string `json:"format"`
+}
+
+type generateDocsTool struct{}
+
+const (
+	GenerateDocsToolName = "generate_docs"
+
+	DocsFormatComments = "comments"
+	DocsFormatMarkdown = "markdown"
+
+	// maxSignatureLines keeps large structs and interfaces readable
+	maxSignatureLines = 15
+
+	generateDocsDescription = `Lists the public API of a Go package to document it, with the signature, location and current doc comment of every exported symbol.
+
+WHEN TO USE THIS TOOL:
+- Use when asked to document a package, either with Go doc comments or with Markdown docs
+- Use to find the exported symbols that are missing doc comments
+
+HOW TO USE:
+- Provide the directory of the package
+- Choose the format: "comments" to add Go doc comments to the source, "markdown" to write a Markdown file in the docs directory
+- Follow the instructions at the end of the output and make the changes with the edit and write tools, so they go through the normal review
+
+LIMITATIONS:
+- Only Go packages are supported
+- Test files and the files and symbols excluded in the docs configuration are skipped
+`
+)
+
+// apiSymbol is an exported symbol of a package
+type apiSymbol struct {
+	Name      string
+	Signature string
+	File      string
+	Line      int
+	Doc       string
+}
+
+func NewGenerateDocsTool() BaseTool {
+	return &generateDocsTool{}
+}
+
+func (g *generateDocsTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        GenerateDocsToolName,
+		Description: generateDocsDescription,
+		Parameters: map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "The directory of the package to document",
+			},
+			"format": map[string]any{
+				"type":        "string",
+				"description": "How to document the package, defaults to comments",
+				"enum":        []string{DocsFormatComments, DocsFormatMarkdown},
+			},
+		},
+		Required: []string{"path"},
+	}
+}
+
+func (g *generateDocsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params GenerateDocsParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.Format == "" {
+		params.Format = DocsFormatComments
+	}
+	if params.Format != DocsFormatComments && params.Format != DocsFormatMarkdown {
+		return NewTextErrorResponse(fmt.Sprintf("unknown format %q, use %s or %s", params.Format, DocsFormatComments, DocsFormatMarkdown)), nil
+	}
+
+	wd := config.WorkingDirectory()
+	dir := params.Path
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(wd, dir)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return NewTextErrorResponse(fmt.Sprintf("not a directory: %s", dir)), nil
+	}
+
+	pkgName, symbols, err := packageAPI(dir, wd, config.Get().Docs.Exclude)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	if len(symbols) == 0 {
+		return NewTextResponse(fmt.Sprintf("Package %s has no exported symbols to document", pkgName)), nil
+	}
+
+	rel, err := filepath.Rel(wd, dir)
+	if err != nil {
+		rel = dir
+	}
+	var missing, documented []apiSymbol
+	for _, symbol := range symbols {
+		if symbol.Doc == "" {
+			missing = append(missing, symbol)
+		} else {
+			documented = append(documented, symbol)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Package %s (%s): %d exported symbols, %d without doc comments\n", pkgName, rel, len(symbols), len(missing))
+	writeSymbols := func(title string, symbols []apiSymbol, withDoc bool) {
+		if len(symbols) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "\n## %s\n", title)
+		for _, symbol := range symbols {
+			fmt.Fprintf(&sb, "\n%s:%d\n%s\n", symbol.File, symbol.Line, symbol.Signature)
+			if withDoc {
+				fmt.Fprintf(&sb, "Doc: %s\n", strings.ReplaceAll(strings.TrimSpace(symbol.Doc), "\n", "\n     "))
+			}
+		}
+	}
+
+	switch params.Format {
+	case DocsFormatComments:
+		if len(missing) == 0 {
+			return NewTextResponse(fmt.Sprintf("All %d exported symbols of package %s have doc comments", len(symbols), pkgName)), nil
+		}
+		writeSymbols("Missing doc comments", missing, false)
+		sb.WriteString("\nAdd a doc comment above each symbol listed above with the edit tool. Start each comment with the name of the symbol, describe what it does and how to use it rather than how it works, and match the length and style of the existing comments of the package. Only add comments, don't change any code.\n")
+	case DocsFormatMarkdown:
+		writeSymbols("Missing doc comments", missing, false)
+		writeSymbols("Documented", documented, true)
+		docsFile := filepath.Join(config.Get().Docs.Dir, rel+".md")
+		action := "Create"
+		if _, err := os.Stat(filepath.Join(wd, docsFile)); err == nil {
+			action = "Update"
+		}
+		fmt.Fprintf(&sb, "\n%s %s with the write or edit tool: an overview of what the package is for, then a section per symbol with its signature in a code block and a description. Read the code of symbols without doc comments to describe them. Keep the parts of an existing file that are still accurate and remove the sections of symbols that no longer exist.\n", action, docsFile)
+	}
+	return NewTextResponse(sb.String()), nil
+}
+
+// packageAPI returns the name of the Go package in dir and its exported
+// symbols, skipping tests and the excluded files and symbols
+func packageAPI(dir, wd string, exclude []string) (string, []apiSymbol, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse the package: %w", err)
+	}
+	if len(pkgs) == 0 {
+		return "", nil, fmt.Errorf("no Go package found in %s, only Go packages are supported", dir)
+	}
+
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		if pkg == nil || len(p.Files) > len(pkg.Files) {
+			pkg = p
+		}
+	}
+	files := make([]string, 0, len(pkg.Files))
+	for name := range pkg.Files {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+
+	var symbols []apiSymbol
+	for _, name := range files {
+		rel, err := filepath.Rel(wd, name)
+		if err != nil {
+			rel = name
+		}
+		if docsExcluded(exclude, rel) {
+			continue
+		}
+		add := func(symbolName string, node ast.Node, doc *ast.CommentGroup) {
+			if docsExcluded(exclude, symbolName) {
+				return
+			}
+			symbols = append(symbols, apiSymbol{
+				Name:      symbolName,
+				Signature: formatSignature(fset, node),
+				File:      rel,
+				Line:      fset.Position(node.Pos()).Line,
+				Doc:       doc.Text(),
+			})
+		}
+
+		for _, decl := range pkg.Files[name].Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				symbolName, ok := funcName(d)
+				if !ok {
+					continue
+				}
+				signature := *d
+				signature.Body = nil
+				signature.Doc = nil
+				add(symbolName, &signature, d.Doc)
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					// A single spec is documented by the comment of its declaration
+					doc := d.Doc
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						if !s.Name.IsExported() {
+							continue
+						}
+						if s.Doc != nil {
+							doc = s.Doc
+						}
+						add(s.Name.Name, &ast.GenDecl{Tok: token.TYPE, Specs: []ast.Spec{s}}, doc)
+					case *ast.ValueSpec:
+						if s.Doc != nil {
+							doc = s.Doc
+						}
+						for _, ident := range s.Names {
+							if ident.IsExported() {
+								add(ident.Name, &ast.GenDecl{Tok: d.Tok, Specs: []ast.Spec{s}}, doc)
+								break
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return pkg.Name, symbols, nil
+}
+
+// funcName returns the name of an exported function or of an exported method
+// of an exported type, e.g. "Service.Run"
+func funcName(d *ast.FuncDecl) (string, bool) {
+	if !d.Name.IsExported() {
+		return "", false
+	}
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return d.Name.Name, true
+	}
+	recv := d.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	switch r := recv.(type) {
+	case *ast.IndexExpr:
+		recv = r.X
+	case *ast.IndexListExpr:
+		recv = r.X
+	}
+	ident, ok := recv.(*ast.Ident)
+	if !ok || !ident.IsExported() {
+		return "", false
+	}
+	return ident.Name + "." + d.Name.Name, true
+}
+
+func formatSignature(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) > maxSignatureLines {
+		lines = append(lines[:maxSignatureLines], "\t// ...")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// docsExcluded reports whether a file path or symbol name matches one of the
+// exclude patterns
+func docsExcluded(exclude []string, name string) bool {
+	for _, pattern := range exclude {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, filepath.Base(name)); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageAPI(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"store.go": `package store
+
+// Store keeps items
+type Store struct {
+	items map[string]string
+}
+
+func New() *Store { return &Store{} }
+
+// Get returns an item
+func (s *Store) Get(key string) string { return s.items[key] }
+
+func (s *Store) put(key, value string) {}
+
+type entry struct{}
+
+func (entry) Key() string { return "" }
+
+const (
+	// MaxItems limits the store
+	MaxItems = 10
+	minItems = 1
+)
+`,
+		"internal.go": `package store
+
+func Excluded() {}
+`,
+		"store_test.go": `package store
+
+func TestHelper() {}
+`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	name, symbols, err := packageAPI(dir, dir, []string{"internal.go"})
+	require.NoError(t, err)
+	assert.Equal(t, "store", name)
+
+	var names []string
+	for _, symbol := range symbols {
+		names = append(names, symbol.Name)
+	}
+	assert.Equal(t, []string{"Store", "New", "Store.Get", "MaxItems"}, names)
+
+	assert.Equal(t, "Store keeps items\n", symbols[0].Doc)
+	assert.Contains(t, symbols[0].Signature, "type Store struct")
+	assert.Equal(t, "func New() *Store", symbols[1].Signature)
+	assert.Empty(t, symbols[1].Doc)
+	assert.Equal(t, "store.go", symbols[2].File)
+	assert.Equal(t, 11, symbols[2].Line)
+
+	_, symbols, err = packageAPI(dir, dir, []string{"Store*"})
+	require.NoError(t, err)
+	assert.Len(t, symbols, 3)
+}
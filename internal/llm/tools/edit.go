@@ -3,16 +3,15 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/kirmad/superopencode/internal/config"
 	"github.com/kirmad/superopencode/internal/diff"
 	"github.com/kirmad/superopencode/internal/history"
-	"github.com/kirmad/superopencode/internal/logging"
 	"github.com/kirmad/superopencode/internal/lsp"
 	"github.com/kirmad/superopencode/internal/permission"
 )
@@ -136,6 +135,8 @@ func (e *editTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		params.FilePath = filepath.Join(wd, params.FilePath)
 	}
 
+	diagnosticsBefore := snapshotDiagnostics(e.lspClients)
+
 	var response ToolResponse
 	var err error
 
@@ -165,7 +166,7 @@ func (e *editTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 
 	waitForLspDiagnostics(ctx, params.FilePath, e.lspClients)
 	text := fmt.Sprintf("<result>\n%s\n</result>\n", response.Content)
-	text += getDiagnostics(params.FilePath, e.lspClients)
+	text += diagnosticsDelta(diagnosticsBefore, e.lspClients)
 	response.Content = text
 	return response, nil
 }
@@ -218,8 +219,13 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string)
 		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
 
-	err = os.WriteFile(filePath, []byte(content), 0o644)
+	snapshot, err := e.files.Snapshot(ctx, sessionID, filePath)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error snapshotting file: %w", err)
+	}
+	err = history.WriteFile(filePath, []byte(content), 0o644)
 	if err != nil {
+		e.files.Release(snapshot)
 		return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
 	}
 
@@ -232,13 +238,10 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string)
 
 	// Add the new content to the file history
 	_, err = e.files.CreateVersion(ctx, sessionID, filePath, content)
-	if err != nil {
-		// Log error but don't fail the operation
-		logging.Debug("Error creating file history version", "error", err)
-	}
+	history.ReleaseRecorded(e.files, snapshot, err)
 
-	recordFileWrite(filePath)
-	recordFileRead(filePath)
+	recordFileWrite(ctx, filePath)
+	recordFileRead(ctx, filePath)
 
 	return WithResponseMetadata(
 		NewTextResponse("File created: "+filePath),
@@ -263,17 +266,12 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 		return NewTextErrorResponse(fmt.Sprintf("path is a directory, not a file: %s", filePath)), nil
 	}
 
-	if getLastReadTime(filePath).IsZero() {
-		return NewTextErrorResponse("you must read the file before editing it. Use the View tool first"), nil
+	if kind, reason := detectFileKind(filePath); kind != FileKindSource {
+		return fileKindResponse(filePath, kind, reason, "edit"), nil
 	}
 
-	modTime := fileInfo.ModTime()
-	lastRead := getLastReadTime(filePath)
-	if modTime.After(lastRead) {
-		return NewTextErrorResponse(
-			fmt.Sprintf("file %s has been modified since it was last read (mod time: %s, last read: %s)",
-				filePath, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339),
-			)), nil
+	if response, ok := checkFileUnchanged(ctx, filePath, "edit"); !ok {
+		return response, nil
 	}
 
 	content, err := os.ReadFile(filePath)
@@ -281,7 +279,8 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 		return ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	oldContent := string(content)
+	oldContent, format := decodeText(content)
+	oldString, _ = format.normalize(oldString)
 
 	index := strings.Index(oldContent, oldString)
 	if index == -1 {
@@ -294,6 +293,10 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 	}
 
 	newContent := oldContent[:index] + oldContent[index+len(oldString):]
+	data, err := encodeText(newContent, format)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
 
 	sessionID, messageID := GetContextValues(ctx)
 
@@ -328,9 +331,18 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 	if !p {
 		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
+	// The file may have been edited while the permission was pending
+	if response, ok := checkFileUnchanged(ctx, filePath, "edit"); !ok {
+		return response, nil
+	}
 
-	err = os.WriteFile(filePath, []byte(newContent), 0o644)
+	snapshot, err := e.files.Snapshot(ctx, sessionID, filePath)
 	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error snapshotting file: %w", err)
+	}
+	err = history.WriteFile(filePath, data, 0o644)
+	if err != nil {
+		e.files.Release(snapshot)
 		return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
 	}
 
@@ -343,24 +355,22 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 			return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
 		}
 	}
+	var historyErrs []error
 	if file.Content != oldContent {
 		// User Manually changed the content store an intermediate version
 		_, err = e.files.CreateVersion(ctx, sessionID, filePath, oldContent)
-		if err != nil {
-			logging.Debug("Error creating file history version", "error", err)
-		}
+		historyErrs = append(historyErrs, err)
 	}
 	// Store the new version
 	_, err = e.files.CreateVersion(ctx, sessionID, filePath, "")
-	if err != nil {
-		logging.Debug("Error creating file history version", "error", err)
-	}
+	historyErrs = append(historyErrs, err)
+	history.ReleaseRecorded(e.files, snapshot, errors.Join(historyErrs...))
 
-	recordFileWrite(filePath)
-	recordFileRead(filePath)
+	recordFileWrite(ctx, filePath)
+	recordFileRead(ctx, filePath)
 
 	return WithResponseMetadata(
-		NewTextResponse("Content deleted from file: "+filePath),
+		NewTextResponse(strings.TrimSpace("Content deleted from file: "+filePath+"\n"+formatNote(format, false))),
 		EditResponseMetadata{
 			Diff:      diff,
 			Additions: additions,
@@ -382,17 +392,12 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 		return NewTextErrorResponse(fmt.Sprintf("path is a directory, not a file: %s", filePath)), nil
 	}
 
-	if getLastReadTime(filePath).IsZero() {
-		return NewTextErrorResponse("you must read the file before editing it. Use the View tool first"), nil
+	if kind, reason := detectFileKind(filePath); kind != FileKindSource {
+		return fileKindResponse(filePath, kind, reason, "edit"), nil
 	}
 
-	modTime := fileInfo.ModTime()
-	lastRead := getLastReadTime(filePath)
-	if modTime.After(lastRead) {
-		return NewTextErrorResponse(
-			fmt.Sprintf("file %s has been modified since it was last read (mod time: %s, last read: %s)",
-				filePath, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339),
-			)), nil
+	if response, ok := checkFileUnchanged(ctx, filePath, "edit"); !ok {
+		return response, nil
 	}
 
 	content, err := os.ReadFile(filePath)
@@ -400,7 +405,9 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 		return ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	oldContent := string(content)
+	oldContent, format := decodeText(content)
+	oldString, _ = format.normalize(oldString)
+	newString, converted := format.normalize(newString)
 
 	index := strings.Index(oldContent, oldString)
 	if index == -1 {
@@ -417,6 +424,10 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 	if oldContent == newContent {
 		return NewTextErrorResponse("new content is the same as old content. No changes made."), nil
 	}
+	data, err := encodeText(newContent, format)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
 	sessionID, messageID := GetContextValues(ctx)
 
 	if sessionID == "" || messageID == "" {
@@ -448,9 +459,18 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 	if !p {
 		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
+	// The file may have been edited while the permission was pending
+	if response, ok := checkFileUnchanged(ctx, filePath, "edit"); !ok {
+		return response, nil
+	}
 
-	err = os.WriteFile(filePath, []byte(newContent), 0o644)
+	snapshot, err := e.files.Snapshot(ctx, sessionID, filePath)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error snapshotting file: %w", err)
+	}
+	err = history.WriteFile(filePath, data, 0o644)
 	if err != nil {
+		e.files.Release(snapshot)
 		return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
 	}
 
@@ -463,24 +483,22 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 			return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
 		}
 	}
+	var historyErrs []error
 	if file.Content != oldContent {
 		// User Manually changed the content store an intermediate version
 		_, err = e.files.CreateVersion(ctx, sessionID, filePath, oldContent)
-		if err != nil {
-			logging.Debug("Error creating file history version", "error", err)
-		}
+		historyErrs = append(historyErrs, err)
 	}
 	// Store the new version
 	_, err = e.files.CreateVersion(ctx, sessionID, filePath, newContent)
-	if err != nil {
-		logging.Debug("Error creating file history version", "error", err)
-	}
+	historyErrs = append(historyErrs, err)
+	history.ReleaseRecorded(e.files, snapshot, errors.Join(historyErrs...))
 
-	recordFileWrite(filePath)
-	recordFileRead(filePath)
+	recordFileWrite(ctx, filePath)
+	recordFileRead(ctx, filePath)
 
 	return WithResponseMetadata(
-		NewTextResponse("Content replaced in file: "+filePath),
+		NewTextResponse(strings.TrimSpace("Content replaced in file: "+filePath+"\n"+formatNote(format, converted))),
 		EditResponseMetadata{
 			Diff:      diff,
 			Additions: additions,
@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	encodingUTF8    = "UTF-8"
+	encodingUTF16LE = "UTF-16LE"
+	encodingUTF16BE = "UTF-16BE"
+	encodingLatin1  = "latin-1"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// textFormat is how a text file is stored. The edit and write tools work on
+// UTF-8 text with LF line endings and store it back in the format of the file,
+// so editing a Windows file doesn't change every line of it.
+type textFormat struct {
+	Encoding string
+	BOM      bool
+	CRLF     bool // Lines end with \r\n
+	Mixed    bool // Both line endings are used, the text is kept as it is
+}
+
+// isDefault reports whether the format is UTF-8 with LF line endings
+func (f textFormat) isDefault() bool {
+	return f.Encoding == encodingUTF8 && !f.BOM && !f.CRLF && !f.Mixed
+}
+
+func (f textFormat) String() string {
+	var parts []string
+	encoding := f.Encoding
+	if f.BOM {
+		encoding += " with BOM"
+	}
+	parts = append(parts, encoding)
+	switch {
+	case f.Mixed:
+		parts = append(parts, "mixed line endings")
+	case f.CRLF:
+		parts = append(parts, "CRLF line endings")
+	default:
+		parts = append(parts, "LF line endings")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// decodeText detects the format of file content and returns it as UTF-8 text
+// with LF line endings, unless the line endings are mixed. Content that isn't
+// valid UTF-8 and has no byte order mark is read as latin-1.
+func decodeText(data []byte) (string, textFormat) {
+	var text string
+	var format textFormat
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		format = textFormat{Encoding: encodingUTF8, BOM: true}
+		text = string(data[len(bomUTF8):])
+	case bytes.HasPrefix(data, bomUTF16LE):
+		format = textFormat{Encoding: encodingUTF16LE, BOM: true}
+		text = decodeUTF16(data[len(bomUTF16LE):], binary.LittleEndian)
+	case bytes.HasPrefix(data, bomUTF16BE):
+		format = textFormat{Encoding: encodingUTF16BE, BOM: true}
+		text = decodeUTF16(data[len(bomUTF16BE):], binary.BigEndian)
+	case utf8.Valid(data):
+		format = textFormat{Encoding: encodingUTF8}
+		text = string(data)
+	default:
+		format = textFormat{Encoding: encodingLatin1}
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		text = string(runes)
+	}
+
+	crlf := strings.Count(text, "\r\n")
+	lf := strings.Count(text, "\n") - crlf
+	switch {
+	case crlf > 0 && lf > 0:
+		format.Mixed = true
+	case crlf > 0:
+		format.CRLF = true
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	return text, format
+}
+
+// normalize converts text written by the agent to the LF line endings of the
+// decoded content. It reports whether the text had CRLF line endings the file
+// doesn't use.
+func (f textFormat) normalize(text string) (string, bool) {
+	if f.Mixed || !strings.Contains(text, "\r\n") {
+		return text, false
+	}
+	return strings.ReplaceAll(text, "\r\n", "\n"), !f.CRLF
+}
+
+// encodeText stores text in a format, it fails for characters latin-1 can't
+// represent
+func encodeText(text string, f textFormat) ([]byte, error) {
+	if f.CRLF && !f.Mixed {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	var buf bytes.Buffer
+	switch f.Encoding {
+	case encodingUTF16LE, encodingUTF16BE:
+		var order binary.ByteOrder = binary.LittleEndian
+		bom := bomUTF16LE
+		if f.Encoding == encodingUTF16BE {
+			order, bom = binary.BigEndian, bomUTF16BE
+		}
+		if f.BOM {
+			buf.Write(bom)
+		}
+		var unit [2]byte
+		for _, u := range utf16.Encode([]rune(text)) {
+			order.PutUint16(unit[:], u)
+			buf.Write(unit[:])
+		}
+	case encodingLatin1:
+		for _, r := range text {
+			if r > 0xFF {
+				return nil, fmt.Errorf("the file is latin-1 encoded and can't store %q, use characters of latin-1 or escape them", r)
+			}
+			buf.WriteByte(byte(r))
+		}
+	default:
+		if f.BOM {
+			buf.Write(bomUTF8)
+		}
+		buf.WriteString(text)
+	}
+	return buf.Bytes(), nil
+}
+
+// formatNote tells the agent how its text was stored, empty for UTF-8 files
+// with LF line endings written as such
+func formatNote(f textFormat, converted bool) string {
+	var notes []string
+	if !f.isDefault() {
+		notes = append(notes, fmt.Sprintf("Kept the file's format: %s.", f))
+	}
+	if converted {
+		notes = append(notes, "The CRLF line endings of your text were converted to the LF line endings of the file.")
+	}
+	return strings.Join(notes, " ")
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
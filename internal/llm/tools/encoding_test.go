@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextFormatRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		text   string
+		format textFormat
+	}{
+		{"utf-8", []byte("a\nb\n"), "a\nb\n", textFormat{Encoding: encodingUTF8}},
+		{"crlf", []byte("a\r\nb\r\n"), "a\nb\n", textFormat{Encoding: encodingUTF8, CRLF: true}},
+		{"bom", []byte("\xef\xbb\xbfa\r\n"), "a\n", textFormat{Encoding: encodingUTF8, BOM: true, CRLF: true}},
+		{"mixed", []byte("a\r\nb\n"), "a\r\nb\n", textFormat{Encoding: encodingUTF8, Mixed: true}},
+		{"latin-1", []byte("caf\xe9\n"), "café\n", textFormat{Encoding: encodingLatin1}},
+		{"utf-16le", []byte{0xFF, 0xFE, 'h', 0, 'i', 0, '\r', 0, '\n', 0}, "hi\n", textFormat{Encoding: encodingUTF16LE, BOM: true, CRLF: true}},
+		{"utf-16be", []byte{0xFE, 0xFF, 0, 'h', 0, 'i'}, "hi", textFormat{Encoding: encodingUTF16BE, BOM: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, format := decodeText(tt.data)
+			assert.Equal(t, tt.text, text)
+			assert.Equal(t, tt.format, format)
+
+			data, err := encodeText(text, format)
+			require.NoError(t, err)
+			assert.Equal(t, tt.data, data)
+		})
+	}
+}
+
+func TestTextFormatEdit(t *testing.T) {
+	text, format := decodeText([]byte("one\r\ntwo\r\n"))
+
+	// Text of the agent with either line ending keeps the file's CRLF
+	newText, converted := format.normalize("zwei\r\ndrei")
+	assert.False(t, converted)
+	data, err := encodeText(text[:4]+newText+"\n", format)
+	require.NoError(t, err)
+	assert.Equal(t, "one\r\nzwei\r\ndrei\r\n", string(data))
+
+	_, format = decodeText([]byte("lf\n"))
+	_, converted = format.normalize("crlf\r\n")
+	assert.True(t, converted)
+	assert.Contains(t, formatNote(format, converted), "converted to the LF line endings")
+}
+
+func TestEncodeLatin1Unrepresentable(t *testing.T) {
+	_, err := encodeText("price: €", textFormat{Encoding: encodingLatin1})
+	assert.Error(t, err)
+}
+
+func TestNeedsDecoding(t *testing.T) {
+	assert.False(t, needsDecoding([]byte("plain text")))
+	assert.True(t, needsDecoding([]byte("\xef\xbb\xbfbom")))
+	assert.True(t, needsDecoding([]byte("caf\xe9 au lait")))
+	// A rune cut at the end of the header is still UTF-8
+	header := []byte(strings.Repeat("a", fileSampleSize-1) + "\xc3")
+	assert.False(t, needsDecoding(header))
+	assert.True(t, needsDecoding([]byte("caf\xc3")))
+}
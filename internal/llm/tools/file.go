@@ -1,53 +1,153 @@
 package tools
 
 import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
 	"sync"
 	"time"
 )
 
-// File record to track when files were read/written
+const (
+	// maxTrackedFiles is how many of the files a session read last keep
+	// their content, to tell the agent how they changed
+	maxTrackedFiles = 20
+	// maxTrackedFileSize is the size of the largest file content kept
+	maxTrackedFileSize = 64 * 1024
+)
+
+// File record to track when a session read/wrote a file and what it saw
 type fileRecord struct {
 	path      string
 	readTime  time.Time
 	writeTime time.Time
+	hash      [sha256.Size]byte // Content when last read or written
+	content   string            // Content when last read, for recent files
+	tracked   bool              // Whether content is kept
+}
+
+// fileKey identifies the record of a file in a session. Sessions keep their
+// own records: a change made by another session is a change made on disk.
+type fileKey struct {
+	sessionID string
+	path      string
+}
+
+// FileConflictMetadata describes a file that changed on disk since the
+// session last read it
+type FileConflictMetadata struct {
+	FilePath   string    `json:"file_path"`
+	LastRead   time.Time `json:"last_read"`
+	ModifiedAt time.Time `json:"modified_at"`
 }
 
 var (
-	fileRecords     = make(map[string]fileRecord)
+	fileRecords     = make(map[fileKey]fileRecord)
 	fileRecordMutex sync.RWMutex
 )
 
-func recordFileRead(path string) {
+func recordFileRead(ctx context.Context, path string) {
+	key := newFileKey(ctx, path)
+	content, err := os.ReadFile(path)
+
 	fileRecordMutex.Lock()
 	defer fileRecordMutex.Unlock()
 
-	record, exists := fileRecords[path]
+	record, exists := fileRecords[key]
 	if !exists {
 		record = fileRecord{path: path}
 	}
 	record.readTime = time.Now()
-	fileRecords[path] = record
-}
-
-func getLastReadTime(path string) time.Time {
-	fileRecordMutex.RLock()
-	defer fileRecordMutex.RUnlock()
-
-	record, exists := fileRecords[path]
-	if !exists {
-		return time.Time{}
+	record.hash = [sha256.Size]byte{}
+	record.content, record.tracked = "", false
+	if err == nil {
+		record.hash = sha256.Sum256(content)
+		if len(content) <= maxTrackedFileSize {
+			record.content, record.tracked = string(content), true
+		}
 	}
-	return record.readTime
+	fileRecords[key] = record
+	untrackOldFiles(key.sessionID)
 }
 
-func recordFileWrite(path string) {
+func recordFileWrite(ctx context.Context, path string) {
+	key := newFileKey(ctx, path)
+
 	fileRecordMutex.Lock()
 	defer fileRecordMutex.Unlock()
 
-	record, exists := fileRecords[path]
+	record, exists := fileRecords[key]
 	if !exists {
 		record = fileRecord{path: path}
 	}
 	record.writeTime = time.Now()
-	fileRecords[path] = record
+	fileRecords[key] = record
+}
+
+// RecordFileWrite records a change made to path for a session outside of its
+// file tools, e.g. by a rollback, so its next edit doesn't see the change as
+// made by someone else. Files the session hasn't read must still be read
+// first.
+func RecordFileWrite(sessionID, path string) {
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, sessionID)
+	fileRecordMutex.RLock()
+	_, exists := fileRecords[newFileKey(ctx, path)]
+	fileRecordMutex.RUnlock()
+	if !exists {
+		return
+	}
+	recordFileWrite(ctx, path)
+	recordFileRead(ctx, path)
+}
+
+// checkFileUnchanged returns an error response when the session hasn't read
+// the file or when its content changed on disk since, e.g. because the user
+// edited it meanwhile. The agent must read it again instead of overwriting
+// those changes.
+func checkFileUnchanged(ctx context.Context, path, action string) (ToolResponse, bool) {
+	fileRecordMutex.RLock()
+	record, exists := fileRecords[newFileKey(ctx, path)]
+	fileRecordMutex.RUnlock()
+
+	if !exists || record.readTime.IsZero() {
+		return NewTextErrorResponse(fmt.Sprintf("you must read the file %s before you %s it. Use the View tool first", path, action)), false
+	}
+	if hashFile(path) == record.hash {
+		return ToolResponse{}, true
+	}
+
+	var modTime time.Time
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+	return WithResponseMetadata(
+		NewTextErrorResponse(fmt.Sprintf(`<file_conflict>
+path: %s
+last_read: %s
+modified: %s
+</file_conflict>
+The file changed on disk since you last read it, probably because the user or another tool edited it. Nothing was changed. Read it again with the View tool and redo your change on its current content, keeping the other changes.`,
+			path, record.readTime.Format(time.RFC3339), modTime.Format(time.RFC3339))),
+		FileConflictMetadata{
+			FilePath:   path,
+			LastRead:   record.readTime,
+			ModifiedAt: modTime,
+		},
+	), false
+}
+
+func newFileKey(ctx context.Context, path string) fileKey {
+	sessionID, _ := GetContextValues(ctx)
+	return fileKey{sessionID: sessionID, path: path}
+}
+
+// hashFile returns the hash of the content of path, zero when it can't be
+// read
+func hashFile(path string) [sha256.Size]byte {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}
+	}
+	return sha256.Sum256(content)
 }
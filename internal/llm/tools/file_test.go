@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckFileUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "session-a")
+	other := context.WithValue(context.Background(), SessionIDContextKey, "session-b")
+
+	response, ok := checkFileUnchanged(ctx, path, "edit")
+	assert.False(t, ok, "the file wasn't read")
+	assert.Contains(t, response.Content, "you must read the file")
+
+	recordFileRead(ctx, path)
+	_, ok = checkFileUnchanged(ctx, path, "edit")
+	assert.True(t, ok)
+
+	// Touching a file doesn't change it
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(path, later, later))
+	_, ok = checkFileUnchanged(ctx, path, "edit")
+	assert.True(t, ok)
+
+	// Another session writes the file and keeps its own record
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nfunc main() {}\n"), 0o644))
+	recordFileWrite(other, path)
+	recordFileRead(other, path)
+	_, ok = checkFileUnchanged(other, path, "edit")
+	assert.True(t, ok)
+
+	// The change is detected even when the modification time is kept
+	require.NoError(t, os.Chtimes(path, later, later))
+	response, ok = checkFileUnchanged(ctx, path, "edit")
+	assert.False(t, ok)
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "<file_conflict>")
+	var metadata FileConflictMetadata
+	require.NoError(t, json.Unmarshal([]byte(response.Metadata), &metadata))
+	assert.Equal(t, path, metadata.FilePath)
+	assert.False(t, metadata.LastRead.IsZero())
+
+	recordFileRead(ctx, path)
+	_, ok = checkFileUnchanged(ctx, path, "edit")
+	assert.True(t, ok, "reading the file again resolves the conflict")
+}
+
+func TestRecordFileWrite(t *testing.T) {
+	dir := t.TempDir()
+	path, unread := filepath.Join(dir, "main.go"), filepath.Join(dir, "other.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+	require.NoError(t, os.WriteFile(unread, []byte("package main\n"), 0o644))
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "session-rollback")
+	recordFileRead(ctx, path)
+
+	// A rollback of the session restores the file
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nfunc main() {}\n"), 0o644))
+	RecordFileWrite("session-rollback", path)
+	_, ok := checkFileUnchanged(ctx, path, "edit")
+	assert.True(t, ok, "the session's own write is no conflict")
+
+	RecordFileWrite("session-rollback", unread)
+	response, ok := checkFileUnchanged(ctx, unread, "edit")
+	assert.False(t, ok)
+	assert.Contains(t, response.Content, "you must read the file", "unread files must still be read")
+}
@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/diff"
+)
+
+// maxUpdateDiffLength is the length of the longest diff shown in a file
+// update, larger changes ask the agent to read the file again
+const maxUpdateDiffLength = 4000
+
+// FileUpdate is a change made on disk to a file a session read, outside of
+// the file tools of the session
+type FileUpdate struct {
+	Path      string
+	Diff      string // Empty when the change is too large to show
+	Additions int
+	Removals  int
+	Deleted   bool
+}
+
+// FileUpdates returns the changes made on disk to the files the session
+// recently read and to its pinned files since the session last saw them.
+// The changes are then considered seen.
+func FileUpdates(sessionID string, pinned []string) []FileUpdate {
+	fileRecordMutex.Lock()
+	defer fileRecordMutex.Unlock()
+
+	var updates []FileUpdate
+	for key, record := range fileRecords {
+		if key.sessionID != sessionID || !record.tracked {
+			continue
+		}
+		content, err := os.ReadFile(record.path)
+		if os.IsNotExist(err) {
+			updates = append(updates, FileUpdate{Path: record.path, Deleted: true})
+			delete(fileRecords, key)
+			continue
+		}
+		if err != nil || sha256.Sum256(content) == record.hash {
+			continue
+		}
+
+		update := FileUpdate{Path: record.path}
+		if len(content) <= maxTrackedFileSize {
+			update.Diff, update.Additions, update.Removals = diff.GenerateDiff(record.content, string(content), record.path)
+		}
+		if update.Diff == "" || len(update.Diff) > maxUpdateDiffLength {
+			// The agent must read the file again before editing it
+			update.Diff = ""
+			record.content, record.tracked = "", false
+		} else {
+			record.readTime = time.Now()
+			record.hash = sha256.Sum256(content)
+			record.content = string(content)
+		}
+		fileRecords[key] = record
+		updates = append(updates, update)
+	}
+
+	// Pinned files are in every prompt, follow their changes from now on
+	for _, path := range pinned {
+		key := fileKey{sessionID: sessionID, path: path}
+		if record, ok := fileRecords[key]; ok && record.tracked {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil || len(content) > maxTrackedFileSize {
+			continue
+		}
+		record := fileRecords[key]
+		record.path = path
+		record.readTime = time.Now()
+		record.hash = sha256.Sum256(content)
+		record.content, record.tracked = string(content), true
+		fileRecords[key] = record
+	}
+	untrackOldFiles(sessionID)
+
+	sort.Slice(updates, func(i, j int) bool {
+		return updates[i].Path < updates[j].Path
+	})
+	return updates
+}
+
+// FileUpdatesNote renders file updates as a note for the agent. It returns
+// an empty string when there are none.
+func FileUpdatesNote(updates []FileUpdate) string {
+	if len(updates) == 0 {
+		return ""
+	}
+	wd := config.WorkingDirectory()
+	var sb strings.Builder
+	sb.WriteString("<file-updates>\nThese files changed on disk since you last read them, e.g. because the user edited them. Work from their current content.\n")
+	for _, update := range updates {
+		path := relativePath(update.Path, wd)
+		switch {
+		case update.Deleted:
+			fmt.Fprintf(&sb, "\n# %s was deleted\n", path)
+		case update.Diff == "":
+			fmt.Fprintf(&sb, "\n# %s changed too much to show, read it again before relying on it\n", path)
+		default:
+			fmt.Fprintf(&sb, "\n# %s (+%d -%d)\n```diff\n%s\n```\n", path, update.Additions, update.Removals, strings.TrimRight(update.Diff, "\n"))
+		}
+	}
+	sb.WriteString("</file-updates>")
+	return sb.String()
+}
+
+// untrackOldFiles drops the content of the files a session read before its
+// last maxTrackedFiles files. Callers hold fileRecordMutex.
+func untrackOldFiles(sessionID string) {
+	var tracked []fileKey
+	for key, record := range fileRecords {
+		if key.sessionID == sessionID && record.tracked {
+			tracked = append(tracked, key)
+		}
+	}
+	if len(tracked) <= maxTrackedFiles {
+		return
+	}
+	sort.Slice(tracked, func(i, j int) bool {
+		return fileRecords[tracked[i]].readTime.After(fileRecords[tracked[j]].readTime)
+	})
+	for _, key := range tracked[maxTrackedFiles:] {
+		record := fileRecords[key]
+		record.content, record.tracked = "", false
+		fileRecords[key] = record
+	}
+}
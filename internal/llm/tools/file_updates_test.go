@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useWorkingDir points the working directory of the config at dir until the
+// test ends. The config is loaded once per process, the first test to load it
+// sets its working directory for all the others.
+func useWorkingDir(t *testing.T, dir string) *config.Config {
+	t.Helper()
+	_, err := config.Load(dir, false)
+	require.NoError(t, err)
+	cfg := config.Get()
+	previous := cfg.WorkingDir
+	cfg.WorkingDir = dir
+	t.Cleanup(func() { cfg.WorkingDir = previous })
+	return cfg
+}
+
+func TestFileUpdates(t *testing.T) {
+	dir := t.TempDir()
+	useWorkingDir(t, dir)
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "updates")
+
+	read := filepath.Join(dir, "main.go")
+	pinned := filepath.Join(dir, "NOTES.md")
+	removed := filepath.Join(dir, "old.go")
+	require.NoError(t, os.WriteFile(read, []byte("package main\n\nfunc main() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(pinned, []byte("use tabs\n"), 0o644))
+	require.NoError(t, os.WriteFile(removed, []byte("package main\n"), 0o644))
+	recordFileRead(ctx, read)
+	recordFileRead(ctx, removed)
+
+	assert.Empty(t, FileUpdates("updates", []string{pinned}), "nothing changed yet")
+
+	require.NoError(t, os.WriteFile(read, []byte("package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"), 0o644))
+	require.NoError(t, os.WriteFile(pinned, []byte("use spaces\n"), 0o644))
+	require.NoError(t, os.Remove(removed))
+
+	updates := FileUpdates("updates", []string{pinned})
+	require.Len(t, updates, 3)
+	assert.Equal(t, pinned, updates[0].Path)
+	assert.Equal(t, read, updates[1].Path)
+	assert.Positive(t, updates[1].Additions)
+	assert.Contains(t, updates[1].Diff, "println")
+	assert.True(t, updates[2].Deleted)
+
+	note := FileUpdatesNote(updates)
+	assert.Contains(t, note, "# main.go (+")
+	assert.Contains(t, note, "# old.go was deleted")
+	assert.Empty(t, FileUpdatesNote(nil))
+
+	assert.Empty(t, FileUpdates("updates", []string{pinned}), "the updates were seen")
+	_, ok := checkFileUnchanged(ctx, read, "edit")
+	assert.True(t, ok, "the agent saw the change")
+
+	// Large changes ask for a new read
+	require.NoError(t, os.WriteFile(read, []byte(strings.Repeat("// comment\n", 1000)), 0o644))
+	updates = FileUpdates("updates", nil)
+	require.Len(t, updates, 1)
+	assert.Empty(t, updates[0].Diff)
+	_, ok = checkFileUnchanged(ctx, read, "edit")
+	assert.False(t, ok)
+}
+
+func TestUntrackOldFiles(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "untrack")
+	for i := range maxTrackedFiles + 2 {
+		path := filepath.Join(dir, fmt.Sprintf("file%d.go", i))
+		require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+		recordFileRead(ctx, path)
+	}
+
+	fileRecordMutex.RLock()
+	defer fileRecordMutex.RUnlock()
+	tracked := 0
+	for key, record := range fileRecords {
+		if key.sessionID == "untrack" && record.tracked {
+			tracked++
+		}
+	}
+	assert.Equal(t, maxTrackedFiles, tracked)
+	assert.False(t, fileRecords[fileKey{"untrack", filepath.Join(dir, "file0.go")}].tracked, "the oldest file isn't tracked")
+}
@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+// FileKind classifies the files the file tools don't handle like source files
+type FileKind string
+
+const (
+	FileKindSource    FileKind = ""
+	FileKindBinary    FileKind = "binary"
+	FileKindMinified  FileKind = "minified"
+	FileKindLockfile  FileKind = "lockfile"
+	FileKindGenerated FileKind = "generated"
+)
+
+const (
+	// fileSampleSize is how much of a file is looked at to classify it
+	fileSampleSize = 64 * 1024
+	// binarySampleSize is the prefix searched for NUL bytes, like git does
+	binarySampleSize = 8000
+	// defaultMinifiedLineLength applies without a configured length
+	defaultMinifiedLineLength = 1000
+)
+
+func (k FileKind) noun() string {
+	if k == FileKindLockfile {
+		return "lockfile"
+	}
+	return string(k) + " file"
+}
+
+// FileKindMetadata is the metadata of a response refusing a file for its kind
+type FileKindMetadata struct {
+	FilePath string   `json:"file_path"`
+	Kind     FileKind `json:"kind"`
+	Reason   string   `json:"reason"`
+	Size     int64    `json:"size"`
+}
+
+// detectFileKind classifies a file by the configured lockfile and generated
+// patterns and by a sample of its content. The reason explains the kind.
+func detectFileKind(path string) (FileKind, string) {
+	var cfg config.FileDetectionConfig
+	if c := config.Get(); c != nil {
+		cfg = c.FileDetection
+	}
+	if pattern, ok := matchFilePattern(cfg.Lockfiles, path); ok {
+		return FileKindLockfile, fmt.Sprintf("matches the lockfile pattern %q", pattern)
+	}
+	if pattern, ok := matchFilePattern(cfg.Generated, path); ok {
+		return FileKindGenerated, fmt.Sprintf("matches the generated file pattern %q", pattern)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return FileKindSource, ""
+	}
+	defer f.Close()
+	sample := make([]byte, fileSampleSize)
+	n, _ := io.ReadFull(f, sample)
+	sample = sample[:n]
+
+	if reason := binaryReason(sample); reason != "" {
+		return FileKindBinary, reason
+	}
+	minLength := cfg.MinifiedLineLength
+	if minLength <= 0 {
+		minLength = defaultMinifiedLineLength
+	}
+	if n > minLength {
+		lines := bytes.Count(sample, []byte("\n")) + 1
+		if avg := n / lines; avg > minLength {
+			return FileKindMinified, fmt.Sprintf("lines are %d characters long on average", avg)
+		}
+	}
+	return FileKindSource, ""
+}
+
+// binaryReason returns why a sample looks binary, empty for text. UTF-16 text
+// is recognized by its byte order mark.
+func binaryReason(sample []byte) string {
+	if bytes.HasPrefix(sample, []byte{0xFF, 0xFE}) || bytes.HasPrefix(sample, []byte{0xFE, 0xFF}) {
+		return ""
+	}
+	if bytes.IndexByte(sample[:min(len(sample), binarySampleSize)], 0) >= 0 {
+		return "it contains NUL bytes"
+	}
+	if utf8.Valid(sample) {
+		return ""
+	}
+	// Latin-1 text is invalid UTF-8 too, but has few control characters
+	control := 0
+	for _, b := range sample {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' && b != 0x1b {
+			control++
+		}
+	}
+	if control*10 > len(sample) {
+		return "it is not valid UTF-8 and has many control characters"
+	}
+	return ""
+}
+
+// matchFilePattern matches a path against glob patterns of file names and of
+// paths relative to the working directory
+func matchFilePattern(patterns []string, path string) (string, bool) {
+	if len(patterns) == 0 {
+		return "", false
+	}
+	rel := path
+	if r, err := filepath.Rel(config.WorkingDirectory(), path); err == nil {
+		rel = r
+	}
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
+			return pattern, true
+		}
+		if matched, _ := filepath.Match(pattern, filepath.ToSlash(rel)); matched {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// fileKindResponse explains to the agent why a tool won't handle a file and
+// what to do instead. action is "read" or "edit".
+func fileKindResponse(path string, kind FileKind, reason, action string) ToolResponse {
+	var advice string
+	switch {
+	case kind == FileKindBinary && action == "edit":
+		advice = "Binary files can't be edited as text."
+	case kind == FileKindBinary:
+		advice = "Its content can't be shown as text. If you need to inspect it, use bash with a tool for its format, e.g. file, xxd or sqlite3."
+	case kind == FileKindMinified && action == "edit":
+		advice = "Edit the source it is built from and rebuild it instead."
+	case kind == FileKindMinified:
+		advice = "Reading it would fill the context with a few huge lines. Search it with grep, passing the file as path, or read the source it is built from."
+	case kind == FileKindLockfile && action == "edit":
+		advice = "Update it with the package manager instead, e.g. by changing the manifest and running the install command."
+	case kind == FileKindLockfile:
+		advice = "Find the dependency you need with grep, passing the file as path, or read a range with start_line and end_line."
+	case action == "edit":
+		advice = "Change the source it is generated from and regenerate it instead."
+	default:
+		advice = "Read the source it is generated from, look up what you need with grep, or read a range with start_line and end_line or a symbol."
+	}
+
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	return WithResponseMetadata(
+		NewTextErrorResponse(fmt.Sprintf("%s is a %s (%s). %s", path, kind.noun(), reason, advice)),
+		FileKindMetadata{
+			FilePath: path,
+			Kind:     kind,
+			Reason:   reason,
+			Size:     size,
+		},
+	)
+}
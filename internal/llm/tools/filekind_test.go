@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFileKind(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, content, 0o644))
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+		kind    FileKind
+	}{
+		{"main.go", []byte("package main\n\nfunc main() {}\n"), FileKindSource},
+		{"image.bin", []byte{0x89, 'P', 'N', 'G', 0x00, 0x01, 0x02}, FileKindBinary},
+		{"utf16.txt", []byte{0xFF, 0xFE, 'h', 0x00, 'i', 0x00}, FileKindSource},
+		{"latin1.txt", []byte("caf\xe9 cr\xe8me\n"), FileKindSource},
+		{"app.js", []byte(strings.Repeat("var a=1;", 500) + "\n" + strings.Repeat("b();", 500)), FileKindMinified},
+		{"empty.txt", nil, FileKindSource},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, reason := detectFileKind(write(tt.name, tt.content))
+			assert.Equal(t, tt.kind, kind)
+			assert.Equal(t, kind == FileKindSource, reason == "")
+		})
+	}
+}
+
+func TestFileKindResponse(t *testing.T) {
+	resp := fileKindResponse("/repo/go.sum", FileKindLockfile, `matches the lockfile pattern "go.sum"`, "edit")
+	assert.True(t, resp.IsError)
+	assert.Contains(t, resp.Content, "/repo/go.sum is a lockfile")
+	assert.Contains(t, resp.Content, "package manager")
+	assert.Contains(t, resp.Metadata, `"kind":"lockfile"`)
+}
@@ -0,0 +1,380 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/permission"
+)
+
+type FlakyTestParams struct {
+	Command       string `json:"command"`
+	Runs          int    `json:"runs"`
+	Parallel      int    `json:"parallel"`
+	BisectCommits int    `json:"bisect_commits"`
+	Timeout       int    `json:"timeout"`
+}
+
+type FlakyTestPermissionsParams struct {
+	Command       string `json:"command"`
+	Runs          int    `json:"runs"`
+	BisectCommits int    `json:"bisect_commits"`
+}
+
+type flakyTestTool struct {
+	permissions permission.Service
+}
+
+const (
+	FlakyTestToolName = "flaky_test"
+
+	defaultFlakyRuns     = 20
+	maxFlakyRuns         = 200
+	defaultFlakyParallel = 4
+	maxFlakyParallel     = 16
+	maxBisectCommits     = 50
+	defaultFlakyTimeout  = 300 // Seconds per run
+	maxFailureSamples    = 3
+
+	flakyTestDescription = `Runs a single test many times in parallel to find out whether it is flaky, and optionally bisects the recent commits for the one that made it flaky.
+
+WHEN TO USE THIS TOOL:
+- Use when a test fails intermittently, or to check whether a test failure is reproducible
+- Use before quarantining or "fixing" a test that only sometimes fails
+
+HOW TO USE:
+- Provide the command that runs only the test in question, e.g. "go test -run '^TestStore$' -count=1 ./internal/store" or "pytest tests/test_store.py::test_get"
+- For Go, always pass -count=1 so results are not cached
+- Set bisect_commits to search the last commits for the one that introduced the failures
+
+OUTPUT:
+- The pass rate, run durations and samples of the distinct failures
+- With bisection, the first commit at which the test failed. A commit counts as good when all of its runs passed, so increase runs for rare failures
+- Suggestions to fix or quarantine the test
+
+LIMITATIONS:
+- Bisection checks out the commits into temporary git worktrees, uncommitted changes are not included
+- Runs share the machine, so failures caused by load only show up with parallel runs
+`
+)
+
+// durationPattern matches the durations test runners print, e.g. "(0.01s)"
+var durationPattern = regexp.MustCompile(`\(\d+(?:\.\d+)?[µnm]?s\)`)
+
+// flakyStats summarizes the runs of a test command
+type flakyStats struct {
+	Runs     int
+	Passed   int
+	Total    time.Duration
+	Min, Max time.Duration
+	Failures map[string]int // Failure sample -> count
+}
+
+func (s flakyStats) PassRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Passed) / float64(s.Runs)
+}
+
+func NewFlakyTestTool(permissions permission.Service) BaseTool {
+	return &flakyTestTool{permissions: permissions}
+}
+
+func (f *flakyTestTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        FlakyTestToolName,
+		Description: flakyTestDescription,
+		Parameters: map[string]any{
+			"command": map[string]any{
+				"type":        "string",
+				"description": "The command running only the test to check",
+			},
+			"runs": map[string]any{
+				"type":        "number",
+				"description": fmt.Sprintf("How often to run the test (default %d, max %d)", defaultFlakyRuns, maxFlakyRuns),
+			},
+			"parallel": map[string]any{
+				"type":        "number",
+				"description": fmt.Sprintf("How many runs at the same time (default %d, max %d)", defaultFlakyParallel, maxFlakyParallel),
+			},
+			"bisect_commits": map[string]any{
+				"type":        "number",
+				"description": fmt.Sprintf("Number of recent commits to bisect when the test fails (default 0, max %d)", maxBisectCommits),
+			},
+			"timeout": map[string]any{
+				"type":        "number",
+				"description": fmt.Sprintf("Timeout of a single run in seconds (default %d)", defaultFlakyTimeout),
+			},
+		},
+		Required: []string{"command"},
+	}
+}
+
+func (f *flakyTestTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params FlakyTestParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if strings.TrimSpace(params.Command) == "" {
+		return NewTextErrorResponse("command is required"), nil
+	}
+	if params.Runs <= 0 {
+		params.Runs = defaultFlakyRuns
+	}
+	params.Runs = min(params.Runs, maxFlakyRuns)
+	if params.Parallel <= 0 {
+		params.Parallel = defaultFlakyParallel
+	}
+	params.Parallel = min(params.Parallel, maxFlakyParallel, params.Runs)
+	params.BisectCommits = min(max(params.BisectCommits, 0), maxBisectCommits)
+	if params.Timeout <= 0 {
+		params.Timeout = defaultFlakyTimeout
+	}
+	timeout := time.Duration(params.Timeout) * time.Second
+
+	sessionID, _ := GetContextValues(ctx)
+	if sessionID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID is required to run tests")
+	}
+	description := fmt.Sprintf("Run %d times: %s", params.Runs, params.Command)
+	if params.BisectCommits > 0 {
+		description += fmt.Sprintf(", bisecting the last %d commits in temporary worktrees", params.BisectCommits)
+	}
+	if !f.permissions.Request(permission.CreatePermissionRequest{
+		SessionID:   sessionID,
+		Path:        config.WorkingDirectory(),
+		ToolName:    FlakyTestToolName,
+		Action:      "execute",
+		Description: description,
+		Params: FlakyTestPermissionsParams{
+			Command:       params.Command,
+			Runs:          params.Runs,
+			BisectCommits: params.BisectCommits,
+		},
+	}) {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+
+	dir := config.WorkingDirectory()
+	stats := runRepeated(ctx, dir, params.Command, params.Runs, params.Parallel, timeout)
+	if ctx.Err() != nil {
+		return ToolResponse{}, ctx.Err()
+	}
+
+	var sb strings.Builder
+	sb.WriteString(formatFlakyStats(stats))
+	if params.BisectCommits > 0 && stats.Passed < stats.Runs {
+		sb.WriteString("\n")
+		sb.WriteString(bisectFlaky(ctx, dir, params.Command, params.BisectCommits, params.Runs, params.Parallel, timeout))
+	}
+	sb.WriteString("\n")
+	sb.WriteString(flakySuggestions(params.Command, stats))
+	return NewTextResponse(sb.String()), nil
+}
+
+// runRepeated runs command runs times, at most parallel at once
+func runRepeated(ctx context.Context, dir, command string, runs, parallel int, timeout time.Duration) flakyStats {
+	shellPath := commandShell()
+
+	stats := flakyStats{Failures: make(map[string]int)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallel)
+	for range runs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return stats
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			runCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			cmd := exec.CommandContext(runCtx, shellPath, "-c", command)
+			cmd.Dir = dir
+			started := time.Now()
+			out, err := cmd.CombinedOutput()
+			took := time.Since(started)
+
+			mu.Lock()
+			defer mu.Unlock()
+			stats.Runs++
+			stats.Total += took
+			if stats.Min == 0 || took < stats.Min {
+				stats.Min = took
+			}
+			stats.Max = max(stats.Max, took)
+			if err == nil {
+				stats.Passed++
+				return
+			}
+			sample := failureSample(string(out))
+			if runCtx.Err() == context.DeadlineExceeded {
+				sample = fmt.Sprintf("timed out after %s", timeout)
+			}
+			stats.Failures[sample]++
+		}()
+	}
+	wg.Wait()
+	return stats
+}
+
+// commandShell returns the configured shell for running commands
+func commandShell() string {
+	if cfg := config.Get(); cfg != nil && cfg.Shell.Path != "" {
+		return cfg.Shell.Path
+	}
+	return "/bin/bash"
+}
+
+// failureSample picks the lines identifying a failure, e.g. the failed
+// assertion, so identical failures are counted together
+func failureSample(output string) string {
+	var picked []string
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		lower := strings.ToLower(trimmed)
+		if strings.HasPrefix(trimmed, "--- FAIL") || strings.Contains(lower, "error") || strings.Contains(lower, "assert") || strings.Contains(lower, "panic") {
+			picked = append(picked, trimmed)
+		}
+		if len(picked) == 3 {
+			break
+		}
+	}
+	if len(picked) == 0 {
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		picked = lines[max(0, len(lines)-3):]
+	}
+	sample := strings.Join(picked, "\n")
+	// Durations differ between runs of the same failure
+	return durationPattern.ReplaceAllString(sample, "(…)")
+}
+
+func formatFlakyStats(stats flakyStats) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Passed %d of %d runs (%.0f%%)\n", stats.Passed, stats.Runs, stats.PassRate()*100)
+	if stats.Runs > 0 {
+		fmt.Fprintf(&sb, "Duration: avg %s, min %s, max %s\n",
+			(stats.Total / time.Duration(stats.Runs)).Round(time.Millisecond), stats.Min.Round(time.Millisecond), stats.Max.Round(time.Millisecond))
+	}
+	if len(stats.Failures) > 0 {
+		fmt.Fprintf(&sb, "\n%d distinct failure(s):\n", len(stats.Failures))
+		samples := make([]string, 0, len(stats.Failures))
+		for sample := range stats.Failures {
+			samples = append(samples, sample)
+		}
+		sort.Slice(samples, func(i, j int) bool {
+			return stats.Failures[samples[i]] > stats.Failures[samples[j]]
+		})
+		for i, sample := range samples {
+			if i == maxFailureSamples {
+				fmt.Fprintf(&sb, "\n... and %d more\n", len(samples)-i)
+				break
+			}
+			fmt.Fprintf(&sb, "\n[%dx]\n%s\n", stats.Failures[sample], sample)
+		}
+	}
+	return sb.String()
+}
+
+// bisectFlaky searches the last commits for the first one at which the test
+// fails. A commit is good when all runs pass at it.
+func bisectFlaky(ctx context.Context, dir, command string, commits, runs, parallel int, timeout time.Duration) string {
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-list", "--first-parent", "-n", fmt.Sprint(commits+1), "HEAD").Output()
+	if err != nil {
+		return fmt.Sprintf("Bisection failed: git rev-list: %v\n", err)
+	}
+	revs := strings.Fields(string(out))
+	if len(revs) < 2 {
+		return "Bisection skipped: not enough commits\n"
+	}
+
+	failsAt := func(rev string) (bool, error) {
+		worktree, err := os.MkdirTemp("", "opencode-bisect-*")
+		if err != nil {
+			return false, err
+		}
+		defer os.RemoveAll(worktree)
+		if out, err := exec.CommandContext(ctx, "git", "-C", dir, "worktree", "add", "--detach", worktree, rev).CombinedOutput(); err != nil {
+			return false, fmt.Errorf("git worktree add: %s", strings.TrimSpace(string(out)))
+		}
+		defer exec.Command("git", "-C", dir, "worktree", "remove", "--force", worktree).Run()
+		stats := runRepeated(ctx, worktree, command, runs, parallel, timeout)
+		return stats.Passed < stats.Runs, ctx.Err()
+	}
+
+	// revs[0] is HEAD, where the test fails; look for the newest good commit
+	bad, good := 0, len(revs)-1
+	fails, err := failsAt(revs[good])
+	if err != nil {
+		return fmt.Sprintf("Bisection failed: %v\n", err)
+	}
+	if fails {
+		return fmt.Sprintf("Bisection: the test already fails at %s, %d commits back. It was broken before the bisected range.\n", shortRev(revs[good]), good)
+	}
+	for good-bad > 1 {
+		mid := (bad + good) / 2
+		fails, err := failsAt(revs[mid])
+		if err != nil {
+			return fmt.Sprintf("Bisection failed: %v\n", err)
+		}
+		if fails {
+			bad = mid
+		} else {
+			good = mid
+		}
+	}
+	subject, _ := exec.CommandContext(ctx, "git", "-C", dir, "log", "-1", "--format=%h %s", revs[bad]).Output()
+	return fmt.Sprintf("Bisection: the failures start at %s (all %d runs passed at its parent %s)\n", strings.TrimSpace(string(subject)), runs, shortRev(revs[good]))
+}
+
+func flakySuggestions(command string, stats flakyStats) string {
+	switch {
+	case stats.Runs == 0:
+		return "No run finished."
+	case stats.Passed == stats.Runs:
+		return "The test passed every run. If it fails elsewhere, it may depend on the environment, the test order or the load: try more runs, more parallel runs or a shuffled order (go test -shuffle=on, pytest -p random_order)."
+	case stats.Passed == 0:
+		return "The test failed every run, it is broken rather than flaky. Fix the test or the code instead of quarantining it."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("The test is flaky. Common causes to check:\n")
+	sb.WriteString("- timing: sleeps, timeouts or polling that are too tight, especially under parallel load\n")
+	sb.WriteString("- shared state: globals, files, ports or database rows used by other tests\n")
+	sb.WriteString("- order: map iteration, goroutine scheduling or test order the test silently depends on\n")
+	sb.WriteString("- external services: network calls or the clock\n")
+	sb.WriteString("\nFix the cause if you can. Otherwise quarantine the test with a reference to a tracking issue: ")
+	switch {
+	case strings.Contains(command, "go test"):
+		sb.WriteString(`t.Skip("flaky, see <issue>") at the top of the test.`)
+	case strings.Contains(command, "pytest"):
+		sb.WriteString(`@pytest.mark.skip(reason="flaky, see <issue>"), or @pytest.mark.flaky(reruns=3) with pytest-rerunfailures.`)
+	case strings.Contains(command, "jest"), strings.Contains(command, "vitest"), strings.Contains(command, "npm"):
+		sb.WriteString("test.skip(...) with a comment pointing to the issue.")
+	default:
+		sb.WriteString("the skip mechanism of the test framework.")
+	}
+	return sb.String()
+}
+
+func shortRev(rev string) string {
+	if len(rev) > 7 {
+		return rev[:7]
+	}
+	return rev
+}
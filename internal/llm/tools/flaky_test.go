@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunRepeated(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	stats := runRepeated(ctx, dir, "exit 0", 5, 2, time.Minute)
+	assert.Equal(t, 5, stats.Runs)
+	assert.Equal(t, 5, stats.Passed)
+	assert.Empty(t, stats.Failures)
+
+	stats = runRepeated(ctx, dir, "echo '--- FAIL: TestX (0.01s)'; exit 1", 4, 4, time.Minute)
+	assert.Equal(t, 0, stats.Passed)
+	assert.Equal(t, map[string]int{"--- FAIL: TestX (…)": 4}, stats.Failures)
+
+	// Fails every other run
+	stats = runRepeated(ctx, dir, `n=$(cat c 2>/dev/null || echo 0); echo $((n+1)) > c; [ $((n % 2)) -eq 0 ]`, 6, 1, time.Minute)
+	assert.Equal(t, 3, stats.Passed)
+	assert.InDelta(t, 0.5, stats.PassRate(), 0.001)
+}
+
+func TestBisectFlaky(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	for _, name := range []string{"a", "b", "c", "broken", "d", "e"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644))
+		git("add", name)
+		git("commit", "-q", "-m", "add "+name)
+	}
+
+	result := bisectFlaky(context.Background(), dir, "test ! -f broken", 5, 2, 2, time.Minute)
+	assert.True(t, strings.Contains(result, "add broken"), result)
+}
@@ -0,0 +1,363 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+type GitHistoryParams struct {
+	Path      string `json:"path"`
+	Mode      string `json:"mode"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Function  string `json:"function"`
+	Limit     int    `json:"limit"`
+	Diff      bool   `json:"diff"`
+}
+
+type gitHistoryTool struct{}
+
+const (
+	GitHistoryToolName = "git_history"
+
+	defaultHistoryLimit  = 10
+	maxHistoryLimit      = 50
+	maxHistoryDiffLines  = 40 // Per commit
+	maxHistoryBodyLines  = 3
+	gitHistoryTimeout    = 30 * time.Second
+	uncommittedBlameHash = "0000000000000000000000000000000000000000"
+
+	gitHistoryDescription = `Shows who changed code, when and why, from the git history of a file.
+
+WHEN TO USE THIS TOOL:
+- Use to answer "why is this code like this?" or "when did this change?" with the actual commits instead of guessing
+- Use before changing code that looks wrong on purpose, to find the commit and its reasoning
+
+HOW TO USE:
+- mode "blame": the last commit of each line in start_line..end_line (or the whole file), grouped into ranges
+- mode "log": the commits that changed the file, or only the lines start_line..end_line, or a function by name
+- The default mode is blame for a line range and log otherwise
+- Set diff to include what each commit changed in log mode; the diffs of line ranges and functions are always included
+- limit caps the number of commits (default 10, max 50)
+
+OUTPUT:
+- Blame: "lines  commit  date  author  subject" per range of lines
+- Log: "commit  date  author  subject", the start of the commit message, and the compacted diff
+
+Cite the commit hash and subject when you explain a change to the user.
+`
+)
+
+// blameLine is a line of git blame --porcelain output
+type blameLine struct {
+	Hash string
+	Line int
+}
+
+// blameCommit is the commit information of git blame --porcelain output
+type blameCommit struct {
+	Author  string
+	Time    time.Time
+	Summary string
+}
+
+// historyCommit is a commit of the git log output
+type historyCommit struct {
+	Hash    string
+	Author  string
+	Date    string
+	Subject string
+	Body    string
+	Diff    string
+}
+
+func NewGitHistoryTool() BaseTool {
+	return &gitHistoryTool{}
+}
+
+func (g *gitHistoryTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        GitHistoryToolName,
+		Description: gitHistoryDescription,
+		Parameters: map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "The file to show the history of",
+			},
+			"mode": map[string]any{
+				"type":        "string",
+				"enum":        []string{"blame", "log"},
+				"description": "blame for the last commit of each line, log for the commits over time",
+			},
+			"start_line": map[string]any{
+				"type":        "number",
+				"description": "First line of the range (1-based)",
+			},
+			"end_line": map[string]any{
+				"type":        "number",
+				"description": "Last line of the range, defaults to start_line",
+			},
+			"function": map[string]any{
+				"type":        "string",
+				"description": "Name of a function to show the log of, instead of a line range",
+			},
+			"limit": map[string]any{
+				"type":        "number",
+				"description": fmt.Sprintf("Maximum number of commits (default %d, max %d)", defaultHistoryLimit, maxHistoryLimit),
+			},
+			"diff": map[string]any{
+				"type":        "boolean",
+				"description": "Include the changes of each commit in log mode",
+			},
+		},
+		Required: []string{"path"},
+	}
+}
+
+func (g *gitHistoryTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params GitHistoryParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.Path == "" {
+		return NewTextErrorResponse("path is required"), nil
+	}
+	if params.StartLine < 0 || params.EndLine < 0 {
+		return NewTextErrorResponse("line numbers start at 1"), nil
+	}
+	if params.EndLine == 0 {
+		params.EndLine = params.StartLine
+	}
+	if params.EndLine < params.StartLine {
+		return NewTextErrorResponse("end_line is before start_line"), nil
+	}
+	if params.Limit <= 0 {
+		params.Limit = defaultHistoryLimit
+	}
+	params.Limit = min(params.Limit, maxHistoryLimit)
+	if params.Mode == "" {
+		params.Mode = "log"
+		if params.StartLine > 0 && params.Function == "" {
+			params.Mode = "blame"
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, gitHistoryTimeout)
+	defer cancel()
+	wd := config.WorkingDirectory()
+	path := relativePath(absolutePath(params.Path, wd), wd)
+
+	switch params.Mode {
+	case "blame":
+		if params.Function != "" {
+			return NewTextErrorResponse("blame takes a line range, use log mode for a function"), nil
+		}
+		args := []string{"blame", "--porcelain"}
+		if params.StartLine > 0 {
+			args = append(args, "-L", fmt.Sprintf("%d,%d", params.StartLine, params.EndLine))
+		}
+		out, err := runGit(ctx, wd, append(args, "--", path)...)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
+		}
+		lines, commits := parseBlamePorcelain(out)
+		if len(lines) == 0 {
+			return NewTextResponse("No blame information"), nil
+		}
+		return NewTextResponse(truncateOutput(formatBlame(lines, commits))), nil
+	case "log":
+		args := []string{"log", "-n", strconv.Itoa(params.Limit), "--date=short", "--format=%x1e%H%x1f%an%x1f%ad%x1f%s%x1f%b%x1d"}
+		switch {
+		case params.Function != "":
+			args = append(args, fmt.Sprintf("-L:%s:%s", params.Function, path))
+		case params.StartLine > 0:
+			args = append(args, fmt.Sprintf("-L%d,%d:%s", params.StartLine, params.EndLine, path))
+		default:
+			if params.Diff {
+				args = append(args, "-p")
+			}
+			args = append(args, "--follow", "--", path)
+		}
+		out, err := runGit(ctx, wd, args...)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
+		}
+		commits := parseGitLog(out)
+		if len(commits) == 0 {
+			return NewTextResponse(fmt.Sprintf("No commits changed %s", path)), nil
+		}
+		return NewTextResponse(truncateOutput(formatHistory(commits))), nil
+	default:
+		return NewTextErrorResponse(fmt.Sprintf("unknown mode %q, use blame or log", params.Mode)), nil
+	}
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s failed: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+// parseBlamePorcelain reads the output of git blame --porcelain, the commit
+// information is only printed for the first line of each commit
+func parseBlamePorcelain(out string) ([]blameLine, map[string]blameCommit) {
+	var lines []blameLine
+	commits := make(map[string]blameCommit)
+	var current string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "\t") {
+			continue // The content of the line
+		}
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && len(fields[0]) == 40 {
+			if final, err := strconv.Atoi(fields[2]); err == nil {
+				current = fields[0]
+				lines = append(lines, blameLine{Hash: current, Line: final})
+				continue
+			}
+		}
+		key, value, _ := strings.Cut(line, " ")
+		commit := commits[current]
+		switch key {
+		case "author":
+			commit.Author = value
+		case "author-time":
+			if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+				commit.Time = time.Unix(sec, 0).UTC()
+			}
+		case "summary":
+			commit.Summary = value
+		default:
+			continue
+		}
+		commits[current] = commit
+	}
+	return lines, commits
+}
+
+// formatBlame groups consecutive lines of the same commit into ranges
+func formatBlame(lines []blameLine, commits map[string]blameCommit) string {
+	var sb strings.Builder
+	for start := 0; start < len(lines); {
+		end := start
+		for end+1 < len(lines) && lines[end+1].Hash == lines[start].Hash && lines[end+1].Line == lines[end].Line+1 {
+			end++
+		}
+		span := strconv.Itoa(lines[start].Line)
+		if end > start {
+			span += "-" + strconv.Itoa(lines[end].Line)
+		}
+		hash := lines[start].Hash
+		if hash == uncommittedBlameHash {
+			fmt.Fprintf(&sb, "%-9s  not committed yet\n", span)
+		} else {
+			commit := commits[hash]
+			fmt.Fprintf(&sb, "%-9s  %s  %s  %s  %s\n", span, hash[:7], commit.Time.Format("2006-01-02"), commit.Author, commit.Summary)
+		}
+		start = end + 1
+	}
+	return sb.String()
+}
+
+// parseGitLog reads the records of the git log format of the tool, the diff
+// follows the record terminator
+func parseGitLog(out string) []historyCommit {
+	var commits []historyCommit
+	for _, record := range strings.Split(out, "\x1e") {
+		header, diff, _ := strings.Cut(record, "\x1d")
+		fields := strings.SplitN(header, "\x1f", 5)
+		if len(fields) < 5 {
+			continue
+		}
+		commits = append(commits, historyCommit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Date:    fields[2],
+			Subject: fields[3],
+			Body:    fields[4],
+			Diff:    compactDiff(diff),
+		})
+	}
+	return commits
+}
+
+// compactDiff keeps the hunk headers and changed lines of a diff
+func compactDiff(diff string) string {
+	var kept []string
+	skipped := 0
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "---") {
+			continue
+		}
+		if !strings.HasPrefix(line, "@@") && !strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "-") {
+			continue
+		}
+		if len(kept) >= maxHistoryDiffLines {
+			skipped++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if skipped > 0 {
+		kept = append(kept, fmt.Sprintf("... %d more changed lines", skipped))
+	}
+	return strings.Join(kept, "\n")
+}
+
+// commitBody returns the first lines of a commit message body, without
+// trailers like Signed-off-by
+func commitBody(body string) []string {
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || isTrailer(line) {
+			continue
+		}
+		if len(lines) == maxHistoryBodyLines {
+			lines = append(lines, "...")
+			break
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func isTrailer(line string) bool {
+	key, _, ok := strings.Cut(line, ": ")
+	return ok && !strings.Contains(key, " ") && strings.Contains(key, "-")
+}
+
+func formatHistory(commits []historyCommit) string {
+	var sb strings.Builder
+	for i, commit := range commits {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "%s  %s  %s  %s\n", commit.Hash[:min(7, len(commit.Hash))], commit.Date, commit.Author, commit.Subject)
+		for _, line := range commitBody(commit.Body) {
+			fmt.Fprintf(&sb, "    %s\n", line)
+		}
+		if commit.Diff != "" {
+			sb.WriteString(commit.Diff)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
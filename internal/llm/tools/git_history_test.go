@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBlamePorcelain(t *testing.T) {
+	a := strings.Repeat("a", 40)
+	b := strings.Repeat("b", 40)
+	out := a + " 1 10 2\n" +
+		"author Alice\n" +
+		"author-mail <alice@example.com>\n" +
+		"author-time 1700000000\n" +
+		"summary Add retries\n" +
+		"filename client.go\n" +
+		"\tfor i := 0; i < 3; i++ {\n" +
+		a + " 2 11\n" +
+		"\t\tresp, err = do()\n" +
+		b + " 5 12 1\n" +
+		"author Bob\n" +
+		"author-time 1710000000\n" +
+		"summary Fix backoff\n" +
+		"previous " + a + " client.go\n" +
+		"filename client.go\n" +
+		"\t\ttime.Sleep(backoff(i))\n" +
+		a + " 3 13 1\n" +
+		"\t}\n" +
+		uncommittedBlameHash + " 14 14 1\n" +
+		"author Not Committed Yet\n" +
+		"summary Version of client.go from client.go\n" +
+		"\t// TODO\n"
+
+	lines, commits := parseBlamePorcelain(out)
+	require.Len(t, lines, 5)
+	assert.Equal(t, blameLine{Hash: b, Line: 12}, lines[2])
+	assert.Equal(t, "Alice", commits[a].Author)
+	assert.Equal(t, "Fix backoff", commits[b].Summary)
+
+	assert.Equal(t, "10-11      aaaaaaa  2023-11-14  Alice  Add retries\n"+
+		"12         bbbbbbb  2024-03-09  Bob  Fix backoff\n"+
+		"13         aaaaaaa  2023-11-14  Alice  Add retries\n"+
+		"14         not committed yet\n", formatBlame(lines, commits))
+}
+
+func TestParseGitLog(t *testing.T) {
+	out := "\x1e" + strings.Repeat("1", 40) + "\x1fAlice\x1f2024-03-09\x1fFix backoff\x1fThe backoff grew linearly.\n\nSigned-off-by: Alice <alice@example.com>\n\x1d\n" +
+		"diff --git a/client.go b/client.go\n" +
+		"--- a/client.go\n" +
+		"+++ b/client.go\n" +
+		"@@ -12,1 +12,1 @@ func get()\n" +
+		"-\t\ttime.Sleep(time.Duration(i) * time.Second)\n" +
+		"+\t\ttime.Sleep(backoff(i))\n" +
+		"\x1e" + strings.Repeat("2", 40) + "\x1fBob\x1f2023-11-14\x1fAdd retries\x1f\x1d\n"
+
+	commits := parseGitLog(out)
+	require.Len(t, commits, 2)
+	assert.Equal(t, "@@ -12,1 +12,1 @@ func get()\n-\t\ttime.Sleep(time.Duration(i) * time.Second)\n+\t\ttime.Sleep(backoff(i))", commits[0].Diff)
+	assert.Empty(t, commits[1].Diff)
+
+	assert.Equal(t, "1111111  2024-03-09  Alice  Fix backoff\n"+
+		"    The backoff grew linearly.\n"+
+		"@@ -12,1 +12,1 @@ func get()\n"+
+		"-\t\ttime.Sleep(time.Duration(i) * time.Second)\n"+
+		"+\t\ttime.Sleep(backoff(i))\n"+
+		"\n"+
+		"2222222  2023-11-14  Bob  Add retries\n", formatHistory(commits))
+}
+
+func TestCompactDiff(t *testing.T) {
+	var diff strings.Builder
+	diff.WriteString("@@ -1,50 +1,50 @@\n")
+	for range maxHistoryDiffLines + 5 {
+		diff.WriteString("+x\n context\n")
+	}
+	lines := strings.Split(compactDiff(diff.String()), "\n")
+	require.Len(t, lines, maxHistoryDiffLines+1)
+	assert.Equal(t, "... 6 more changed lines", lines[len(lines)-1])
+}
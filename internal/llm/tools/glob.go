@@ -13,6 +13,7 @@ import (
 	"github.com/kirmad/superopencode/internal/config"
 	"github.com/kirmad/superopencode/internal/fileutil"
 	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/sparse"
 )
 
 const (
@@ -46,6 +47,7 @@ LIMITATIONS:
 - Results are limited to 100 files (newest first)
 - Does not search file contents (use Grep tool for that)
 - Hidden files (starting with '.') are skipped
+- In a sparse checkout, only the checked out files are found
 
 TIPS:
 - For the most useful results, combine with the Grep tool: first find files with Glob, then search their contents with Grep
@@ -116,6 +118,10 @@ func (g *globTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 			output += "\n\n(Results are truncated. Consider using a more specific path or pattern.)"
 		}
 	}
+	if checkout, err := sparse.Detect(ctx, searchPath); err == nil && checkout.Sparse {
+		output += fmt.Sprintf("\n\n(Sparse checkout: only the files of %s are checked out and searched. Use the %s tool to add directories.)",
+			strings.Join(checkout.Patterns, ", "), SparseCheckoutToolName)
+	}
 
 	return WithResponseMetadata(
 		NewTextResponse(output),
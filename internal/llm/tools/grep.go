@@ -25,6 +25,9 @@ type GrepParams struct {
 	LiteralText bool   `json:"literal_text"`
 }
 
+// maxSkippedFiles bounds the skipped files listed in the results
+const maxSkippedFiles = 10
+
 type grepMatch struct {
 	path     string
 	modTime  time.Time
@@ -69,7 +72,7 @@ COMMON INCLUDE PATTERN EXAMPLES:
 LIMITATIONS:
 - Results are limited to 100 files (newest first)
 - Performance depends on the number of files being searched
-- Very large binary files may be skipped
+- Binary files, lockfiles, generated and minified files are skipped when searching a directory, pass one as path to search it
 - Hidden files (starting with '.') are skipped
 
 TIPS:
@@ -142,7 +145,7 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		searchPath = config.WorkingDirectory()
 	}
 
-	matches, truncated, err := searchFiles(searchPattern, searchPath, params.Include, 100)
+	matches, skipped, truncated, err := searchFiles(searchPattern, searchPath, params.Include, 100)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error searching files: %w", err)
 	}
@@ -173,6 +176,10 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 			output += "\n(Results are truncated. Consider using a more specific path or pattern.)"
 		}
 	}
+	if len(skipped) > 0 {
+		output += fmt.Sprintf("\n\n(Skipped matches in %d lockfiles, generated or minified files. Pass one as path to search it: %s)",
+			len(skipped), strings.Join(skipped[:min(len(skipped), maxSkippedFiles)], ", "))
+	}
 
 	return WithResponseMetadata(
 		NewTextResponse(output),
@@ -183,12 +190,25 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	), nil
 }
 
-func searchFiles(pattern, rootPath, include string, limit int) ([]grepMatch, bool, error) {
+// searchFiles searches the files below rootPath. Matches in lockfiles,
+// generated and minified files are left out of directory searches, their
+// paths are returned as skipped.
+func searchFiles(pattern, rootPath, include string, limit int) ([]grepMatch, []string, bool, error) {
 	matches, err := searchWithRipgrep(pattern, rootPath, include)
 	if err != nil {
 		matches, err = searchFilesWithRegex(pattern, rootPath, include)
 		if err != nil {
-			return nil, false, err
+			return nil, nil, false, err
+		}
+	}
+
+	var skipped []string
+	if info, err := os.Stat(rootPath); err == nil && info.IsDir() {
+		matches, skipped = skipSpecialFiles(matches)
+	}
+	for i := range matches {
+		if len(matches[i].lineText) > MaxLineLength {
+			matches[i].lineText = matches[i].lineText[:MaxLineLength] + "..."
 		}
 	}
 
@@ -201,7 +221,28 @@ func searchFiles(pattern, rootPath, include string, limit int) ([]grepMatch, boo
 		matches = matches[:limit]
 	}
 
-	return matches, truncated, nil
+	return matches, skipped, truncated, nil
+}
+
+// skipSpecialFiles drops the matches in files that aren't source files
+func skipSpecialFiles(matches []grepMatch) ([]grepMatch, []string) {
+	kinds := make(map[string]FileKind)
+	var kept []grepMatch
+	var skipped []string
+	for _, match := range matches {
+		kind, ok := kinds[match.path]
+		if !ok {
+			kind, _ = detectFileKind(match.path)
+			kinds[match.path] = kind
+			if kind != FileKindSource {
+				skipped = append(skipped, match.path)
+			}
+		}
+		if kind == FileKindSource {
+			kept = append(kept, match)
+		}
+	}
+	return kept, skipped
 }
 
 func searchWithRipgrep(pattern, path, include string) ([]grepMatch, error) {
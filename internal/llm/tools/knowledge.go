@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/knowledge"
+)
+
+type KnowledgeSearchParams struct {
+	Query  string `json:"query"`
+	Source string `json:"source"`
+	Limit  int    `json:"limit"`
+}
+
+type knowledgeSearchTool struct{}
+
+const (
+	KnowledgeSearchToolName = "knowledge_search"
+
+	defaultKnowledgeLimit = 5
+	maxKnowledgeLimit     = 20
+
+	knowledgeSearchDescription = `Searches the internal documentation of the team by meaning: %s.
+
+WHEN TO USE THIS TOOL:
+- Use for questions about the team's processes, architecture decisions, services, runbooks and conventions that the code doesn't answer
+- Use before guessing how an internal system works
+
+HOW TO USE:
+- Describe what you look for in a sentence, e.g. "how are database migrations deployed"
+- Set source to search a single source
+- limit caps the number of passages (default 5, max 20)
+
+OUTPUT:
+- The closest passages, each with its page title, source and URL
+
+Cite the URL of the pages you use in your answer.
+`
+)
+
+func NewKnowledgeSearchTool() BaseTool {
+	return &knowledgeSearchTool{}
+}
+
+// knowledgeSources returns the names of the configured sources
+func knowledgeSources() []string {
+	var names []string
+	for name := range config.Get().Knowledge.Sources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (k *knowledgeSearchTool) Info() ToolInfo {
+	sources := knowledgeSources()
+	return ToolInfo{
+		Name:        KnowledgeSearchToolName,
+		Description: fmt.Sprintf(knowledgeSearchDescription, strings.Join(sources, ", ")),
+		Parameters: map[string]any{
+			"query": map[string]any{
+				"type":        "string",
+				"description": "What to look for, in a sentence",
+			},
+			"source": map[string]any{
+				"type":        "string",
+				"description": "Source to search, all of them when empty",
+				"enum":        sources,
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "Number of passages to return (default 5, max 20)",
+			},
+		},
+		Required: []string{"query"},
+	}
+}
+
+func (k *knowledgeSearchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params KnowledgeSearchParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if strings.TrimSpace(params.Query) == "" {
+		return NewTextErrorResponse("query is required"), nil
+	}
+	if params.Source != "" && !slices.Contains(knowledgeSources(), params.Source) {
+		return NewTextErrorResponse(fmt.Sprintf("unknown source %q, use one of %s", params.Source, strings.Join(knowledgeSources(), ", "))), nil
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultKnowledgeLimit
+	}
+	limit = min(limit, maxKnowledgeLimit)
+
+	index, err := knowledge.Shared()
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("the docs index is unavailable: %s", err)), nil
+	}
+	results, err := index.Search(ctx, params.Query, limit, params.Source)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("failed to search the docs: %s", err)), nil
+	}
+	if len(results) == 0 {
+		return NewTextResponse("No passages found. The docs may not be synced yet, see `opencode knowledge sync`."), nil
+	}
+	return NewTextResponse(formatKnowledgeResults(results)), nil
+}
+
+func formatKnowledgeResults(results []knowledge.Result) string {
+	var sb strings.Builder
+	for i, result := range results {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "[%d] %s (%s, %s)\n%s", i+1, result.Title, result.Source, result.URL, result.Text)
+	}
+	return sb.String()
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/sparse"
 )
 
 type LSParams struct {
@@ -33,6 +34,7 @@ type lsTool struct{}
 const (
 	LSToolName    = "ls"
 	MaxLSFiles    = 1000
+	maxLSMissing  = 20
 	lsDescription = `Directory listing tool that shows files and subdirectories in a tree structure, helping you explore and understand the project organization.
 
 WHEN TO USE THIS TOOL:
@@ -50,6 +52,7 @@ FEATURES:
 - Automatically skips hidden files/directories (starting with '.')
 - Skips common system directories like __pycache__
 - Can filter out files matching specific patterns
+- In a sparse checkout, lists the entries of the directory that are tracked but not checked out
 
 LIMITATIONS:
 - Results are limited to 1000 files
@@ -104,6 +107,9 @@ func (l *lsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	}
 
 	if _, err := os.Stat(searchPath); os.IsNotExist(err) {
+		if sparse.OutsideSparseSet(ctx, searchPath) {
+			return NewTextErrorResponse(fmt.Sprintf("path is not checked out: %s is outside the sparse checkout, use the %s tool to add it", searchPath, SparseCheckoutToolName)), nil
+		}
 		return NewTextErrorResponse(fmt.Sprintf("path does not exist: %s", searchPath)), nil
 	}
 
@@ -118,6 +124,9 @@ func (l *lsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	if truncated {
 		output = fmt.Sprintf("There are more than %d files in the directory. Use a more specific path or use the Glob tool to find specific files. The first %d files and directories are included below:\n\n%s", MaxLSFiles, MaxLSFiles, output)
 	}
+	if missing, err := sparse.Missing(ctx, searchPath); err == nil && len(missing) > 0 {
+		output += missingNote(missing)
+	}
 
 	return WithResponseMetadata(
 		NewTextResponse(output),
@@ -128,6 +137,16 @@ func (l *lsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	), nil
 }
 
+// missingNote lists the entries of a directory left out of a sparse checkout
+func missingNote(missing []string) string {
+	more := ""
+	if len(missing) > maxLSMissing {
+		more = fmt.Sprintf(" and %d more", len(missing)-maxLSMissing)
+		missing = missing[:maxLSMissing]
+	}
+	return fmt.Sprintf("\nNot checked out (sparse checkout): %s%s. Use the %s tool to add them.\n", strings.Join(missing, ", "), more, SparseCheckoutToolName)
+}
+
 func listDirectory(initialPath string, ignorePatterns []string, limit int) ([]string, bool, error) {
 	var results []string
 	truncated := false
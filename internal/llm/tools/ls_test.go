@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -120,8 +121,8 @@ func TestLsTool_Run(t *testing.T) {
 	})
 
 	t.Run("handles empty path parameter", func(t *testing.T) {
-		// For this test, we need to mock the config.WorkingDirectory function
-		// Since we can't easily do that, we'll just check that the response doesn't contain an error message
+		// An empty path lists the working directory
+		useWorkingDir(t, tempDir)
 		
 		tool := NewLsTool()
 		params := LSParams{
@@ -139,9 +140,8 @@ func TestLsTool_Run(t *testing.T) {
 		response, err := tool.Run(context.Background(), call)
 		require.NoError(t, err)
 		
-		// The response should either contain a valid directory listing or an error
-		// We'll just check that it's not empty
-		assert.NotEmpty(t, response.Content)
+		assert.Contains(t, response.Content, "dir1")
+		assert.Contains(t, response.Content, "file1.txt")
 	})
 
 	t.Run("handles invalid parameters", func(t *testing.T) {
@@ -194,6 +194,7 @@ func TestLsTool_Run(t *testing.T) {
 		parentDir := filepath.Dir(tempDir)
 		err = os.Chdir(parentDir)
 		require.NoError(t, err)
+		useWorkingDir(t, parentDir)
 		
 		tool := NewLsTool()
 		params := LSParams{
@@ -217,6 +218,37 @@ func TestLsTool_Run(t *testing.T) {
 	})
 }
 
+func TestLsTool_SparseCheckout(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	for _, name := range []string{"app/main.go", "libs/one/one.go", "libs/two/two.go"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644))
+	}
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "init")
+	git("sparse-checkout", "set", "--cone", "app")
+
+	tool := NewLsTool()
+	response, err := tool.Run(context.Background(), ToolCall{Name: LSToolName, Input: `{"path":"` + dir + `"}`})
+	require.NoError(t, err)
+	assert.Contains(t, response.Content, "main.go")
+	assert.Contains(t, response.Content, "Not checked out (sparse checkout): libs/.")
+
+	response, err = tool.Run(context.Background(), ToolCall{Name: LSToolName, Input: `{"path":"` + filepath.Join(dir, "libs", "two") + `"}`})
+	require.NoError(t, err)
+	assert.True(t, response.IsError)
+	assert.Contains(t, response.Content, "outside the sparse checkout")
+}
+
 func TestShouldSkip(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -0,0 +1,478 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/diff"
+	"github.com/kirmad/superopencode/internal/history"
+	"github.com/kirmad/superopencode/internal/permission"
+)
+
+type MergeConflictsParams struct {
+	FilePath string `json:"file_path"`
+	Context  int    `json:"context"`
+}
+
+type ConflictResolution struct {
+	Hunk    int    `json:"hunk"`
+	Choice  string `json:"choice"` // ours, theirs, both, base or custom
+	Content string `json:"content"`
+}
+
+type ResolveConflictParams struct {
+	FilePath      string               `json:"file_path"`
+	Resolutions   []ConflictResolution `json:"resolutions"`
+	VerifyCommand string               `json:"verify_command"`
+}
+
+type ResolveConflictPermissionsParams struct {
+	FilePath      string `json:"file_path"`
+	Diff          string `json:"diff"`
+	VerifyCommand string `json:"verify_command,omitempty"`
+}
+
+type mergeConflictsTool struct{}
+
+type resolveConflictTool struct {
+	permissions permission.Service
+	files       history.Service
+}
+
+const (
+	MergeConflictsToolName  = "merge_conflicts"
+	ResolveConflictToolName = "resolve_conflict"
+
+	defaultConflictContext = 5
+	maxConflictContext     = 50
+
+	mergeConflictsDescription = `Lists the conflicts of an ongoing git merge, rebase or cherry-pick, with both sides of every conflicted hunk and the code around it.
+
+WHEN TO USE THIS TOOL:
+- Use when a merge, rebase, cherry-pick or stash pop stopped with conflicts
+- Use before resolving conflicts with the resolve_conflict tool
+
+HOW TO USE:
+- Without file_path, lists the conflicted files and all of their hunks
+- With file_path, shows only the hunks of that file
+- Increase context to see more of the code around each hunk
+
+OUTPUT:
+- The operation in progress and the conflicted files
+- Every hunk with its number, line, "ours" side (the branch being merged into, or the upstream being rebased onto), the common base when git recorded it, and the "theirs" side
+
+Read the history of both sides (e.g. git log on the file) when the intent of a change is unclear. Never resolve a hunk by guessing.
+`
+
+	resolveConflictDescription = `Resolves conflicted hunks of a file, listed by the merge_conflicts tool, and marks the file resolved once it builds.
+
+HOW TO USE:
+- Give a resolution for each hunk to resolve, by the hunk number from merge_conflicts:
+  - "ours" or "theirs" keeps one side
+  - "both" keeps ours followed by theirs
+  - "base" keeps the common base, when git recorded it
+  - "custom" replaces the hunk with content, use it to combine the changes of both sides
+- Hunks without a resolution keep their conflict markers
+- Give verify_command, e.g. "go build ./..." or "npm test", to run once the file has no conflicts left
+
+OUTPUT:
+- When hunks remain, the number of remaining hunks
+- When none remain and the verify command passes (or none was given), the file is staged with git add and marked resolved
+- When the verify command fails, its output. The file stays unstaged so you can fix it with the edit tool and run this tool again without resolutions
+
+Continue the merge or rebase (e.g. git rebase --continue) only when all files are resolved.
+`
+)
+
+// conflictHunk is a conflicted region of a file, the line numbers are 0-based
+// indexes of the marker lines
+type conflictHunk struct {
+	Start, End  int // "<<<<<<<" and ">>>>>>>" lines
+	OursLabel   string
+	TheirsLabel string
+	Ours        []string
+	Base        []string
+	HasBase     bool
+	Theirs      []string
+}
+
+func NewMergeConflictsTool() BaseTool {
+	return &mergeConflictsTool{}
+}
+
+func (m *mergeConflictsTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        MergeConflictsToolName,
+		Description: mergeConflictsDescription,
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "Only show the hunks of this file",
+			},
+			"context": map[string]any{
+				"type":        "number",
+				"description": fmt.Sprintf("Lines of code shown around each hunk (default %d, max %d)", defaultConflictContext, maxConflictContext),
+			},
+		},
+		Required: []string{},
+	}
+}
+
+func (m *mergeConflictsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params MergeConflictsParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.Context <= 0 {
+		params.Context = defaultConflictContext
+	}
+	params.Context = min(params.Context, maxConflictContext)
+
+	wd := config.WorkingDirectory()
+	files := []string{}
+	if params.FilePath != "" {
+		files = append(files, absolutePath(params.FilePath, wd))
+	} else {
+		out, err := exec.CommandContext(ctx, "git", "-C", wd, "diff", "--name-only", "--diff-filter=U").Output()
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("failed to list conflicted files: %s", err)), nil
+		}
+		root := gitToplevel(ctx, wd)
+		for _, name := range strings.Fields(string(out)) {
+			files = append(files, filepath.Join(root, name))
+		}
+	}
+
+	var sb strings.Builder
+	if op := mergeOperation(ctx, wd); op != "" {
+		fmt.Fprintf(&sb, "A %s is in progress.\n\n", op)
+	}
+	if len(files) == 0 {
+		sb.WriteString("No conflicted files.")
+		return NewTextResponse(sb.String()), nil
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(&sb, "%s: %s\n\n", relativePath(file, wd), err)
+			continue
+		}
+		sb.WriteString(formatConflicts(relativePath(file, wd), string(content), params.Context))
+		sb.WriteString("\n")
+	}
+	return NewTextResponse(truncateOutput(strings.TrimSpace(sb.String()))), nil
+}
+
+func NewResolveConflictTool(permissions permission.Service, files history.Service) BaseTool {
+	return &resolveConflictTool{permissions: permissions, files: files}
+}
+
+func (r *resolveConflictTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ResolveConflictToolName,
+		Description: resolveConflictDescription,
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "The path of the conflicted file",
+			},
+			"resolutions": map[string]any{
+				"type":        "array",
+				"description": "The resolutions of the hunks",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"hunk": map[string]any{
+							"type":        "number",
+							"description": "The hunk number from merge_conflicts",
+						},
+						"choice": map[string]any{
+							"type": "string",
+							"enum": []string{"ours", "theirs", "both", "base", "custom"},
+						},
+						"content": map[string]any{
+							"type":        "string",
+							"description": "The code replacing the hunk for the custom choice",
+						},
+					},
+					"required": []string{"hunk", "choice"},
+				},
+			},
+			"verify_command": map[string]any{
+				"type":        "string",
+				"description": "Build or test command that must pass before the file is marked resolved",
+			},
+		},
+		Required: []string{"file_path"},
+	}
+}
+
+func (r *resolveConflictTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ResolveConflictParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.FilePath == "" {
+		return NewTextErrorResponse("file_path is required"), nil
+	}
+	sessionID, messageID := GetContextValues(ctx)
+	if sessionID == "" || messageID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for resolving conflicts")
+	}
+
+	wd := config.WorkingDirectory()
+	filePath := absolutePath(params.FilePath, wd)
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("failed to read file: %s", err)), nil
+	}
+	oldContent := string(content)
+	newContent, err := resolveHunks(oldContent, params.Resolutions)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	remaining := len(parseConflicts(newContent))
+
+	description := fmt.Sprintf("Resolve %d conflicts in %s", len(params.Resolutions), relativePath(filePath, wd))
+	if remaining == 0 {
+		description += " and stage it"
+		if params.VerifyCommand != "" {
+			description += fmt.Sprintf(" after running %s", params.VerifyCommand)
+		}
+	}
+	fileDiff, additions, removals := diff.GenerateDiff(oldContent, newContent, filePath)
+	if !r.permissions.Request(permission.CreatePermissionRequest{
+		SessionID:   sessionID,
+		Path:        wd,
+		ToolName:    ResolveConflictToolName,
+		Action:      "write",
+		Description: description,
+		Params: ResolveConflictPermissionsParams{
+			FilePath:      filePath,
+			Diff:          fileDiff,
+			VerifyCommand: params.VerifyCommand,
+		},
+	}) {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+
+	if newContent != oldContent {
+		snapshot, err := r.files.Snapshot(ctx, sessionID, filePath)
+		if err != nil {
+			return ToolResponse{}, fmt.Errorf("error snapshotting file: %w", err)
+		}
+		if err := history.WriteFile(filePath, []byte(newContent), 0o644); err != nil {
+			r.files.Release(snapshot)
+			return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
+		}
+		if _, err := r.files.GetByPathAndSession(ctx, filePath, sessionID); err != nil {
+			if _, err := r.files.Create(ctx, sessionID, filePath, oldContent); err != nil {
+				return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
+			}
+		}
+		_, err = r.files.CreateVersion(ctx, sessionID, filePath, newContent)
+		history.ReleaseRecorded(r.files, snapshot, err)
+		recordFileWrite(ctx, filePath)
+		recordFileRead(ctx, filePath)
+	}
+
+	metadata := EditResponseMetadata{Diff: fileDiff, Additions: additions, Removals: removals}
+	if remaining > 0 {
+		return WithResponseMetadata(NewTextResponse(fmt.Sprintf("%d conflicted hunks remain in %s, run merge_conflicts to see their new numbers.", remaining, relativePath(filePath, wd))), metadata), nil
+	}
+
+	if params.VerifyCommand != "" {
+		cmd := exec.CommandContext(ctx, commandShell(), "-c", params.VerifyCommand)
+		cmd.Dir = wd
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return WithResponseMetadata(NewTextErrorResponse(fmt.Sprintf("No conflicts remain in %s, but %s fails, so the file was not marked resolved:\n\n%s", relativePath(filePath, wd), params.VerifyCommand, truncateOutput(strings.TrimSpace(string(out))))), metadata), nil
+		}
+	}
+	if out, err := exec.CommandContext(ctx, "git", "-C", filepath.Dir(filePath), "add", "--", filePath).CombinedOutput(); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("failed to stage %s: %s", filePath, strings.TrimSpace(string(out)))), nil
+	}
+
+	result := fmt.Sprintf("Resolved %s and staged it.", relativePath(filePath, wd))
+	if out, err := exec.CommandContext(ctx, "git", "-C", wd, "diff", "--name-only", "--diff-filter=U").Output(); err == nil {
+		if left := strings.Fields(string(out)); len(left) > 0 {
+			result += fmt.Sprintf(" Files still conflicted: %s.", strings.Join(left, ", "))
+		} else if op := mergeOperation(ctx, wd); op != "" {
+			result += fmt.Sprintf(" All conflicts are resolved, the %s can be continued.", op)
+		}
+	}
+	return WithResponseMetadata(NewTextResponse(result), metadata), nil
+}
+
+// parseConflicts finds the conflicted hunks of a file in the merge or diff3
+// marker style
+func parseConflicts(content string) []conflictHunk {
+	lines := strings.Split(content, "\n")
+	var hunks []conflictHunk
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "<<<<<<<") {
+			continue
+		}
+		hunk := conflictHunk{Start: i, OursLabel: markerLabel(lines[i])}
+		section := &hunk.Ours
+		closed := false
+		for j := i + 1; j < len(lines); j++ {
+			line := lines[j]
+			switch {
+			case strings.HasPrefix(line, "|||||||") && section == &hunk.Ours:
+				hunk.HasBase = true
+				section = &hunk.Base
+			case line == "=======" || strings.HasPrefix(line, "======= "):
+				section = &hunk.Theirs
+			case strings.HasPrefix(line, ">>>>>>>") && section == &hunk.Theirs:
+				hunk.End = j
+				hunk.TheirsLabel = markerLabel(line)
+				closed = true
+			default:
+				*section = append(*section, line)
+			}
+			if closed {
+				break
+			}
+		}
+		if !closed {
+			break
+		}
+		hunks = append(hunks, hunk)
+		i = hunk.End
+	}
+	return hunks
+}
+
+func markerLabel(line string) string {
+	return strings.TrimSpace(line[7:])
+}
+
+// resolveHunks replaces the hunks with the chosen resolutions, hunks are
+// numbered from 1
+func resolveHunks(content string, resolutions []ConflictResolution) (string, error) {
+	hunks := parseConflicts(content)
+	chosen := make(map[int]ConflictResolution, len(resolutions))
+	for _, res := range resolutions {
+		if res.Hunk < 1 || res.Hunk > len(hunks) {
+			return "", fmt.Errorf("hunk %d does not exist, the file has %d conflicted hunks", res.Hunk, len(hunks))
+		}
+		if _, ok := chosen[res.Hunk]; ok {
+			return "", fmt.Errorf("hunk %d has more than one resolution", res.Hunk)
+		}
+		if res.Choice == "base" && !hunks[res.Hunk-1].HasBase {
+			return "", fmt.Errorf("hunk %d has no base, git records it with merge.conflictStyle diff3", res.Hunk)
+		}
+		chosen[res.Hunk] = res
+	}
+
+	lines := strings.Split(content, "\n")
+	var out []string
+	next := 0
+	for n, hunk := range hunks {
+		res, ok := chosen[n+1]
+		if !ok {
+			continue
+		}
+		out = append(out, lines[next:hunk.Start]...)
+		switch res.Choice {
+		case "ours":
+			out = append(out, hunk.Ours...)
+		case "theirs":
+			out = append(out, hunk.Theirs...)
+		case "both":
+			out = append(out, hunk.Ours...)
+			out = append(out, hunk.Theirs...)
+		case "base":
+			out = append(out, hunk.Base...)
+		case "custom":
+			if res.Content != "" {
+				out = append(out, strings.Split(strings.TrimSuffix(res.Content, "\n"), "\n")...)
+			}
+		default:
+			return "", fmt.Errorf("unknown choice %q for hunk %d, use ours, theirs, both, base or custom", res.Choice, n+1)
+		}
+		next = hunk.End + 1
+	}
+	out = append(out, lines[next:]...)
+	return strings.Join(out, "\n"), nil
+}
+
+// formatConflicts shows the hunks of a file with contextLines of code around
+// each of them
+func formatConflicts(name, content string, contextLines int) string {
+	hunks := parseConflicts(content)
+	if len(hunks) == 0 {
+		return fmt.Sprintf("%s: no conflict markers left\n", name)
+	}
+	lines := strings.Split(content, "\n")
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %d conflicted hunks\n", name, len(hunks))
+	for n, hunk := range hunks {
+		fmt.Fprintf(&sb, "\nHunk %d (line %d):\n", n+1, hunk.Start+1)
+		writeLines(&sb, "context before", lines[max(hunk.Start-contextLines, 0):hunk.Start])
+		writeLines(&sb, conflictSide("ours", hunk.OursLabel), hunk.Ours)
+		if hunk.HasBase {
+			writeLines(&sb, "base", hunk.Base)
+		}
+		writeLines(&sb, conflictSide("theirs", hunk.TheirsLabel), hunk.Theirs)
+		writeLines(&sb, "context after", lines[hunk.End+1:min(hunk.End+1+contextLines, len(lines))])
+	}
+	return sb.String()
+}
+
+func conflictSide(side, label string) string {
+	if label == "" {
+		return side
+	}
+	return fmt.Sprintf("%s (%s)", side, label)
+}
+
+func writeLines(sb *strings.Builder, title string, lines []string) {
+	fmt.Fprintf(sb, "--- %s\n", title)
+	for _, line := range lines {
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+}
+
+// mergeOperation names the git operation that stopped with conflicts
+func mergeOperation(ctx context.Context, wd string) string {
+	out, err := exec.CommandContext(ctx, "git", "-C", wd, "rev-parse", "--absolute-git-dir").Output()
+	if err != nil {
+		return ""
+	}
+	gitDir := strings.TrimSpace(string(out))
+	for _, op := range []struct{ marker, name string }{
+		{"rebase-merge", "rebase"},
+		{"rebase-apply", "rebase"},
+		{"MERGE_HEAD", "merge"},
+		{"CHERRY_PICK_HEAD", "cherry-pick"},
+		{"REVERT_HEAD", "revert"},
+	} {
+		if fileExists(filepath.Join(gitDir, op.marker)) {
+			return op.name
+		}
+	}
+	return ""
+}
+
+func gitToplevel(ctx context.Context, wd string) string {
+	out, err := exec.CommandContext(ctx, "git", "-C", wd, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return wd
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func absolutePath(path, wd string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(wd, path)
+}
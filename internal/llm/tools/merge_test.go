@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const conflictedFile = `package main
+
+func main() {
+<<<<<<< HEAD
+	fmt.Println("ours")
+=======
+	fmt.Println("theirs")
+>>>>>>> feature
+}
+
+func helper() int {
+<<<<<<< HEAD
+	return 1
+||||||| base
+	return 0
+=======
+	return 2
+>>>>>>> feature
+}
+`
+
+func TestParseConflicts(t *testing.T) {
+	hunks := parseConflicts(conflictedFile)
+	require.Len(t, hunks, 2)
+
+	assert.Equal(t, 3, hunks[0].Start)
+	assert.Equal(t, 7, hunks[0].End)
+	assert.Equal(t, "HEAD", hunks[0].OursLabel)
+	assert.Equal(t, "feature", hunks[0].TheirsLabel)
+	assert.Equal(t, []string{"\tfmt.Println(\"ours\")"}, hunks[0].Ours)
+	assert.Equal(t, []string{"\tfmt.Println(\"theirs\")"}, hunks[0].Theirs)
+	assert.False(t, hunks[0].HasBase)
+
+	assert.True(t, hunks[1].HasBase)
+	assert.Equal(t, []string{"\treturn 0"}, hunks[1].Base)
+	assert.Equal(t, []string{"\treturn 2"}, hunks[1].Theirs)
+
+	assert.Empty(t, parseConflicts("<<<<<<< HEAD\nunterminated\n"))
+}
+
+func TestResolveHunks(t *testing.T) {
+	resolved, err := resolveHunks(conflictedFile, []ConflictResolution{
+		{Hunk: 1, Choice: "both"},
+		{Hunk: 2, Choice: "custom", Content: "\treturn 1 + 2\n"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, parseConflicts(resolved))
+	assert.Contains(t, resolved, "\tfmt.Println(\"ours\")\n\tfmt.Println(\"theirs\")\n}")
+	assert.Contains(t, resolved, "func helper() int {\n\treturn 1 + 2\n}\n")
+
+	partial, err := resolveHunks(conflictedFile, []ConflictResolution{{Hunk: 2, Choice: "base"}})
+	require.NoError(t, err)
+	require.Len(t, parseConflicts(partial), 1)
+	assert.Contains(t, partial, "func helper() int {\n\treturn 0\n}")
+
+	_, err = resolveHunks(conflictedFile, []ConflictResolution{{Hunk: 3, Choice: "ours"}})
+	assert.Error(t, err)
+	_, err = resolveHunks(conflictedFile, []ConflictResolution{{Hunk: 1, Choice: "base"}})
+	assert.Error(t, err, "hunk 1 has no base")
+	_, err = resolveHunks(conflictedFile, []ConflictResolution{{Hunk: 1, Choice: "mine"}})
+	assert.Error(t, err)
+}
+
+func TestFormatConflicts(t *testing.T) {
+	out := formatConflicts("main.go", conflictedFile, 1)
+	assert.True(t, strings.HasPrefix(out, "main.go: 2 conflicted hunks\n"))
+	assert.Contains(t, out, "Hunk 1 (line 4):\n--- context before\nfunc main() {\n--- ours (HEAD)\n\tfmt.Println(\"ours\")\n--- theirs (feature)\n\tfmt.Println(\"theirs\")\n--- context after\n}\n")
+	assert.Contains(t, out, "--- base\n\treturn 0\n")
+
+	assert.Equal(t, "main.go: no conflict markers left\n", formatConflicts("main.go", "package main\n", 3))
+}
@@ -3,15 +3,14 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"time"
 
 	"github.com/kirmad/superopencode/internal/config"
 	"github.com/kirmad/superopencode/internal/diff"
 	"github.com/kirmad/superopencode/internal/history"
-	"github.com/kirmad/superopencode/internal/logging"
 	"github.com/kirmad/superopencode/internal/lsp"
 	"github.com/kirmad/superopencode/internal/permission"
 )
@@ -105,10 +104,6 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			absPath = filepath.Join(wd, absPath)
 		}
 
-		if getLastReadTime(absPath).IsZero() {
-			return NewTextErrorResponse(fmt.Sprintf("you must read the file %s before patching it. Use the FileRead tool first", filePath)), nil
-		}
-
 		fileInfo, err := os.Stat(absPath)
 		if err != nil {
 			if os.IsNotExist(err) {
@@ -121,13 +116,12 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			return NewTextErrorResponse(fmt.Sprintf("path is a directory, not a file: %s", absPath)), nil
 		}
 
-		modTime := fileInfo.ModTime()
-		lastRead := getLastReadTime(absPath)
-		if modTime.After(lastRead) {
-			return NewTextErrorResponse(
-				fmt.Sprintf("file %s has been modified since it was last read (mod time: %s, last read: %s)",
-					absPath, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339),
-				)), nil
+		if kind, reason := detectFileKind(absPath); kind != FileKindSource {
+			return fileKindResponse(absPath, kind, reason, "edit"), nil
+		}
+
+		if response, ok := checkFileUnchanged(ctx, absPath, "patch"); !ok {
+			return response, nil
 		}
 	}
 
@@ -257,7 +251,30 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		}
 	}
 
-	// Apply the changes to the filesystem
+	// The files may have been edited while the permissions were pending
+	for _, filePath := range filesToRead {
+		absPath := filePath
+		if !filepath.IsAbs(absPath) {
+			absPath = filepath.Join(config.WorkingDirectory(), absPath)
+		}
+		if response, ok := checkFileUnchanged(ctx, absPath, "patch"); !ok {
+			return response, nil
+		}
+	}
+
+	diagnosticsBefore := snapshotDiagnostics(p.lspClients)
+
+	// Apply the changes to the filesystem, each file is snapshotted before it
+	// changes
+	var snapshots []history.Snapshot
+	takeSnapshot := func(absPath string) error {
+		snapshot, err := p.files.Snapshot(ctx, sessionID, absPath)
+		if err != nil {
+			return fmt.Errorf("failed to snapshot %s: %w", absPath, err)
+		}
+		snapshots = append(snapshots, snapshot)
+		return nil
+	}
 	err = diff.ApplyCommit(commit, func(path string, content string) error {
 		absPath := path
 		if !filepath.IsAbs(absPath) {
@@ -271,20 +288,30 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			return fmt.Errorf("failed to create parent directories for %s: %w", absPath, err)
 		}
 
-		return os.WriteFile(absPath, []byte(content), 0o644)
+		if err := takeSnapshot(absPath); err != nil {
+			return err
+		}
+		return history.WriteFile(absPath, []byte(content), 0o644)
 	}, func(path string) error {
 		absPath := path
 		if !filepath.IsAbs(absPath) {
 			wd := config.WorkingDirectory()
 			absPath = filepath.Join(wd, absPath)
 		}
+		if err := takeSnapshot(absPath); err != nil {
+			return err
+		}
 		return os.Remove(absPath)
 	})
 	if err != nil {
+		// The files changed before the failure are recovered from their
+		// snapshots on the next start
 		return NewTextErrorResponse(fmt.Sprintf("failed to apply patch: %s", err)), nil
 	}
 
-	// Update file history for all modified files
+	// Update file history for all modified files, the snapshots of the files
+	// it fails for are kept
+	historyErrs := make(map[string]error)
 	changedFiles := []string{}
 	totalAdditions := 0
 	totalRemovals := 0
@@ -317,17 +344,13 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		if err != nil && change.Type != diff.ActionAdd {
 			// If not adding a file, create history entry for existing file
 			_, err = p.files.Create(ctx, sessionID, absPath, oldContent)
-			if err != nil {
-				logging.Debug("Error creating file history", "error", err)
-			}
+			historyErrs[absPath] = errors.Join(historyErrs[absPath], err)
 		}
 
 		if err == nil && change.Type != diff.ActionAdd && file.Content != oldContent {
 			// User manually changed content, store intermediate version
 			_, err = p.files.CreateVersion(ctx, sessionID, absPath, oldContent)
-			if err != nil {
-				logging.Debug("Error creating file history version", "error", err)
-			}
+			historyErrs[absPath] = errors.Join(historyErrs[absPath], err)
 		}
 
 		// Store new version
@@ -336,13 +359,14 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		} else {
 			_, err = p.files.CreateVersion(ctx, sessionID, absPath, newContent)
 		}
-		if err != nil {
-			logging.Debug("Error creating file history version", "error", err)
-		}
+		historyErrs[absPath] = errors.Join(historyErrs[absPath], err)
 
 		// Record file operations
-		recordFileWrite(absPath)
-		recordFileRead(absPath)
+		recordFileWrite(ctx, absPath)
+		recordFileRead(ctx, absPath)
+	}
+	for _, snapshot := range snapshots {
+		history.ReleaseRecorded(p.files, snapshot, historyErrs[snapshot.Path])
 	}
 
 	// Run LSP diagnostics on all changed files
@@ -353,14 +377,7 @@ func (p *patchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	result := fmt.Sprintf("Patch applied successfully. %d files changed, %d additions, %d removals",
 		len(changedFiles), totalAdditions, totalRemovals)
 
-	diagnosticsText := ""
-	for _, filePath := range changedFiles {
-		diagnosticsText += getDiagnostics(filePath, p.lspClients)
-	}
-
-	if diagnosticsText != "" {
-		result += "\n\nDiagnostics:\n" + diagnosticsText
-	}
+	result += diagnosticsDelta(diagnosticsBefore, p.lspClients)
 
 	return WithResponseMetadata(
 		NewTextResponse(result),
@@ -0,0 +1,432 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/permission"
+)
+
+type ProfileParams struct {
+	Command string `json:"command"`
+	Profile string `json:"profile"`
+	Top     int    `json:"top"`
+	Timeout int    `json:"timeout"`
+}
+
+type ProfilePermissionsParams struct {
+	Command string `json:"command"`
+}
+
+type profileTool struct {
+	permissions permission.Service
+}
+
+const (
+	ProfileToolName = "profile"
+
+	defaultProfileTop     = 20
+	maxProfileTop         = 100
+	defaultProfileTimeout = 300 // Seconds
+
+	profileDescription = `Profiles the CPU usage of a command and returns its hottest functions in compact form, so optimizations can target the code that actually takes the time.
+
+WHEN TO USE THIS TOOL:
+- Use before optimizing code, to find where the time goes instead of guessing
+- Use after an optimization, to show the improvement with numbers
+
+HOW TO USE:
+- Go: a "go test" command of a single package running a benchmark or test, e.g. "go test -run '^$' -bench BenchmarkParse ./internal/parser". The tool adds -cpuprofile
+- Python: a "python" command, profiled with py-spy (must be installed)
+- Node.js: a "node" command, profiled with --cpu-prof
+- Or pass the path of an existing profile instead of a command: a Go pprof file, a collapsed stack file ("frame;frame;frame count", as used for flame graphs and written by py-spy --format raw) or a Node .cpuprofile
+
+OUTPUT:
+- flat: share of the samples spent in the function itself
+- cum: share of the samples spent in the function and the functions it calls
+- Functions with a high flat share are the hot spots, functions with a high cum share are the paths leading to them
+
+TIPS:
+- Read the code of the hot functions before proposing optimizations and cite the numbers as evidence
+- Profile again after changing the code to verify the improvement
+`
+)
+
+// hotspot is a function of a profile with its share of the samples
+type hotspot struct {
+	Name string
+	Flat float64 // Percent
+	Cum  float64 // Percent
+}
+
+// sampledStack is a call stack, root first, with its number of samples
+type sampledStack struct {
+	Frames []string
+	Count  int64
+}
+
+func NewProfileTool(permissions permission.Service) BaseTool {
+	return &profileTool{permissions: permissions}
+}
+
+func (p *profileTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ProfileToolName,
+		Description: profileDescription,
+		Parameters: map[string]any{
+			"command": map[string]any{
+				"type":        "string",
+				"description": "The go test, python or node command to profile",
+			},
+			"profile": map[string]any{
+				"type":        "string",
+				"description": "Path of an existing profile to analyze instead of running a command",
+			},
+			"top": map[string]any{
+				"type":        "number",
+				"description": fmt.Sprintf("Number of functions to return (default %d, max %d)", defaultProfileTop, maxProfileTop),
+			},
+			"timeout": map[string]any{
+				"type":        "number",
+				"description": fmt.Sprintf("Timeout of the command in seconds (default %d)", defaultProfileTimeout),
+			},
+		},
+	}
+}
+
+func (p *profileTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ProfileParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	params.Command = strings.TrimSpace(params.Command)
+	if (params.Command == "") == (params.Profile == "") {
+		return NewTextErrorResponse("provide either a command or a profile"), nil
+	}
+	if params.Top <= 0 {
+		params.Top = defaultProfileTop
+	}
+	params.Top = min(params.Top, maxProfileTop)
+	if params.Timeout <= 0 {
+		params.Timeout = defaultProfileTimeout
+	}
+
+	wd := config.WorkingDirectory()
+	if params.Profile != "" {
+		path := params.Profile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(wd, path)
+		}
+		hotspots, total, err := analyzeProfile(ctx, path, params.Top)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
+		}
+		return NewTextResponse(formatHotspots(params.Profile, hotspots, total)), nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "opencode-profile-*")
+	if err != nil {
+		return ToolResponse{}, err
+	}
+	defer os.RemoveAll(tmpDir)
+	command, profilePath, err := profiledCommand(params.Command, tmpDir)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	sessionID, _ := GetContextValues(ctx)
+	if sessionID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID is required to profile a command")
+	}
+	if !p.permissions.Request(permission.CreatePermissionRequest{
+		SessionID:   sessionID,
+		Path:        wd,
+		ToolName:    ProfileToolName,
+		Action:      "execute",
+		Description: fmt.Sprintf("Profile: %s", command),
+		Params:      ProfilePermissionsParams{Command: command},
+	}) {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, time.Duration(params.Timeout)*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(runCtx, commandShell(), "-c", command)
+	cmd.Dir = wd
+	out, runErr := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return ToolResponse{}, ctx.Err()
+	}
+	if runCtx.Err() == context.DeadlineExceeded {
+		return NewTextErrorResponse(fmt.Sprintf("the command timed out after %ds", params.Timeout)), nil
+	}
+
+	if profilePath == "" {
+		// node writes the profile under a generated name
+		matches, _ := filepath.Glob(filepath.Join(tmpDir, "*.cpuprofile"))
+		if len(matches) > 0 {
+			profilePath = matches[0]
+		}
+	}
+	if _, err := os.Stat(profilePath); profilePath == "" || err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("the command wrote no profile: %v\n%s", runErr, truncateOutput(strings.TrimSpace(string(out))))), nil
+	}
+	hotspots, total, err := analyzeProfile(ctx, profilePath, params.Top)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	result := formatHotspots(params.Command, hotspots, total)
+	if runErr != nil {
+		result += fmt.Sprintf("\nThe command failed (%v), the profile may be incomplete:\n%s\n", runErr, truncateOutput(strings.TrimSpace(string(out))))
+	}
+	return NewTextResponse(result), nil
+}
+
+// profiledCommand adds the profiler to command. The returned profile path is
+// empty when the profiler picks the file name within dir.
+func profiledCommand(command, dir string) (string, string, error) {
+	fields := strings.Fields(command)
+	switch {
+	case len(fields) >= 2 && fields[0] == "go" && fields[1] == "test":
+		profile := filepath.Join(dir, "cpu.pprof")
+		// The test binary is kept next to the profile to resolve the symbols
+		return fmt.Sprintf("%s -cpuprofile=%s -o=%s", command, profile, filepath.Join(dir, "pkg.test")), profile, nil
+	case strings.HasPrefix(fields[0], "python"):
+		if _, err := exec.LookPath("py-spy"); err != nil {
+			return "", "", fmt.Errorf("py-spy is required to profile Python, install it with pip install py-spy")
+		}
+		profile := filepath.Join(dir, "profile.txt")
+		return fmt.Sprintf("py-spy record --format raw --output %s -- %s", profile, command), profile, nil
+	case fields[0] == "node":
+		return fmt.Sprintf("node --cpu-prof --cpu-prof-dir=%s %s", dir, strings.Join(fields[1:], " ")), "", nil
+	}
+	return "", "", fmt.Errorf("cannot profile %q: use a go test, python or node command, or pass an existing profile", fields[0])
+}
+
+// analyzeProfile returns the top hotspots of a pprof, collapsed stack or Node
+// profile and its total
+func analyzeProfile(ctx context.Context, path string, top int) ([]hotspot, string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read the profile: %w", err)
+	}
+	switch {
+	case strings.HasSuffix(path, ".cpuprofile") || (len(content) > 0 && content[0] == '{'):
+		stacks, err := parseNodeProfile(content)
+		if err != nil {
+			return nil, "", err
+		}
+		hotspots, total := aggregateStacks(stacks, top)
+		return hotspots, fmt.Sprintf("%d samples", total), nil
+	case isCollapsed(content):
+		hotspots, total := aggregateStacks(parseCollapsed(string(content)), top)
+		return hotspots, fmt.Sprintf("%d samples", total), nil
+	}
+
+	args := []string{"tool", "pprof", "-top", fmt.Sprintf("-nodecount=%d", top)}
+	if binary := filepath.Join(filepath.Dir(path), "pkg.test"); fileExists(binary) {
+		args = append(args, binary)
+	}
+	out, err := exec.CommandContext(ctx, "go", append(args, path)...).CombinedOutput()
+	if err != nil {
+		return nil, "", fmt.Errorf("go tool pprof failed: %s", truncateOutput(strings.TrimSpace(string(out))))
+	}
+	hotspots, total := parsePprofTop(string(out))
+	return hotspots, total, nil
+}
+
+// parsePprofTop parses the output of go tool pprof -top, lines like
+// "     0.50s 45.45% 45.45%      0.80s 72.73%  main.parse"
+func parsePprofTop(output string) ([]hotspot, string) {
+	var hotspots []hotspot
+	total := ""
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if rest, ok := strings.CutPrefix(line, "Duration: "); ok {
+			if _, samples, ok := strings.Cut(rest, "Total samples = "); ok {
+				total = samples
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			continue
+		}
+		flat, err1 := parsePercent(fields[1])
+		cum, err2 := parsePercent(fields[4])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		hotspots = append(hotspots, hotspot{Name: strings.Join(fields[5:], " "), Flat: flat, Cum: cum})
+	}
+	return hotspots, total
+}
+
+func parsePercent(s string) (float64, error) {
+	if !strings.HasSuffix(s, "%") {
+		return 0, fmt.Errorf("not a percentage: %s", s)
+	}
+	return strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+}
+
+// isCollapsed reports whether content is in the collapsed stack format
+func isCollapsed(content []byte) bool {
+	line, _, _ := strings.Cut(strings.TrimSpace(string(content)), "\n")
+	i := strings.LastIndexByte(line, ' ')
+	if i < 0 {
+		return false
+	}
+	_, err := strconv.ParseInt(line[i+1:], 10, 64)
+	return err == nil
+}
+
+// parseCollapsed parses collapsed stacks, lines like "main;parse;scan 42"
+func parseCollapsed(content string) []sampledStack {
+	var stacks []sampledStack
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			continue
+		}
+		count, err := strconv.ParseInt(line[i+1:], 10, 64)
+		if err != nil || count <= 0 {
+			continue
+		}
+		stacks = append(stacks, sampledStack{Frames: strings.Split(line[:i], ";"), Count: count})
+	}
+	return stacks
+}
+
+// parseNodeProfile turns the call tree of a V8 .cpuprofile into stacks
+func parseNodeProfile(content []byte) ([]sampledStack, error) {
+	var profile struct {
+		Nodes []struct {
+			ID        int `json:"id"`
+			CallFrame struct {
+				FunctionName string `json:"functionName"`
+				URL          string `json:"url"`
+				LineNumber   int    `json:"lineNumber"`
+			} `json:"callFrame"`
+			HitCount int64 `json:"hitCount"`
+			Children []int `json:"children"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(content, &profile); err != nil {
+		return nil, fmt.Errorf("failed to parse the Node profile: %w", err)
+	}
+
+	names := make(map[int]string, len(profile.Nodes))
+	parents := make(map[int]int, len(profile.Nodes))
+	for _, node := range profile.Nodes {
+		frame := node.CallFrame
+		name := frame.FunctionName
+		if name == "" {
+			name = "(anonymous)"
+		}
+		if frame.URL != "" {
+			// Line numbers of V8 profiles are zero based
+			name = fmt.Sprintf("%s (%s:%d)", name, strings.TrimPrefix(frame.URL, "file://"), frame.LineNumber+1)
+		}
+		names[node.ID] = name
+		for _, child := range node.Children {
+			parents[child] = node.ID
+		}
+	}
+
+	var stacks []sampledStack
+	for _, node := range profile.Nodes {
+		if node.HitCount == 0 || names[node.ID] == "(root)" {
+			continue
+		}
+		var frames []string
+		for id, ok := node.ID, true; ok; id, ok = parents[id] {
+			if names[id] != "(root)" {
+				frames = append([]string{names[id]}, frames...)
+			}
+		}
+		stacks = append(stacks, sampledStack{Frames: frames, Count: node.HitCount})
+	}
+	return stacks, nil
+}
+
+// aggregateStacks computes the flat and cumulative shares of the functions of
+// stacks and returns the top ones, sorted by flat share
+func aggregateStacks(stacks []sampledStack, top int) ([]hotspot, int64) {
+	var total int64
+	flat := make(map[string]int64)
+	cum := make(map[string]int64)
+	for _, stack := range stacks {
+		if len(stack.Frames) == 0 {
+			continue
+		}
+		total += stack.Count
+		flat[stack.Frames[len(stack.Frames)-1]] += stack.Count
+		// Recursive functions count once per stack
+		seen := make(map[string]bool, len(stack.Frames))
+		for _, frame := range stack.Frames {
+			if !seen[frame] {
+				seen[frame] = true
+				cum[frame] += stack.Count
+			}
+		}
+	}
+	if total == 0 {
+		return nil, 0
+	}
+
+	hotspots := make([]hotspot, 0, len(cum))
+	for name, count := range cum {
+		hotspots = append(hotspots, hotspot{
+			Name: name,
+			Flat: float64(flat[name]) * 100 / float64(total),
+			Cum:  float64(count) * 100 / float64(total),
+		})
+	}
+	sort.Slice(hotspots, func(i, j int) bool {
+		if hotspots[i].Flat != hotspots[j].Flat {
+			return hotspots[i].Flat > hotspots[j].Flat
+		}
+		if hotspots[i].Cum != hotspots[j].Cum {
+			return hotspots[i].Cum > hotspots[j].Cum
+		}
+		return hotspots[i].Name < hotspots[j].Name
+	})
+	if len(hotspots) > top {
+		hotspots = hotspots[:top]
+	}
+	return hotspots, total
+}
+
+func formatHotspots(source string, hotspots []hotspot, total string) string {
+	if len(hotspots) == 0 {
+		return fmt.Sprintf("The profile of %s has no samples, the command may have run too briefly. Profile a longer running benchmark or test.", source)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Profile of %s", source)
+	if total != "" {
+		fmt.Fprintf(&sb, " (%s)", total)
+	}
+	sb.WriteString("\n\n  flat    cum  function\n")
+	for _, h := range hotspots {
+		fmt.Fprintf(&sb, "%5.1f%% %5.1f%%  %s\n", h.Flat, h.Cum, h.Name)
+	}
+	return sb.String()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
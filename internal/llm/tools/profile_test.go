@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateStacks(t *testing.T) {
+	stacks := parseCollapsed(`main;run;parse 6
+main;run;parse;parse 2
+main;run;write 2
+not a stack
+`)
+	hotspots, total := aggregateStacks(stacks, 3)
+	assert.Equal(t, int64(10), total)
+	assert.Equal(t, []hotspot{
+		{Name: "parse", Flat: 80, Cum: 80},
+		{Name: "write", Flat: 20, Cum: 20},
+		{Name: "main", Flat: 0, Cum: 100},
+	}, hotspots)
+}
+
+func TestParsePprofTop(t *testing.T) {
+	hotspots, total := parsePprofTop(`File: parser.test
+Type: cpu
+Duration: 1.20s, Total samples = 1.10s (91.67%)
+Showing nodes accounting for 1.10s, 100% of 1.10s total
+      flat  flat%   sum%        cum   cum%
+     0.50s 45.45% 45.45%      0.80s 72.73%  example.com/parser.(*Parser).scan
+     0.30s 27.27% 72.73%      0.30s 27.27%  runtime.mallocgc
+`)
+	assert.Equal(t, "1.10s (91.67%)", total)
+	assert.Equal(t, []hotspot{
+		{Name: "example.com/parser.(*Parser).scan", Flat: 45.45, Cum: 72.73},
+		{Name: "runtime.mallocgc", Flat: 27.27, Cum: 27.27},
+	}, hotspots)
+}
+
+func TestParseNodeProfile(t *testing.T) {
+	stacks, err := parseNodeProfile([]byte(`{"nodes": [
+  {"id": 1, "callFrame": {"functionName": "(root)"}, "hitCount": 0, "children": [2]},
+  {"id": 2, "callFrame": {"functionName": "main", "url": "file:///app/main.js", "lineNumber": 0}, "hitCount": 1, "children": [3]},
+  {"id": 3, "callFrame": {"functionName": "", "url": "file:///app/parse.js", "lineNumber": 9}, "hitCount": 3}
+]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []sampledStack{
+		{Frames: []string{"main (/app/main.js:1)"}, Count: 1},
+		{Frames: []string{"main (/app/main.js:1)", "(anonymous) (/app/parse.js:10)"}, Count: 3},
+	}, stacks)
+}
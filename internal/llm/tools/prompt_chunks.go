@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+const ReadPromptToolName = "read_prompt"
+
+// PromptChunksDir is where the chunks of the prompts of a session too large
+// for a turn are saved
+func PromptChunksDir(sessionID string) string {
+	return filepath.Join(config.Get().Data.Directory, "prompts", sessionID)
+}
+
+// SplitChunks splits a text in chunks of at most size bytes, cut at the end
+// of a line when there is one in the chunk
+func SplitChunks(text string, size int) []string {
+	var chunks []string
+	for len(text) > size {
+		cut := strings.LastIndexByte(text[:size], '\n') + 1
+		if cut == 0 {
+			cut = size
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				cut = size
+			}
+		}
+		chunks = append(chunks, text[:cut])
+		text = text[cut:]
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+// SavePromptChunks saves the chunks of a prompt of a session, numbered from
+// 1, and returns the ID the read_prompt tool reads them by
+func SavePromptChunks(sessionID string, chunks []string) (string, error) {
+	id := uuid.New().String()[:8]
+	dir := filepath.Join(PromptChunksDir(sessionID), id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	for i, chunk := range chunks {
+		if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(i+1)+".txt"), []byte(chunk), 0o644); err != nil {
+			return "", err
+		}
+	}
+	return id, nil
+}
+
+type readPromptTool struct{}
+
+type ReadPromptParams struct {
+	ID    string `json:"id"`
+	Chunk int    `json:"chunk"`
+}
+
+func NewReadPromptTool() BaseTool {
+	return &readPromptTool{}
+}
+
+func (r *readPromptTool) Info() ToolInfo {
+	return ToolInfo{
+		Name: ReadPromptToolName,
+		Description: `Read a chunk of a message of the user that was too large to send in one turn.
+
+When a message is that large, you get a note with the ID of the prompt, its number of chunks and its start and end instead. Read the chunks you need in order, from 1, before answering. Each result says whether more chunks follow.`,
+		Parameters: map[string]any{
+			"id": map[string]any{
+				"type":        "string",
+				"description": "ID of the prompt from the note",
+			},
+			"chunk": map[string]any{
+				"type":        "integer",
+				"description": "Number of the chunk to read, from 1",
+			},
+		},
+		Required: []string{"id", "chunk"},
+	}
+}
+
+func (r *readPromptTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ReadPromptParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	sessionID, _ := GetContextValues(ctx)
+	if sessionID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID is required")
+	}
+	id := strings.TrimSpace(params.ID)
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return NewTextErrorResponse(fmt.Sprintf("invalid prompt id %q", params.ID)), nil
+	}
+
+	dir := filepath.Join(PromptChunksDir(sessionID), id)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("no prompt %q in this session", id)), nil
+	}
+	total := len(entries)
+	if params.Chunk < 1 || params.Chunk > total {
+		return NewTextErrorResponse(fmt.Sprintf("prompt %s has chunks 1 to %d", id, total)), nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, strconv.Itoa(params.Chunk)+".txt"))
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("failed to read chunk %d of prompt %s: %w", params.Chunk, id, err)
+	}
+
+	next := "This is the last chunk."
+	if params.Chunk < total {
+		next = fmt.Sprintf("Chunk %d follows.", params.Chunk+1)
+	}
+	return NewTextResponse(fmt.Sprintf("Chunk %d of %d of prompt %s. %s\n\n%s", params.Chunk, total, id, next, data)), nil
+}
@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitChunks(t *testing.T) {
+	assert.Equal(t, []string{"one\ntwo\n", "three\n", "four"}, SplitChunks("one\ntwo\nthree\nfour", 9))
+	assert.Equal(t, []string{"abcd", "efgh", "ij"}, SplitChunks("abcdefghij", 4), "lines longer than a chunk are cut")
+	assert.Equal(t, []string{"aé", "éé"}, SplitChunks("aééé", 4), "runes are not cut")
+	assert.Empty(t, SplitChunks("", 4))
+}
+
+func TestReadPromptTool(t *testing.T) {
+	cfg := useWorkingDir(t, t.TempDir())
+	dataDir := cfg.Data.Directory
+	cfg.Data.Directory = t.TempDir()
+	t.Cleanup(func() { cfg.Data.Directory = dataDir })
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "large")
+
+	id, err := SavePromptChunks("large", []string{"first\n", "second\n"})
+	require.NoError(t, err)
+
+	read := func(ctx context.Context, params ReadPromptParams) ToolResponse {
+		input, err := json.Marshal(params)
+		require.NoError(t, err)
+		response, err := NewReadPromptTool().Run(ctx, ToolCall{Input: string(input)})
+		require.NoError(t, err)
+		return response
+	}
+
+	response := read(ctx, ReadPromptParams{ID: id, Chunk: 1})
+	require.False(t, response.IsError, response.Content)
+	assert.True(t, strings.HasPrefix(response.Content, "Chunk 1 of 2 of prompt "+id+". Chunk 2 follows."))
+	assert.True(t, strings.HasSuffix(response.Content, "\n\nfirst\n"))
+	assert.Contains(t, read(ctx, ReadPromptParams{ID: id, Chunk: 2}).Content, "This is the last chunk.")
+
+	assert.True(t, read(ctx, ReadPromptParams{ID: id, Chunk: 3}).IsError)
+	assert.True(t, read(ctx, ReadPromptParams{ID: "../large/" + id, Chunk: 1}).IsError)
+	other := context.WithValue(context.Background(), SessionIDContextKey, "other")
+	assert.True(t, read(other, ReadPromptParams{ID: id, Chunk: 1}).IsError, "prompts belong to their session")
+}
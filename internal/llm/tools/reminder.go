@@ -0,0 +1,227 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kirmad/superopencode/internal/locale"
+	"github.com/kirmad/superopencode/internal/pubsub"
+)
+
+const (
+	ReminderToolName = "reminder"
+
+	// ReminderFiredEvent is published when a reminder is due
+	ReminderFiredEvent pubsub.EventType = "fired"
+
+	// Limits that keep reminders from flooding a session
+	maxSessionReminders = 10
+	minReminderDelay    = 10 * time.Second
+	maxReminderDelay    = 24 * time.Hour
+)
+
+// Reminder is a note the agent asked to get back at a later time
+type Reminder struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"-"`
+	Note      string    `json:"note"`
+	Set       time.Time `json:"set"`
+	Due       time.Time `json:"due"`
+}
+
+// Message returns the note the agent receives when the reminder fires
+func (r Reminder) Message() string {
+	return fmt.Sprintf(`<system-reminder>
+Reminder %s, set at %s and due at %s:
+%s
+It is %s now. Follow up on it, or tell the user if there is nothing to do.
+</system-reminder>`, r.ID, locale.Clock(r.Set), locale.Clock(r.Due), r.Note, locale.Clock(time.Now()))
+}
+
+type reminderStore struct {
+	*pubsub.Broker[Reminder]
+	mu        sync.Mutex
+	reminders map[string]Reminder
+	timers    map[string]*time.Timer
+}
+
+var reminders = &reminderStore{
+	Broker:    pubsub.NewBroker[Reminder](),
+	reminders: make(map[string]Reminder),
+	timers:    make(map[string]*time.Timer),
+}
+
+// SubscribeReminders returns the reminders as they fire. Reminders can only
+// be set while someone is subscribed to deliver them.
+func SubscribeReminders(ctx context.Context) <-chan pubsub.Event[Reminder] {
+	return reminders.Subscribe(ctx)
+}
+
+// SessionReminders returns the pending reminders of a session, the next due
+// first
+func SessionReminders(sessionID string) []Reminder {
+	reminders.mu.Lock()
+	defer reminders.mu.Unlock()
+	var pending []Reminder
+	for _, reminder := range reminders.reminders {
+		if reminder.SessionID == sessionID {
+			pending = append(pending, reminder)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Due.Before(pending[j].Due) })
+	return pending
+}
+
+func (s *reminderStore) add(sessionID, note string, delay time.Duration) (Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, reminder := range s.reminders {
+		if reminder.SessionID == sessionID {
+			count++
+		}
+	}
+	if count >= maxSessionReminders {
+		return Reminder{}, fmt.Errorf("this session has %d pending reminders already, cancel one first", count)
+	}
+
+	now := time.Now()
+	reminder := Reminder{
+		ID:        uuid.New().String()[:8],
+		SessionID: sessionID,
+		Note:      note,
+		Set:       now,
+		Due:       now.Add(delay),
+	}
+	s.reminders[reminder.ID] = reminder
+	s.timers[reminder.ID] = time.AfterFunc(delay, func() {
+		s.mu.Lock()
+		_, pending := s.reminders[reminder.ID]
+		delete(s.reminders, reminder.ID)
+		delete(s.timers, reminder.ID)
+		s.mu.Unlock()
+		if pending {
+			s.Publish(ReminderFiredEvent, reminder)
+		}
+	})
+	return reminder, nil
+}
+
+func (s *reminderStore) cancel(sessionID, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reminder, ok := s.reminders[id]
+	if !ok || reminder.SessionID != sessionID {
+		return false
+	}
+	s.timers[id].Stop()
+	delete(s.reminders, id)
+	delete(s.timers, id)
+	return true
+}
+
+type reminderTool struct{}
+
+type ReminderParams struct {
+	Action string `json:"action"`
+	In     string `json:"in"`
+	Note   string `json:"note"`
+	ID     string `json:"id"`
+}
+
+func NewReminderTool() BaseTool {
+	return &reminderTool{}
+}
+
+func (r *reminderTool) Info() ToolInfo {
+	return ToolInfo{
+		Name: ReminderToolName,
+		Description: `Tell the time, and set reminders that come back to you as a message in this session once they are due, while the user keeps the session open.
+
+Use a reminder when you kicked off something external that takes a while, e.g. a CI pipeline, a deployment or a long build, instead of waiting or polling: set a reminder such as "re-check CI of PR 123" in 10m, finish your turn, and check on it when the reminder arrives. The reminder message repeats the note, so write what to do and how.
+
+Actions:
+- set: remind you of the note after the delay in "in", e.g. "90s", "10m" or "1h30m"
+- list: show the current time and the pending reminders of this session
+- cancel: cancel the pending reminder with the given id`,
+		Parameters: map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"description": "What to do",
+				"enum":        []string{"set", "list", "cancel"},
+			},
+			"in": map[string]any{
+				"type":        "string",
+				"description": "Delay before the reminder fires, between 10s and 24h, e.g. \"10m\" (set)",
+			},
+			"note": map[string]any{
+				"type":        "string",
+				"description": "What to remind you of (set)",
+			},
+			"id": map[string]any{
+				"type":        "string",
+				"description": "ID of the reminder to cancel (cancel)",
+			},
+		},
+		Required: []string{"action"},
+	}
+}
+
+func (r *reminderTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ReminderParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	sessionID, _ := GetContextValues(ctx)
+	if sessionID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID is required")
+	}
+	now := locale.DateTime(time.Now())
+
+	switch params.Action {
+	case "set":
+		if reminders.GetSubscriberCount() == 0 {
+			return NewTextErrorResponse("reminders only work in interactive sessions, nothing would deliver this one"), nil
+		}
+		note := strings.TrimSpace(params.Note)
+		if note == "" {
+			return NewTextErrorResponse("note is required"), nil
+		}
+		delay, err := time.ParseDuration(strings.TrimSpace(params.In))
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("invalid delay %q, use a duration such as \"90s\", \"10m\" or \"1h30m\"", params.In)), nil
+		}
+		if delay < minReminderDelay || delay > maxReminderDelay {
+			return NewTextErrorResponse(fmt.Sprintf("the delay must be between %s and %s", minReminderDelay, maxReminderDelay)), nil
+		}
+		reminder, err := reminders.add(sessionID, note, delay)
+		if err != nil {
+			return NewTextErrorResponse(err.Error()), nil
+		}
+		return NewTextResponse(fmt.Sprintf("It is %s. Reminder %s is due at %s, you will get it as a message. Finish your turn instead of waiting for it.", now, reminder.ID, locale.Clock(reminder.Due))), nil
+	case "list":
+		pending := SessionReminders(sessionID)
+		if len(pending) == 0 {
+			return NewTextResponse(fmt.Sprintf("It is %s. There are no pending reminders.", now)), nil
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "It is %s. Pending reminders:\n", now)
+		for _, reminder := range pending {
+			fmt.Fprintf(&sb, "- %s due at %s (in %s): %s\n", reminder.ID, locale.Clock(reminder.Due), time.Until(reminder.Due).Round(time.Second), reminder.Note)
+		}
+		return NewTextResponse(strings.TrimRight(sb.String(), "\n")), nil
+	case "cancel":
+		if !reminders.cancel(sessionID, params.ID) {
+			return NewTextErrorResponse(fmt.Sprintf("no pending reminder %q", params.ID)), nil
+		}
+		return NewTextResponse(fmt.Sprintf("Canceled reminder %s.", params.ID)), nil
+	default:
+		return NewTextErrorResponse(fmt.Sprintf("invalid action %q, use set, list or cancel", params.Action)), nil
+	}
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runReminder(t *testing.T, ctx context.Context, params ReminderParams) ToolResponse {
+	t.Helper()
+	input, err := json.Marshal(params)
+	require.NoError(t, err)
+	response, err := NewReminderTool().Run(ctx, ToolCall{Input: string(input)})
+	require.NoError(t, err)
+	return response
+}
+
+func TestReminderTool(t *testing.T) {
+	ctx := context.WithValue(context.Background(), SessionIDContextKey, "reminders")
+
+	response := runReminder(t, ctx, ReminderParams{Action: "set", In: "10m", Note: "re-check CI"})
+	assert.True(t, response.IsError, "nothing delivers reminders")
+
+	subCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := SubscribeReminders(subCtx)
+
+	response = runReminder(t, ctx, ReminderParams{Action: "set", In: "1s", Note: "too soon"})
+	assert.Contains(t, response.Content, "between")
+	response = runReminder(t, ctx, ReminderParams{Action: "set", In: "ten minutes", Note: "re-check CI"})
+	assert.Contains(t, response.Content, "invalid delay")
+
+	response = runReminder(t, ctx, ReminderParams{Action: "set", In: "10m", Note: "re-check CI"})
+	require.False(t, response.IsError, response.Content)
+	pending := SessionReminders("reminders")
+	require.Len(t, pending, 1)
+	assert.Equal(t, "re-check CI", pending[0].Note)
+
+	response = runReminder(t, ctx, ReminderParams{Action: "list"})
+	assert.Contains(t, response.Content, pending[0].ID)
+
+	other := context.WithValue(context.Background(), SessionIDContextKey, "other")
+	response = runReminder(t, other, ReminderParams{Action: "cancel", ID: pending[0].ID})
+	assert.True(t, response.IsError, "reminders belong to their session")
+	response = runReminder(t, ctx, ReminderParams{Action: "cancel", ID: pending[0].ID})
+	assert.False(t, response.IsError)
+	assert.Empty(t, SessionReminders("reminders"))
+
+	// Reminders fire once due
+	reminder, err := reminders.add("reminders", "build done?", 10*time.Millisecond)
+	require.NoError(t, err)
+	select {
+	case event := <-events:
+		assert.Equal(t, ReminderFiredEvent, event.Type)
+		assert.Equal(t, reminder.ID, event.Payload.ID)
+		assert.Contains(t, event.Payload.Message(), "build done?")
+	case <-time.After(time.Second):
+		t.Fatal("the reminder didn't fire")
+	}
+	assert.Empty(t, SessionReminders("reminders"))
+}
@@ -0,0 +1,392 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+type SastScanParams struct {
+	Path     string   `json:"path"`
+	Scanners []string `json:"scanners"`
+}
+
+type sastScanTool struct{}
+
+const (
+	SastScanToolName = "sast_scan"
+
+	ScannerGosec   = "gosec"
+	ScannerSemgrep = "semgrep"
+	ScannerBandit  = "bandit"
+
+	maxSastFindings = 100
+
+	sastScanDescription = `Runs static application security testing (SAST) scanners over the code and returns their findings in one normalized list, sorted by severity.
+
+WHEN TO USE THIS TOOL:
+- Use at the start of a security review, to find the known insecure patterns before reading the code
+- Use after fixing a finding, to check that it is gone
+
+HOW TO USE:
+- Provide the directory or file to scan, defaults to the whole project
+- Optionally name the scanners to run: gosec (Go), semgrep (many languages) and bandit (Python). By default every installed scanner that applies to the project runs
+
+OUTPUT:
+- One line per finding: severity, confidence, scanner and rule, CWE, location and message
+- The list of scanners that ran, and of those that were skipped because they are not installed
+
+LIMITATIONS:
+- Scanners report false positives: read the code of each finding before reporting it
+- Scanners miss logic flaws like broken authorization, review those by reading the code
+`
+)
+
+// SastFinding is a finding of a scanner in the common schema
+type SastFinding struct {
+	Scanner    string `json:"scanner"`
+	Rule       string `json:"rule"`
+	Severity   string `json:"severity"`   // high, medium or low
+	Confidence string `json:"confidence"` // high, medium, low or empty when unknown
+	CWE        string `json:"cwe"`        // e.g. "CWE-89", empty when unknown
+	File       string `json:"file"`       // Relative to the working directory
+	Line       int    `json:"line"`
+	Message    string `json:"message"`
+}
+
+var severityRank = map[string]int{"high": 0, "medium": 1, "low": 2}
+
+func NewSastScanTool() BaseTool {
+	return &sastScanTool{}
+}
+
+func (s *sastScanTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        SastScanToolName,
+		Description: sastScanDescription,
+		Parameters: map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "The directory or file to scan, defaults to the working directory",
+			},
+			"scanners": map[string]any{
+				"type":        "array",
+				"description": "The scanners to run, defaults to all applicable installed scanners",
+				"items": map[string]any{
+					"type": "string",
+					"enum": []string{ScannerGosec, ScannerSemgrep, ScannerBandit},
+				},
+			},
+		},
+	}
+}
+
+func (s *sastScanTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params SastScanParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	wd := config.WorkingDirectory()
+	target := wd
+	if params.Path != "" {
+		target = params.Path
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(wd, target)
+		}
+	}
+	if _, err := os.Stat(target); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("path not found: %s", target)), nil
+	}
+
+	scanners := params.Scanners
+	if len(scanners) == 0 {
+		scanners = applicableScanners(wd)
+	}
+	var findings []SastFinding
+	var ran, missing, failed []string
+	for _, scanner := range scanners {
+		if _, err := exec.LookPath(scanner); err != nil {
+			missing = append(missing, scanner)
+			continue
+		}
+		result, err := runScanner(ctx, scanner, target, wd)
+		if ctx.Err() != nil {
+			return ToolResponse{}, ctx.Err()
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", scanner, err))
+			continue
+		}
+		ran = append(ran, scanner)
+		findings = append(findings, result...)
+	}
+	if len(ran) == 0 && len(failed) == 0 {
+		return NewTextErrorResponse(fmt.Sprintf("no scanner installed, install one of: %s", strings.Join(missing, ", "))), nil
+	}
+	sortFindings(findings)
+	return NewTextResponse(formatFindings(findings, ran, missing, failed)), nil
+}
+
+// applicableScanners returns the scanners for the languages of the project
+func applicableScanners(wd string) []string {
+	scanners := []string{}
+	if fileExists(filepath.Join(wd, "go.mod")) {
+		scanners = append(scanners, ScannerGosec)
+	}
+	scanners = append(scanners, ScannerSemgrep)
+	for _, marker := range []string{"pyproject.toml", "setup.py", "requirements.txt"} {
+		if fileExists(filepath.Join(wd, marker)) {
+			scanners = append(scanners, ScannerBandit)
+			break
+		}
+	}
+	return scanners
+}
+
+func runScanner(ctx context.Context, scanner, target, wd string) ([]SastFinding, error) {
+	var cmd *exec.Cmd
+	switch scanner {
+	case ScannerGosec:
+		pattern := "./..."
+		dir := target
+		if info, err := os.Stat(target); err == nil && !info.IsDir() {
+			dir, pattern = filepath.Dir(target), "."
+		}
+		cmd = exec.CommandContext(ctx, "gosec", "-fmt=json", "-quiet", "-no-fail", pattern)
+		cmd.Dir = dir
+	case ScannerSemgrep:
+		cmd = exec.CommandContext(ctx, "semgrep", "scan", "--config", "auto", "--json", "--quiet", target)
+		cmd.Dir = wd
+	case ScannerBandit:
+		cmd = exec.CommandContext(ctx, "bandit", "-r", target, "-f", "json", "-q")
+		cmd.Dir = wd
+	default:
+		return nil, fmt.Errorf("unknown scanner")
+	}
+	// Scanners exit with an error status when they find issues, so the output
+	// decides whether the scan worked
+	out, runErr := cmd.Output()
+	findings, err := parseScannerOutput(scanner, out, wd)
+	if err != nil {
+		if runErr != nil {
+			return nil, runErr
+		}
+		return nil, err
+	}
+	return findings, nil
+}
+
+// parseScannerOutput normalizes the JSON report of a scanner
+func parseScannerOutput(scanner string, out []byte, wd string) ([]SastFinding, error) {
+	var findings []SastFinding
+	switch scanner {
+	case ScannerGosec:
+		var report struct {
+			Issues []struct {
+				Severity   string `json:"severity"`
+				Confidence string `json:"confidence"`
+				CWE        struct {
+					ID string `json:"id"`
+				} `json:"cwe"`
+				RuleID  string `json:"rule_id"`
+				Details string `json:"details"`
+				File    string `json:"file"`
+				Line    string `json:"line"` // A range like "12-14" for multi-line issues
+			} `json:"Issues"`
+		}
+		if err := json.Unmarshal(out, &report); err != nil {
+			return nil, fmt.Errorf("failed to parse the report: %w", err)
+		}
+		for _, issue := range report.Issues {
+			first, _, _ := strings.Cut(issue.Line, "-")
+			line, _ := strconv.Atoi(first)
+			findings = append(findings, SastFinding{
+				Scanner:    scanner,
+				Rule:       issue.RuleID,
+				Severity:   normalizeSeverity(issue.Severity),
+				Confidence: normalizeSeverity(issue.Confidence),
+				CWE:        cweID(issue.CWE.ID),
+				File:       relativePath(issue.File, wd),
+				Line:       line,
+				Message:    issue.Details,
+			})
+		}
+	case ScannerSemgrep:
+		var report struct {
+			Results []struct {
+				CheckID string `json:"check_id"`
+				Path    string `json:"path"`
+				Start   struct {
+					Line int `json:"line"`
+				} `json:"start"`
+				Extra struct {
+					Message  string `json:"message"`
+					Severity string `json:"severity"`
+					Metadata struct {
+						CWE        any    `json:"cwe"` // A string or a list of strings
+						Confidence string `json:"confidence"`
+					} `json:"metadata"`
+				} `json:"extra"`
+			} `json:"results"`
+		}
+		if err := json.Unmarshal(out, &report); err != nil {
+			return nil, fmt.Errorf("failed to parse the report: %w", err)
+		}
+		for _, result := range report.Results {
+			var cwe string
+			switch v := result.Extra.Metadata.CWE.(type) {
+			case string:
+				cwe = v
+			case []any:
+				if len(v) > 0 {
+					cwe, _ = v[0].(string)
+				}
+			}
+			cwe, _, _ = strings.Cut(cwe, ":")
+			findings = append(findings, SastFinding{
+				Scanner:    scanner,
+				Rule:       result.CheckID,
+				Severity:   normalizeSeverity(result.Extra.Severity),
+				Confidence: normalizeSeverity(result.Extra.Metadata.Confidence),
+				CWE:        strings.TrimSpace(cwe),
+				File:       relativePath(result.Path, wd),
+				Line:       result.Start.Line,
+				Message:    strings.TrimSpace(result.Extra.Message),
+			})
+		}
+	case ScannerBandit:
+		var report struct {
+			Results []struct {
+				Filename        string `json:"filename"`
+				LineNumber      int    `json:"line_number"`
+				IssueSeverity   string `json:"issue_severity"`
+				IssueConfidence string `json:"issue_confidence"`
+				IssueCWE        struct {
+					ID int `json:"id"`
+				} `json:"issue_cwe"`
+				TestID    string `json:"test_id"`
+				IssueText string `json:"issue_text"`
+			} `json:"results"`
+		}
+		if err := json.Unmarshal(out, &report); err != nil {
+			return nil, fmt.Errorf("failed to parse the report: %w", err)
+		}
+		for _, result := range report.Results {
+			cwe := ""
+			if result.IssueCWE.ID > 0 {
+				cwe = cweID(strconv.Itoa(result.IssueCWE.ID))
+			}
+			findings = append(findings, SastFinding{
+				Scanner:    scanner,
+				Rule:       result.TestID,
+				Severity:   normalizeSeverity(result.IssueSeverity),
+				Confidence: normalizeSeverity(result.IssueConfidence),
+				CWE:        cwe,
+				File:       relativePath(result.Filename, wd),
+				Line:       result.LineNumber,
+				Message:    result.IssueText,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// normalizeSeverity maps the levels of the scanners to high, medium and low
+func normalizeSeverity(level string) string {
+	switch strings.ToLower(level) {
+	case "critical", "high", "error":
+		return "high"
+	case "medium", "warning", "moderate":
+		return "medium"
+	case "low", "info", "note":
+		return "low"
+	}
+	return ""
+}
+
+func cweID(id string) string {
+	if id == "" {
+		return ""
+	}
+	return "CWE-" + id
+}
+
+func relativePath(path, wd string) string {
+	if !filepath.IsAbs(path) {
+		return filepath.ToSlash(filepath.Clean(path))
+	}
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// sortFindings orders findings by severity, then confidence, then location
+func sortFindings(findings []SastFinding) {
+	rank := func(level string) int {
+		if r, ok := severityRank[level]; ok {
+			return r
+		}
+		return len(severityRank)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if rank(a.Severity) != rank(b.Severity) {
+			return rank(a.Severity) < rank(b.Severity)
+		}
+		if rank(a.Confidence) != rank(b.Confidence) {
+			return rank(a.Confidence) < rank(b.Confidence)
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+}
+
+func formatFindings(findings []SastFinding, ran, missing, failed []string) string {
+	var sb strings.Builder
+	counts := make(map[string]int)
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	fmt.Fprintf(&sb, "%d finding(s): %d high, %d medium, %d low\n", len(findings), counts["high"], counts["medium"], counts["low"])
+	fmt.Fprintf(&sb, "Scanners run: %s\n", strings.Join(ran, ", "))
+	if len(missing) > 0 {
+		fmt.Fprintf(&sb, "Not installed: %s\n", strings.Join(missing, ", "))
+	}
+	for _, failure := range failed {
+		fmt.Fprintf(&sb, "Failed: %s\n", failure)
+	}
+	if len(findings) > 0 {
+		sb.WriteString("\n")
+	}
+	for i, f := range findings {
+		if i == maxSastFindings {
+			fmt.Fprintf(&sb, "... and %d more, scan a narrower path to see them\n", len(findings)-i)
+			break
+		}
+		labels := []string{f.Severity}
+		if f.Confidence != "" {
+			labels = append(labels, "confidence "+f.Confidence)
+		}
+		rule := f.Scanner
+		if f.Rule != "" {
+			rule += " " + f.Rule
+		}
+		labels = append(labels, rule)
+		if f.CWE != "" {
+			labels = append(labels, f.CWE)
+		}
+		fmt.Fprintf(&sb, "[%s] %s:%d %s\n", strings.Join(labels, ", "), f.File, f.Line, f.Message)
+	}
+	return sb.String()
+}
@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScannerOutput(t *testing.T) {
+	wd := "/work/app"
+
+	gosec, err := parseScannerOutput(ScannerGosec, []byte(`{"Issues": [
+  {"severity": "MEDIUM", "confidence": "HIGH", "cwe": {"id": "22"}, "rule_id": "G304", "details": "Potential file inclusion via variable", "file": "/work/app/store/store.go", "line": "42-44"}
+]}`), wd)
+	require.NoError(t, err)
+	assert.Equal(t, []SastFinding{{Scanner: ScannerGosec, Rule: "G304", Severity: "medium", Confidence: "high", CWE: "CWE-22", File: "store/store.go", Line: 42, Message: "Potential file inclusion via variable"}}, gosec)
+
+	semgrep, err := parseScannerOutput(ScannerSemgrep, []byte(`{"results": [
+  {"check_id": "python.lang.security.audit.formatted-sql-query", "path": "app/db.py", "start": {"line": 12},
+   "extra": {"message": "SQL built with string formatting", "severity": "ERROR", "metadata": {"cwe": ["CWE-89: SQL Injection"], "confidence": "MEDIUM"}}}
+]}`), wd)
+	require.NoError(t, err)
+	assert.Equal(t, []SastFinding{{Scanner: ScannerSemgrep, Rule: "python.lang.security.audit.formatted-sql-query", Severity: "high", Confidence: "medium", CWE: "CWE-89", File: "app/db.py", Line: 12, Message: "SQL built with string formatting"}}, semgrep)
+
+	bandit, err := parseScannerOutput(ScannerBandit, []byte(`{"results": [
+  {"filename": "./app/run.py", "line_number": 7, "issue_severity": "LOW", "issue_confidence": "HIGH", "issue_cwe": {"id": 78}, "test_id": "B404", "issue_text": "Consider possible security implications of subprocess"}
+]}`), wd)
+	require.NoError(t, err)
+	assert.Equal(t, "app/run.py", bandit[0].File)
+	assert.Equal(t, "CWE-78", bandit[0].CWE)
+	assert.Equal(t, "low", bandit[0].Severity)
+
+	_, err = parseScannerOutput(ScannerBandit, []byte("not json"), wd)
+	assert.Error(t, err)
+
+	findings := append(append(bandit, gosec...), semgrep...)
+	sortFindings(findings)
+	assert.Equal(t, []string{ScannerSemgrep, ScannerGosec, ScannerBandit}, []string{findings[0].Scanner, findings[1].Scanner, findings[2].Scanner})
+}
@@ -0,0 +1,144 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+// minMaskedLength is the length from which values are masked in output,
+// shorter values are rarely secrets and masking them would garble it
+const minMaskedLength = 8
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// EnvVar is an environment variable injected into the commands of a session
+type EnvVar struct {
+	Name  string
+	Value string
+	// Session is true for variables set with /env, false for the defaults of
+	// the config
+	Session bool
+}
+
+// Masked returns the value with all but its length hidden
+func (v EnvVar) Masked() string {
+	if len(v.Value) < minMaskedLength {
+		return strings.Repeat("*", len(v.Value))
+	}
+	return v.Value[:2] + strings.Repeat("*", len(v.Value)-2)
+}
+
+type envStore struct {
+	mu   sync.Mutex
+	vars map[string]map[string]string // sessionID -> name -> value
+}
+
+// sessionEnv holds the variables set with /env. They are only kept in memory
+// so tokens don't end up in the database.
+var sessionEnv = &envStore{vars: make(map[string]map[string]string)}
+
+// SetSessionEnv sets a variable for the commands of a session
+func SetSessionEnv(sessionID, name, value string) error {
+	if !envNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid variable name %q", name)
+	}
+	sessionEnv.mu.Lock()
+	defer sessionEnv.mu.Unlock()
+	if sessionEnv.vars[sessionID] == nil {
+		sessionEnv.vars[sessionID] = make(map[string]string)
+	}
+	sessionEnv.vars[sessionID][name] = value
+	return nil
+}
+
+// UnsetSessionEnv removes a variable set for a session, it reports whether
+// the variable was set
+func UnsetSessionEnv(sessionID, name string) bool {
+	sessionEnv.mu.Lock()
+	defer sessionEnv.mu.Unlock()
+	if _, ok := sessionEnv.vars[sessionID][name]; !ok {
+		return false
+	}
+	delete(sessionEnv.vars[sessionID], name)
+	return true
+}
+
+// SessionEnv returns the variables injected into the commands of a session:
+// the env defaults of the shell config, with ${VAR} references expanded, and
+// the variables set for the session, which take precedence
+func SessionEnv(sessionID string) []EnvVar {
+	vars := make(map[string]EnvVar)
+	if cfg := config.Get(); cfg != nil {
+		for _, entry := range cfg.Shell.Env {
+			name, value, ok := strings.Cut(entry, "=")
+			if !ok || !envNamePattern.MatchString(name) {
+				continue
+			}
+			vars[name] = EnvVar{Name: name, Value: os.ExpandEnv(value)}
+		}
+	}
+
+	sessionEnv.mu.Lock()
+	for name, value := range sessionEnv.vars[sessionID] {
+		vars[name] = EnvVar{Name: name, Value: value, Session: true}
+	}
+	sessionEnv.mu.Unlock()
+
+	result := make([]EnvVar, 0, len(vars))
+	for _, v := range vars {
+		result = append(result, v)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// ParseEnvAssignment parses KEY=VALUE, the value may be quoted
+func ParseEnvAssignment(assignment string) (string, string, error) {
+	name, value, ok := strings.Cut(strings.TrimSpace(assignment), "=")
+	if !ok {
+		return "", "", fmt.Errorf("expected KEY=VALUE")
+	}
+	name = strings.TrimSpace(name)
+	if !envNamePattern.MatchString(name) {
+		return "", "", fmt.Errorf("invalid variable name %q", name)
+	}
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return name, value, nil
+}
+
+// MaskEnv replaces the values of the variables in text, e.g. a token a
+// command printed, with the variable name
+func MaskEnv(text string, vars []EnvVar) string {
+	// Longer values first, so a value containing another is masked whole
+	sorted := append([]EnvVar(nil), vars...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i].Value) > len(sorted[j].Value) })
+	for _, v := range sorted {
+		if len(v.Value) >= minMaskedLength {
+			text = strings.ReplaceAll(text, v.Value, "[masked $"+v.Name+"]")
+		}
+	}
+	return text
+}
+
+// envScript exports the variables before a command and restores the previous
+// values after it, so they don't leak into the commands of other sessions
+// sharing the shell
+func envScript(vars []EnvVar) (setup, restore string) {
+	var set, reset strings.Builder
+	for i, v := range vars {
+		saved := fmt.Sprintf("__opencode_env_%d", i)
+		fmt.Fprintf(&set, "if [ -n \"${%s+x}\" ]; then %s=\"$%s\"; %s_set=1; else %s_set=; fi\n", v.Name, saved, v.Name, saved, saved)
+		fmt.Fprintf(&set, "export %s=%s\n", v.Name, shellQuote(v.Value))
+		fmt.Fprintf(&reset, "if [ -n \"$%s_set\" ]; then export %s=\"$%s\"; else unset %s; fi\n", saved, v.Name, saved, v.Name)
+		fmt.Fprintf(&reset, "unset %s %s_set\n", saved, saved)
+	}
+	return set.String(), reset.String()
+}
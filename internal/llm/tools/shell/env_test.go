@@ -0,0 +1,84 @@
+package shell
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionEnv(t *testing.T) {
+	require.NoError(t, SetSessionEnv("s1", "TOKEN", "secret-value"))
+	require.NoError(t, SetSessionEnv("s1", "API_URL", "http://localhost"))
+	assert.Error(t, SetSessionEnv("s1", "1BAD", "x"))
+
+	assert.Equal(t, []EnvVar{
+		{Name: "API_URL", Value: "http://localhost", Session: true},
+		{Name: "TOKEN", Value: "secret-value", Session: true},
+	}, SessionEnv("s1"))
+	assert.Empty(t, SessionEnv("s2"))
+
+	assert.True(t, UnsetSessionEnv("s1", "TOKEN"))
+	assert.False(t, UnsetSessionEnv("s1", "TOKEN"))
+	assert.Len(t, SessionEnv("s1"), 1)
+}
+
+func TestParseEnvAssignment(t *testing.T) {
+	tests := []struct {
+		input string
+		name  string
+		value string
+		err   bool
+	}{
+		{input: "KEY=value", name: "KEY", value: "value"},
+		{input: `KEY="a=b c"`, name: "KEY", value: "a=b c"},
+		{input: "KEY='x'", name: "KEY", value: "x"},
+		{input: "KEY=", name: "KEY", value: ""},
+		{input: "KEY", err: true},
+		{input: "MY-KEY=x", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			name, value, err := ParseEnvAssignment(tt.input)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.name, name)
+			assert.Equal(t, tt.value, value)
+		})
+	}
+}
+
+func TestMaskEnv(t *testing.T) {
+	vars := []EnvVar{
+		{Name: "SHORT", Value: "abc"},
+		{Name: "TOKEN", Value: "tok_12345"},
+		{Name: "LONG", Value: "tok_12345_suffix"},
+	}
+	masked := MaskEnv("abc tok_12345 tok_12345_suffix", vars)
+	assert.Equal(t, "abc [masked $TOKEN] [masked $LONG]", masked)
+
+	assert.Equal(t, "se**********", EnvVar{Value: "secret-value"}.Masked())
+	assert.Equal(t, "***", EnvVar{Value: "abc"}.Masked())
+}
+
+func TestEnvScriptRestoresVariables(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	setup, restore := envScript([]EnvVar{
+		{Name: "OPENCODE_SET", Value: "it's new"},
+		{Name: "OPENCODE_UNSET", Value: "added"},
+	})
+	script := "OPENCODE_SET=old\n" + setup +
+		`echo "$OPENCODE_SET|$OPENCODE_UNSET"` + "\n" + restore +
+		`echo "$OPENCODE_SET|${OPENCODE_UNSET-unset}"`
+	output, err := exec.Command(sh, "-c", script).Output()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"it's new|added", "old|unset"}, strings.Split(strings.TrimSpace(string(output)), "\n"))
+}
@@ -26,6 +26,7 @@ type PersistentShell struct {
 
 type commandExecution struct {
 	command    string
+	env        []EnvVar
 	timeout    time.Duration
 	resultChan chan commandResult
 	ctx        context.Context
@@ -131,12 +132,12 @@ func newPersistentShell(cwd string) *PersistentShell {
 
 func (s *PersistentShell) processCommands() {
 	for cmd := range s.commandQueue {
-		result := s.execCommand(cmd.command, cmd.timeout, cmd.ctx)
+		result := s.execCommand(cmd.command, cmd.env, cmd.timeout, cmd.ctx)
 		cmd.resultChan <- result
 	}
 }
 
-func (s *PersistentShell) execCommand(command string, timeout time.Duration, ctx context.Context) commandResult {
+func (s *PersistentShell) execCommand(command string, env []EnvVar, timeout time.Duration, ctx context.Context) commandResult {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -161,15 +162,18 @@ func (s *PersistentShell) execCommand(command string, timeout time.Duration, ctx
 		os.Remove(cwdFile)
 	}()
 
+	setupEnv, restoreEnv := envScript(env)
 	fullCommand := fmt.Sprintf(`
-eval %s < /dev/null > %s 2> %s
+%seval %s < /dev/null > %s 2> %s
 EXEC_EXIT_CODE=$?
-pwd > %s
+%spwd > %s
 echo $EXEC_EXIT_CODE > %s
 `,
+		setupEnv,
 		shellQuote(command),
 		shellQuote(stdoutFile),
 		shellQuote(stderrFile),
+		restoreEnv,
 		shellQuote(cwdFile),
 		shellQuote(statusFile),
 	)
@@ -268,7 +272,8 @@ func (s *PersistentShell) killChildren() {
 	}
 }
 
-func (s *PersistentShell) Exec(ctx context.Context, command string, timeoutMs int) (string, string, int, bool, error) {
+// Exec runs a command with the variables exported for its duration
+func (s *PersistentShell) Exec(ctx context.Context, command string, env []EnvVar, timeoutMs int) (string, string, int, bool, error) {
 	if !s.isAlive {
 		return "", "Shell is not alive", 1, false, errors.New("shell is not alive")
 	}
@@ -278,6 +283,7 @@ func (s *PersistentShell) Exec(ctx context.Context, command string, timeoutMs in
 	resultChan := make(chan commandResult)
 	s.commandQueue <- &commandExecution{
 		command:    command,
+		env:        env,
 		timeout:    timeout,
 		resultChan: resultChan,
 		ctx:        ctx,
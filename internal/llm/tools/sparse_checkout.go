@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/permission"
+	"github.com/kirmad/superopencode/internal/sparse"
+)
+
+type SparseCheckoutParams struct {
+	Paths []string `json:"paths"`
+}
+
+type SparseCheckoutPermissionsParams struct {
+	Paths []string `json:"paths"`
+}
+
+type sparseCheckoutTool struct {
+	permissions permission.Service
+}
+
+const (
+	SparseCheckoutToolName = "sparse_checkout"
+
+	sparseCheckoutDescription = `Shows and widens the sparse checkout of the repository, when only part of its files are on disk.
+
+WHEN TO USE THIS TOOL:
+- Use when ls reports entries that are not checked out, or a path you need is outside the sparse checkout
+- Use before reading or editing files outside the sparse set, instead of recreating them
+
+HOW TO USE:
+- Without paths: shows the sparse set, whether the repository is a partial clone, and the entries of the working directory that are not checked out
+- With paths: adds the directories to the sparse set and checks out their files, after the user approves
+- Paths are directories, relative to the working directory or absolute; when the checkout is not in cone mode they are added as patterns
+
+NOTES:
+- Add the smallest directories you need: in a huge repository, checking out a whole top-level directory can take long and fill the disk
+- In a partial clone, the added files are downloaded from the remote
+`
+)
+
+func NewSparseCheckoutTool(permissions permission.Service) BaseTool {
+	return &sparseCheckoutTool{permissions: permissions}
+}
+
+func (s *sparseCheckoutTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        SparseCheckoutToolName,
+		Description: sparseCheckoutDescription,
+		Parameters: map[string]any{
+			"paths": map[string]any{
+				"type":        "array",
+				"description": "Directories to add to the sparse checkout, empty to show it",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+		},
+	}
+}
+
+func (s *sparseCheckoutTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params SparseCheckoutParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	wd := config.WorkingDirectory()
+	checkout, err := sparse.Detect(ctx, wd)
+	if err != nil {
+		return ToolResponse{}, err
+	}
+	if !checkout.Sparse {
+		if checkout.PartialClone {
+			return NewTextResponse("The repository is a partial clone with all its files checked out, there is nothing to add."), nil
+		}
+		return NewTextErrorResponse("the working directory is not a sparse checkout, all its files are on disk"), nil
+	}
+	if len(params.Paths) == 0 {
+		return NewTextResponse(sparseStatus(ctx, wd, checkout)), nil
+	}
+
+	paths := make([]string, 0, len(params.Paths))
+	for _, path := range params.Paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(wd, path)
+		}
+		rel, err := filepath.Rel(checkout.Root, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return NewTextErrorResponse(fmt.Sprintf("%s is not a directory of the repository %s", path, checkout.Root)), nil
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+
+	sessionID, _ := GetContextValues(ctx)
+	if sessionID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID is required to change the sparse checkout")
+	}
+	if !s.permissions.Request(permission.CreatePermissionRequest{
+		SessionID:   sessionID,
+		Path:        checkout.Root,
+		ToolName:    SparseCheckoutToolName,
+		Action:      "write",
+		Description: fmt.Sprintf("Check out %s", strings.Join(paths, ", ")),
+		Params:      SparseCheckoutPermissionsParams{Paths: paths},
+	}) {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+
+	if err := sparse.Add(ctx, checkout.Root, paths...); err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	return NewTextResponse(fmt.Sprintf("Checked out %s. The sparse set is now: %s",
+		strings.Join(paths, ", "), strings.Join(sparsePatterns(ctx, checkout.Root), ", "))), nil
+}
+
+// sparseStatus describes the sparse checkout of the working directory
+func sparseStatus(ctx context.Context, wd string, checkout sparse.Checkout) string {
+	var sb strings.Builder
+	mode := "patterns"
+	if checkout.Cone {
+		mode = "directories, cone mode"
+	}
+	fmt.Fprintf(&sb, "Repository: %s\nSparse set (%s):\n", checkout.Root, mode)
+	for _, pattern := range checkout.Patterns {
+		fmt.Fprintf(&sb, "- %s\n", pattern)
+	}
+	if checkout.PartialClone {
+		filter := checkout.Filter
+		if filter == "" {
+			filter = "unknown filter"
+		}
+		fmt.Fprintf(&sb, "Partial clone (%s): missing objects are downloaded from the remote when needed\n", filter)
+	}
+	missing, err := sparse.Missing(ctx, wd)
+	if err != nil {
+		fmt.Fprintf(&sb, "Could not list the entries that are not checked out: %s\n", err)
+	} else if len(missing) > 0 {
+		sb.WriteString(strings.TrimPrefix(missingNote(missing), "\n"))
+	}
+	return sb.String()
+}
+
+func sparsePatterns(ctx context.Context, root string) []string {
+	checkout, err := sparse.Detect(ctx, root)
+	if err != nil {
+		return nil
+	}
+	return checkout.Patterns
+}
@@ -0,0 +1,285 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxOutlineSymbols bounds the outline shown for a large file
+	maxOutlineSymbols = 80
+	// maxDefinitionLines bounds the lines of a signature before its body
+	maxDefinitionLines = 20
+)
+
+// fileSymbol is a definition in a file, lines are 1-based and inclusive
+type fileSymbol struct {
+	Name  string
+	Kind  string
+	Start int
+	End   int
+}
+
+// definitionPattern matches the definition lines of common languages. The
+// name is in the first non-empty group.
+var definitionPattern = regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:pub(?:\([^)]*\))?\s+)?(?:async\s+)?(?:(?:public|private|protected|static|abstract|final|override)\s+)*` +
+	`(?:(def|function\*?|func|fn|class|interface|struct|enum|trait|impl|type|module)\s+([A-Za-z_$][\w$]*)` +
+	`|(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*=>|[A-Za-z_$][\w$]*\s*=>))`)
+
+// fileSymbols returns the definitions of a file. Go files are
+// parsed, other languages are matched line by line with their blocks ending at
+// the closing brace or, without braces, at the next line indented as much.
+func fileSymbols(path, content string) []fileSymbol {
+	if strings.ToLower(filepath.Ext(path)) == ".go" {
+		if symbols, err := goSymbols(content); err == nil {
+			return symbols
+		}
+	}
+	lines := strings.Split(content, "\n")
+	var symbols []fileSymbol
+	for i := 0; i < len(lines); i++ {
+		m := definitionPattern.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		kind, name := m[1], m[2]
+		if name == "" {
+			kind, name = "function", m[3]
+		}
+		end := blockEnd(lines, i)
+		symbols = append(symbols, fileSymbol{Name: name, Kind: strings.TrimSuffix(kind, "*"), Start: i + 1, End: end + 1})
+	}
+	return symbols
+}
+
+// goSymbols returns the functions, methods and types of a Go file including
+// their doc comments. Methods are named "Type.Method".
+func goSymbols(content string) ([]fileSymbol, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	var symbols []fileSymbol
+	add := func(name, kind string, node ast.Node, doc *ast.CommentGroup) {
+		start := fset.Position(node.Pos()).Line
+		if doc != nil {
+			start = fset.Position(doc.Pos()).Line
+		}
+		symbols = append(symbols, fileSymbol{Name: name, Kind: kind, Start: start, End: fset.Position(node.End()).Line})
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			name, kind := d.Name.Name, "func"
+			if recv := receiverName(d); recv != "" {
+				name, kind = recv+"."+name, "method"
+			}
+			add(name, kind, d, d.Doc)
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				s := spec.(*ast.TypeSpec)
+				// A single type keeps the doc comment of its declaration
+				doc, node := s.Doc, ast.Node(s)
+				if len(d.Specs) == 1 {
+					doc, node = d.Doc, d
+				}
+				add(s.Name.Name, "type", node, doc)
+			}
+		}
+	}
+	return symbols, nil
+}
+
+// receiverName returns the type of a method receiver, empty for functions
+func receiverName(d *ast.FuncDecl) string {
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return ""
+	}
+	recv := d.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	switch r := recv.(type) {
+	case *ast.IndexExpr:
+		recv = r.X
+	case *ast.IndexListExpr:
+		recv = r.X
+	}
+	if ident, ok := recv.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// findSymbol returns the definition with the given name. "Type.Method" and
+// "Class.method" select a method, a bare name matches methods as well when no
+// top-level definition has it.
+func findSymbol(path, content, name string) (fileSymbol, error) {
+	symbols := fileSymbols(path, content)
+	parent, member, nested := strings.Cut(name, ".")
+	var candidates []fileSymbol
+	for _, s := range symbols {
+		if s.Name == name {
+			return s, nil
+		}
+		if strings.HasSuffix(s.Name, "."+name) {
+			candidates = append(candidates, s)
+		}
+	}
+	// Methods of other languages are nested in the block of their class
+	if nested {
+		for _, p := range symbols {
+			if p.Name != parent {
+				continue
+			}
+			for _, s := range symbols {
+				if s.Name == member && s.Start > p.Start && s.End <= p.End {
+					return s, nil
+				}
+			}
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	if len(candidates) > 1 {
+		var names []string
+		for _, c := range candidates {
+			names = append(names, c.Name)
+		}
+		return fileSymbol{}, fmt.Errorf("%q is ambiguous, use one of: %s", name, strings.Join(names, ", "))
+	}
+	return fileSymbol{}, fmt.Errorf("no definition of %q found", name)
+}
+
+// formatOutline lists the definitions of a file with their line ranges
+func formatOutline(symbols []fileSymbol) string {
+	var sb strings.Builder
+	for i, s := range symbols {
+		if i == maxOutlineSymbols {
+			fmt.Fprintf(&sb, "... and %d more\n", len(symbols)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "%6d-%d %s %s\n", s.Start, s.End, s.Kind, s.Name)
+	}
+	return sb.String()
+}
+
+// blockEnd returns the last line of the block starting at a definition line.
+// Blocks end at their closing brace, blocks of languages without braces at
+// the next line indented as much as the definition.
+func blockEnd(lines []string, start int) int {
+	depth, opened := 0, false
+	for i := start; i < len(lines); i++ {
+		code := strings.TrimSpace(stripStrings(lines[i]))
+		for _, r := range code {
+			switch r {
+			case '{':
+				depth++
+				opened = true
+			case '}':
+				depth--
+			}
+		}
+		if opened {
+			if depth <= 0 {
+				return i
+			}
+			continue
+		}
+		switch {
+		case strings.HasSuffix(code, ";"), i-start >= maxDefinitionLines:
+			return i
+		case strings.HasSuffix(code, ":"), signatureComplete(lines[start:i+1]) && !nextOpensBrace(lines, i):
+			return indentedBlockEnd(lines, start)
+		}
+	}
+	return len(lines) - 1
+}
+
+// signatureComplete reports whether the parentheses of a definition are closed
+// and it doesn't continue on the next line
+func signatureComplete(lines []string) bool {
+	depth := 0
+	for _, line := range lines {
+		depth += strings.Count(stripStrings(line), "(") - strings.Count(stripStrings(line), ")")
+	}
+	last := strings.TrimSpace(stripStrings(lines[len(lines)-1]))
+	for _, suffix := range []string{",", "(", "=", "=>", "->", "|", "&"} {
+		if strings.HasSuffix(last, suffix) {
+			return false
+		}
+	}
+	return depth <= 0
+}
+
+// nextOpensBrace reports whether the next non-empty line starts with a brace,
+// as in Allman style
+func nextOpensBrace(lines []string, i int) bool {
+	for j := i + 1; j < len(lines); j++ {
+		if next := strings.TrimSpace(lines[j]); next != "" {
+			return strings.HasPrefix(next, "{")
+		}
+	}
+	return false
+}
+
+// indentedBlockEnd returns the last line indented deeper than the definition
+func indentedBlockEnd(lines []string, start int) int {
+	indent := indentation(lines[start])
+	end := start
+	for i := start + 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		if indentation(lines[i]) <= indent {
+			// Ruby and Lua close their blocks with end
+			if strings.TrimSpace(lines[i]) == "end" {
+				end = i
+			}
+			break
+		}
+		end = i
+	}
+	return end
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// stripStrings drops quoted strings and line comments, so braces in them
+// don't count
+func stripStrings(line string) string {
+	var sb strings.Builder
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if escaped {
+				escaped = false
+			} else if r == '\\' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		case r == '/' && strings.HasPrefix(line[i:], "//"), r == '#':
+			return sb.String()
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const goSource = `package demo
+
+// Server serves requests
+type Server struct {
+	addr string
+}
+
+// Start starts the server
+func (s *Server) Start() error {
+	return nil
+}
+
+func helper() {
+	_ = "}"
+}
+`
+
+const pythonSource = `import os
+
+
+class Loader:
+    def __init__(self, path):
+        self.path = path
+
+    def load(self):
+        with open(self.path) as f:
+            return f.read()
+
+
+def main():
+    Loader("x").load()
+`
+
+const jsSource = `export class Store {
+  get(key) {
+    return this.items["}"];
+  }
+}
+
+export const add = (a, b) => {
+  return a + b;
+};
+
+function multiline(
+  a,
+  b,
+) {
+  return a * b;
+}
+`
+
+func TestFindSymbolGo(t *testing.T) {
+	s, err := findSymbol("demo.go", goSource, "Server.Start")
+	require.NoError(t, err)
+	assert.Equal(t, fileSymbol{Name: "Server.Start", Kind: "method", Start: 8, End: 11}, s)
+
+	// A bare method name is enough when it is unique
+	s, err = findSymbol("demo.go", goSource, "Start")
+	require.NoError(t, err)
+	assert.Equal(t, 8, s.Start)
+
+	s, err = findSymbol("demo.go", goSource, "Server")
+	require.NoError(t, err)
+	assert.Equal(t, 3, s.Start)
+	assert.Equal(t, 6, s.End)
+
+	_, err = findSymbol("demo.go", goSource, "Missing")
+	assert.Error(t, err)
+}
+
+func TestFindSymbolPython(t *testing.T) {
+	s, err := findSymbol("loader.py", pythonSource, "Loader")
+	require.NoError(t, err)
+	assert.Equal(t, 4, s.Start)
+	assert.Equal(t, 10, s.End)
+
+	s, err = findSymbol("loader.py", pythonSource, "Loader.load")
+	require.NoError(t, err)
+	assert.Equal(t, 8, s.Start)
+	assert.Equal(t, 10, s.End)
+
+	s, err = findSymbol("loader.py", pythonSource, "main")
+	require.NoError(t, err)
+	assert.Equal(t, 13, s.Start)
+	assert.Equal(t, 14, s.End)
+}
+
+func TestFindSymbolJavaScript(t *testing.T) {
+	s, err := findSymbol("store.js", jsSource, "Store")
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.Start)
+	assert.Equal(t, 5, s.End)
+
+	s, err = findSymbol("store.js", jsSource, "add")
+	require.NoError(t, err)
+	assert.Equal(t, 7, s.Start)
+	assert.Equal(t, 9, s.End)
+
+	s, err = findSymbol("store.js", jsSource, "multiline")
+	require.NoError(t, err)
+	assert.Equal(t, 11, s.Start)
+	assert.Equal(t, 16, s.End)
+}
+
+func TestFormatOutline(t *testing.T) {
+	outline := formatOutline(fileSymbols("demo.go", goSource))
+	assert.Contains(t, outline, "3-6 type Server")
+	assert.Contains(t, outline, "8-11 method Server.Start")
+	assert.Contains(t, outline, "13-15 func helper")
+}
@@ -43,6 +43,34 @@ func GetTodoCount(sessionID string) int {
 	return len(todos)
 }
 
+// AddTodos appends pending todos to the list of a session, e.g. tasks found
+// by a workflow outside the agent loop
+func AddTodos(sessionID string, items []TodoItem) {
+	if sessionID == "" || len(items) == 0 {
+		return
+	}
+
+	todoStorage.mu.Lock()
+	defer todoStorage.mu.Unlock()
+
+	todoStorage.todos[sessionID] = append(todoStorage.todos[sessionID], items...)
+}
+
+// SetTodoStatus changes the status of a todo of a session, it reports
+// whether the todo exists
+func SetTodoStatus(sessionID, id, status string) bool {
+	todoStorage.mu.Lock()
+	defer todoStorage.mu.Unlock()
+
+	for i, todo := range todoStorage.todos[sessionID] {
+		if todo.ID == id {
+			todoStorage.todos[sessionID][i].Status = status
+			return true
+		}
+	}
+	return false
+}
+
 // TodoReadTool implements the TodoRead functionality
 type TodoReadTool struct{}
 
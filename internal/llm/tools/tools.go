@@ -12,6 +12,19 @@ type ToolInfo struct {
 	Required    []string
 }
 
+// Schema returns the parameters as an object schema. Most tools only define
+// the properties, some define the whole schema.
+func (i ToolInfo) Schema() map[string]any {
+	if _, ok := i.Parameters["type"]; ok {
+		return i.Parameters
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": i.Parameters,
+		"required":   i.Required,
+	}
+}
+
 type toolResponseType string
 
 type (
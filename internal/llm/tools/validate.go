@@ -0,0 +1,404 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ArgumentProblem is an argument of a tool call that doesn't match the schema
+// of the tool
+type ArgumentProblem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned for tool calls with arguments that can't be
+// repaired. Its response tells the model what to correct.
+type ValidationError struct {
+	Tool     string            `json:"tool"`
+	Problems []ArgumentProblem `json:"problems"`
+	// Expected summarizes the parameters of the tool
+	Expected string `json:"expected"`
+}
+
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Invalid arguments for %s:\n", e.Tool)
+	for _, p := range e.Problems {
+		if p.Path == "" {
+			fmt.Fprintf(&sb, "- %s\n", p.Message)
+		} else {
+			fmt.Fprintf(&sb, "- %s: %s\n", p.Path, p.Message)
+		}
+	}
+	fmt.Fprintf(&sb, "Expected parameters: %s\nCall the tool again with corrected arguments.", e.Expected)
+	return sb.String()
+}
+
+// Response returns the error as a tool response for the model
+func (e *ValidationError) Response() ToolResponse {
+	return WithResponseMetadata(NewTextErrorResponse(e.Error()), e)
+}
+
+// ValidateInput checks the arguments of a tool call against the schema of the
+// tool before it runs. Common mistakes of models are repaired: numbers and
+// booleans sent as strings, arrays and objects sent as JSON strings, enum
+// values in the wrong case and null for optional arguments, which are left
+// out. It returns the input to run the tool with and the repairs made, or a
+// *ValidationError.
+func ValidateInput(info ToolInfo, input string) (string, []string, error) {
+	if strings.TrimSpace(input) == "" {
+		input = "{}"
+	}
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+	var args any
+	if err := decoder.Decode(&args); err != nil {
+		return "", nil, &ValidationError{
+			Tool:     info.Name,
+			Problems: []ArgumentProblem{{Message: fmt.Sprintf("the arguments are not valid JSON: %s", err)}},
+			Expected: describeParameters(info.Schema()),
+		}
+	}
+
+	v := &argumentValidator{}
+	repaired := v.check("", args, info.Schema())
+	if len(v.problems) > 0 {
+		return "", v.repairs, &ValidationError{
+			Tool:     info.Name,
+			Problems: v.problems,
+			Expected: describeParameters(info.Schema()),
+		}
+	}
+	if len(v.repairs) == 0 {
+		return input, nil, nil
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(repaired); err != nil {
+		return input, nil, nil
+	}
+	return strings.TrimSpace(buf.String()), v.repairs, nil
+}
+
+type argumentValidator struct {
+	problems []ArgumentProblem
+	repairs  []string
+}
+
+func (v *argumentValidator) problem(path, format string, args ...any) {
+	v.problems = append(v.problems, ArgumentProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *argumentValidator) repair(path, format string, args ...any) {
+	if path == "" {
+		path = "arguments"
+	}
+	v.repairs = append(v.repairs, path+": "+fmt.Sprintf(format, args...))
+}
+
+// check validates a value against a schema and returns it repaired
+func (v *argumentValidator) check(path string, value any, schema map[string]any) any {
+	if schema == nil {
+		return value
+	}
+	types := schemaTypeList(schema["type"])
+	if len(types) > 0 && !matchesType(value, types) {
+		repaired, ok := coerce(value, types)
+		if !ok {
+			v.problem(path, "expected %s, got %s", strings.Join(types, " or "), describeValue(value))
+			return value
+		}
+		v.repair(path, "converted %s to %s", describeValue(value), strings.Join(types, " or "))
+		value = repaired
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		return v.checkObject(path, value, schema)
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range value {
+			value[i] = v.check(fmt.Sprintf("%s[%d]", path, i), item, items)
+		}
+		return value
+	}
+
+	if enum := schemaEnum(schema["enum"]); len(enum) > 0 {
+		return v.checkEnum(path, value, enum)
+	}
+	return value
+}
+
+func (v *argumentValidator) checkObject(path string, object map[string]any, schema map[string]any) map[string]any {
+	properties, _ := schema["properties"].(map[string]any)
+	required := map[string]bool{}
+	for _, name := range schemaRequiredNames(schema["required"]) {
+		required[name] = true
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := object[name]
+		propertyPath := joinPath(path, name)
+		property, known := properties[name].(map[string]any)
+		if !known {
+			if schema["additionalProperties"] == false {
+				v.problem(propertyPath, "unknown argument")
+			}
+			continue
+		}
+		if value == nil && !required[name] && !allowsNull(property) {
+			delete(object, name)
+			v.repair(propertyPath, "left out null")
+			continue
+		}
+		object[name] = v.check(propertyPath, value, property)
+	}
+
+	for _, name := range schemaRequiredNames(schema["required"]) {
+		if _, ok := object[name]; ok {
+			continue
+		}
+		if property, ok := properties[name].(map[string]any); ok {
+			if def, ok := property["default"]; ok {
+				object[name] = def
+				v.repair(joinPath(path, name), "used the default %v", def)
+				continue
+			}
+		}
+		v.problem(joinPath(path, name), "required argument is missing")
+	}
+	return object
+}
+
+func (v *argumentValidator) checkEnum(path string, value any, enum []string) any {
+	s, ok := value.(string)
+	if !ok {
+		s = fmt.Sprint(value)
+	}
+	for _, allowed := range enum {
+		if s == allowed {
+			return value
+		}
+	}
+	for _, allowed := range enum {
+		if strings.EqualFold(s, allowed) {
+			v.repair(path, "used %q for %q", allowed, s)
+			return allowed
+		}
+	}
+	v.problem(path, "%s is not one of %s", describeValue(value), strings.Join(enum, ", "))
+	return value
+}
+
+func matchesType(value any, types []string) bool {
+	for _, t := range types {
+		switch t {
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := value.(json.Number); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := value.(json.Number); ok {
+				if _, err := n.Int64(); err == nil {
+					return true
+				}
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]any); ok {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]any); ok {
+				return true
+			}
+		case "null":
+			if value == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// coerce converts a value to one of the types, the way models get them wrong
+func coerce(value any, types []string) (any, bool) {
+	for _, t := range types {
+		switch v := value.(type) {
+		case string:
+			s := strings.TrimSpace(v)
+			switch t {
+			case "integer":
+				if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+					return json.Number(strconv.FormatInt(n, 10)), true
+				}
+			case "number":
+				// ParseFloat also accepts NaN and Inf, which JSON doesn't
+				if _, err := strconv.ParseFloat(s, 64); err == nil && json.Valid([]byte(s)) {
+					return json.Number(s), true
+				}
+			case "boolean":
+				if b, err := strconv.ParseBool(s); err == nil {
+					return b, true
+				}
+			case "array", "object":
+				decoder := json.NewDecoder(strings.NewReader(s))
+				decoder.UseNumber()
+				var parsed any
+				if err := decoder.Decode(&parsed); err == nil && matchesType(parsed, []string{t}) {
+					return parsed, true
+				}
+			}
+		case json.Number:
+			switch t {
+			case "string":
+				return v.String(), true
+			case "integer":
+				// 5.0 doesn't decode into an int
+				if f, err := v.Float64(); err == nil && f == math.Trunc(f) {
+					return json.Number(strconv.FormatInt(int64(f), 10)), true
+				}
+			}
+		case bool:
+			if t == "string" {
+				return strconv.FormatBool(v), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func allowsNull(schema map[string]any) bool {
+	for _, t := range schemaTypeList(schema["type"]) {
+		if t == "null" {
+			return true
+		}
+	}
+	return schema["nullable"] == true
+}
+
+func schemaTypeList(t any) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	case []any:
+		var types []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func schemaEnum(enum any) []string {
+	switch enum := enum.(type) {
+	case []string:
+		return enum
+	case []any:
+		values := make([]string, len(enum))
+		for i, v := range enum {
+			values[i] = fmt.Sprint(v)
+		}
+		return values
+	}
+	return nil
+}
+
+func schemaRequiredNames(required any) []string {
+	switch required := required.(type) {
+	case []string:
+		return required
+	case []any:
+		var names []string
+		for _, name := range required {
+			if s, ok := name.(string); ok {
+				names = append(names, s)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func describeValue(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		if len(value) > 40 {
+			value = value[:40] + "..."
+		}
+		return fmt.Sprintf("string %q", value)
+	case json.Number:
+		return "number " + value.String()
+	case bool:
+		return fmt.Sprintf("boolean %t", value)
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// describeParameters summarizes the top level parameters of a schema, e.g.
+// {"file_path": string (required), "limit": integer}
+func describeParameters(schema map[string]any) string {
+	properties, _ := schema["properties"].(map[string]any)
+	required := map[string]bool{}
+	for _, name := range schemaRequiredNames(schema["required"]) {
+		required[name] = true
+	}
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		property, _ := properties[name].(map[string]any)
+		t := strings.Join(schemaTypeList(property["type"]), "|")
+		if t == "" {
+			t = "any"
+		}
+		if enum := schemaEnum(property["enum"]); len(enum) > 0 {
+			t += " (" + strings.Join(enum, ", ") + ")"
+		}
+		if required[name] {
+			t += " (required)"
+		}
+		parts[i] = fmt.Sprintf("%q: %s", name, t)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
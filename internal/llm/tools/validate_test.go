@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var validateInfo = ToolInfo{
+	Name: "view",
+	Parameters: map[string]any{
+		"file_path": map[string]any{"type": "string"},
+		"offset":    map[string]any{"type": "integer"},
+		"recursive": map[string]any{"type": "boolean"},
+		"format":    map[string]any{"type": "string", "enum": []string{"text", "markdown"}},
+		"paths":     map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+	},
+	Required: []string{"file_path"},
+}
+
+func TestValidateInput(t *testing.T) {
+	t.Run("valid input is unchanged", func(t *testing.T) {
+		input := `{"file_path": "main.go", "offset": 10}`
+		got, repairs, err := ValidateInput(validateInfo, input)
+		require.NoError(t, err)
+		assert.Empty(t, repairs)
+		assert.Equal(t, input, got)
+	})
+
+	t.Run("repairs", func(t *testing.T) {
+		got, repairs, err := ValidateInput(validateInfo, `{"file_path": "a<b>.go", "offset": "10", "recursive": "true", "format": "Markdown", "paths": "[\"a\", \"b\"]", "limit": 5, "missing": null}`)
+		require.NoError(t, err)
+		assert.Len(t, repairs, 4)
+
+		var params struct {
+			FilePath  string   `json:"file_path"`
+			Offset    int      `json:"offset"`
+			Recursive bool     `json:"recursive"`
+			Format    string   `json:"format"`
+			Paths     []string `json:"paths"`
+			Limit     int      `json:"limit"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(got), &params))
+		assert.Equal(t, "a<b>.go", params.FilePath)
+		assert.Equal(t, 10, params.Offset)
+		assert.True(t, params.Recursive)
+		assert.Equal(t, "markdown", params.Format)
+		assert.Equal(t, []string{"a", "b"}, params.Paths)
+		assert.Equal(t, 5, params.Limit)
+	})
+
+	t.Run("null optional arguments are left out", func(t *testing.T) {
+		got, repairs, err := ValidateInput(validateInfo, `{"file_path": "main.go", "offset": null, "offset2": 5.0}`)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"offset: left out null"}, repairs)
+		assert.JSONEq(t, `{"file_path": "main.go", "offset2": 5.0}`, got)
+	})
+
+	t.Run("integral floats", func(t *testing.T) {
+		got, _, err := ValidateInput(validateInfo, `{"file_path": "main.go", "offset": 5.0}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"file_path": "main.go", "offset": 5}`, got)
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		got, _, err := ValidateInput(ToolInfo{Name: "ls", Parameters: map[string]any{}}, "")
+		require.NoError(t, err)
+		assert.Equal(t, "{}", got)
+	})
+
+	t.Run("problems", func(t *testing.T) {
+		_, _, err := ValidateInput(validateInfo, `{"offset": "ten", "format": "html", "paths": ["a", {}]}`)
+		var invalid *ValidationError
+		require.ErrorAs(t, err, &invalid)
+		assert.Equal(t, []ArgumentProblem{
+			{Path: "format", Message: `string "html" is not one of text, markdown`},
+			{Path: "offset", Message: `expected integer, got string "ten"`},
+			{Path: "paths[1]", Message: "expected string, got an object"},
+			{Path: "file_path", Message: "required argument is missing"},
+		}, invalid.Problems)
+
+		resp := invalid.Response()
+		assert.True(t, resp.IsError)
+		assert.Contains(t, resp.Content, "Invalid arguments for view:")
+		assert.Contains(t, resp.Content, `"file_path": string (required)`)
+		assert.Contains(t, resp.Metadata, `"path":"offset"`)
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		_, _, err := ValidateInput(validateInfo, `{"file_path": `)
+		var invalid *ValidationError
+		require.ErrorAs(t, err, &invalid)
+		assert.Contains(t, invalid.Problems[0].Message, "not valid JSON")
+	})
+
+	t.Run("unknown arguments of strict schemas", func(t *testing.T) {
+		_, _, err := ValidateInput(NewTodoWriteTool().Info(), `{"todos": [], "extra": 1}`)
+		var invalid *ValidationError
+		require.ErrorAs(t, err, &invalid)
+		assert.Equal(t, []ArgumentProblem{{Path: "extra", Message: "unknown argument"}}, invalid.Problems)
+	})
+}
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/kirmad/superopencode/internal/config"
 	"github.com/kirmad/superopencode/internal/logging"
@@ -16,9 +18,12 @@ import (
 )
 
 type ViewParams struct {
-	FilePath string `json:"file_path"`
-	Offset   int    `json:"offset"`
-	Limit    int    `json:"limit"`
+	FilePath  string `json:"file_path"`
+	Offset    int    `json:"offset"`
+	Limit     int    `json:"limit"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Symbol    string `json:"symbol"`
 }
 
 type viewTool struct {
@@ -26,8 +31,10 @@ type viewTool struct {
 }
 
 type ViewResponseMetadata struct {
-	FilePath string `json:"file_path"`
-	Content  string `json:"content"`
+	FilePath  string `json:"file_path"`
+	Content   string `json:"content"`
+	StartLine int    `json:"start_line,omitempty"` // First line read, 1-based
+	EndLine   int    `json:"end_line,omitempty"`   // Last line read
 }
 
 const (
@@ -46,17 +53,20 @@ HOW TO USE:
 - Provide the path to the file you want to view
 - Optionally specify an offset to start reading from a specific line
 - Optionally specify a limit to control how many lines are read
+- Or specify start_line and end_line (1-based, inclusive) to read a line range
+- Or specify a symbol to read only one function, method, class or type (e.g. "Server.Start")
 
 FEATURES:
 - Displays file contents with line numbers for easy reference
 - Can read from any position in a file using the offset parameter
 - Handles large files by limiting the number of lines read
+- Reads files larger than 250KB in parts and lists their definitions to choose from
 - Automatically truncates very long lines for better display
 - Suggests similar file names when the requested file isn't found
 
 LIMITATIONS:
-- Maximum file size is 250KB
-- Default reading limit is 2000 lines
+- Files larger than 250KB must be read with a range or a symbol
+- Default reading limit is 2000 lines, and at most 250KB are read at once
 - Lines longer than 2000 characters are truncated
 - Cannot display binary files or images
 - Images can be identified but not displayed
@@ -64,9 +74,13 @@ LIMITATIONS:
 TIPS:
 - Use with Glob tool to first find files you want to view
 - For code exploration, first use Grep to find relevant files, then View to examine them
-- When viewing large files, use the offset parameter to read specific sections`
+- When viewing large files, use the offset parameter to read specific sections
+- To look at one function of a long file, use the symbol parameter instead of reading the whole file`
 )
 
+// MaxSymbolFileSize bounds the files searched for a symbol or outlined
+const MaxSymbolFileSize = 10 * 1024 * 1024
+
 func NewViewTool(lspClients map[string]*lsp.Client) BaseTool {
 	return &viewTool{
 		lspClients,
@@ -90,6 +104,18 @@ func (v *viewTool) Info() ToolInfo {
 				"type":        "integer",
 				"description": "The number of lines to read (defaults to 2000)",
 			},
+			"start_line": map[string]any{
+				"type":        "integer",
+				"description": "The first line to read (1-based), instead of offset",
+			},
+			"end_line": map[string]any{
+				"type":        "integer",
+				"description": "The last line to read (inclusive), defaults to 2000 lines after start_line",
+			},
+			"symbol": map[string]any{
+				"type":        "string",
+				"description": "Name of a function, method (Type.Method), class or type to read instead of the whole file",
+			},
 		},
 		Required: []string{"file_path"},
 	}
@@ -150,17 +176,6 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return NewTextErrorResponse(fmt.Sprintf("Path is a directory, not a file: %s", filePath)), nil
 	}
 
-	// Check file size
-	if fileInfo.Size() > MaxReadSize {
-		return NewTextErrorResponse(fmt.Sprintf("File is too large (%d bytes). Maximum size is %d bytes",
-			fileInfo.Size(), MaxReadSize)), nil
-	}
-
-	// Set default limit if not provided
-	if params.Limit <= 0 {
-		params.Limit = DefaultReadLimit
-	}
-
 	// Check if it's an image file
 	isImage, imageType := isImageFile(filePath)
 	// TODO: handle images
@@ -168,6 +183,57 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return NewTextErrorResponse(fmt.Sprintf("This is an image file of type: %s\nUse a different tool to process images", imageType)), nil
 	}
 
+	if params.StartLine < 0 || params.EndLine < 0 || params.Offset < 0 {
+		return NewTextErrorResponse("line numbers and offset can't be negative"), nil
+	}
+	if params.StartLine > 0 {
+		params.Offset = params.StartLine - 1
+		if params.EndLine > 0 {
+			if params.EndLine < params.StartLine {
+				return NewTextErrorResponse("end_line is before start_line"), nil
+			}
+			params.Limit = params.EndLine - params.StartLine + 1
+		}
+	}
+	ranged := params.Offset > 0 || params.Limit > 0
+
+	// Generated files and lockfiles may be read in parts
+	switch kind, reason := detectFileKind(filePath); kind {
+	case FileKindBinary, FileKindMinified:
+		return fileKindResponse(filePath, kind, reason, "read"), nil
+	case FileKindLockfile, FileKindGenerated:
+		if !ranged && params.Symbol == "" {
+			return fileKindResponse(filePath, kind, reason, "read"), nil
+		}
+	}
+
+	var symbol *fileSymbol
+	if params.Symbol != "" {
+		if fileInfo.Size() > MaxSymbolFileSize {
+			return NewTextErrorResponse(fmt.Sprintf("File is too large to search for a symbol (%d bytes), use grep to find its line and read a range", fileInfo.Size())), nil
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return ToolResponse{}, fmt.Errorf("error reading file: %w", err)
+		}
+		text, _ := decodeText(data)
+		found, err := findSymbol(filePath, text, params.Symbol)
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("%s in %s\n\nDefinitions:\n%s", err, filePath, formatOutline(fileSymbols(filePath, text)))), nil
+		}
+		symbol = &found
+		params.Offset = found.Start - 1
+		params.Limit = min(found.End-found.Start+1, DefaultReadLimit)
+	} else if fileInfo.Size() > MaxReadSize && !ranged {
+		// Large files are read in parts the agent picks
+		return NewTextErrorResponse(largeFileMessage(filePath, fileInfo.Size())), nil
+	}
+
+	// Set default limit if not provided
+	if params.Limit <= 0 {
+		params.Limit = DefaultReadLimit
+	}
+
 	// Read the file content
 	content, lineCount, err := readTextFile(filePath, params.Offset, params.Limit)
 	if err != nil {
@@ -180,22 +246,51 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	output += addLineNumbers(content, params.Offset+1)
 
 	// Add a note if the content was truncated
-	if lineCount > params.Offset+len(strings.Split(content, "\n")) {
-		output += fmt.Sprintf("\n\n(File has more lines. Use 'offset' parameter to read beyond line %d)",
-			params.Offset+len(strings.Split(content, "\n")))
+	lastLine := params.Offset + len(strings.Split(content, "\n"))
+	if symbol != nil && lastLine < symbol.End {
+		output += fmt.Sprintf("\n\n(%s continues until line %d. Use 'start_line' %d to read the rest)",
+			params.Symbol, symbol.End, lastLine+1)
+	} else if symbol == nil && lineCount > lastLine {
+		output += fmt.Sprintf("\n\n(File has %d lines. Use 'offset' parameter to read beyond line %d)",
+			lineCount, lastLine)
 	}
 	output += "\n</file>\n"
 	output += getDiagnostics(filePath, v.lspClients)
-	recordFileRead(filePath)
+	recordFileRead(ctx, filePath)
 	return WithResponseMetadata(
 		NewTextResponse(output),
 		ViewResponseMetadata{
-			FilePath: filePath,
-			Content:  content,
+			FilePath:  filePath,
+			Content:   content,
+			StartLine: params.Offset + 1,
+			EndLine:   lastLine,
 		},
 	), nil
 }
 
+// largeFileMessage explains how to read a file too large to read at once and
+// lists its definitions to pick from
+func largeFileMessage(filePath string, size int64) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "File is too large to read at once (%d bytes, maximum %d bytes). Read it in parts:\n", size, MaxReadSize)
+	sb.WriteString("- start_line and end_line, or offset and limit, for a range of lines\n")
+	sb.WriteString("- symbol for a single function, method, class or type\n")
+	sb.WriteString("- the grep tool to find the lines you need first\n")
+	if size > MaxSymbolFileSize {
+		return sb.String()
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return sb.String()
+	}
+	lines := strings.Count(string(data), "\n") + 1
+	fmt.Fprintf(&sb, "\nThe file has %d lines.", lines)
+	if symbols := fileSymbols(filePath, string(data)); len(symbols) > 0 {
+		fmt.Fprintf(&sb, " Definitions:\n%s", formatOutline(symbols))
+	}
+	return sb.String()
+}
+
 func addLineNumbers(content string, startLine int) string {
 	if content == "" {
 		return ""
@@ -228,6 +323,22 @@ func readTextFile(filePath string, offset, limit int) (string, int, error) {
 	}
 	defer file.Close()
 
+	// Files that aren't plain UTF-8 are decoded as a whole, like the edit and
+	// write tools see them
+	header := make([]byte, fileSampleSize)
+	n, _ := io.ReadFull(file, header)
+	if needsDecoding(header[:n]) {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", 0, err
+		}
+		text, _ := decodeText(data)
+		return pageLines(strings.Split(text, "\n"), offset, limit)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+
 	lineCount := 0
 
 	scanner := NewLineScanner(file)
@@ -249,14 +360,17 @@ func readTextFile(filePath string, offset, limit int) (string, int, error) {
 
 	var lines []string
 	lineCount = offset
+	size := 0
 
-	for scanner.Scan() && len(lines) < limit {
+	// A page stops at MaxReadSize, so long lines can't blow the context
+	for len(lines) < limit && size < MaxReadSize && scanner.Scan() {
 		lineCount++
 		lineText := scanner.Text()
 		if len(lineText) > MaxLineLength {
 			lineText = lineText[:MaxLineLength] + "..."
 		}
 		lines = append(lines, lineText)
+		size += len(lineText) + 1
 	}
 
 	// Continue scanning to get total line count
@@ -271,6 +385,39 @@ func readTextFile(filePath string, offset, limit int) (string, int, error) {
 	return strings.Join(lines, "\n"), lineCount, nil
 }
 
+// needsDecoding reports whether a file starts with a byte order mark or isn't
+// valid UTF-8. A rune cut at the end of a full header doesn't count.
+func needsDecoding(header []byte) bool {
+	if bytes.HasPrefix(header, bomUTF8) || bytes.HasPrefix(header, bomUTF16LE) || bytes.HasPrefix(header, bomUTF16BE) {
+		return true
+	}
+	if len(header) == fileSampleSize {
+		header = header[:len(header)-(utf8.UTFMax-1)]
+	}
+	return !utf8.Valid(header)
+}
+
+// pageLines returns a page of decoded lines with the same limits as reading a
+// file line by line
+func pageLines(lines []string, offset, limit int) (string, int, error) {
+	if offset >= len(lines) {
+		return "", len(lines), nil
+	}
+	var page []string
+	size := 0
+	for _, line := range lines[offset:] {
+		if len(page) >= limit || size >= MaxReadSize {
+			break
+		}
+		if len(line) > MaxLineLength {
+			line = line[:MaxLineLength] + "..."
+		}
+		page = append(page, line)
+		size += len(line) + 1
+	}
+	return strings.Join(page, "\n"), len(lines), nil
+}
+
 func isImageFile(filePath string) (bool, string) {
 	ext := strings.ToLower(filepath.Ext(filePath))
 	switch ext {
@@ -3,16 +3,15 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/kirmad/superopencode/internal/config"
 	"github.com/kirmad/superopencode/internal/diff"
 	"github.com/kirmad/superopencode/internal/history"
-	"github.com/kirmad/superopencode/internal/logging"
 	"github.com/kirmad/superopencode/internal/lsp"
 	"github.com/kirmad/superopencode/internal/permission"
 )
@@ -115,6 +114,7 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	if !filepath.IsAbs(filePath) {
 		filePath = filepath.Join(config.WorkingDirectory(), filePath)
 	}
+	diagnosticsBefore := snapshotDiagnostics(w.lspClients)
 
 	fileInfo, err := os.Stat(filePath)
 	if err == nil {
@@ -122,33 +122,36 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			return NewTextErrorResponse(fmt.Sprintf("Path is a directory, not a file: %s", filePath)), nil
 		}
 
-		modTime := fileInfo.ModTime()
-		lastRead := getLastReadTime(filePath)
-		if modTime.After(lastRead) {
-			return NewTextErrorResponse(fmt.Sprintf("File %s has been modified since it was last read.\nLast modification: %s\nLast read: %s\n\nPlease read the file again before modifying it.",
-				filePath, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339))), nil
-		}
-
-		oldContent, readErr := os.ReadFile(filePath)
-		if readErr == nil && string(oldContent) == params.Content {
-			return NewTextErrorResponse(fmt.Sprintf("File %s already contains the exact content. No changes made.", filePath)), nil
+		if response, ok := checkFileUnchanged(ctx, filePath, "overwrite"); !ok {
+			return response, nil
 		}
 	} else if !os.IsNotExist(err) {
 		return ToolResponse{}, fmt.Errorf("error checking file: %w", err)
 	}
 
-	dir := filepath.Dir(filePath)
-	if err = os.MkdirAll(dir, 0o755); err != nil {
-		return ToolResponse{}, fmt.Errorf("error creating directory: %w", err)
-	}
-
+	// An existing file keeps its encoding, byte order mark and line endings
 	oldContent := ""
+	format := textFormat{Encoding: encodingUTF8}
+	converted := false
 	if fileInfo != nil && !fileInfo.IsDir() {
 		oldBytes, readErr := os.ReadFile(filePath)
 		if readErr == nil {
-			oldContent = string(oldBytes)
+			oldContent, format = decodeText(oldBytes)
+			params.Content, converted = format.normalize(params.Content)
+		}
+		if oldContent == params.Content {
+			return NewTextErrorResponse(fmt.Sprintf("File %s already contains the exact content. No changes made.", filePath)), nil
 		}
 	}
+	data, err := encodeText(params.Content, format)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	dir := filepath.Dir(filePath)
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return ToolResponse{}, fmt.Errorf("error creating directory: %w", err)
+	}
 
 	sessionID, messageID := GetContextValues(ctx)
 	if sessionID == "" || messageID == "" {
@@ -182,9 +185,20 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	if !p {
 		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
+	// The file may have been edited while the permission was pending
+	if fileInfo != nil {
+		if response, ok := checkFileUnchanged(ctx, filePath, "overwrite"); !ok {
+			return response, nil
+		}
+	}
 
-	err = os.WriteFile(filePath, []byte(params.Content), 0o644)
+	snapshot, err := w.files.Snapshot(ctx, sessionID, filePath)
 	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error snapshotting file: %w", err)
+	}
+	err = history.WriteFile(filePath, data, 0o644)
+	if err != nil {
+		w.files.Release(snapshot)
 		return ToolResponse{}, fmt.Errorf("error writing file: %w", err)
 	}
 
@@ -197,26 +211,27 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			return ToolResponse{}, fmt.Errorf("error creating file history: %w", err)
 		}
 	}
+	var historyErrs []error
 	if file.Content != oldContent {
 		// User Manually changed the content store an intermediate version
 		_, err = w.files.CreateVersion(ctx, sessionID, filePath, oldContent)
-		if err != nil {
-			logging.Debug("Error creating file history version", "error", err)
-		}
+		historyErrs = append(historyErrs, err)
 	}
 	// Store the new version
 	_, err = w.files.CreateVersion(ctx, sessionID, filePath, params.Content)
-	if err != nil {
-		logging.Debug("Error creating file history version", "error", err)
-	}
+	historyErrs = append(historyErrs, err)
+	history.ReleaseRecorded(w.files, snapshot, errors.Join(historyErrs...))
 
-	recordFileWrite(filePath)
-	recordFileRead(filePath)
+	recordFileWrite(ctx, filePath)
+	recordFileRead(ctx, filePath)
 	waitForLspDiagnostics(ctx, filePath, w.lspClients)
 
 	result := fmt.Sprintf("File successfully written: %s", filePath)
+	if note := formatNote(format, converted); note != "" {
+		result += "\n" + note
+	}
 	result = fmt.Sprintf("<result>\n%s\n</result>", result)
-	result += getDiagnostics(filePath, w.lspClients)
+	result += diagnosticsDelta(diagnosticsBefore, w.lspClients)
 	return WithResponseMetadata(NewTextResponse(result),
 		WriteResponseMetadata{
 			Diff:      diff,
@@ -0,0 +1,221 @@
+// Package locale formats dates, times and numbers for the locale and
+// timezone of the user, so the prompts and the UI don't assume US formats or
+// the timezone of the machine.
+package locale
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ISO is the locale used when none is configured or detected: ISO 8601
+// dates, a 24-hour clock and plain numbers
+const ISO = "iso"
+
+// Locale is how dates, times and numbers are written in a locale
+type Locale struct {
+	Tag     string
+	Date    string // Layout of dates
+	Time    string // Layout of times of the day
+	Decimal string // Decimal separator
+	Group   string // Thousands separator
+}
+
+var locales = map[string]Locale{
+	ISO:     {Tag: ISO, Date: "2006-01-02", Time: "15:04", Decimal: "."},
+	"en-US": {Tag: "en-US", Date: "1/2/2006", Time: "3:04 PM", Decimal: ".", Group: ","},
+	"en-GB": {Tag: "en-GB", Date: "02/01/2006", Time: "15:04", Decimal: ".", Group: ","},
+	"en-AU": {Tag: "en-AU", Date: "2/01/2006", Time: "3:04 PM", Decimal: ".", Group: ","},
+	"en-CA": {Tag: "en-CA", Date: "2006-01-02", Time: "3:04 PM", Decimal: ".", Group: ","},
+	"en-IN": {Tag: "en-IN", Date: "2/1/2006", Time: "3:04 PM", Decimal: ".", Group: ","},
+	"de-DE": {Tag: "de-DE", Date: "02.01.2006", Time: "15:04", Decimal: ",", Group: "."},
+	"de-CH": {Tag: "de-CH", Date: "02.01.2006", Time: "15:04", Decimal: ".", Group: "’"},
+	"fr-FR": {Tag: "fr-FR", Date: "02/01/2006", Time: "15:04", Decimal: ",", Group: " "},
+	"es-ES": {Tag: "es-ES", Date: "2/1/2006", Time: "15:04", Decimal: ",", Group: "."},
+	"it-IT": {Tag: "it-IT", Date: "02/01/2006", Time: "15:04", Decimal: ",", Group: "."},
+	"nl-NL": {Tag: "nl-NL", Date: "2-1-2006", Time: "15:04", Decimal: ",", Group: "."},
+	"pt-BR": {Tag: "pt-BR", Date: "02/01/2006", Time: "15:04", Decimal: ",", Group: "."},
+	"pl-PL": {Tag: "pl-PL", Date: "2.01.2006", Time: "15:04", Decimal: ",", Group: " "},
+	"sv-SE": {Tag: "sv-SE", Date: "2006-01-02", Time: "15:04", Decimal: ",", Group: " "},
+	"ru-RU": {Tag: "ru-RU", Date: "02.01.2006", Time: "15:04", Decimal: ",", Group: " "},
+	"ja-JP": {Tag: "ja-JP", Date: "2006/01/02", Time: "15:04", Decimal: ".", Group: ","},
+	"zh-CN": {Tag: "zh-CN", Date: "2006/1/2", Time: "15:04", Decimal: ".", Group: ","},
+	"ko-KR": {Tag: "ko-KR", Date: "2006. 1. 2.", Time: "PM 3:04", Decimal: ".", Group: ","},
+}
+
+// defaultRegions picks the locale of a language given without a region
+var defaultRegions = map[string]string{
+	"en": "en-US", "de": "de-DE", "fr": "fr-FR", "es": "es-ES", "it": "it-IT", "nl": "nl-NL",
+	"pt": "pt-BR", "pl": "pl-PL", "sv": "sv-SE", "ru": "ru-RU", "ja": "ja-JP", "zh": "zh-CN", "ko": "ko-KR",
+}
+
+var current = struct {
+	sync.RWMutex
+	locale   Locale
+	location *time.Location
+}{
+	locale:   locales[ISO],
+	location: time.Local,
+}
+
+// Supported returns the tags of the supported locales
+func Supported() []string {
+	return slices.Sorted(maps.Keys(locales))
+}
+
+// Lookup returns the locale of a tag such as "de-DE", "de_DE.UTF-8" or "de".
+// A region that isn't supported falls back to the default region of the
+// language.
+func Lookup(tag string) (Locale, bool) {
+	tag, _, _ = strings.Cut(tag, ".") // Encoding, e.g. en_US.UTF-8
+	tag, _, _ = strings.Cut(tag, "@") // Modifier, e.g. de_DE@euro
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if strings.EqualFold(tag, ISO) {
+		return locales[ISO], true
+	}
+	lang, region, _ := strings.Cut(tag, "-")
+	lang = strings.ToLower(lang)
+	if l, ok := locales[lang+"-"+strings.ToUpper(region)]; ok {
+		return l, true
+	}
+	l, ok := locales[defaultRegions[lang]]
+	return l, ok
+}
+
+// Detect returns the locale of the environment, from LC_ALL, LC_TIME or
+// LANG, or the ISO locale
+func Detect() Locale {
+	for _, name := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" || value == "C" || value == "POSIX" {
+			continue
+		}
+		if l, ok := Lookup(value); ok {
+			return l
+		}
+	}
+	return locales[ISO]
+}
+
+// Set sets the locale and the IANA timezone of the user. The locale of the
+// environment and the local timezone are used when they are empty.
+func Set(tag, timezone string) error {
+	l := Detect()
+	if tag != "" {
+		var ok bool
+		if l, ok = Lookup(tag); !ok {
+			return fmt.Errorf("unsupported locale %q, use one of %s", tag, strings.Join(Supported(), ", "))
+		}
+	}
+	location := time.Local
+	if timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q: %w", timezone, err)
+		}
+	}
+	current.Lock()
+	defer current.Unlock()
+	current.locale = l
+	current.location = location
+	return nil
+}
+
+// Current returns the locale of the user
+func Current() Locale {
+	current.RLock()
+	defer current.RUnlock()
+	return current.locale
+}
+
+// Location returns the timezone of the user
+func Location() *time.Location {
+	current.RLock()
+	defer current.RUnlock()
+	return current.location
+}
+
+// Now returns the current time in the timezone of the user
+func Now() time.Time {
+	return time.Now().In(Location())
+}
+
+// Zone describes the timezone of the user at t, e.g. "Europe/Berlin
+// (CEST, UTC+02:00)"
+func Zone(t time.Time) string {
+	t = t.In(Location())
+	name := Location().String()
+	if name == "Local" {
+		name = "local time"
+	}
+	abbrev := t.Format("MST")
+	offset := "UTC" + t.Format("-07:00")
+	if abbrev == name || strings.HasPrefix(abbrev, "+") || strings.HasPrefix(abbrev, "-") {
+		return fmt.Sprintf("%s (%s)", name, offset)
+	}
+	return fmt.Sprintf("%s (%s, %s)", name, abbrev, offset)
+}
+
+// Date formats the date of t in the timezone of the user
+func Date(t time.Time) string {
+	return t.In(Location()).Format(Current().Date)
+}
+
+// Clock formats the time of the day of t in the timezone of the user, with
+// the abbreviation of the timezone so it's never mistaken for another one
+func Clock(t time.Time) string {
+	return t.In(Location()).Format(Current().Time + " MST")
+}
+
+// DateTime formats t with its date, time and timezone
+func DateTime(t time.Time) string {
+	t = t.In(Location())
+	return t.Format(Current().Date + " " + Current().Time + " MST")
+}
+
+// Int formats an integer with the thousands separator of the locale
+func Int(n int64) string {
+	return group(strconv.FormatInt(n, 10), Current().Group)
+}
+
+// Float formats a number with the given number of decimals and the
+// separators of the locale
+func Float(f float64, decimals int) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	l := Current()
+	whole, fraction, _ := strings.Cut(strconv.FormatFloat(f, 'f', decimals, 64), ".")
+	whole = group(whole, l.Group)
+	if fraction == "" {
+		return whole
+	}
+	return whole + l.Decimal + fraction
+}
+
+// group inserts a separator between the groups of three digits of an integer
+func group(digits, sep string) string {
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if len(digits) <= 3 || sep == "" {
+		return sign + digits
+	}
+	var sb strings.Builder
+	sb.WriteString(sign)
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteString(sep)
+		}
+		sb.WriteRune(digit)
+	}
+	return sb.String()
+}
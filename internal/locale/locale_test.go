@@ -0,0 +1,61 @@
+package locale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	for tag, want := range map[string]string{
+		"de-DE":       "de-DE",
+		"de_DE.UTF-8": "de-DE",
+		"de_AT@euro":  "de-DE",
+		"fr":          "fr-FR",
+		"EN-gb":       "en-GB",
+		"ISO":         ISO,
+	} {
+		l, ok := Lookup(tag)
+		require.True(t, ok, tag)
+		assert.Equal(t, want, l.Tag, tag)
+	}
+	_, ok := Lookup("xx-YY")
+	assert.False(t, ok)
+}
+
+func TestDetect(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_TIME", "C")
+	t.Setenv("LANG", "sv_SE.UTF-8")
+	assert.Equal(t, "sv-SE", Detect().Tag)
+	t.Setenv("LANG", "")
+	assert.Equal(t, ISO, Detect().Tag)
+}
+
+func TestFormat(t *testing.T) {
+	t.Cleanup(func() { Set(ISO, "") })
+	at := time.Date(2026, 3, 7, 18, 5, 0, 0, time.UTC)
+
+	require.NoError(t, Set("de-DE", "Europe/Berlin"))
+	assert.Equal(t, "07.03.2026", Date(at))
+	assert.Equal(t, "19:05 CET", Clock(at))
+	assert.Equal(t, "07.03.2026 19:05 CET", DateTime(at))
+	assert.Equal(t, "Europe/Berlin (CET, UTC+01:00)", Zone(at))
+	assert.Equal(t, "1.234.567", Int(1234567))
+	assert.Equal(t, "-1.234,50", Float(-1234.5, 2))
+
+	require.NoError(t, Set("en-US", "America/New_York"))
+	assert.Equal(t, "3/7/2026", Date(at))
+	assert.Equal(t, "1:05 PM EST", Clock(at))
+	assert.Equal(t, "999", Int(999))
+	assert.Equal(t, "0.42", Float(0.42, 2))
+
+	require.NoError(t, Set(ISO, "UTC"))
+	assert.Equal(t, "2026-03-07", Date(at))
+	assert.Equal(t, "UTC (UTC+00:00)", Zone(at))
+
+	assert.Error(t, Set("xx", ""))
+	assert.Error(t, Set("", "Mars/Olympus"))
+}
@@ -0,0 +1,176 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/version"
+)
+
+// CrashDir is the directory crash bundles are written to. Without it, panics
+// are only written to a log file in the working directory.
+var CrashDir string
+
+// crashLogTail is the number of recent log messages included in a bundle
+const crashLogTail = 200
+
+var crashInfo = struct {
+	sync.Mutex
+	providers map[string]func() any
+	bundles   []string
+}{
+	providers: make(map[string]func() any),
+}
+
+// SetCrashInfo adds a section to the crash bundles, e.g. a config snapshot or
+// the current session. fn is called when a bundle is written and must not
+// return secrets.
+func SetCrashInfo(name string, fn func() any) {
+	crashInfo.Lock()
+	defer crashInfo.Unlock()
+	crashInfo.providers[name] = fn
+}
+
+// CrashBundles returns the crash bundles written by this process
+func CrashBundles() []string {
+	crashInfo.Lock()
+	defer crashInfo.Unlock()
+	return append([]string(nil), crashInfo.bundles...)
+}
+
+// secretPatterns match credentials that may end up in panic values or logs,
+// with the replacement that keeps the surrounding context
+var secretPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]{8,}`), "${1}[REDACTED]"},
+	{regexp.MustCompile(`(?i)((?:api[_-]?key|token|secret|password)["']?\s*[:=]\s*["']?)[^\s"',}]+`), "${1}[REDACTED]"},
+	{regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_-]{8,}`), "[REDACTED]"},
+	{regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|github_pat)_[A-Za-z0-9_]{8,}`), "[REDACTED]"},
+	{regexp.MustCompile(`\bAIza[A-Za-z0-9_-]{20,}`), "[REDACTED]"},
+}
+
+// Redact replaces credentials in s
+func Redact(s string) string {
+	for _, secret := range secretPatterns {
+		s = secret.pattern.ReplaceAllString(s, secret.replacement)
+	}
+	return s
+}
+
+// writeCrashBundle writes a directory with the panic, the stacks of all
+// goroutines, the recent logs and the registered crash info
+func writeCrashBundle(name string, r any, stack []byte) (string, error) {
+	now := time.Now()
+	dir := filepath.Join(CrashDir, fmt.Sprintf("%s-%s", now.Format("20060102-150405"), sanitizeName(name)))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create crash directory: %w", err)
+	}
+
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "Panic in %s: %v\n\n", name, r)
+	fmt.Fprintf(&summary, "Time: %s\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&summary, "Version: %s\n", version.Version)
+	fmt.Fprintf(&summary, "Go: %s %s/%s\n\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&summary, "Stack Trace:\n%s\n", stack)
+
+	goroutines := make([]byte, 1<<20)
+	goroutines = goroutines[:runtime.Stack(goroutines, true)]
+
+	var logs strings.Builder
+	messages := List()
+	for _, msg := range messages[max(0, len(messages)-crashLogTail):] {
+		fmt.Fprintf(&logs, "%s %-5s %s", msg.Time.Format(time.RFC3339), strings.ToUpper(msg.Level), msg.Message)
+		for _, attr := range msg.Attributes {
+			fmt.Fprintf(&logs, " %s=%s", attr.Key, attr.Value)
+		}
+		logs.WriteString("\n")
+	}
+
+	info, err := json.MarshalIndent(collectCrashInfo(), "", "  ")
+	if err != nil {
+		info = []byte(fmt.Sprintf("failed to marshal crash info: %v", err))
+	}
+
+	files := map[string]string{
+		"crash.txt":      summary.String(),
+		"goroutines.txt": string(goroutines),
+		"logs.txt":       logs.String(),
+		"context.json":   string(info),
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(Redact(content)), 0o600); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", file, err)
+		}
+	}
+
+	crashInfo.Lock()
+	crashInfo.bundles = append(crashInfo.bundles, dir)
+	crashInfo.Unlock()
+	return dir, nil
+}
+
+// collectCrashInfo calls the crash info providers, a provider that panics
+// itself is reported as unavailable
+func collectCrashInfo() map[string]any {
+	crashInfo.Lock()
+	names := make([]string, 0, len(crashInfo.providers))
+	for name := range crashInfo.providers {
+		names = append(names, name)
+	}
+	providers := crashInfo.providers
+	crashInfo.Unlock()
+	sort.Strings(names)
+
+	info := make(map[string]any, len(names))
+	for _, name := range names {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					info[name] = fmt.Sprintf("unavailable: %v", r)
+				}
+			}()
+			info[name] = providers[name]()
+		}()
+	}
+	return info
+}
+
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// writePanicLog writes a panic to a log file in the working directory, used
+// without a crash directory
+func writePanicLog(name string, r any, stack []byte) {
+	timestamp := time.Now().Format("20060102-150405")
+	filename := fmt.Sprintf("opencode-panic-%s-%s.log", name, timestamp)
+
+	file, err := os.Create(filename)
+	if err != nil {
+		ErrorPersist(fmt.Sprintf("Failed to create panic log: %v", err))
+		return
+	}
+	defer file.Close()
+
+	// Write panic information and stack trace
+	fmt.Fprintf(file, "Panic in %s: %v\n\n", name, r)
+	fmt.Fprintf(file, "Time: %s\n\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(file, "Stack Trace:\n%s\n", stack)
+
+	InfoPersist(fmt.Sprintf("Panic details written to %s", filename))
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, "Authorization: Bearer [REDACTED]", Redact("Authorization: Bearer abcdefghijklmnop"))
+	assert.Equal(t, `{"apiKey": "[REDACTED]"}`, Redact(`{"apiKey": "hunter2hunter2"}`))
+	assert.Equal(t, "key [REDACTED] failed", Redact("key sk-ant-0123456789abcdef failed"))
+	assert.Equal(t, "token=[REDACTED] used", Redact("token=ghp_0123456789abcdef used"))
+	assert.Equal(t, "nothing to hide", Redact("nothing to hide"))
+}
+
+func TestWriteCrashBundle(t *testing.T) {
+	CrashDir = t.TempDir()
+	t.Cleanup(func() { CrashDir = "" })
+	SetCrashInfo("session_id", func() any { return "session-1" })
+	SetCrashInfo("broken", func() any { panic("boom") })
+
+	dir, err := writeCrashBundle("agent.Run", "api_key=sk-0123456789abcdef", []byte("stack"))
+	require.NoError(t, err)
+	assert.Contains(t, CrashBundles(), dir)
+
+	summary, err := os.ReadFile(filepath.Join(dir, "crash.txt"))
+	require.NoError(t, err)
+	assert.Contains(t, string(summary), "Panic in agent.Run")
+	assert.NotContains(t, string(summary), "sk-0123456789abcdef")
+
+	info, err := os.ReadFile(filepath.Join(dir, "context.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(info), `"session_id": "session-1"`)
+	assert.Contains(t, string(info), "unavailable: boom")
+
+	for _, file := range []string{"goroutines.txt", "logs.txt"} {
+		assert.FileExists(t, filepath.Join(dir, file))
+	}
+}
@@ -0,0 +1,211 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/locale"
+)
+
+// modulePrefix is trimmed from package paths to name the module of a log record
+const modulePrefix = "github.com/kirmad/superopencode/"
+
+// levels holds the minimum level of every module, changed at runtime through
+// SetLevel. A module inherits the level of its closest configured parent,
+// e.g. "llm" covers "llm/agent".
+var levels = struct {
+	sync.RWMutex
+	def     slog.Level
+	modules map[string]slog.Level
+}{
+	def:     slog.LevelInfo,
+	modules: make(map[string]slog.Level),
+}
+
+// ParseLevel parses a level name: debug, info, warn or error
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return level, fmt.Errorf("invalid log level %q, use debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
+// SetLevel sets the minimum level of a module, or the default level when
+// module is empty
+func SetLevel(module string, level slog.Level) {
+	levels.Lock()
+	defer levels.Unlock()
+	module = strings.Trim(module, "/")
+	if module == "" {
+		levels.def = level
+		return
+	}
+	levels.modules[module] = level
+}
+
+// ResetLevel makes a module use the level of its parent again
+func ResetLevel(module string) {
+	levels.Lock()
+	defer levels.Unlock()
+	delete(levels.modules, strings.Trim(module, "/"))
+}
+
+// Levels returns the default level and the levels of the modules that
+// override it
+func Levels() (slog.Level, map[string]slog.Level) {
+	levels.RLock()
+	defer levels.RUnlock()
+	return levels.def, maps.Clone(levels.modules)
+}
+
+// FormatLevels describes the current levels, e.g. "info (llm/agent=debug)"
+func FormatLevels() string {
+	def, modules := Levels()
+	if len(modules) == 0 {
+		return strings.ToLower(def.String())
+	}
+	names := make([]string, 0, len(modules))
+	for module := range modules {
+		names = append(names, module)
+	}
+	sort.Strings(names)
+	overrides := make([]string, len(names))
+	for i, module := range names {
+		overrides[i] = module + "=" + strings.ToLower(modules[module].String())
+	}
+	return fmt.Sprintf("%s (%s)", strings.ToLower(def.String()), strings.Join(overrides, ", "))
+}
+
+func moduleLevel(module string) slog.Level {
+	levels.RLock()
+	defer levels.RUnlock()
+	for module != "" {
+		if level, ok := levels.modules[module]; ok {
+			return level
+		}
+		i := strings.LastIndex(module, "/")
+		if i < 0 {
+			break
+		}
+		module = module[:i]
+	}
+	return levels.def
+}
+
+func minLevel() slog.Level {
+	levels.RLock()
+	defer levels.RUnlock()
+	lowest := levels.def
+	for _, level := range levels.modules {
+		lowest = min(lowest, level)
+	}
+	return lowest
+}
+
+// moduleOf returns the module of the function at pc, e.g. "llm/agent" for a
+// function of internal/llm/agent
+func moduleOf(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return ""
+	}
+	// Type parameters and receivers may contain package paths themselves
+	name := fn.Name()
+	if i := strings.IndexAny(name, "[("); i >= 0 {
+		name = name[:i]
+	}
+	pkg := name
+	i := max(0, strings.LastIndex(name, "/"))
+	if j := strings.Index(name[i:], "."); j >= 0 {
+		pkg = name[:i+j]
+	}
+	pkg = strings.TrimPrefix(pkg, modulePrefix)
+	return strings.TrimPrefix(pkg, "internal/")
+}
+
+// Handler filters records by the level of their module and writes them to
+// the console handler, feeding the logs page or the debug file, and to an
+// optional structured file handler.
+type Handler struct {
+	console slog.Handler
+	file    slog.Handler
+}
+
+// NewHandler returns a handler writing to console and, when not nil, to file.
+// The handlers should accept every level, filtering happens in Handler.
+func NewHandler(console, file slog.Handler) *Handler {
+	return &Handler{console: console, file: file}
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= minLevel()
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	module := moduleOf(r.PC)
+	if r.Level < moduleLevel(module) {
+		return nil
+	}
+	err := h.console.Handle(ctx, r)
+	if h.file != nil {
+		r = r.Clone()
+		if module != "" {
+			r.AddAttrs(slog.String("module", module))
+		}
+		if fileErr := h.file.Handle(ctx, r); fileErr != nil && err == nil {
+			err = fileErr
+		}
+	}
+	return err
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := &Handler{console: h.console.WithAttrs(attrs)}
+	if h.file != nil {
+		clone.file = h.file.WithAttrs(attrs)
+	}
+	return clone
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	clone := &Handler{console: h.console.WithGroup(name)}
+	if h.file != nil {
+		clone.file = h.file.WithGroup(name)
+	}
+	return clone
+}
+
+// ZonedTime writes the time of log records in the timezone of the user, with
+// its offset, for the ReplaceAttr option of slog handlers
+func ZonedTime(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && len(groups) == 0 && a.Value.Kind() == slog.KindTime {
+		a.Value = slog.TimeValue(a.Value.Time().In(locale.Location()))
+	}
+	return a
+}
+
+// log writes a record with the caller of the exported logging function as
+// its source, so the module levels apply to the caller's package
+func log(level slog.Level, msg string, args ...any) {
+	ctx := context.Background()
+	logger := slog.Default()
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // skip Callers, log and the exported function
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = logger.Handler().Handle(ctx, r)
+}
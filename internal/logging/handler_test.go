@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/locale"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleLevels(t *testing.T) {
+	t.Cleanup(func() {
+		SetLevel("", slog.LevelInfo)
+		ResetLevel("llm")
+		ResetLevel("llm/agent")
+	})
+
+	SetLevel("", slog.LevelWarn)
+	SetLevel("llm", slog.LevelDebug)
+	SetLevel("llm/agent", slog.LevelError)
+
+	assert.Equal(t, slog.LevelWarn, moduleLevel("tui"))
+	assert.Equal(t, slog.LevelDebug, moduleLevel("llm/provider"))
+	assert.Equal(t, slog.LevelError, moduleLevel("llm/agent"))
+	assert.Equal(t, slog.LevelDebug, minLevel())
+	assert.Equal(t, "warn (llm=debug, llm/agent=error)", FormatLevels())
+
+	ResetLevel("llm/agent")
+	assert.Equal(t, slog.LevelDebug, moduleLevel("llm/agent"))
+
+	_, err := ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestHandlerFiltersByModule(t *testing.T) {
+	t.Cleanup(func() { ResetLevel("logging") })
+
+	var console, file bytes.Buffer
+	h := NewHandler(
+		slog.NewTextHandler(&console, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.NewJSONHandler(&file, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	)
+	pc, _, _, _ := runtime.Caller(0)
+	assert.Equal(t, "logging", moduleOf(pc))
+
+	SetLevel("logging", slog.LevelWarn)
+	require.NoError(t, h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "dropped", pc)))
+	assert.Zero(t, console.Len())
+
+	require.NoError(t, h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelWarn, "kept", pc)))
+	assert.Contains(t, console.String(), "kept")
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(file.Bytes(), &entry))
+	assert.Equal(t, "kept", entry["msg"])
+	assert.Equal(t, "logging", entry["module"])
+}
+
+func TestZonedTime(t *testing.T) {
+	t.Cleanup(func() { locale.Set("", "") })
+	require.NoError(t, locale.Set("", "Asia/Tokyo"))
+
+	var file bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&file, &slog.HandlerOptions{ReplaceAttr: ZonedTime}))
+	logger.Info("zoned")
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(file.Bytes(), &entry))
+	assert.True(t, strings.HasSuffix(entry["time"].(string), "+09:00"), entry["time"])
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "opencode.log")
+	f, err := OpenRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	read := func(name string) string {
+		content, err := os.ReadFile(name)
+		require.NoError(t, err)
+		return string(content)
+	}
+	assert.Equal(t, "fourth\n", read(path))
+	assert.Equal(t, "third\n", read(path+".1"))
+	assert.Equal(t, "second\n", read(path+".2"))
+	assert.NoFileExists(t, path+".3")
+}
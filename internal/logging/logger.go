@@ -9,7 +9,6 @@ import (
 	"runtime"
 	"runtime/debug"
 	"sync"
-	"time"
 )
 
 func getCaller() string {
@@ -24,67 +23,59 @@ func getCaller() string {
 }
 func Info(msg string, args ...any) {
 	source := getCaller()
-	slog.Info(msg, append([]any{"source", source}, args...)...)
+	log(slog.LevelInfo, msg, append([]any{"source", source}, args...)...)
 }
 
 func Debug(msg string, args ...any) {
-	// slog.Debug(msg, args...)
 	source := getCaller()
-	slog.Debug(msg, append([]any{"source", source}, args...)...)
+	log(slog.LevelDebug, msg, append([]any{"source", source}, args...)...)
 }
 
 func Warn(msg string, args ...any) {
-	slog.Warn(msg, args...)
+	log(slog.LevelWarn, msg, args...)
 }
 
 func Error(msg string, args ...any) {
-	slog.Error(msg, args...)
+	log(slog.LevelError, msg, args...)
 }
 
 func InfoPersist(msg string, args ...any) {
 	args = append(args, persistKeyArg, true)
-	slog.Info(msg, args...)
+	log(slog.LevelInfo, msg, args...)
 }
 
 func DebugPersist(msg string, args ...any) {
 	args = append(args, persistKeyArg, true)
-	slog.Debug(msg, args...)
+	log(slog.LevelDebug, msg, args...)
 }
 
 func WarnPersist(msg string, args ...any) {
 	args = append(args, persistKeyArg, true)
-	slog.Warn(msg, args...)
+	log(slog.LevelWarn, msg, args...)
 }
 
 func ErrorPersist(msg string, args ...any) {
 	args = append(args, persistKeyArg, true)
-	slog.Error(msg, args...)
+	log(slog.LevelError, msg, args...)
 }
 
 // RecoverPanic is a common function to handle panics gracefully.
-// It logs the error, creates a panic log file with stack trace,
+// It logs the error, writes a crash bundle with the stack traces, recent
+// logs and crash info (or a panic log file without a crash directory),
 // and executes an optional cleanup function before returning.
 func RecoverPanic(name string, cleanup func()) {
 	if r := recover(); r != nil {
 		// Log the panic
 		ErrorPersist(fmt.Sprintf("Panic in %s: %v", name, r))
 
-		// Create a timestamped panic log file
-		timestamp := time.Now().Format("20060102-150405")
-		filename := fmt.Sprintf("opencode-panic-%s-%s.log", name, timestamp)
-
-		file, err := os.Create(filename)
-		if err != nil {
-			ErrorPersist(fmt.Sprintf("Failed to create panic log: %v", err))
+		stack := debug.Stack()
+		if CrashDir == "" {
+			writePanicLog(name, r, stack)
+		} else if dir, err := writeCrashBundle(name, r, stack); err != nil {
+			ErrorPersist(fmt.Sprintf("Failed to write crash report: %v", err))
+			writePanicLog(name, r, stack)
 		} else {
-			defer file.Close()
-
-			// Write panic information and stack trace
-			fmt.Fprintf(file, "Panic in %s: %v\n\n", name, r)
-			fmt.Fprintf(file, "Time: %s\n\n", time.Now().Format(time.RFC3339))
-			fmt.Fprintf(file, "Stack Trace:\n%s\n", debug.Stack())
-
-			InfoPersist(fmt.Sprintf("Panic details written to %s", filename))
+			InfoPersist(fmt.Sprintf("Crash report written to %s", dir))
 		}
 
 		// Execute cleanup function if provided
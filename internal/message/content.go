@@ -108,6 +108,42 @@ type Finish struct {
 
 func (Finish) isPart() {}
 
+// Route records why the model of an assistant message was picked
+type Route struct {
+	Tier   string `json:"tier"`
+	Reason string `json:"reason"`
+}
+
+func (Route) isPart() {}
+
+// Usage records the tokens and cost of the request that produced an assistant
+// message, as reported by the provider
+type Usage struct {
+	InputTokens         int64   `json:"input_tokens"`
+	OutputTokens        int64   `json:"output_tokens"`
+	CacheCreationTokens int64   `json:"cache_creation_tokens"`
+	CacheReadTokens     int64   `json:"cache_read_tokens"`
+	Cost                float64 `json:"cost"`
+}
+
+func (Usage) isPart() {}
+
+// Citation is a file:line reference made in an assistant message. Verified
+// citations point at lines the agent read or matched during the request.
+type Citation struct {
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	EndLine  int    `json:"end_line,omitempty"`
+	Verified bool   `json:"verified"`
+}
+
+// Citations holds the file references of an assistant message
+type Citations struct {
+	Items []Citation `json:"items"`
+}
+
+func (Citations) isPart() {}
+
 type Message struct {
 	ID        string
 	Role      MessageRole
@@ -176,6 +212,33 @@ func (m *Message) ToolResults() []ToolResult {
 	return toolResults
 }
 
+func (m *Message) Route() *Route {
+	for _, part := range m.Parts {
+		if c, ok := part.(Route); ok {
+			return &c
+		}
+	}
+	return nil
+}
+
+func (m *Message) Usage() *Usage {
+	for _, part := range m.Parts {
+		if c, ok := part.(Usage); ok {
+			return &c
+		}
+	}
+	return nil
+}
+
+func (m *Message) Citations() []Citation {
+	for _, part := range m.Parts {
+		if c, ok := part.(Citations); ok {
+			return c.Items
+		}
+	}
+	return nil
+}
+
 func (m *Message) IsFinished() bool {
 	for _, part := range m.Parts {
 		if _, ok := part.(Finish); ok {
@@ -318,6 +381,26 @@ func (m *Message) AddFinish(reason FinishReason) {
 	m.Parts = append(m.Parts, Finish{Reason: reason, Time: time.Now().Unix()})
 }
 
+func (m *Message) SetUsage(usage Usage) {
+	for i, part := range m.Parts {
+		if _, ok := part.(Usage); ok {
+			m.Parts[i] = usage
+			return
+		}
+	}
+	m.Parts = append(m.Parts, usage)
+}
+
+func (m *Message) SetCitations(citations []Citation) {
+	for i, part := range m.Parts {
+		if _, ok := part.(Citations); ok {
+			m.Parts[i] = Citations{Items: citations}
+			return
+		}
+	}
+	m.Parts = append(m.Parts, Citations{Items: citations})
+}
+
 func (m *Message) AddImageURL(url, detail string) {
 	m.Parts = append(m.Parts, ImageURLContent{URL: url, Detail: detail})
 }
@@ -5,14 +5,20 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/kirmad/superopencode/internal/db"
 	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/logging"
 	"github.com/kirmad/superopencode/internal/pubsub"
 )
 
+// persistInterval is how long the updates of a streaming message are batched
+// before they are written. Subscribers get every update right away.
+const persistInterval = 250 * time.Millisecond
+
 type CreateMessageParams struct {
 	Role  MessageRole
 	Parts []ContentPart
@@ -27,17 +33,27 @@ type Service interface {
 	List(ctx context.Context, sessionID string) ([]Message, error)
 	Delete(ctx context.Context, id string) error
 	DeleteSessionMessages(ctx context.Context, sessionID string) error
+	// Flush writes the pending updates of streaming messages
+	Flush(ctx context.Context) error
 }
 
 type service struct {
 	*pubsub.Broker[Message]
 	q db.Querier
+
+	// writeMu orders the writes, so a batched update never overwrites a later
+	// one
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[string]db.UpdateMessageParams
+	timer   *time.Timer
 }
 
 func NewService(q db.Querier) Service {
 	return &service{
-		Broker: pubsub.NewBroker[Message](),
-		q:      q,
+		Broker:  pubsub.NewBroker[Message](),
+		q:       q,
+		pending: make(map[string]db.UpdateMessageParams),
 	}
 }
 
@@ -46,7 +62,12 @@ func (s *service) Delete(ctx context.Context, id string) error {
 	if err != nil {
 		return err
 	}
+	s.writeMu.Lock()
+	s.mu.Lock()
+	delete(s.pending, id)
+	s.mu.Unlock()
 	err = s.q.DeleteMessage(ctx, message.ID)
+	s.writeMu.Unlock()
 	if err != nil {
 		return err
 	}
@@ -98,30 +119,101 @@ func (s *service) DeleteSessionMessages(ctx context.Context, sessionID string) e
 	return nil
 }
 
+// Update publishes the message and persists it. The updates of a message that
+// is still streaming are batched, the last one is written when it finishes.
 func (s *service) Update(ctx context.Context, message Message) error {
 	parts, err := marshallParts(message.Parts)
 	if err != nil {
 		return err
 	}
-	finishedAt := sql.NullInt64{}
-	if f := message.FinishPart(); f != nil {
-		finishedAt.Int64 = f.Time
-		finishedAt.Valid = true
+	params := db.UpdateMessageParams{
+		ID:    message.ID,
+		Parts: string(parts),
 	}
-	err = s.q.UpdateMessage(ctx, db.UpdateMessageParams{
-		ID:         message.ID,
-		Parts:      string(parts),
-		FinishedAt: finishedAt,
-	})
-	if err != nil {
-		return err
+	if f := message.FinishPart(); f != nil {
+		params.FinishedAt = sql.NullInt64{Int64: f.Time, Valid: true}
+		if err := s.write(ctx, params); err != nil {
+			return err
+		}
+	} else {
+		s.mu.Lock()
+		s.pending[message.ID] = params
+		if s.timer == nil {
+			s.timer = time.AfterFunc(persistInterval, s.flushPending)
+		}
+		s.mu.Unlock()
 	}
 	message.UpdatedAt = time.Now().Unix()
 	s.Publish(pubsub.UpdatedEvent, message)
 	return nil
 }
 
+// write persists an update right away, replacing the pending one
+func (s *service) write(ctx context.Context, params db.UpdateMessageParams) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.mu.Lock()
+	delete(s.pending, params.ID)
+	s.mu.Unlock()
+	return s.q.UpdateMessage(ctx, params)
+}
+
+func (s *service) Flush(ctx context.Context) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = make(map[string]db.UpdateMessageParams)
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.mu.Unlock()
+
+	var firstErr error
+	var failed []db.UpdateMessageParams
+	for _, params := range batch {
+		if err := s.q.UpdateMessage(ctx, params); err != nil {
+			failed = append(failed, params)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if len(failed) > 0 {
+		// Keep the failed updates for the next flush, unless a newer one was
+		// queued meanwhile
+		s.mu.Lock()
+		for _, params := range failed {
+			if _, ok := s.pending[params.ID]; !ok {
+				s.pending[params.ID] = params
+			}
+		}
+		s.mu.Unlock()
+	}
+	return firstErr
+}
+
+func (s *service) flushPending() {
+	if err := s.Flush(context.Background()); err != nil {
+		logging.Error("Failed to persist streaming messages", "error", err)
+	}
+}
+
+// hasPending reports whether updates wait to be written, reads flush them
+// first so they see the latest content
+func (s *service) hasPending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending) > 0
+}
+
 func (s *service) Get(ctx context.Context, id string) (Message, error) {
+	if s.hasPending() {
+		if err := s.Flush(ctx); err != nil {
+			return Message{}, err
+		}
+	}
 	dbMessage, err := s.q.GetMessage(ctx, id)
 	if err != nil {
 		return Message{}, err
@@ -130,6 +222,11 @@ func (s *service) Get(ctx context.Context, id string) (Message, error) {
 }
 
 func (s *service) List(ctx context.Context, sessionID string) ([]Message, error) {
+	if s.hasPending() {
+		if err := s.Flush(ctx); err != nil {
+			return nil, err
+		}
+	}
 	dbMessages, err := s.q.ListMessagesBySession(ctx, sessionID)
 	if err != nil {
 		return nil, err
@@ -170,6 +267,9 @@ const (
 	toolCallType   partType = "tool_call"
 	toolResultType partType = "tool_result"
 	finishType     partType = "finish"
+	routeType      partType = "route"
+	usageType      partType = "usage"
+	citationsType  partType = "citations"
 )
 
 type partWrapper struct {
@@ -177,6 +277,16 @@ type partWrapper struct {
 	Data ContentPart `json:"data"`
 }
 
+// MarshalParts encodes the parts of a message the way they are stored
+func MarshalParts(parts []ContentPart) ([]byte, error) {
+	return marshallParts(parts)
+}
+
+// UnmarshalParts decodes the parts of a message encoded by MarshalParts
+func UnmarshalParts(data []byte) ([]ContentPart, error) {
+	return unmarshallParts(data)
+}
+
 func marshallParts(parts []ContentPart) ([]byte, error) {
 	wrappedParts := make([]partWrapper, len(parts))
 
@@ -198,6 +308,12 @@ func marshallParts(parts []ContentPart) ([]byte, error) {
 			typ = toolResultType
 		case Finish:
 			typ = finishType
+		case Route:
+			typ = routeType
+		case Usage:
+			typ = usageType
+		case Citations:
+			typ = citationsType
 		default:
 			return nil, fmt.Errorf("unknown part type: %T", part)
 		}
@@ -271,6 +387,24 @@ func unmarshallParts(data []byte) ([]ContentPart, error) {
 				return nil, err
 			}
 			parts = append(parts, part)
+		case routeType:
+			part := Route{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		case usageType:
+			part := Usage{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		case citationsType:
+			part := Citations{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
 		default:
 			return nil, fmt.Errorf("unknown part type: %s", wrapper.Type)
 		}
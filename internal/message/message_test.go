@@ -0,0 +1,173 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memQuerier keeps messages in memory, every write takes writeLatency like a
+// synced sqlite commit
+type memQuerier struct {
+	db.Querier
+	writeLatency time.Duration
+
+	mu       sync.Mutex
+	messages map[string]db.Message
+	writes   int
+	failing  error // Returned by the updates while set
+}
+
+func newMemQuerier(writeLatency time.Duration) *memQuerier {
+	return &memQuerier{writeLatency: writeLatency, messages: make(map[string]db.Message)}
+}
+
+func (q *memQuerier) CreateMessage(ctx context.Context, arg db.CreateMessageParams) (db.Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msg := db.Message{ID: arg.ID, SessionID: arg.SessionID, Role: arg.Role, Parts: arg.Parts, Model: arg.Model}
+	q.messages[arg.ID] = msg
+	return msg, nil
+}
+
+func (q *memQuerier) UpdateMessage(ctx context.Context, arg db.UpdateMessageParams) error {
+	time.Sleep(q.writeLatency)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.failing != nil {
+		return q.failing
+	}
+	msg := q.messages[arg.ID]
+	msg.Parts = arg.Parts
+	msg.FinishedAt = arg.FinishedAt
+	q.messages[arg.ID] = msg
+	q.writes++
+	return nil
+}
+
+func (q *memQuerier) GetMessage(ctx context.Context, id string) (db.Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.messages[id], nil
+}
+
+func (q *memQuerier) writeCount() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.writes
+}
+
+func TestUpdateBatchesStreamingWrites(t *testing.T) {
+	ctx := context.Background()
+	q := newMemQuerier(0)
+	s := NewService(q)
+	events := s.Subscribe(ctx)
+
+	msg, err := s.Create(ctx, "session", CreateMessageParams{Role: Assistant})
+	require.NoError(t, err)
+	<-events
+
+	for _, chunk := range []string{"Hel", "lo", " world"} {
+		msg.AppendContent(chunk)
+		require.NoError(t, s.Update(ctx, msg))
+		event := <-events
+		assert.Equal(t, msg.Content().String(), event.Payload.Content().String())
+	}
+	assert.Equal(t, 0, q.writeCount())
+
+	// Reads see the pending content
+	stored, err := s.Get(ctx, msg.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world", stored.Content().String())
+	assert.Equal(t, 1, q.writeCount())
+
+	msg.AppendContent("!")
+	msg.AddFinish(FinishReasonEndTurn)
+	require.NoError(t, s.Update(ctx, msg))
+	assert.Equal(t, 2, q.writeCount())
+
+	stored, err = s.Get(ctx, msg.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world!", stored.Content().String())
+	assert.NotNil(t, stored.FinishPart())
+}
+
+func TestUpdateFlushesAfterInterval(t *testing.T) {
+	ctx := context.Background()
+	q := newMemQuerier(0)
+	s := NewService(q)
+
+	msg, err := s.Create(ctx, "session", CreateMessageParams{Role: Assistant})
+	require.NoError(t, err)
+	msg.AppendContent("partial")
+	require.NoError(t, s.Update(ctx, msg))
+
+	assert.Eventually(t, func() bool { return q.writeCount() == 1 }, 10*persistInterval, persistInterval/5)
+}
+
+func TestFlushKeepsFailedUpdates(t *testing.T) {
+	ctx := context.Background()
+	q := newMemQuerier(0)
+	s := NewService(q).(*service)
+
+	first, err := s.Create(ctx, "session", CreateMessageParams{Role: Assistant})
+	require.NoError(t, err)
+	second, err := s.Create(ctx, "session", CreateMessageParams{Role: Assistant})
+	require.NoError(t, err)
+	first.AppendContent("first")
+	require.NoError(t, s.Update(ctx, first))
+	second.AppendContent("second")
+	require.NoError(t, s.Update(ctx, second))
+
+	q.mu.Lock()
+	q.failing = errors.New("database is locked")
+	q.mu.Unlock()
+	assert.EqualError(t, s.Flush(ctx), "database is locked")
+	assert.True(t, s.hasPending(), "the failed updates wait for the next flush")
+
+	// A newer update queued after the failure wins over the failed one
+	second.AppendContent(" and more")
+	require.NoError(t, s.Update(ctx, second))
+	q.mu.Lock()
+	q.failing = nil
+	q.mu.Unlock()
+	require.NoError(t, s.Flush(ctx))
+	assert.False(t, s.hasPending())
+
+	stored, err := s.Get(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "first", stored.Content().String())
+	stored, err = s.Get(ctx, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "second and more", stored.Content().String())
+}
+
+// BenchmarkStreamingUpdate measures the time a streamed chunk blocks the
+// agent, writes reports the database writes per chunk
+func BenchmarkStreamingUpdate(b *testing.B) {
+	ctx := context.Background()
+	q := newMemQuerier(200 * time.Microsecond)
+	s := NewService(q)
+	msg, err := s.Create(ctx, "session", CreateMessageParams{Role: Assistant})
+	require.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		msg.AppendContent("chunk ")
+		if err := s.Update(ctx, msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+	msg.AddFinish(FinishReasonEndTurn)
+	if err := s.Update(ctx, msg); err != nil {
+		b.Fatal(err)
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(q.writeCount())/float64(b.N), "writes/op")
+}
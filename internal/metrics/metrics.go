@@ -0,0 +1,404 @@
+// Package metrics persists the outcome of agent tasks and the latency of turns
+// and aggregates them into weekly trends to spot regressions in agent
+// performance.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/db"
+)
+
+// Task statuses
+const (
+	StatusDone     = "done"
+	StatusFailed   = "failed"
+	StatusCanceled = "canceled"
+	// StatusBudgetExceeded is a task stopped because it spent more than its
+	// budget, it counts as failed in the trends
+	StatusBudgetExceeded = "budget_exceeded"
+)
+
+// Task is the outcome of a task run by a subagent
+type Task struct {
+	ID               string // Task session ID
+	SessionID        string // Session that launched the task
+	Subagent         string
+	Status           string
+	Duration         time.Duration
+	PromptTokens     int64
+	CompletionTokens int64
+	Cost             float64
+	RetryAttempts    int       // Attempts after the first, by its retry policy
+	Created          time.Time // Set for recorded tasks
+}
+
+// ParallelRun is the outcome of a parallel_tasks call, its tasks are
+// recorded as tasks of their own
+type ParallelRun struct {
+	ID             string // Tool call ID, the prefix of the IDs of its tasks
+	SessionID      string
+	Tasks          int
+	Succeeded      int
+	Levels         int
+	MaxParallel    int
+	Duration       time.Duration
+	Tokens         int64
+	Cost           float64
+	BudgetExceeded bool
+	DAG            string // JSON of the levels and the outcome of every task
+	Created        time.Time
+}
+
+// Summary sums up the tasks of a subagent type over a period
+type Summary struct {
+	Subagent       string
+	Runs           int
+	Succeeded      int
+	Failed         int
+	Canceled       int
+	BudgetExceeded int
+	Retries        int // Attempts after the first of all runs
+	AvgDuration    time.Duration
+	P90Duration    time.Duration
+	Tokens         int64
+	Cost           float64
+}
+
+// SuccessRate returns the share of the runs that succeeded
+func (s Summary) SuccessRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Succeeded) / float64(s.Runs)
+}
+
+// CostPerRun returns the average cost of a run
+func (s Summary) CostPerRun() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return s.Cost / float64(s.Runs)
+}
+
+// Trend summarizes the tasks of a subagent type in a week
+type Trend struct {
+	Week        string // ISO week, e.g. 2026-W42
+	Subagent    string
+	Runs        int
+	Succeeded   int
+	Failed      int
+	Canceled    int
+	AvgDuration time.Duration
+	Cost        float64
+}
+
+// SuccessRate returns the share of the runs that succeeded
+func (t Trend) SuccessRate() float64 {
+	if t.Runs == 0 {
+		return 0
+	}
+	return float64(t.Succeeded) / float64(t.Runs)
+}
+
+// CostPerRun returns the average cost of a run
+func (t Trend) CostPerRun() float64 {
+	if t.Runs == 0 {
+		return 0
+	}
+	return t.Cost / float64(t.Runs)
+}
+
+// Latency is the time a turn waited for the first token of the model
+type Latency struct {
+	ID         string // First assistant message of the turn
+	SessionID  string
+	Model      string
+	FirstToken time.Duration
+}
+
+// LatencyTrend summarizes the time to the first token of a model in a week
+type LatencyTrend struct {
+	Week   string
+	Model  string
+	Turns  int
+	Median time.Duration
+	P90    time.Duration
+}
+
+type Service interface {
+	// RecordTask stores the outcome of a task
+	RecordTask(ctx context.Context, task Task) error
+	// Trends returns the weekly trends of the last weeks, oldest first
+	Trends(ctx context.Context, weeks int) ([]Trend, error)
+	// Tasks returns the tasks recorded in the last weeks, oldest first
+	Tasks(ctx context.Context, weeks int) ([]Task, error)
+	// RecordParallelRun stores the outcome of a parallel run
+	RecordParallelRun(ctx context.Context, run ParallelRun) error
+	// ParallelRuns returns the parallel runs recorded in the last weeks,
+	// oldest first
+	ParallelRuns(ctx context.Context, weeks int) ([]ParallelRun, error)
+	// RecordLatency stores the time to the first token of a turn
+	RecordLatency(ctx context.Context, latency Latency) error
+	// LatencyTrends returns the weekly first token latencies of the last
+	// weeks, oldest first
+	LatencyTrends(ctx context.Context, weeks int) ([]LatencyTrend, error)
+}
+
+type service struct {
+	q db.Querier
+}
+
+func NewService(q db.Querier) Service {
+	return &service{q: q}
+}
+
+func (s *service) RecordTask(ctx context.Context, task Task) error {
+	return s.q.CreateTaskMetric(ctx, db.CreateTaskMetricParams{
+		ID:               task.ID,
+		SessionID:        task.SessionID,
+		Subagent:         task.Subagent,
+		Status:           task.Status,
+		DurationMs:       task.Duration.Milliseconds(),
+		PromptTokens:     task.PromptTokens,
+		CompletionTokens: task.CompletionTokens,
+		Cost:             task.Cost,
+		RetryAttempts:    int64(task.RetryAttempts),
+	})
+}
+
+func (s *service) Trends(ctx context.Context, weeks int) ([]Trend, error) {
+	rows, err := s.q.ListTaskMetricsSince(ctx, since(weeks).Unix())
+	if err != nil {
+		return nil, err
+	}
+	return aggregate(rows), nil
+}
+
+// aggregate groups task metrics by week and subagent type
+func aggregate(rows []db.TaskMetric) []Trend {
+	type key struct{ week, subagent string }
+	byKey := make(map[key]*Trend)
+	durations := make(map[key]int64)
+	for _, row := range rows {
+		k := key{Week(time.Unix(row.CreatedAt, 0)), row.Subagent}
+		trend, ok := byKey[k]
+		if !ok {
+			trend = &Trend{Week: k.week, Subagent: k.subagent}
+			byKey[k] = trend
+		}
+		trend.Runs++
+		switch row.Status {
+		case StatusDone:
+			trend.Succeeded++
+		case StatusFailed, StatusBudgetExceeded:
+			trend.Failed++
+		case StatusCanceled:
+			trend.Canceled++
+		}
+		trend.Cost += row.Cost
+		durations[k] += row.DurationMs
+	}
+
+	trends := make([]Trend, 0, len(byKey))
+	for k, trend := range byKey {
+		trend.AvgDuration = time.Duration(durations[k]/int64(trend.Runs)) * time.Millisecond
+		trends = append(trends, *trend)
+	}
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Week != trends[j].Week {
+			return trends[i].Week < trends[j].Week
+		}
+		return trends[i].Subagent < trends[j].Subagent
+	})
+	return trends
+}
+
+func (s *service) Tasks(ctx context.Context, weeks int) ([]Task, error) {
+	rows, err := s.q.ListTaskMetricsSince(ctx, since(weeks).Unix())
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]Task, len(rows))
+	for i, row := range rows {
+		tasks[i] = Task{
+			ID:               row.ID,
+			SessionID:        row.SessionID,
+			Subagent:         row.Subagent,
+			Status:           row.Status,
+			Duration:         time.Duration(row.DurationMs) * time.Millisecond,
+			PromptTokens:     row.PromptTokens,
+			CompletionTokens: row.CompletionTokens,
+			Cost:             row.Cost,
+			RetryAttempts:    int(row.RetryAttempts),
+			Created:          time.Unix(row.CreatedAt, 0),
+		}
+	}
+	return tasks, nil
+}
+
+func (s *service) RecordParallelRun(ctx context.Context, run ParallelRun) error {
+	var budgetExceeded int64
+	if run.BudgetExceeded {
+		budgetExceeded = 1
+	}
+	return s.q.CreateParallelRun(ctx, db.CreateParallelRunParams{
+		ID:             run.ID,
+		SessionID:      run.SessionID,
+		TaskCount:      int64(run.Tasks),
+		Succeeded:      int64(run.Succeeded),
+		LevelCount:     int64(run.Levels),
+		MaxParallel:    int64(run.MaxParallel),
+		DurationMs:     run.Duration.Milliseconds(),
+		Tokens:         run.Tokens,
+		Cost:           run.Cost,
+		BudgetExceeded: budgetExceeded,
+		Dag:            run.DAG,
+	})
+}
+
+func (s *service) ParallelRuns(ctx context.Context, weeks int) ([]ParallelRun, error) {
+	rows, err := s.q.ListParallelRunsSince(ctx, since(weeks).Unix())
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]ParallelRun, len(rows))
+	for i, row := range rows {
+		runs[i] = ParallelRun{
+			ID:             row.ID,
+			SessionID:      row.SessionID,
+			Tasks:          int(row.TaskCount),
+			Succeeded:      int(row.Succeeded),
+			Levels:         int(row.LevelCount),
+			MaxParallel:    int(row.MaxParallel),
+			Duration:       time.Duration(row.DurationMs) * time.Millisecond,
+			Tokens:         row.Tokens,
+			Cost:           row.Cost,
+			BudgetExceeded: row.BudgetExceeded != 0,
+			DAG:            row.Dag,
+			Created:        time.Unix(row.CreatedAt, 0),
+		}
+	}
+	return runs, nil
+}
+
+// Summarize sums up tasks per subagent type, sorted by subagent
+func Summarize(tasks []Task) []Summary {
+	bySubagent := make(map[string]*Summary)
+	durations := make(map[string][]int64)
+	for _, task := range tasks {
+		summary, ok := bySubagent[task.Subagent]
+		if !ok {
+			summary = &Summary{Subagent: task.Subagent}
+			bySubagent[task.Subagent] = summary
+		}
+		summary.Runs++
+		switch task.Status {
+		case StatusDone:
+			summary.Succeeded++
+		case StatusFailed:
+			summary.Failed++
+		case StatusCanceled:
+			summary.Canceled++
+		case StatusBudgetExceeded:
+			summary.BudgetExceeded++
+		}
+		summary.Retries += task.RetryAttempts
+		summary.Tokens += task.PromptTokens + task.CompletionTokens
+		summary.Cost += task.Cost
+		durations[task.Subagent] = append(durations[task.Subagent], task.Duration.Milliseconds())
+	}
+
+	summaries := make([]Summary, 0, len(bySubagent))
+	for subagent, summary := range bySubagent {
+		sorted := durations[subagent]
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		var total int64
+		for _, ms := range sorted {
+			total += ms
+		}
+		summary.AvgDuration = time.Duration(total/int64(summary.Runs)) * time.Millisecond
+		summary.P90Duration = percentile(sorted, 90)
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Subagent < summaries[j].Subagent
+	})
+	return summaries
+}
+
+func (s *service) RecordLatency(ctx context.Context, latency Latency) error {
+	return s.q.CreateTurnLatency(ctx, db.CreateTurnLatencyParams{
+		ID:           latency.ID,
+		SessionID:    latency.SessionID,
+		Model:        latency.Model,
+		FirstTokenMs: latency.FirstToken.Milliseconds(),
+	})
+}
+
+func (s *service) LatencyTrends(ctx context.Context, weeks int) ([]LatencyTrend, error) {
+	rows, err := s.q.ListTurnLatenciesSince(ctx, since(weeks).Unix())
+	if err != nil {
+		return nil, err
+	}
+	return aggregateLatencies(rows), nil
+}
+
+// aggregateLatencies groups turn latencies by week and model
+func aggregateLatencies(rows []db.TurnLatency) []LatencyTrend {
+	type key struct{ week, model string }
+	byKey := make(map[key][]int64)
+	for _, row := range rows {
+		k := key{Week(time.Unix(row.CreatedAt, 0)), row.Model}
+		byKey[k] = append(byKey[k], row.FirstTokenMs)
+	}
+
+	trends := make([]LatencyTrend, 0, len(byKey))
+	for k, latencies := range byKey {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		trends = append(trends, LatencyTrend{
+			Week:   k.week,
+			Model:  k.model,
+			Turns:  len(latencies),
+			Median: percentile(latencies, 50),
+			P90:    percentile(latencies, 90),
+		})
+	}
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Week != trends[j].Week {
+			return trends[i].Week < trends[j].Week
+		}
+		return trends[i].Model < trends[j].Model
+	})
+	return trends
+}
+
+// percentile returns the nearest rank percentile of sorted latencies in
+// milliseconds
+func percentile(sorted []int64, p int) time.Duration {
+	rank := max(1, (p*len(sorted)+99)/100)
+	return time.Duration(sorted[rank-1]) * time.Millisecond
+}
+
+// since returns the start of the first of the last weeks
+func since(weeks int) time.Time {
+	if weeks <= 0 {
+		weeks = 1
+	}
+	return weekStart(time.Now()).AddDate(0, 0, -7*(weeks-1))
+}
+
+// Week returns the ISO week of t, e.g. 2026-W42
+func Week(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// weekStart returns the Monday midnight of the week of t
+func weekStart(t time.Time) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+}
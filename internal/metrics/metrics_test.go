@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregate(t *testing.T) {
+	monday := time.Date(2026, 10, 12, 10, 0, 0, 0, time.Local)
+	nextWeek := monday.AddDate(0, 0, 7)
+	rows := []db.TaskMetric{
+		{Subagent: "task", Status: StatusDone, DurationMs: 1000, Cost: 0.1, CreatedAt: monday.Unix()},
+		{Subagent: "task", Status: StatusFailed, DurationMs: 3000, Cost: 0.3, CreatedAt: monday.Add(48 * time.Hour).Unix()},
+		{Subagent: "research", Status: StatusDone, DurationMs: 2000, Cost: 0.2, CreatedAt: monday.Unix()},
+		{Subagent: "task", Status: StatusCanceled, DurationMs: 500, Cost: 0.05, CreatedAt: nextWeek.Unix()},
+	}
+
+	trends := aggregate(rows)
+	assert.Len(t, trends, 3)
+
+	assert.Equal(t, "2026-W42", trends[0].Week)
+	assert.Equal(t, "research", trends[0].Subagent)
+
+	task := trends[1]
+	assert.Equal(t, "task", task.Subagent)
+	assert.Equal(t, 2, task.Runs)
+	assert.Equal(t, 1, task.Succeeded)
+	assert.Equal(t, 1, task.Failed)
+	assert.Equal(t, 2*time.Second, task.AvgDuration)
+	assert.InDelta(t, 0.5, task.SuccessRate(), 1e-9)
+	assert.InDelta(t, 0.2, task.CostPerRun(), 1e-9)
+
+	assert.Equal(t, "2026-W43", trends[2].Week)
+	assert.Equal(t, 1, trends[2].Canceled)
+	assert.Zero(t, trends[2].SuccessRate())
+}
+
+func TestWeekStart(t *testing.T) {
+	sunday := time.Date(2026, 10, 18, 23, 0, 0, 0, time.Local)
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local), weekStart(sunday))
+	assert.Equal(t, Week(sunday), Week(weekStart(sunday)))
+}
+
+func TestAggregateLatencies(t *testing.T) {
+	monday := time.Date(2026, 10, 12, 10, 0, 0, 0, time.Local)
+	var rows []db.TurnLatency
+	for _, ms := range []int64{900, 100, 500, 300, 700, 200, 400, 800, 600, 1000} {
+		rows = append(rows, db.TurnLatency{Model: "claude-4-sonnet", FirstTokenMs: ms, CreatedAt: monday.Unix()})
+	}
+	rows = append(rows, db.TurnLatency{Model: "gpt-4.1", FirstTokenMs: 250, CreatedAt: monday.Unix()})
+
+	trends := aggregateLatencies(rows)
+	assert.Len(t, trends, 2)
+
+	claude := trends[0]
+	assert.Equal(t, "claude-4-sonnet", claude.Model)
+	assert.Equal(t, 10, claude.Turns)
+	assert.Equal(t, 500*time.Millisecond, claude.Median)
+	assert.Equal(t, 900*time.Millisecond, claude.P90)
+
+	gpt := trends[1]
+	assert.Equal(t, 1, gpt.Turns)
+	assert.Equal(t, 250*time.Millisecond, gpt.Median)
+	assert.Equal(t, 250*time.Millisecond, gpt.P90)
+}
+
+func TestSummarize(t *testing.T) {
+	summaries := Summarize([]Task{
+		{Subagent: "task", Status: StatusDone, Duration: time.Second, PromptTokens: 100, CompletionTokens: 20, Cost: 0.1, RetryAttempts: 2},
+		{Subagent: "task", Status: StatusBudgetExceeded, Duration: 3 * time.Second, Cost: 0.5},
+		{Subagent: "research", Status: StatusCanceled, Duration: 2 * time.Second},
+	})
+	assert.Len(t, summaries, 2)
+	assert.Equal(t, "research", summaries[0].Subagent)
+	assert.Equal(t, 1, summaries[0].Canceled)
+
+	task := summaries[1]
+	assert.Equal(t, 2, task.Runs)
+	assert.Equal(t, 1, task.BudgetExceeded)
+	assert.Equal(t, 2, task.Retries)
+	assert.Equal(t, 2*time.Second, task.AvgDuration)
+	assert.Equal(t, 3*time.Second, task.P90Duration)
+	assert.Equal(t, int64(120), task.Tokens)
+	assert.InDelta(t, 0.5, task.SuccessRate(), 1e-9)
+	assert.InDelta(t, 0.3, task.CostPerRun(), 1e-9)
+}
@@ -0,0 +1,204 @@
+// Package migration stores the plans of batched code migrations, so a
+// migration can be resumed from another session, and manages the git
+// worktrees the batches are migrated in.
+package migration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Batch states
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// ErrNotFound is returned for unknown plans
+var ErrNotFound = errors.New("migration not found")
+
+// Batch is a group of files migrated by one subagent
+type Batch struct {
+	Number  int      `json:"number"`
+	Files   []string `json:"files"` // Relative to the working directory
+	Status  string   `json:"status"`
+	Summary string   `json:"summary,omitempty"` // Report of the subagent
+	Error   string   `json:"error,omitempty"`
+	Patch   string   `json:"patch,omitempty"` // Saved changes that could not be applied
+}
+
+// Plan is a migration split into batches of files
+type Plan struct {
+	ID           string    `json:"id"`
+	Instructions string    `json:"instructions"`
+	Pattern      string    `json:"pattern"`
+	Verify       string    `json:"verify,omitempty"` // Command checking each batch
+	Created      time.Time `json:"created"`
+	Batches      []Batch   `json:"batches"`
+}
+
+// New splits files into batches of batchSize
+func New(id, pattern, instructions, verify string, files []string, batchSize int) Plan {
+	plan := Plan{
+		ID:           id,
+		Instructions: instructions,
+		Pattern:      pattern,
+		Verify:       verify,
+		Created:      time.Now(),
+	}
+	batchSize = max(batchSize, 1)
+	for start := 0; start < len(files); start += batchSize {
+		plan.Batches = append(plan.Batches, Batch{
+			Number: len(plan.Batches) + 1,
+			Files:  files[start:min(start+batchSize, len(files))],
+			Status: StatusPending,
+		})
+	}
+	return plan
+}
+
+// Progress counts the done and failed batches
+func (p Plan) Progress() (done, failed int) {
+	for _, batch := range p.Batches {
+		switch batch.Status {
+		case StatusDone:
+			done++
+		case StatusFailed:
+			failed++
+		}
+	}
+	return done, failed
+}
+
+// Finished reports whether every batch is done
+func (p Plan) Finished() bool {
+	done, _ := p.Progress()
+	return done == len(p.Batches)
+}
+
+func (p Plan) String() string {
+	done, failed := p.Progress()
+	status := fmt.Sprintf("%d/%d batches done", done, len(p.Batches))
+	if failed > 0 {
+		status += fmt.Sprintf(", %d failed", failed)
+	}
+	return fmt.Sprintf("%s (%s): %s", p.ID, status, p.Instructions)
+}
+
+// Save writes the plan to dir
+func Save(dir string, plan Plan) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create migrations directory: %w", err)
+	}
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write atomically, batches finishing at the same time save in turn but a
+	// crash must not leave a truncated plan
+	tmp := filepath.Join(dir, plan.ID+".json.tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save migration: %w", err)
+	}
+	return os.Rename(tmp, filepath.Join(dir, plan.ID+".json"))
+}
+
+// Load reads a plan from dir. Batches left running by an interrupted run are
+// pending again.
+func Load(dir, id string) (Plan, error) {
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Plan{}, ErrNotFound
+	}
+	if err != nil {
+		return Plan{}, err
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return Plan{}, fmt.Errorf("failed to parse migration %s: %w", id, err)
+	}
+	for i := range plan.Batches {
+		if plan.Batches[i].Status == StatusRunning {
+			plan.Batches[i].Status = StatusPending
+		}
+	}
+	return plan, nil
+}
+
+// List returns the plans in dir, the most recent first
+func List(dir string) ([]Plan, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var plans []Plan
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		plan, err := Load(dir, id)
+		if err != nil {
+			continue
+		}
+		plans = append(plans, plan)
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].Created.After(plans[j].Created) })
+	return plans, nil
+}
+
+// AddWorktree checks out the HEAD of repo into a new detached worktree
+func AddWorktree(ctx context.Context, repo, path string) error {
+	_, err := git(ctx, repo, nil, "worktree", "add", "--detach", path, "HEAD")
+	return err
+}
+
+// RemoveWorktree deletes a worktree created by AddWorktree
+func RemoveWorktree(repo, path string) {
+	git(context.Background(), repo, nil, "worktree", "remove", "--force", path)
+	os.RemoveAll(path)
+}
+
+// Diff returns the changes made in a worktree, including new files, as a
+// patch for git apply
+func Diff(ctx context.Context, worktree string) (string, error) {
+	if _, err := git(ctx, worktree, nil, "add", "-A"); err != nil {
+		return "", err
+	}
+	return git(ctx, worktree, nil, "diff", "--cached", "--binary", "HEAD")
+}
+
+// Apply applies a patch from Diff to the working tree of repo
+func Apply(ctx context.Context, repo, patch string) error {
+	_, err := git(ctx, repo, strings.NewReader(patch), "apply", "--whitespace=nowarn", "-")
+	return err
+}
+
+func git(ctx context.Context, dir string, stdin *strings.Reader, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
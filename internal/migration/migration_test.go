@@ -0,0 +1,94 @@
+package migration
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	plan := New("m1", "**/*.go", "use errors.Is", "", []string{"a.go", "b.go", "c.go", "d.go", "e.go"}, 2)
+
+	require.Len(t, plan.Batches, 3)
+	assert.Equal(t, []string{"a.go", "b.go"}, plan.Batches[0].Files)
+	assert.Equal(t, []string{"e.go"}, plan.Batches[2].Files)
+	assert.Equal(t, 3, plan.Batches[2].Number)
+	assert.False(t, plan.Finished())
+
+	plan.Batches[0].Status = StatusDone
+	plan.Batches[1].Status = StatusFailed
+	done, failed := plan.Progress()
+	assert.Equal(t, 1, done)
+	assert.Equal(t, 1, failed)
+	assert.Equal(t, "m1 (1/3 batches done, 1 failed): use errors.Is", plan.String())
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	plan := New("m1", "*.go", "migrate", "go build ./...", []string{"a.go", "b.go"}, 1)
+	plan.Batches[0].Status = StatusRunning
+	require.NoError(t, Save(dir, plan))
+
+	loaded, err := Load(dir, "m1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, loaded.Batches[0].Status, "interrupted batches are pending again")
+	assert.Equal(t, "go build ./...", loaded.Verify)
+
+	_, err = Load(dir, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, Save(dir, New("m2", "*.go", "other", "", []string{"a.go"}, 1)))
+	plans, err := List(dir)
+	require.NoError(t, err)
+	require.Len(t, plans, 2)
+	assert.Equal(t, "m2", plans[0].ID)
+
+	plans, err = List(filepath.Join(dir, "none"))
+	require.NoError(t, err)
+	assert.Empty(t, plans)
+}
+
+func TestWorktreeDiffApply(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "test"},
+	} {
+		_, err := git(ctx, repo, nil, args...)
+		require.NoError(t, err)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "a.txt"), []byte("old\n"), 0o644))
+	_, err := git(ctx, repo, nil, "add", "-A")
+	require.NoError(t, err)
+	_, err = git(ctx, repo, nil, "commit", "-q", "-m", "init")
+	require.NoError(t, err)
+
+	worktree := filepath.Join(t.TempDir(), "wt")
+	require.NoError(t, AddWorktree(ctx, repo, worktree))
+	require.NoError(t, os.WriteFile(filepath.Join(worktree, "a.txt"), []byte("new\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(worktree, "b.txt"), []byte("added\n"), 0o644))
+
+	patch, err := Diff(ctx, worktree)
+	require.NoError(t, err)
+	RemoveWorktree(repo, worktree)
+	assert.NoDirExists(t, worktree)
+
+	require.NoError(t, Apply(ctx, repo, patch))
+	data, err := os.ReadFile(filepath.Join(repo, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", string(data))
+	assert.FileExists(t, filepath.Join(repo, "b.txt"))
+
+	// The same patch no longer applies
+	assert.Error(t, Apply(ctx, repo, patch))
+}
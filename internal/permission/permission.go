@@ -39,6 +39,8 @@ type Service interface {
 	Deny(permission PermissionRequest)
 	Request(opts CreatePermissionRequest) bool
 	AutoApproveSession(sessionID string)
+	// RevokeAutoApproval asks for the permissions of a session again
+	RevokeAutoApproval(sessionID string)
 	IsSessionAutoApproved(sessionID string) bool
 }
 
@@ -112,6 +114,12 @@ func (s *permissionService) AutoApproveSession(sessionID string) {
 	s.autoApproveSessions = append(s.autoApproveSessions, sessionID)
 }
 
+func (s *permissionService) RevokeAutoApproval(sessionID string) {
+	s.autoApproveSessions = slices.DeleteFunc(s.autoApproveSessions, func(id string) bool {
+		return id == sessionID
+	})
+}
+
 func (s *permissionService) IsSessionAutoApproved(sessionID string) bool {
 	return slices.Contains(s.autoApproveSessions, sessionID)
 }
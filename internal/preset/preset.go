@@ -0,0 +1,222 @@
+// Package preset bundles an agent setup into a single file a team can share:
+// agent models, allowed tools, instruction files, session templates and
+// project commands.
+package preset
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/templates"
+)
+
+// Version is the version of the preset file format
+const Version = 1
+
+// Preset is a shareable agent setup
+type Preset struct {
+	Version      int                               `json:"version"`
+	Name         string                            `json:"name,omitempty"`
+	Description  string                            `json:"description,omitempty"`
+	Agents       map[config.AgentName]config.Agent `json:"agents,omitempty"`
+	AllowedTools []string                          `json:"allowedTools,omitempty"` // Tools the agents may use, all when empty
+	Instructions map[string]string                 `json:"instructions,omitempty"` // Instruction file path, relative to the working directory -> content
+	Templates    []templates.Template              `json:"templates,omitempty"`
+	Commands     map[string]string                 `json:"commands,omitempty"` // Command file path, relative to the project commands directory -> content
+}
+
+// Export builds a preset from the current configuration, the session
+// templates and the project commands
+func Export(name, description string) (Preset, error) {
+	cfg := config.Get()
+	p := Preset{
+		Version:      Version,
+		Name:         name,
+		Description:  description,
+		Agents:       cfg.Agents,
+		AllowedTools: cfg.AllowedTools,
+	}
+
+	instructions, err := readInstructions(cfg)
+	if err != nil {
+		return Preset{}, err
+	}
+	p.Instructions = instructions
+
+	if p.Templates, err = templates.List(); err != nil {
+		return Preset{}, err
+	}
+
+	commands, err := readFiles(commandsDir(cfg), ".md")
+	if err != nil {
+		return Preset{}, fmt.Errorf("failed to read commands: %w", err)
+	}
+	p.Commands = commands
+	return p, nil
+}
+
+// readInstructions reads the project instruction files among the context
+// paths. Global context files stay with each user.
+func readInstructions(cfg *config.Config) (map[string]string, error) {
+	instructions := make(map[string]string)
+	for _, path := range cfg.ContextPaths {
+		if filepath.IsAbs(path) || !filepath.IsLocal(path) {
+			continue
+		}
+		full := filepath.Join(cfg.WorkingDir, path)
+		info, err := os.Stat(full)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			files, err := readFiles(full, "")
+			if err != nil {
+				return nil, err
+			}
+			for name, content := range files {
+				instructions[filepath.ToSlash(filepath.Join(path, name))] = content
+			}
+			continue
+		}
+		data, err := os.ReadFile(full)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		instructions[filepath.ToSlash(path)] = string(data)
+	}
+	return instructions, nil
+}
+
+// readFiles returns the content of the files under dir with the given
+// extension, any when empty, keyed by their slash separated relative path
+func readFiles(dir, ext string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if ext != "" && !strings.HasSuffix(strings.ToLower(d.Name()), ext) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	return files, err
+}
+
+// Parse reads and validates a preset file
+func Parse(data []byte) (Preset, error) {
+	var p Preset
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Preset{}, fmt.Errorf("failed to parse preset: %w", err)
+	}
+	if p.Version > Version {
+		return Preset{}, fmt.Errorf("preset version %d is newer than the supported version %d", p.Version, Version)
+	}
+	// Presets come from other people, keep their files in the project
+	for path := range p.Instructions {
+		if !filepath.IsLocal(filepath.FromSlash(path)) {
+			return Preset{}, fmt.Errorf("invalid instruction file path %q", path)
+		}
+	}
+	for path := range p.Commands {
+		if !filepath.IsLocal(filepath.FromSlash(path)) || !strings.HasSuffix(strings.ToLower(path), ".md") {
+			return Preset{}, fmt.Errorf("invalid command file path %q", path)
+		}
+	}
+	for _, t := range p.Templates {
+		if err := templates.ValidateName(t.Name); err != nil {
+			return Preset{}, err
+		}
+	}
+	return p, nil
+}
+
+// Result lists what an import changed
+type Result struct {
+	Agents  []config.AgentName
+	Written []string // Files written, relative to the working directory or the data directory
+	Skipped []string // Existing files left alone
+}
+
+// Import applies a preset: the agents and allowed tools are written to the
+// config file, the other files are written in the project. Existing files
+// are only replaced when overwrite is set.
+func Import(p Preset, overwrite bool) (Result, error) {
+	cfg := config.Get()
+	var result Result
+
+	if len(p.Agents) > 0 || p.AllowedTools != nil {
+		if err := config.UpdateAgents(p.Agents, p.AllowedTools); err != nil {
+			return result, err
+		}
+		for name := range p.Agents {
+			result.Agents = append(result.Agents, name)
+		}
+		slices.Sort(result.Agents)
+	}
+
+	write := func(root, path, content string) error {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		rel, err := filepath.Rel(cfg.WorkingDir, full)
+		if err != nil {
+			rel = full
+		}
+		if _, err := os.Stat(full); err == nil && !overwrite {
+			result.Skipped = append(result.Skipped, rel)
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", rel, err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", rel, err)
+		}
+		result.Written = append(result.Written, rel)
+		return nil
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(p.Instructions)) {
+		if err := write(cfg.WorkingDir, path, p.Instructions[path]); err != nil {
+			return result, err
+		}
+	}
+	for _, t := range p.Templates {
+		data, err := json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return result, err
+		}
+		if err := write(templates.Dir(), t.Name+".json", string(data)); err != nil {
+			return result, err
+		}
+	}
+	for _, path := range slices.Sorted(maps.Keys(p.Commands)) {
+		if err := write(commandsDir(cfg), path, p.Commands[path]); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// commandsDir returns the directory of the project commands
+func commandsDir(cfg *config.Config) string {
+	return filepath.Join(cfg.Data.Directory, "commands")
+}
@@ -0,0 +1,58 @@
+package preset
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(`{
+		"version": 1,
+		"name": "backend",
+		"agents": {"coder": {"model": "claude-3.7-sonnet", "maxTokens": 5000}},
+		"allowedTools": ["view", "grep"],
+		"instructions": {"OpenCode.md": "Use tabs"},
+		"templates": [{"name": "bug-triage", "systemPrompt": "Reproduce first"}],
+		"commands": {"git/commit.md": "RUN git diff"}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, "backend", p.Name)
+	assert.Equal(t, int64(5000), p.Agents[config.AgentCoder].MaxTokens)
+	assert.Equal(t, []string{"view", "grep"}, p.AllowedTools)
+	assert.Equal(t, "Reproduce first", p.Templates[0].SystemPrompt)
+
+	for _, data := range []string{
+		`{"version": 2}`,
+		`{"instructions": {"../outside.md": ""}}`,
+		`{"instructions": {"/etc/profile": ""}}`,
+		`{"commands": {"../../.bashrc.md": ""}}`,
+		`{"commands": {"script.sh": ""}}`,
+		`{"templates": [{"name": "../escape"}]}`,
+		`not json`,
+	} {
+		_, err := Parse([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
+
+func TestReadInstructions(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "OpenCode.md"), []byte("Use tabs"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".cursor", "rules", "go"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".cursor", "rules", "go", "style.mdc"), []byte("gofmt"), 0o644))
+
+	instructions, err := readInstructions(&config.Config{
+		WorkingDir:   dir,
+		ContextPaths: []string{"OpenCode.md", ".cursor/rules/", "CLAUDE.md", filepath.Join(dir, "global.md")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"OpenCode.md":                "Use tabs",
+		".cursor/rules/go/style.mdc": "gofmt",
+	}, instructions)
+}
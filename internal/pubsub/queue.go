@@ -0,0 +1,134 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// Queue is a bounded ring buffer of events between a subscription and a slow
+// consumer. Events with the same coalescing key replace each other while
+// queued, so high-frequency updates like streamed message chunks don't fill
+// it up. When it is full of events that can't be coalesced, Push waits for the
+// consumer instead of dropping them.
+type Queue[T any] struct {
+	mu      sync.Mutex
+	entries []queueEntry[T]
+	head    int // Sequence number of the oldest queued event
+	tail    int // Sequence number of the next pushed event
+	keys    map[string]int
+	key     func(Event[T]) string
+	closed  bool
+
+	notEmpty chan struct{}
+	notFull  chan struct{}
+}
+
+type queueEntry[T any] struct {
+	event Event[T]
+	key   string
+}
+
+// NewQueue returns a queue of the given size. key returns the coalescing key
+// of an event, empty for events that must all be delivered; a nil key
+// coalesces nothing.
+func NewQueue[T any](size int, key func(Event[T]) string) *Queue[T] {
+	if size <= 0 {
+		size = bufferSize
+	}
+	return &Queue[T]{
+		entries:  make([]queueEntry[T], size),
+		keys:     make(map[string]int),
+		key:      key,
+		notEmpty: make(chan struct{}, 1),
+		notFull:  make(chan struct{}, 1),
+	}
+}
+
+// Push queues an event, replacing a queued event with the same key. It blocks
+// while the queue is full until there is room or the context is done.
+func (q *Queue[T]) Push(ctx context.Context, event Event[T]) error {
+	key := ""
+	if q.key != nil {
+		key = q.key(event)
+	}
+	for {
+		q.mu.Lock()
+		if key != "" {
+			if seq, ok := q.keys[key]; ok {
+				q.entries[seq%len(q.entries)].event = event
+				q.mu.Unlock()
+				return nil
+			}
+		}
+		if q.tail-q.head < len(q.entries) {
+			q.entries[q.tail%len(q.entries)] = queueEntry[T]{event: event, key: key}
+			if key != "" {
+				q.keys[key] = q.tail
+			}
+			q.tail++
+			q.mu.Unlock()
+			signal(q.notEmpty)
+			return nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.notFull:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Pop returns the oldest event, waiting for one to be pushed. It returns false
+// once the queue is closed and empty, or when the context is done.
+func (q *Queue[T]) Pop(ctx context.Context) (Event[T], bool) {
+	for {
+		q.mu.Lock()
+		if q.tail > q.head {
+			i := q.head % len(q.entries)
+			entry := q.entries[i]
+			q.entries[i] = queueEntry[T]{}
+			if entry.key != "" {
+				delete(q.keys, entry.key)
+			}
+			q.head++
+			q.mu.Unlock()
+			signal(q.notFull)
+			return entry.event, true
+		}
+		closed := q.closed
+		q.mu.Unlock()
+		if closed {
+			return Event[T]{}, false
+		}
+
+		select {
+		case <-q.notEmpty:
+		case <-ctx.Done():
+			return Event[T]{}, false
+		}
+	}
+}
+
+// Len returns the number of queued events
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.tail - q.head
+}
+
+// Close marks the end of the events, Pop still returns the queued ones
+func (q *Queue[T]) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	signal(q.notEmpty)
+}
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,85 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	ID   string
+	Text string
+}
+
+func itemKey(event Event[item]) string {
+	if event.Type != UpdatedEvent {
+		return ""
+	}
+	return event.Payload.ID
+}
+
+func TestQueueCoalescesUpdates(t *testing.T) {
+	ctx := context.Background()
+	q := NewQueue(8, itemKey)
+
+	require.NoError(t, q.Push(ctx, Event[item]{Type: CreatedEvent, Payload: item{ID: "a"}}))
+	for _, text := range []string{"H", "He", "Hel", "Hello"} {
+		require.NoError(t, q.Push(ctx, Event[item]{Type: UpdatedEvent, Payload: item{ID: "a", Text: text}}))
+	}
+	require.NoError(t, q.Push(ctx, Event[item]{Type: CreatedEvent, Payload: item{ID: "b"}}))
+	assert.Equal(t, 3, q.Len())
+
+	event, ok := q.Pop(ctx)
+	require.True(t, ok)
+	assert.Equal(t, CreatedEvent, event.Type)
+	event, _ = q.Pop(ctx)
+	assert.Equal(t, "Hello", event.Payload.Text)
+	event, _ = q.Pop(ctx)
+	assert.Equal(t, "b", event.Payload.ID)
+
+	// Once delivered, an update is queued again instead of replacing
+	require.NoError(t, q.Push(ctx, Event[item]{Type: UpdatedEvent, Payload: item{ID: "a", Text: "Hello!"}}))
+	event, _ = q.Pop(ctx)
+	assert.Equal(t, "Hello!", event.Payload.Text)
+}
+
+func TestQueueWaitsWhenFull(t *testing.T) {
+	ctx := context.Background()
+	q := NewQueue[item](2, nil)
+	require.NoError(t, q.Push(ctx, Event[item]{Payload: item{ID: "1"}}))
+	require.NoError(t, q.Push(ctx, Event[item]{Payload: item{ID: "2"}}))
+
+	pushed := make(chan error)
+	go func() {
+		pushed <- q.Push(ctx, Event[item]{Payload: item{ID: "3"}})
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("push to a full queue returned")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	event, _ := q.Pop(ctx)
+	assert.Equal(t, "1", event.Payload.ID)
+	require.NoError(t, <-pushed)
+
+	q.Close()
+	for _, id := range []string{"2", "3"} {
+		event, ok := q.Pop(ctx)
+		require.True(t, ok)
+		assert.Equal(t, id, event.Payload.ID)
+	}
+	_, ok := q.Pop(ctx)
+	assert.False(t, ok)
+}
+
+func TestQueuePushCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := NewQueue[item](1, nil)
+	require.NoError(t, q.Push(ctx, Event[item]{}))
+	cancel()
+	assert.ErrorIs(t, q.Push(ctx, Event[item]{}), context.Canceled)
+}
@@ -0,0 +1,212 @@
+// Package quota tracks the monthly usage of provider API keys and enforces
+// the quotas configured for them.
+package quota
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/logging"
+)
+
+// ErrQuotaExceeded is returned for requests to a provider whose key used up
+// a quota in stop mode.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// warnThreshold is the share of a quota after which a warning is shown
+const warnThreshold = 0.8
+
+// Usage is the usage of a provider key in a month
+type Usage struct {
+	Provider         models.ModelProvider
+	KeyID            string
+	Month            string // YYYY-MM
+	PromptTokens     int64
+	CompletionTokens int64
+	Cost             float64
+	Quota            config.QuotaConfig // Zero when the key is not configured anymore
+}
+
+// Tokens returns the total number of tokens used
+func (u Usage) Tokens() int64 {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Used returns the share of the quota used, the larger of the token and cost
+// shares. It is zero without a quota.
+func (u Usage) Used() float64 {
+	var used float64
+	if u.Quota.MonthlyTokens > 0 {
+		used = float64(u.Tokens()) / float64(u.Quota.MonthlyTokens)
+	}
+	if u.Quota.MonthlyCost > 0 {
+		used = max(used, u.Cost/u.Quota.MonthlyCost)
+	}
+	return used
+}
+
+type Service interface {
+	// Record adds the usage of a request to the current month
+	Record(ctx context.Context, provider models.ModelProvider, promptTokens, completionTokens int64, cost float64) error
+	// Check returns ErrQuotaExceeded when a request to provider must not be
+	// made, and warns when the quota is nearly or completely used.
+	Check(ctx context.Context, provider models.ModelProvider) error
+	// Report returns the usage of every key in month, including configured
+	// quotas that are not used yet
+	Report(ctx context.Context, month string) ([]Usage, error)
+}
+
+type service struct {
+	q db.Querier
+
+	mu     sync.Mutex
+	warned map[string]float64 // provider/key/month -> threshold warned about
+}
+
+func NewService(q db.Querier) Service {
+	return &service{
+		q:      q,
+		warned: make(map[string]float64),
+	}
+}
+
+// KeyID returns a fingerprint of an API key that identifies it in reports
+// without storing the key itself
+func KeyID(apiKey string) string {
+	if apiKey == "" {
+		return "none"
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// CurrentMonth returns the month usage is currently recorded in
+func CurrentMonth() string {
+	return time.Now().Format("2006-01")
+}
+
+func (s *service) Record(ctx context.Context, provider models.ModelProvider, promptTokens, completionTokens int64, cost float64) error {
+	return s.q.AddProviderUsage(ctx, db.AddProviderUsageParams{
+		Provider:         string(provider),
+		KeyID:            KeyID(config.Get().Providers[provider].APIKey),
+		Month:            CurrentMonth(),
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		Cost:             cost,
+	})
+}
+
+func (s *service) Check(ctx context.Context, provider models.ModelProvider) error {
+	providerCfg := config.Get().Providers[provider]
+	if !providerCfg.Quota.Enabled() {
+		return nil
+	}
+
+	usage, err := s.get(ctx, provider, KeyID(providerCfg.APIKey), CurrentMonth())
+	if err != nil {
+		// Failing to read the usage should not block the user
+		logging.Warn("failed to read provider usage", "provider", provider, "error", err)
+		return nil
+	}
+	usage.Quota = providerCfg.Quota
+
+	used := usage.Used()
+	switch {
+	case used >= 1 && usage.Quota.Mode == config.QuotaStop:
+		return fmt.Errorf("%w: %s used %s of its monthly quota, see `opencode quota`", ErrQuotaExceeded, provider, percent(used))
+	case used >= 1:
+		s.warnOnce(usage, 1, fmt.Sprintf("%s used up its monthly quota (%s)", provider, percent(used)))
+	case used >= warnThreshold:
+		s.warnOnce(usage, warnThreshold, fmt.Sprintf("%s used %s of its monthly quota", provider, percent(used)))
+	}
+	return nil
+}
+
+func (s *service) Report(ctx context.Context, month string) ([]Usage, error) {
+	rows, err := s.q.ListProviderUsageByMonth(ctx, month)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := config.Get()
+	seen := make(map[models.ModelProvider]bool)
+	report := make([]Usage, 0, len(rows))
+	for _, row := range rows {
+		usage := fromDBItem(row)
+		if providerCfg, ok := cfg.Providers[usage.Provider]; ok && KeyID(providerCfg.APIKey) == usage.KeyID {
+			usage.Quota = providerCfg.Quota
+			seen[usage.Provider] = true
+		}
+		report = append(report, usage)
+	}
+	for provider, providerCfg := range cfg.Providers {
+		if seen[provider] || !providerCfg.Quota.Enabled() {
+			continue
+		}
+		report = append(report, Usage{
+			Provider: provider,
+			KeyID:    KeyID(providerCfg.APIKey),
+			Month:    month,
+			Quota:    providerCfg.Quota,
+		})
+	}
+	sort.SliceStable(report, func(i, j int) bool {
+		if report[i].Provider != report[j].Provider {
+			return report[i].Provider < report[j].Provider
+		}
+		return report[i].KeyID < report[j].KeyID
+	})
+	return report, nil
+}
+
+func (s *service) get(ctx context.Context, provider models.ModelProvider, keyID, month string) (Usage, error) {
+	row, err := s.q.GetProviderUsage(ctx, db.GetProviderUsageParams{
+		Provider: string(provider),
+		KeyID:    keyID,
+		Month:    month,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return Usage{Provider: provider, KeyID: keyID, Month: month}, nil
+	}
+	if err != nil {
+		return Usage{}, err
+	}
+	return fromDBItem(row), nil
+}
+
+// warnOnce shows a warning the first time a key crosses threshold in a month
+func (s *service) warnOnce(usage Usage, threshold float64, msg string) {
+	key := string(usage.Provider) + "/" + usage.KeyID + "/" + usage.Month
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.warned[key] >= threshold {
+		return
+	}
+	s.warned[key] = threshold
+	logging.WarnPersist(msg)
+}
+
+func fromDBItem(item db.ProviderUsage) Usage {
+	return Usage{
+		Provider:         models.ModelProvider(item.Provider),
+		KeyID:            item.KeyID,
+		Month:            item.Month,
+		PromptTokens:     item.PromptTokens,
+		CompletionTokens: item.CompletionTokens,
+		Cost:             item.Cost,
+	}
+}
+
+func percent(used float64) string {
+	return fmt.Sprintf("%.0f%%", used*100)
+}
@@ -0,0 +1,29 @@
+package quota
+
+import (
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageUsed(t *testing.T) {
+	usage := Usage{PromptTokens: 600, CompletionTokens: 200, Cost: 3}
+	assert.Equal(t, int64(800), usage.Tokens())
+	assert.Zero(t, usage.Used())
+
+	usage.Quota = config.QuotaConfig{MonthlyTokens: 1000}
+	assert.InDelta(t, 0.8, usage.Used(), 1e-9)
+
+	// The larger share counts
+	usage.Quota.MonthlyCost = 2
+	assert.InDelta(t, 1.5, usage.Used(), 1e-9)
+}
+
+func TestKeyID(t *testing.T) {
+	assert.Equal(t, "none", KeyID(""))
+	assert.Len(t, KeyID("sk-secret"), 12)
+	assert.Equal(t, KeyID("sk-secret"), KeyID("sk-secret"))
+	assert.NotEqual(t, KeyID("sk-secret"), KeyID("sk-other"))
+	assert.NotContains(t, KeyID("sk-secret"), "secret")
+}
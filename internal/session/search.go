@@ -0,0 +1,116 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/kirmad/superopencode/internal/db"
+)
+
+// Markers around the matched terms in the snippets of search results
+const (
+	MatchStart = "\x02"
+	MatchEnd   = "\x03"
+)
+
+// maxSearchMessages is the number of matching messages the sessions of a
+// search are ranked from
+const maxSearchMessages = 500
+
+// SearchResult is a session matching a search, with the snippet of its best
+// matching message
+type SearchResult struct {
+	Session   Session
+	MessageID string
+	Snippet   string // Matched terms are between MatchStart and MatchEnd
+	Matches   int    // Number of matching messages
+}
+
+// Search finds the sessions whose messages match a query, the best match
+// first. Words of the query must all appear in a message, the last one as a
+// prefix, and quoted words as a phrase. Task sessions are only searched with
+// tasks set.
+func (s *service) Search(ctx context.Context, query string, limit int, tasks bool) ([]SearchResult, error) {
+	match := FTSQuery(query)
+	if match == "" {
+		return nil, errors.New("empty search")
+	}
+	ephemeral := int64(0)
+	if tasks {
+		ephemeral = 1
+	}
+	rows, err := s.q.SearchMessages(ctx, db.SearchMessagesParams{
+		MarkStart:  MatchStart,
+		MarkEnd:    MatchEnd,
+		Query:      match,
+		Ephemeral:  ephemeral,
+		MaxResults: maxSearchMessages,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	bySession := make(map[string]int)
+	for _, row := range rows {
+		if i, ok := bySession[row.SessionID]; ok {
+			results[i].Matches++
+			continue
+		}
+		if limit > 0 && len(results) == limit {
+			continue
+		}
+		sess, err := s.Get(ctx, row.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		bySession[row.SessionID] = len(results)
+		results = append(results, SearchResult{
+			Session:   sess,
+			MessageID: row.MessageID,
+			Snippet:   strings.Join(strings.Fields(row.Snippet), " "),
+			Matches:   1,
+		})
+	}
+	return results, nil
+}
+
+// FTSQuery turns a search typed by the user into an FTS5 query: every word
+// is quoted so punctuation doesn't break the syntax, the words are all
+// required and the last one matches as a prefix while it's being typed.
+// Quoted words are matched as a phrase.
+func FTSQuery(query string) string {
+	var terms []string
+	var term strings.Builder
+	quoted, prefix := false, false
+	flush := func() {
+		if term.Len() > 0 {
+			terms = append(terms, `"`+strings.ReplaceAll(term.String(), `"`, `""`)+`"`)
+			term.Reset()
+		}
+	}
+	for _, r := range query {
+		switch {
+		case r == '"':
+			flush()
+			quoted = !quoted
+			prefix = false
+		case unicode.IsSpace(r) && !quoted:
+			flush()
+			prefix = false
+		default:
+			term.WriteRune(r)
+			prefix = !quoted
+		}
+	}
+	flush()
+	if len(terms) == 0 {
+		return ""
+	}
+	if prefix {
+		terms[len(terms)-1] += "*"
+	}
+	return strings.Join(terms, " ")
+}
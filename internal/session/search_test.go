@@ -0,0 +1,88 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFTSQuery(t *testing.T) {
+	assert.Equal(t, `"retry" "polic"*`, FTSQuery("retry polic"))
+	assert.Equal(t, `"retry" "policy"`, FTSQuery(" retry policy "))
+	assert.Equal(t, `"go test" "race"*`, FTSQuery(`"go test" race`))
+	assert.Equal(t, `"don't" "a-b"*`, FTSQuery("don't a-b"))
+	assert.Equal(t, `"NOT" "say" "hi"`, FTSQuery(`NOT say"hi"`), "operators are words, quotes split terms")
+	assert.Empty(t, FTSQuery(` "" `))
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	conn, err := db.ConnectEphemeral()
+	require.NoError(t, err)
+	defer conn.Close()
+	q := db.New(conn)
+	sessions := NewService(q)
+
+	addSession := func(id, parentID string, texts ...string) {
+		ephemeral := int64(0)
+		if parentID != "" {
+			ephemeral = 1
+		}
+		_, err := q.CreateSession(ctx, db.CreateSessionParams{
+			ID:              id,
+			ParentSessionID: sql.NullString{String: parentID, Valid: parentID != ""},
+			Title:           "Session " + id,
+			Ephemeral:       ephemeral,
+		})
+		require.NoError(t, err)
+		for i, text := range texts {
+			_, err := q.CreateMessage(ctx, db.CreateMessageParams{
+				ID:        id + string(rune('a'+i)),
+				SessionID: id,
+				Role:      "user",
+				Parts:     `[{"type":"text","data":{"text":` + `"` + text + `"}},{"type":"tool_result","data":{"content":"flaky output"}}]`,
+			})
+			require.NoError(t, err)
+		}
+	}
+	addSession("retries", "", "Add retry policies to the agent tasks", "Retrying a failed task resumes it")
+	addSession("budget", "", "Add budgets to parallel tasks")
+	addSession("task", "retries", "Retry the failed tasks of the run")
+
+	results, err := sessions.Search(ctx, "retr", 10, false)
+	require.NoError(t, err)
+	require.Len(t, results, 1, "task sessions are left out")
+	assert.Equal(t, "retries", results[0].Session.ID)
+	assert.Equal(t, 2, results[0].Matches, "retry and retrying match as a prefix")
+	assert.Contains(t, results[0].Snippet, MatchStart)
+
+	results, err = sessions.Search(ctx, "retry", 10, true)
+	require.NoError(t, err)
+	assert.Len(t, results, 2, "retry matches retrying through stemming")
+
+	results, err = sessions.Search(ctx, "tasks", 1, false)
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	results, err = sessions.Search(ctx, "flaky", 10, true)
+	require.NoError(t, err)
+	assert.Empty(t, results, "tool results aren't indexed")
+
+	// Updates and deletes are indexed
+	require.NoError(t, q.UpdateMessage(ctx, db.UpdateMessageParams{ID: "budgeta", Parts: `[{"type":"text","data":{"text":"Cap the cost of parallel runs"}}]`}))
+	results, err = sessions.Search(ctx, "cap cost", 10, false)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "budget", results[0].Session.ID)
+	require.NoError(t, q.DeleteMessage(ctx, "budgeta"))
+	results, err = sessions.Search(ctx, "cost", 10, false)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	_, err = sessions.Search(ctx, "  ", 10, false)
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package session
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/kirmad/superopencode/internal/db"
@@ -17,7 +18,10 @@ type Session struct {
 	PromptTokens     int64
 	CompletionTokens int64
 	SummaryMessageID string
+	SystemPrompt     string // Session specific additions to the system prompt
+	Language         string // Language the agents answer in, locale.language when empty
 	Cost             float64
+	Ephemeral        bool // Created for a task or a title, collected after the retention
 	CreatedAt        int64
 	UpdatedAt        int64
 }
@@ -29,8 +33,11 @@ type Service interface {
 	CreateTaskSession(ctx context.Context, toolCallID, parentSessionID, title string) (Session, error)
 	Get(ctx context.Context, id string) (Session, error)
 	List(ctx context.Context) ([]Session, error)
+	ListAll(ctx context.Context) ([]Session, error)
+	ListExpired(ctx context.Context, before time.Time) ([]Session, error)
 	Save(ctx context.Context, session Session) (Session, error)
 	Delete(ctx context.Context, id string) error
+	Search(ctx context.Context, query string, limit int, tasks bool) ([]SearchResult, error)
 }
 
 type service struct {
@@ -56,6 +63,7 @@ func (s *service) CreateTaskSession(ctx context.Context, toolCallID, parentSessi
 		ID:              toolCallID,
 		ParentSessionID: sql.NullString{String: parentSessionID, Valid: true},
 		Title:           title,
+		Ephemeral:       1,
 	})
 	if err != nil {
 		return Session{}, err
@@ -70,6 +78,7 @@ func (s *service) CreateTitleSession(ctx context.Context, parentSessionID string
 		ID:              "title-" + parentSessionID,
 		ParentSessionID: sql.NullString{String: parentSessionID, Valid: true},
 		Title:           "Generate a title",
+		Ephemeral:       1,
 	})
 	if err != nil {
 		return Session{}, err
@@ -111,6 +120,11 @@ func (s *service) Save(ctx context.Context, session Session) (Session, error) {
 			Valid:  session.SummaryMessageID != "",
 		},
 		Cost: session.Cost,
+		SystemPrompt: sql.NullString{
+			String: session.SystemPrompt,
+			Valid:  session.SystemPrompt != "",
+		},
+		Language: session.Language,
 	})
 	if err != nil {
 		return Session{}, err
@@ -132,6 +146,33 @@ func (s *service) List(ctx context.Context) ([]Session, error) {
 	return sessions, nil
 }
 
+// ListAll returns all sessions including task sessions, the most recent first
+func (s *service) ListAll(ctx context.Context) ([]Session, error) {
+	dbSessions, err := s.q.ListAllSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, len(dbSessions))
+	for i, dbSession := range dbSessions {
+		sessions[i] = s.fromDBItem(dbSession)
+	}
+	return sessions, nil
+}
+
+// ListExpired returns the ephemeral sessions last updated before a time, the
+// oldest first
+func (s *service) ListExpired(ctx context.Context, before time.Time) ([]Session, error) {
+	dbSessions, err := s.q.ListExpiredEphemeralSessions(ctx, before.Unix())
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, len(dbSessions))
+	for i, dbSession := range dbSessions {
+		sessions[i] = s.fromDBItem(dbSession)
+	}
+	return sessions, nil
+}
+
 func (s service) fromDBItem(item db.Session) Session {
 	return Session{
 		ID:               item.ID,
@@ -141,7 +182,10 @@ func (s service) fromDBItem(item db.Session) Session {
 		PromptTokens:     item.PromptTokens,
 		CompletionTokens: item.CompletionTokens,
 		SummaryMessageID: item.SummaryMessageID.String,
+		SystemPrompt:     item.SystemPrompt.String,
+		Language:         item.Language,
 		Cost:             item.Cost,
+		Ephemeral:        item.Ephemeral != 0,
 		CreatedAt:        item.CreatedAt,
 		UpdatedAt:        item.UpdatedAt,
 	}
@@ -0,0 +1,47 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListExpired(t *testing.T) {
+	ctx := context.Background()
+	conn, err := db.ConnectEphemeral()
+	require.NoError(t, err)
+	defer conn.Close()
+	sessions := NewService(db.New(conn))
+
+	parent, err := sessions.Create(ctx, "parent")
+	require.NoError(t, err)
+	task, err := sessions.CreateTaskSession(ctx, "call", parent.ID, "task")
+	require.NoError(t, err)
+	title, err := sessions.CreateTitleSession(ctx, parent.ID)
+	require.NoError(t, err)
+	// The sessions are dated back, the trigger would set updated_at to now
+	_, err = conn.Exec("DROP TRIGGER update_sessions_updated_at")
+	require.NoError(t, err)
+	for id, days := range map[string]int{parent.ID: 30, task.ID: 10, title.ID: 2} {
+		_, err := conn.Exec("UPDATE sessions SET updated_at = ? WHERE id = ?", time.Now().AddDate(0, 0, -days).Unix(), id)
+		require.NoError(t, err)
+	}
+
+	expired, err := sessions.ListExpired(ctx, time.Now().AddDate(0, 0, -7))
+	require.NoError(t, err)
+	require.Len(t, expired, 1, "sessions that aren't ephemeral never expire")
+	assert.Equal(t, task.ID, expired[0].ID)
+
+	expired, err = sessions.ListExpired(ctx, time.Now().AddDate(0, 0, -1))
+	require.NoError(t, err)
+	require.Len(t, expired, 2)
+	assert.Equal(t, []string{task.ID, title.ID}, []string{expired[0].ID, expired[1].ID}, "the oldest first")
+
+	expired, err = sessions.ListExpired(ctx, time.Now().AddDate(0, 0, -60))
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+}
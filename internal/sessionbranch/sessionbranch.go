@@ -0,0 +1,297 @@
+// Package sessionbranch keeps the work of each session on its own git branch.
+// Uncommitted changes are stashed when leaving a session branch and restored
+// when coming back to it, and the branch a session started from is kept in
+// the git config to compare against.
+package sessionbranch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// baseKey is the git config key of a session branch holding its base branch
+const baseKey = "opencodebase"
+
+// Branch is a session branch of the repository
+type Branch struct {
+	Name      string
+	SessionID string
+	Updated   time.Time // Date of the last commit
+}
+
+// Name returns the branch of a session
+func Name(prefix, sessionID string) string {
+	return prefix + sessionID
+}
+
+// Current returns the checked out branch, empty for a detached HEAD
+func Current(ctx context.Context, dir string) (string, error) {
+	out, err := git(ctx, dir, "branch", "--show-current")
+	return strings.TrimSpace(out), err
+}
+
+// Enter checks out the branch of a session, creating it from the current
+// branch the first time. The uncommitted changes of the session branch being
+// left are stashed, those stashed for the entered branch are restored.
+func Enter(ctx context.Context, dir, prefix, sessionID string) (string, error) {
+	branch := Name(prefix, sessionID)
+	current, err := Current(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+	if current == branch {
+		return branch, nil
+	}
+
+	if strings.HasPrefix(current, prefix) {
+		if err := stash(ctx, dir, current); err != nil {
+			return "", err
+		}
+	}
+	if exists(ctx, dir, branch) {
+		if _, err := git(ctx, dir, "checkout", "--quiet", branch); err != nil {
+			return "", err
+		}
+		return branch, unstash(ctx, dir, branch)
+	}
+
+	// A new session starts from the current branch, taking the uncommitted
+	// changes along unless they belong to another session
+	base := current
+	if strings.HasPrefix(current, prefix) {
+		base = Base(ctx, dir, current)
+	}
+	if base == "" {
+		base = "HEAD"
+	}
+	if _, err := git(ctx, dir, "checkout", "--quiet", "-b", branch, base); err != nil {
+		return "", err
+	}
+	if base != "HEAD" {
+		_, err = git(ctx, dir, "config", fmt.Sprintf("branch.%s.%s", branch, baseKey), base)
+	}
+	return branch, err
+}
+
+// Base returns the branch a session branch was created from
+func Base(ctx context.Context, dir, branch string) string {
+	out, _ := git(ctx, dir, "config", "--get", fmt.Sprintf("branch.%s.%s", branch, baseKey))
+	return strings.TrimSpace(out)
+}
+
+// Checkpoint commits all changes to the current session branch and returns
+// the short hash of the commit
+func Checkpoint(ctx context.Context, dir, prefix, message string) (string, error) {
+	current, err := Current(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(current, prefix) {
+		return "", fmt.Errorf("%q is not a session branch", current)
+	}
+	if _, err := git(ctx, dir, "add", "--all"); err != nil {
+		return "", err
+	}
+	if out, _ := git(ctx, dir, "status", "--porcelain"); strings.TrimSpace(out) == "" {
+		return "", fmt.Errorf("nothing to commit on %s", current)
+	}
+	if _, err := git(ctx, dir, "commit", "--quiet", "--no-verify", "-m", message); err != nil {
+		return "", err
+	}
+	out, err := git(ctx, dir, "rev-parse", "--short", "HEAD")
+	return strings.TrimSpace(out), err
+}
+
+// Compare summarizes how a session branch differs from its base: the commits
+// made on it, the changed files including uncommitted changes
+func Compare(ctx context.Context, dir, branch string) (string, error) {
+	base := Base(ctx, dir, branch)
+	if base == "" {
+		return "", fmt.Errorf("the base branch of %s is unknown", branch)
+	}
+	commits, err := git(ctx, dir, "log", "--oneline", "--no-decorate", base+".."+branch)
+	if err != nil {
+		return "", err
+	}
+	// Against the merge base, so changes made to the base since don't show up
+	mergeBase, err := git(ctx, dir, "merge-base", base, branch)
+	if err != nil {
+		return "", err
+	}
+	args := []string{"diff", "--stat", strings.TrimSpace(mergeBase)}
+	if current, _ := Current(ctx, dir); current != branch {
+		args = append(args, branch)
+	}
+	stat, err := git(ctx, dir, args...)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s compared to %s\n", branch, base)
+	if commits = strings.TrimSpace(commits); commits == "" {
+		sb.WriteString("\nNo commits yet.\n")
+	} else {
+		fmt.Fprintf(&sb, "\nCommits:\n%s\n", commits)
+	}
+	if stat = strings.TrimRight(stat, "\n"); stat == "" {
+		sb.WriteString("\nNo changes.\n")
+	} else {
+		fmt.Fprintf(&sb, "\nChanges:\n%s\n", stat)
+	}
+	return sb.String(), nil
+}
+
+// Uncommitted reports whether the working tree has changes that aren't
+// committed, untracked files included
+func Uncommitted(ctx context.Context, dir string) (bool, error) {
+	out, err := git(ctx, dir, "status", "--porcelain")
+	return strings.TrimSpace(out) != "", err
+}
+
+// RemoteDefault returns the default branch of the origin remote, empty when
+// it isn't known
+func RemoteDefault(ctx context.Context, dir string) string {
+	out, _ := git(ctx, dir, "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+	return strings.TrimPrefix(strings.TrimSpace(out), "origin/")
+}
+
+// Push publishes a branch to the origin remote and makes it the upstream of
+// the branch
+func Push(ctx context.Context, dir, branch string) error {
+	_, err := git(ctx, dir, "push", "--quiet", "--set-upstream", "origin", branch)
+	return err
+}
+
+// List returns the session branches of the repository
+func List(ctx context.Context, dir, prefix string) ([]Branch, error) {
+	out, err := git(ctx, dir, "for-each-ref", "--format=%(refname:short)%09%(committerdate:unix)", "refs/heads/"+prefix+"*")
+	if err != nil {
+		return nil, err
+	}
+	var branches []Branch
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		name, date, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		var sec int64
+		fmt.Sscan(date, &sec)
+		branches = append(branches, Branch{
+			Name:      name,
+			SessionID: strings.TrimPrefix(name, prefix),
+			Updated:   time.Unix(sec, 0),
+		})
+	}
+	return branches, nil
+}
+
+// UnmergedError is returned when deleting a session branch with commits that
+// aren't merged into its base branch
+type UnmergedError struct {
+	Branch  string
+	Base    string
+	Commits int
+}
+
+func (e *UnmergedError) Error() string {
+	return fmt.Sprintf("%s has %d commits not merged into %s", e.Branch, e.Commits, e.Base)
+}
+
+// Delete removes a session branch, its stashed changes and its base. The
+// checked out branch can't be deleted, and a branch with commits that aren't
+// merged into its base branch, or into HEAD when its base is unknown, is kept
+// with an *UnmergedError.
+func Delete(ctx context.Context, dir, branch string) error {
+	if current, _ := Current(ctx, dir); current == branch {
+		return fmt.Errorf("%s is checked out", branch)
+	}
+	base := Base(ctx, dir, branch)
+	if base == "" || !exists(ctx, dir, base) {
+		base = "HEAD"
+	}
+	out, err := git(ctx, dir, "rev-list", "--count", base+".."+branch)
+	if err != nil {
+		return err
+	}
+	var commits int
+	fmt.Sscan(out, &commits)
+	if commits > 0 {
+		return &UnmergedError{Branch: branch, Base: base, Commits: commits}
+	}
+
+	if ref, ok := findStash(ctx, dir, branch); ok {
+		if _, err := git(ctx, dir, "stash", "drop", "--quiet", ref); err != nil {
+			return err
+		}
+	}
+	// "git branch -d" checks against HEAD instead of the base, the branch is
+	// known to be merged at this point
+	_, err = git(ctx, dir, "branch", "--delete", "--force", branch)
+	return err
+}
+
+// stash saves the uncommitted changes of a session branch, if any
+func stash(ctx context.Context, dir, branch string) error {
+	out, err := git(ctx, dir, "status", "--porcelain")
+	if err != nil || strings.TrimSpace(out) == "" {
+		return err
+	}
+	_, err = git(ctx, dir, "stash", "push", "--quiet", "--include-untracked", "-m", stashMessage(branch))
+	return err
+}
+
+// unstash restores the changes stashed for a session branch
+func unstash(ctx context.Context, dir, branch string) error {
+	ref, ok := findStash(ctx, dir, branch)
+	if !ok {
+		return nil
+	}
+	if _, err := git(ctx, dir, "stash", "pop", "--quiet", ref); err != nil {
+		return fmt.Errorf("restoring the uncommitted changes of %s failed, they are kept in %s: %w", branch, ref, err)
+	}
+	return nil
+}
+
+func findStash(ctx context.Context, dir, branch string) (string, bool) {
+	out, err := git(ctx, dir, "stash", "list", "--format=%gd%x09%gs")
+	if err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(out, "\n") {
+		ref, subject, ok := strings.Cut(line, "\t")
+		// Subjects look like "On <branch>: <message>"
+		if ok && strings.HasSuffix(subject, ": "+stashMessage(branch)) {
+			return ref, true
+		}
+	}
+	return "", false
+}
+
+func stashMessage(branch string) string {
+	return "opencode " + branch
+}
+
+func exists(ctx context.Context, dir, branch string) bool {
+	_, err := git(ctx, dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
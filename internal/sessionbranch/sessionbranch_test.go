@@ -0,0 +1,173 @@
+package sessionbranch
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const prefix = "opencode/session-"
+
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "test"},
+	} {
+		_, err := git(ctx, dir, args...)
+		require.NoError(t, err)
+	}
+	writeFile(t, dir, "README.md", "readme\n")
+	_, err := git(ctx, dir, "add", "-A")
+	require.NoError(t, err)
+	_, err = git(ctx, dir, "commit", "-q", "-m", "init")
+	require.NoError(t, err)
+	return dir
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+}
+
+func TestEnterStashesChangesPerSession(t *testing.T) {
+	ctx := context.Background()
+	dir := newRepo(t)
+
+	branch, err := Enter(ctx, dir, prefix, "one")
+	require.NoError(t, err)
+	assert.Equal(t, "opencode/session-one", branch)
+	assert.Equal(t, "main", Base(ctx, dir, branch))
+	writeFile(t, dir, "one.txt", "work of one\n")
+
+	// A second session starts from main without the changes of the first
+	_, err = Enter(ctx, dir, prefix, "two")
+	require.NoError(t, err)
+	assert.Equal(t, "main", Base(ctx, dir, "opencode/session-two"))
+	assert.NoFileExists(t, filepath.Join(dir, "one.txt"))
+
+	_, err = Enter(ctx, dir, prefix, "one")
+	require.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(dir, "one.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "work of one\n", string(data))
+	_, ok := findStash(ctx, dir, "opencode/session-one")
+	assert.False(t, ok, "the stash is popped")
+}
+
+func TestCheckpointAndCompare(t *testing.T) {
+	ctx := context.Background()
+	dir := newRepo(t)
+
+	_, err := Checkpoint(ctx, dir, prefix, "on main")
+	assert.Error(t, err, "main is not a session branch")
+
+	branch, err := Enter(ctx, dir, prefix, "one")
+	require.NoError(t, err)
+	_, err = Checkpoint(ctx, dir, prefix, "nothing")
+	assert.Error(t, err)
+
+	writeFile(t, dir, "feature.go", "package feature\n")
+	hash, err := Checkpoint(ctx, dir, prefix, "Add feature")
+	require.NoError(t, err)
+	assert.NotEmpty(t, hash)
+
+	writeFile(t, dir, "README.md", "readme\nmore\n")
+	summary, err := Compare(ctx, dir, branch)
+	require.NoError(t, err)
+	assert.Contains(t, summary, "opencode/session-one compared to main")
+	assert.Contains(t, summary, hash+" Add feature")
+	assert.Contains(t, summary, "feature.go")
+	assert.Contains(t, summary, "README.md", "uncommitted changes are included")
+}
+
+func TestUncommittedAndPush(t *testing.T) {
+	ctx := context.Background()
+	dir := newRepo(t)
+	remote := t.TempDir()
+	_, err := git(ctx, remote, "init", "-q", "--bare")
+	require.NoError(t, err)
+	_, err = git(ctx, dir, "remote", "add", "origin", remote)
+	require.NoError(t, err)
+
+	branch, err := Enter(ctx, dir, prefix, "one")
+	require.NoError(t, err)
+	writeFile(t, dir, "feature.go", "package feature\n")
+	uncommitted, err := Uncommitted(ctx, dir)
+	require.NoError(t, err)
+	assert.True(t, uncommitted, "untracked files are uncommitted")
+
+	_, err = Checkpoint(ctx, dir, prefix, "Add feature")
+	require.NoError(t, err)
+	uncommitted, err = Uncommitted(ctx, dir)
+	require.NoError(t, err)
+	assert.False(t, uncommitted)
+
+	require.NoError(t, Push(ctx, dir, branch))
+	_, err = git(ctx, remote, "rev-parse", "--verify", "refs/heads/"+branch)
+	assert.NoError(t, err, "the branch is on the remote")
+	upstream, err := git(ctx, dir, "rev-parse", "--abbrev-ref", branch+"@{upstream}")
+	require.NoError(t, err)
+	assert.Equal(t, "origin/"+branch+"\n", upstream)
+
+	assert.Empty(t, RemoteDefault(ctx, dir), "origin/HEAD isn't set")
+	_, err = git(ctx, dir, "symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/"+branch)
+	require.NoError(t, err)
+	assert.Equal(t, branch, RemoteDefault(ctx, dir))
+}
+
+func TestListAndDelete(t *testing.T) {
+	ctx := context.Background()
+	dir := newRepo(t)
+
+	_, err := Enter(ctx, dir, prefix, "one")
+	require.NoError(t, err)
+	writeFile(t, dir, "one.txt", "stashed\n")
+	_, err = Enter(ctx, dir, prefix, "two")
+	require.NoError(t, err)
+
+	branches, err := List(ctx, dir, prefix)
+	require.NoError(t, err)
+	require.Len(t, branches, 2)
+	assert.Equal(t, "one", branches[0].SessionID)
+	assert.False(t, branches[0].Updated.IsZero())
+
+	assert.Error(t, Delete(ctx, dir, "opencode/session-two"), "checked out")
+	require.NoError(t, Delete(ctx, dir, "opencode/session-one"))
+	_, ok := findStash(ctx, dir, "opencode/session-one")
+	assert.False(t, ok, "the stash of the branch is dropped")
+
+	branches, err = List(ctx, dir, prefix)
+	require.NoError(t, err)
+	require.Len(t, branches, 1)
+	assert.Equal(t, "two", branches[0].SessionID)
+
+	// Commits that aren't in the base branch keep the branch
+	writeFile(t, dir, "two.txt", "work\n")
+	_, err = Checkpoint(ctx, dir, prefix, "work")
+	require.NoError(t, err)
+	_, err = Enter(ctx, dir, prefix, "three")
+	require.NoError(t, err)
+	var unmerged *UnmergedError
+	require.ErrorAs(t, Delete(ctx, dir, "opencode/session-two"), &unmerged)
+	assert.Equal(t, UnmergedError{Branch: "opencode/session-two", Base: "main", Commits: 1}, *unmerged)
+	assert.True(t, exists(ctx, dir, "opencode/session-two"))
+
+	_, err = git(ctx, dir, "checkout", "-q", "main")
+	require.NoError(t, err)
+	_, err = git(ctx, dir, "merge", "-q", "opencode/session-two")
+	require.NoError(t, err)
+	require.NoError(t, Delete(ctx, dir, "opencode/session-two"), "merged into its base")
+	assert.False(t, exists(ctx, dir, "opencode/session-two"))
+}
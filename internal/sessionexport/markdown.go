@@ -0,0 +1,133 @@
+package sessionexport
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/locale"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+// Markdown renders the sessions of an archive to read or share them. Task
+// sessions follow the exported session, each under its own heading.
+func (a Archive) Markdown() (string, error) {
+	var sb strings.Builder
+	for i, sess := range a.Sessions {
+		if i > 0 {
+			sb.WriteString("---\n\n")
+		}
+		if err := writeSession(&sb, sess, i > 0); err != nil {
+			return "", err
+		}
+	}
+	return sb.String(), nil
+}
+
+func writeSession(sb *strings.Builder, sess Session, task bool) error {
+	if task {
+		fmt.Fprintf(sb, "# Task: %s\n\n", sess.Title)
+	} else {
+		fmt.Fprintf(sb, "# %s\n\n", sess.Title)
+	}
+	sb.WriteString("| | |\n|---|---|\n")
+	fmt.Fprintf(sb, "| Session | `%s` |\n", sess.ID)
+	if task {
+		fmt.Fprintf(sb, "| Started by | `%s` |\n", sess.ParentSessionID)
+	}
+	fmt.Fprintf(sb, "| Created | %s |\n", locale.DateTime(time.Unix(sess.CreatedAt, 0)))
+	fmt.Fprintf(sb, "| Messages | %d |\n", len(sess.Messages))
+	fmt.Fprintf(sb, "| Tokens | %s in, %s out |\n", locale.Int(sess.PromptTokens), locale.Int(sess.CompletionTokens))
+	fmt.Fprintf(sb, "| Cost | $%.4f |\n", sess.Cost)
+	if sess.Language != "" {
+		fmt.Fprintf(sb, "| Language | %s |\n", sess.Language)
+	}
+	sb.WriteString("\n")
+	if sess.SystemPrompt != "" {
+		fmt.Fprintf(sb, "**Session instructions**\n\n%s\n", fence(sess.SystemPrompt, ""))
+	}
+
+	for _, msg := range sess.Messages {
+		parts, err := message.UnmarshalParts(msg.Parts)
+		if err != nil {
+			return fmt.Errorf("message %s of session %s: %w", msg.ID, sess.ID, err)
+		}
+		writeMessage(sb, msg, parts, msg.ID == sess.SummaryMessageID)
+	}
+	return nil
+}
+
+func writeMessage(sb *strings.Builder, msg Message, parts []message.ContentPart, summary bool) {
+	heading := []string{roleName(message.MessageRole(msg.Role))}
+	if msg.Model != "" && message.MessageRole(msg.Role) == message.Assistant {
+		heading = append(heading, msg.Model)
+	}
+	heading = append(heading, locale.DateTime(time.Unix(msg.CreatedAt, 0)))
+	fmt.Fprintf(sb, "## %s\n\n", strings.Join(heading, " · "))
+	if summary {
+		sb.WriteString("_Summary of the conversation before it was compacted_\n\n")
+	}
+
+	for _, part := range parts {
+		switch part := part.(type) {
+		case message.ReasoningContent:
+			if strings.TrimSpace(part.Thinking) != "" {
+				fmt.Fprintf(sb, "<details>\n<summary>Reasoning</summary>\n\n%s\n\n</details>\n\n", strings.TrimSpace(part.Thinking))
+			}
+		case message.TextContent:
+			if strings.TrimSpace(part.Text) != "" {
+				fmt.Fprintf(sb, "%s\n\n", strings.TrimSpace(part.Text))
+			}
+		case message.ImageURLContent:
+			fmt.Fprintf(sb, "![image](%s)\n\n", part.URL)
+		case message.BinaryContent:
+			fmt.Fprintf(sb, "_Attachment %s (%s, %d bytes)_\n\n", part.Path, part.MIMEType, len(part.Data))
+		case message.ToolCall:
+			fmt.Fprintf(sb, "**Tool call** `%s`\n\n%s\n", part.Name, fence(part.Input, "json"))
+		case message.ToolResult:
+			label := fmt.Sprintf("**Result of** `%s`", part.Name)
+			if part.IsError {
+				label += " (error)"
+			}
+			fmt.Fprintf(sb, "%s\n\n%s\n", label, fence(part.Content, ""))
+		case message.Usage:
+			fmt.Fprintf(sb, "_%s in, %s out tokens, $%.4f_\n\n", locale.Int(part.InputTokens), locale.Int(part.OutputTokens), part.Cost)
+		case message.Finish:
+			switch part.Reason {
+			case message.FinishReasonEndTurn, message.FinishReasonToolUse, "stop", "":
+			default:
+				fmt.Fprintf(sb, "_Stopped: %s_\n\n", strings.ReplaceAll(string(part.Reason), "_", " "))
+			}
+		}
+	}
+}
+
+func roleName(role message.MessageRole) string {
+	switch role {
+	case message.User:
+		return "User"
+	case message.Assistant:
+		return "Assistant"
+	case message.Tool:
+		return "Tool results"
+	case message.System:
+		return "System"
+	}
+	return string(role)
+}
+
+// fence puts text in a code block, with a fence longer than the runs of
+// backticks in the text
+func fence(text, lang string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	marker := strings.Repeat("`", max(3, longest+1))
+	return fmt.Sprintf("%s%s\n%s\n%s\n", marker, lang, strings.TrimRight(text, "\n"), marker)
+}
@@ -0,0 +1,239 @@
+// Package sessionexport writes a session, with its messages and the sessions
+// of its tasks, to a portable archive, and loads archives back into the
+// database. Archives are JSON; sessions can also be rendered as markdown to
+// read or share them.
+package sessionexport
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/message"
+)
+
+// Version is the version of the archive format
+const Version = 1
+
+// Archive is an exported session. The exported session comes first, followed
+// by the sessions of its tasks, parents before their children.
+type Archive struct {
+	Version  int       `json:"version"`
+	Exported int64     `json:"exported"`
+	Sessions []Session `json:"sessions"`
+}
+
+// Session is a session of an archive
+type Session struct {
+	ID               string    `json:"id"`
+	ParentSessionID  string    `json:"parent_session_id,omitempty"`
+	Title            string    `json:"title"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	Cost             float64   `json:"cost"`
+	SummaryMessageID string    `json:"summary_message_id,omitempty"`
+	SystemPrompt     string    `json:"system_prompt,omitempty"`
+	Language         string    `json:"language,omitempty"`
+	Ephemeral        bool      `json:"ephemeral,omitempty"`
+	CreatedAt        int64     `json:"created_at"`
+	UpdatedAt        int64     `json:"updated_at"`
+	Messages         []Message `json:"messages"`
+}
+
+// Message is a message of an archived session, its parts are stored as they
+// are in the database
+type Message struct {
+	ID         string          `json:"id"`
+	Role       string          `json:"role"`
+	Model      string          `json:"model,omitempty"`
+	Parts      json.RawMessage `json:"parts"`
+	CreatedAt  int64           `json:"created_at"`
+	UpdatedAt  int64           `json:"updated_at"`
+	FinishedAt int64           `json:"finished_at,omitempty"`
+}
+
+// Export reads a session and the sessions of its tasks, recursively. The
+// sessions made to generate titles are left out.
+func Export(ctx context.Context, q db.Querier, sessionID string) (Archive, error) {
+	root, err := q.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return Archive{}, fmt.Errorf("unknown session %s", sessionID)
+	}
+	all, err := q.ListAllSessions(ctx)
+	if err != nil {
+		return Archive{}, err
+	}
+	children := make(map[string][]db.Session)
+	for _, sess := range all {
+		if sess.ParentSessionID.Valid && !strings.HasPrefix(sess.ID, "title-") {
+			children[sess.ParentSessionID.String] = append(children[sess.ParentSessionID.String], sess)
+		}
+	}
+
+	archive := Archive{Version: Version, Exported: time.Now().Unix()}
+	queue := []db.Session{root}
+	for len(queue) > 0 {
+		sess := queue[0]
+		queue = queue[1:]
+		exported, err := exportSession(ctx, q, sess)
+		if err != nil {
+			return Archive{}, err
+		}
+		archive.Sessions = append(archive.Sessions, exported)
+		tasks := children[sess.ID]
+		slices.SortFunc(tasks, func(a, b db.Session) int {
+			return cmp.Compare(a.CreatedAt, b.CreatedAt)
+		})
+		queue = append(queue, tasks...)
+	}
+	// The exported session is a regular session where it's imported, even
+	// when it ran a task
+	archive.Sessions[0].ParentSessionID = ""
+	archive.Sessions[0].Ephemeral = false
+	return archive, nil
+}
+
+func exportSession(ctx context.Context, q db.Querier, sess db.Session) (Session, error) {
+	msgs, err := q.ListMessagesBySession(ctx, sess.ID)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to list the messages of %s: %w", sess.ID, err)
+	}
+	exported := Session{
+		ID:               sess.ID,
+		ParentSessionID:  sess.ParentSessionID.String,
+		Title:            sess.Title,
+		PromptTokens:     sess.PromptTokens,
+		CompletionTokens: sess.CompletionTokens,
+		Cost:             sess.Cost,
+		SummaryMessageID: sess.SummaryMessageID.String,
+		SystemPrompt:     sess.SystemPrompt.String,
+		Language:         sess.Language,
+		Ephemeral:        sess.Ephemeral != 0,
+		CreatedAt:        sess.CreatedAt,
+		UpdatedAt:        sess.UpdatedAt,
+		Messages:         make([]Message, len(msgs)),
+	}
+	for i, msg := range msgs {
+		exported.Messages[i] = Message{
+			ID:         msg.ID,
+			Role:       msg.Role,
+			Model:      msg.Model.String,
+			Parts:      json.RawMessage(msg.Parts),
+			CreatedAt:  msg.CreatedAt,
+			UpdatedAt:  msg.UpdatedAt,
+			FinishedAt: msg.FinishedAt.Int64,
+		}
+	}
+	return exported, nil
+}
+
+// Read decodes an archive and checks it can be imported
+func Read(data []byte) (Archive, error) {
+	var archive Archive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return Archive{}, fmt.Errorf("not a session archive: %w", err)
+	}
+	if archive.Version < 1 || archive.Version > Version {
+		return Archive{}, fmt.Errorf("unsupported archive version %d, this version of OpenCode reads version %d", archive.Version, Version)
+	}
+	if len(archive.Sessions) == 0 {
+		return Archive{}, errors.New("the archive has no session")
+	}
+	ids := make(map[string]bool, len(archive.Sessions))
+	for _, sess := range archive.Sessions {
+		if sess.ID == "" || ids[sess.ID] {
+			return Archive{}, fmt.Errorf("the archive has a missing or duplicate session ID %q", sess.ID)
+		}
+		if sess.ParentSessionID != "" && !ids[sess.ParentSessionID] {
+			return Archive{}, fmt.Errorf("session %s comes before its parent %s", sess.ID, sess.ParentSessionID)
+		}
+		ids[sess.ID] = true
+		for _, msg := range sess.Messages {
+			if _, err := message.UnmarshalParts(msg.Parts); err != nil {
+				return Archive{}, fmt.Errorf("message %s of session %s: %w", msg.ID, sess.ID, err)
+			}
+		}
+	}
+	return archive, nil
+}
+
+// Import saves the sessions of an archive with their IDs, so the task
+// sessions stay linked to the tool calls that ran them. The messages keep
+// their times, the sessions are created at the import so they're listed
+// first. Nothing is saved when one of the sessions exists.
+func Import(ctx context.Context, conn *sql.DB, archive Archive) error {
+	q := db.New(conn)
+	for _, sess := range archive.Sessions {
+		if _, err := q.GetSessionByID(ctx, sess.ID); err == nil {
+			return fmt.Errorf("session %s (%s) exists already, delete it to import it again", sess.ID, sess.Title)
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := q.WithTx(tx)
+	for _, sess := range archive.Sessions {
+		if err := importSession(ctx, qtx, sess); err != nil {
+			return fmt.Errorf("failed to import session %s: %w", sess.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func importSession(ctx context.Context, q *db.Queries, sess Session) error {
+	ephemeral := int64(0)
+	if sess.Ephemeral {
+		ephemeral = 1
+	}
+	if _, err := q.CreateSession(ctx, db.CreateSessionParams{
+		ID:               sess.ID,
+		ParentSessionID:  sql.NullString{String: sess.ParentSessionID, Valid: sess.ParentSessionID != ""},
+		Title:            sess.Title,
+		PromptTokens:     sess.PromptTokens,
+		CompletionTokens: sess.CompletionTokens,
+		Cost:             sess.Cost,
+		Ephemeral:        ephemeral,
+	}); err != nil {
+		return err
+	}
+	for _, msg := range sess.Messages {
+		if err := q.ImportMessage(ctx, db.ImportMessageParams{
+			ID:         msg.ID,
+			SessionID:  sess.ID,
+			Role:       msg.Role,
+			Parts:      string(msg.Parts),
+			Model:      sql.NullString{String: msg.Model, Valid: msg.Model != ""},
+			CreatedAt:  msg.CreatedAt,
+			UpdatedAt:  msg.UpdatedAt,
+			FinishedAt: sql.NullInt64{Int64: msg.FinishedAt, Valid: msg.FinishedAt != 0},
+		}); err != nil {
+			return fmt.Errorf("message %s: %w", msg.ID, err)
+		}
+	}
+	if sess.SummaryMessageID == "" && sess.SystemPrompt == "" && sess.Language == "" {
+		return nil
+	}
+	_, err := q.UpdateSession(ctx, db.UpdateSessionParams{
+		ID:               sess.ID,
+		Title:            sess.Title,
+		PromptTokens:     sess.PromptTokens,
+		CompletionTokens: sess.CompletionTokens,
+		SummaryMessageID: sql.NullString{String: sess.SummaryMessageID, Valid: sess.SummaryMessageID != ""},
+		Cost:             sess.Cost,
+		SystemPrompt:     sql.NullString{String: sess.SystemPrompt, Valid: sess.SystemPrompt != ""},
+		Language:         sess.Language,
+	})
+	return err
+}
@@ -0,0 +1,123 @@
+package sessionexport
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createSession(t *testing.T, q *db.Queries, id, parentID string, msgs ...[]message.ContentPart) {
+	t.Helper()
+	ctx := context.Background()
+	ephemeral := int64(0)
+	if parentID != "" {
+		ephemeral = 1
+	}
+	_, err := q.CreateSession(ctx, db.CreateSessionParams{
+		ID:              id,
+		ParentSessionID: sql.NullString{String: parentID, Valid: parentID != ""},
+		Title:           "Session " + id,
+		PromptTokens:    100,
+		Cost:            0.25,
+		Ephemeral:       ephemeral,
+	})
+	require.NoError(t, err)
+	for i, parts := range msgs {
+		data, err := message.MarshalParts(parts)
+		require.NoError(t, err)
+		role := message.User
+		if i%2 == 1 {
+			role = message.Assistant
+		}
+		require.NoError(t, q.ImportMessage(ctx, db.ImportMessageParams{
+			ID:        id + "-" + string(rune('a'+i)),
+			SessionID: id,
+			Role:      string(role),
+			Parts:     string(data),
+			Model:     sql.NullString{String: "claude-4-sonnet", Valid: role == message.Assistant},
+			CreatedAt: int64(1000 + i),
+			UpdatedAt: int64(1000 + i),
+		}))
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	conn, err := db.ConnectEphemeral()
+	require.NoError(t, err)
+	defer conn.Close()
+	q := db.New(conn)
+
+	createSession(t, q, "root", "",
+		[]message.ContentPart{message.TextContent{Text: "Fix the build"}},
+		[]message.ContentPart{
+			message.TextContent{Text: "Running a task"},
+			message.ToolCall{ID: "task", Name: "agent", Input: `{"prompt":"find it"}`, Finished: true},
+			message.Usage{InputTokens: 1200, OutputTokens: 80, Cost: 0.01},
+			message.Finish{Reason: message.FinishReasonToolUse},
+		},
+	)
+	createSession(t, q, "task", "root",
+		[]message.ContentPart{message.TextContent{Text: "find it"}},
+		[]message.ContentPart{message.ToolResult{ToolCallID: "grep", Name: "grep", Content: "Found in ```main.go```"}},
+	)
+	createSession(t, q, "title-root", "root", []message.ContentPart{message.TextContent{Text: "Generate a title"}})
+	createSession(t, q, "other", "")
+
+	archive, err := Export(ctx, q, "root")
+	require.NoError(t, err)
+	require.Len(t, archive.Sessions, 2, "the title session is left out")
+	assert.Equal(t, "root", archive.Sessions[0].ID)
+	assert.Equal(t, "task", archive.Sessions[1].ID)
+	assert.Equal(t, "root", archive.Sessions[1].ParentSessionID)
+	assert.Len(t, archive.Sessions[0].Messages, 2)
+	assert.Equal(t, 0.25, archive.Sessions[0].Cost)
+
+	markdown, err := archive.Markdown()
+	require.NoError(t, err)
+	assert.Contains(t, markdown, "# Session root\n")
+	assert.Contains(t, markdown, "**Tool call** `agent`\n\n```json\n{\"prompt\":\"find it\"}\n```\n")
+	assert.Contains(t, markdown, "# Task: Session task\n")
+	assert.Contains(t, markdown, "**Result of** `grep`\n\n````\nFound in ```main.go```\n````\n", "the fence is longer than the backticks of the text")
+
+	_, err = Export(ctx, q, "missing")
+	assert.Error(t, err)
+
+	data, err := json.Marshal(archive)
+	require.NoError(t, err)
+	read, err := Read(data)
+	require.NoError(t, err)
+	assert.Error(t, Import(ctx, conn, read), "the sessions exist")
+
+	for _, id := range []string{"title-root", "task", "root"} {
+		require.NoError(t, q.DeleteSessionMessages(ctx, id))
+		require.NoError(t, q.DeleteSession(ctx, id))
+	}
+	require.NoError(t, Import(ctx, conn, read))
+	imported, err := Export(ctx, q, "root")
+	require.NoError(t, err)
+	imported.Exported = archive.Exported
+	for i := range imported.Sessions {
+		// Imported sessions are created at the import
+		imported.Sessions[i].CreatedAt = archive.Sessions[i].CreatedAt
+		imported.Sessions[i].UpdatedAt = archive.Sessions[i].UpdatedAt
+	}
+	assert.Equal(t, archive, imported)
+}
+
+func TestRead(t *testing.T) {
+	_, err := Read([]byte(`{"version":2,"sessions":[{"id":"a"}]}`))
+	assert.ErrorContains(t, err, "unsupported archive version")
+	_, err = Read([]byte(`{"version":1,"sessions":[]}`))
+	assert.ErrorContains(t, err, "no session")
+	_, err = Read([]byte(`{"version":1,"sessions":[{"id":"b","parent_session_id":"a"},{"id":"a"}]}`))
+	assert.ErrorContains(t, err, "before its parent")
+	_, err = Read([]byte(`{"version":1,"sessions":[{"id":"a","messages":[{"id":"m","parts":{}}]}]}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,380 @@
+// Package sessionlock makes sure a single OpenCode process drives a session
+// at a time, e.g. not both the TUI and the daemon, so two writers never
+// interleave their messages in it.
+//
+// A process holds a session with a file in the locks directory, named after
+// the session and refreshed while the process runs. Another process may
+// take the session over, the previous holder then stops driving it.
+package sessionlock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kirmad/superopencode/internal/pubsub"
+)
+
+const (
+	// HeartbeatInterval is how often the locks of a process are refreshed
+	HeartbeatInterval = 5 * time.Second
+
+	// staleAfter is how long the lock of a process that stopped refreshing
+	// it, e.g. because it crashed, is kept
+	staleAfter = 3 * HeartbeatInterval
+)
+
+// Owner is the process holding a session
+type Owner struct {
+	ID         string    `json:"id"` // Instance of OpenCode
+	PID        int       `json:"pid"`
+	Host       string    `json:"host"`
+	Command    string    `json:"command"` // e.g. "opencode daemon"
+	AcquiredAt time.Time `json:"acquired_at"`
+}
+
+// String describes the owner for the user, e.g. "opencode daemon (pid 42)"
+func (o Owner) String() string {
+	command := o.Command
+	if command == "" {
+		command = "another process"
+	}
+	host, _ := os.Hostname()
+	if o.Host != "" && o.Host != host {
+		return fmt.Sprintf("%s (pid %d on %s)", command, o.PID, o.Host)
+	}
+	return fmt.Sprintf("%s (pid %d)", command, o.PID)
+}
+
+// HeldError is returned when another process drives a session
+type HeldError struct {
+	SessionID string
+	Owner     Owner
+}
+
+func (e *HeldError) Error() string {
+	return fmt.Sprintf("the session is driven by %s, take it over with /takeover", e.Owner)
+}
+
+// Event reports a change of the holder of a session
+type Event struct {
+	SessionID string
+	// Holder is the other process driving the session, nil when the session
+	// is free or held by this process
+	Holder *Owner
+	// Lost is set when another process took the session over from this one
+	Lost bool
+}
+
+// Manager holds the sessions of a process
+type Manager struct {
+	*pubsub.Broker[Event]
+	dir  string
+	self Owner
+
+	mu      sync.Mutex
+	held    map[string]bool
+	watched string // Session whose holder changes are published
+	holder  *Owner // Last known holder of the watched session
+}
+
+// NewManager returns the manager of the locks of this process in dir
+func NewManager(dir string) *Manager {
+	host, _ := os.Hostname()
+	return &Manager{
+		Broker: pubsub.NewBroker[Event](),
+		dir:    dir,
+		self: Owner{
+			ID:      uuid.New().String(),
+			PID:     os.Getpid(),
+			Host:    host,
+			Command: command(os.Args),
+		},
+		held: make(map[string]bool),
+	}
+}
+
+// valueFlags are the flags of the root command followed by a value
+var valueFlags = map[string]bool{
+	"-c": true, "--cwd": true, "-f": true, "--output-format": true,
+	"-P": true, "--post": true, "--stdin-limit": true,
+}
+
+// command describes how OpenCode was started: its subcommand, or -p for a
+// non-interactive prompt
+func command(args []string) string {
+	name := "opencode"
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-p" || arg == "--prompt" || strings.HasPrefix(arg, "--prompt="):
+			return name + " -p"
+		case valueFlags[arg]:
+			i++
+		case !strings.HasPrefix(arg, "-"):
+			return name + " " + arg
+		}
+	}
+	return name
+}
+
+// Self returns the owner the locks of this process are written with
+func (m *Manager) Self() Owner {
+	return m.self
+}
+
+func (m *Manager) path(sessionID string) string {
+	return filepath.Join(m.dir, sessionID+".json")
+}
+
+// read returns the owner of the lock of a session and whether it's still
+// refreshed. A lock that is being written has no owner yet but is live.
+func (m *Manager) read(sessionID string) (Owner, bool, error) {
+	path := m.path(sessionID)
+	info, err := os.Stat(path)
+	if err != nil {
+		return Owner{}, false, err
+	}
+	live := time.Since(info.ModTime()) < staleAfter
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Owner{}, false, err
+	}
+	var owner Owner
+	_ = json.Unmarshal(data, &owner)
+	return owner, live, nil
+}
+
+// Holder returns the other process driving a session, if any
+func (m *Manager) Holder(sessionID string) (Owner, bool) {
+	owner, live, err := m.read(sessionID)
+	if err != nil || !live || owner.ID == m.self.ID {
+		return Owner{}, false
+	}
+	return owner, true
+}
+
+// Holds reports whether this process holds a session
+func (m *Manager) Holds(sessionID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.held[sessionID]
+}
+
+// Held returns the sessions this process holds
+func (m *Manager) Held() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Collect(maps.Keys(m.held))
+}
+
+// Acquire holds a session for this process until it's released. It fails
+// with a HeldError when another process holds it.
+func (m *Manager) Acquire(sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create the locks directory: %w", err)
+	}
+	// A stale lock is removed and the creation tried again
+	for range 2 {
+		owner := m.self
+		owner.AcquiredAt = time.Now()
+		err := m.create(sessionID, owner)
+		if err == nil {
+			m.held[sessionID] = true
+			return nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+
+		current, live, err := m.read(sessionID)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if current.ID == m.self.ID {
+			m.held[sessionID] = true
+			return m.touch(sessionID)
+		}
+		if live {
+			return &HeldError{SessionID: sessionID, Owner: current}
+		}
+		if err := os.Remove(m.path(sessionID)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to lock session %s", sessionID)
+}
+
+// create writes the lock of a session unless it exists
+func (m *Manager) create(sessionID string, owner Owner) error {
+	f, err := os.OpenFile(m.path(sessionID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(f).Encode(owner)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Takeover holds a session for this process whether or not another process
+// holds it, and returns the previous holder. That process notices it lost the
+// session at its next heartbeat and stops driving it.
+func (m *Manager) Takeover(sessionID string) (Owner, bool, error) {
+	previous, held := m.Holder(sessionID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return Owner{}, false, fmt.Errorf("failed to create the locks directory: %w", err)
+	}
+	owner := m.self
+	owner.AcquiredAt = time.Now()
+	data, err := json.Marshal(owner)
+	if err != nil {
+		return Owner{}, false, err
+	}
+	// Replaced in one step, the previous holder never reads a partial lock
+	tmp, err := os.CreateTemp(m.dir, sessionID+".*.tmp")
+	if err != nil {
+		return Owner{}, false, err
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), m.path(sessionID))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return Owner{}, false, fmt.Errorf("failed to take the session over: %w", err)
+	}
+	m.held[sessionID] = true
+	if m.watched == sessionID {
+		m.holder = nil
+	}
+	return previous, held, nil
+}
+
+// Release stops holding a session
+func (m *Manager) Release(sessionID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.release(sessionID)
+}
+
+func (m *Manager) release(sessionID string) {
+	if !m.held[sessionID] {
+		return
+	}
+	delete(m.held, sessionID)
+	// The lock may have been taken over meanwhile
+	if owner, _, err := m.read(sessionID); err == nil && owner.ID == m.self.ID {
+		os.Remove(m.path(sessionID))
+	}
+}
+
+// ReleaseAll stops holding the sessions of this process, when it exits
+func (m *Manager) ReleaseAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for sessionID := range m.held {
+		m.release(sessionID)
+	}
+}
+
+// Watch publishes the changes of the holder of a session, the one the user
+// works in, from now on. Its current holder is published right away.
+func (m *Manager) Watch(sessionID string) {
+	m.mu.Lock()
+	m.watched = sessionID
+	m.holder = nil
+	m.mu.Unlock()
+	m.checkWatched(true)
+}
+
+// touch refreshes the lock of a session held by this process
+func (m *Manager) touch(sessionID string) error {
+	now := time.Now()
+	return os.Chtimes(m.path(sessionID), now, now)
+}
+
+// Refresh refreshes the locks of this process and returns the sessions other
+// processes took over since the last refresh. It publishes the sessions lost
+// and the changes of the holder of the watched session.
+func (m *Manager) Refresh() []string {
+	m.mu.Lock()
+	var lost []string
+	var events []Event
+	for sessionID := range m.held {
+		owner, live, err := m.read(sessionID)
+		switch {
+		case err == nil && owner.ID == m.self.ID:
+			if err := m.touch(sessionID); err == nil {
+				continue
+			}
+		case errors.Is(err, fs.ErrNotExist):
+			// Removed by hand or by a cleanup, written again
+			if m.create(sessionID, m.self) == nil {
+				continue
+			}
+		}
+		if err != nil || owner.ID == m.self.ID {
+			continue // Tried again at the next refresh
+		}
+		delete(m.held, sessionID)
+		lost = append(lost, sessionID)
+		event := Event{SessionID: sessionID, Lost: true}
+		if live {
+			event.Holder = &owner
+		}
+		if sessionID == m.watched {
+			m.holder = event.Holder
+		}
+		events = append(events, event)
+	}
+	m.mu.Unlock()
+
+	for _, event := range events {
+		m.Publish(pubsub.UpdatedEvent, event)
+	}
+	m.checkWatched(false)
+	return lost
+}
+
+// checkWatched publishes the holder of the watched session when it changed
+func (m *Manager) checkWatched(always bool) {
+	m.mu.Lock()
+	sessionID := m.watched
+	if sessionID == "" {
+		m.mu.Unlock()
+		return
+	}
+	var holder *Owner
+	if owner, ok := m.Holder(sessionID); ok {
+		holder = &owner
+	}
+	changed := always || (holder == nil) != (m.holder == nil) || (holder != nil && holder.ID != m.holder.ID)
+	m.holder = holder
+	m.mu.Unlock()
+
+	if changed {
+		m.Publish(pubsub.UpdatedEvent, Event{SessionID: sessionID, Holder: holder})
+	}
+}
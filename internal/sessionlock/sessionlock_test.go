@@ -0,0 +1,96 @@
+package sessionlock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquire(t *testing.T) {
+	dir := t.TempDir()
+	tui, daemon := NewManager(dir), NewManager(dir)
+
+	require.NoError(t, tui.Acquire("s1"))
+	require.NoError(t, tui.Acquire("s1"), "acquiring a held session again succeeds")
+	assert.True(t, tui.Holds("s1"))
+
+	err := daemon.Acquire("s1")
+	var held *HeldError
+	require.ErrorAs(t, err, &held)
+	assert.Equal(t, tui.Self().ID, held.Owner.ID)
+	owner, ok := daemon.Holder("s1")
+	assert.True(t, ok)
+	assert.Equal(t, tui.Self().PID, owner.PID)
+	_, ok = tui.Holder("s1")
+	assert.False(t, ok, "a process isn't the other holder of its own sessions")
+
+	tui.Release("s1")
+	assert.NoError(t, daemon.Acquire("s1"))
+}
+
+func TestAcquireStale(t *testing.T) {
+	dir := t.TempDir()
+	crashed, next := NewManager(dir), NewManager(dir)
+	require.NoError(t, crashed.Acquire("s1"))
+
+	old := time.Now().Add(-2 * staleAfter)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "s1.json"), old, old))
+	assert.NoError(t, next.Acquire("s1"))
+	assert.Equal(t, []string{"s1"}, crashed.Refresh())
+}
+
+func TestTakeover(t *testing.T) {
+	dir := t.TempDir()
+	daemon, tui := NewManager(dir), NewManager(dir)
+	require.NoError(t, daemon.Acquire("s1"))
+
+	events := daemon.Subscribe(context.Background())
+	previous, ok, err := tui.Takeover("s1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, daemon.Self().ID, previous.ID)
+	assert.True(t, tui.Holds("s1"))
+
+	assert.Equal(t, []string{"s1"}, daemon.Refresh())
+	assert.False(t, daemon.Holds("s1"))
+	event := <-events
+	assert.True(t, event.Payload.Lost)
+	require.NotNil(t, event.Payload.Holder)
+	assert.Equal(t, tui.Self().ID, event.Payload.Holder.ID)
+
+	daemon.Release("s1")
+	_, ok = daemon.Holder("s1")
+	assert.True(t, ok, "releasing a lost session leaves the new holder alone")
+	assert.Empty(t, tui.Refresh())
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	tui, daemon := NewManager(dir), NewManager(dir)
+	events := tui.Subscribe(context.Background())
+
+	tui.Watch("s1")
+	assert.Nil(t, (<-events).Payload.Holder)
+
+	require.NoError(t, daemon.Acquire("s1"))
+	tui.Refresh()
+	event := <-events
+	require.NotNil(t, event.Payload.Holder)
+	assert.Equal(t, daemon.Self().ID, event.Payload.Holder.ID)
+
+	daemon.ReleaseAll()
+	tui.Refresh()
+	assert.Nil(t, (<-events).Payload.Holder)
+}
+
+func TestCommand(t *testing.T) {
+	assert.Equal(t, "opencode", command([]string{"/usr/bin/opencode"}))
+	assert.Equal(t, "opencode", command([]string{"opencode", "-d", "--cwd=/tmp", "-c", "/tmp"}))
+	assert.Equal(t, "opencode -p", command([]string{"opencode", "-q", "-p", "hi"}))
+	assert.Equal(t, "opencode daemon", command([]string{"opencode", "daemon"}))
+}
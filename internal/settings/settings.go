@@ -0,0 +1,72 @@
+// Package settings holds the settings that can change while the app runs.
+// The config, command line flags and TUI toggles all write them through the
+// Service, which notifies subscribers of every change.
+package settings
+
+import (
+	"sync"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/pubsub"
+)
+
+// Settings are the runtime settings of the app
+type Settings struct {
+	// SkipPermissions approves all tool permissions of the sessions
+	SkipPermissions bool
+	// DetailedLogs logs the requests and responses of providers, it only
+	// applies at startup
+	DetailedLogs bool
+}
+
+// FromConfig returns the settings the config starts with
+func FromConfig(cfg *config.Config) Settings {
+	if cfg == nil {
+		return Settings{}
+	}
+	return Settings{
+		DetailedLogs: cfg.DetailedLogs,
+	}
+}
+
+type Service interface {
+	pubsub.Suscriber[Settings]
+	// Get returns the current settings
+	Get() Settings
+	// Update changes the settings and publishes them when they changed
+	Update(func(*Settings)) Settings
+}
+
+type service struct {
+	*pubsub.Broker[Settings]
+
+	mu       sync.RWMutex
+	settings Settings
+}
+
+func NewService(initial Settings) Service {
+	return &service{
+		Broker:   pubsub.NewBroker[Settings](),
+		settings: initial,
+	}
+}
+
+func (s *service) Get() Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings
+}
+
+func (s *service) Update(change func(*Settings)) Settings {
+	s.mu.Lock()
+	updated := s.settings
+	change(&updated)
+	changed := updated != s.settings
+	s.settings = updated
+	s.mu.Unlock()
+
+	if changed {
+		s.Publish(pubsub.UpdatedEvent, updated)
+	}
+	return updated
+}
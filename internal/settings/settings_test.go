@@ -0,0 +1,60 @@
+package settings
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromConfig(t *testing.T) {
+	assert.Equal(t, Settings{}, FromConfig(nil))
+	assert.Equal(t, Settings{DetailedLogs: true}, FromConfig(&config.Config{DetailedLogs: true}))
+}
+
+func TestUpdatePublishesChanges(t *testing.T) {
+	s := NewService(Settings{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := s.Subscribe(ctx)
+
+	updated := s.Update(func(s *Settings) { s.SkipPermissions = true })
+	assert.True(t, updated.SkipPermissions)
+	assert.Equal(t, updated, s.Get())
+
+	select {
+	case event := <-events:
+		assert.Equal(t, pubsub.UpdatedEvent, event.Type)
+		assert.True(t, event.Payload.SkipPermissions)
+	case <-time.After(time.Second):
+		t.Fatal("no event published")
+	}
+
+	// Setting the same value again doesn't publish
+	s.Update(func(s *Settings) { s.SkipPermissions = true })
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	s := NewService(Settings{})
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Update(func(s *Settings) { s.SkipPermissions = !s.SkipPermissions })
+			_ = s.Get()
+		}()
+	}
+	wg.Wait()
+	require.False(t, s.Get().SkipPermissions, "an even number of toggles")
+}
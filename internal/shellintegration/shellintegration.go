@@ -0,0 +1,279 @@
+// Package shellintegration installs shell functions that run OpenCode for
+// common workflows, such as triaging a failed command or drafting a commit
+// message.
+package shellintegration
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Supported shells
+const (
+	Bash = "bash"
+	Zsh  = "zsh"
+	Fish = "fish"
+)
+
+// Shells lists the supported shells
+var Shells = []string{Bash, Zsh, Fish}
+
+// Markers of the block added to the startup file of bash and zsh
+const (
+	blockStart = "# >>> opencode shell integration >>>"
+	blockEnd   = "# <<< opencode shell integration <<<"
+)
+
+// Detect returns the shell of the user from $SHELL
+func Detect() (string, error) {
+	shell := filepath.Base(os.Getenv("SHELL"))
+	for _, s := range Shells {
+		if s == shell {
+			return s, nil
+		}
+	}
+	if shell == "." || shell == "" {
+		return "", errors.New("$SHELL is not set, choose a shell with --shell")
+	}
+	return "", fmt.Errorf("unsupported shell %q, choose one of %s with --shell", shell, strings.Join(Shells, ", "))
+}
+
+// Script returns the functions for a shell
+func Script(shell string) (string, error) {
+	switch shell {
+	case Bash:
+		return strings.ReplaceAll(posixScript, "{{shell}}", Bash), nil
+	case Zsh:
+		return strings.ReplaceAll(posixScript, "{{shell}}", Zsh), nil
+	case Fish:
+		return fishScript, nil
+	default:
+		return "", fmt.Errorf("unsupported shell %q, choose one of %s", shell, strings.Join(Shells, ", "))
+	}
+}
+
+// Paths are the files of the integration of a shell
+type Paths struct {
+	Script  string // The functions
+	Startup string // The file sourcing the functions, empty when the shell loads the script itself
+}
+
+// PathsFor returns where the integration of a shell is installed for the
+// user with this home directory
+func PathsFor(shell, home string) (Paths, error) {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		configDir = filepath.Join(home, ".config")
+	}
+	switch shell {
+	case Bash:
+		return Paths{
+			Script:  filepath.Join(configDir, "opencode", "shell", "functions.bash"),
+			Startup: filepath.Join(home, ".bashrc"),
+		}, nil
+	case Zsh:
+		zdotdir := os.Getenv("ZDOTDIR")
+		if zdotdir == "" {
+			zdotdir = home
+		}
+		return Paths{
+			Script:  filepath.Join(configDir, "opencode", "shell", "functions.zsh"),
+			Startup: filepath.Join(zdotdir, ".zshrc"),
+		}, nil
+	case Fish:
+		// Fish loads every file of conf.d when it starts
+		return Paths{Script: filepath.Join(configDir, "fish", "conf.d", "opencode.fish")}, nil
+	default:
+		return Paths{}, fmt.Errorf("unsupported shell %q, choose one of %s", shell, strings.Join(Shells, ", "))
+	}
+}
+
+// Install writes the functions of a shell and sources them from its startup
+// file. Installing again updates the functions.
+func Install(shell, home string) (Paths, error) {
+	paths, err := PathsFor(shell, home)
+	if err != nil {
+		return Paths{}, err
+	}
+	script, err := Script(shell)
+	if err != nil {
+		return Paths{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(paths.Script), 0o755); err != nil {
+		return Paths{}, fmt.Errorf("failed to create %s: %w", filepath.Dir(paths.Script), err)
+	}
+	if err := os.WriteFile(paths.Script, []byte(script), 0o644); err != nil {
+		return Paths{}, fmt.Errorf("failed to write %s: %w", paths.Script, err)
+	}
+	if paths.Startup == "" {
+		return paths, nil
+	}
+
+	content, err := os.ReadFile(paths.Startup)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Paths{}, fmt.Errorf("failed to read %s: %w", paths.Startup, err)
+	}
+	rest := removeBlock(string(content))
+	if rest != "" && !strings.HasSuffix(rest, "\n") {
+		rest += "\n"
+	}
+	block := fmt.Sprintf("%s\n[ -f %s ] && . %s\n%s\n", blockStart, quote(paths.Script), quote(paths.Script), blockEnd)
+	if err := os.WriteFile(paths.Startup, []byte(rest+block), 0o644); err != nil {
+		return Paths{}, fmt.Errorf("failed to write %s: %w", paths.Startup, err)
+	}
+	return paths, nil
+}
+
+// Uninstall removes the functions of a shell and the lines sourcing them,
+// and returns the files it changed
+func Uninstall(shell, home string) ([]string, error) {
+	paths, err := PathsFor(shell, home)
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	if err := os.Remove(paths.Script); err == nil {
+		changed = append(changed, paths.Script)
+		// The directory of the bash and zsh scripts is only ours
+		if paths.Startup != "" {
+			_ = os.Remove(filepath.Dir(paths.Script))
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return changed, fmt.Errorf("failed to remove %s: %w", paths.Script, err)
+	}
+	if paths.Startup == "" {
+		return changed, nil
+	}
+
+	content, err := os.ReadFile(paths.Startup)
+	if errors.Is(err, os.ErrNotExist) {
+		return changed, nil
+	}
+	if err != nil {
+		return changed, fmt.Errorf("failed to read %s: %w", paths.Startup, err)
+	}
+	rest := removeBlock(string(content))
+	if rest == string(content) {
+		return changed, nil
+	}
+	if err := os.WriteFile(paths.Startup, []byte(rest), 0o644); err != nil {
+		return changed, fmt.Errorf("failed to write %s: %w", paths.Startup, err)
+	}
+	return append(changed, paths.Startup), nil
+}
+
+// removeBlock removes the block of the integration from a startup file
+func removeBlock(content string) string {
+	start := strings.Index(content, blockStart)
+	if start < 0 {
+		return content
+	}
+	end := strings.Index(content[start:], blockEnd)
+	if end < 0 {
+		return content
+	}
+	end += start + len(blockEnd)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+	return content[:start] + content[end:]
+}
+
+// quote quotes a path for sh
+func quote(path string) string {
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
+
+const posixScript = `# OpenCode shell functions for {{shell}}, installed by "opencode integrations shell".
+# Remove them with "opencode integrations shell --uninstall".
+
+# ocfix [command...]: run the command, or the previous one again, and let
+# OpenCode triage its output when it fails
+ocfix() {
+  local cmd answer output code
+  if [ $# -gt 0 ]; then
+    cmd="$*"
+  else
+    cmd=$(fc -ln -1 | sed 's/^[[:space:]]*//')
+    if [ -z "$cmd" ]; then
+      echo "ocfix: no previous command" >&2
+      return 1
+    fi
+    printf 'Run "%s" again to capture its output? [y/N] ' "$cmd" >&2
+    read -r answer
+    case "$answer" in
+      y|Y|yes) ;;
+      *) return 1 ;;
+    esac
+  fi
+  output=$(eval "$cmd" 2>&1)
+  code=$?
+  if [ $code -eq 0 ]; then
+    printf '%s\n' "$output"
+    echo "ocfix: the command succeeded, nothing to triage" >&2
+    return 0
+  fi
+  printf '%s\n' "$output" | opencode -q --post= -p "The command \"$cmd\" failed with exit code $code in $(pwd). Its output is attached. Find the cause of the failure and explain how to fix it."
+}
+
+# occommit [git commit flags...]: commit the staged changes with a message
+# drafted by OpenCode, opened in the editor for review
+occommit() {
+  local msg
+  if git diff --cached --quiet; then
+    echo "occommit: nothing staged, stage changes with git add first" >&2
+    return 1
+  fi
+  msg=$(git diff --cached | opencode -q --post= -p "Write a commit message for the staged changes attached below, in the style of the recent commits of this repository: a short subject line, then a blank line and a body explaining what changed and why when it isn't obvious. Answer with the commit message only.") || return
+  if [ -z "$msg" ]; then
+    echo "occommit: no commit message drafted" >&2
+    return 1
+  fi
+  git commit -e -m "$msg" "$@"
+}
+`
+
+const fishScript = `# OpenCode shell functions for fish, installed by "opencode integrations shell".
+# Remove them with "opencode integrations shell --uninstall".
+
+function ocfix --description 'Run a command, or the previous one again, and let OpenCode triage its output when it fails'
+    set -l cmd
+    if test (count $argv) -gt 0
+        set cmd (string join ' ' -- $argv)
+    else
+        set cmd $history[1]
+        if test -z "$cmd"
+            echo "ocfix: no previous command" >&2
+            return 1
+        end
+        read -l -P "Run \"$cmd\" again to capture its output? [y/N] " answer
+        if not contains -- "$answer" y Y yes
+            return 1
+        end
+    end
+    set -l output (eval $cmd 2>&1)
+    set -l code $status
+    if test $code -eq 0
+        printf '%s\n' $output
+        echo "ocfix: the command succeeded, nothing to triage" >&2
+        return 0
+    end
+    printf '%s\n' $output | opencode -q --post= -p "The command \"$cmd\" failed with exit code $code in "(pwd)". Its output is attached. Find the cause of the failure and explain how to fix it."
+end
+
+function occommit --description 'Commit the staged changes with a message drafted by OpenCode'
+    if git diff --cached --quiet
+        echo "occommit: nothing staged, stage changes with git add first" >&2
+        return 1
+    end
+    set -l msg (git diff --cached | opencode -q --post= -p "Write a commit message for the staged changes attached below, in the style of the recent commits of this repository: a short subject line, then a blank line and a body explaining what changed and why when it isn't obvious. Answer with the commit message only." | string collect)
+    if test -z "$msg"
+        echo "occommit: no commit message drafted" >&2
+        return 1
+    end
+    git commit -e -m "$msg" $argv
+end
+`
@@ -0,0 +1,88 @@
+package shellintegration
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallAndUninstall(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("ZDOTDIR", "")
+	bashrc := filepath.Join(home, ".bashrc")
+	require.NoError(t, os.WriteFile(bashrc, []byte("export EDITOR=vim"), 0o644))
+
+	paths, err := Install(Bash, home)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".config", "opencode", "shell", "functions.bash"), paths.Script)
+	assert.FileExists(t, paths.Script)
+	_, err = Install(Bash, home)
+	require.NoError(t, err, "installing again updates the functions")
+	content, err := os.ReadFile(bashrc)
+	require.NoError(t, err)
+	assert.Equal(t, "export EDITOR=vim\n"+blockStart+"\n[ -f '"+paths.Script+"' ] && . '"+paths.Script+"'\n"+blockEnd+"\n", string(content))
+
+	changed, err := Uninstall(Bash, home)
+	require.NoError(t, err)
+	assert.Equal(t, []string{paths.Script, bashrc}, changed)
+	content, err = os.ReadFile(bashrc)
+	require.NoError(t, err)
+	assert.Equal(t, "export EDITOR=vim\n", string(content))
+	assert.NoDirExists(t, filepath.Dir(paths.Script))
+
+	changed, err = Uninstall(Bash, home)
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	paths, err = Install(Fish, home)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".config", "fish", "conf.d", "opencode.fish"), paths.Script)
+	assert.Empty(t, paths.Startup)
+	changed, err = Uninstall(Fish, home)
+	require.NoError(t, err)
+	assert.Equal(t, []string{paths.Script}, changed)
+
+	_, err = Install("tcsh", home)
+	assert.Error(t, err)
+}
+
+func TestScripts(t *testing.T) {
+	for _, shell := range Shells {
+		script, err := Script(shell)
+		require.NoError(t, err)
+		path, err := exec.LookPath(shell)
+		if err != nil {
+			continue
+		}
+		file := filepath.Join(t.TempDir(), "functions."+shell)
+		require.NoError(t, os.WriteFile(file, []byte(script), 0o644))
+		out, err := exec.Command(path, "-n", file).CombinedOutput()
+		assert.NoError(t, err, "%s: %s", shell, out)
+	}
+}
+
+func TestOcfix(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+	// A fake opencode printing its prompt and the piped input
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "opencode"), []byte("#!/bin/sh\nprintf '%s\\n' \"$4\"\ncat\n"), 0o755))
+	script, err := Script(Bash)
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "functions.bash")
+	require.NoError(t, os.WriteFile(file, []byte(script), 0o644))
+
+	cmd := exec.Command(bash, "-c", `. "$1"; ocfix 'echo boom; exit 3'`, "bash", file)
+	cmd.Dir = bin
+	cmd.Env = append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "The command \"echo boom; exit 3\" failed with exit code 3 in "+bin+". Its output is attached. Find the cause of the failure and explain how to fix it.\nboom\n", string(out))
+}
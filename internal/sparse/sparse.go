@@ -0,0 +1,272 @@
+// Package sparse detects git repositories checked out sparsely or cloned
+// partially, where the tracked files aren't all on disk, and widens the sparse
+// checkout on demand. Tools walking the tree use it to tell a path that
+// doesn't exist from a path that isn't checked out.
+package sparse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheTTL is how long a detected checkout is reused before git is asked
+// again, the sparse set can be changed outside of OpenCode
+const cacheTTL = 30 * time.Second
+
+// Checkout is how a repository is checked out
+type Checkout struct {
+	Root         string
+	Sparse       bool     // Only the files matching Patterns are on disk
+	Cone         bool     // The patterns are directories, recursively
+	Patterns     []string // The sparse set, relative to Root
+	PartialClone bool     // Objects are fetched from the remote when needed
+	Filter       string   // The filter of the partial clone, e.g. blob:none
+}
+
+// Enabled reports whether the repository is sparse or partial
+func (c Checkout) Enabled() bool {
+	return c.Sparse || c.PartialClone
+}
+
+type cached struct {
+	checkout Checkout
+	at       time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]cached)
+)
+
+// Detect returns how the repository of a directory is checked out, the zero
+// Checkout outside of a git repository
+func Detect(ctx context.Context, dir string) (Checkout, error) {
+	cacheMu.Lock()
+	entry, ok := cache[dir]
+	cacheMu.Unlock()
+	if ok && time.Since(entry.at) < cacheTTL {
+		return entry.checkout, nil
+	}
+
+	checkout, err := detect(ctx, dir)
+	if err != nil {
+		return Checkout{}, err
+	}
+	cacheMu.Lock()
+	cache[dir] = cached{checkout: checkout, at: time.Now()}
+	cacheMu.Unlock()
+	return checkout, nil
+}
+
+func detect(ctx context.Context, dir string) (Checkout, error) {
+	root, err := git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		// Not a repository, or git isn't installed
+		return Checkout{}, nil
+	}
+	checkout := Checkout{Root: strings.TrimSpace(root)}
+
+	// git config exits with 1 when no key matches
+	out, _ := git(ctx, dir, "config", "--get-regexp",
+		`^(core\.sparsecheckout|core\.sparsecheckoutcone|extensions\.partialclone|remote\..*\.promisor|remote\..*\.partialclonefilter)$`)
+	for _, line := range strings.Split(out, "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch {
+		case key == "core.sparsecheckout":
+			checkout.Sparse = value == "true"
+		case key == "core.sparsecheckoutcone":
+			checkout.Cone = value == "true"
+		case key == "extensions.partialclone":
+			checkout.PartialClone = value != ""
+		case strings.HasSuffix(key, ".promisor"):
+			checkout.PartialClone = checkout.PartialClone || value == "true"
+		case strings.HasSuffix(key, ".partialclonefilter"):
+			checkout.Filter = value
+		}
+	}
+	if !checkout.Sparse {
+		checkout.Cone = false
+		return checkout, nil
+	}
+
+	out, err = git(ctx, dir, "sparse-checkout", "list")
+	if err != nil {
+		return Checkout{}, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			checkout.Patterns = append(checkout.Patterns, line)
+		}
+	}
+	return checkout, nil
+}
+
+// Missing returns the entries of a directory that are tracked but not
+// checked out, directories with a trailing slash. It's empty unless the
+// repository is sparse.
+func Missing(ctx context.Context, dir string) ([]string, error) {
+	checkout, err := Detect(ctx, dir)
+	if err != nil || !checkout.Sparse {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	err = skipped(ctx, dir, ".", func(path string) bool {
+		name, _, isDir := strings.Cut(path, "/")
+		entry := name
+		if isDir {
+			entry += "/"
+		}
+		if _, ok := seen[entry]; !ok {
+			// Directories holding checked out files exist, and a skipped
+			// file can be on disk when it was modified
+			_, err := os.Lstat(filepath.Join(dir, name))
+			seen[entry] = os.IsNotExist(err)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for name, ok := range seen {
+		if ok {
+			missing = append(missing, name)
+		}
+	}
+	slices.Sort(missing)
+	return missing, nil
+}
+
+// OutsideSparseSet reports whether a path that doesn't exist is tracked but
+// not checked out, so widening the sparse checkout brings it in
+func OutsideSparseSet(ctx context.Context, path string) bool {
+	dir := filepath.Dir(path)
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+	checkout, err := Detect(ctx, dir)
+	if err != nil || !checkout.Sparse {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	found := false
+	err = skipped(ctx, dir, rel, func(string) bool {
+		found = true
+		return false
+	})
+	return err == nil && found
+}
+
+// skipped calls fn with the tracked files under path that git skips in the
+// worktree, relative to dir, until fn returns false
+func skipped(ctx context.Context, dir, path string, fn func(string) bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "ls-files", "-t", "-z", "--", path)
+	cmd.Dir = dir
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("git ls-files: %w", err)
+	}
+
+	stopped := false
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	scanner.Split(splitNUL)
+	for scanner.Scan() {
+		// Entries are "<tag> <path>", S for the skip-worktree files
+		tag, file, _ := strings.Cut(scanner.Text(), " ")
+		if tag == "S" && !fn(file) {
+			stopped = true
+			break
+		}
+	}
+	if stopped {
+		cancel()
+		io.Copy(io.Discard, stdout)
+		cmd.Wait()
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		cmd.Wait()
+		return err
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("git ls-files: %w", err)
+	}
+	return nil
+}
+
+func splitNUL(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Add widens the sparse checkout of the repository of dir with directories
+// relative to its root, or patterns when the checkout isn't in cone mode, and
+// checks out their files. Partial clones fetch the files from the remote.
+func Add(ctx context.Context, dir string, paths ...string) error {
+	checkout, err := Detect(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if !checkout.Sparse {
+		return fmt.Errorf("%s is not a sparse checkout", dir)
+	}
+	args := append([]string{"sparse-checkout", "add", "--"}, paths...)
+	_, err = git(ctx, checkout.Root, args...)
+	Invalidate()
+	return err
+}
+
+// Invalidate forgets the detected checkouts, after their sparse set changed
+func Invalidate() {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	clear(cache)
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
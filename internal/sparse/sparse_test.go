@@ -0,0 +1,83 @@
+package sparse
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSparseRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	for _, name := range []string{"README.md", "app/main.go", "libs/one/one.go", "libs/two/two.go"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(name+"\n"), 0o644))
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "test"},
+		{"add", "-A"},
+		{"commit", "-q", "-m", "init"},
+		{"sparse-checkout", "set", "--cone", "app", "libs/one"},
+	} {
+		_, err := git(ctx, dir, args...)
+		require.NoError(t, err)
+	}
+	Invalidate()
+	return dir
+}
+
+func TestDetect(t *testing.T) {
+	ctx := context.Background()
+	dir := newSparseRepo(t)
+
+	checkout, err := Detect(ctx, filepath.Join(dir, "app"))
+	require.NoError(t, err)
+	assert.True(t, checkout.Enabled())
+	assert.True(t, checkout.Sparse)
+	assert.True(t, checkout.Cone)
+	assert.False(t, checkout.PartialClone)
+	assert.Equal(t, []string{"app", "libs/one"}, checkout.Patterns)
+
+	checkout, err = Detect(ctx, t.TempDir())
+	require.NoError(t, err)
+	assert.False(t, checkout.Enabled(), "not a repository")
+}
+
+func TestMissingAndAdd(t *testing.T) {
+	ctx := context.Background()
+	dir := newSparseRepo(t)
+
+	missing, err := Missing(ctx, dir)
+	require.NoError(t, err)
+	assert.Empty(t, missing, "libs holds a checked out directory")
+	missing, err = Missing(ctx, filepath.Join(dir, "libs"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"two/"}, missing)
+
+	assert.True(t, OutsideSparseSet(ctx, filepath.Join(dir, "libs", "two")))
+	assert.True(t, OutsideSparseSet(ctx, filepath.Join(dir, "libs", "two", "two.go")))
+	assert.False(t, OutsideSparseSet(ctx, filepath.Join(dir, "libs", "three")), "not tracked")
+
+	require.NoError(t, Add(ctx, filepath.Join(dir, "app"), "libs/two"))
+	assert.FileExists(t, filepath.Join(dir, "libs", "two", "two.go"))
+	missing, err = Missing(ctx, filepath.Join(dir, "libs"))
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+	checkout, err := Detect(ctx, dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app", "libs/one", "libs/two"}, checkout.Patterns)
+
+	assert.Error(t, Add(ctx, t.TempDir(), "app"), "not a sparse checkout")
+}
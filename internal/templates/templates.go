@@ -0,0 +1,125 @@
+// Package templates stores named starting contexts for new sessions.
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/models"
+)
+
+// Template is a saved starting context for new sessions
+type Template struct {
+	Name           string         `json:"name"`
+	Description    string         `json:"description,omitempty"`
+	SystemPrompt   string         `json:"systemPrompt,omitempty"`   // Session instructions
+	Language       string         `json:"language,omitempty"`       // Response language of the session
+	PinnedFiles    []string       `json:"pinnedFiles,omitempty"`    // Relative to the working directory
+	PinnedSnippets []string       `json:"pinnedSnippets,omitempty"` // Pinned text snippets
+	Tools          []string       `json:"tools,omitempty"`          // Enabled tools, all when empty
+	Model          models.ModelID `json:"model,omitempty"`          // Coder model, unchanged when empty
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Dir returns the directory templates are stored in
+func Dir() string {
+	return filepath.Join(config.Get().Data.Directory, "templates")
+}
+
+// ValidateName checks that name can be used as a template file name
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid template name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// List returns the saved templates sorted by name
+func List() ([]Template, error) {
+	entries, err := os.ReadDir(Dir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates: %w", err)
+	}
+
+	var templates []Template
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		t, err := Load(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+// Load reads the template with the given name
+func Load(name string) (Template, error) {
+	if err := ValidateName(name); err != nil {
+		return Template{}, err
+	}
+	data, err := os.ReadFile(filepath.Join(Dir(), name+".json"))
+	if err != nil {
+		return Template{}, fmt.Errorf("failed to read template %s: %w", name, err)
+	}
+	var t Template
+	if err := json.Unmarshal(data, &t); err != nil {
+		return Template{}, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	t.Name = name
+	return t, nil
+}
+
+// Save writes t, replacing an existing template with the same name
+func Save(t Template) error {
+	if err := ValidateName(t.Name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(Dir(), 0o755); err != nil {
+		return fmt.Errorf("failed to create templates directory: %w", err)
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(Dir(), t.Name+".json"), data, 0o644)
+}
+
+// Summary describes what a template configures
+func (t Template) Summary() string {
+	var parts []string
+	if t.Description != "" {
+		parts = append(parts, t.Description)
+	}
+	if t.Model != "" {
+		parts = append(parts, string(t.Model))
+	}
+	if pins := len(t.PinnedFiles) + len(t.PinnedSnippets); pins > 0 {
+		parts = append(parts, fmt.Sprintf("%d pinned", pins))
+	}
+	if len(t.Tools) > 0 {
+		parts = append(parts, fmt.Sprintf("%d tools", len(t.Tools)))
+	}
+	if t.SystemPrompt != "" {
+		parts = append(parts, "instructions")
+	}
+	if t.Language != "" {
+		parts = append(parts, t.Language)
+	}
+	return strings.Join(parts, " · ")
+}
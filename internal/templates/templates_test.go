@@ -0,0 +1,29 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"bug-triage", "release_notes", "v1.2"} {
+		assert.NoError(t, ValidateName(name), name)
+	}
+	for _, name := range []string{"", "../escape", "two words", ".hidden"} {
+		assert.Error(t, ValidateName(name), name)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tmpl := Template{
+		Description:    "Triage incoming bugs",
+		SystemPrompt:   "Always reproduce first",
+		PinnedFiles:    []string{"CONTRIBUTING.md"},
+		PinnedSnippets: []string{"we use tabs"},
+		Tools:          []string{"view", "grep"},
+		Model:          "claude-3.5-haiku",
+	}
+	assert.Equal(t, "Triage incoming bugs · claude-3.5-haiku · 2 pinned · 2 tools · instructions", tmpl.Summary())
+	assert.Empty(t, Template{}.Summary())
+}
@@ -0,0 +1,138 @@
+// Package tmux integrates OpenCode with the tmux window it runs in: the
+// window is named after the session and shows its state, and the scrollback
+// of other panes can be captured as context.
+package tmux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// StatusOption is the window option holding the state of the session, for
+// the status line, e.g. "#{@opencode_status}" in status-right
+const StatusOption = "@opencode_status"
+
+// maxWindowName is the length of the window names at most
+const maxWindowName = 30
+
+// Inside reports whether OpenCode runs in a tmux pane
+func Inside() bool {
+	return os.Getenv("TMUX") != "" && os.Getenv("TMUX_PANE") != ""
+}
+
+// Window is the tmux window of the pane OpenCode runs in
+type Window struct {
+	pane string
+
+	mu     sync.Mutex
+	name   string // Last name set
+	status string // Last status set
+}
+
+// Attach returns the window of the pane OpenCode runs in
+func Attach() (*Window, error) {
+	if !Inside() {
+		return nil, errors.New("not running inside tmux")
+	}
+	if _, err := exec.LookPath("tmux"); err != nil {
+		return nil, errors.New("tmux is not installed")
+	}
+	return &Window{pane: os.Getenv("TMUX_PANE")}, nil
+}
+
+// Rename names the window, unless it has this name already
+func (w *Window) Rename(ctx context.Context, name string) error {
+	name = WindowName(name)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if name == w.name {
+		return nil
+	}
+	if _, err := run(ctx, "rename-window", "-t", w.pane, name); err != nil {
+		return err
+	}
+	w.name = name
+	return nil
+}
+
+// SetStatus sets the status option of the window, unless it has this status
+// already
+func (w *Window) SetStatus(ctx context.Context, status string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if status == w.status {
+		return nil
+	}
+	if _, err := run(ctx, "set-option", "-w", "-t", w.pane, StatusOption, status); err != nil {
+		return err
+	}
+	w.status = status
+	return nil
+}
+
+// Detach hands the name of the window back to tmux and clears its status
+func (w *Window) Detach(ctx context.Context) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.name != "" {
+		_, _ = run(ctx, "set-option", "-w", "-t", w.pane, "automatic-rename", "on")
+	}
+	if w.status != "" {
+		_, _ = run(ctx, "set-option", "-w", "-u", "-t", w.pane, StatusOption)
+	}
+	w.name, w.status = "", ""
+}
+
+// WindowName shortens a session title to a window name
+func WindowName(title string) string {
+	name := strings.Join(strings.Fields(title), " ")
+	if name == "" {
+		return "opencode"
+	}
+	if utf8.RuneCountInString(name) > maxWindowName {
+		name = string([]rune(name)[:maxWindowName-1]) + "…"
+	}
+	return name
+}
+
+// Status returns the status of a session for the status line
+func Status(busy bool, cost float64) string {
+	if busy {
+		return fmt.Sprintf("● $%.2f", cost)
+	}
+	return fmt.Sprintf("○ $%.2f", cost)
+}
+
+// CapturePane returns the last lines of the scrollback of a pane, wrapped
+// lines joined, without the trailing blank lines. target is a tmux target
+// such as "{last}" for the previously active pane, or "%3".
+func CapturePane(ctx context.Context, target string, lines int) (string, error) {
+	if !Inside() {
+		return "", errors.New("not running inside tmux")
+	}
+	out, err := run(ctx, "capture-pane", "-p", "-J", "-t", target, "-S", fmt.Sprintf("-%d", lines))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(out, " \t\n"), nil
+}
+
+func run(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "tmux", args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("tmux %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("tmux %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,35 @@
+package tmux
+
+import (
+	"context"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowName(t *testing.T) {
+	assert.Equal(t, "opencode", WindowName(""))
+	assert.Equal(t, "opencode", WindowName(" \n\t"))
+	assert.Equal(t, "Fix the flaky test", WindowName("Fix  the\nflaky test "))
+
+	long := WindowName("Refactor the permission service to support scoped grants")
+	assert.Equal(t, maxWindowName, utf8.RuneCountInString(long))
+	assert.Equal(t, "Refactor the permission servi…", long)
+}
+
+func TestStatus(t *testing.T) {
+	assert.Equal(t, "● $0.42", Status(true, 0.4213))
+	assert.Equal(t, "○ $0.00", Status(false, 0))
+}
+
+func TestOutsideTmux(t *testing.T) {
+	t.Setenv("TMUX", "")
+	t.Setenv("TMUX_PANE", "")
+	assert.False(t, Inside())
+
+	_, err := Attach()
+	assert.Error(t, err)
+	_, err = CapturePane(context.Background(), "{last}", 10)
+	assert.Error(t, err)
+}
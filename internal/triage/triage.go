@@ -0,0 +1,317 @@
+// Package triage maps the frames of stack traces and error logs to the files
+// of the workspace and builds the prompt asking for a root cause.
+package triage
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxFrames     = 15    // Resolved frames shown with their code
+	contextLines  = 6     // Lines of code around a frame
+	maxTraceChars = 12000 // Tail of the trace kept in the prompt
+	maxIndexFiles = 50000 // Files indexed to resolve bare file names
+)
+
+// Frame is a location found in a trace
+type Frame struct {
+	Path     string // As written in the trace
+	Line     int
+	Function string // Empty when the trace doesn't name it
+
+	File string // Path relative to the workspace, empty when unresolved
+}
+
+var (
+	// File "/app/store.py", line 12, in get
+	pythonFrame = regexp.MustCompile(`File "([^"]+)", line (\d+)(?:, in (\S+))?`)
+	// at get (/app/dist/store.js:12:5) or at /app/dist/store.js:12:5
+	jsFrame = regexp.MustCompile(`at (?:(\S+) \()?((?:file://|webpack:///)?[^()\s]+\.\w+):(\d+):\d+\)?`)
+	// at com.example.Store.get(Store.java:12)
+	javaFrame = regexp.MustCompile(`at ([\w$.<>]+)\(([\w$]+\.\w+):(\d+)\)`)
+	// /app/store.go:12 +0x1d, store_test.go:12: message, src/main.rs:12:5
+	genericFrame = regexp.MustCompile(`([\w./\\-]*\w\.\w+):(\d+)`)
+	// Go function lines preceding their location, e.g. main.(*Store).Get(...)
+	goFunction = regexp.MustCompile(`^([\w./-]+\.[\w.()*\[\]]+)\(.*\)$`)
+)
+
+// external marks paths of dependencies and runtimes rather than project code
+var external = []string{"node_modules/", "site-packages/", "dist-packages/", "/go/pkg/mod/", "/usr/lib/", "/usr/local/go/", "<frozen", "node:internal"}
+
+// Parse extracts the frames of a stack trace or log, in order and without
+// duplicates
+func Parse(trace string) []Frame {
+	var frames []Frame
+	seen := make(map[string]bool)
+	add := func(frame Frame) {
+		key := fmt.Sprintf("%s:%d", frame.Path, frame.Line)
+		if frame.Line <= 0 || seen[key] {
+			return
+		}
+		seen[key] = true
+		frames = append(frames, frame)
+	}
+
+	var previous string
+	scanner := bufio.NewScanner(strings.NewReader(trace))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case pythonFrame.MatchString(line):
+			m := pythonFrame.FindStringSubmatch(line)
+			add(Frame{Path: m[1], Line: atoi(m[2]), Function: m[3]})
+		case javaFrame.MatchString(line):
+			m := javaFrame.FindStringSubmatch(line)
+			// The package of the class is the directory of the file
+			dir := ""
+			if parts := strings.Split(m[1], "."); len(parts) > 2 {
+				dir = strings.Join(parts[:len(parts)-2], "/") + "/"
+			}
+			add(Frame{Path: dir + m[2], Line: atoi(m[3]), Function: m[1]})
+		case jsFrame.MatchString(line):
+			m := jsFrame.FindStringSubmatch(line)
+			add(Frame{Path: m[2], Line: atoi(m[3]), Function: m[1]})
+		default:
+			for _, m := range genericFrame.FindAllStringSubmatch(line, -1) {
+				frame := Frame{Path: m[1], Line: atoi(m[2])}
+				if fn := goFunction.FindStringSubmatch(strings.TrimSpace(previous)); fn != nil && strings.HasPrefix(line, "\t") {
+					frame.Function = fn[1]
+				}
+				add(frame)
+			}
+		}
+		if trimmed != "" {
+			previous = line
+		}
+	}
+	return frames
+}
+
+// Resolve maps the frames to files of the workspace in wd. Paths from other
+// machines, containers and build directories are matched by their longest
+// suffix that exists in the workspace.
+func Resolve(frames []Frame, wd string) []Frame {
+	var index map[string][]string // Base name -> relative paths, built on demand
+	resolved := make([]Frame, len(frames))
+	for i, frame := range frames {
+		resolved[i] = frame
+		if isExternal(frame.Path) {
+			continue
+		}
+		for _, candidate := range candidates(frame.Path) {
+			if file := resolvePath(candidate, wd); file != "" {
+				resolved[i].File = file
+				break
+			}
+		}
+		if resolved[i].File != "" {
+			continue
+		}
+		if index == nil {
+			index = indexFiles(wd)
+		}
+		resolved[i].File = matchIndex(index, frame.Path)
+	}
+	return resolved
+}
+
+// candidates returns the paths to try for a frame: the path itself and, for
+// compiled JavaScript, the source it was built from
+func candidates(path string) []string {
+	path = strings.TrimPrefix(path, "file://")
+	path = strings.TrimPrefix(path, "webpack:///")
+	path = filepath.ToSlash(path)
+	result := []string{path}
+	for _, buildDir := range []string{"dist/", "build/", "out/", "lib/"} {
+		if strings.Contains(path, buildDir) {
+			source := strings.Replace(path, buildDir, "src/", 1)
+			result = append(result, source)
+			if strings.HasSuffix(source, ".js") {
+				result = append(result, strings.TrimSuffix(source, ".js")+".ts", strings.TrimSuffix(source, ".js")+".tsx")
+			}
+		}
+	}
+	return result
+}
+
+// resolvePath finds the longest suffix of path that is a file in wd
+func resolvePath(path, wd string) string {
+	if filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") && isFile(path) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i := range parts {
+		// A bare file name is too ambiguous to match on its own unless the
+		// trace only has the name
+		if i == len(parts)-1 && len(parts) > 1 {
+			break
+		}
+		rel := strings.Join(parts[i:], "/")
+		if isFile(filepath.Join(wd, rel)) {
+			return rel
+		}
+	}
+	return ""
+}
+
+// indexFiles lists the files of the workspace by base name
+func indexFiles(wd string) map[string][]string {
+	index := make(map[string][]string)
+	count := 0
+	filepath.WalkDir(wd, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != wd && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor" || name == "target") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if count++; count > maxIndexFiles {
+			return filepath.SkipAll
+		}
+		rel, err := filepath.Rel(wd, path)
+		if err == nil {
+			index[d.Name()] = append(index[d.Name()], filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return index
+}
+
+// matchIndex picks the indexed file sharing the longest path suffix with
+// path, it gives up when several files match equally well
+func matchIndex(index map[string][]string, path string) string {
+	path = filepath.ToSlash(path)
+	matches := index[filepath.Base(path)]
+	best, bestScore, tie := "", -1, false
+	for _, match := range matches {
+		score := commonSuffix(match, path)
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = match, score, false
+		case score == bestScore:
+			tie = true
+		}
+	}
+	if tie {
+		return ""
+	}
+	return best
+}
+
+// commonSuffix counts the path elements a and b end with
+func commonSuffix(a, b string) int {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	n := 0
+	for n < len(as) && n < len(bs) && as[len(as)-1-n] == bs[len(bs)-1-n] {
+		n++
+	}
+	return n
+}
+
+// Prompt builds the request for a root cause analysis of trace, with the code
+// around the frames resolved in wd
+func Prompt(trace string, frames []Frame, wd string) string {
+	var sb strings.Builder
+	sb.WriteString("<triage>\nFind the root cause of this error.\n\n```\n")
+	trace = strings.TrimSpace(trace)
+	if len(trace) > maxTraceChars {
+		trace = "..." + trace[len(trace)-maxTraceChars:]
+	}
+	sb.WriteString(trace)
+	sb.WriteString("\n```\n")
+
+	var unresolved []string
+	shown := 0
+	for _, frame := range frames {
+		if frame.File == "" {
+			if !isExternal(frame.Path) {
+				unresolved = append(unresolved, fmt.Sprintf("%s:%d", frame.Path, frame.Line))
+			}
+			continue
+		}
+		if shown == maxFrames {
+			continue
+		}
+		code, ok := codeAround(filepath.Join(wd, frame.File), frame.Line)
+		if !ok {
+			continue
+		}
+		shown++
+		fmt.Fprintf(&sb, "\n%s:%d", frame.File, frame.Line)
+		if frame.Function != "" {
+			fmt.Fprintf(&sb, " (%s)", frame.Function)
+		}
+		fmt.Fprintf(&sb, "\n```\n%s```\n", code)
+	}
+	if shown == 0 {
+		sb.WriteString("\nNone of the frames could be mapped to files of the workspace. Search the workspace for the functions, messages and identifiers of the trace.\n")
+	}
+	if len(unresolved) > 0 {
+		fmt.Fprintf(&sb, "\nNot found in the workspace: %s\n", strings.Join(unresolved, ", "))
+	}
+
+	sb.WriteString(`
+The frames above were mapped to the workspace from the trace, the code around each frame is shown with the line of the frame marked with ">". The code may have changed since the trace was recorded.
+Read more of the code with your tools where needed, but don't change any files. Answer with:
+1. Root cause hypothesis: what went wrong and where, citing file:line
+2. Evidence: the parts of the trace and the code supporting it, and how confident you are
+3. Next steps: how to confirm the hypothesis and how to fix it
+</triage>`)
+	return sb.String()
+}
+
+// codeAround returns the numbered lines of file around line
+func codeAround(file string, line int) (string, bool) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", false
+	}
+	lines := strings.Split(string(content), "\n")
+	if line > len(lines) {
+		return "", false
+	}
+	var sb strings.Builder
+	for i := max(1, line-contextLines); i <= min(len(lines), line+contextLines); i++ {
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&sb, "%s%5d| %s\n", marker, i, lines[i-1])
+	}
+	return sb.String(), true
+}
+
+func isExternal(path string) bool {
+	path = filepath.ToSlash(path)
+	for _, marker := range external {
+		if strings.Contains(path, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
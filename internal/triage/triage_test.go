@@ -0,0 +1,89 @@
+package triage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		trace string
+		want  []Frame
+	}{
+		{
+			name: "go panic",
+			trace: `panic: runtime error: invalid memory address or nil pointer dereference
+goroutine 1 [running]:
+example.com/app/store.(*Store).Get(...)
+	/build/app/store/store.go:42 +0x1d
+main.main()
+	/build/app/main.go:12 +0x25`,
+			want: []Frame{
+				{Path: "/build/app/store/store.go", Line: 42, Function: "example.com/app/store.(*Store).Get"},
+				{Path: "/build/app/main.go", Line: 12, Function: "main.main"},
+			},
+		},
+		{
+			name: "python",
+			trace: `Traceback (most recent call last):
+  File "/srv/app/store.py", line 12, in get
+    return self.items[key]
+KeyError: 'a'`,
+			want: []Frame{{Path: "/srv/app/store.py", Line: 12, Function: "get"}},
+		},
+		{
+			name: "node",
+			trace: `TypeError: Cannot read properties of undefined
+    at get (/app/dist/store.js:12:5)
+    at /app/dist/main.js:3:1`,
+			want: []Frame{
+				{Path: "/app/dist/store.js", Line: 12, Function: "get"},
+				{Path: "/app/dist/main.js", Line: 3},
+			},
+		},
+		{
+			name:  "java",
+			trace: "java.lang.NullPointerException\n\tat com.example.Store.get(Store.java:12)",
+			want:  []Frame{{Path: "com/example/Store.java", Line: 12, Function: "com.example.Store.get"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Parse(tt.trace))
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	wd := t.TempDir()
+	for _, file := range []string{"store/store.go", "src/store.ts", "src/main/java/com/example/Store.java"} {
+		path := filepath.Join(wd, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("line 1\nline 2\nline 3\n"), 0o644))
+	}
+
+	frames := Resolve([]Frame{
+		{Path: "/build/app/store/store.go", Line: 2},
+		{Path: "/app/dist/store.js", Line: 2},
+		{Path: "com/example/Store.java", Line: 2},
+		{Path: "/usr/local/go/src/runtime/panic.go", Line: 2},
+		{Path: "/build/app/missing.go", Line: 2},
+	}, wd)
+
+	var files []string
+	for _, frame := range frames {
+		files = append(files, frame.File)
+	}
+	assert.Equal(t, []string{"store/store.go", "src/store.ts", "src/main/java/com/example/Store.java", "", ""}, files)
+
+	prompt := Prompt("panic", frames, wd)
+	assert.Contains(t, prompt, "store/store.go:2\n```\n     1| line 1\n>    2| line 2\n")
+	assert.Contains(t, prompt, "Not found in the workspace: /build/app/missing.go:2")
+	assert.False(t, strings.Contains(prompt, "runtime/panic.go"))
+}
@@ -25,11 +25,20 @@ type SessionClearedMsg struct{}
 
 type EditorFocusMsg bool
 
+// UsageToggledMsg re-renders the messages after the usage footers were shown
+// or hidden
+type UsageToggledMsg struct{}
+
 // Messages for input handling and slash suggestions
 type ReplaceInputMsg struct {
 	Text string
 }
 
+// RedactInputMsg replaces a redacted text in the prompts sent before
+type RedactInputMsg struct {
+	Text string
+}
+
 type GetCurrentInputMsg struct{}
 
 type CurrentInputMsg struct {
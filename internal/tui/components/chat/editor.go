@@ -163,6 +163,11 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case ReplaceInputMsg:
 		m.textarea.SetValue(msg.Text)
 		return m, nil
+	case RedactInputMsg:
+		for i, prompt := range m.promptHistory {
+			m.promptHistory[i] = strings.ReplaceAll(prompt, msg.Text, "[redacted]")
+		}
+		return m, nil
 	case GetCurrentInputMsg:
 		return m, util.CmdHandler(CurrentInputMsg{Text: m.textarea.Value()})
 	case SessionSelectedMsg:
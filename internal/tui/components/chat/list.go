@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"slices"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -11,6 +12,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/kirmad/superopencode/internal/app"
+	"github.com/kirmad/superopencode/internal/llm/agent"
 	"github.com/kirmad/superopencode/internal/message"
 	"github.com/kirmad/superopencode/internal/pubsub"
 	"github.com/kirmad/superopencode/internal/session"
@@ -36,6 +38,7 @@ type messagesCmp struct {
 	spinner       spinner.Model
 	rendering     bool
 	attachments   viewport.Model
+	expandTasks   bool // Show all the progress of the running tasks
 }
 type renderFinishedMsg struct{}
 
@@ -44,6 +47,7 @@ type MessageKeys struct {
 	PageUp       key.Binding
 	HalfPageUp   key.Binding
 	HalfPageDown key.Binding
+	ToggleTasks  key.Binding
 }
 
 var messageKeys = MessageKeys{
@@ -63,6 +67,10 @@ var messageKeys = MessageKeys{
 		key.WithKeys("ctrl+d", "ctrl+d"),
 		key.WithHelp("ctrl+d", "½ page down"),
 	),
+	ToggleTasks: key.NewBinding(
+		key.WithKeys("ctrl+b"),
+		key.WithHelp("ctrl+b", "expand tasks"),
+	),
 }
 
 func (m *messagesCmp) Init() tea.Cmd {
@@ -72,7 +80,7 @@ func (m *messagesCmp) Init() tea.Cmd {
 func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	switch msg := msg.(type) {
-	case dialog.ThemeChangedMsg:
+	case dialog.ThemeChangedMsg, UsageToggledMsg:
 		m.rerender()
 		return m, nil
 	case SessionSelectedMsg:
@@ -95,6 +103,10 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport = u
 			cmds = append(cmds, cmd)
 		}
+		if key.Matches(msg, messageKeys.ToggleTasks) {
+			m.expandTasks = !m.expandTasks
+			m.rerender()
+		}
 
 	case renderFinishedMsg:
 		m.rendering = false
@@ -107,6 +119,21 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.renderView()
 			}
 		}
+	case pubsub.Event[agent.TaskProgress]:
+		if msg.Payload.ParentSessionID != m.session.ID {
+			break
+		}
+		// The task of a tool call made progress
+		for _, v := range m.messages {
+			if slices.ContainsFunc(v.ToolCalls(), func(c message.ToolCall) bool { return c.ID == msg.Payload.CallID }) {
+				delete(m.cachedContent, v.ID)
+				m.renderView()
+				if v.ID == m.messages[len(m.messages)-1].ID {
+					m.viewport.GotoBottom()
+				}
+				break
+			}
+		}
 	case pubsub.Event[message.Message]:
 		needsRerender := false
 		if msg.Type == pubsub.CreatedEvent {
@@ -150,6 +177,17 @@ func (m *messagesCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					break
 				}
 			}
+		} else if msg.Type == pubsub.DeletedEvent && msg.Payload.SessionID == m.session.ID {
+			if i := slices.IndexFunc(m.messages, func(v message.Message) bool { return v.ID == msg.Payload.ID }); i >= 0 {
+				m.messages = slices.Delete(m.messages, i, i+1)
+				if m.currentMsgID == msg.Payload.ID && len(m.messages) > 0 {
+					m.currentMsgID = m.messages[len(m.messages)-1].ID
+				}
+				// The messages after it move up and the one before may have
+				// shown the tool calls it answered
+				m.cachedContent = make(map[string]cacheItem)
+				needsRerender = true
+			}
 		}
 		if needsRerender {
 			m.renderView()
@@ -225,6 +263,7 @@ func (m *messagesCmp) renderView() {
 				m.app.Messages,
 				m.currentMsgID,
 				isSummary,
+				m.expandTasks,
 				m.width,
 				pos,
 			)
@@ -382,7 +421,9 @@ func (m *messagesCmp) help() string {
 			lipgloss.Left,
 			baseStyle.Foreground(t.TextMuted()).Bold(true).Render("press "),
 			baseStyle.Foreground(t.Text()).Bold(true).Render("esc"),
-			baseStyle.Foreground(t.TextMuted()).Bold(true).Render(" to exit cancel"),
+			baseStyle.Foreground(t.TextMuted()).Bold(true).Render(" to exit cancel, "),
+			baseStyle.Foreground(t.Text()).Bold(true).Render("ctrl+x"),
+			baseStyle.Foreground(t.TextMuted()).Bold(true).Render(" to stop after the current tool"),
 		)
 	} else {
 		text += lipgloss.JoinHorizontal(
@@ -451,7 +492,9 @@ func (m *messagesCmp) SetSession(session session.Session) tea.Cmd {
 	if len(m.messages) > 0 {
 		m.currentMsgID = m.messages[len(m.messages)-1].ID
 	}
-	delete(m.cachedContent, m.currentMsgID)
+	// Drop the renders of the previous session, its messages are rendered
+	// again from the database when it's opened
+	m.cachedContent = make(map[string]cacheItem)
 	m.rendering = true
 	return func() tea.Msg {
 		m.renderView()
@@ -465,6 +508,7 @@ func (m *messagesCmp) BindingKeys() []key.Binding {
 		m.viewport.KeyMap.PageUp,
 		m.viewport.KeyMap.HalfPageUp,
 		m.viewport.KeyMap.HalfPageDown,
+		messageKeys.ToggleTasks,
 	}
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"path/filepath"
 	"strings"
 	"time"
@@ -113,6 +114,79 @@ func renderUserMessage(msg message.Message, isFocused bool, width int, position
 	return userMsg
 }
 
+// modelLabel names the model of an assistant message and, when the request
+// was routed, the tier and reason it was picked for
+func modelLabel(msg message.Message) string {
+	name := models.SupportedModels[msg.Model].Name
+	if r := msg.Route(); r != nil {
+		return fmt.Sprintf("%s · %s: %s", name, r.Tier, r.Reason)
+	}
+	return name
+}
+
+// usageLabel summarizes the tokens and cost of the request behind an
+// assistant message, empty when usage footers are hidden
+func usageLabel(msg message.Message) string {
+	usage := msg.Usage()
+	if usage == nil {
+		return ""
+	}
+	if cfg := config.Get(); cfg != nil && cfg.TUI.HideUsage {
+		return ""
+	}
+	input := usage.InputTokens + usage.CacheCreationTokens + usage.CacheReadTokens
+	label := formatTokenCount(input) + " in"
+	if usage.CacheReadTokens > 0 {
+		label += fmt.Sprintf(" (%s cached)", formatTokenCount(usage.CacheReadTokens))
+	}
+	return fmt.Sprintf("%s · %s out · $%.4f", label, formatTokenCount(usage.OutputTokens), usage.Cost)
+}
+
+// sourcesLabel renders the file references of an assistant message as
+// terminal hyperlinks. References the agent did not read during the request
+// are marked with a warning color.
+func sourcesLabel(msg message.Message) string {
+	citations := msg.Citations()
+	if len(citations) == 0 {
+		return ""
+	}
+	t := theme.CurrentTheme()
+	muted := lipgloss.NewStyle().Foreground(t.TextMuted()).Background(t.Background())
+	verified := lipgloss.NewStyle().Foreground(t.Info()).Background(t.Background())
+	unverified := lipgloss.NewStyle().Foreground(t.Warning()).Background(t.Background())
+
+	links := make([]string, len(citations))
+	for i, c := range citations {
+		label := fmt.Sprintf("%s:%d", removeWorkingDirPrefix(c.Path), c.Line)
+		if c.EndLine > c.Line {
+			label += fmt.Sprintf("-%d", c.EndLine)
+		}
+		style := verified
+		if !c.Verified {
+			style = unverified
+			label += " (unverified)"
+		}
+		uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(c.Path)}).String()
+		links[i] = ansi.SetHyperlink(uri) + style.Render(label) + ansi.ResetHyperlink()
+	}
+	return muted.Render("Sources: ") + strings.Join(links, muted.Render(" · "))
+}
+
+// formatTokenCount formats a token count in a human-readable format, e.g. 1.2K
+func formatTokenCount(tokens int64) string {
+	var formatted string
+	switch {
+	case tokens >= 1_000_000:
+		formatted = fmt.Sprintf("%.1fM", float64(tokens)/1_000_000)
+	case tokens >= 1_000:
+		formatted = fmt.Sprintf("%.1fK", float64(tokens)/1_000)
+	default:
+		return fmt.Sprintf("%d", tokens)
+	}
+	formatted = strings.Replace(formatted, ".0K", "K", 1)
+	return strings.Replace(formatted, ".0M", "M", 1)
+}
+
 // Returns multiple uiMessages because of the tool calls
 func renderAssistantMessage(
 	msg message.Message,
@@ -121,6 +195,7 @@ func renderAssistantMessage(
 	messagesService message.Service, // We need this to get the task tool messages
 	focusedUIMessageId string,
 	isSummary bool,
+	expandTasks bool, // Show all the progress of the running tasks
 	width int,
 	position int,
 ) []uiMessage {
@@ -143,25 +218,38 @@ func renderAssistantMessage(
 			info = append(info, baseStyle.
 				Width(width-1).
 				Foreground(t.TextMuted()).
-				Render(fmt.Sprintf(" %s (%s)", models.SupportedModels[msg.Model].Name, took)),
+				Render(fmt.Sprintf(" %s (%s)", modelLabel(msg), took)),
 			)
 		case message.FinishReasonCanceled:
 			info = append(info, baseStyle.
 				Width(width-1).
 				Foreground(t.TextMuted()).
-				Render(fmt.Sprintf(" %s (%s)", models.SupportedModels[msg.Model].Name, "canceled")),
+				Render(fmt.Sprintf(" %s (%s)", modelLabel(msg), "canceled")),
 			)
 		case message.FinishReasonError:
 			info = append(info, baseStyle.
 				Width(width-1).
 				Foreground(t.TextMuted()).
-				Render(fmt.Sprintf(" %s (%s)", models.SupportedModels[msg.Model].Name, "error")),
+				Render(fmt.Sprintf(" %s (%s)", modelLabel(msg), "error")),
 			)
 		case message.FinishReasonPermissionDenied:
 			info = append(info, baseStyle.
 				Width(width-1).
 				Foreground(t.TextMuted()).
-				Render(fmt.Sprintf(" %s (%s)", models.SupportedModels[msg.Model].Name, "permission denied")),
+				Render(fmt.Sprintf(" %s (%s)", modelLabel(msg), "permission denied")),
+			)
+		}
+		if label := usageLabel(msg); label != "" {
+			info = append(info, baseStyle.
+				Width(width-1).
+				Foreground(t.TextMuted()).
+				Render(" "+label),
+			)
+		}
+		if sources := sourcesLabel(msg); sources != "" {
+			info = append(info, baseStyle.
+				Width(width-1).
+				Render(" "+sources),
 			)
 		}
 	}
@@ -195,6 +283,7 @@ func renderAssistantMessage(
 			messagesService,
 			focusedUIMessageId,
 			false,
+			expandTasks,
 			width,
 			i+1,
 		)
@@ -244,6 +333,16 @@ func toolName(name string) string {
 		return "Read Todos"
 	case tools.TodoWriteToolName:
 		return "Update Todos"
+	case tools.ReminderToolName:
+		return "Reminder"
+	case tools.ReadPromptToolName:
+		return "Read Prompt"
+	case tools.KnowledgeSearchToolName:
+		return "Docs"
+	case agent.LogQueryToolName:
+		return "Logs"
+	case agent.ParallelTasksToolName:
+		return "Parallel Tasks"
 	}
 	return name
 }
@@ -276,6 +375,16 @@ func getToolAction(name string) string {
 		return "Reading todo list..."
 	case tools.TodoWriteToolName:
 		return "Updating todos..."
+	case tools.ReminderToolName:
+		return "Setting reminder..."
+	case tools.ReadPromptToolName:
+		return "Reading prompt..."
+	case tools.KnowledgeSearchToolName:
+		return "Searching docs..."
+	case agent.LogQueryToolName:
+		return "Querying logs..."
+	case agent.ParallelTasksToolName:
+		return "Planning tasks..."
 	}
 	return "Working..."
 }
@@ -347,6 +456,9 @@ func renderToolParams(paramWidth int, toolCall message.ToolCall) string {
 		var params agent.AgentParams
 		json.Unmarshal([]byte(toolCall.Input), &params)
 		prompt := strings.ReplaceAll(params.Prompt, "\n", " ")
+		if params.Continuation != "" {
+			return renderParams(paramWidth, prompt, "continues", params.Continuation)
+		}
 		return renderParams(paramWidth, prompt)
 	case tools.BashToolName:
 		var params tools.BashParams
@@ -425,6 +537,12 @@ func renderToolParams(paramWidth int, toolCall message.ToolCall) string {
 		if params.Offset != 0 {
 			toolParams = append(toolParams, "offset", fmt.Sprintf("%d", params.Offset))
 		}
+		if params.StartLine != 0 {
+			toolParams = append(toolParams, "lines", fmt.Sprintf("%d-%d", params.StartLine, params.EndLine))
+		}
+		if params.Symbol != "" {
+			toolParams = append(toolParams, "symbol", params.Symbol)
+		}
 		return renderParams(paramWidth, toolParams...)
 	case tools.WriteToolName:
 		var params tools.WriteParams
@@ -435,6 +553,51 @@ func renderToolParams(paramWidth int, toolCall message.ToolCall) string {
 		return ""
 	case tools.TodoWriteToolName:
 		return ""
+	case tools.ReadPromptToolName:
+		var params tools.ReadPromptParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		return renderParams(paramWidth, params.ID, "chunk", fmt.Sprint(params.Chunk))
+	case tools.KnowledgeSearchToolName:
+		var params tools.KnowledgeSearchParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		if params.Source != "" {
+			return renderParams(paramWidth, params.Query, "source", params.Source)
+		}
+		return renderParams(paramWidth, params.Query)
+	case agent.LogQueryToolName:
+		var params agent.LogQueryParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		session := params.Session
+		if session == "" {
+			session = "all sessions"
+		}
+		var errorsOnly string
+		if params.ErrorsOnly {
+			errorsOnly = "true"
+		}
+		return renderParams(paramWidth, session, "kind", params.Kind, "name", params.Name, "errors_only", errorsOnly,
+			"contains", params.Contains, "since", params.Since, "id", params.ID)
+	case agent.ParallelTasksToolName:
+		var params agent.ParallelTaskParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		ids := make([]string, len(params.Tasks))
+		for i, task := range params.Tasks {
+			ids[i] = task.ID
+			if len(task.DependsOn) > 0 {
+				ids[i] += " ← " + strings.Join(task.DependsOn, ",")
+			}
+		}
+		return renderParams(paramWidth, strings.Join(ids, "; "))
+	case tools.ReminderToolName:
+		var params tools.ReminderParams
+		json.Unmarshal([]byte(toolCall.Input), &params)
+		switch params.Action {
+		case "set":
+			return renderParams(paramWidth, params.Note, "in", params.In)
+		case "cancel":
+			return renderParams(paramWidth, "cancel "+params.ID)
+		}
+		return renderParams(paramWidth, params.Action)
 	default:
 		input := strings.ReplaceAll(toolCall.Input, "\n", " ")
 		params = renderParams(paramWidth, input)
@@ -558,6 +721,33 @@ func renderTodoList(todos []map[string]interface{}, width int, t theme.Theme) st
 	return result
 }
 
+// renderParallelTaskMetrics shows the DAG of a parallel_tasks run level by
+// level, with the outcome of every task
+func renderParallelTaskMetrics(metadata agent.ParallelTaskMetrics) string {
+	byID := make(map[string]agent.ParallelTaskMetric, len(metadata.Tasks))
+	for _, task := range metadata.Tasks {
+		byID[task.ID] = task
+	}
+	lines := []string{fmt.Sprintf("%d tasks in %d levels, up to %d at a time, %s, $%.4f",
+		len(metadata.Tasks), len(metadata.Levels), metadata.MaxParallel, metadata.Duration.Round(time.Second), metadata.CostUSD)}
+	for l, ids := range metadata.Levels {
+		tasks := make([]string, len(ids))
+		for i, id := range ids {
+			task := byID[id]
+			tasks[i] = fmt.Sprintf("%s (%s", id, task.Status)
+			if task.Status != agent.TaskStatusSkipped {
+				tasks[i] += " " + task.Duration.Round(time.Second).String()
+			}
+			if task.Retries > 0 {
+				tasks[i] += fmt.Sprintf(", %d retries", task.Retries)
+			}
+			tasks[i] += ")"
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s", l+1, strings.Join(tasks, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
 func renderToolResponse(toolCall message.ToolCall, response message.ToolResult, width int) string {
 	t := theme.CurrentTheme()
 	baseStyle := styles.BaseStyle()
@@ -643,6 +833,10 @@ func renderToolResponse(toolCall message.ToolCall, response message.ToolResult,
 			toMarkdown(resultContent, true, width),
 			t.Background(),
 		)
+	case agent.ParallelTasksToolName:
+		metadata := agent.ParallelTaskMetrics{}
+		json.Unmarshal([]byte(response.Metadata), &metadata)
+		return baseStyle.Width(width).Foreground(t.TextMuted()).Render(renderParallelTaskMetrics(metadata))
 	case tools.TodoReadToolName:
 		return styles.ForceReplaceBackgroundWithLipgloss(
 			renderTodoReadResponse(response.Content, width, t),
@@ -668,6 +862,7 @@ func renderToolMessage(
 	messagesService message.Service,
 	focusedUIMessageId string,
 	nested bool,
+	expandTasks bool,
 	width int,
 	position int,
 ) uiMessage {
@@ -740,16 +935,25 @@ func renderToolMessage(
 		parts = append(parts, lipgloss.JoinHorizontal(lipgloss.Left, prefix, toolNameText, formattedParams))
 	}
 
-	if toolCall.Name == agent.AgentToolName {
-		taskMessages, _ := messagesService.List(context.Background(), toolCall.ID)
+	if activity, ok := agent.RunningTaskActivity(toolCall.ID); ok && toolCall.Name == agent.AgentToolName && response == nil && !nested {
+		parts = append(parts, renderTaskProgress(activity, messagesService, focusedUIMessageId, expandTasks, width)...)
+		responseContent = ""
+	} else if toolCall.Name == agent.AgentToolName {
+		taskMessages, _ := messagesService.List(context.Background(), agent.TaskSessionID(toolCall))
 		toolCalls := []message.ToolCall{}
 		for _, v := range taskMessages {
 			toolCalls = append(toolCalls, v.ToolCalls()...)
 		}
 		for _, call := range toolCalls {
-			rendered := renderToolMessage(call, []message.Message{}, messagesService, focusedUIMessageId, true, width, 0)
+			rendered := renderToolMessage(call, []message.Message{}, messagesService, focusedUIMessageId, true, false, width, 0)
 			parts = append(parts, rendered.content)
 		}
+		if !nested {
+			parts = append(parts, baseStyle.
+				Width(width-2).
+				Foreground(t.TextMuted()).
+				Render(" ctrl+g inspect task"))
+		}
 	}
 	if responseContent != "" && !nested {
 		parts = append(parts, responseContent)
@@ -776,6 +980,60 @@ func renderToolMessage(
 	return toolMsg
 }
 
+// Collapsed task progress shows the last tool calls and the last line of the
+// partial response
+const (
+	collapsedTaskToolCalls = 3
+	collapsedTaskLines     = 1
+	expandedTaskLines      = 5
+)
+
+// renderTaskProgress renders what a running task did so far, relayed by the
+// task before it reports
+func renderTaskProgress(activity agent.TaskActivity, messagesService message.Service, focusedUIMessageId string, expanded bool, width int) []string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	muted := baseStyle.Width(width - 2).Foreground(t.TextMuted())
+
+	calls := len(activity.ToolCalls)
+	header := fmt.Sprintf(" Task in progress · %d tool calls · %s", calls, time.Since(activity.Started).Round(time.Second))
+	if calls == 1 {
+		header = fmt.Sprintf(" Task in progress · 1 tool call · %s", time.Since(activity.Started).Round(time.Second))
+	}
+	parts := []string{baseStyle.Width(width - 2).Foreground(t.Primary()).Render(header)}
+
+	toolCalls := activity.ToolCalls
+	maxLines := expandedTaskLines
+	if !expanded {
+		maxLines = collapsedTaskLines
+		if len(toolCalls) > collapsedTaskToolCalls {
+			parts = append(parts, muted.Render(fmt.Sprintf(" └ … %d earlier", len(toolCalls)-collapsedTaskToolCalls)))
+			toolCalls = toolCalls[len(toolCalls)-collapsedTaskToolCalls:]
+		}
+	}
+	for _, call := range toolCalls {
+		rendered := renderToolMessage(call, []message.Message{}, messagesService, focusedUIMessageId, true, false, width, 0)
+		parts = append(parts, rendered.content)
+	}
+
+	if content := strings.TrimSpace(activity.Content); content != "" {
+		lines := strings.Split(content, "\n")
+		if len(lines) > maxLines {
+			lines = lines[len(lines)-maxLines:]
+		}
+		for _, line := range lines {
+			parts = append(parts, muted.Italic(true).Render(" │ "+ansi.Truncate(line, width-6, "…")))
+		}
+	}
+
+	toggle := "ctrl+b expand"
+	if expanded {
+		toggle = "ctrl+b collapse"
+	}
+	parts = append(parts, muted.Render(" ctrl+g inspect task · "+toggle))
+	return parts
+}
+
 // Helper function to format the time difference between two Unix timestamps
 func formatTimestampDiff(start, end int64) string {
 	diffSeconds := float64(end-start) / 1000.0 // Convert to seconds
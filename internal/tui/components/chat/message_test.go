@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/kirmad/superopencode/internal/message"
 	"github.com/kirmad/superopencode/internal/tui/theme"
 )
 
@@ -443,4 +444,21 @@ func TestTodoReadResponseEdgeCases(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestUsageLabel(t *testing.T) {
+	msg := message.Message{Role: message.Assistant}
+	if label := usageLabel(msg); label != "" {
+		t.Errorf("usageLabel() without usage = %q, want empty", label)
+	}
+
+	msg.SetUsage(message.Usage{
+		InputTokens:     2_500,
+		CacheReadTokens: 10_000,
+		OutputTokens:    640,
+		Cost:            0.0123,
+	})
+	want := "12.5K in (10K cached) · 640 out · $0.0123"
+	if label := usageLabel(msg); label != want {
+		t.Errorf("usageLabel() = %q, want %q", label, want)
+	}
+}
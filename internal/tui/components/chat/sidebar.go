@@ -11,6 +11,7 @@ import (
 	"github.com/kirmad/superopencode/internal/config"
 	"github.com/kirmad/superopencode/internal/diff"
 	"github.com/kirmad/superopencode/internal/history"
+	"github.com/kirmad/superopencode/internal/llm/prompt"
 	"github.com/kirmad/superopencode/internal/pubsub"
 	"github.com/kirmad/superopencode/internal/session"
 	"github.com/kirmad/superopencode/internal/tui/styles"
@@ -99,6 +100,8 @@ func (m *sidebarCmp) View() string {
 				lspsConfigured(m.width),
 				" ",
 				m.modifiedFiles(),
+				" ",
+				m.pinnedContext(),
 			),
 		)
 }
@@ -225,6 +228,58 @@ func (m *sidebarCmp) modifiedFiles() string {
 		)
 }
 
+func (m *sidebarCmp) pinnedContext() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	pinnedTitle := baseStyle.
+		Width(m.width).
+		Foreground(t.Primary()).
+		Bold(true).
+		Render("Pinned Context:")
+
+	items := prompt.ListPins(m.session.ID)
+	if len(items) == 0 {
+		return baseStyle.
+			Width(m.width).
+			Render(
+				lipgloss.JoinVertical(
+					lipgloss.Top,
+					pinnedTitle,
+					baseStyle.Foreground(t.TextMuted()).Render("Nothing pinned, use /pin"),
+				),
+			)
+	}
+
+	var total int64
+	var itemViews []string
+	for _, item := range items {
+		total += item.Tokens
+		tokens := baseStyle.
+			Foreground(t.TextMuted()).
+			PaddingLeft(1).
+			Render(fmt.Sprintf("~%d tok", item.Tokens))
+		label := baseStyle.Render(fmt.Sprintf("#%d %s", item.ID, item.Label()))
+		itemViews = append(itemViews, baseStyle.
+			Width(m.width).
+			Render(lipgloss.JoinHorizontal(lipgloss.Left, label, tokens)))
+	}
+	itemViews = append(itemViews, baseStyle.
+		Width(m.width).
+		Foreground(t.TextMuted()).
+		Render(fmt.Sprintf("Total: ~%d tokens", total)))
+
+	return baseStyle.
+		Width(m.width).
+		Render(
+			lipgloss.JoinVertical(
+				lipgloss.Top,
+				pinnedTitle,
+				lipgloss.JoinVertical(lipgloss.Left, itemViews...),
+			),
+		)
+}
+
 func (m *sidebarCmp) SetSize(width, height int) tea.Cmd {
 	m.width = width
 	m.height = height
@@ -8,11 +8,14 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/agent"
 	"github.com/kirmad/superopencode/internal/llm/models"
+	"github.com/kirmad/superopencode/internal/locale"
 	"github.com/kirmad/superopencode/internal/lsp"
 	"github.com/kirmad/superopencode/internal/lsp/protocol"
 	"github.com/kirmad/superopencode/internal/pubsub"
 	"github.com/kirmad/superopencode/internal/session"
+	"github.com/kirmad/superopencode/internal/sessionlock"
 	"github.com/kirmad/superopencode/internal/tui/components/chat"
 	"github.com/kirmad/superopencode/internal/tui/styles"
 	"github.com/kirmad/superopencode/internal/tui/theme"
@@ -29,6 +32,15 @@ type statusCmp struct {
 	messageTTL time.Duration
 	lspClients map[string]*lsp.Client
 	session    session.Session
+
+	// Preflight estimate of the next prompt of the session
+	promptEstimate int64
+
+	// Time to the first token of the last turn of the session
+	firstToken time.Duration
+
+	// Other process driving the session, nil when this one may
+	lockHolder *sessionlock.Owner
 }
 
 // clearMessageCmd is a command that clears status messages after a timeout
@@ -48,9 +60,31 @@ func (m statusCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		return m, nil
 	case chat.SessionSelectedMsg:
+		if msg.ID != m.session.ID {
+			m.promptEstimate = 0
+			m.firstToken = 0
+			m.lockHolder = nil
+		}
 		m.session = msg
 	case chat.SessionClearedMsg:
 		m.session = session.Session{}
+		m.promptEstimate = 0
+		m.firstToken = 0
+		m.lockHolder = nil
+	case pubsub.Event[sessionlock.Event]:
+		if msg.Payload.SessionID == m.session.ID {
+			m.lockHolder = msg.Payload.Holder
+		}
+	case pubsub.Event[agent.AgentEvent]:
+		if msg.Payload.SessionID != m.session.ID {
+			break
+		}
+		switch msg.Payload.Type {
+		case agent.AgentEventTypePreflight:
+			m.promptEstimate = msg.Payload.PromptTokens
+		case agent.AgentEventTypeLatency:
+			m.firstToken = msg.Payload.FirstToken
+		}
 	case pubsub.Event[session.Session]:
 		if msg.Type == pubsub.UpdatedEvent {
 			if m.session.ID == msg.Payload.ID {
@@ -89,23 +123,23 @@ func formatTokensAndCost(tokens, contextWindow int64, cost float64) string {
 	var formattedTokens string
 	switch {
 	case tokens >= 1_000_000:
-		formattedTokens = fmt.Sprintf("%.1fM", float64(tokens)/1_000_000)
+		formattedTokens = locale.Float(float64(tokens)/1_000_000, 1) + "M"
 	case tokens >= 1_000:
-		formattedTokens = fmt.Sprintf("%.1fK", float64(tokens)/1_000)
+		formattedTokens = locale.Float(float64(tokens)/1_000, 1) + "K"
 	default:
-		formattedTokens = fmt.Sprintf("%d", tokens)
+		formattedTokens = locale.Int(tokens)
 	}
 
 	// Remove .0 suffix if present
-	if strings.HasSuffix(formattedTokens, ".0K") {
-		formattedTokens = strings.Replace(formattedTokens, ".0K", "K", 1)
-	}
-	if strings.HasSuffix(formattedTokens, ".0M") {
-		formattedTokens = strings.Replace(formattedTokens, ".0M", "M", 1)
+	zero := locale.Current().Decimal + "0"
+	for _, unit := range []string{"K", "M"} {
+		if strings.HasSuffix(formattedTokens, zero+unit) {
+			formattedTokens = strings.TrimSuffix(formattedTokens, zero+unit) + unit
+		}
 	}
 
 	// Format cost with $ symbol and 2 decimal places
-	formattedCost := fmt.Sprintf("$%.2f", cost)
+	formattedCost := "$" + locale.Float(cost, 2)
 
 	percentage := (float64(tokens) / float64(contextWindow)) * 100
 	if percentage > 80 {
@@ -126,7 +160,8 @@ func (m statusCmp) View() string {
 
 	tokenInfoWidth := 0
 	if m.session.ID != "" {
-		totalTokens := m.session.PromptTokens + m.session.CompletionTokens
+		// Prefer the preflight estimate while a request is being prepared
+		totalTokens := max(m.session.PromptTokens+m.session.CompletionTokens, m.promptEstimate)
 		tokens := formatTokensAndCost(totalTokens, model.ContextWindow, m.session.Cost)
 		tokensStyle := styles.Padded().
 			Background(t.Text()).
@@ -142,6 +177,8 @@ func (m statusCmp) View() string {
 	diagnostics := styles.Padded().
 		Background(t.BackgroundDarker()).
 		Render(m.projectDiagnostics())
+	diagnostics += m.latency()
+	diagnostics += m.lock()
 
 	availableWidht := max(0, m.width-lipgloss.Width(helpWidget)-lipgloss.Width(m.model())-lipgloss.Width(diagnostics)-tokenInfoWidth)
 
@@ -258,6 +295,36 @@ func (m *statusCmp) projectDiagnostics() string {
 	return strings.Join(diagnostics, " ")
 }
 
+// latency shows the time to the first token of the last turn in debug mode,
+// highlighted when it misses latency.firstTokenTargetMs
+func (m statusCmp) latency() string {
+	cfg := config.Get()
+	if !cfg.Debug || m.firstToken == 0 {
+		return ""
+	}
+	t := theme.CurrentTheme()
+	style := styles.Padded().
+		Background(t.BackgroundDarker()).
+		Foreground(t.TextMuted())
+	if target := cfg.Latency.FirstTokenTargetMs; target > 0 && m.firstToken > time.Duration(target)*time.Millisecond {
+		style = style.Foreground(t.Warning())
+	}
+	return style.Render(fmt.Sprintf("TTFT %s", m.firstToken.Round(10*time.Millisecond)))
+}
+
+// lock shows the other process driving the session, until it's taken over
+// with /takeover
+func (m statusCmp) lock() string {
+	if m.lockHolder == nil {
+		return ""
+	}
+	t := theme.CurrentTheme()
+	return styles.Padded().
+		Background(t.Warning()).
+		Foreground(t.Background()).
+		Render(fmt.Sprintf("🔒 %s", m.lockHolder))
+}
+
 func (m statusCmp) availableFooterMsgWidth(diagnostics, tokenInfo string) int {
 	tokensWidth := 0
 	if m.session.ID != "" {
@@ -18,6 +18,7 @@ type Command struct {
 	Description string
 	Content     string // Raw content for slash commands
 	FilePath    string // Path to the command file for file expansion base path
+	Args        string // Text following the command name when run as a slash command
 	Handler     func(cmd Command) tea.Cmd
 }
 
@@ -17,6 +17,7 @@ const (
 	UserCommandPrefix    = "user:"
 	ProjectCommandPrefix = "project:"
 	BuiltinCommandPrefix = "builtin:"
+	MCPCommandPrefix     = "mcp:"
 )
 
 // namedArgPattern is a regex pattern to find named arguments in the format $NAME
@@ -34,6 +35,281 @@ func loadBuiltinCommands() []Command {
 				return util.CmdHandler(ClearSessionMsg{})
 			},
 		},
+		{
+			ID:          BuiltinCommandPrefix + "pin",
+			Title:       "pin",
+			Description: "Pin a file or text snippet to every prompt of this session",
+			Content:     "Pin a file or text snippet to the session context",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(PinContextMsg{Target: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "unpin",
+			Title:       "unpin",
+			Description: "Unpin context by number or file path (all when empty)",
+			Content:     "Remove pinned context from the session",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(UnpinContextMsg{Target: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "system",
+			Title:       "system",
+			Description: "Edit the system prompt of this session, or append the given text",
+			Content:     "Edit the session instructions appended to the system prompt",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(EditSystemPromptMsg{Append: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "language",
+			Title:       "language",
+			Description: "Show or set the response language of this session: [language | reset]",
+			Content:     "Have the agents explain in a language while the code stays in English",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(SetLanguageMsg{Language: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "save-template",
+			Title:       "save-template",
+			Description: "Save this session's setup as a template for new sessions",
+			Content:     "Save the session instructions, pinned context, tools and model as a template",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(SaveTemplateMsg{Name: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "loglevel",
+			Title:       "loglevel",
+			Description: "Show the log levels, or set one: [module] <level|reset>",
+			Content:     "Change the log level of the application or of a module",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(SetLogLevelMsg{Args: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "second-opinion",
+			Title:       "second-opinion",
+			Description: "Have a reviewer agent check the pending changes, optionally with a focus",
+			Content:     "Review the uncommitted changes with a reviewer agent and address its findings",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(SecondOpinionMsg{Focus: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "issue",
+			Title:       "issue",
+			Description: "Draft an issue from this session: [--file] [notes]",
+			Content:     "Turn the problem, investigation and open items of the session into an issue draft",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(ExportIssueMsg{Args: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "pr",
+			Title:       "pr",
+			Description: "Draft a pull request from this session: [--open] [notes]",
+			Content:     "Describe the changes of the session as a pull request, and optionally push the branch and open it",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(PullRequestMsg{Args: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "docs",
+			Title:       "docs",
+			Description: "Document a Go package: <package dir> [markdown]",
+			Content:     "Add doc comments to the public API of a package, or write Markdown docs for it",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(GenerateDocsMsg{Args: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "gen-tests",
+			Title:       "gen-tests",
+			Description: "Generate tests until coverage improves: [package pattern or test path]",
+			Content:     "Write tests for uncovered code, measuring coverage after every round",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(GenerateTestsMsg{Target: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "triage",
+			Title:       "triage",
+			Description: "Find the root cause of an error: <pasted stack trace or log file>",
+			Content:     "Map the frames of a stack trace or error log to the workspace and ask for a root cause hypothesis",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(TriageMsg{Trace: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "security-audit",
+			Title:       "security-audit",
+			Description: "Audit the security of the project with SAST scanners: [path or focus]",
+			Content:     "Run a security agent with gosec, semgrep and bandit and add remediation tasks to the todo list",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(SecurityAuditMsg{Scope: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "migrate",
+			Title:       "migrate",
+			Description: "Migrate files in batches with parallel subagents: <glob> <instructions> | resume <id>",
+			Content:     "Plan a migration as todos and run its batches in isolated git worktrees, without arguments list the migrations",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(MigrateMsg{Args: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "checkpoint",
+			Title:       "checkpoint",
+			Description: "Commit the session to its branch: [message] | save <name> | restore <name> | list",
+			Content:     "Commit all changes to the session branch, or save and restore named snapshots of the workspace",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(CheckpointMsg{Message: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "compare-branch",
+			Title:       "compare-branch",
+			Description: "Compare the session branch with the branch it started from",
+			Content:     "Show the commits and changed files of the session branch",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(CompareBranchMsg{})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "cleanup-branches",
+			Title:       "cleanup-branches",
+			Description: "Delete the branches of deleted sessions: [idle days]",
+			Content:     "Delete the session branches of deleted sessions, and of sessions without commits for the given days",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(CleanupBranchesMsg{Args: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "env",
+			Title:       "env",
+			Description: "Set variables for the bash commands of this session: set KEY=VALUE | unset KEY",
+			Content:     "List, set or unset the environment variables injected into the bash commands of this session",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(SessionEnvMsg{Args: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "lens",
+			Title:       "lens",
+			Description: "List the code lenses of a file, or run one and pin its result: <file> [number]",
+			Content:     "Show the LSP code lenses of a file, like run test or references, and add the result of one to the session context",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(CodeLensMsg{Args: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "mcp",
+			Title:       "mcp",
+			Description: "Check the MCP servers and show their health and tools",
+			Content:     "Connect to the configured MCP servers and show their status",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(MCPStatusMsg{})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "timeline",
+			Title:       "timeline",
+			Description: "Show the session as a timeline of turns, LLM calls, tools and permission waits",
+			Content:     "Show when the session waited on the model, tools or permissions, built from detailed logs",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(TimelineMsg{})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "context",
+			Title:       "context",
+			Description: "Show what the next turn sends to the model, with token counts, and drop items",
+			Content:     "Inspect the system prompt, tools, pinned context and history of the session sent on the next turn",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(ContextInspectorMsg{})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "tmux",
+			Title:       "tmux",
+			Description: "Add the scrollback of a tmux pane to the prompt: [pane] [lines]",
+			Content:     "Capture the last lines of a tmux pane, the previous one by default, into the editor as context",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(TmuxCaptureMsg{Args: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "logs",
+			Title:       "logs",
+			Description: "Ask about the detailed logs, e.g. which tool call failed and why: <question>",
+			Content:     "Have the agent answer a question by querying the LLM calls, tool calls and HTTP requests of the detailed logs",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(LogsMsg{Question: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "redact",
+			Title:       "redact",
+			Description: "Replace a text, like a secret pasted by mistake, in the session and its detailed logs",
+			Content:     "Redact a text from the messages, title, instructions and pinned context of the session and from the detailed logs",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(RedactMsg{Text: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "takeover",
+			Title:       "takeover",
+			Description: "Drive the session from here when another OpenCode process drives it",
+			Content:     "Take the session over from the process holding it, which stops working on it",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(TakeoverMsg{})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "postmortem",
+			Title:       "postmortem",
+			Description: "Review what a turn changed, roll it back and learn from it: [turn]",
+			Content:     "List the files and commands of a turn, the last one by default, roll back files and add a post-mortem to the project memory",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(PostMortemMsg{Turn: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "diff",
+			Title:       "diff",
+			Description: "Compare the workspace between two points of the session: [from] [to]",
+			Content:     "Show the combined diff of the files changed between two turns or checkpoints, and export it as a patch",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(SessionDiffMsg{Args: cmd.Args})
+			},
+		},
+		{
+			ID:          BuiltinCommandPrefix + "search",
+			Title:       "search",
+			Description: "Find the sessions whose messages match: <query>",
+			Content:     "Search the messages of all sessions and switch to one of the matching sessions, the best match first",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(SearchSessionsMsg{Query: cmd.Args})
+			},
+		},
+	}
+}
+
+// NewMCPPromptCommand returns the command running a prompt of an MCP server,
+// invoked as /<server>:<name>
+func NewMCPPromptCommand(server, name, description string) Command {
+	return Command{
+		ID:          MCPCommandPrefix + server + ":" + name,
+		Title:       server + ":" + name,
+		Description: description,
+		Content:     fmt.Sprintf("Run the prompt %s of the MCP server %s", name, server),
+		Handler: func(cmd Command) tea.Cmd {
+			return util.CmdHandler(MCPPromptMsg{Server: server, Name: name, Args: cmd.Args})
+		},
 	}
 }
 
@@ -50,7 +326,31 @@ func LoadCustomCommands() ([]Command, error) {
 	builtinCommands := loadBuiltinCommands()
 	commands = append(commands, builtinCommands...)
 
-	// Load user commands from XDG_CONFIG_HOME/opencode/commands
+	// Load user commands, then project commands
+	for _, dir := range commandDirs(cfg) {
+		dirCommands, err := loadCommandsFromDir(dir.path, dir.prefix)
+		if err != nil {
+			// Log error but continue - we'll still try to load other commands
+			fmt.Printf("Warning: failed to load commands from %s: %v\n", dir.path, err)
+			continue
+		}
+		commands = append(commands, dirCommands...)
+	}
+
+	return commands, nil
+}
+
+// commandDir is a directory of command files and the prefix of their IDs
+type commandDir struct {
+	path   string
+	prefix string
+}
+
+// commandDirs returns the directories commands are loaded from:
+// XDG_CONFIG_HOME/opencode/commands, $HOME/.opencode/commands and the
+// commands of the data directory
+func commandDirs(cfg *config.Config) []commandDir {
+	var dirs []commandDir
 	xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
 	if xdgConfigHome == "" {
 		// Default to ~/.config if XDG_CONFIG_HOME is not set
@@ -59,42 +359,31 @@ func LoadCustomCommands() ([]Command, error) {
 			xdgConfigHome = filepath.Join(home, ".config")
 		}
 	}
-
 	if xdgConfigHome != "" {
-		userCommandsDir := filepath.Join(xdgConfigHome, "opencode", "commands")
-		userCommands, err := loadCommandsFromDir(userCommandsDir, UserCommandPrefix)
-		if err != nil {
-			// Log error but continue - we'll still try to load other commands
-			fmt.Printf("Warning: failed to load user commands from XDG_CONFIG_HOME: %v\n", err)
-		} else {
-			commands = append(commands, userCommands...)
-		}
+		dirs = append(dirs, commandDir{filepath.Join(xdgConfigHome, "opencode", "commands"), UserCommandPrefix})
 	}
-
-	// Load commands from $HOME/.opencode/commands
-	home, err := os.UserHomeDir()
-	if err == nil {
-		homeCommandsDir := filepath.Join(home, ".opencode", "commands")
-		homeCommands, err := loadCommandsFromDir(homeCommandsDir, UserCommandPrefix)
-		if err != nil {
-			// Log error but continue - we'll still try to load other commands
-			fmt.Printf("Warning: failed to load home commands: %v\n", err)
-		} else {
-			commands = append(commands, homeCommands...)
-		}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, commandDir{filepath.Join(home, ".opencode", "commands"), UserCommandPrefix})
 	}
+	return append(dirs, commandDir{filepath.Join(cfg.Data.Directory, "commands"), ProjectCommandPrefix})
+}
 
-	// Load project commands from data directory
-	projectCommandsDir := filepath.Join(cfg.Data.Directory, "commands")
-	projectCommands, err := loadCommandsFromDir(projectCommandsDir, ProjectCommandPrefix)
-	if err != nil {
-		// Log error but return what we have so far
-		fmt.Printf("Warning: failed to load project commands: %v\n", err)
-	} else {
-		commands = append(commands, projectCommands...)
+// CommandDirs returns the directories commands are loaded from
+func CommandDirs() []string {
+	cfg := config.Get()
+	if cfg == nil {
+		return nil
+	}
+	var paths []string
+	for _, dir := range commandDirs(cfg) {
+		paths = append(paths, dir.path)
 	}
+	return paths
+}
 
-	return commands, nil
+// IsFileCommand reports whether a command was loaded from a command file
+func IsFileCommand(cmd Command) bool {
+	return strings.HasPrefix(cmd.ID, UserCommandPrefix) || strings.HasPrefix(cmd.ID, ProjectCommandPrefix)
 }
 
 // loadCommandsFromDir loads commands from a specific directory with the given prefix
@@ -211,3 +500,145 @@ type CommandRunCustomMsg struct {
 type ClearSessionMsg struct {
 	SessionID string // Session ID to clear messages for
 }
+
+// PinContextMsg is sent when the /pin command is executed
+type PinContextMsg struct {
+	Target string // File path or text snippet to pin
+}
+
+// UnpinContextMsg is sent when the /unpin command is executed
+type UnpinContextMsg struct {
+	Target string // Pin number, file path or empty for all
+}
+
+// EditSystemPromptMsg is sent when the /system command is executed
+type EditSystemPromptMsg struct {
+	Append string // Text appended to the session instructions without opening the editor
+}
+
+// SetLanguageMsg is sent when the /language command is executed
+type SetLanguageMsg struct {
+	Language string // Language of the session, "reset" for locale.language, empty to show it
+}
+
+// SetLogLevelMsg is sent when the /loglevel command is executed
+type SetLogLevelMsg struct {
+	Args string // "[module] <level>", "<module> reset" or empty to show the levels
+}
+
+// ExportIssueMsg is sent when the /issue command is executed
+type ExportIssueMsg struct {
+	Args string // "[--file] [notes]"
+}
+
+// PullRequestMsg is sent when the /pr command is executed
+type PullRequestMsg struct {
+	Args string // "[--open] [notes]"
+}
+
+// GenerateDocsMsg is sent when the /docs command is executed
+type GenerateDocsMsg struct {
+	Args string // "<package dir> [markdown]"
+}
+
+// GenerateTestsMsg is sent when the /gen-tests command is executed
+type GenerateTestsMsg struct {
+	Target string
+}
+
+// TriageMsg is sent when the /triage command is executed
+type TriageMsg struct {
+	Trace string // Pasted stack trace or log, or the path of a log file
+}
+
+// SecurityAuditMsg is sent when the /security-audit command is executed
+type SecurityAuditMsg struct {
+	Scope string
+}
+
+// MigrateMsg is sent when the /migrate command is executed
+type MigrateMsg struct {
+	Args string // "<glob> <instructions>", "resume <id>" or empty to list
+}
+
+// CheckpointMsg is sent when the /checkpoint command is executed
+type CheckpointMsg struct {
+	Message string // Commit message, or "save <name>", "restore <name>" or "list"
+}
+
+// CompareBranchMsg is sent when the /compare-branch command is executed
+type CompareBranchMsg struct{}
+
+// CleanupBranchesMsg is sent when the /cleanup-branches command is executed
+type CleanupBranchesMsg struct {
+	Args string // Idle days, optional
+}
+
+// SessionEnvMsg is sent when the /env command is executed
+type SessionEnvMsg struct {
+	Args string // "set KEY=VALUE", "unset KEY" or empty to list
+}
+
+// CodeLensMsg is sent when the /lens command is executed
+type CodeLensMsg struct {
+	Args string // "<file>" to list the lenses, "<file> <number>" to run one
+}
+
+// MCPStatusMsg is sent when the /mcp command is executed
+type MCPStatusMsg struct{}
+
+// TimelineMsg is sent when the /timeline command is executed
+type TimelineMsg struct{}
+
+// MCPPromptMsg is sent when the command of an MCP prompt is executed
+type MCPPromptMsg struct {
+	Server string
+	Name   string
+	Args   string // name=value pairs, or the value of a prompt's only argument
+}
+
+// SecondOpinionMsg is sent when the /second-opinion command is executed
+type SecondOpinionMsg struct {
+	Focus string
+}
+
+// SaveTemplateMsg is sent when the /save-template command is executed
+type SaveTemplateMsg struct {
+	Name string // Template name
+}
+
+// ContextInspectorMsg is sent when the /context command is executed
+type ContextInspectorMsg struct{}
+
+// TmuxCaptureMsg is sent when the /tmux command is executed
+type TmuxCaptureMsg struct {
+	Args string // Pane and number of lines
+}
+
+// LogsMsg is sent when the /logs command is executed
+type LogsMsg struct {
+	Question string
+}
+
+// RedactMsg is sent when the /redact command is executed
+type RedactMsg struct {
+	Text string
+}
+
+// TakeoverMsg is sent when the /takeover command is executed
+type TakeoverMsg struct{}
+
+// PostMortemMsg is sent when the /postmortem command is executed
+type PostMortemMsg struct {
+	Turn string // Number of the turn, the last one when empty
+}
+
+// SessionDiffMsg is sent when the /diff command is executed
+type SessionDiffMsg struct {
+	Args string // The points to compare, e.g. "2 5"
+}
+
+// SearchSessionsMsg is sent when the /search command is executed
+type SearchSessionsMsg struct {
+	Query string
+}
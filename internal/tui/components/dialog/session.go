@@ -1,6 +1,9 @@
 package dialog
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -24,11 +27,14 @@ type SessionDialog interface {
 	tea.Model
 	layout.Bindings
 	SetSessions(sessions []session.Session)
+	SetSearchResults(query string, results []session.SearchResult)
 	SetSelectedSession(sessionID string)
 }
 
 type sessionDialogCmp struct {
 	sessions          []session.Session
+	query             string
+	snippets          []string // Snippets of the search results, by session
 	selectedIdx       int
 	width             int
 	height            int
@@ -120,9 +126,12 @@ func (s *sessionDialogCmp) View() string {
 
 	// Calculate max width needed for session titles
 	maxWidth := 40 // Minimum width
+	if s.query != "" {
+		maxWidth = 60
+	}
 	for _, sess := range s.sessions {
-		if len(sess.Title) > maxWidth-4 { // Account for padding
-			maxWidth = len(sess.Title) + 4
+		if len(sessionTitle(sess)) > maxWidth-4 { // Account for padding
+			maxWidth = len(sessionTitle(sess)) + 4
 		}
 	}
 
@@ -130,6 +139,9 @@ func (s *sessionDialogCmp) View() string {
 
 	// Limit height to avoid taking up too much screen space
 	maxVisibleSessions := min(10, len(s.sessions))
+	if s.snippets != nil {
+		maxVisibleSessions = min(6, len(s.sessions))
+	}
 
 	// Build the session list
 	sessionItems := make([]string, 0, maxVisibleSessions)
@@ -159,15 +171,22 @@ func (s *sessionDialogCmp) View() string {
 				Bold(true)
 		}
 
-		sessionItems = append(sessionItems, itemStyle.Padding(0, 1).Render(sess.Title))
+		sessionItems = append(sessionItems, itemStyle.Padding(0, 1).Render(sessionTitle(sess)))
+		if s.snippets != nil {
+			sessionItems = append(sessionItems, renderSnippet(s.snippets[i], maxWidth))
+		}
 	}
 
+	heading := "Switch Session"
+	if s.query != "" {
+		heading = fmt.Sprintf("Sessions matching %q", s.query)
+	}
 	title := baseStyle.
 		Foreground(t.Primary()).
 		Bold(true).
 		Width(maxWidth).
 		Padding(0, 1).
-		Render("Switch Session")
+		Render(heading)
 
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
@@ -185,12 +204,58 @@ func (s *sessionDialogCmp) View() string {
 		Render(content)
 }
 
+// sessionTitle returns the title of a session in the list, task sessions are
+// marked as such
+func sessionTitle(sess session.Session) string {
+	if sess.Ephemeral {
+		return "↳ " + sess.Title
+	}
+	return sess.Title
+}
+
+// renderSnippet renders the snippet of a search result on one line under its
+// session, with the matched terms highlighted
+func renderSnippet(snippet string, width int) string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	textStyle := baseStyle.Foreground(t.TextMuted())
+	matchStyle := baseStyle.Foreground(t.Accent()).Bold(true)
+
+	var sb strings.Builder
+	remaining := width - 4
+	for i, part := range strings.Split(snippet, session.MatchStart) {
+		match, text := "", part
+		if i > 0 {
+			match, text, _ = strings.Cut(part, session.MatchEnd)
+		}
+		for _, segment := range []struct {
+			text  string
+			style lipgloss.Style
+		}{{match, matchStyle}, {text, textStyle}} {
+			runes := []rune(segment.text)
+			if len(runes) > remaining {
+				runes = append(runes[:max(0, remaining-1)], '…')
+			}
+			remaining -= len(runes)
+			if len(runes) > 0 {
+				sb.WriteString(segment.style.Render(string(runes)))
+			}
+		}
+		if remaining <= 0 {
+			break
+		}
+	}
+	return baseStyle.Width(width).Padding(0, 3).Render(sb.String())
+}
+
 func (s *sessionDialogCmp) BindingKeys() []key.Binding {
 	return layout.KeyMapToSlice(sessionKeys)
 }
 
 func (s *sessionDialogCmp) SetSessions(sessions []session.Session) {
 	s.sessions = sessions
+	s.query = ""
+	s.snippets = nil
 
 	// If we have a selected session ID, find its index
 	if s.selectedSessionID != "" {
@@ -206,6 +271,19 @@ func (s *sessionDialogCmp) SetSessions(sessions []session.Session) {
 	s.selectedIdx = 0
 }
 
+// SetSearchResults lists the sessions matching a search, the best match
+// first, each with the snippet of its best matching message
+func (s *sessionDialogCmp) SetSearchResults(query string, results []session.SearchResult) {
+	s.sessions = make([]session.Session, len(results))
+	s.snippets = make([]string, len(results))
+	for i, result := range results {
+		s.sessions[i] = result.Session
+		s.snippets[i] = result.Snippet
+	}
+	s.query = query
+	s.selectedIdx = 0
+}
+
 func (s *sessionDialogCmp) SetSelectedSession(sessionID string) {
 	s.selectedSessionID = sessionID
 
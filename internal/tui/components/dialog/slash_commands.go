@@ -63,13 +63,7 @@ func (scp *SlashCommandProcessor) ProcessSlashCommand(input string) *SlashComman
 
 	// Remove the leading slash and split into command and remaining text
 	trimmed := strings.TrimSpace(input)[1:] // Remove leading "/"
-	parts := strings.SplitN(trimmed, " ", 2)
-	
-	commandName := parts[0]
-	remainingText := ""
-	if len(parts) > 1 {
-		remainingText = strings.TrimSpace(parts[1])
-	}
+	commandName, remainingText := splitCommandName(trimmed)
 
 	// Find the command
 	command := scp.findCommand(commandName)
@@ -177,6 +171,14 @@ func (scp *SlashCommandProcessor) findCommand(name string) *Command {
 		}
 	}
 
+	// Try with mcp: prefix, the prompts of MCP servers
+	mcpCommand := MCPCommandPrefix + name
+	for _, cmd := range scp.commands {
+		if scp.matchesCommandName(cmd.ID, mcpCommand) {
+			return &cmd
+		}
+	}
+
 	return nil
 }
 
@@ -200,6 +202,8 @@ func (scp *SlashCommandProcessor) GetAvailableCommands() []string {
 			name = strings.TrimPrefix(name, ProjectCommandPrefix)
 		} else if strings.HasPrefix(name, BuiltinCommandPrefix) {
 			name = strings.TrimPrefix(name, BuiltinCommandPrefix)
+		} else if strings.HasPrefix(name, MCPCommandPrefix) {
+			name = strings.TrimPrefix(name, MCPCommandPrefix)
 		}
 
 		if !seen[name] {
@@ -245,6 +249,8 @@ func (scp *SlashCommandProcessor) GetSuggestions(input string, maxCount int) []S
 			name = strings.TrimPrefix(name, ProjectCommandPrefix)
 		} else if strings.HasPrefix(name, BuiltinCommandPrefix) {
 			name = strings.TrimPrefix(name, BuiltinCommandPrefix)
+		} else if strings.HasPrefix(name, MCPCommandPrefix) {
+			name = strings.TrimPrefix(name, MCPCommandPrefix)
 		}
 		
 		// Skip if we've already added this command name
@@ -286,6 +292,8 @@ func (scp *SlashCommandProcessor) getAllSuggestions(maxCount int) []Suggestion {
 			name = strings.TrimPrefix(name, ProjectCommandPrefix)
 		} else if strings.HasPrefix(name, BuiltinCommandPrefix) {
 			name = strings.TrimPrefix(name, BuiltinCommandPrefix)
+		} else if strings.HasPrefix(name, MCPCommandPrefix) {
+			name = strings.TrimPrefix(name, MCPCommandPrefix)
 		}
 		
 		// Skip if we've already added this command name
@@ -400,8 +408,7 @@ func ValidateSlashCommand(input string) error {
 	}
 	
 	// Extract command name
-	parts := strings.SplitN(trimmed[1:], " ", 2)
-	commandName := parts[0]
+	commandName, _ := splitCommandName(trimmed[1:])
 	
 	if commandName == "" {
 		return fmt.Errorf("command name cannot be empty")
@@ -413,4 +420,14 @@ func ValidateSlashCommand(input string) error {
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// splitCommandName splits the command name from the text following it, which
+// may start on a new line, e.g. a pasted stack trace
+func splitCommandName(input string) (string, string) {
+	i := strings.IndexAny(input, " \t\r\n")
+	if i < 0 {
+		return input, ""
+	}
+	return input[:i], strings.TrimSpace(input[i+1:])
+}
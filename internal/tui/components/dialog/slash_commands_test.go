@@ -185,6 +185,26 @@ func TestCommandResolutionOrder(t *testing.T) {
 	}
 }
 
+func TestMCPPromptCommand(t *testing.T) {
+	processor := NewSlashCommandProcessor([]Command{NewMCPPromptCommand("github", "review", "Review a PR")})
+
+	result := processor.ProcessSlashCommand("/github:review pr=12")
+	if result.Error != nil {
+		t.Fatalf("ProcessSlashCommand failed: %v", result.Error)
+	}
+	if result.Processed.Command.ID != "mcp:github:review" {
+		t.Errorf("Expected to find 'mcp:github:review' command, got '%s'", result.Processed.Command.ID)
+	}
+	if result.Processed.RemainingText != "pr=12" {
+		t.Errorf("Expected remaining text 'pr=12', got '%s'", result.Processed.RemainingText)
+	}
+
+	suggestions := processor.GetSuggestions("/git", 10)
+	if len(suggestions) != 1 || suggestions[0].Command != "github:review" {
+		t.Errorf("Expected suggestion 'github:review', got %v", suggestions)
+	}
+}
+
 // Tests for slash command suggestions functionality
 func TestSlashCommandProcessor_GetSuggestions(t *testing.T) {
 	commands := []Command{
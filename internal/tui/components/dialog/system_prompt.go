@@ -0,0 +1,180 @@
+package dialog
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kirmad/superopencode/internal/tui/styles"
+	"github.com/kirmad/superopencode/internal/tui/theme"
+	"github.com/kirmad/superopencode/internal/tui/util"
+)
+
+// ShowSystemPromptDialogMsg is sent to open the system prompt editor of a session
+type ShowSystemPromptDialogMsg struct {
+	SessionID  string
+	BasePrompt string // Read-only system prompt of the agent
+	Additions  string // Editable session additions
+}
+
+// CloseSystemPromptDialogMsg is sent when the system prompt editor is closed
+type CloseSystemPromptDialogMsg struct {
+	Submit    bool
+	SessionID string
+	Additions string
+}
+
+type systemPromptDialogKeyMap struct {
+	Save   key.Binding
+	Scroll key.Binding
+	Escape key.Binding
+}
+
+var systemPromptKeys = systemPromptDialogKeyMap{
+	Save: key.NewBinding(
+		key.WithKeys("ctrl+s"),
+		key.WithHelp("ctrl+s", "save"),
+	),
+	Scroll: key.NewBinding(
+		key.WithKeys("pgup", "pgdown"),
+		key.WithHelp("pgup/pgdown", "scroll prompt"),
+	),
+	Escape: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "cancel"),
+	),
+}
+
+// SystemPromptDialogCmp shows the effective system prompt of a session and
+// lets the user edit the session specific additions.
+type SystemPromptDialogCmp struct {
+	width, height int
+	sessionID     string
+	basePrompt    string
+	base          viewport.Model
+	additions     textarea.Model
+}
+
+// NewSystemPromptDialogCmp creates a new SystemPromptDialogCmp
+func NewSystemPromptDialogCmp(sessionID, basePrompt, additions string) SystemPromptDialogCmp {
+	t := theme.CurrentTheme()
+
+	base := viewport.New(0, 0)
+	base.Style = lipgloss.NewStyle().Foreground(t.TextMuted()).Background(t.Background())
+
+	ta := textarea.New()
+	ta.Placeholder = "Add instructions for this session, e.g. we use tabs, never spaces"
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.FocusedStyle.Base = ta.FocusedStyle.Base.Background(t.Background())
+	ta.FocusedStyle.Text = ta.FocusedStyle.Text.Background(t.Background()).Foreground(t.Text())
+	ta.FocusedStyle.CursorLine = ta.FocusedStyle.CursorLine.Background(t.Background())
+	ta.FocusedStyle.Placeholder = ta.FocusedStyle.Placeholder.Background(t.Background()).Foreground(t.TextMuted())
+	ta.SetValue(additions)
+	ta.Focus()
+
+	return SystemPromptDialogCmp{
+		sessionID:  sessionID,
+		basePrompt: basePrompt,
+		base:       base,
+		additions:  ta,
+	}
+}
+
+// Init implements tea.Model.
+func (m SystemPromptDialogCmp) Init() tea.Cmd {
+	return textarea.Blink
+}
+
+// Update implements tea.Model.
+func (m SystemPromptDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, systemPromptKeys.Escape):
+			return m, util.CmdHandler(CloseSystemPromptDialogMsg{SessionID: m.sessionID})
+		case key.Matches(msg, systemPromptKeys.Save):
+			return m, util.CmdHandler(CloseSystemPromptDialogMsg{
+				Submit:    true,
+				SessionID: m.sessionID,
+				Additions: m.additions.Value(),
+			})
+		case key.Matches(msg, systemPromptKeys.Scroll):
+			var cmd tea.Cmd
+			m.base, cmd = m.base.Update(msg)
+			return m, cmd
+		}
+	case tea.WindowSizeMsg:
+		m.SetSize(msg.Width, msg.Height)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.additions, cmd = m.additions.Update(msg)
+	return m, cmd
+}
+
+// View implements tea.Model.
+func (m SystemPromptDialogCmp) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	width := m.base.Width
+
+	title := baseStyle.
+		Foreground(t.Primary()).
+		Bold(true).
+		Width(width).
+		Render("System Prompt")
+
+	label := func(text string) string {
+		return baseStyle.
+			Foreground(t.TextMuted()).
+			Width(width).
+			Padding(1, 0, 0, 0).
+			Render(text)
+	}
+
+	help := baseStyle.
+		Foreground(t.TextMuted()).
+		Width(width).
+		Padding(1, 0, 0, 0).
+		Render("ctrl+s save • pgup/pgdown scroll prompt • esc cancel")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		label("Agent prompt (read-only)"),
+		m.base.View(),
+		label("Session instructions (appended to the prompt)"),
+		m.additions.View(),
+		help,
+	)
+
+	return baseStyle.Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(t.Background()).
+		BorderForeground(t.TextMuted()).
+		Width(lipgloss.Width(content) + 4).
+		Render(content)
+}
+
+// SetSize sets the size of the component.
+func (m *SystemPromptDialogCmp) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+
+	contentWidth := max(40, min(100, width-12))
+	available := max(10, height-16)
+	m.base.Width = contentWidth
+	m.base.Height = available * 2 / 3
+	m.base.SetContent(lipgloss.NewStyle().Width(contentWidth).Render(m.basePrompt))
+	m.additions.SetWidth(contentWidth)
+	m.additions.SetHeight(available - m.base.Height)
+}
+
+// Bindings implements layout.Bindings.
+func (m SystemPromptDialogCmp) Bindings() []key.Binding {
+	return []key.Binding{systemPromptKeys.Save, systemPromptKeys.Scroll, systemPromptKeys.Escape}
+}
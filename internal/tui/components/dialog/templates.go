@@ -0,0 +1,178 @@
+package dialog
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kirmad/superopencode/internal/templates"
+	"github.com/kirmad/superopencode/internal/tui/layout"
+	"github.com/kirmad/superopencode/internal/tui/styles"
+	"github.com/kirmad/superopencode/internal/tui/theme"
+	"github.com/kirmad/superopencode/internal/tui/util"
+)
+
+// ShowTemplateDialogMsg is sent to pick the template of a new session
+type ShowTemplateDialogMsg struct {
+	Templates []templates.Template
+}
+
+// TemplateSelectedMsg is sent when the starting point of a new session is
+// picked. Template is nil for a blank session.
+type TemplateSelectedMsg struct {
+	Template *templates.Template
+}
+
+// CloseTemplateDialogMsg is sent when the template dialog is closed
+type CloseTemplateDialogMsg struct{}
+
+// TemplateDialog interface for the new session template picker
+type TemplateDialog interface {
+	tea.Model
+	layout.Bindings
+	SetTemplates(templates []templates.Template)
+}
+
+type templateDialogCmp struct {
+	templates   []templates.Template
+	selectedIdx int // 0 is the blank session
+	width       int
+	height      int
+}
+
+type templateKeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Enter  key.Binding
+	Escape key.Binding
+}
+
+var templateKeys = templateKeyMap{
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑", "previous template"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓", "next template"),
+	),
+	Enter: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "start session"),
+	),
+	Escape: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "close"),
+	),
+}
+
+func (t *templateDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (t *templateDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, templateKeys.Up):
+			if t.selectedIdx > 0 {
+				t.selectedIdx--
+			}
+			return t, nil
+		case key.Matches(msg, templateKeys.Down):
+			if t.selectedIdx < len(t.templates) {
+				t.selectedIdx++
+			}
+			return t, nil
+		case key.Matches(msg, templateKeys.Enter):
+			if t.selectedIdx == 0 {
+				return t, util.CmdHandler(TemplateSelectedMsg{})
+			}
+			selected := t.templates[t.selectedIdx-1]
+			return t, util.CmdHandler(TemplateSelectedMsg{Template: &selected})
+		case key.Matches(msg, templateKeys.Escape):
+			return t, util.CmdHandler(CloseTemplateDialogMsg{})
+		}
+	case tea.WindowSizeMsg:
+		t.width = msg.Width
+		t.height = msg.Height
+	}
+	return t, nil
+}
+
+func (t *templateDialogCmp) View() string {
+	th := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	type item struct{ name, summary string }
+	items := []item{{name: "Blank session"}}
+	for _, tmpl := range t.templates {
+		items = append(items, item{name: tmpl.Name, summary: tmpl.Summary()})
+	}
+
+	maxWidth := 40
+	for _, it := range items {
+		maxWidth = max(maxWidth, lipgloss.Width(it.name)+lipgloss.Width(it.summary)+6)
+	}
+	maxWidth = max(30, min(maxWidth, t.width-15))
+
+	// Keep the selection visible when there are many templates
+	maxVisible := min(10, len(items))
+	startIdx := 0
+	if t.selectedIdx >= maxVisible {
+		startIdx = t.selectedIdx - maxVisible + 1
+	}
+
+	rows := make([]string, 0, maxVisible)
+	for i := startIdx; i < min(startIdx+maxVisible, len(items)); i++ {
+		nameStyle := baseStyle.Padding(0, 1)
+		summaryStyle := baseStyle.Foreground(th.TextMuted())
+		if i == t.selectedIdx {
+			nameStyle = nameStyle.Background(th.Primary()).Foreground(th.Background()).Bold(true)
+			summaryStyle = summaryStyle.Background(th.Primary()).Foreground(th.Background())
+		}
+		name := nameStyle.Render(items[i].name)
+		summary := ""
+		if items[i].summary != "" {
+			summary = summaryStyle.Width(max(0, maxWidth-lipgloss.Width(name))).Render(items[i].summary)
+		} else {
+			name = nameStyle.Width(maxWidth).Render(items[i].name)
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Left, name, summary))
+	}
+
+	title := baseStyle.
+		Foreground(th.Primary()).
+		Bold(true).
+		Width(maxWidth).
+		Padding(0, 1).
+		Render("New Session")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		baseStyle.Width(maxWidth).Render(""),
+		baseStyle.Width(maxWidth).Render(lipgloss.JoinVertical(lipgloss.Left, rows...)),
+		baseStyle.Width(maxWidth).Render(""),
+	)
+
+	return baseStyle.Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderBackground(th.Background()).
+		BorderForeground(th.TextMuted()).
+		Width(lipgloss.Width(content) + 4).
+		Render(content)
+}
+
+func (t *templateDialogCmp) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(templateKeys)
+}
+
+func (t *templateDialogCmp) SetTemplates(templates []templates.Template) {
+	t.templates = templates
+	t.selectedIdx = 0
+}
+
+// NewTemplateDialogCmp creates a new template picker dialog
+func NewTemplateDialogCmp() TemplateDialog {
+	return &templateDialogCmp{}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/kirmad/superopencode/internal/locale"
 	"github.com/kirmad/superopencode/internal/logging"
 	"github.com/kirmad/superopencode/internal/tui/layout"
 	"github.com/kirmad/superopencode/internal/tui/styles"
@@ -58,7 +59,7 @@ func (i *detailCmp) updateContent() {
 
 	header := lipgloss.JoinHorizontal(
 		lipgloss.Center,
-		timeStyle.Render(i.currentLog.Time.Format(time.RFC3339)),
+		timeStyle.Render(i.currentLog.Time.In(locale.Location()).Format(time.RFC3339)),
 		"  ",
 		levelStyle.Render(i.currentLog.Level),
 	)
@@ -7,6 +7,7 @@ import (
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/table"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/kirmad/superopencode/internal/locale"
 	"github.com/kirmad/superopencode/internal/logging"
 	"github.com/kirmad/superopencode/internal/pubsub"
 	"github.com/kirmad/superopencode/internal/tui/layout"
@@ -108,7 +109,7 @@ func (i *tableCmp) setRows() {
 
 		row := table.Row{
 			log.ID,
-			log.Time.Format("15:04:05"),
+			log.Time.In(locale.Location()).Format("15:04:05"),
 			log.Level,
 			log.Message,
 			string(bm),
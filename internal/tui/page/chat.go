@@ -3,17 +3,30 @@ package page
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/kirmad/superopencode/internal/app"
 	"github.com/kirmad/superopencode/internal/completions"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/kirmad/superopencode/internal/llm/prompt"
+	"github.com/kirmad/superopencode/internal/llm/tools"
+	"github.com/kirmad/superopencode/internal/llm/tools/shell"
 	"github.com/kirmad/superopencode/internal/logging"
 	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/migration"
 	"github.com/kirmad/superopencode/internal/session"
+	"github.com/kirmad/superopencode/internal/templates"
+	"github.com/kirmad/superopencode/internal/tmux"
+	"github.com/kirmad/superopencode/internal/triage"
 	"github.com/kirmad/superopencode/internal/tui/components/chat"
 	"github.com/kirmad/superopencode/internal/tui/components/dialog"
 	"github.com/kirmad/superopencode/internal/tui/layout"
@@ -22,6 +35,10 @@ import (
 
 var ChatPage PageID = "chat"
 
+// mcpMentionTimeout bounds reading the MCP resources of a message and
+// getting an MCP prompt
+const mcpMentionTimeout = 30 * time.Second
+
 // slashNamedArgPattern is used to find named arguments in command content
 var slashNamedArgPattern = regexp.MustCompile(`\$([A-Z][A-Z0-9_]*)`)
 
@@ -31,24 +48,24 @@ type CommandSetter interface {
 }
 
 type chatPage struct {
-	app                        *app.App
-	editor                     layout.Container
-	messages                   layout.Container
-	layout                     layout.SplitPaneLayout
-	session                    session.Session
-	completionDialog           dialog.CompletionDialog
-	showCompletionDialog       bool
-	commands                   []dialog.Command // Commands for slash command processing
-	slashProcessor             *dialog.SlashCommandProcessor
-	slashSuggestionDialog      *dialog.SlashSuggestionDialog
-	showSlashSuggestions       bool
-	dangerouslySkipPermissions bool
+	app                   *app.App
+	editor                layout.Container
+	messages              layout.Container
+	layout                layout.SplitPaneLayout
+	session               session.Session
+	completionDialog      dialog.CompletionDialog
+	showCompletionDialog  bool
+	commands              []dialog.Command // Commands for slash command processing
+	slashProcessor        *dialog.SlashCommandProcessor
+	slashSuggestionDialog *dialog.SlashSuggestionDialog
+	showSlashSuggestions  bool
 }
 
 type ChatKeyMap struct {
 	ShowCompletionDialog key.Binding
 	NewSession           key.Binding
 	Cancel               key.Binding
+	Stop                 key.Binding
 }
 
 var keyMap = ChatKeyMap{
@@ -64,6 +81,10 @@ var keyMap = ChatKeyMap{
 		key.WithKeys("esc"),
 		key.WithHelp("esc", "cancel"),
 	),
+	Stop: key.NewBinding(
+		key.WithKeys("ctrl+x"),
+		key.WithHelp("ctrl+x", "stop after the current tool"),
+	),
 }
 
 func (p *chatPage) Init() tea.Cmd {
@@ -144,6 +165,174 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case dialog.ClearSessionMsg:
 		// Handle /clear command - clear messages from database and UI
 		return p, p.clearSessionAndMessages()
+	case dialog.PinContextMsg:
+		return p, p.pinContext(msg.Target)
+	case dialog.UnpinContextMsg:
+		return p, p.unpinContext(msg.Target)
+	case dialog.EditSystemPromptMsg:
+		return p, p.editSystemPrompt(msg.Append)
+	case dialog.SetLanguageMsg:
+		return p, p.setLanguage(msg.Language)
+	case dialog.SaveTemplateMsg:
+		return p, p.saveTemplate(msg.Name)
+	case dialog.SetLogLevelMsg:
+		return p, setLogLevel(msg.Args)
+	case dialog.ExportIssueMsg:
+		return p, p.exportIssue(msg.Args)
+	case dialog.PullRequestMsg:
+		return p, p.pullRequest(msg.Args)
+	case dialog.GenerateDocsMsg:
+		return p, p.generateDocs(msg.Args)
+	case dialog.GenerateTestsMsg:
+		return p, p.generateTests(msg.Target)
+	case dialog.TriageMsg:
+		return p, p.triage(msg.Trace)
+	case triageDoneMsg:
+		return p, p.sendMessage(msg.prompt, nil)
+	case dialog.LogsMsg:
+		question := strings.TrimSpace(msg.Question)
+		if question == "" {
+			return p, util.ReportWarn("Usage: /logs <question about the detailed logs>")
+		}
+		return p, p.sendMessage(agent.LogsPrompt(question, p.session.ID), nil)
+	case dialog.SecurityAuditMsg:
+		return p, p.securityAudit(msg.Scope)
+	case securityAuditDoneMsg:
+		if msg.sessionID != p.session.ID {
+			return p, nil
+		}
+		if app.NoFindings(msg.audit.Report) {
+			return p, util.ReportInfo("Security audit: no findings")
+		}
+		return p, p.sendMessage(app.SecurityAuditPrompt(msg.audit), nil)
+	case dialog.MigrateMsg:
+		return p, p.migrate(msg.Args)
+	case dialog.CheckpointMsg:
+		return p, p.checkpoint(msg.Message)
+	case dialog.CompareBranchMsg:
+		return p, p.compareBranch()
+	case dialog.CleanupBranchesMsg:
+		return p, p.cleanupBranches(msg.Args)
+	case dialog.SessionEnvMsg:
+		return p, p.sessionEnv(msg.Args)
+	case dialog.CodeLensMsg:
+		return p, p.codeLens(msg.Args)
+	case dialog.MCPStatusMsg:
+		return p, mcpStatus()
+	case dialog.TimelineMsg:
+		if p.session.ID == "" {
+			return p, util.ReportWarn("No active session")
+		}
+		return p, tea.Sequence(
+			util.CmdHandler(PageChangeMsg{ID: TimelinePage}),
+			util.CmdHandler(ShowTimelineMsg{SessionID: p.session.ID}),
+		)
+	case dialog.ContextInspectorMsg:
+		if p.session.ID == "" {
+			return p, util.ReportWarn("No active session")
+		}
+		return p, tea.Sequence(
+			util.CmdHandler(PageChangeMsg{ID: ContextPage}),
+			util.CmdHandler(ShowContextMsg{SessionID: p.session.ID}),
+		)
+	case dialog.RedactMsg:
+		if p.session.ID == "" {
+			return p, util.ReportWarn("No active session")
+		}
+		if msg.Text == "" {
+			return p, util.ReportWarn("Usage: /redact <text>")
+		}
+		changed, err := p.app.CoderAgent.Redact(context.Background(), p.session.ID, msg.Text)
+		if err != nil {
+			return p, util.ReportError(err)
+		}
+		return p, tea.Batch(
+			util.CmdHandler(chat.RedactInputMsg{Text: msg.Text}),
+			util.ReportInfo(fmt.Sprintf("Redacted %d messages, the next turns are sent without the text", changed)),
+		)
+	case dialog.TakeoverMsg:
+		if p.session.ID == "" {
+			return p, util.ReportWarn("No active session")
+		}
+		previous, held, err := p.app.TakeOver(p.session.ID)
+		if err != nil {
+			return p, util.ReportError(err)
+		}
+		if !held {
+			return p, util.ReportInfo("No other process drives the session, it's driven from here")
+		}
+		return p, util.ReportInfo(fmt.Sprintf("Took the session over from %s", previous))
+	case dialog.PostMortemMsg:
+		if p.session.ID == "" {
+			return p, util.ReportWarn("No active session")
+		}
+		var turn int
+		if msg.Turn != "" {
+			n, err := strconv.Atoi(strings.TrimSpace(msg.Turn))
+			if err != nil || n < 1 {
+				return p, util.ReportWarn("Usage: /postmortem [turn]")
+			}
+			turn = n
+		}
+		return p, tea.Sequence(
+			util.CmdHandler(PageChangeMsg{ID: PostMortemPage}),
+			util.CmdHandler(ShowPostMortemMsg{SessionID: p.session.ID, Turn: turn}),
+		)
+	case dialog.SessionDiffMsg:
+		if p.session.ID == "" {
+			return p, util.ReportWarn("No active session")
+		}
+		points := strings.Fields(msg.Args)
+		if len(points) > 2 {
+			return p, util.ReportWarn("Usage: /diff [from] [to]")
+		}
+		show := ShowSessionDiffMsg{SessionID: p.session.ID}
+		if len(points) > 0 {
+			show.From = points[0]
+		}
+		if len(points) > 1 {
+			show.To = points[1]
+		}
+		return p, tea.Sequence(
+			util.CmdHandler(PageChangeMsg{ID: SessionDiffPage}),
+			util.CmdHandler(show),
+		)
+	case dialog.TmuxCaptureMsg:
+		return p, captureTmuxPane(msg.Args)
+	case tmuxCaptureDoneMsg:
+		return p, tea.Batch(
+			util.CmdHandler(chat.ReplaceInputMsg{Text: msg.text}),
+			util.ReportInfo(fmt.Sprintf("Captured %d lines of pane %s, add your question and send", msg.lines, msg.target)),
+		)
+	case dialog.MCPPromptMsg:
+		return p, mcpPrompt(msg.Server, msg.Name, msg.Args)
+	case mcpPromptDoneMsg:
+		return p, p.sendMessage(msg.prompt, nil)
+	case codeLensDoneMsg:
+		if msg.sessionID != p.session.ID {
+			return p, nil
+		}
+		item, err := prompt.Pin(msg.sessionID, msg.output)
+		if err != nil {
+			return p, util.ReportError(err)
+		}
+		logging.InfoPersist(msg.output)
+		return p, util.ReportInfo(fmt.Sprintf("Pinned the result of the code lens as #%d (~%d tokens)", item.ID, item.Tokens))
+	case dialog.SecondOpinionMsg:
+		return p, p.secondOpinion(msg.Focus)
+	case secondOpinionDoneMsg:
+		if msg.sessionID != p.session.ID {
+			return p, nil
+		}
+		if app.NoFindings(msg.review) {
+			return p, util.ReportInfo("Second opinion: no findings")
+		}
+		return p, p.sendMessage(app.SecondOpinionPrompt(msg.review), nil)
+	case dialog.TemplateSelectedMsg:
+		if msg.Template == nil {
+			return p, p.clearSessionAndMessages()
+		}
+		return p, p.startFromTemplate(*msg.Template)
 	case chat.SessionSelectedMsg:
 		if p.session.ID == "" {
 			cmd := p.setSidebar()
@@ -151,6 +340,12 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmds = append(cmds, cmd)
 			}
 		}
+		if msg.ID != p.session.ID {
+			cmds = append(cmds, p.enterSessionBranch(msg.ID))
+			if p.app.Settings.Get().SkipPermissions {
+				p.app.Permissions.AutoApproveSession(msg.ID)
+			}
+		}
 		p.session = msg
 	case tea.KeyMsg:
 		switch {
@@ -158,7 +353,7 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			p.showCompletionDialog = true
 			// Continue sending keys to layout->chat
 		case key.Matches(msg, keyMap.NewSession):
-			return p, p.clearSessionAndMessages()
+			return p, p.newSession()
 		case key.Matches(msg, keyMap.Cancel):
 			if p.session.ID != "" {
 				// Cancel the current session's generation process
@@ -166,6 +361,12 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				p.app.CoderAgent.Cancel(p.session.ID)
 				return p, nil
 			}
+		case key.Matches(msg, keyMap.Stop):
+			// Unlike a cancel, the running tool finishes and the agent
+			// reports where it stopped
+			if p.session.ID != "" && p.app.CoderAgent.Stop(p.session.ID) {
+				return p, util.ReportInfo("Stopping after the current tool, the agent will report where it stopped")
+			}
 		}
 	}
 	if p.showCompletionDialog {
@@ -251,8 +452,8 @@ func (p *chatPage) sendMessage(text string, attachments []message.Attachment) te
 			return util.ReportError(err)
 		}
 
-		// Auto-approve permissions if dangerous flag is set
-		if p.dangerouslySkipPermissions {
+		// Auto-approve permissions if they are skipped
+		if p.app.Settings.Get().SkipPermissions {
 			logging.Warn("⚠️ DANGEROUS: --dangerously-skip-permissions active. All tool permissions bypassed for interactive session %s", session.ID)
 			p.app.Permissions.AutoApproveSession(session.ID)
 		}
@@ -262,9 +463,27 @@ func (p *chatPage) sendMessage(text string, attachments []message.Attachment) te
 		if cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+		// The branch is checked out before the agent starts changing files
+		cmds = append(cmds, p.enterSessionBranch(session.ID))
 		cmds = append(cmds, util.CmdHandler(chat.SessionSelectedMsg(session)))
 	}
 
+	// Mentioned MCP resources are read from their servers before the message
+	// is sent
+	if strings.Contains(text, "@mcp:") {
+		sessionID := p.session.ID
+		cmds = append(cmds, func() tea.Msg {
+			ctx, cancel := context.WithTimeout(context.Background(), mcpMentionTimeout)
+			defer cancel()
+			expanded := agent.ExpandMCPMentions(ctx, text)
+			if _, err := p.app.CoderAgent.Run(context.Background(), sessionID, expanded, attachments...); err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			return nil
+		})
+		return tea.Batch(cmds...)
+	}
+
 	_, err := p.app.CoderAgent.Run(context.Background(), p.session.ID, text, attachments...)
 	if err != nil {
 		return util.ReportError(err)
@@ -298,6 +517,14 @@ func (p *chatPage) handleSlashCommand(text string, attachments []message.Attachm
 		return util.ReportError(fmt.Errorf("%s", errorMsg))
 	}
 
+	// Built-in commands and MCP prompts are handled by the application instead
+	// of the agent
+	if command := result.Processed.Command; (strings.HasPrefix(command.ID, dialog.BuiltinCommandPrefix) || strings.HasPrefix(command.ID, dialog.MCPCommandPrefix)) && command.Handler != nil {
+		builtin := *command
+		builtin.Args = result.Processed.RemainingText
+		return builtin.Handler(builtin)
+	}
+
 	// If the command needs arguments dialog, show it
 	if result.NeedsArgDialog {
 		// Extract argument names from the command content
@@ -325,6 +552,807 @@ func (p *chatPage) handleSlashCommand(text string, attachments []message.Attachm
 	return p.sendMessage(result.Processed.Content, attachments)
 }
 
+// pinContext pins a file or snippet to the current session
+func (p *chatPage) pinContext(target string) tea.Cmd {
+	if p.session.ID == "" {
+		return util.ReportWarn("Start a session before pinning context")
+	}
+	item, err := prompt.Pin(p.session.ID, target)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	return util.ReportInfo(fmt.Sprintf("Pinned #%d %s (~%d tokens)", item.ID, item.Label(), item.Tokens))
+}
+
+// unpinContext removes pinned context from the current session
+func (p *chatPage) unpinContext(target string) tea.Cmd {
+	if p.session.ID == "" {
+		return util.ReportWarn("No active session")
+	}
+	removed, err := prompt.Unpin(p.session.ID, target)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	if len(removed) == 1 {
+		return util.ReportInfo(fmt.Sprintf("Unpinned #%d %s", removed[0].ID, removed[0].Label()))
+	}
+	return util.ReportInfo(fmt.Sprintf("Unpinned %d items", len(removed)))
+}
+
+// exportIssue drafts an issue from the session in the background, and files
+// it on GitHub when the arguments start with --file
+func (p *chatPage) exportIssue(args string) tea.Cmd {
+	if p.session.ID == "" {
+		return util.ReportWarn("Start a session before exporting an issue")
+	}
+	notes, file := strings.CutPrefix(strings.TrimSpace(args), "--file")
+	notes = strings.TrimSpace(notes)
+	sessionID := p.session.ID
+	return tea.Batch(
+		util.ReportInfo("Drafting an issue from the session..."),
+		func() tea.Msg {
+			draft, err := p.app.ExportIssue(context.Background(), sessionID, notes, file)
+			switch {
+			case err != nil && draft.Path != "":
+				return util.InfoMsg{Type: util.InfoTypeWarn, Msg: fmt.Sprintf("Issue draft saved to %s, filing failed: %v", draft.Path, err)}
+			case err != nil:
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("Issue export failed: %v", err)}
+			case draft.URL != "":
+				return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "Issue filed: " + draft.URL, TTL: 30 * time.Second}
+			}
+			return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "Issue draft saved to " + draft.Path, TTL: 30 * time.Second}
+		},
+	)
+}
+
+// pullRequest drafts a pull request from the changes of the session in the
+// background, and pushes the branch and opens it on GitHub when the arguments
+// start with --open
+func (p *chatPage) pullRequest(args string) tea.Cmd {
+	if p.session.ID == "" {
+		return util.ReportWarn("Start a session before drafting a pull request")
+	}
+	notes, open := strings.CutPrefix(strings.TrimSpace(args), "--open")
+	notes = strings.TrimSpace(notes)
+	sessionID := p.session.ID
+	return tea.Batch(
+		util.ReportInfo("Drafting a pull request from the session..."),
+		func() tea.Msg {
+			draft, err := p.app.DraftPullRequest(context.Background(), sessionID, notes, open)
+			switch {
+			case err != nil && draft.Path != "":
+				return util.InfoMsg{Type: util.InfoTypeWarn, Msg: fmt.Sprintf("Pull request draft saved to %s, opening failed: %v", draft.Path, err)}
+			case err != nil:
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("Pull request draft failed: %v", err)}
+			case draft.URL != "":
+				return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "Pull request opened: " + draft.URL, TTL: 30 * time.Second}
+			}
+			return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "Pull request draft saved to " + draft.Path, TTL: 30 * time.Second}
+		},
+	)
+}
+
+// generateDocs asks the coder agent to document a package with the
+// generate_docs tool
+func (p *chatPage) generateDocs(args string) tea.Cmd {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 || (len(fields) == 2 && fields[1] != tools.DocsFormatMarkdown) {
+		return util.ReportWarn("Usage: /docs <package dir> [markdown]")
+	}
+	format := tools.DocsFormatComments
+	if len(fields) == 2 {
+		format = tools.DocsFormatMarkdown
+	}
+	return p.sendMessage(fmt.Sprintf("Document the package in %s: run the %s tool with format %q and follow its instructions.", fields[0], tools.GenerateDocsToolName, format), nil)
+}
+
+// generateTests runs the test generation workflow in the background and
+// reports the coverage gain once it is done
+func (p *chatPage) generateTests(target string) tea.Cmd {
+	if p.session.ID == "" {
+		return util.ReportWarn("Start a session before generating tests")
+	}
+	sessionID := p.session.ID
+	target = strings.TrimSpace(target)
+	return tea.Batch(
+		util.ReportInfo("Measuring test coverage..."),
+		func() tea.Msg {
+			report, err := p.app.GenerateTests(context.Background(), sessionID, target)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("Test generation failed: %v", err)}
+			}
+			logging.InfoPersist(report.String())
+			return nil
+		},
+	)
+}
+
+// triageDoneMsg carries the prompt built from a stack trace
+type triageDoneMsg struct {
+	prompt string
+}
+
+// triage maps the frames of a stack trace or log to the workspace in the
+// background and asks the agent for the root cause
+func (p *chatPage) triage(trace string) tea.Cmd {
+	trace = strings.TrimSpace(trace)
+	if trace == "" {
+		return util.ReportWarn("Usage: /triage <pasted stack trace or log file>")
+	}
+	return func() tea.Msg {
+		wd := config.WorkingDirectory()
+		if !strings.Contains(trace, "\n") {
+			path := trace
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(wd, path)
+			}
+			if content, err := os.ReadFile(path); err == nil {
+				trace = string(content)
+			}
+		}
+		frames := triage.Resolve(triage.Parse(trace), wd)
+		return triageDoneMsg{prompt: triage.Prompt(trace, frames, wd)}
+	}
+}
+
+// secondOpinionDoneMsg carries the findings of a finished review
+type secondOpinionDoneMsg struct {
+	sessionID string
+	review    string
+}
+
+// secondOpinion runs the reviewer agent over the pending changes in the
+// background, its findings are sent to the coder once it is done
+func (p *chatPage) secondOpinion(focus string) tea.Cmd {
+	if p.session.ID == "" {
+		return util.ReportWarn("Start a session before asking for a second opinion")
+	}
+	if p.app.CoderAgent.IsBusy() {
+		return util.ReportWarn("Agent is busy, please wait before asking for a second opinion...")
+	}
+	sessionID := p.session.ID
+	return tea.Batch(
+		util.ReportInfo("Asking a reviewer for a second opinion..."),
+		func() tea.Msg {
+			review, err := p.app.SecondOpinion(context.Background(), sessionID, focus)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("Second opinion failed: %v", err)}
+			}
+			return secondOpinionDoneMsg{sessionID: sessionID, review: review}
+		},
+	)
+}
+
+// securityAuditDoneMsg carries the outcome of a finished security audit
+type securityAuditDoneMsg struct {
+	sessionID string
+	audit     app.SecurityAudit
+}
+
+// securityAudit runs the security agent in the background, its findings are
+// handed to the coder once it is done
+func (p *chatPage) securityAudit(scope string) tea.Cmd {
+	if p.session.ID == "" {
+		return util.ReportWarn("Start a session before running a security audit")
+	}
+	if p.app.CoderAgent.IsBusy() {
+		return util.ReportWarn("Agent is busy, please wait before running a security audit...")
+	}
+	sessionID := p.session.ID
+	scope = strings.TrimSpace(scope)
+	return tea.Batch(
+		util.ReportInfo("Running a security audit..."),
+		func() tea.Msg {
+			audit, err := p.app.SecurityAudit(context.Background(), sessionID, scope)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("Security audit failed: %v", err)}
+			}
+			return securityAuditDoneMsg{sessionID: sessionID, audit: audit}
+		},
+	)
+}
+
+// migrate plans a migration, or resumes one, and runs its batches in the
+// background. Without arguments it lists the migrations.
+func (p *chatPage) migrate(args string) tea.Cmd {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		plans, err := migration.List(app.MigrationsDir())
+		if err != nil {
+			return util.ReportError(err)
+		}
+		if len(plans) == 0 {
+			return util.ReportInfo("No migrations, usage: /migrate <glob> <instructions>")
+		}
+		lines := make([]string, len(plans))
+		for i, plan := range plans {
+			lines[i] = plan.String()
+		}
+		logging.InfoPersist("Migrations:\n" + strings.Join(lines, "\n"))
+		return nil
+	}
+	if p.session.ID == "" {
+		return util.ReportWarn("Start a session before running a migration")
+	}
+	sessionID := p.session.ID
+
+	var id string
+	switch {
+	case fields[0] == "resume" && len(fields) == 2:
+		id = fields[1]
+	case len(fields) < 2:
+		return util.ReportWarn("Usage: /migrate <glob> <instructions> or /migrate resume <id>")
+	default:
+		pattern := fields[0]
+		instructions := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(args), pattern))
+		plan, err := p.app.PlanMigration(sessionID, pattern, instructions)
+		if err != nil {
+			return util.ReportError(err)
+		}
+		id = plan.ID
+	}
+
+	return tea.Batch(
+		util.ReportInfo(fmt.Sprintf("Running migration %s...", id)),
+		func() tea.Msg {
+			plan, err := p.app.RunMigration(context.Background(), sessionID, id)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("Migration %s failed: %v", id, err)}
+			}
+			report := fmt.Sprintf("Migration %s", plan)
+			if !plan.Finished() {
+				report += fmt.Sprintf(", resume it with /migrate resume %s", plan.ID)
+			}
+			logging.InfoPersist(report)
+			return nil
+		},
+	)
+}
+
+// enterSessionBranch checks out the branch of a session when session
+// branches are enabled. The branch stays while the agent works, so its
+// changes don't move to another branch.
+func (p *chatPage) enterSessionBranch(sessionID string) tea.Cmd {
+	if !app.SessionBranchesEnabled() {
+		return nil
+	}
+	if p.app.CoderAgent.IsBusy() {
+		return util.ReportWarn("Agent is busy, staying on the current branch")
+	}
+	branch, err := p.app.EnterSessionBranch(context.Background(), sessionID)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	return util.ReportInfo("Working on branch " + branch)
+}
+
+// checkpoint commits the changes of the session to its branch, or saves,
+// restores and lists the named checkpoints of the session
+func (p *chatPage) checkpoint(args string) tea.Cmd {
+	fields := strings.Fields(args)
+	if len(fields) == 1 && fields[0] == "list" {
+		return p.listCheckpoints()
+	}
+	if p.app.CoderAgent.IsBusy() {
+		return util.ReportWarn("Agent is busy, please wait before using a checkpoint...")
+	}
+	if len(fields) == 2 && (fields[0] == "save" || fields[0] == "restore") {
+		if fields[0] == "save" {
+			saved, err := p.app.SaveCheckpoint(context.Background(), p.session.ID, fields[1])
+			if err != nil {
+				return util.ReportError(err)
+			}
+			return util.ReportInfo("Saved checkpoint " + saved.String())
+		}
+		restored, err := p.app.RestoreCheckpoint(context.Background(), p.session.ID, fields[1])
+		if err != nil {
+			return util.ReportError(err)
+		}
+		if len(restored) == 0 {
+			return util.ReportInfo("The workspace is already at checkpoint " + fields[1])
+		}
+		logging.InfoPersist(fmt.Sprintf("Restored checkpoint %s:\n%s", fields[1], strings.Join(restored, "\n")))
+		return util.ReportInfo(fmt.Sprintf("Restored checkpoint %s, %d files changed", fields[1], len(restored)))
+	}
+
+	commit, err := p.app.CheckpointSession(context.Background(), p.session.ID, strings.TrimSpace(args))
+	if err != nil {
+		return util.ReportError(err)
+	}
+	return util.ReportInfo("Committed checkpoint " + commit)
+}
+
+// listCheckpoints shows the named checkpoints of the session
+func (p *chatPage) listCheckpoints() tea.Cmd {
+	checkpoints, err := p.app.ListCheckpoints(p.session.ID)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	if len(checkpoints) == 0 {
+		return util.ReportInfo("No checkpoints, usage: /checkpoint save <name>")
+	}
+	lines := make([]string, len(checkpoints))
+	for i, checkpoint := range checkpoints {
+		lines[i] = checkpoint.String()
+	}
+	logging.InfoPersist("Checkpoints:\n" + strings.Join(lines, "\n"))
+	return nil
+}
+
+// compareBranch shows how the session branch differs from its base branch
+func (p *chatPage) compareBranch() tea.Cmd {
+	summary, err := p.app.CompareSessionBranch(context.Background(), p.session.ID)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	logging.InfoPersist(summary)
+	return nil
+}
+
+// cleanupBranches deletes the session branches of deleted sessions, and of
+// sessions idle for the given number of days
+func (p *chatPage) cleanupBranches(args string) tea.Cmd {
+	var idle time.Duration
+	if args = strings.TrimSpace(args); args != "" {
+		days, err := strconv.Atoi(args)
+		if err != nil || days <= 0 {
+			return util.ReportWarn("Usage: /cleanup-branches [idle days]")
+		}
+		idle = time.Duration(days) * 24 * time.Hour
+	}
+	deleted, kept, err := p.app.CleanupSessionBranches(context.Background(), idle)
+	if len(deleted) > 0 {
+		logging.InfoPersist("Deleted session branches:\n" + strings.Join(deleted, "\n"))
+	}
+	if len(kept) > 0 {
+		logging.WarnPersist("Kept session branches with commits not merged into their base branch, merge or delete them with git:\n" + strings.Join(kept, "\n"))
+	}
+	if err != nil {
+		return util.ReportError(err)
+	}
+	if len(deleted) == 0 && len(kept) == 0 {
+		return util.ReportInfo("No abandoned session branches")
+	}
+	if len(kept) > 0 {
+		return util.ReportWarn(fmt.Sprintf("Deleted %d session branches, kept %d with unmerged commits", len(deleted), len(kept)))
+	}
+	return util.ReportInfo(fmt.Sprintf("Deleted %d session branches", len(deleted)))
+}
+
+// sessionEnv lists, sets or unsets the variables injected into the bash
+// commands of the current session. Values are never shown unmasked.
+func (p *chatPage) sessionEnv(args string) tea.Cmd {
+	if p.session.ID == "" {
+		return util.ReportWarn("Start a session before setting variables")
+	}
+	action, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	switch action {
+	case "":
+		vars := shell.SessionEnv(p.session.ID)
+		if len(vars) == 0 {
+			return util.ReportInfo("No variables, set one with /env set KEY=VALUE")
+		}
+		lines := make([]string, len(vars))
+		for i, v := range vars {
+			source := "config"
+			if v.Session {
+				source = "session"
+			}
+			lines[i] = fmt.Sprintf("%s=%s (%s)", v.Name, v.Masked(), source)
+		}
+		logging.InfoPersist("Session variables:\n" + strings.Join(lines, "\n"))
+		return nil
+	case "set":
+		name, value, err := shell.ParseEnvAssignment(rest)
+		if err != nil {
+			return util.ReportWarn("Usage: /env set KEY=VALUE: " + err.Error())
+		}
+		if err := shell.SetSessionEnv(p.session.ID, name, value); err != nil {
+			return util.ReportError(err)
+		}
+		return util.ReportInfo("Set " + name + " for the bash commands of this session")
+	case "unset":
+		name := strings.TrimSpace(rest)
+		if !shell.UnsetSessionEnv(p.session.ID, name) {
+			return util.ReportWarn(name + " is not set for this session")
+		}
+		return util.ReportInfo("Unset " + name)
+	default:
+		return util.ReportWarn("Usage: /env [set KEY=VALUE | unset KEY]")
+	}
+}
+
+// codeLensDoneMsg carries the outcome of a code lens run with /lens
+type codeLensDoneMsg struct {
+	sessionID string
+	output    string
+}
+
+// codeLens lists the code lenses of a file, or runs one of them in the
+// background and pins its result to the session so the agent sees it
+func (p *chatPage) codeLens(args string) tea.Cmd {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		return util.ReportWarn("Usage: /lens <file> [number]")
+	}
+	path := fields[0]
+	if len(fields) == 1 {
+		return func() tea.Msg {
+			lenses, err := p.app.CodeLenses(context.Background(), path)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("Code lenses failed: %v", err)}
+			}
+			if len(lenses) == 0 {
+				return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "No code lenses in " + path}
+			}
+			lines := make([]string, len(lenses))
+			for i, lens := range lenses {
+				lines[i] = fmt.Sprintf("%d. %s (%s)", i+1, lens, lens.Client)
+			}
+			logging.InfoPersist("Code lenses, run one with /lens " + path + " <number>:\n" + strings.Join(lines, "\n"))
+			return nil
+		}
+	}
+
+	if p.session.ID == "" {
+		return util.ReportWarn("Start a session before running a code lens")
+	}
+	n, err := strconv.Atoi(fields[1])
+	if err != nil || n <= 0 {
+		return util.ReportWarn("Usage: /lens <file> [number]")
+	}
+	sessionID := p.session.ID
+	return tea.Batch(
+		util.ReportInfo("Running the code lens..."),
+		func() tea.Msg {
+			ctx := context.Background()
+			lenses, err := p.app.CodeLenses(ctx, path)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("Code lenses failed: %v", err)}
+			}
+			if n > len(lenses) {
+				return util.InfoMsg{Type: util.InfoTypeWarn, Msg: fmt.Sprintf("%s has %d code lenses", path, len(lenses))}
+			}
+			output, err := p.app.RunCodeLens(ctx, lenses[n-1])
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("Code lens failed: %v", err)}
+			}
+			return codeLensDoneMsg{sessionID: sessionID, output: output}
+		},
+	)
+}
+
+// Lines of scrollback captured by /tmux
+const (
+	defaultTmuxLines = 200
+	maxTmuxLines     = 5000
+)
+
+type tmuxCaptureDoneMsg struct {
+	target string
+	lines  int
+	text   string // The prompt quoting the scrollback
+}
+
+// captureTmuxPane captures the scrollback of a tmux pane, the previously
+// active one by default, into the editor
+func captureTmuxPane(args string) tea.Cmd {
+	if !tmux.Inside() {
+		return util.ReportWarn("OpenCode doesn't run inside tmux")
+	}
+	target, lines := "{last}", defaultTmuxLines
+	fields := strings.Fields(args)
+	if len(fields) > 2 {
+		return util.ReportWarn("Usage: /tmux [pane] [lines]")
+	}
+	if len(fields) > 0 {
+		// A single number is the number of lines of the previous pane
+		if n, err := strconv.Atoi(fields[len(fields)-1]); err == nil {
+			if n <= 0 {
+				return util.ReportWarn("Usage: /tmux [pane] [lines]")
+			}
+			lines = min(n, maxTmuxLines)
+			fields = fields[:len(fields)-1]
+		} else if len(fields) == 2 {
+			return util.ReportWarn("Usage: /tmux [pane] [lines]")
+		}
+		if len(fields) == 1 {
+			target = fields[0]
+		}
+	}
+
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		output, err := tmux.CapturePane(ctx, target, lines)
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("Capturing pane %s failed: %v", target, err)}
+		}
+		if output == "" {
+			return util.InfoMsg{Type: util.InfoTypeWarn, Msg: fmt.Sprintf("Pane %s is empty", target)}
+		}
+		fence := "```"
+		for strings.Contains(output, fence) {
+			fence += "`"
+		}
+		captured := strings.Count(output, "\n") + 1
+		text := fmt.Sprintf("Output of tmux pane %s (last %d lines):\n%s\n%s\n%s\n\n", target, captured, fence, output, fence)
+		return tmuxCaptureDoneMsg{target: target, lines: captured, text: text}
+	}
+}
+
+// mcpStatus checks the MCP servers in the background and shows their status
+func mcpStatus() tea.Cmd {
+	if len(config.Get().MCPServers) == 0 {
+		return util.ReportInfo("No MCP servers configured")
+	}
+	return tea.Batch(
+		util.ReportInfo("Checking the MCP servers..."),
+		func() tea.Msg {
+			statuses := agent.CheckMCPServers(context.Background())
+			lines := make([]string, len(statuses))
+			for i, status := range statuses {
+				lines[i] = status.String()
+			}
+			logging.InfoPersist("MCP servers:\n" + strings.Join(lines, "\n"))
+			return nil
+		},
+	)
+}
+
+// mcpPromptDoneMsg carries a rendered MCP prompt, it is sent to the agent
+type mcpPromptDoneMsg struct {
+	prompt string
+}
+
+// mcpPrompt renders a prompt of an MCP server in the background. The
+// arguments are name=value pairs, the whole text is the value of a prompt
+// with a single argument.
+func mcpPrompt(server, name, args string) tea.Cmd {
+	var found *agent.MCPPrompt
+	for _, p := range agent.MCPPrompts() {
+		if p.Server == server && p.Name == name {
+			found = &p
+			break
+		}
+	}
+	if found == nil {
+		return util.ReportError(fmt.Errorf("MCP server %s has no prompt %s", server, name))
+	}
+
+	values := parseMCPPromptArgs(*found, args)
+	for _, arg := range found.Arguments {
+		if arg.Required && values[arg.Name] == "" {
+			return util.ReportWarn(fmt.Sprintf("Missing argument %s, usage: /%s:%s %s", arg.Name, server, name, found.Usage()))
+		}
+	}
+	return tea.Batch(
+		util.ReportInfo(fmt.Sprintf("Getting the prompt %s from %s...", name, server)),
+		func() tea.Msg {
+			ctx, cancel := context.WithTimeout(context.Background(), mcpMentionTimeout)
+			defer cancel()
+			text, err := agent.GetMCPPrompt(ctx, server, name, values)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("MCP prompt failed: %v", err)}
+			}
+			return mcpPromptDoneMsg{prompt: text}
+		},
+	)
+}
+
+// parseMCPPromptArgs reads the name=value pairs of the arguments of a prompt
+func parseMCPPromptArgs(p agent.MCPPrompt, args string) map[string]string {
+	values := make(map[string]string)
+	args = strings.TrimSpace(args)
+	if args == "" {
+		return values
+	}
+	if len(p.Arguments) == 1 && !strings.HasPrefix(args, p.Arguments[0].Name+"=") {
+		values[p.Arguments[0].Name] = args
+		return values
+	}
+	for _, field := range strings.Fields(args) {
+		if key, value, ok := strings.Cut(field, "="); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// setLogLevel changes the default log level or the level of a module, or
+// shows the current levels without arguments
+func setLogLevel(args string) tea.Cmd {
+	fields := strings.Fields(args)
+	var module, name string
+	switch len(fields) {
+	case 0:
+		return util.ReportInfo("Log level: " + logging.FormatLevels())
+	case 1:
+		name = fields[0]
+	case 2:
+		module, name = fields[0], fields[1]
+	default:
+		return util.ReportWarn("Usage: /loglevel [module] <debug|info|warn|error|reset>")
+	}
+
+	if name == "reset" && module != "" {
+		logging.ResetLevel(module)
+		return util.ReportInfo("Log level: " + logging.FormatLevels())
+	}
+	level, err := logging.ParseLevel(name)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	logging.SetLevel(module, level)
+	return util.ReportInfo("Log level: " + logging.FormatLevels())
+}
+
+// editSystemPrompt opens the system prompt editor of the current session, or
+// appends text to the session instructions directly
+func (p *chatPage) editSystemPrompt(text string) tea.Cmd {
+	if p.session.ID == "" {
+		return util.ReportWarn("Start a session before editing its system prompt")
+	}
+	sess, err := p.app.Sessions.Get(context.Background(), p.session.ID)
+	if err != nil {
+		return util.ReportError(err)
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return util.CmdHandler(dialog.ShowSystemPromptDialogMsg{
+			SessionID:  sess.ID,
+			BasePrompt: prompt.GetAgentPrompt(config.AgentCoder, p.app.CoderAgent.Model().Provider),
+			Additions:  sess.SystemPrompt,
+		})
+	}
+
+	if sess.SystemPrompt != "" {
+		sess.SystemPrompt += "\n"
+	}
+	sess.SystemPrompt += text
+	if _, err := p.app.Sessions.Save(context.Background(), sess); err != nil {
+		return util.ReportError(err)
+	}
+	return util.ReportInfo("Added to the session instructions")
+}
+
+// setLanguage shows or sets the language the agents answer in the current
+// session
+func (p *chatPage) setLanguage(language string) tea.Cmd {
+	if p.session.ID == "" {
+		return util.ReportWarn("Start a session before setting its language")
+	}
+	sess, err := p.app.Sessions.Get(context.Background(), p.session.ID)
+	if err != nil {
+		return util.ReportError(err)
+	}
+
+	language = strings.TrimSpace(language)
+	switch language {
+	case "":
+		if sess.Language != "" {
+			return util.ReportInfo("Response language: " + prompt.LanguageName(sess.Language))
+		}
+		if language := config.Get().Locale.Language; language != "" {
+			return util.ReportInfo("Response language: " + prompt.LanguageName(language) + " (config)")
+		}
+		return util.ReportInfo("No response language, the agents answer in the language of your messages")
+	case "reset":
+		sess.Language = ""
+	default:
+		sess.Language = language
+	}
+	if _, err := p.app.Sessions.Save(context.Background(), sess); err != nil {
+		return util.ReportError(err)
+	}
+	if sess.Language == "" {
+		return util.ReportInfo("Reset the response language of this session")
+	}
+	return util.ReportInfo("The agents now answer in " + prompt.LanguageName(sess.Language) + ", code stays in English")
+}
+
+// newSession starts a new session, letting the user pick a template first
+// when templates are available
+func (p *chatPage) newSession() tea.Cmd {
+	list, err := templates.List()
+	if err != nil {
+		logging.Warn("Failed to load session templates", "error", err)
+	}
+	if len(list) == 0 {
+		return p.clearSessionAndMessages()
+	}
+	return util.CmdHandler(dialog.ShowTemplateDialogMsg{Templates: list})
+}
+
+// startFromTemplate creates a new session configured by a template
+func (p *chatPage) startFromTemplate(t templates.Template) tea.Cmd {
+	if p.app.CoderAgent.IsBusy() {
+		return util.ReportWarn("Agent is busy, please wait before starting a new session...")
+	}
+	ctx := context.Background()
+
+	sess, err := p.app.Sessions.Create(ctx, "New Session")
+	if err != nil {
+		return util.ReportError(err)
+	}
+	if t.SystemPrompt != "" || t.Language != "" {
+		sess.SystemPrompt = t.SystemPrompt
+		sess.Language = t.Language
+		if sess, err = p.app.Sessions.Save(ctx, sess); err != nil {
+			return util.ReportError(err)
+		}
+	}
+	if p.app.Settings.Get().SkipPermissions {
+		p.app.Permissions.AutoApproveSession(sess.ID)
+	}
+
+	var problems []string
+	for _, target := range append(append([]string{}, t.PinnedFiles...), t.PinnedSnippets...) {
+		if _, err := prompt.Pin(sess.ID, target); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	agent.SetSessionTools(sess.ID, t.Tools)
+	if t.Model != "" && t.Model != p.app.CoderAgent.Model().ID {
+		if _, err := p.app.CoderAgent.Update(config.AgentCoder, t.Model); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	report := util.ReportInfo(fmt.Sprintf("Started session from template %s", t.Name))
+	if len(problems) > 0 {
+		report = util.ReportWarn(fmt.Sprintf("Started session from template %s: %s", t.Name, strings.Join(problems, "; ")))
+	}
+	return tea.Batch(
+		util.CmdHandler(chat.SessionSelectedMsg(sess)),
+		report,
+	)
+}
+
+// saveTemplate saves the setup of the current session as a template
+func (p *chatPage) saveTemplate(name string) tea.Cmd {
+	name = strings.TrimSpace(name)
+	if p.session.ID == "" {
+		return util.ReportWarn("Start a session before saving it as a template")
+	}
+	if name == "" {
+		return util.ReportWarn("Usage: /save-template <name>")
+	}
+	if err := templates.ValidateName(name); err != nil {
+		return util.ReportError(err)
+	}
+	sess, err := p.app.Sessions.Get(context.Background(), p.session.ID)
+	if err != nil {
+		return util.ReportError(err)
+	}
+
+	t := templates.Template{
+		Name:         name,
+		Description:  sess.Title,
+		SystemPrompt: sess.SystemPrompt,
+		Language:     sess.Language,
+		Tools:        agent.SessionTools(sess.ID),
+		Model:        p.app.CoderAgent.Model().ID,
+	}
+	for _, pin := range prompt.ListPins(sess.ID) {
+		switch pin.Kind {
+		case prompt.PinKindFile:
+			path := pin.Path
+			if rel, err := filepath.Rel(config.WorkingDirectory(), path); err == nil && !strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+			t.PinnedFiles = append(t.PinnedFiles, path)
+		default:
+			t.PinnedSnippets = append(t.PinnedSnippets, pin.Content)
+		}
+	}
+	if err := templates.Save(t); err != nil {
+		return util.ReportError(err)
+	}
+	return util.ReportInfo(fmt.Sprintf("Saved template %s", name))
+}
+
 func (p *chatPage) SetSize(width, height int) tea.Cmd {
 	return p.layout.SetSize(width, height)
 }
@@ -381,8 +1409,11 @@ func (p *chatPage) BindingKeys() []key.Binding {
 	return bindings
 }
 
-func NewChatPage(app *app.App, dangerouslySkipPermissions bool) tea.Model {
-	cg := completions.NewFileAndFolderContextGroup()
+func NewChatPage(app *app.App) tea.Model {
+	cg := completions.NewContextGroups(
+		completions.NewMCPResourcesContextGroup(),
+		completions.NewFileAndFolderContextGroup(),
+	)
 	completionDialog := dialog.NewCompletionDialogCmp(cg)
 
 	messagesContainer := layout.NewContainer(
@@ -394,13 +1425,12 @@ func NewChatPage(app *app.App, dangerouslySkipPermissions bool) tea.Model {
 		layout.WithBorder(true, false, false, false),
 	)
 	return &chatPage{
-		app:                        app,
-		editor:                     editorContainer,
-		messages:                   messagesContainer,
-		completionDialog:           completionDialog,
-		commands:                   nil, // Will be set later via SetCommands
-		slashProcessor:             nil, // Will be created when commands are set
-		dangerouslySkipPermissions: dangerouslySkipPermissions,
+		app:              app,
+		editor:           editorContainer,
+		messages:         messagesContainer,
+		completionDialog: completionDialog,
+		commands:         nil, // Will be set later via SetCommands
+		slashProcessor:   nil, // Will be created when commands are set
 		layout: layout.NewSplitPane(
 			layout.WithLeftPanel(messagesContainer),
 			layout.WithBottomPanel(editorContainer),
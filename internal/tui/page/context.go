@@ -0,0 +1,277 @@
+package page
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kirmad/superopencode/internal/app"
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/kirmad/superopencode/internal/tui/layout"
+	"github.com/kirmad/superopencode/internal/tui/styles"
+	"github.com/kirmad/superopencode/internal/tui/theme"
+	"github.com/kirmad/superopencode/internal/tui/util"
+)
+
+var ContextPage PageID = "context"
+
+// ShowContextMsg shows what the next turn of a session sends to the model
+type ShowContextMsg struct {
+	SessionID string
+}
+
+var contextKindTitles = map[agent.ContextItemKind]string{
+	agent.ContextSystem:       "System",
+	agent.ContextProject:      "Project",
+	agent.ContextInstructions: "Session",
+	agent.ContextTool:         "Tool",
+	agent.ContextPin:          "Pinned",
+	agent.ContextSummary:      "Summary",
+	agent.ContextMessage:      "Message",
+	agent.ContextToolOutput:   "Tool output",
+}
+
+type ContextKeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Drop   key.Binding
+	Sort   key.Binding
+	Reload key.Binding
+}
+
+var contextKeys = ContextKeyMap{
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "previous item"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "next item"),
+	),
+	Drop: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "drop item"),
+	),
+	Sort: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "sort by size"),
+	),
+	Reload: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "reload"),
+	),
+}
+
+type ContextPageModel interface {
+	tea.Model
+	layout.Sizeable
+	layout.Bindings
+}
+
+type contextPage struct {
+	app           *app.App
+	width, height int
+	sessionID     string
+	report        agent.ContextReport
+	items         []agent.ContextItem // The items of the report as shown
+	bySize        bool
+	selected      int
+	offset        int // First item shown
+	err           error
+}
+
+func (p *contextPage) Init() tea.Cmd {
+	return nil
+}
+
+func (p *contextPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		return p, p.SetSize(msg.Width, msg.Height)
+	case ShowContextMsg:
+		p.sessionID = msg.SessionID
+		p.selected, p.offset = 0, 0
+		p.reload()
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, contextKeys.Up):
+			if p.selected > 0 {
+				p.selected--
+			}
+		case key.Matches(msg, contextKeys.Down):
+			if p.selected < len(p.items)-1 {
+				p.selected++
+			}
+		case key.Matches(msg, contextKeys.Sort):
+			p.bySize = !p.bySize
+			p.selected, p.offset = 0, 0
+			p.arrange()
+		case key.Matches(msg, contextKeys.Reload):
+			p.reload()
+		case key.Matches(msg, contextKeys.Drop):
+			return p, p.dropSelected()
+		}
+	}
+	return p, nil
+}
+
+// reload inspects the context of the session again
+func (p *contextPage) reload() {
+	if p.sessionID == "" {
+		return
+	}
+	p.report, p.err = p.app.CoderAgent.InspectContext(context.Background(), p.sessionID)
+	p.arrange()
+}
+
+// arrange orders the items of the report, the largest first when sorted by
+// size, and keeps the selection in range
+func (p *contextPage) arrange() {
+	p.items = slices.Clone(p.report.Items)
+	if p.bySize {
+		slices.SortStableFunc(p.items, func(a, b agent.ContextItem) int {
+			return cmp.Compare(b.Tokens, a.Tokens)
+		})
+	}
+	p.selected = max(0, min(p.selected, len(p.items)-1))
+}
+
+// dropSelected removes the selected item from the context of the session
+func (p *contextPage) dropSelected() tea.Cmd {
+	if p.selected >= len(p.items) {
+		return nil
+	}
+	item := p.items[p.selected]
+	if item.Ref == "" {
+		return util.ReportWarn(fmt.Sprintf("%s items can't be dropped", contextKindTitles[item.Kind]))
+	}
+	if err := p.app.CoderAgent.DropContext(context.Background(), p.sessionID, item.Ref); err != nil {
+		return util.ReportError(err)
+	}
+	p.reload()
+	return util.ReportInfo(fmt.Sprintf("Dropped %s (~%d tokens) from the context", item.Label, item.Tokens))
+}
+
+func (p *contextPage) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	mutedStyle := baseStyle.Foreground(t.TextMuted())
+	innerWidth := p.width - 2
+
+	title := baseStyle.Foreground(t.Primary()).Bold(true).Width(innerWidth).Render("Context of the Next Turn")
+	rows := []string{title}
+	switch {
+	case p.err != nil:
+		rows = append(rows, baseStyle.Foreground(t.Error()).Width(innerWidth).Render(p.err.Error()))
+	case len(p.items) == 0:
+		rows = append(rows, mutedStyle.Width(innerWidth).Render("No session selected"))
+	default:
+		rows = append(rows, p.renderSummary(innerWidth), "")
+		rows = append(rows, p.renderItems(innerWidth)...)
+	}
+
+	border := baseStyle.
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderNormal()).
+		BorderBackground(t.Background())
+
+	return baseStyle.Width(p.width).Height(p.height).Render(
+		border.Width(innerWidth).Height(p.height - 2).Render(lipgloss.JoinVertical(lipgloss.Left, rows...)),
+	)
+}
+
+// renderSummary shows the size of the prompt against the context window of
+// the model, with the tokens reserved for the response
+func (p *contextPage) renderSummary(width int) string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	report := p.report
+
+	line := fmt.Sprintf("~%d tokens with %s", report.Total, report.Model.Name)
+	window := report.Model.ContextWindow
+	if window <= 0 {
+		return baseStyle.Width(width).Render(line)
+	}
+	line += fmt.Sprintf(", %d%% of the %d token window, %d reserved for the response", report.Total*100/window, window, report.Reserved)
+
+	barWidth := max(10, width-2)
+	used := int(min(int64(barWidth), report.Total*int64(barWidth)/window))
+	reserved := int(min(int64(barWidth-used), report.Reserved*int64(barWidth)/window))
+	color := t.Success()
+	if report.Total+report.Reserved > window*9/10 {
+		color = t.Warning()
+	}
+	bar := lipgloss.JoinHorizontal(lipgloss.Left,
+		baseStyle.Foreground(color).Render(strings.Repeat("█", used)),
+		baseStyle.Foreground(t.TextMuted()).Render(strings.Repeat("▒", reserved)),
+		baseStyle.Foreground(t.TextMuted()).Render(strings.Repeat("░", barWidth-used-reserved)),
+	)
+	return lipgloss.JoinVertical(lipgloss.Left, baseStyle.Width(width).Render(line), bar)
+}
+
+// renderItems lists the items around the selection that fit the page
+func (p *contextPage) renderItems(width int) []string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	visible := p.listHeight()
+	if p.selected < p.offset {
+		p.offset = p.selected
+	} else if p.selected >= p.offset+visible {
+		p.offset = p.selected - visible + 1
+	}
+	p.offset = max(0, min(p.offset, len(p.items)-visible))
+
+	var rows []string
+	for i := p.offset; i < min(len(p.items), p.offset+visible); i++ {
+		item := p.items[i]
+		share := 0.0
+		if p.report.Total > 0 {
+			share = float64(item.Tokens) * 100 / float64(p.report.Total)
+		}
+		info := fmt.Sprintf(" %-12s %7d %5.1f%%  ", contextKindTitles[item.Kind], item.Tokens, share)
+		style := baseStyle
+		if item.Ref == "" {
+			style = baseStyle.Foreground(t.TextMuted())
+		}
+		row := lipgloss.JoinHorizontal(lipgloss.Left,
+			style.Render(info),
+			style.Width(max(0, width-lipgloss.Width(info))).MaxHeight(1).Render(item.Label),
+		)
+		if i == p.selected {
+			row = baseStyle.Background(t.BackgroundSecondary()).Width(width).Render(row)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// listHeight is the number of items that fit below the title and summary
+func (p *contextPage) listHeight() int {
+	return max(1, p.height-7)
+}
+
+func (p *contextPage) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(contextKeys)
+}
+
+// GetSize implements ContextPageModel.
+func (p *contextPage) GetSize() (int, int) {
+	return p.width, p.height
+}
+
+// SetSize implements ContextPageModel.
+func (p *contextPage) SetSize(width int, height int) tea.Cmd {
+	p.width = width
+	p.height = height
+	return nil
+}
+
+func NewContextPage(app *app.App) ContextPageModel {
+	return &contextPage{app: app}
+}
@@ -0,0 +1,235 @@
+package page
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kirmad/superopencode/internal/app"
+	"github.com/kirmad/superopencode/internal/metrics"
+	"github.com/kirmad/superopencode/internal/tui/layout"
+	"github.com/kirmad/superopencode/internal/tui/styles"
+	"github.com/kirmad/superopencode/internal/tui/theme"
+	"github.com/kirmad/superopencode/internal/tui/util"
+)
+
+var MetricsPage PageID = "metrics"
+
+// ShowMetricsMsg reloads the metrics dashboard
+type ShowMetricsMsg struct{}
+
+const (
+	defaultMetricsWeeks = 8
+	maxMetricsWeeks     = 52
+	successBarWidth     = 10
+)
+
+type MetricsKeyMap struct {
+	MoreWeeks  key.Binding
+	LessWeeks  key.Binding
+	Reload     key.Binding
+	ScrollDown key.Binding
+	ScrollUp   key.Binding
+}
+
+var metricsKeys = MetricsKeyMap{
+	MoreWeeks: key.NewBinding(
+		key.WithKeys("+", "="),
+		key.WithHelp("+", "more weeks"),
+	),
+	LessWeeks: key.NewBinding(
+		key.WithKeys("-"),
+		key.WithHelp("-", "fewer weeks"),
+	),
+	Reload: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "reload"),
+	),
+	ScrollDown: key.NewBinding(
+		key.WithKeys("down", "j", "pgdown"),
+		key.WithHelp("↓/j", "scroll down"),
+	),
+	ScrollUp: key.NewBinding(
+		key.WithKeys("up", "k", "pgup"),
+		key.WithHelp("↑/k", "scroll up"),
+	),
+}
+
+type MetricPage interface {
+	tea.Model
+	layout.Sizeable
+	layout.Bindings
+}
+
+type metricsPage struct {
+	app           *app.App
+	width, height int
+	weeks         int
+	trends        []metrics.Trend
+	table         viewport.Model
+}
+
+func (p *metricsPage) Init() tea.Cmd {
+	return p.reload()
+}
+
+func (p *metricsPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		return p, p.SetSize(msg.Width, msg.Height)
+	case ShowMetricsMsg:
+		return p, p.reload()
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, metricsKeys.MoreWeeks):
+			p.weeks = min(maxMetricsWeeks, p.weeks+1)
+			return p, p.reload()
+		case key.Matches(msg, metricsKeys.LessWeeks):
+			p.weeks = max(1, p.weeks-1)
+			return p, p.reload()
+		case key.Matches(msg, metricsKeys.Reload):
+			return p, p.reload()
+		case key.Matches(msg, metricsKeys.ScrollDown), key.Matches(msg, metricsKeys.ScrollUp):
+			var cmd tea.Cmd
+			p.table, cmd = p.table.Update(msg)
+			return p, cmd
+		}
+	}
+	return p, nil
+}
+
+func (p *metricsPage) reload() tea.Cmd {
+	trends, err := p.app.Metrics.Trends(context.Background(), p.weeks)
+	if err != nil {
+		return util.ReportError(fmt.Errorf("failed to read task metrics: %w", err))
+	}
+	p.trends = trends
+	p.renderTable()
+	return nil
+}
+
+// renderTable lists the weekly trends of every subagent type with the change
+// against the previous week of the same type
+func (p *metricsPage) renderTable() {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	width := max(10, p.table.Width)
+
+	if len(p.trends) == 0 {
+		p.table.SetContent(baseStyle.Foreground(t.TextMuted()).Width(width).
+			Render(fmt.Sprintf("No tasks recorded in the last %d weeks", p.weeks)))
+		return
+	}
+
+	rows := []string{baseStyle.Foreground(t.TextMuted()).Bold(true).Width(width).Render(
+		fmt.Sprintf("%-9s %-10s %5s  %-15s %-16s %s", "WEEK", "SUBAGENT", "RUNS", "SUCCESS", "AVG DURATION", "COST/RUN"))}
+
+	previous := make(map[string]metrics.Trend)
+	for _, trend := range p.trends {
+		prev, hasPrev := previous[trend.Subagent]
+		previous[trend.Subagent] = trend
+
+		successStyle := baseStyle.Foreground(t.Success())
+		switch rate := trend.SuccessRate(); {
+		case rate < 0.5:
+			successStyle = baseStyle.Foreground(t.Error())
+		case rate < 0.8:
+			successStyle = baseStyle.Foreground(t.Warning())
+		}
+
+		duration := fmt.Sprintf("%-7s", trend.AvgDuration.Round(time.Second))
+		cost := fmt.Sprintf("$%.4f", trend.CostPerRun())
+		success := fmt.Sprintf("%s %3.0f%%", successBar(trend.SuccessRate()), trend.SuccessRate()*100)
+		if hasPrev {
+			success += " " + p.change(trend.SuccessRate()-prev.SuccessRate(), true)
+			duration += " " + p.change(float64(trend.AvgDuration-prev.AvgDuration), false)
+			cost += " " + p.change(trend.CostPerRun()-prev.CostPerRun(), false)
+		}
+
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Left,
+			baseStyle.Render(fmt.Sprintf("%-9s %-10s %5d  ", trend.Week, trend.Subagent, trend.Runs)),
+			successStyle.Width(16).Render(success),
+			baseStyle.Width(17).Render(duration),
+			baseStyle.Render(cost),
+		))
+	}
+	p.table.SetContent(lipgloss.JoinVertical(lipgloss.Left, rows...))
+}
+
+// change renders the direction of a change against the previous week,
+// colored by whether the change is an improvement
+func (p *metricsPage) change(delta float64, higherIsBetter bool) string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	switch {
+	case delta == 0:
+		return baseStyle.Foreground(t.TextMuted()).Render("=")
+	case (delta > 0) == higherIsBetter:
+		return baseStyle.Foreground(t.Success()).Render(arrow(delta))
+	default:
+		return baseStyle.Foreground(t.Error()).Render(arrow(delta))
+	}
+}
+
+func arrow(delta float64) string {
+	if delta > 0 {
+		return "↑"
+	}
+	return "↓"
+}
+
+// successBar renders a success rate as a bar
+func successBar(rate float64) string {
+	filled := int(rate*successBarWidth + 0.5)
+	return strings.Repeat("█", filled) + strings.Repeat("░", successBarWidth-filled)
+}
+
+func (p *metricsPage) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	innerWidth := p.width - 2
+
+	title := baseStyle.Foreground(t.Primary()).Bold(true).Width(innerWidth).
+		Render(fmt.Sprintf("Agent Metrics · last %d weeks", p.weeks))
+
+	border := baseStyle.
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderNormal()).
+		BorderBackground(t.Background())
+
+	return baseStyle.Width(p.width).Height(p.height).Render(
+		border.Width(innerWidth).Render(lipgloss.JoinVertical(lipgloss.Left, title, p.table.View())),
+	)
+}
+
+func (p *metricsPage) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(metricsKeys)
+}
+
+// GetSize implements MetricPage.
+func (p *metricsPage) GetSize() (int, int) {
+	return p.width, p.height
+}
+
+// SetSize implements MetricPage.
+func (p *metricsPage) SetSize(width int, height int) tea.Cmd {
+	p.width = width
+	p.height = height
+	p.table.Width = max(10, width-2)
+	p.table.Height = max(3, height-3)
+	p.renderTable()
+	return nil
+}
+
+func NewMetricsPage(app *app.App) MetricPage {
+	return &metricsPage{
+		app:   app,
+		weeks: defaultMetricsWeeks,
+		table: viewport.New(0, 0),
+	}
+}
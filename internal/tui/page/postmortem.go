@@ -0,0 +1,361 @@
+package page
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kirmad/superopencode/internal/app"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/locale"
+	"github.com/kirmad/superopencode/internal/tui/layout"
+	"github.com/kirmad/superopencode/internal/tui/styles"
+	"github.com/kirmad/superopencode/internal/tui/theme"
+	"github.com/kirmad/superopencode/internal/tui/util"
+)
+
+var PostMortemPage PageID = "postmortem"
+
+// ShowPostMortemMsg shows what a turn of a session changed, the last turn
+// when Turn is 0
+type ShowPostMortemMsg struct {
+	SessionID string
+	Turn      int
+}
+
+type PostMortemKeyMap struct {
+	Up         key.Binding
+	Down       key.Binding
+	Toggle     key.Binding
+	ToggleAll  key.Binding
+	Rollback   key.Binding
+	PostMortem key.Binding
+	Previous   key.Binding
+	Next       key.Binding
+}
+
+var postMortemKeys = PostMortemKeyMap{
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "previous file"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "next file"),
+	),
+	Toggle: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "select file"),
+	),
+	ToggleAll: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "select all files"),
+	),
+	Rollback: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "roll back and write post-mortem"),
+	),
+	PostMortem: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "write post-mortem only"),
+	),
+	Previous: key.NewBinding(
+		key.WithKeys("["),
+		key.WithHelp("[", "previous turn"),
+	),
+	Next: key.NewBinding(
+		key.WithKeys("]"),
+		key.WithHelp("]", "next turn"),
+	),
+}
+
+type PostMortemPageModel interface {
+	tea.Model
+	layout.Sizeable
+	layout.Bindings
+}
+
+type postMortemPage struct {
+	app           *app.App
+	width, height int
+	report        app.TurnReport
+	selected      map[string]bool // Paths of the files to roll back
+	cursor        int
+	offset        int  // First file shown
+	confirming    bool // Rollback asked to overwrite the files changed since the turn
+	err           error
+}
+
+func (p *postMortemPage) Init() tea.Cmd {
+	return nil
+}
+
+func (p *postMortemPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		return p, p.SetSize(msg.Width, msg.Height)
+	case ShowPostMortemMsg:
+		p.load(msg.SessionID, msg.Turn)
+	case tea.KeyMsg:
+		files := p.report.Files
+		if !key.Matches(msg, postMortemKeys.Rollback) {
+			p.confirming = false
+		}
+		switch {
+		case key.Matches(msg, postMortemKeys.Up):
+			if p.cursor > 0 {
+				p.cursor--
+			}
+		case key.Matches(msg, postMortemKeys.Down):
+			if p.cursor < len(files)-1 {
+				p.cursor++
+			}
+		case key.Matches(msg, postMortemKeys.Toggle):
+			if p.cursor < len(files) {
+				path := files[p.cursor].Path
+				p.selected[path] = !p.selected[path]
+			}
+		case key.Matches(msg, postMortemKeys.ToggleAll):
+			all := !slices.ContainsFunc(files, func(f app.FileChange) bool { return !p.selected[f.Path] })
+			for _, file := range files {
+				p.selected[file.Path] = !all
+			}
+		case key.Matches(msg, postMortemKeys.Previous):
+			if p.report.Turn > 1 {
+				p.load(p.report.SessionID, p.report.Turn-1)
+			}
+		case key.Matches(msg, postMortemKeys.Next):
+			if p.report.Turn < p.report.Turns {
+				p.load(p.report.SessionID, p.report.Turn+1)
+			}
+		case key.Matches(msg, postMortemKeys.Rollback):
+			return p, p.rollback()
+		case key.Matches(msg, postMortemKeys.PostMortem):
+			return p, p.writePostMortem(nil)
+		}
+	}
+	return p, nil
+}
+
+// load reports a turn of a session, with the files not changed since the
+// turn selected for rollback
+func (p *postMortemPage) load(sessionID string, turn int) {
+	p.report, p.err = p.app.ReportTurn(context.Background(), sessionID, turn)
+	p.report.SessionID = sessionID
+	p.selected = make(map[string]bool)
+	for _, file := range p.report.Files {
+		p.selected[file.Path] = !file.Changed
+	}
+	p.cursor, p.offset = 0, 0
+	p.confirming = false
+}
+
+// rollback restores the selected files and writes the post-mortem of the
+// turn. The selected files changed since the turn are only overwritten once
+// the rollback is confirmed by pressing enter again.
+func (p *postMortemPage) rollback() tea.Cmd {
+	var paths, changed []string
+	for _, file := range p.report.Files {
+		if p.selected[file.Path] {
+			paths = append(paths, file.Path)
+			if file.Changed {
+				changed = append(changed, file.Path)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return util.ReportWarn("Select the files to roll back, or press m to only write the post-mortem")
+	}
+	if len(changed) > 0 && !p.confirming {
+		p.confirming = true
+		return util.ReportWarn(fmt.Sprintf("%d selected files changed since the turn, press enter again to overwrite the changes", len(changed)))
+	}
+	p.confirming = false
+	report := p.report
+	rolledBack, err := p.app.RollbackTurn(context.Background(), report, paths, changed)
+	p.load(report.SessionID, report.Turn)
+	if err != nil {
+		return tea.Batch(util.ReportError(fmt.Errorf("rolled back %d of %d files: %w", len(rolledBack), len(paths), err)), p.writePostMortem(&report, rolledBack...))
+	}
+	return p.writePostMortem(&report, rolledBack...)
+}
+
+// writePostMortem has the agent summarize what went wrong in the background
+// and appends it to the project memory file
+func (p *postMortemPage) writePostMortem(report *app.TurnReport, rolledBack ...string) tea.Cmd {
+	if report == nil {
+		report = &p.report
+	}
+	if report.SessionID == "" || p.err != nil {
+		return nil
+	}
+	turn := *report
+	progress := fmt.Sprintf("Writing the post-mortem of turn %d...", turn.Turn)
+	if len(rolledBack) > 0 {
+		progress = fmt.Sprintf("Rolled back %d files, writing the post-mortem of turn %d...", len(rolledBack), turn.Turn)
+	}
+	return tea.Batch(
+		util.ReportInfo(progress),
+		func() tea.Msg {
+			_, path, err := p.app.WritePostMortem(context.Background(), turn, rolledBack)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("Post-mortem failed: %v", err)}
+			}
+			return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "Post-mortem added to " + path, TTL: 30 * time.Second}
+		},
+	)
+}
+
+func (p *postMortemPage) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	mutedStyle := baseStyle.Foreground(t.TextMuted())
+	innerWidth := p.width - 2
+
+	title := "Post-mortem"
+	if p.report.Turn > 0 {
+		title = fmt.Sprintf("Post-mortem of Turn %d of %d, %s", p.report.Turn, p.report.Turns, locale.DateTime(p.report.Time))
+	}
+	rows := []string{baseStyle.Foreground(t.Primary()).Bold(true).Width(innerWidth).Render(title)}
+	switch {
+	case p.err != nil:
+		rows = append(rows, baseStyle.Foreground(t.Error()).Width(innerWidth).Render(p.err.Error()))
+	case p.report.Turn == 0:
+		rows = append(rows, mutedStyle.Width(innerWidth).Render("No session selected"))
+	default:
+		rows = append(rows, mutedStyle.Width(innerWidth).MaxHeight(2).Render("Asked: "+p.report.Prompt), "")
+		rows = append(rows, p.renderFiles(innerWidth)...)
+		rows = append(rows, "")
+		rows = append(rows, p.renderCommands(innerWidth)...)
+	}
+
+	border := baseStyle.
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderNormal()).
+		BorderBackground(t.Background())
+
+	return baseStyle.Width(p.width).Height(p.height).Render(
+		border.Width(innerWidth).Height(p.height - 2).Render(lipgloss.JoinVertical(lipgloss.Left, rows...)),
+	)
+}
+
+// renderFiles lists the files changed by the turn around the cursor, with
+// the ones selected for rollback checked
+func (p *postMortemPage) renderFiles(width int) []string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	files := p.report.Files
+	if len(files) == 0 {
+		return []string{baseStyle.Foreground(t.TextMuted()).Width(width).Render("The turn changed no files")}
+	}
+
+	visible := p.listHeight()
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	} else if p.cursor >= p.offset+visible {
+		p.offset = p.cursor - visible + 1
+	}
+	p.offset = max(0, min(p.offset, len(files)-visible))
+
+	wd := config.WorkingDirectory()
+	rows := []string{baseStyle.Bold(true).Width(width).Render(fmt.Sprintf("Files changed (%d)", len(files)))}
+	for i := p.offset; i < min(len(files), p.offset+visible); i++ {
+		file := files[i]
+		check := "[ ]"
+		if p.selected[file.Path] {
+			check = "[x]"
+		}
+		path, err := filepath.Rel(wd, file.Path)
+		if err != nil {
+			path = file.Path
+		}
+		row := lipgloss.JoinHorizontal(lipgloss.Left,
+			baseStyle.Render(fmt.Sprintf(" %s ", check)),
+			baseStyle.Foreground(t.Success()).Render(fmt.Sprintf("+%-4d", file.Additions)),
+			baseStyle.Foreground(t.Error()).Render(fmt.Sprintf("-%-4d ", file.Removals)),
+			baseStyle.Render(path),
+		)
+		var notes []string
+		if file.Created {
+			notes = append(notes, "created, removed on rollback")
+		}
+		if file.Changed {
+			notes = append(notes, "changed since the turn, rollback loses the changes")
+		}
+		if len(notes) > 0 {
+			row = lipgloss.JoinHorizontal(lipgloss.Left, row, baseStyle.Foreground(t.Warning()).Render(fmt.Sprintf("  (%s)", strings.Join(notes, "; "))))
+		}
+		row = baseStyle.Width(width).MaxHeight(1).Render(row)
+		if i == p.cursor {
+			row = baseStyle.Background(t.BackgroundSecondary()).Width(width).Render(row)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// renderCommands lists the commands run by the turn, which can't be rolled
+// back
+func (p *postMortemPage) renderCommands(width int) []string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	mutedStyle := baseStyle.Foreground(t.TextMuted())
+	commands := p.report.Commands
+	if len(commands) == 0 {
+		return []string{mutedStyle.Width(width).Render("The turn ran no commands")}
+	}
+
+	rows := []string{
+		baseStyle.Bold(true).Width(width).Render(fmt.Sprintf("Commands run (%d)", len(commands))),
+		mutedStyle.Width(width).Render("Commands can't be rolled back, undo their effects by hand"),
+	}
+	for _, command := range commands[:min(len(commands), commandRows)] {
+		status := baseStyle.Foreground(t.Success()).Render(" ✓ ")
+		if command.Failed {
+			status = baseStyle.Foreground(t.Error()).Render(" ✗ ")
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Left,
+			status,
+			baseStyle.Width(max(0, width-3)).MaxHeight(1).Render(command.Command),
+		))
+	}
+	if len(commands) > commandRows {
+		rows = append(rows, mutedStyle.Width(width).Render(fmt.Sprintf(" and %d more", len(commands)-commandRows)))
+	}
+	return rows
+}
+
+// commandRows is the number of commands listed
+const commandRows = 8
+
+// listHeight is the number of files that fit above the commands
+func (p *postMortemPage) listHeight() int {
+	commands := min(len(p.report.Commands), commandRows) + 3
+	return max(1, p.height-9-commands)
+}
+
+func (p *postMortemPage) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(postMortemKeys)
+}
+
+// GetSize implements PostMortemPageModel.
+func (p *postMortemPage) GetSize() (int, int) {
+	return p.width, p.height
+}
+
+// SetSize implements PostMortemPageModel.
+func (p *postMortemPage) SetSize(width int, height int) tea.Cmd {
+	p.width = width
+	p.height = height
+	return nil
+}
+
+func NewPostMortemPage(app *app.App) PostMortemPageModel {
+	return &postMortemPage{app: app, selected: make(map[string]bool)}
+}
@@ -0,0 +1,346 @@
+package page
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kirmad/superopencode/internal/app"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/diff"
+	"github.com/kirmad/superopencode/internal/locale"
+	"github.com/kirmad/superopencode/internal/tui/layout"
+	"github.com/kirmad/superopencode/internal/tui/styles"
+	"github.com/kirmad/superopencode/internal/tui/theme"
+	"github.com/kirmad/superopencode/internal/tui/util"
+)
+
+var SessionDiffPage PageID = "sessiondiff"
+
+// ShowSessionDiffMsg shows the changes of a session between two of its
+// points, from its start to its end when they are empty
+type ShowSessionDiffMsg struct {
+	SessionID string
+	From      string
+	To        string
+}
+
+// sessionDiffPointsWidth is the width of the list of points
+const sessionDiffPointsWidth = 36
+
+type SessionDiffKeyMap struct {
+	Up         key.Binding
+	Down       key.Binding
+	From       key.Binding
+	To         key.Binding
+	ScrollDown key.Binding
+	ScrollUp   key.Binding
+	Export     key.Binding
+	Reload     key.Binding
+}
+
+var sessionDiffKeys = SessionDiffKeyMap{
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "previous point"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "next point"),
+	),
+	From: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "compare from the point"),
+	),
+	To: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "compare to the point"),
+	),
+	ScrollDown: key.NewBinding(
+		key.WithKeys("pgdown", "ctrl+d"),
+		key.WithHelp("pgdn", "scroll diff down"),
+	),
+	ScrollUp: key.NewBinding(
+		key.WithKeys("pgup", "ctrl+u"),
+		key.WithHelp("pgup", "scroll diff up"),
+	),
+	Export: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "export patch"),
+	),
+	Reload: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "reload"),
+	),
+}
+
+type SessionDiffPageModel interface {
+	tea.Model
+	layout.Sizeable
+	layout.Bindings
+}
+
+type sessionDiffPage struct {
+	app           *app.App
+	width, height int
+	sessionID     string
+	points        []app.SessionPoint
+	from, to      int // Points compared
+	cursor        int
+	changes       []app.FileChange
+	content       viewport.Model
+	err           error
+}
+
+func (p *sessionDiffPage) Init() tea.Cmd {
+	return nil
+}
+
+func (p *sessionDiffPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		return p, p.SetSize(msg.Width, msg.Height)
+	case ShowSessionDiffMsg:
+		return p, p.load(msg.SessionID, msg.From, msg.To)
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, sessionDiffKeys.Up):
+			if p.cursor > 0 {
+				p.cursor--
+			}
+		case key.Matches(msg, sessionDiffKeys.Down):
+			if p.cursor < len(p.points)-1 {
+				p.cursor++
+			}
+		case key.Matches(msg, sessionDiffKeys.From):
+			if p.cursor < len(p.points) {
+				p.from = p.cursor
+				p.compare()
+			}
+		case key.Matches(msg, sessionDiffKeys.To):
+			if p.cursor < len(p.points) {
+				p.to = p.cursor
+				p.compare()
+			}
+		case key.Matches(msg, sessionDiffKeys.ScrollDown), key.Matches(msg, sessionDiffKeys.ScrollUp):
+			var cmd tea.Cmd
+			p.content, cmd = p.content.Update(msg)
+			return p, cmd
+		case key.Matches(msg, sessionDiffKeys.Export):
+			return p, p.export()
+		case key.Matches(msg, sessionDiffKeys.Reload):
+			if len(p.points) > 0 {
+				return p, p.load(p.sessionID, p.points[p.from].Name, p.points[p.to].Name)
+			}
+		}
+	}
+	return p, nil
+}
+
+// load lists the points of a session and compares the named ones
+func (p *sessionDiffPage) load(sessionID, from, to string) tea.Cmd {
+	p.sessionID = sessionID
+	p.points, p.err = p.app.SessionPoints(context.Background(), sessionID)
+	p.from, p.to, p.cursor = 0, 0, 0
+	if p.err != nil {
+		p.changes = nil
+		p.render()
+		return nil
+	}
+
+	var cmd tea.Cmd
+	find := func(name, fallback string) int {
+		point, err := app.FindSessionPoint(p.points, name)
+		if err != nil {
+			cmd = util.ReportWarn(err.Error())
+			point, _ = app.FindSessionPoint(p.points, fallback)
+		}
+		return max(0, slices.IndexFunc(p.points, func(other app.SessionPoint) bool { return other.Name == point.Name }))
+	}
+	p.from = find(cmp.Or(from, app.StartPoint), app.StartPoint)
+	p.to = find(cmp.Or(to, app.EndPoint), app.EndPoint)
+	p.cursor = p.to
+	p.compare()
+	return cmd
+}
+
+// compare diffs the files of the session between the selected points
+func (p *sessionDiffPage) compare() {
+	p.changes, p.err = p.app.DiffSessionPoints(context.Background(), p.sessionID, p.points[p.from], p.points[p.to])
+	p.content.GotoTop()
+	p.render()
+}
+
+// export writes the changes between the selected points as a patch file
+func (p *sessionDiffPage) export() tea.Cmd {
+	if len(p.points) == 0 {
+		return nil
+	}
+	path, err := app.ExportPatch(p.changes, p.points[p.from], p.points[p.to])
+	if err != nil {
+		return util.ReportWarn(err.Error())
+	}
+	return util.CmdHandler(util.InfoMsg{
+		Type: util.InfoTypeInfo,
+		Msg:  fmt.Sprintf("Patch of %d files written to %s, apply it with git apply", len(p.changes), path),
+		TTL:  30 * time.Second,
+	})
+}
+
+// render draws the diff of every changed file in the viewport
+func (p *sessionDiffPage) render() {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	mutedStyle := baseStyle.Foreground(t.TextMuted())
+	width := max(10, p.content.Width)
+
+	switch {
+	case p.err != nil:
+		p.content.SetContent(baseStyle.Foreground(t.Error()).Width(width).Render(p.err.Error()))
+		return
+	case len(p.points) == 0:
+		p.content.SetContent(mutedStyle.Width(width).Render("No session selected"))
+		return
+	case len(p.changes) == 0:
+		p.content.SetContent(mutedStyle.Width(width).Render("No files changed between the two points"))
+		return
+	}
+
+	wd := config.WorkingDirectory()
+	var rows []string
+	for _, change := range p.changes {
+		path, err := filepath.Rel(wd, change.Path)
+		if err != nil {
+			path = change.Path
+		}
+		header := lipgloss.JoinHorizontal(lipgloss.Left,
+			baseStyle.Bold(true).Render(path+" "),
+			baseStyle.Foreground(t.Success()).Render(fmt.Sprintf("+%d ", change.Additions)),
+			baseStyle.Foreground(t.Error()).Render(fmt.Sprintf("-%d", change.Removals)),
+		)
+		switch {
+		case change.Created:
+			header = lipgloss.JoinHorizontal(lipgloss.Left, header, mutedStyle.Render("  (created)"))
+		case change.Deleted:
+			header = lipgloss.JoinHorizontal(lipgloss.Left, header, mutedStyle.Render("  (deleted)"))
+		}
+		rows = append(rows, header)
+
+		unified, _, _ := diff.GenerateDiff(change.Before, change.After, change.Path)
+		formatted, err := diff.FormatDiff(unified, diff.WithTotalWidth(width))
+		if err != nil {
+			formatted = unified
+		}
+		rows = append(rows, formatted, "")
+	}
+	p.content.SetContent(lipgloss.JoinVertical(lipgloss.Left, rows...))
+}
+
+func (p *sessionDiffPage) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	innerWidth := p.width - 2
+
+	title := "Session Diff"
+	if len(p.points) > 0 {
+		var additions, removals int
+		for _, change := range p.changes {
+			additions += change.Additions
+			removals += change.Removals
+		}
+		title = fmt.Sprintf("Session Diff from %s to %s · %d files +%d -%d",
+			p.points[p.from].Label, p.points[p.to].Label, len(p.changes), additions, removals)
+	}
+	titleRow := baseStyle.Foreground(t.Primary()).Bold(true).Width(innerWidth).MaxHeight(1).Render(title)
+
+	body := lipgloss.JoinHorizontal(lipgloss.Top,
+		baseStyle.Width(sessionDiffPointsWidth).Height(p.content.Height).Render(p.renderPoints()),
+		baseStyle.Width(1).Render(""),
+		p.content.View(),
+	)
+
+	border := baseStyle.
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderNormal()).
+		BorderBackground(t.Background())
+
+	return baseStyle.Width(p.width).Height(p.height).Render(
+		border.Width(innerWidth).Render(lipgloss.JoinVertical(lipgloss.Left, titleRow, body)),
+	)
+}
+
+// renderPoints lists the points of the session around the cursor, with the
+// compared ones marked
+func (p *sessionDiffPage) renderPoints() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	mutedStyle := baseStyle.Foreground(t.TextMuted())
+	width := sessionDiffPointsWidth - 1
+
+	visible := max(1, p.content.Height)
+	offset := max(0, min(p.cursor-visible/2, len(p.points)-visible))
+	var rows []string
+	for i := offset; i < min(len(p.points), offset+visible); i++ {
+		point := p.points[i]
+		marker := "     "
+		switch {
+		case i == p.from && i == p.to:
+			marker = "both "
+		case i == p.from:
+			marker = "from "
+		case i == p.to:
+			marker = "to   "
+		}
+		label := point.Label
+		if !point.Time.IsZero() {
+			label += " " + mutedStyle.Render(locale.Clock(point.Time))
+		}
+		row := lipgloss.JoinHorizontal(lipgloss.Left,
+			baseStyle.Foreground(t.Primary()).Render(marker),
+			baseStyle.Render(label),
+		)
+		row = baseStyle.Width(width).MaxHeight(1).Render(row)
+		if i == p.cursor {
+			row = baseStyle.Background(t.BackgroundSecondary()).Width(width).Render(row)
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+	return strings.Join(rows, "\n")
+}
+
+func (p *sessionDiffPage) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(sessionDiffKeys)
+}
+
+// GetSize implements SessionDiffPageModel.
+func (p *sessionDiffPage) GetSize() (int, int) {
+	return p.width, p.height
+}
+
+// SetSize implements SessionDiffPageModel.
+func (p *sessionDiffPage) SetSize(width int, height int) tea.Cmd {
+	p.width = width
+	p.height = height
+	p.content.Width = max(10, width-2-sessionDiffPointsWidth-1)
+	p.content.Height = max(3, height-3)
+	p.render()
+	return nil
+}
+
+func NewSessionDiffPage(app *app.App) SessionDiffPageModel {
+	return &sessionDiffPage{
+		app:     app,
+		content: viewport.New(0, 0),
+	}
+}
@@ -0,0 +1,389 @@
+package page
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kirmad/superopencode/internal/app"
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/pubsub"
+	"github.com/kirmad/superopencode/internal/session"
+	"github.com/kirmad/superopencode/internal/tui/layout"
+	"github.com/kirmad/superopencode/internal/tui/styles"
+	"github.com/kirmad/superopencode/internal/tui/theme"
+	"github.com/kirmad/superopencode/internal/tui/util"
+)
+
+var TasksPage PageID = "tasks"
+
+// InspectTasksMsg opens the task inspector on the tasks of a session
+type InspectTasksMsg struct {
+	SessionID string
+}
+
+type tasksTickMsg struct{}
+
+// Task statuses shown in the inspector
+const (
+	taskQueued     = "queued"
+	taskRunning    = "running"
+	taskDone       = "done"
+	taskFailed     = "failed"
+	taskCanceled   = "canceled"
+	taskOverBudget = "budget" // Stopped by its budget
+)
+
+// taskInfo is a task launched by an agent tool call. The task runs in a child
+// session with the ID of the tool call.
+type taskInfo struct {
+	id       string
+	prompt   string
+	priority string
+	status   string
+	cost     float64
+	started  int64 // Unix seconds
+	finished int64 // Unix seconds, 0 while running
+	messages []message.Message
+}
+
+func (t taskInfo) elapsed() time.Duration {
+	if t.started == 0 {
+		return 0
+	}
+	end := t.finished
+	if end == 0 {
+		end = time.Now().Unix()
+	}
+	return time.Duration(max(0, end-t.started)) * time.Second
+}
+
+type TasksKeyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Scroll key.Binding
+	Cancel key.Binding
+}
+
+var tasksKeys = TasksKeyMap{
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "previous task"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "next task"),
+	),
+	Scroll: key.NewBinding(
+		key.WithKeys("pgup", "pgdown"),
+		key.WithHelp("pgup/pgdown", "scroll task"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "cancel task"),
+	),
+}
+
+type TaskPage interface {
+	tea.Model
+	layout.Sizeable
+	layout.Bindings
+}
+
+type tasksPage struct {
+	app           *app.App
+	width, height int
+	sessionID     string
+	tasks         []taskInfo
+	selected      int
+	stream        viewport.Model
+	ticking       bool
+}
+
+func (p *tasksPage) Init() tea.Cmd {
+	return nil
+}
+
+func (p *tasksPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		return p, p.SetSize(msg.Width, msg.Height)
+	case InspectTasksMsg:
+		p.sessionID = msg.SessionID
+		p.selected = -1
+		return p, p.reload()
+	case tasksTickMsg:
+		p.ticking = false
+		return p, p.reload()
+	case pubsub.Event[message.Message], pubsub.Event[session.Session]:
+		return p, p.reload()
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, tasksKeys.Up):
+			if p.selected > 0 {
+				p.selected--
+				p.renderStream(true)
+			}
+			return p, nil
+		case key.Matches(msg, tasksKeys.Down):
+			if p.selected < len(p.tasks)-1 {
+				p.selected++
+				p.renderStream(true)
+			}
+			return p, nil
+		case key.Matches(msg, tasksKeys.Cancel):
+			return p, p.cancelSelected()
+		case key.Matches(msg, tasksKeys.Scroll):
+			var cmd tea.Cmd
+			p.stream, cmd = p.stream.Update(msg)
+			return p, cmd
+		}
+	}
+	return p, nil
+}
+
+// cancelSelected stops the selected task, the other tasks keep running
+func (p *tasksPage) cancelSelected() tea.Cmd {
+	if p.selected < 0 || p.selected >= len(p.tasks) || p.tasks[p.selected].status != taskRunning {
+		return util.ReportWarn("The selected task is not running")
+	}
+	if !agent.CancelTask(p.tasks[p.selected].id) {
+		return util.ReportWarn("The selected task has not started yet")
+	}
+	return util.ReportInfo("Task canceled")
+}
+
+// reload reads the tasks of the session and keeps ticking while one runs so
+// the elapsed time stays current
+func (p *tasksPage) reload() tea.Cmd {
+	if p.sessionID == "" {
+		return nil
+	}
+	tasks, err := loadTasks(context.Background(), p.app, p.sessionID)
+	if err != nil {
+		return nil
+	}
+	p.tasks = tasks
+	if p.selected < 0 || p.selected >= len(tasks) {
+		// Start on the latest task
+		p.selected = len(tasks) - 1
+	}
+	p.renderStream(false)
+
+	for _, task := range tasks {
+		if task.status == taskRunning && !p.ticking {
+			p.ticking = true
+			return tea.Tick(time.Second, func(time.Time) tea.Msg { return tasksTickMsg{} })
+		}
+	}
+	return nil
+}
+
+// loadTasks returns the tasks launched by agent tool calls of a session
+func loadTasks(ctx context.Context, app *app.App, sessionID string) ([]taskInfo, error) {
+	msgs, err := app.Messages.List(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]message.ToolResult)
+	for _, msg := range msgs {
+		for _, result := range msg.ToolResults() {
+			results[result.ToolCallID] = result
+		}
+	}
+
+	var tasks []taskInfo
+	for _, msg := range msgs {
+		if msg.Role != message.Assistant {
+			continue
+		}
+		for _, call := range msg.ToolCalls() {
+			if call.Name != agent.AgentToolName {
+				continue
+			}
+			var params agent.AgentParams
+			_ = json.Unmarshal([]byte(call.Input), &params)
+			task := taskInfo{
+				id:       agent.TaskSessionID(call),
+				prompt:   params.Prompt,
+				priority: params.Priority,
+				status:   taskQueued,
+			}
+
+			if taskSession, err := app.Sessions.Get(ctx, task.id); err == nil {
+				task.cost = taskSession.Cost
+				task.started = taskSession.CreatedAt
+				task.status = taskRunning
+			}
+			task.messages, _ = app.Messages.List(ctx, task.id)
+
+			result, hasResult := results[call.ID]
+			switch {
+			case hasResult && strings.HasPrefix(result.Content, agent.TaskCanceledResult):
+				task.status = taskCanceled
+			case hasResult && strings.HasPrefix(result.Content, agent.TaskBudgetExceededResult):
+				task.status = taskOverBudget
+			case hasResult && result.IsError:
+				task.status = taskFailed
+			case hasResult:
+				task.status = taskDone
+			case msg.IsFinished() && msg.FinishReason() != message.FinishReasonToolUse:
+				task.status = taskCanceled
+			}
+			if task.status != taskRunning && task.status != taskQueued {
+				task.finished = task.started
+				for _, taskMsg := range task.messages {
+					task.finished = max(task.finished, taskMsg.UpdatedAt)
+				}
+			}
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+// renderStream shows the messages of the selected task, following the end of
+// the stream unless the user scrolled up
+func (p *tasksPage) renderStream(reset bool) {
+	if p.selected < 0 || p.selected >= len(p.tasks) {
+		p.stream.SetContent("")
+		return
+	}
+	atBottom := p.stream.AtBottom()
+
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	width := max(10, p.stream.Width-2)
+	muted := baseStyle.Foreground(t.TextMuted()).Width(width)
+	text := baseStyle.Width(width)
+
+	var parts []string
+	task := p.tasks[p.selected]
+	parts = append(parts, baseStyle.Foreground(t.Primary()).Bold(true).Width(width).Render("Prompt"))
+	parts = append(parts, text.Render(task.prompt), "")
+	for _, msg := range task.messages {
+		switch msg.Role {
+		case message.Assistant:
+			if thinking := msg.ReasoningContent().Thinking; thinking != "" && msg.Content().Text == "" {
+				parts = append(parts, muted.Render("Thinking: "+truncateLines(thinking, 3)))
+			}
+			if content := msg.Content().Text; content != "" {
+				parts = append(parts, text.Render(content))
+			}
+			for _, call := range msg.ToolCalls() {
+				parts = append(parts, baseStyle.Foreground(t.Secondary()).Width(width).Render(
+					fmt.Sprintf("→ %s %s", call.Name, truncateLines(call.Input, 1))))
+			}
+		case message.Tool:
+			for _, result := range msg.ToolResults() {
+				style := muted
+				if result.IsError {
+					style = baseStyle.Foreground(t.Error()).Width(width)
+				}
+				parts = append(parts, style.Render("  "+truncateLines(result.Content, 3)))
+			}
+		}
+	}
+
+	p.stream.SetContent(lipgloss.JoinVertical(lipgloss.Left, parts...))
+	if reset {
+		p.stream.GotoTop()
+	} else if atBottom {
+		p.stream.GotoBottom()
+	}
+}
+
+// truncateLines keeps the first n lines of s
+func truncateLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) <= n {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines[:n], "\n") + fmt.Sprintf("\n… %d more lines", len(lines)-n)
+}
+
+func (p *tasksPage) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	listWidth := p.width - 2
+
+	title := baseStyle.Foreground(t.Primary()).Bold(true).Width(listWidth).Render("Tasks")
+	rows := []string{title}
+	if len(p.tasks) == 0 {
+		rows = append(rows, baseStyle.Foreground(t.TextMuted()).Width(listWidth).Render("No tasks in this session"))
+	}
+	for i, task := range p.tasks {
+		statusStyle := baseStyle.Foreground(t.TextMuted())
+		switch task.status {
+		case taskRunning:
+			statusStyle = baseStyle.Foreground(t.Warning())
+		case taskDone:
+			statusStyle = baseStyle.Foreground(t.Success())
+		case taskFailed, taskOverBudget:
+			statusStyle = baseStyle.Foreground(t.Error())
+		}
+		info := fmt.Sprintf(" %-8s %6s  $%.4f  ", task.status, task.elapsed().String(), task.cost)
+		if task.priority != "" && task.priority != agent.TaskPriorityNormal {
+			info += "[" + task.priority + "] "
+		}
+		prompt := strings.ReplaceAll(task.prompt, "\n", " ")
+		row := lipgloss.JoinHorizontal(lipgloss.Left,
+			statusStyle.Render(info),
+			baseStyle.Width(max(0, listWidth-lipgloss.Width(info))).MaxHeight(1).Render(prompt),
+		)
+		if i == p.selected {
+			row = baseStyle.Background(t.BackgroundSecondary()).Width(listWidth).Render(row)
+		}
+		rows = append(rows, row)
+	}
+	list := lipgloss.JoinVertical(lipgloss.Left, rows...)
+
+	border := baseStyle.
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderNormal()).
+		BorderBackground(t.Background())
+
+	return baseStyle.Width(p.width).Height(p.height).Render(lipgloss.JoinVertical(lipgloss.Top,
+		border.Width(listWidth).Height(p.listHeight()).Render(list),
+		border.Width(listWidth).Render(p.stream.View()),
+	))
+}
+
+func (p *tasksPage) listHeight() int {
+	return max(3, min(len(p.tasks)+1, p.height/3))
+}
+
+func (p *tasksPage) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(tasksKeys)
+}
+
+// GetSize implements TaskPage.
+func (p *tasksPage) GetSize() (int, int) {
+	return p.width, p.height
+}
+
+// SetSize implements TaskPage.
+func (p *tasksPage) SetSize(width int, height int) tea.Cmd {
+	p.width = width
+	p.height = height
+	p.stream.Width = max(10, width-2)
+	p.stream.Height = max(3, height-p.listHeight()-4)
+	p.renderStream(false)
+	return nil
+}
+
+func NewTasksPage(app *app.App) TaskPage {
+	return &tasksPage{
+		app:      app,
+		selected: -1,
+		stream:   viewport.New(0, 0),
+	}
+}
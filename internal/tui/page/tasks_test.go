@@ -0,0 +1,205 @@
+package page
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/kirmad/superopencode/internal/app"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/db"
+	"github.com/kirmad/superopencode/internal/llm/agent"
+	"github.com/kirmad/superopencode/internal/message"
+	"github.com/kirmad/superopencode/internal/session"
+	"github.com/kirmad/superopencode/internal/tui/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTasksApp returns an app on an empty in-memory database, and a session
+// launching tasks
+func newTasksApp(t *testing.T) (*app.App, string) {
+	t.Helper()
+	_, err := config.Load(t.TempDir(), false)
+	require.NoError(t, err)
+	conn, err := db.ConnectEphemeral()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	a := &app.App{Sessions: session.NewService(q), Messages: message.NewService(q)}
+	sess, err := a.Sessions.Create(context.Background(), "parent")
+	require.NoError(t, err)
+	return a, sess.ID
+}
+
+// launchTasks adds an assistant message calling the agent tool with inputs
+// by call ID, finished with reason unless it's empty
+func launchTasks(t *testing.T, a *app.App, sessionID string, reason message.FinishReason, inputs ...string) {
+	t.Helper()
+	ctx := context.Background()
+	var parts []message.ContentPart
+	for i := 0; i+1 < len(inputs); i += 2 {
+		parts = append(parts, message.ToolCall{ID: inputs[i], Name: agent.AgentToolName, Input: inputs[i+1], Type: "function", Finished: true})
+	}
+	msg, err := a.Messages.Create(ctx, sessionID, message.CreateMessageParams{Role: message.Assistant, Parts: parts})
+	require.NoError(t, err)
+	if reason != "" {
+		msg.AddFinish(reason)
+		require.NoError(t, a.Messages.Update(ctx, msg))
+	}
+}
+
+// answerTasks adds the tool message with the results of tasks
+func answerTasks(t *testing.T, a *app.App, sessionID string, results ...message.ToolResult) {
+	t.Helper()
+	var parts []message.ContentPart
+	for _, result := range results {
+		parts = append(parts, result)
+	}
+	_, err := a.Messages.Create(context.Background(), sessionID, message.CreateMessageParams{Role: message.Tool, Parts: parts})
+	require.NoError(t, err)
+}
+
+// startTaskSession creates the session of a task with a message of its agent
+func startTaskSession(t *testing.T, a *app.App, sessionID, taskID string) {
+	t.Helper()
+	ctx := context.Background()
+	_, err := a.Sessions.CreateTaskSession(ctx, taskID, sessionID, "New Agent Session")
+	require.NoError(t, err)
+	_, err = a.Messages.Create(ctx, taskID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "prompt of " + taskID}},
+	})
+	require.NoError(t, err)
+}
+
+func taskStatuses(tasks []taskInfo) map[string]string {
+	statuses := make(map[string]string)
+	for _, task := range tasks {
+		statuses[task.id] = task.status
+	}
+	return statuses
+}
+
+func TestLoadTasks(t *testing.T) {
+	ctx := context.Background()
+	a, sessionID := newTasksApp(t)
+	launchTasks(t, a, sessionID, message.FinishReasonToolUse,
+		"call-done", `{"prompt":"find the callers","priority":"high"}`,
+		"call-failed", `{"prompt":"read the docs"}`,
+	)
+	answerTasks(t, a, sessionID,
+		message.ToolResult{ToolCallID: "call-done", Content: "the callers"},
+		message.ToolResult{ToolCallID: "call-failed", Content: "error generating agent", IsError: true},
+	)
+	launchTasks(t, a, sessionID, message.FinishReasonToolUse,
+		"call-running", `{"prompt":"run the tests"}`,
+		"call-queued", `{"prompt":"write the summary"}`,
+	)
+	for _, id := range []string{"call-done", "call-failed", "call-running"} {
+		startTaskSession(t, a, sessionID, id)
+	}
+
+	tasks, err := loadTasks(ctx, a, sessionID)
+	require.NoError(t, err)
+	require.Len(t, tasks, 4)
+	assert.Equal(t, map[string]string{
+		"call-done":    taskDone,
+		"call-failed":  taskFailed,
+		"call-running": taskRunning,
+		"call-queued":  taskQueued,
+	}, taskStatuses(tasks))
+
+	done := tasks[0]
+	assert.Equal(t, "find the callers", done.prompt)
+	assert.Equal(t, "high", done.priority)
+	require.Len(t, done.messages, 1)
+	assert.Equal(t, "prompt of call-done", done.messages[0].Content().String())
+	assert.NotZero(t, done.started)
+	assert.GreaterOrEqual(t, done.finished, done.started, "finished tasks stop their clock")
+	assert.Zero(t, tasks[2].finished, "running tasks have no end")
+	assert.Zero(t, tasks[3].started, "queued tasks have not started")
+	assert.Empty(t, tasks[3].messages)
+
+	// Tasks without a result of a request that ended were canceled with it
+	launchTasks(t, a, sessionID, message.FinishReasonCanceled, "call-aborted", `{"prompt":"refactor"}`)
+	tasks, err = loadTasks(ctx, a, sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, taskCanceled, taskStatuses(tasks)["call-aborted"])
+}
+
+func TestLoadCanceledTasks(t *testing.T) {
+	a, sessionID := newTasksApp(t)
+	launchTasks(t, a, sessionID, message.FinishReasonToolUse,
+		"call-canceled", `{"prompt":"find the callers"}`,
+		"call-failed", `{"prompt":"read the docs"}`,
+	)
+	answerTasks(t, a, sessionID,
+		message.ToolResult{ToolCallID: "call-canceled", Content: agent.TaskCanceledResult + "\n\n<continuation>call-canceled</continuation>", IsError: true},
+		message.ToolResult{ToolCallID: "call-failed", Content: "error generating agent", IsError: true},
+	)
+	startTaskSession(t, a, sessionID, "call-canceled")
+
+	tasks, err := loadTasks(context.Background(), a, sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"call-canceled": taskCanceled, "call-failed": taskFailed}, taskStatuses(tasks))
+	assert.NotZero(t, tasks[0].finished, "canceled tasks stop their clock")
+}
+
+func TestCancelSelectedTask(t *testing.T) {
+	a, sessionID := newTasksApp(t)
+	launchTasks(t, a, sessionID, message.FinishReasonToolUse,
+		"call-done", `{"prompt":"first"}`,
+		"call-running", `{"prompt":"second"}`,
+	)
+	answerTasks(t, a, sessionID, message.ToolResult{ToolCallID: "call-done", Content: "report"})
+	startTaskSession(t, a, sessionID, "call-running")
+
+	p := NewTasksPage(a).(*tasksPage)
+	p.Update(InspectTasksMsg{SessionID: sessionID})
+	require.Equal(t, taskRunning, p.tasks[1].status)
+
+	// The task session exists but its run is unknown to this process, e.g.
+	// it was left running by a crash
+	_, cmd := p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	assert.Equal(t, util.InfoMsg{Type: util.InfoTypeWarn, Msg: "The selected task has not started yet"}, cmd())
+
+	p.Update(tea.KeyMsg{Type: tea.KeyUp})
+	_, cmd = p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	assert.Equal(t, util.InfoMsg{Type: util.InfoTypeWarn, Msg: "The selected task is not running"}, cmd())
+}
+
+func TestTaskElapsed(t *testing.T) {
+	assert.Zero(t, taskInfo{}.elapsed(), "not started")
+	assert.Equal(t, 90*time.Second, taskInfo{started: 100, finished: 190}.elapsed())
+	running := taskInfo{started: time.Now().Add(-time.Minute).Unix()}
+	assert.GreaterOrEqual(t, running.elapsed(), time.Minute)
+}
+
+func TestTruncateLines(t *testing.T) {
+	assert.Equal(t, "one\ntwo", truncateLines("one\ntwo\n", 2))
+	assert.Equal(t, "one\n… 2 more lines", truncateLines("one\ntwo\nthree", 1))
+}
+
+func TestTasksPageSelection(t *testing.T) {
+	a, sessionID := newTasksApp(t)
+	launchTasks(t, a, sessionID, message.FinishReasonToolUse,
+		"call-1", `{"prompt":"first"}`,
+		"call-2", `{"prompt":"second"}`,
+	)
+
+	p := NewTasksPage(a).(*tasksPage)
+	p.SetSize(80, 24)
+	p.Update(InspectTasksMsg{SessionID: sessionID})
+	require.Len(t, p.tasks, 2)
+	assert.Equal(t, 1, p.selected, "the inspector starts on the latest task")
+
+	p.Update(tea.KeyMsg{Type: tea.KeyDown})
+	assert.Equal(t, 1, p.selected)
+	p.Update(tea.KeyMsg{Type: tea.KeyUp})
+	assert.Equal(t, 0, p.selected)
+	p.Update(tea.KeyMsg{Type: tea.KeyUp})
+	assert.Equal(t, 0, p.selected)
+	assert.Contains(t, p.stream.View(), "first")
+}
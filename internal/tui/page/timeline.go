@@ -0,0 +1,231 @@
+package page
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kirmad/superopencode/internal/app"
+	"github.com/kirmad/superopencode/internal/detailed_logging"
+	"github.com/kirmad/superopencode/internal/tui/layout"
+	"github.com/kirmad/superopencode/internal/tui/styles"
+	"github.com/kirmad/superopencode/internal/tui/theme"
+)
+
+var TimelinePage PageID = "timeline"
+
+// ShowTimelineMsg shows the timeline of a session
+type ShowTimelineMsg struct {
+	SessionID string
+}
+
+const (
+	timelineLabelWidth = 12
+	timelineSlowest    = 5
+)
+
+var timelineLaneTitles = map[detailed_logging.TimelineLane]string{
+	detailed_logging.LaneUser:       "User",
+	detailed_logging.LaneLLM:        "LLM",
+	detailed_logging.LaneTool:       "Tools",
+	detailed_logging.LanePermission: "Permission",
+}
+
+type TimelineKeyMap struct {
+	Reload     key.Binding
+	ScrollDown key.Binding
+	ScrollUp   key.Binding
+}
+
+var timelineKeys = TimelineKeyMap{
+	Reload: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "reload"),
+	),
+	ScrollDown: key.NewBinding(
+		key.WithKeys("down", "j", "pgdown"),
+		key.WithHelp("↓/j", "scroll down"),
+	),
+	ScrollUp: key.NewBinding(
+		key.WithKeys("up", "k", "pgup"),
+		key.WithHelp("↑/k", "scroll up"),
+	),
+}
+
+type TimelinePageModel interface {
+	tea.Model
+	layout.Sizeable
+	layout.Bindings
+}
+
+type timelinePage struct {
+	app           *app.App
+	width, height int
+	sessionID     string
+	timeline      detailed_logging.Timeline
+	content       viewport.Model
+}
+
+func (p *timelinePage) Init() tea.Cmd {
+	return nil
+}
+
+func (p *timelinePage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		return p, p.SetSize(msg.Width, msg.Height)
+	case ShowTimelineMsg:
+		p.sessionID = msg.SessionID
+		p.content.GotoTop()
+		p.reload()
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, timelineKeys.Reload):
+			p.reload()
+		case key.Matches(msg, timelineKeys.ScrollDown), key.Matches(msg, timelineKeys.ScrollUp):
+			var cmd tea.Cmd
+			p.content, cmd = p.content.Update(msg)
+			return p, cmd
+		}
+	}
+	return p, nil
+}
+
+// reload rebuilds the timeline from what the detailed logger recorded so far
+func (p *timelinePage) reload() {
+	p.timeline = detailed_logging.BuildTimeline(p.app.DetailedLogger.Snapshot(), p.sessionID, time.Now())
+	p.render()
+}
+
+// render draws a lane per kind of activity over the duration of the session,
+// followed by the slowest steps and the activities in order
+func (p *timelinePage) render() {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	mutedStyle := baseStyle.Foreground(t.TextMuted())
+	width := max(10, p.content.Width)
+
+	switch {
+	case !p.app.DetailedLogger.IsEnabled():
+		p.content.SetContent(mutedStyle.Width(width).
+			Render("The timeline is built from detailed logs, start with --detailed-logs to record them"))
+		return
+	case len(p.timeline.Spans) == 0:
+		p.content.SetContent(mutedStyle.Width(width).Render("No activity recorded for this session yet"))
+		return
+	}
+
+	chartWidth := max(10, width-timelineLabelWidth-12)
+	laneColors := map[detailed_logging.TimelineLane]lipgloss.AdaptiveColor{
+		detailed_logging.LaneUser:       t.Primary(),
+		detailed_logging.LaneLLM:        t.Secondary(),
+		detailed_logging.LaneTool:       t.Accent(),
+		detailed_logging.LanePermission: t.Warning(),
+	}
+
+	var rows []string
+	for _, lane := range detailed_logging.TimelineLanes {
+		busy := ""
+		if lane != detailed_logging.LaneUser {
+			busy = formatTimelineDuration(p.timeline.Busy(lane))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Left,
+			baseStyle.Width(timelineLabelWidth).Render(timelineLaneTitles[lane]),
+			baseStyle.Foreground(laneColors[lane]).Render(p.timeline.Chart(lane, chartWidth)),
+			mutedStyle.Render(fmt.Sprintf(" %10s", busy)),
+		))
+	}
+	total := formatTimelineDuration(p.timeline.Duration())
+	rows = append(rows, mutedStyle.Render(strings.Repeat(" ", timelineLabelWidth)+"0"+
+		strings.Repeat(" ", max(1, chartWidth-1-len(total)))+total), "")
+
+	rows = append(rows, baseStyle.Foreground(t.TextMuted()).Bold(true).Render("SLOWEST"))
+	for _, span := range p.timeline.Slowest(timelineSlowest) {
+		rows = append(rows, p.renderSpan(span))
+	}
+
+	rows = append(rows, "", baseStyle.Foreground(t.TextMuted()).Bold(true).Render("ACTIVITY"))
+	for _, span := range p.timeline.Spans {
+		rows = append(rows, p.renderSpan(span))
+	}
+	p.content.SetContent(lipgloss.JoinVertical(lipgloss.Left, rows...))
+}
+
+// renderSpan lists an activity with its offset from the start of the session
+func (p *timelinePage) renderSpan(span detailed_logging.TimelineSpan) string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+
+	duration := formatTimelineDuration(span.Duration())
+	switch {
+	case span.Lane == detailed_logging.LaneUser:
+		duration = ""
+	case span.Running:
+		duration += " running"
+	}
+	style := baseStyle
+	if span.Error {
+		style = baseStyle.Foreground(t.Error())
+	}
+	return style.Render(fmt.Sprintf("+%-8s %-11s %-16s %s",
+		formatTimelineDuration(span.Start.Sub(p.timeline.Start)),
+		timelineLaneTitles[span.Lane],
+		duration,
+		span.Label,
+	))
+}
+
+func formatTimelineDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+func (p *timelinePage) View() string {
+	t := theme.CurrentTheme()
+	baseStyle := styles.BaseStyle()
+	innerWidth := p.width - 2
+
+	title := baseStyle.Foreground(t.Primary()).Bold(true).Width(innerWidth).
+		Render("Session Timeline")
+
+	border := baseStyle.
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderNormal()).
+		BorderBackground(t.Background())
+
+	return baseStyle.Width(p.width).Height(p.height).Render(
+		border.Width(innerWidth).Render(lipgloss.JoinVertical(lipgloss.Left, title, p.content.View())),
+	)
+}
+
+func (p *timelinePage) BindingKeys() []key.Binding {
+	return layout.KeyMapToSlice(timelineKeys)
+}
+
+// GetSize implements TimelinePageModel.
+func (p *timelinePage) GetSize() (int, int) {
+	return p.width, p.height
+}
+
+// SetSize implements TimelinePageModel.
+func (p *timelinePage) SetSize(width int, height int) tea.Cmd {
+	p.width = width
+	p.height = height
+	p.content.Width = max(10, width-2)
+	p.content.Height = max(3, height-3)
+	p.render()
+	return nil
+}
+
+func NewTimelinePage(app *app.App) TimelinePageModel {
+	return &timelinePage{
+		app:     app,
+		content: viewport.New(0, 0),
+	}
+}
@@ -0,0 +1,228 @@
+package tui
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+	"github.com/kirmad/superopencode/internal/config"
+	"github.com/kirmad/superopencode/internal/logging"
+	"github.com/kirmad/superopencode/internal/tui/components/dialog"
+	"github.com/kirmad/superopencode/internal/tui/page"
+	"github.com/kirmad/superopencode/internal/tui/theme"
+	"github.com/kirmad/superopencode/internal/tui/util"
+)
+
+// reloadDebounce groups the events of a file being saved into one reload
+const reloadDebounce = 300 * time.Millisecond
+
+// configChangedMsg is sent when config files or command files changed
+type configChangedMsg struct {
+	config   bool
+	commands bool
+}
+
+// configWatcher watches the config files and the command directories
+type configWatcher struct {
+	watcher     *fsnotify.Watcher
+	files       map[string]bool
+	commandDirs []string
+	// changed is signaled once the pending changes settled
+	changed chan struct{}
+
+	mu      sync.Mutex
+	pending configChangedMsg
+	timer   *time.Timer
+}
+
+// newConfigWatcher watches the directories of the config files, which may not
+// exist yet or be replaced when saved, and the command directories with
+// their subdirectories
+func newConfigWatcher() (*configWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+	w := &configWatcher{
+		watcher:     watcher,
+		files:       make(map[string]bool),
+		commandDirs: dialog.CommandDirs(),
+		changed:     make(chan struct{}, 1),
+	}
+	dirs := make(map[string]bool)
+	for _, file := range config.WatchedFiles() {
+		w.files[file] = true
+		dirs[filepath.Dir(file)] = true
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil && !os.IsNotExist(err) {
+			logging.Debug("Failed to watch config directory", "dir", dir, "error", err)
+		}
+	}
+	for _, dir := range w.commandDirs {
+		w.addTree(dir)
+	}
+	go w.run()
+	return w, nil
+}
+
+// addTree watches a directory and its subdirectories
+func (w *configWatcher) addTree(root string) {
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if err := w.watcher.Add(path); err != nil {
+			logging.Debug("Failed to watch command directory", "dir", path, "error", err)
+		}
+		return nil
+	})
+}
+
+func (w *configWatcher) run() {
+	defer logging.RecoverPanic("config-watcher", nil)
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handle(event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			logging.Debug("Config watcher error", "error", err)
+		}
+	}
+}
+
+func (w *configWatcher) handle(event fsnotify.Event) {
+	var change configChangedMsg
+	switch {
+	case w.files[event.Name]:
+		change.config = true
+	case w.isCommandPath(event.Name):
+		if event.Has(fsnotify.Create) {
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				w.addTree(event.Name)
+			}
+		}
+		change.commands = true
+	default:
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending.config = w.pending.config || change.config
+	w.pending.commands = w.pending.commands || change.commands
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(reloadDebounce, func() {
+		select {
+		case w.changed <- struct{}{}:
+		default:
+			// The changes are added to the reload waiting to be handled
+		}
+	})
+}
+
+func (w *configWatcher) isCommandPath(path string) bool {
+	for _, dir := range w.commandDirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// wait waits for the next changes
+func (w *configWatcher) wait() tea.Cmd {
+	return func() tea.Msg {
+		<-w.changed
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		change := w.pending
+		w.pending = configChangedMsg{}
+		return change
+	}
+}
+
+// reload applies a change of the config files or the command files, with a
+// status message on what changed
+func (a *appModel) reload(change configChangedMsg) tea.Cmd {
+	var reloaded, restart []string
+	var cmds []tea.Cmd
+
+	if change.config {
+		themeName := config.Get().TUI.Theme
+		result, err := config.Reload()
+		if err != nil {
+			return util.ReportError(fmt.Errorf("config not reloaded: %w", err))
+		}
+		if len(result.Applied) > 0 {
+			reloaded = append(reloaded, "config ("+strings.Join(result.Applied, ", ")+")")
+		}
+		restart = result.Restart
+		if name := config.Get().TUI.Theme; name != themeName && name != "" {
+			if err := theme.SetTheme(name); err != nil {
+				logging.Warn("Failed to apply reloaded theme", "theme", name, "error", err)
+			} else {
+				var cmd tea.Cmd
+				a.pages[a.currentPage], cmd = a.pages[a.currentPage].Update(dialog.ThemeChangedMsg{ThemeName: name})
+				cmds = append(cmds, cmd)
+			}
+		}
+	}
+
+	if change.commands {
+		if err := a.reloadCommands(); err != nil {
+			return util.ReportError(fmt.Errorf("commands not reloaded: %w", err))
+		}
+		reloaded = append(reloaded, "commands")
+	}
+
+	msg := "Reloaded " + strings.Join(reloaded, " and ")
+	switch {
+	case len(restart) > 0 && len(reloaded) > 0:
+		cmds = append(cmds, util.ReportWarn(msg+", restart to apply "+strings.Join(restart, ", ")))
+	case len(restart) > 0:
+		cmds = append(cmds, util.ReportWarn("Restart to apply the config changes to "+strings.Join(restart, ", ")))
+	case len(reloaded) > 0:
+		cmds = append(cmds, util.ReportInfo(msg))
+	}
+	return tea.Batch(cmds...)
+}
+
+// reloadCommands replaces the commands loaded from command files and passes
+// the new registry to the chat page
+func (a *appModel) reloadCommands() error {
+	loaded, err := dialog.LoadCustomCommands()
+	if err != nil {
+		return err
+	}
+	commands := make([]dialog.Command, 0, len(a.commands))
+	for _, cmd := range a.commands {
+		if !dialog.IsFileCommand(cmd) {
+			commands = append(commands, cmd)
+		}
+	}
+	for _, cmd := range loaded {
+		if dialog.IsFileCommand(cmd) {
+			commands = append(commands, cmd)
+		}
+	}
+	a.commands = commands
+	if chatPage, ok := a.pages[page.ChatPage].(page.CommandSetter); ok {
+		chatPage.SetCommands(a.commands)
+	}
+	return nil
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/kirmad/superopencode/internal/permission"
 	"github.com/kirmad/superopencode/internal/pubsub"
 	"github.com/kirmad/superopencode/internal/session"
+	"github.com/kirmad/superopencode/internal/settings"
 	"github.com/kirmad/superopencode/internal/tui/components/chat"
 	"github.com/kirmad/superopencode/internal/tui/components/core"
 	"github.com/kirmad/superopencode/internal/tui/components/dialog"
@@ -33,6 +34,7 @@ type keyMap struct {
 	Filepicker    key.Binding
 	Models        key.Binding
 	SwitchTheme   key.Binding
+	Tasks         key.Binding
 }
 
 type startCompactSessionMsg struct{}
@@ -78,6 +80,11 @@ var keys = keyMap{
 		key.WithKeys("ctrl+t"),
 		key.WithHelp("ctrl+t", "switch theme"),
 	),
+
+	Tasks: key.NewBinding(
+		key.WithKeys("ctrl+g"),
+		key.WithHelp("ctrl+g", "inspect tasks"),
+	),
 }
 
 var helpEsc = key.NewBinding(
@@ -105,9 +112,8 @@ type appModel struct {
 	app             *app.App
 	selectedSession session.Session
 
-	showPermissions           bool
-	permissions               dialog.PermissionDialogCmp
-	dangerouslySkipPermissions bool
+	showPermissions bool
+	permissions     dialog.PermissionDialogCmp
 
 	showHelp bool
 	help     dialog.HelpCmp
@@ -121,6 +127,7 @@ type appModel struct {
 	showCommandDialog bool
 	commandDialog     dialog.CommandDialog
 	commands          []dialog.Command
+	configWatcher     *configWatcher
 
 	showModelDialog bool
 	modelDialog     dialog.ModelDialog
@@ -137,6 +144,12 @@ type appModel struct {
 	showMultiArgumentsDialog bool
 	multiArgumentsDialog     dialog.MultiArgumentsDialogCmp
 
+	showSystemPromptDialog bool
+	systemPromptDialog     dialog.SystemPromptDialogCmp
+
+	showTemplateDialog bool
+	templateDialog     dialog.TemplateDialog
+
 	isCompacting      bool
 	compactingMessage string
 }
@@ -164,6 +177,9 @@ func (a appModel) Init() tea.Cmd {
 	cmds = append(cmds, cmd)
 	cmd = a.themeDialog.Init()
 	cmds = append(cmds, cmd)
+	if a.configWatcher != nil {
+		cmds = append(cmds, a.configWatcher.wait())
+	}
 
 	// Check if we should show the init dialog
 	cmds = append(cmds, func() tea.Msg {
@@ -205,6 +221,10 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.sessionDialog = session.(dialog.SessionDialog)
 		cmds = append(cmds, sessionCmd)
 
+		template, templateCmd := a.templateDialog.Update(msg)
+		a.templateDialog = template.(dialog.TemplateDialog)
+		cmds = append(cmds, templateCmd)
+
 		command, commandCmd := a.commandDialog.Update(msg)
 		a.commandDialog = command.(dialog.CommandDialog)
 		cmds = append(cmds, commandCmd)
@@ -222,6 +242,10 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, argsCmd, a.multiArgumentsDialog.Init())
 		}
 
+		if a.showSystemPromptDialog {
+			a.systemPromptDialog.SetSize(msg.Width, msg.Height)
+		}
+
 		return a, tea.Batch(cmds...)
 	// Status
 	case util.InfoMsg:
@@ -300,6 +324,19 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.showSessionDialog = false
 		return a, nil
 
+	case dialog.ShowTemplateDialogMsg:
+		a.templateDialog.SetTemplates(msg.Templates)
+		a.showTemplateDialog = true
+		return a, nil
+
+	case dialog.CloseTemplateDialogMsg:
+		a.showTemplateDialog = false
+		return a, nil
+
+	case dialog.TemplateSelectedMsg:
+		// The chat page starts the session
+		a.showTemplateDialog = false
+
 	case dialog.CloseCommandDialogMsg:
 		a.showCommandDialog = false
 		return a, nil
@@ -323,6 +360,11 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case pubsub.Event[agent.AgentEvent]:
 		payload := msg.Payload
+		if payload.Type == agent.AgentEventTypePreflight || payload.Type == agent.AgentEventTypeLatency {
+			s, cmd := a.status.Update(msg)
+			a.status = s.(core.StatusCmp)
+			return a, cmd
+		}
 		if payload.Error != nil {
 			a.isCompacting = false
 			return a, util.ReportError(payload.Error)
@@ -348,6 +390,9 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.showThemeDialog = false
 		return a, nil
 
+	case configChangedMsg:
+		return a, tea.Batch(a.reload(msg), a.configWatcher.wait())
+
 	case dialog.ThemeChangedMsg:
 		a.pages[a.currentPage], cmd = a.pages[a.currentPage].Update(msg)
 		a.showThemeDialog = false
@@ -394,12 +439,41 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case chat.SessionSelectedMsg:
 		a.selectedSession = msg
+		a.app.Touch(msg.ID)
+		a.app.Warmup()
 		a.sessionDialog.SetSelectedSession(msg.ID)
-
+		sessionID := msg.ID
+		logging.SetCrashInfo("session_id", func() any { return sessionID })
+
+	case pubsub.Event[settings.Settings]:
+		// Toggling takes effect for the open session right away
+		if a.selectedSession.ID != "" {
+			if msg.Payload.SkipPermissions {
+				a.app.Permissions.AutoApproveSession(a.selectedSession.ID)
+			} else {
+				a.app.Permissions.RevokeAutoApproval(a.selectedSession.ID)
+			}
+		}
+		return a, nil
 	case pubsub.Event[session.Session]:
 		if msg.Type == pubsub.UpdatedEvent && msg.Payload.ID == a.selectedSession.ID {
 			a.selectedSession = msg.Payload
 		}
+	case dialog.SearchSessionsMsg:
+		if strings.TrimSpace(msg.Query) == "" {
+			return a, util.ReportWarn("Usage: /search <query>")
+		}
+		results, err := a.app.Sessions.Search(context.Background(), msg.Query, 20, config.Get().TaskSessions.ShowInPicker)
+		if err != nil {
+			return a, util.ReportError(err)
+		}
+		if len(results) == 0 {
+			return a, util.ReportWarn(fmt.Sprintf("No session matches %q", msg.Query))
+		}
+		a.sessionDialog.SetSearchResults(msg.Query, results)
+		a.showSessionDialog = true
+		return a, nil
+
 	case dialog.SessionSelectedMsg:
 		a.showSessionDialog = false
 		if a.currentPage == page.ChatPage {
@@ -443,7 +517,29 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return a, nil
 
+	case dialog.ShowSystemPromptDialogMsg:
+		a.systemPromptDialog = dialog.NewSystemPromptDialogCmp(msg.SessionID, msg.BasePrompt, msg.Additions)
+		a.systemPromptDialog.SetSize(a.width, a.height)
+		a.showSystemPromptDialog = true
+		return a, a.systemPromptDialog.Init()
+
+	case dialog.CloseSystemPromptDialogMsg:
+		a.showSystemPromptDialog = false
+		if !msg.Submit {
+			return a, nil
+		}
+		return a, a.saveSystemPrompt(msg.SessionID, msg.Additions)
+
 	case tea.KeyMsg:
+		// Idle sessions and LSP servers are brought back on interaction
+		a.app.Touch(a.selectedSession.ID)
+		// The system prompt editor captures all keys while it is open
+		if a.showSystemPromptDialog {
+			d, cmd := a.systemPromptDialog.Update(msg)
+			a.systemPromptDialog = d.(dialog.SystemPromptDialogCmp)
+			return a, cmd
+		}
+
 		// If multi-arguments dialog is open, let it handle the key press first
 		if a.showMultiArgumentsDialog {
 			args, cmd := a.multiArgumentsDialog.Update(msg)
@@ -461,6 +557,9 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if a.showSessionDialog {
 				a.showSessionDialog = false
 			}
+			if a.showTemplateDialog {
+				a.showTemplateDialog = false
+			}
 			if a.showCommandDialog {
 				a.showCommandDialog = false
 			}
@@ -478,7 +577,7 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, keys.SwitchSession):
 			if a.currentPage == page.ChatPage && !a.showQuit && !a.showPermissions && !a.showCommandDialog {
 				// Load sessions and show the dialog
-				sessions, err := a.app.Sessions.List(context.Background())
+				sessions, err := a.app.ListPickerSessions(context.Background())
 				if err != nil {
 					return a, util.ReportError(err)
 				}
@@ -521,7 +620,7 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, nil
 		case key.Matches(msg, returnKey) || key.Matches(msg):
 			if msg.String() == quitKey {
-				if a.currentPage == page.LogsPage {
+				if a.currentPage == page.LogsPage || a.currentPage == page.TasksPage || a.currentPage == page.MetricsPage || a.currentPage == page.TimelinePage || a.currentPage == page.ContextPage || a.currentPage == page.PostMortemPage || a.currentPage == page.SessionDiffPage {
 					return a, a.moveToPage(page.ChatPage)
 				}
 			} else if !a.filepicker.IsCWDFocused() {
@@ -546,12 +645,20 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					a.filepicker.ToggleFilepicker(a.showFilepicker)
 					return a, nil
 				}
-				if a.currentPage == page.LogsPage {
+				if a.currentPage == page.LogsPage || a.currentPage == page.TasksPage || a.currentPage == page.MetricsPage || a.currentPage == page.TimelinePage || a.currentPage == page.ContextPage || a.currentPage == page.PostMortemPage || a.currentPage == page.SessionDiffPage {
 					return a, a.moveToPage(page.ChatPage)
 				}
 			}
 		case key.Matches(msg, keys.Logs):
 			return a, a.moveToPage(page.LogsPage)
+		case key.Matches(msg, keys.Tasks):
+			if a.currentPage != page.ChatPage || a.selectedSession.ID == "" {
+				return a, nil
+			}
+			return a, tea.Sequence(
+				a.moveToPage(page.TasksPage),
+				util.CmdHandler(page.InspectTasksMsg{SessionID: a.selectedSession.ID}),
+			)
 		case key.Matches(msg, keys.Help):
 			if a.showQuit {
 				return a, nil
@@ -617,6 +724,16 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	if a.showTemplateDialog {
+		d, templateCmd := a.templateDialog.Update(msg)
+		a.templateDialog = d.(dialog.TemplateDialog)
+		cmds = append(cmds, templateCmd)
+		// Only block key messages send all other messages down
+		if _, ok := msg.(tea.KeyMsg); ok {
+			return a, tea.Batch(cmds...)
+		}
+	}
+
 	if a.showCommandDialog {
 		d, commandCmd := a.commandDialog.Update(msg)
 		a.commandDialog = d.(dialog.CommandDialog)
@@ -661,6 +778,10 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	a.status = s.(core.StatusCmp)
 	a.pages[a.currentPage], cmd = a.pages[a.currentPage].Update(msg)
 	cmds = append(cmds, cmd)
+	if _, ok := msg.(tea.KeyMsg); !ok && followsWork(a.currentPage) {
+		a.pages[page.ChatPage], cmd = a.pages[page.ChatPage].Update(msg)
+		cmds = append(cmds, cmd)
+	}
 	return a, tea.Batch(cmds...)
 }
 
@@ -679,7 +800,10 @@ func (a *appModel) findCommand(id string) (dialog.Command, bool) {
 }
 
 func (a *appModel) moveToPage(pageID page.PageID) tea.Cmd {
-	if a.app.CoderAgent.IsBusy() {
+	// The task inspector and the timeline follow running work, the chat page
+	// keeps receiving events while they are open
+	inspecting := followsWork(pageID) || followsWork(a.currentPage)
+	if a.app.CoderAgent.IsBusy() && !inspecting {
 		// For now we don't move to any page if the agent is busy
 		return util.ReportWarn("Agent is busy, please wait...")
 	}
@@ -700,6 +824,11 @@ func (a *appModel) moveToPage(pageID page.PageID) tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
+// followsWork tells the pages that can be opened while the agent is busy
+func followsWork(pageID page.PageID) bool {
+	return pageID == page.TasksPage || pageID == page.TimelinePage || pageID == page.ContextPage
+}
+
 func (a appModel) View() string {
 	components := []string{
 		a.pages[a.currentPage].View(),
@@ -773,7 +902,7 @@ func (a appModel) View() string {
 		if a.showPermissions {
 			bindings = append(bindings, a.permissions.BindingKeys()...)
 		}
-		if a.currentPage == page.LogsPage {
+		if a.currentPage == page.LogsPage || a.currentPage == page.TasksPage || a.currentPage == page.MetricsPage || a.currentPage == page.TimelinePage || a.currentPage == page.ContextPage || a.currentPage == page.PostMortemPage || a.currentPage == page.SessionDiffPage {
 			bindings = append(bindings, logsKeyReturnKey)
 		}
 		if !a.app.CoderAgent.IsBusy() {
@@ -881,6 +1010,36 @@ func (a appModel) View() string {
 		)
 	}
 
+	if a.showTemplateDialog {
+		overlay := a.templateDialog.View()
+		row := lipgloss.Height(appView) / 2
+		row -= lipgloss.Height(overlay) / 2
+		col := lipgloss.Width(appView) / 2
+		col -= lipgloss.Width(overlay) / 2
+		appView = layout.PlaceOverlay(
+			col,
+			row,
+			overlay,
+			appView,
+			true,
+		)
+	}
+
+	if a.showSystemPromptDialog {
+		overlay := a.systemPromptDialog.View()
+		row := lipgloss.Height(appView) / 2
+		row -= lipgloss.Height(overlay) / 2
+		col := lipgloss.Width(appView) / 2
+		col -= lipgloss.Width(overlay) / 2
+		appView = layout.PlaceOverlay(
+			col,
+			row,
+			overlay,
+			appView,
+			true,
+		)
+	}
+
 	if a.showMultiArgumentsDialog {
 		overlay := a.multiArgumentsDialog.View()
 		row := lipgloss.Height(appView) / 2
@@ -899,26 +1058,48 @@ func (a appModel) View() string {
 	return appView
 }
 
-func New(app *app.App, dangerouslySkipPermissions bool) tea.Model {
+// saveSystemPrompt stores the session instructions edited in the system prompt editor
+func (a appModel) saveSystemPrompt(sessionID, additions string) tea.Cmd {
+	sess, err := a.app.Sessions.Get(context.Background(), sessionID)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	sess.SystemPrompt = strings.TrimSpace(additions)
+	if _, err := a.app.Sessions.Save(context.Background(), sess); err != nil {
+		return util.ReportError(err)
+	}
+	if sess.SystemPrompt == "" {
+		return util.ReportInfo("Session instructions cleared")
+	}
+	return util.ReportInfo("Session instructions saved")
+}
+
+func New(app *app.App) tea.Model {
 	startPage := page.ChatPage
 	model := &appModel{
-		currentPage:               startPage,
-		loadedPages:               make(map[page.PageID]bool),
-		status:                    core.NewStatusCmp(app.LSPClients),
-		help:                      dialog.NewHelpCmp(),
-		quit:                      dialog.NewQuitCmp(),
-		sessionDialog:             dialog.NewSessionDialogCmp(),
-		commandDialog:             dialog.NewCommandDialogCmp(),
-		modelDialog:               dialog.NewModelDialogCmp(),
-		permissions:               dialog.NewPermissionDialogCmp(),
-		initDialog:                dialog.NewInitDialogCmp(),
-		themeDialog:               dialog.NewThemeDialogCmp(),
-		app:                       app,
-		commands:                  []dialog.Command{},
-		dangerouslySkipPermissions: dangerouslySkipPermissions,
+		currentPage:    startPage,
+		loadedPages:    make(map[page.PageID]bool),
+		status:         core.NewStatusCmp(app.LSPClients),
+		help:           dialog.NewHelpCmp(),
+		quit:           dialog.NewQuitCmp(),
+		sessionDialog:  dialog.NewSessionDialogCmp(),
+		templateDialog: dialog.NewTemplateDialogCmp(),
+		commandDialog:  dialog.NewCommandDialogCmp(),
+		modelDialog:    dialog.NewModelDialogCmp(),
+		permissions:    dialog.NewPermissionDialogCmp(),
+		initDialog:     dialog.NewInitDialogCmp(),
+		themeDialog:    dialog.NewThemeDialogCmp(),
+		app:            app,
+		commands:       []dialog.Command{},
 		pages: map[page.PageID]tea.Model{
-			page.ChatPage: page.NewChatPage(app, dangerouslySkipPermissions),
-			page.LogsPage: page.NewLogsPage(),
+			page.ChatPage:        page.NewChatPage(app),
+			page.LogsPage:        page.NewLogsPage(),
+			page.TasksPage:       page.NewTasksPage(app),
+			page.MetricsPage:     page.NewMetricsPage(app),
+			page.TimelinePage:    page.NewTimelinePage(app),
+			page.ContextPage:     page.NewContextPage(app),
+			page.PostMortemPage:  page.NewPostMortemPage(app),
+			page.SessionDiffPage: page.NewSessionDiffPage(app),
 		},
 		filepicker: dialog.NewFilepickerCmp(app),
 	}
@@ -953,6 +1134,46 @@ If there are Cursor rules (in .cursor/rules/ or .cursorrules) or Copilot rules (
 			}
 		},
 	})
+
+	model.RegisterCommand(dialog.Command{
+		ID:          "toggle-usage",
+		Title:       "Toggle Usage Footers",
+		Description: "Show or hide the tokens and cost under each response",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			hide := !config.Get().TUI.HideUsage
+			if err := config.UpdateHideUsage(hide); err != nil {
+				return util.ReportError(err)
+			}
+			return util.CmdHandler(chat.UsageToggledMsg{})
+		},
+	})
+
+	model.RegisterCommand(dialog.Command{
+		ID:          "toggle-skip-permissions",
+		Title:       "Toggle Skip Permissions",
+		Description: "Run tools without asking for permission, or ask again",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			updated := app.Settings.Update(func(s *settings.Settings) {
+				s.SkipPermissions = !s.SkipPermissions
+			})
+			if updated.SkipPermissions {
+				return util.ReportWarn("⚠️ DANGEROUS: tool permissions are skipped")
+			}
+			return util.ReportInfo("Tool permissions are asked for again")
+		},
+	})
+
+	model.RegisterCommand(dialog.Command{
+		ID:          "metrics",
+		Title:       "Agent Metrics",
+		Description: "Show weekly trends of the tasks run by subagents",
+		Handler: func(cmd dialog.Command) tea.Cmd {
+			return tea.Sequence(
+				util.CmdHandler(page.PageChangeMsg{ID: page.MetricsPage}),
+				util.CmdHandler(page.ShowMetricsMsg{}),
+			)
+		},
+	})
 	// Load custom commands
 	customCommands, err := dialog.LoadCustomCommands()
 	if err != nil {
@@ -963,10 +1184,23 @@ If there are Cursor rules (in .cursor/rules/ or .cursorrules) or Copilot rules (
 		}
 	}
 
+	// The prompts of the MCP servers, listed when the coder agent was created
+	for _, p := range agent.MCPPrompts() {
+		model.RegisterCommand(dialog.NewMCPPromptCommand(p.Server, p.Name, p.Description))
+	}
+
 	// Pass commands to chat page for slash command support
 	if chatPage, ok := model.pages[page.ChatPage].(page.CommandSetter); ok {
 		chatPage.SetCommands(model.commands)
 	}
 
+	// Changes to the config and the command files apply without a restart
+	watcher, err := newConfigWatcher()
+	if err != nil {
+		logging.Warn("Failed to watch the config files", "error", err)
+	} else {
+		model.configWatcher = watcher
+	}
+
 	return model
 }
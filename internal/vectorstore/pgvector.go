@@ -0,0 +1,205 @@
+package vectorstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// pgvectorStore keeps the records in PostgreSQL with the pgvector extension,
+// a collection of the store being a table searched with an HNSW index
+type pgvectorStore struct {
+	db     *sql.DB
+	prefix string
+
+	mu   sync.Mutex
+	dims map[string]int // Dimensions of the tables known to exist
+}
+
+// NewPGVector opens the vector store of the PostgreSQL database of dsn with
+// the pgx driver
+func NewPGVector(dsn, prefix string) (Store, error) {
+	return openPGVector("pgx", dsn, prefix)
+}
+
+func openPGVector(driver, dsn, prefix string) (Store, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("pgvector: %w", err)
+	}
+	if _, err := db.Exec(`CREATE EXTENSION IF NOT EXISTS vector`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("pgvector: failed to enable the vector extension: %w", err)
+	}
+	return &pgvectorStore{db: db, prefix: prefix, dims: make(map[string]int)}, nil
+}
+
+// ensureTable creates the table of a collection the first time records are
+// added to it, and checks the records have the dimensions of the table. The
+// error of a mismatch inside the transaction wouldn't tell.
+func (p *pgvectorStore) ensureTable(ctx context.Context, table string, dims int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	existing, ok := p.dims[table]
+	if !ok {
+		var err error
+		if existing, err = p.createTable(ctx, table, dims); err != nil {
+			return err
+		}
+		p.dims[table] = existing
+	}
+	if existing != dims {
+		return fmt.Errorf("pgvector: collection %s has %d dimensions, the records have %d, drop it to change the embedding model", table, existing, dims)
+	}
+	return nil
+}
+
+// createTable creates the table of a collection unless it exists, and returns
+// the dimensions of its vectors
+func (p *pgvectorStore) createTable(ctx context.Context, table string, dims int) (int, error) {
+	schema := fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id TEXT PRIMARY KEY,
+		embedding vector(%[2]d) NOT NULL,
+		metadata JSONB NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS %[1]s_embedding_idx ON %[1]s USING hnsw (embedding vector_cosine_ops);
+	`, table, dims)
+	if _, err := p.db.ExecContext(ctx, schema); err != nil {
+		return 0, fmt.Errorf("pgvector: failed to create table %s: %w", table, err)
+	}
+	// The type modifier of a vector column is its dimensions
+	var existing int
+	err := p.db.QueryRowContext(ctx, `SELECT atttypmod FROM pg_attribute WHERE attrelid = to_regclass($1) AND attname = 'embedding'`, table).Scan(&existing)
+	if err != nil {
+		return 0, fmt.Errorf("pgvector: failed to read the dimensions of table %s: %w", table, err)
+	}
+	return existing, nil
+}
+
+// exists reports whether the table of a collection exists
+func (p *pgvectorStore) exists(ctx context.Context, table string) (bool, error) {
+	p.mu.Lock()
+	_, created := p.dims[table]
+	p.mu.Unlock()
+	if created {
+		return true, nil
+	}
+	var ok bool
+	err := p.db.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, table).Scan(&ok)
+	return ok, err
+}
+
+// vectorLiteral writes a vector the way pgvector parses it, e.g. [1,0.5]
+func vectorLiteral(vector []float32) string {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, value := range vector {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatFloat(float64(value), 'g', -1, 32))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+func (p *pgvectorStore) Upsert(ctx context.Context, collection string, records []Record) error {
+	dims, err := checkDimensions(records)
+	if err != nil || dims == 0 {
+		return err
+	}
+	table := name(p.prefix, collection)
+	if err := p.ensureTable(ctx, table, dims); err != nil {
+		return err
+	}
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	query := fmt.Sprintf(`INSERT INTO %s (id, embedding, metadata) VALUES ($1, $2::vector, $3::jsonb)
+	ON CONFLICT (id) DO UPDATE SET embedding = EXCLUDED.embedding, metadata = EXCLUDED.metadata`, table)
+	for _, record := range records {
+		metadata, err := json.Marshal(cloneMetadata(record.Metadata))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, record.ID, vectorLiteral(record.Vector), string(metadata)); err != nil {
+			return fmt.Errorf("pgvector: failed to store record %s: %w", record.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (p *pgvectorStore) Delete(ctx context.Context, collection string, ids []string) error {
+	table := name(p.prefix, collection)
+	if ok, err := p.exists(ctx, table); err != nil || !ok || len(ids) == 0 {
+		return err
+	}
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, table)
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (p *pgvectorStore) Search(ctx context.Context, collection string, vector []float32, limit int, filter map[string]string) ([]Match, error) {
+	table := name(p.prefix, collection)
+	if ok, err := p.exists(ctx, table); err != nil || !ok {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	filterJSON, err := json.Marshal(cloneMetadata(filter))
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT id, metadata, 1 - (embedding <=> $1::vector) FROM %s
+	WHERE metadata @> $2::jsonb ORDER BY embedding <=> $1::vector LIMIT $3`, table)
+	rows, err := p.db.QueryContext(ctx, query, vectorLiteral(vector), string(filterJSON), limit)
+	if err != nil {
+		return nil, fmt.Errorf("pgvector: %w", err)
+	}
+	defer rows.Close()
+	var found []Match
+	for rows.Next() {
+		var match Match
+		var metadata []byte
+		if err := rows.Scan(&match.ID, &metadata, &match.Score); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(metadata, &match.Metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata of record %s: %w", match.ID, err)
+		}
+		found = append(found, match)
+	}
+	return found, rows.Err()
+}
+
+func (p *pgvectorStore) Drop(ctx context.Context, collection string) error {
+	table := name(p.prefix, collection)
+	p.mu.Lock()
+	delete(p.dims, table)
+	p.mu.Unlock()
+	_, err := p.db.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s`, table))
+	return err
+}
+
+func (p *pgvectorStore) Close() error {
+	return p.db.Close()
+}
@@ -0,0 +1,204 @@
+package vectorstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePG answers the statements the store sends to create tables and store
+// records, keeping the dimensions of the tables like PostgreSQL does
+type fakePG struct {
+	mu      sync.Mutex
+	tables  map[string]int
+	inserts int
+}
+
+var (
+	fakePGs      = make(map[string]*fakePG) // DSN -> database
+	fakePGsMutex sync.Mutex
+	createTable  = regexp.MustCompile(`CREATE TABLE IF NOT EXISTS (\w+) \(\s*id TEXT PRIMARY KEY,\s*embedding vector\((\d+)\)`)
+)
+
+func init() {
+	sql.Register("fakepg", fakePGDriver{})
+}
+
+// newFakePG returns the database of dsn
+func newFakePG(dsn string) *fakePG {
+	fakePGsMutex.Lock()
+	defer fakePGsMutex.Unlock()
+	db := &fakePG{tables: make(map[string]int)}
+	fakePGs[dsn] = db
+	return db
+}
+
+type fakePGDriver struct{}
+
+func (fakePGDriver) Open(dsn string) (driver.Conn, error) {
+	fakePGsMutex.Lock()
+	defer fakePGsMutex.Unlock()
+	db, ok := fakePGs[dsn]
+	if !ok {
+		return nil, fmt.Errorf("unknown database %s", dsn)
+	}
+	return fakePGConn{db}, nil
+}
+
+type fakePGConn struct{ db *fakePG }
+
+func (c fakePGConn) Prepare(query string) (driver.Stmt, error) {
+	return fakePGStmt{db: c.db, query: query}, nil
+}
+func (c fakePGConn) Close() error              { return nil }
+func (c fakePGConn) Begin() (driver.Tx, error) { return fakePGTx{}, nil }
+
+type fakePGTx struct{}
+
+func (fakePGTx) Commit() error   { return nil }
+func (fakePGTx) Rollback() error { return nil }
+
+type fakePGStmt struct {
+	db    *fakePG
+	query string
+}
+
+func (s fakePGStmt) Close() error  { return nil }
+func (s fakePGStmt) NumInput() int { return -1 }
+
+func (s fakePGStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	switch {
+	case strings.Contains(s.query, "CREATE EXTENSION"):
+	case strings.Contains(s.query, "CREATE TABLE"):
+		match := createTable.FindStringSubmatch(s.query)
+		if match == nil {
+			return nil, fmt.Errorf("unexpected schema: %s", s.query)
+		}
+		if _, ok := s.db.tables[match[1]]; !ok {
+			var dims int
+			fmt.Sscan(match[2], &dims)
+			s.db.tables[match[1]] = dims
+		}
+	case strings.HasPrefix(s.query, "INSERT INTO"):
+		table := strings.Fields(s.query)[2]
+		if dims := strings.Count(args[1].(string), ",") + 1; dims != s.db.tables[table] {
+			return nil, fmt.Errorf("expected %d dimensions, not %d", s.db.tables[table], dims)
+		}
+		s.db.inserts++
+	case strings.HasPrefix(s.query, "DROP TABLE IF EXISTS"):
+		delete(s.db.tables, strings.Fields(s.query)[4])
+	default:
+		return nil, fmt.Errorf("unexpected statement: %s", s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakePGStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	dims, ok := s.db.tables[args[0].(string)]
+	switch {
+	case strings.Contains(s.query, "atttypmod"):
+		if !ok {
+			return &fakePGRows{column: "atttypmod"}, nil
+		}
+		return &fakePGRows{column: "atttypmod", values: []driver.Value{int64(dims)}}, nil
+	case strings.Contains(s.query, "to_regclass"):
+		return &fakePGRows{column: "exists", values: []driver.Value{ok}}, nil
+	}
+	return nil, fmt.Errorf("unexpected query: %s", s.query)
+}
+
+// fakePGRows are rows of a single column
+type fakePGRows struct {
+	column string
+	values []driver.Value
+}
+
+func (r *fakePGRows) Columns() []string { return []string{r.column} }
+func (r *fakePGRows) Close() error      { return nil }
+
+func (r *fakePGRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func TestPGVectorDimensions(t *testing.T) {
+	ctx := context.Background()
+	db := newFakePG(t.Name())
+	// A previous run created a collection with another embedding model
+	db.tables[name("opencode_", "memory")] = 4
+
+	store, err := openPGVector("fakepg", t.Name(), "opencode_")
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.Upsert(ctx, "code", []Record{
+		{ID: "a", Vector: []float32{1, 0}},
+		{ID: "b", Vector: []float32{0, 1}},
+	}))
+	assert.Equal(t, 2, db.inserts)
+
+	err = store.Upsert(ctx, "code", []Record{{ID: "c", Vector: []float32{1, 0, 0}}})
+	assert.EqualError(t, err, "pgvector: collection opencode_code has 2 dimensions, the records have 3, drop it to change the embedding model")
+	err = store.Upsert(ctx, "memory", []Record{{ID: "a", Vector: []float32{1, 0}}})
+	assert.ErrorContains(t, err, "has 4 dimensions, the records have 2")
+	assert.Equal(t, 2, db.inserts, "nothing is sent after a mismatch")
+
+	require.NoError(t, store.Drop(ctx, "code"))
+	require.NoError(t, store.Upsert(ctx, "code", []Record{{ID: "c", Vector: []float32{1, 0, 0}}}))
+	assert.Equal(t, 3, db.inserts)
+}
+
+// TestPGVector runs against the PostgreSQL database of
+// $OPENCODE_TEST_PGVECTOR_DSN, with the pgvector extension available
+func TestPGVector(t *testing.T) {
+	dsn := os.Getenv("OPENCODE_TEST_PGVECTOR_DSN")
+	if dsn == "" {
+		t.Skip("OPENCODE_TEST_PGVECTOR_DSN is not set")
+	}
+	ctx := context.Background()
+	store, err := NewPGVector(dsn, "opencode_test_")
+	require.NoError(t, err)
+	defer store.Close()
+	defer store.Drop(ctx, "code")
+
+	require.NoError(t, store.Upsert(ctx, "code", []Record{
+		{ID: "a", Vector: []float32{1, 0}, Metadata: map[string]string{"path": "a.go"}},
+		{ID: "b", Vector: []float32{0.6, 0.8}, Metadata: map[string]string{"path": "b.go"}},
+		{ID: "c", Vector: []float32{0, 1}},
+	}))
+	found, err := store.Search(ctx, "code", []float32{1, 0}, 2, nil)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, "a", found[0].ID)
+	assert.InDelta(t, 1, found[0].Score, 1e-6)
+	assert.Equal(t, "b", found[1].ID)
+
+	found, err = store.Search(ctx, "code", []float32{1, 0}, 5, map[string]string{"path": "b.go"})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "b", found[0].ID)
+
+	require.NoError(t, store.Delete(ctx, "code", []string{"a"}))
+	err = store.Upsert(ctx, "code", []Record{{ID: "d", Vector: []float32{1, 0, 0}}})
+	assert.ErrorContains(t, err, "has 2 dimensions, the records have 3")
+	found, err = store.Search(ctx, "code", []float32{1, 0}, 1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "b", found[0].ID)
+}
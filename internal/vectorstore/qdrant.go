@@ -0,0 +1,221 @@
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	qdrantURL = "http://localhost:6333"
+
+	// qdrantIDKey is the payload key of the ID of a record, Qdrant only
+	// accepts numbers and UUIDs as point IDs
+	qdrantIDKey = "_id"
+
+	qdrantTimeout = time.Minute
+)
+
+// errNotFound is returned for a collection Qdrant doesn't have
+var errNotFound = errors.New("not found")
+
+// qdrantNamespace derives the UUIDs of the points from the IDs of the records
+var qdrantNamespace = uuid.MustParse("6f0c6a39-3d2b-4a57-9a8b-0a5d3c1e2f47")
+
+// qdrantStore keeps the records in a Qdrant server, a collection of the
+// store being a collection of Qdrant
+type qdrantStore struct {
+	url    string
+	apiKey string
+	prefix string
+	client *http.Client
+
+	mu      sync.Mutex
+	created map[string]bool // Collections known to exist
+}
+
+// NewQdrant returns the vector store of the Qdrant server at url
+func NewQdrant(url, apiKey, prefix string) Store {
+	if url == "" {
+		url = qdrantURL
+	}
+	if apiKey == "" {
+		apiKey = os.Getenv("QDRANT_API_KEY")
+	}
+	return &qdrantStore{
+		url:     strings.TrimSuffix(url, "/"),
+		apiKey:  apiKey,
+		prefix:  prefix,
+		client:  &http.Client{Timeout: qdrantTimeout},
+		created: make(map[string]bool),
+	}
+}
+
+// do sends a request to Qdrant and decodes the result of its response
+func (q *qdrantStore) do(ctx context.Context, method, path string, request, result any) error {
+	var body io.Reader
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, q.url+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if q.apiKey != "" {
+		req.Header.Set("api-key", q.apiKey)
+	}
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("qdrant: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("qdrant: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	if result == nil {
+		return nil
+	}
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("qdrant: invalid response: %w", err)
+	}
+	return json.Unmarshal(envelope.Result, result)
+}
+
+// ensureCollection creates a collection of cosine distance the first time
+// records are added to it
+func (q *qdrantStore) ensureCollection(ctx context.Context, collection string, dims int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.created[collection] {
+		return nil
+	}
+	path := "/collections/" + collection
+	err := q.do(ctx, http.MethodGet, path, nil, nil)
+	if errors.Is(err, errNotFound) {
+		err = q.do(ctx, http.MethodPut, path, map[string]any{
+			"vectors": map[string]any{"size": dims, "distance": "Cosine"},
+		}, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create collection %s: %w", collection, err)
+	}
+	q.created[collection] = true
+	return nil
+}
+
+func pointID(id string) string {
+	return uuid.NewSHA1(qdrantNamespace, []byte(id)).String()
+}
+
+func (q *qdrantStore) Upsert(ctx context.Context, collection string, records []Record) error {
+	dims, err := checkDimensions(records)
+	if err != nil || dims == 0 {
+		return err
+	}
+	collection = name(q.prefix, collection)
+	if err := q.ensureCollection(ctx, collection, dims); err != nil {
+		return err
+	}
+	type point struct {
+		ID      string            `json:"id"`
+		Vector  []float32         `json:"vector"`
+		Payload map[string]string `json:"payload"`
+	}
+	points := make([]point, len(records))
+	for i, record := range records {
+		payload := cloneMetadata(record.Metadata)
+		payload[qdrantIDKey] = record.ID
+		points[i] = point{ID: pointID(record.ID), Vector: record.Vector, Payload: payload}
+	}
+	return q.do(ctx, http.MethodPut, "/collections/"+collection+"/points?wait=true", map[string]any{"points": points}, nil)
+}
+
+func (q *qdrantStore) Delete(ctx context.Context, collection string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	points := make([]string, len(ids))
+	for i, id := range ids {
+		points[i] = pointID(id)
+	}
+	err := q.do(ctx, http.MethodPost, "/collections/"+name(q.prefix, collection)+"/points/delete?wait=true", map[string]any{"points": points}, nil)
+	if errors.Is(err, errNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (q *qdrantStore) Search(ctx context.Context, collection string, vector []float32, limit int, filter map[string]string) ([]Match, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	request := map[string]any{
+		"vector":       vector,
+		"limit":        limit,
+		"with_payload": true,
+	}
+	if len(filter) > 0 {
+		var must []map[string]any
+		for key, value := range filter {
+			must = append(must, map[string]any{"key": key, "match": map[string]any{"value": value}})
+		}
+		request["filter"] = map[string]any{"must": must}
+	}
+	var result []struct {
+		Score   float64           `json:"score"`
+		Payload map[string]string `json:"payload"`
+	}
+	err := q.do(ctx, http.MethodPost, "/collections/"+name(q.prefix, collection)+"/points/search", request, &result)
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	found := make([]Match, len(result))
+	for i, point := range result {
+		id := point.Payload[qdrantIDKey]
+		delete(point.Payload, qdrantIDKey)
+		found[i] = Match{ID: id, Metadata: point.Payload, Score: point.Score}
+	}
+	return found, nil
+}
+
+func (q *qdrantStore) Drop(ctx context.Context, collection string) error {
+	collection = name(q.prefix, collection)
+	q.mu.Lock()
+	delete(q.created, collection)
+	q.mu.Unlock()
+	err := q.do(ctx, http.MethodDelete, "/collections/"+collection, nil, nil)
+	if errors.Is(err, errNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (q *qdrantStore) Close() error {
+	q.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,152 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kirmad/superopencode/internal/llm/embedding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQdrant serves the part of the API of Qdrant the store uses
+type fakeQdrant struct {
+	mu          sync.Mutex
+	collections map[string]map[string]qdrantPoint
+	sizes       map[string]int
+}
+
+type qdrantPoint struct {
+	ID      string            `json:"id"`
+	Vector  []float32         `json:"vector"`
+	Payload map[string]string `json:"payload"`
+}
+
+func (f *fakeQdrant) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/collections/"), "/")
+	collection := parts[0]
+	points, ok := f.collections[collection]
+	reply := func(result any) {
+		json.NewEncoder(w).Encode(map[string]any{"result": result, "status": "ok"})
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodPut:
+		var request struct {
+			Vectors struct {
+				Size int `json:"size"`
+			} `json:"vectors"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		f.collections[collection] = map[string]qdrantPoint{}
+		f.sizes[collection] = request.Vectors.Size
+		reply(true)
+	case !ok:
+		http.Error(w, `{"status":{"error":"Not found"}}`, http.StatusNotFound)
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		reply(map[string]any{})
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		delete(f.collections, collection)
+		reply(true)
+	case parts[1] == "points" && len(parts) == 2:
+		var request struct {
+			Points []qdrantPoint `json:"points"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		for _, point := range request.Points {
+			points[point.ID] = point
+		}
+		reply(map[string]any{"status": "completed"})
+	case parts[2] == "delete":
+		var request struct {
+			Points []string `json:"points"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		for _, id := range request.Points {
+			delete(points, id)
+		}
+		reply(map[string]any{"status": "completed"})
+	case parts[2] == "search":
+		var request struct {
+			Vector []float32 `json:"vector"`
+			Limit  int       `json:"limit"`
+			Filter struct {
+				Must []struct {
+					Key   string `json:"key"`
+					Match struct {
+						Value string `json:"value"`
+					} `json:"match"`
+				} `json:"must"`
+			} `json:"filter"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		type scored struct {
+			Score   float64           `json:"score"`
+			Payload map[string]string `json:"payload"`
+		}
+		var result []scored
+		for _, point := range points {
+			keep := true
+			for _, must := range request.Filter.Must {
+				keep = keep && point.Payload[must.Key] == must.Match.Value
+			}
+			if keep {
+				result = append(result, scored{embedding.Similarity(request.Vector, point.Vector), point.Payload})
+			}
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Score > result[j].Score })
+		reply(result[:min(request.Limit, len(result))])
+	}
+}
+
+func TestQdrant(t *testing.T) {
+	fake := &fakeQdrant{collections: map[string]map[string]qdrantPoint{}, sizes: map[string]int{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	store := NewQdrant(server.URL, "", "project_")
+	ctx := context.Background()
+
+	found, err := store.Search(ctx, "code", []float32{1, 0}, 5, nil)
+	require.NoError(t, err)
+	assert.Empty(t, found, "a collection that doesn't exist is empty")
+
+	require.NoError(t, store.Upsert(ctx, "code", []Record{
+		{ID: "main.go:1", Vector: []float32{1, 0}, Metadata: map[string]string{"lang": "go"}},
+		{ID: "app.py:1", Vector: []float32{0.9, 0.1}, Metadata: map[string]string{"lang": "python"}},
+		{ID: "util.go:1", Vector: []float32{0, 1}, Metadata: map[string]string{"lang": "go"}},
+	}))
+	assert.Equal(t, 2, fake.sizes["project_code"])
+
+	found, err = store.Search(ctx, "code", []float32{1, 0}, 2, nil)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, "main.go:1", found[0].ID)
+	assert.Equal(t, map[string]string{"lang": "go"}, found[0].Metadata)
+	assert.Equal(t, "app.py:1", found[1].ID)
+
+	found, err = store.Search(ctx, "code", []float32{1, 0}, 5, map[string]string{"lang": "go"})
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+
+	require.NoError(t, store.Delete(ctx, "code", []string{"main.go:1"}))
+	found, err = store.Search(ctx, "code", []float32{1, 0}, 1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "app.py:1", found[0].ID)
+
+	assert.Error(t, store.Upsert(ctx, "code", []Record{{ID: "x", Vector: []float32{1}}, {ID: "y", Vector: []float32{1, 0}}}))
+	require.NoError(t, store.Drop(ctx, "code"))
+	assert.NotContains(t, fake.collections, "project_code")
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "opencode_code", name("opencode_", "code"))
+	assert.Equal(t, "opencode_my_index_v2", name("opencode_", "My Index.v2"))
+}
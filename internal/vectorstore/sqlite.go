@@ -0,0 +1,164 @@
+package vectorstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+
+	"github.com/kirmad/superopencode/internal/llm/embedding"
+)
+
+// sqliteStore keeps the records in a SQLite file and searches a collection
+// by comparing the query with all its vectors
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// NewSQLite opens the SQLite vector store at path
+func NewSQLite(path string) (Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the vector store: %w", err)
+	}
+	schema := `
+	PRAGMA journal_mode = WAL;
+	CREATE TABLE IF NOT EXISTS vectors (
+		collection TEXT NOT NULL,
+		id TEXT NOT NULL,
+		vector BLOB NOT NULL,
+		metadata TEXT NOT NULL,
+		PRIMARY KEY (collection, id)
+	);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize the vector store: %w", err)
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) Upsert(ctx context.Context, collection string, records []Record) error {
+	if _, err := checkDimensions(records); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, record := range records {
+		metadata, err := json.Marshal(cloneMetadata(record.Metadata))
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT OR REPLACE INTO vectors (collection, id, vector, metadata) VALUES (?, ?, ?, ?)`,
+			collection, record.ID, encodeVector(record.Vector), string(metadata))
+		if err != nil {
+			return fmt.Errorf("failed to store record %s: %w", record.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Delete(ctx context.Context, collection string, ids []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM vectors WHERE collection = ? AND id = ?`, collection, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Search(ctx context.Context, collection string, vector []float32, limit int, filter map[string]string) ([]Match, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, vector, metadata FROM vectors WHERE collection = ?`, collection)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var found []Match
+	for rows.Next() {
+		var id, metadataJSON string
+		var blob []byte
+		if err := rows.Scan(&id, &blob, &metadataJSON); err != nil {
+			return nil, err
+		}
+		var metadata map[string]string
+		if err := json.Unmarshal([]byte(metadataJSON), &metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata of record %s: %w", id, err)
+		}
+		if !matches(metadata, filter) {
+			continue
+		}
+		found = append(found, Match{
+			ID:       id,
+			Metadata: metadata,
+			Score:    embedding.Similarity(vector, decodeVector(blob)),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return best(found, limit), nil
+}
+
+func (s *sqliteStore) Drop(ctx context.Context, collection string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM vectors WHERE collection = ?`, collection)
+	return err
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// best returns the limit matches with the highest score, best first
+func best(found []Match, limit int) []Match {
+	slices.SortStableFunc(found, func(a, b Match) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	return found
+}
+
+// encodeVector stores a vector as little endian float32s
+func encodeVector(vector []float32) []byte {
+	data := make([]byte, len(vector)*4)
+	for i, value := range vector {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(value))
+	}
+	return data
+}
+
+func decodeVector(data []byte) []float32 {
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return vector
+}
@@ -0,0 +1,57 @@
+package vectorstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite(t *testing.T) {
+	store, err := NewSQLite(filepath.Join(t.TempDir(), "vectors.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	ctx := context.Background()
+
+	require.NoError(t, store.Upsert(ctx, "code", []Record{
+		{ID: "a", Vector: []float32{1, 0}, Metadata: map[string]string{"path": "a.go"}},
+		{ID: "b", Vector: []float32{0.6, 0.8}, Metadata: map[string]string{"path": "b.go"}},
+		{ID: "c", Vector: []float32{0, 1}},
+	}))
+	require.NoError(t, store.Upsert(ctx, "memory", []Record{{ID: "a", Vector: []float32{0, 1}}}))
+
+	found, err := store.Search(ctx, "code", []float32{1, 0}, 2, nil)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, "a", found[0].ID)
+	assert.InDelta(t, 1, found[0].Score, 1e-6)
+	assert.Equal(t, "b", found[1].ID)
+	assert.InDelta(t, 0.6, found[1].Score, 1e-6)
+
+	found, err = store.Search(ctx, "code", []float32{1, 0}, 5, map[string]string{"path": "b.go"})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "b", found[0].ID)
+
+	// Replaced and deleted records
+	require.NoError(t, store.Upsert(ctx, "code", []Record{{ID: "c", Vector: []float32{1, 0}}}))
+	require.NoError(t, store.Delete(ctx, "code", []string{"a"}))
+	found, err = store.Search(ctx, "code", []float32{1, 0}, 1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "c", found[0].ID)
+
+	require.NoError(t, store.Drop(ctx, "code"))
+	found, err = store.Search(ctx, "code", []float32{1, 0}, 5, nil)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+	found, err = store.Search(ctx, "memory", []float32{0, 1}, 5, nil)
+	require.NoError(t, err)
+	assert.Len(t, found, 1, "other collections are kept")
+}
+
+func TestVectorLiteral(t *testing.T) {
+	assert.Equal(t, "[1,0.5,-2]", vectorLiteral([]float32{1, 0.5, -2}))
+	assert.Equal(t, []float32{1, 0.5, -2}, decodeVector(encodeVector([]float32{1, 0.5, -2})))
+}
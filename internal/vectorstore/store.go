@@ -0,0 +1,99 @@
+// Package vectorstore stores embeddings and finds the ones closest to a
+// query. The backend is set in the vectorStore section of the config: a
+// SQLite file in the data directory by default, or Qdrant or PostgreSQL with
+// pgvector for indexes too large for it.
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kirmad/superopencode/internal/config"
+)
+
+// Record is an embedding and what it was computed from
+type Record struct {
+	ID       string
+	Vector   []float32
+	Metadata map[string]string // e.g. the path and lines of a chunk of code
+}
+
+// Match is a record found by a search
+type Match struct {
+	ID       string
+	Metadata map[string]string
+	Score    float64 // Cosine similarity with the query, from -1 to 1
+}
+
+// Store keeps records in named collections, e.g. one per index. The vectors
+// of a collection all have the same dimensions.
+type Store interface {
+	// Upsert adds records to a collection, replacing the ones with the same ID
+	Upsert(ctx context.Context, collection string, records []Record) error
+	// Delete removes records from a collection
+	Delete(ctx context.Context, collection string, ids []string) error
+	// Search returns the records of a collection closest to a vector, best
+	// first, among the ones whose metadata has all the values of filter
+	Search(ctx context.Context, collection string, vector []float32, limit int, filter map[string]string) ([]Match, error)
+	// Drop removes a collection and its records
+	Drop(ctx context.Context, collection string) error
+	Close() error
+}
+
+// New opens the store of the config
+func New() (Store, error) {
+	cfg := config.Get()
+	vs := cfg.VectorStore
+	switch vs.Backend {
+	case "", config.VectorStoreSQLite:
+		return NewSQLite(filepath.Join(cfg.Data.Directory, "vectors.db"))
+	case config.VectorStoreQdrant:
+		return NewQdrant(vs.URL, vs.APIKey, vs.Prefix), nil
+	case config.VectorStorePGVector:
+		return NewPGVector(vs.URL, vs.Prefix)
+	}
+	return nil, fmt.Errorf("unknown vector store backend %q", vs.Backend)
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// name turns a collection into the name of a collection or table of a
+// backend
+func name(prefix, collection string) string {
+	return invalidNameChars.ReplaceAllString(strings.ToLower(prefix+collection), "_")
+}
+
+// matches reports whether metadata has all the values of filter
+func matches(metadata, filter map[string]string) bool {
+	for key, value := range filter {
+		if v, ok := metadata[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDimensions makes sure the vectors of records can be stored together
+func checkDimensions(records []Record) (int, error) {
+	if len(records) == 0 {
+		return 0, nil
+	}
+	dims := len(records[0].Vector)
+	for _, record := range records {
+		if len(record.Vector) != dims || dims == 0 {
+			return 0, fmt.Errorf("record %s has %d dimensions, expected %d", record.ID, len(record.Vector), dims)
+		}
+	}
+	return dims, nil
+}
+
+func cloneMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return map[string]string{}
+	}
+	return maps.Clone(metadata)
+}
@@ -8,6 +8,7 @@ import (
 func main() {
 	defer logging.RecoverPanic("main", func() {
 		logging.ErrorPersist("Application terminated due to unhandled panic")
+		cmd.OfferCrashReport()
 	})
 
 	cmd.Execute()